@@ -0,0 +1,451 @@
+package tfpluginschema
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// bundleFormatVersion is the manifest format written by ExportBundle.
+	// ImportBundle rejects other versions.
+	bundleFormatVersion = 1
+	// bundleManifestName and bundleSignatureName are the first two files
+	// of a bundle.
+	bundleManifestName  = "manifest.json"
+	bundleSignatureName = "manifest.json.sig"
+	// maxBundleManifestSize bounds the manifest and signature read before
+	// the signature is checked.
+	maxBundleManifestSize = 16 << 20
+	// bundleMethod is the verification method recorded for providers
+	// installed by ImportBundle.
+	bundleMethod = "bundle"
+)
+
+// ErrBundleVerification is returned by ImportBundle when a bundle's
+// manifest signature does not verify, or its files do not match the
+// manifest, and for schema bundles checked by VerifySchemaBundle.
+var ErrBundleVerification = errors.New("bundle verification failed")
+
+// BundleManifest lists the contents of a bundle written by ExportBundle.
+type BundleManifest struct {
+	FormatVersion int              `json:"format_version"`
+	Created       time.Time        `json:"created"`
+	Providers     []BundleProvider `json:"providers"`
+}
+
+// BundleProvider is one provider of a bundle: its binary, built for
+// Platform, and its schema.
+type BundleProvider struct {
+	Registry  RegistryType `json:"registry"`
+	Namespace string       `json:"namespace"`
+	Name      string       `json:"name"`
+	Version   string       `json:"version"`
+	Platform  string       `json:"platform"` // "<os>_<arch>" of the binary
+	Binary    BundleFile   `json:"binary"`
+	Schema    BundleFile   `json:"schema"`
+}
+
+// Request returns the request for p.
+func (p BundleProvider) Request() Request {
+	return Request{Namespace: p.Namespace, Name: p.Name, Version: p.Version, RegistryType: p.Registry}
+}
+
+// BundleFile is a file in a bundle.
+type BundleFile struct {
+	Path   string `json:"path"`   // Slash-separated path within the bundle
+	Size   int64  `json:"size"`   // Size in bytes
+	SHA256 string `json:"sha256"` // Hex SHA-256 digest
+}
+
+// ExportBundle writes a bundle of the providers of requests to w, for
+// carrying them to a network that cannot reach the registries. Each
+// provider is downloaded, unless it is cached, and its schema read. The
+// bundle is a gzipped tar archive holding the provider binaries, their
+// schemas and a manifest of every file's SHA-256, signed with key, which
+// ImportBundle verifies. Binaries are those of the current platform.
+func (s *Server) ExportBundle(w io.Writer, requests []Request, key ed25519.PrivateKey) (*BundleManifest, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid bundle signing key")
+	}
+
+	manifest := &BundleManifest{FormatVersion: bundleFormatVersion, Created: time.Now().UTC()}
+	var binaries []string
+	var schemas [][]byte
+	seen := make(map[providerKey]bool)
+	for _, req := range requests {
+		req, err := s.prepareRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		pk := cacheKey(req)
+		if seen[pk] {
+			continue
+		}
+		seen[pk] = true
+
+		if err := s.get(req); err != nil {
+			return nil, err
+		}
+		s.mu.RLock()
+		binary := s.dlc[pk]
+		s.mu.RUnlock()
+		ls, err := s.getSchema(req)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := ls.providerSchema()
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(ps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode provider schema: %w", err)
+		}
+
+		p := BundleProvider{
+			Registry:  req.RegistryType,
+			Namespace: pk.namespace,
+			Name:      pk.name,
+			Version:   pk.version,
+			Platform:  pk.platform,
+		}
+		p.Binary, err = bundleFileOf(bundleBinaryDir(p)+"/"+filepath.Base(binary), binary)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(schema)
+		p.Schema = BundleFile{Path: "schemas/" + schemaBundlePath(p.Request()), Size: int64(len(schema)), SHA256: hex.EncodeToString(sum[:])}
+		manifest.Providers = append(manifest.Providers, p)
+		binaries = append(binaries, binary)
+		schemas = append(schemas, schema)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n"
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeBundleFile(tw, bundleManifestName, 0o644, bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, err
+	}
+	if err := writeBundleFile(tw, bundleSignatureName, 0o644, strings.NewReader(signature), int64(len(signature))); err != nil {
+		return nil, err
+	}
+	for i, p := range manifest.Providers {
+		f, err := os.Open(binaries[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to open provider binary: %w", err)
+		}
+		err = writeBundleFile(tw, p.Binary.Path, 0o755, f, p.Binary.Size)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBundleFile(tw, p.Schema.Path, 0o644, bytes.NewReader(schemas[i]), p.Schema.Size); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// ImportBundle verifies the bundle written by ExportBundle read from r
+// against key, then installs its provider binaries into the Server's
+// on-disk cache and, if schemaDir is not empty, writes its schemas to
+// schemaDir in the layout read by WithSchemaBundleDir. Nothing is installed
+// unless the manifest signature and every file verify; failures wrap
+// ErrBundleVerification.
+//
+// Binaries built for a platform other than the current one are verified
+// but not installed. Installed providers are recorded as having passed
+// the "bundle" verification method, so WithRequireVerification accepts
+// them.
+func (s *Server) ImportBundle(r io.Reader, key ed25519.PublicKey, schemaDir string) (*BundleManifest, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid bundle verification key")
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	data, err := readBundleHeaderFile(tr, bundleManifestName)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := readBundleHeaderFile(tr, bundleSignatureName)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, data, sig) {
+		return nil, fmt.Errorf("%w: manifest signature does not verify", ErrBundleVerification)
+	}
+	manifest := &BundleManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %w", ErrBundleVerification, err)
+	}
+	if manifest.FormatVersion != bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d", manifest.FormatVersion)
+	}
+	files, err := s.bundleFiles(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBundleVerification, err)
+	}
+
+	if err := os.MkdirAll(s.cacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(s.cacheDir, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := extractBundleFiles(tr, files, staging); err != nil {
+		return nil, err
+	}
+
+	for _, p := range manifest.Providers {
+		if p.Platform == CurrentPlatform().String() {
+			if err := s.installBundledBinary(p, filepath.Join(staging, filepath.FromSlash(p.Binary.Path))); err != nil {
+				return nil, err
+			}
+		}
+		if schemaDir == "" {
+			continue
+		}
+		dst := filepath.Join(schemaDir, filepath.FromSlash(schemaBundlePath(p.Request())))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create schema bundle directory: %w", err)
+		}
+		if err := moveFile(filepath.Join(staging, filepath.FromSlash(p.Schema.Path)), dst); err != nil {
+			return nil, fmt.Errorf("failed to write schema bundle file: %w", err)
+		}
+	}
+	return manifest, nil
+}
+
+// bundleBinaryDir returns the directory of p's binary within a bundle.
+func bundleBinaryDir(p BundleProvider) string {
+	return path.Join("providers", cachePathSegment(string(p.Registry)), p.Namespace, p.Name, p.Version, p.Platform)
+}
+
+// bundleFileOf describes the file at src, stored at bundlePath.
+func bundleFileOf(bundlePath, src string) (BundleFile, error) {
+	digest, err := hashFile(src)
+	if err != nil {
+		return BundleFile{}, fmt.Errorf("failed to hash provider binary: %w", err)
+	}
+	return BundleFile{Path: bundlePath, Size: fileSize(src), SHA256: digest}, nil
+}
+
+// writeBundleFile adds the size bytes of r to tw as the file name.
+func writeBundleFile(tw *tar.Writer, name string, mode int64, r io.Reader, size int64) error {
+	hdr := &tar.Header{Name: name, Mode: mode, Size: size, Typeflag: tar.TypeReg, ModTime: time.Now().UTC()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// readBundleHeaderFile reads the next file of tr, which must be name.
+func readBundleHeaderFile(tr *tar.Reader, name string) ([]byte, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if hdr.Name != name || hdr.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%w: expected %s, found %s", ErrBundleVerification, name, hdr.Name)
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, maxBundleManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if len(data) > maxBundleManifestSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrBundleVerification, name)
+	}
+	return data, nil
+}
+
+// bundleFiles checks that the providers and paths in manifest are safe to
+// install, and returns the files it lists by path.
+func (s *Server) bundleFiles(manifest *BundleManifest) (map[string]BundleFile, error) {
+	files := make(map[string]BundleFile)
+	add := func(f BundleFile, want string) error {
+		if f.Path != want {
+			return fmt.Errorf("unexpected path %q, want %q", f.Path, want)
+		}
+		if b, err := hex.DecodeString(f.SHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("%s: invalid sha256 %q", f.Path, f.SHA256)
+		}
+		if f.Size < 0 {
+			return fmt.Errorf("%s: invalid size %d", f.Path, f.Size)
+		}
+		if _, ok := files[f.Path]; ok {
+			return fmt.Errorf("%s is listed twice", f.Path)
+		}
+		files[f.Path] = f
+		return nil
+	}
+	for _, p := range manifest.Providers {
+		req := p.Request()
+		if err := s.validateCacheRequestIdentity(req); err != nil {
+			return nil, err
+		}
+		if err := s.validateCacheRequestVersion(req); err != nil {
+			return nil, err
+		}
+		if _, err := ParseRegistryType(string(p.Registry)); err != nil {
+			return nil, err
+		}
+		if err := validateCachePathComponent("platform", p.Platform, true); err != nil {
+			return nil, err
+		}
+		binary := path.Base(p.Binary.Path)
+		if !strings.HasPrefix(strings.ToLower(binary), providerFileNamePrefix+strings.ToLower(p.Name)) {
+			return nil, fmt.Errorf("%s is not a binary of provider %s", p.Binary.Path, p.Name)
+		}
+		if err := add(p.Binary, bundleBinaryDir(p)+"/"+binary); err != nil {
+			return nil, err
+		}
+		if err := add(p.Schema, "schemas/"+schemaBundlePath(req)); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// extractBundleFiles writes the remaining files of tr below dir, checking
+// each against files and that every one of files is present.
+func extractBundleFiles(tr *tar.Reader, files map[string]BundleFile, dir string) error {
+	seen := make(map[string]bool, len(files))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		f, ok := files[hdr.Name]
+		if !ok || seen[hdr.Name] || hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("%w: unexpected file %s", ErrBundleVerification, hdr.Name)
+		}
+		seen[hdr.Name] = true
+
+		dst := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return fmt.Errorf("failed to create import directory: %w", err)
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", f.Path, err)
+		}
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(out, h), io.LimitReader(tr, f.Size+1))
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", f.Path, err)
+		}
+		if n != f.Size || hex.EncodeToString(h.Sum(nil)) != strings.ToLower(f.SHA256) {
+			return fmt.Errorf("%w: %s does not match the manifest", ErrBundleVerification, f.Path)
+		}
+	}
+	for p := range files {
+		if !seen[p] {
+			return fmt.Errorf("%w: %s is missing", ErrBundleVerification, p)
+		}
+	}
+	return nil
+}
+
+// installBundledBinary publishes the verified binary at src as the cache
+// entry of p, replacing any existing entry.
+func (s *Server) installBundledBinary(p BundleProvider, src string) error {
+	extractDir := cacheProviderDir(s.cacheDir, p.Request())
+	if err := ensureWithinBaseDir(s.cacheDir, extractDir+stagingDirSuffix); err != nil {
+		return err
+	}
+	stagingDir, err := os.MkdirTemp(filepath.Dir(extractDir), filepath.Base(extractDir)+stagingDirSuffix+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+	if err := os.Chmod(stagingDir, 0o755); err != nil {
+		return fmt.Errorf("failed to set staging directory permissions: %w", err)
+	}
+
+	binary := path.Base(p.Binary.Path)
+	if err := moveFile(src, filepath.Join(stagingDir, binary)); err != nil {
+		return fmt.Errorf("failed to install provider binary: %w", err)
+	}
+	if err := recordBinaryChecksum(stagingDir, binary); err != nil {
+		return err
+	}
+	if err := recordVerification(stagingDir, []string{bundleMethod}); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(extractDir); err != nil {
+		return fmt.Errorf("failed to clear cache directory %s: %w", extractDir, err)
+	}
+	if err := os.Rename(stagingDir, extractDir); err != nil {
+		return fmt.Errorf("failed to publish cache directory %s: %w", extractDir, err)
+	}
+	s.logger(logComponentCache).Info("Installed provider from bundle", append(s.requestLogAttrs(p.Request()), "path", extractDir)...)
+	return nil
+}
+
+// moveFile moves src to dst, copying it if they are on different file
+// systems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,202 @@
+package tfpluginschema
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rewriteBundle returns data with each file passed through edit, which
+// drops the file by returning nil.
+func rewriteBundle(t *testing.T, data []byte, edit func(name string, content []byte) []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		if content = edit(hdr.Name, content); content == nil {
+			continue
+		}
+		hdr.Size = int64(len(content))
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return out.Bytes()
+}
+
+func TestServer_ExportImportBundle(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	exporter := NewServer(nil, WithCacheDir(t.TempDir()),
+		WithHTTPClient(stubProviderRegistry(t, req, providerArchive(t, "null"))),
+		WithSchemaBundle(fstest.MapFS{"opentofu/hashicorp/null/1.0.0.json": {Data: []byte(describedSchema)}}))
+	t.Cleanup(func() { _ = exporter.Cleanup() })
+	var bundle bytes.Buffer
+	manifest, err := exporter.ExportBundle(&bundle, []Request{req, req}, private)
+	require.NoError(t, err)
+	require.Len(t, manifest.Providers, 1, "duplicate requests are bundled once")
+	p := manifest.Providers[0]
+	platform := CurrentPlatform().String()
+	assert.Equal(t, "providers/opentofu/hashicorp/null/1.0.0/"+platform+"/"+providerFileNamePrefix+"null_v1.0.0", p.Binary.Path)
+	assert.Equal(t, sha256Hex([]byte("binary")), p.Binary.SHA256)
+	assert.Equal(t, "schemas/opentofu/hashicorp/null/1.0.0.json", p.Schema.Path)
+
+	t.Run("import", func(t *testing.T) {
+		cacheDir, schemaDir := t.TempDir(), t.TempDir()
+		s := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true))
+		t.Cleanup(func() { _ = s.Cleanup() })
+		got, err := s.ImportBundle(bytes.NewReader(bundle.Bytes()), public, schemaDir)
+		require.NoError(t, err)
+		assert.Equal(t, manifest.Providers, got.Providers)
+
+		entry := cacheProviderDir(cacheDir, normalizedRequest(req))
+		assert.NoError(t, checkBinaryChecksum(entry))
+		assert.Equal(t, []string{"bundle"}, verifiedMethods(entry))
+		entries, err := os.ReadDir(cacheDir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "the import directory is removed")
+
+		offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true), WithRequireVerification(true),
+			WithSchemaBundleDir(schemaDir), WithPluginExec(false))
+		t.Cleanup(func() { _ = offline.Cleanup() })
+		require.NoError(t, offline.Get(req))
+		schema, err := offline.GetResourceSchema(req, "example_thing")
+		require.NoError(t, err)
+		assert.NotNil(t, schema)
+	})
+
+	tampered := map[string][]byte{
+		"wrong key": bundle.Bytes(),
+		"modified binary": rewriteBundle(t, bundle.Bytes(), func(name string, content []byte) []byte {
+			if name == p.Binary.Path {
+				return []byte("malware")
+			}
+			return content
+		}),
+		"missing schema": rewriteBundle(t, bundle.Bytes(), func(name string, content []byte) []byte {
+			if name == p.Schema.Path {
+				return nil
+			}
+			return content
+		}),
+		"unsigned manifest change": rewriteBundle(t, bundle.Bytes(), func(name string, content []byte) []byte {
+			if name == bundleManifestName {
+				return bytes.Replace(content, []byte(`"1.0.0"`), []byte(`"1.0.1"`), 1)
+			}
+			return content
+		}),
+		"unsafe path": rewriteBundle(t, bundle.Bytes(), func(name string, content []byte) []byte {
+			switch name {
+			case bundleManifestName:
+				var m BundleManifest
+				require.NoError(t, json.Unmarshal(content, &m))
+				m.Providers[0].Namespace = ".."
+				content, err = json.Marshal(m)
+				require.NoError(t, err)
+				return content
+			case bundleSignatureName:
+				return nil
+			}
+			return content
+		}),
+	}
+	for name, data := range tampered {
+		t.Run(name, func(t *testing.T) {
+			key := public
+			if name == "wrong key" {
+				key, _, err = ed25519.GenerateKey(nil)
+				require.NoError(t, err)
+			}
+			if name == "unsafe path" {
+				data = resignBundle(t, data, private)
+			}
+			cacheDir := t.TempDir()
+			s := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true))
+			t.Cleanup(func() { _ = s.Cleanup() })
+			_, err := s.ImportBundle(bytes.NewReader(data), key, t.TempDir())
+			require.ErrorIs(t, err, ErrBundleVerification)
+			_, ok := findProviderBinary(cacheProviderDir(cacheDir, normalizedRequest(req)), "null")
+			assert.False(t, ok, "nothing is installed from a bundle that does not verify")
+		})
+	}
+}
+
+// resignBundle inserts a signature of the manifest of data, which must
+// have none, made with key.
+func resignBundle(t *testing.T, data []byte, key ed25519.PrivateKey) []byte {
+	t.Helper()
+	var manifest []byte
+	rewriteBundle(t, data, func(name string, content []byte) []byte {
+		if name == bundleManifestName {
+			manifest = content
+		}
+		return content
+	})
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest)))
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = io.Copy(tw, tr)
+		require.NoError(t, err)
+		if hdr.Name == bundleManifestName {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: bundleSignatureName, Mode: 0o644, Size: int64(len(sig)), Typeflag: tar.TypeReg}))
+			_, err = tw.Write(sig)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return out.Bytes()
+}
+
+func TestServer_BundleFiles(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	p := BundleProvider{Registry: RegistryTypeOpenTofu, Namespace: "hashicorp", Name: "null", Version: "1.0.0", Platform: "plan9_mips"}
+	p.Binary = BundleFile{Path: bundleBinaryDir(p) + "/" + providerFileNamePrefix + "null", SHA256: sha256Hex(nil)}
+	p.Schema = BundleFile{Path: "schemas/opentofu/hashicorp/null/1.0.0.json", SHA256: sha256Hex(nil)}
+	files, err := s.bundleFiles(&BundleManifest{Providers: []BundleProvider{p}})
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	p.Binary.Path = bundleBinaryDir(p) + "/evil"
+	_, err = s.bundleFiles(&BundleManifest{Providers: []BundleProvider{p}})
+	assert.ErrorContains(t, err, "is not a binary of provider null")
+}
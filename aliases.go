@@ -0,0 +1,213 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+const (
+	// defaultProviderNamespace is the namespace Terraform assumes for legacy
+	// and implied provider addresses (e.g. "aws" or "-/aws").
+	defaultProviderNamespace = "hashicorp"
+	// builtInProviderHost and builtInProviderNamespace form the address of
+	// providers compiled into Terraform itself, e.g.
+	// "terraform.io/builtin/terraform".
+	builtInProviderHost      = "terraform.io"
+	builtInProviderNamespace = "builtin"
+)
+
+var (
+	// ErrBuiltInProvider is returned when a request refers to a provider that
+	// is compiled into Terraform (such as terraform.io/builtin/terraform) and
+	// is therefore not distributed through any registry.
+	ErrBuiltInProvider = errors.New("built-in provider is not available from a registry")
+)
+
+// legacyNamespaceAliases maps namespaces found in pre-0.13 state, lock and
+// configuration files onto the namespace the providers are published under
+// today. "-" is the placeholder Terraform used in legacy provider addresses
+// ("-/aws"), and "terraform-providers" is the GitHub organisation the
+// HashiCorp-maintained providers lived in before the registry namespaces.
+var legacyNamespaceAliases = map[string]string{
+	"-":                   defaultProviderNamespace,
+	"terraform-providers": defaultProviderNamespace,
+}
+
+// registryHosts maps the registry hostnames accepted in provider source
+// addresses onto the corresponding RegistryType.
+var registryHosts = map[string]RegistryType{
+	"registry.terraform.io": RegistryTypeTerraform,
+	"registry.opentofu.org": RegistryTypeOpenTofu,
+}
+
+// WithProviderAliases installs additional provider aliases, applied before
+// the built-in legacy-namespace table. Keys and values are "namespace/name"
+// pairs, compared case-insensitively, e.g.
+//
+//	WithProviderAliases(map[string]string{"mycorp/aws": "hashicorp/aws"})
+//
+// Malformed entries are reported when a request is resolved.
+func WithProviderAliases(aliases map[string]string) ServerOption {
+	return func(s *Server) {
+		if s.providerAliases == nil {
+			s.providerAliases = make(map[string]string, len(aliases))
+		}
+		for from, to := range aliases {
+			s.providerAliases[strings.ToLower(from)] = to
+		}
+	}
+}
+
+// ParseProviderSource parses a provider source address as it may appear in
+// configuration, lock or state files into a Request with an empty Version.
+// The following forms are accepted:
+//
+//	aws                              implied source, resolves to hashicorp/aws
+//	-/aws                            legacy (pre-0.13) address
+//	hashicorp/aws
+//	registry.terraform.io/hashicorp/aws
+//	registry.opentofu.org/hashicorp/aws
+//	app.terraform.io/example-org/internal   private registry host
+//
+// Legacy namespaces are rewritten using the same table the Server applies.
+// The built-in terraform provider (terraform.io/builtin/terraform, or the
+// legacy "terraform" type) yields ErrBuiltInProvider.
+func ParseProviderSource(source string) (Request, error) {
+	parts := strings.Split(strings.TrimSpace(source), "/")
+	var req Request
+	switch len(parts) {
+	case 1:
+		req.Namespace, req.Name = defaultProviderNamespace, parts[0]
+		if strings.EqualFold(req.Name, "terraform") {
+			return Request{}, fmt.Errorf("%w: %s", ErrBuiltInProvider, source)
+		}
+	case 2:
+		req.Namespace, req.Name = parts[0], parts[1]
+	case 3:
+		host := strings.ToLower(parts[0])
+		if host == builtInProviderHost && strings.EqualFold(parts[1], builtInProviderNamespace) {
+			return Request{}, fmt.Errorf("%w: %s", ErrBuiltInProvider, source)
+		}
+		rt, ok := registryHosts[host]
+		if !ok {
+			if _, ok := RegistryType(host).customHost(); !ok {
+				return Request{}, fmt.Errorf("unsupported registry host %q in provider source %q", parts[0], source)
+			}
+			rt = RegistryType(host)
+		}
+		req.Namespace, req.Name, req.RegistryType = parts[1], parts[2], rt
+	default:
+		return Request{}, fmt.Errorf("invalid provider source %q: expected [hostname/]namespace/name", source)
+	}
+
+	ns, name, err := resolveLegacyProviderAddress(req.Namespace, req.Name)
+	if err != nil {
+		return Request{}, err
+	}
+	req.Namespace, req.Name = ns, name
+
+	if err := validateCachePathComponent("namespace", req.Namespace, true); err != nil {
+		return Request{}, fmt.Errorf("invalid provider source %q: %w", source, err)
+	}
+	if err := validateCachePathComponent("name", req.Name, true); err != nil {
+		return Request{}, fmt.Errorf("invalid provider source %q: %w", source, err)
+	}
+	return req, nil
+}
+
+// ParseRequest parses a provider source with an optional "@version" suffix,
+// such as "hashicorp/aws@~>5.0" or "registry.terraform.io/hashicorp/aws@5.31.0",
+// into a Request. The source may take any form accepted by
+// ParseProviderSource, and the suffix may be a concrete version or a
+// constraint. Without a suffix, Version is empty, selecting the latest
+// version.
+func ParseRequest(s string) (Request, error) {
+	source, version, hasVersion := strings.Cut(strings.TrimSpace(s), "@")
+	req, err := ParseProviderSource(source)
+	if err != nil {
+		return Request{}, err
+	}
+	if hasVersion {
+		version = strings.TrimSpace(version)
+		if version == "" {
+			return Request{}, fmt.Errorf("invalid provider request %q: empty version after '@'", s)
+		}
+		if _, err := goversion.NewConstraint(version); err != nil {
+			return Request{}, fmt.Errorf("invalid provider request %q: %w", s, err)
+		}
+		req.Version = version
+	}
+	return req, nil
+}
+
+// MustRequest is like ParseRequest but panics if s cannot be parsed. It is
+// intended for scripts and tests where s is a literal.
+func MustRequest(s string) Request {
+	req, err := ParseRequest(s)
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+// WithDefaultNamespace sets the namespace assumed for requests that leave
+// Namespace empty, as Terraform assumes "hashicorp" for an implied provider
+// source such as "aws". It defaults to "hashicorp"; an empty namespace
+// turns the default off, so that such requests are rejected. The implied
+// "terraform" provider is the built-in one and yields ErrBuiltInProvider.
+func WithDefaultNamespace(namespace string) ServerOption {
+	return func(s *Server) {
+		s.defaultNamespace = namespace
+	}
+}
+
+// resolveLegacyProviderAddress rewrites legacy namespaces via
+// legacyNamespaceAliases and rejects addresses of built-in providers.
+func resolveLegacyProviderAddress(namespace, name string) (string, string, error) {
+	lowerNs := strings.ToLower(namespace)
+	if lowerNs == builtInProviderNamespace || (lowerNs == "-" && strings.EqualFold(name, "terraform")) {
+		return "", "", fmt.Errorf("%w: %s/%s", ErrBuiltInProvider, namespace, name)
+	}
+	if to, ok := legacyNamespaceAliases[lowerNs]; ok {
+		namespace = to
+	}
+	return namespace, name, nil
+}
+
+// resolveProviderAlias maps namespace/name through any aliases configured
+// with WithProviderAliases and then through the legacy-namespace table, so
+// that inputs taken from older state or configuration files resolve to the
+// provider's current registry coordinates. Alias chains are followed to a
+// fixed point, which keeps resolution idempotent: resolving an already
+// resolved address is a no-op. An empty namespace is first replaced with
+// the one set by WithDefaultNamespace.
+func (s *Server) resolveProviderAlias(namespace, name string) (string, string, error) {
+	if namespace == "" && name != "" && s.defaultNamespace != "" {
+		if strings.EqualFold(name, "terraform") {
+			return "", "", fmt.Errorf("%w: %s", ErrBuiltInProvider, name)
+		}
+		namespace = s.defaultNamespace
+	}
+	from := namespace + "/" + name
+	for range len(s.providerAliases) + 1 {
+		to, ok := s.providerAliases[strings.ToLower(namespace+"/"+name)]
+		if !ok {
+			if from != namespace+"/"+name {
+				s.logger(logComponentRegistry).Debug("Resolved provider alias", "from", from, "to", namespace+"/"+name)
+			}
+			return resolveLegacyProviderAddress(namespace, name)
+		}
+		ns, n, found := strings.Cut(to, "/")
+		if !found || ns == "" || n == "" || strings.Contains(n, "/") {
+			return "", "", fmt.Errorf("invalid provider alias target %q for %s/%s: expected namespace/name", to, namespace, name)
+		}
+		if strings.EqualFold(ns+"/"+n, namespace+"/"+name) {
+			return resolveLegacyProviderAddress(ns, n)
+		}
+		namespace, name = ns, n
+	}
+	return "", "", fmt.Errorf("provider alias cycle detected while resolving %s", from)
+}
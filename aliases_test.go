@@ -0,0 +1,191 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProviderSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		want      Request
+		wantErr   string
+		wantErrIs error
+	}{
+		{name: "implied", source: "aws", want: Request{Namespace: "hashicorp", Name: "aws"}},
+		{name: "legacy dash namespace", source: "-/aws", want: Request{Namespace: "hashicorp", Name: "aws"}},
+		{name: "legacy terraform-providers namespace", source: "terraform-providers/azurerm", want: Request{Namespace: "hashicorp", Name: "azurerm"}},
+		{name: "namespace and name", source: "Azure/azapi", want: Request{Namespace: "Azure", Name: "azapi"}},
+		{name: "terraform registry host", source: "registry.terraform.io/hashicorp/aws", want: Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform}},
+		{name: "terraform registry legacy", source: "registry.terraform.io/-/aws", want: Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform}},
+		{name: "opentofu registry host", source: "registry.opentofu.org/hashicorp/aws", want: Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}},
+		{name: "builtin full address", source: "terraform.io/builtin/terraform", wantErrIs: ErrBuiltInProvider},
+		{name: "builtin legacy", source: "-/terraform", wantErrIs: ErrBuiltInProvider},
+		{name: "builtin implied", source: "terraform", wantErrIs: ErrBuiltInProvider},
+		{name: "private registry host", source: "App.Terraform.io/example-org/internal", want: Request{Namespace: "example-org", Name: "internal", RegistryType: RegistryTypeHCPTerraform}},
+		{name: "invalid host", source: "exa_mple.com:x/foo/bar", wantErr: "unsupported registry host"},
+		{name: "host without dot", source: "localhost/foo/bar", wantErr: "unsupported registry host"},
+		{name: "too many parts", source: "a/b/c/d", wantErr: "expected [hostname/]namespace/name"},
+		{name: "invalid characters", source: "hashicorp/a?ws", wantErr: "invalid provider source"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProviderSource(tt.source)
+			switch {
+			case tt.wantErrIs != nil:
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tt.wantErrIs), "got %v", err)
+			case tt.wantErr != "":
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    Request
+		wantErr bool
+	}{
+		{source: "hashicorp/aws", want: Request{Namespace: "hashicorp", Name: "aws"}},
+		{source: "hashicorp/aws@~>5.0", want: Request{Namespace: "hashicorp", Name: "aws", Version: "~>5.0"}},
+		{source: " aws @ 5.31.0 ", want: Request{Namespace: "hashicorp", Name: "aws", Version: "5.31.0"}},
+		{source: "registry.terraform.io/hashicorp/aws@>= 5.0, < 6.0", want: Request{Namespace: "hashicorp", Name: "aws", Version: ">= 5.0, < 6.0", RegistryType: RegistryTypeTerraform}},
+		{source: "hashicorp/aws@", wantErr: true},
+		{source: "hashicorp/aws@five", wantErr: true},
+		{source: "a/b/c/d@1.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := ParseRequest(tt.source)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMustRequest(t *testing.T) {
+	assert.Equal(t, Request{Namespace: "Azure", Name: "azapi", Version: "2.5.0"}, MustRequest("Azure/azapi@2.5.0"))
+	assert.Panics(t, func() { MustRequest("hashicorp/aws@") })
+}
+
+func TestServer_ResolveProviderAlias(t *testing.T) {
+	s := NewServer(nil, WithProviderAliases(map[string]string{
+		"MyCorp/AWS":   "hashicorp/aws",
+		"chain/a":      "chain/b",
+		"chain/b":      "-/c",
+		"loop/x":       "loop/y",
+		"loop/y":       "loop/x",
+		"broken/alias": "no-slash",
+	}))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	ns, name, err := s.resolveProviderAlias("mycorp", "aws")
+	require.NoError(t, err)
+	assert.Equal(t, "hashicorp/aws", ns+"/"+name)
+
+	ns, name, err = s.resolveProviderAlias("chain", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "hashicorp/c", ns+"/"+name, "alias chains and legacy namespaces should both be applied")
+
+	ns2, name2, err := s.resolveProviderAlias(ns, name)
+	require.NoError(t, err)
+	assert.Equal(t, ns+"/"+name, ns2+"/"+name2, "resolution must be idempotent")
+
+	_, _, err = s.resolveProviderAlias("loop", "x")
+	assert.ErrorContains(t, err, "cycle")
+
+	_, _, err = s.resolveProviderAlias("broken", "alias")
+	assert.ErrorContains(t, err, "invalid provider alias target")
+
+	_, _, err = s.resolveProviderAlias("builtin", "terraform")
+	assert.True(t, errors.Is(err, ErrBuiltInProvider))
+}
+
+func TestServer_Get_LegacyNamespaceUsesCurrentCoordinates(t *testing.T) {
+	cacheRoot := t.TempDir()
+	current := Request{Namespace: "hashicorp", Name: "aws", Version: "1.2.3"}
+	bin := writeFakeProviderBinary(t, cacheRoot, current)
+
+	s := NewServer(nil, WithCacheDir(cacheRoot))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	require.NoError(t, s.Get(Request{Namespace: "-", Name: "aws", Version: "1.2.3"}))
+	assert.Equal(t, bin, s.dlc[cacheKey(current)])
+}
+
+func TestServer_GetResourceSchema_AliasedRequestHitsCache(t *testing.T) {
+	s := NewServer(nil, WithProviderAliases(map[string]string{"old/name": "new/name"}))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	s.sc[cacheKey(Request{Namespace: "new", Name: "name", Version: "1.0.0"})] = newConvertedSchema(&tfjson.ProviderSchema{
+		ResourceSchemas: map[string]*tfjson.Schema{"r": {Block: &tfjson.SchemaBlock{}}},
+	})
+
+	got, err := s.GetResourceSchema(Request{Namespace: "old", Name: "name", Version: "1.0.0"}, "r")
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+}
+
+func TestServer_GetAvailableVersions_LegacyNamespace(t *testing.T) {
+	var gotPath string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"}]}`))
+	}))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "-", Name: "aws"})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/providers/hashicorp/aws/versions", gotPath)
+
+	_, err = s.GetAvailableVersions(VersionsRequest{Namespace: "builtin", Name: "terraform"})
+	assert.True(t, errors.Is(err, ErrBuiltInProvider))
+}
+
+func TestWithDefaultNamespace(t *testing.T) {
+	var gotPath string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"}]}`))
+	}))
+	s := NewServer(nil, WithHTTPClient(client), WithProviderAliases(map[string]string{"hashicorp/old": "hashicorp/new"}))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetAvailableVersions(VersionsRequest{Name: "aws"})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/providers/hashicorp/aws/versions", gotPath)
+
+	ns, name, err := s.resolveProviderAlias("", "old")
+	require.NoError(t, err)
+	assert.Equal(t, "hashicorp/new", ns+"/"+name, "aliases apply to the defaulted namespace")
+
+	_, _, err = s.resolveProviderAlias("", "terraform")
+	assert.ErrorIs(t, err, ErrBuiltInProvider)
+
+	s = NewServer(nil, WithHTTPClient(client), WithDefaultNamespace("mycorp"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	_, err = s.GetAvailableVersions(VersionsRequest{Name: "internal"})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/providers/mycorp/internal/versions", gotPath)
+
+	s = NewServer(nil, WithHTTPClient(client), WithDefaultNamespace(""))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	_, err = s.GetAvailableVersions(VersionsRequest{Name: "aws"})
+	assert.ErrorContains(t, err, "namespace", "the default can be turned off")
+}
@@ -0,0 +1,88 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// AuditEntry is one line of the audit log written with WithAuditLog,
+// recording a provider binary the Server is about to execute.
+type AuditEntry struct {
+	Time         time.Time    `json:"time"`
+	Path         string       `json:"path"`
+	SHA256       string       `json:"sha256"`
+	RegistryType RegistryType `json:"registry"`
+	Namespace    string       `json:"namespace"`
+	Name         string       `json:"name"`
+	Version      string       `json:"version"`
+	Tag          string       `json:"tag,omitempty"`
+}
+
+// WithAuditLog appends an AuditEntry as a JSON line to the file at path
+// before every provider binary the Server executes. The file is created
+// with mode 0600 if needed and only ever appended to. A binary is not
+// executed if its entry cannot be written. Each entry carries the tag set
+// with WithAuditTag.
+func WithAuditLog(path string) ServerOption {
+	return func(s *Server) {
+		s.auditLogPath = path
+	}
+}
+
+// WithAuditTag sets a caller-supplied tag, such as a CI job or pipeline
+// identifier, recorded in every audit log entry.
+func WithAuditTag(tag string) ServerOption {
+	return func(s *Server) {
+		s.auditTag = tag
+	}
+}
+
+// auditExecution appends the audit log entry for executing providerPath on
+// behalf of request. It is a no-op without WithAuditLog.
+func (s *Server) auditExecution(request Request, providerPath string) error {
+	if s.auditLogPath == "" {
+		return nil
+	}
+	digest, err := hashFile(providerPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash provider binary for audit log: %w", err)
+	}
+	return s.writeAuditEntry(request, providerPath, digest)
+}
+
+// writeAuditEntry appends the audit log entry for executing providerPath,
+// whose SHA-256 is digest, on behalf of request.
+func (s *Server) writeAuditEntry(request Request, providerPath, digest string) error {
+	line, err := json.Marshal(AuditEntry{
+		Time:         time.Now().UTC(),
+		Path:         providerPath,
+		SHA256:       digest,
+		RegistryType: request.RegistryType,
+		Namespace:    request.Namespace,
+		Name:         request.Name,
+		Version:      request.Version,
+		Tag:          s.auditTag,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode audit log entry: %w", err)
+	}
+
+	// Entries are written with a single append under auditMu, so that lines
+	// from concurrent executions never interleave.
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+	f, err := os.OpenFile(s.auditLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
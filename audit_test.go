@@ -0,0 +1,67 @@
+package tfpluginschema
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_AuditExecution(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, providerFileNamePrefix+"null")
+	require.NoError(t, os.WriteFile(binary, []byte("binary"), 0o755))
+	logPath := filepath.Join(dir, "audit.jsonl")
+
+	s := NewServer(nil, WithCacheDir(dir), WithAuditLog(logPath), WithAuditTag("ci-42"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}
+	require.NoError(t, s.auditExecution(req, binary))
+	require.NoError(t, s.auditExecution(req, binary))
+
+	f, err := os.Open(logPath)
+	require.NoError(t, err)
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 2)
+	assert.Equal(t, binary, entries[0].Path)
+	assert.Equal(t, sha256Hex([]byte("binary")), entries[0].SHA256)
+	assert.Equal(t, "ci-42", entries[0].Tag)
+	assert.Equal(t, RegistryTypeOpenTofu, entries[0].RegistryType)
+	assert.Equal(t, "1.0.0", entries[0].Version)
+	assert.False(t, entries[0].Time.IsZero())
+
+	info, err := os.Stat(logPath)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+}
+
+func TestServer_AuditExecution_Unwritable(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, providerFileNamePrefix+"null")
+	require.NoError(t, os.WriteFile(binary, []byte("binary"), 0o755))
+
+	s := NewServer(nil, WithCacheDir(dir), WithAuditLog(filepath.Join(dir, "missing", "audit.jsonl")))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	err := s.auditExecution(Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}, binary)
+	assert.ErrorContains(t, err, "failed to open audit log")
+}
+
+func TestServer_AuditExecution_Disabled(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	assert.NoError(t, s.auditExecution(Request{}, "does-not-exist"))
+}
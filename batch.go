@@ -0,0 +1,176 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchStatus is the outcome of one item of a batch operation.
+type BatchStatus int
+
+const (
+	// BatchSucceeded means the item was processed.
+	BatchSucceeded BatchStatus = iota
+	// BatchFailed means processing the item failed; see BatchItem.Err.
+	BatchFailed
+	// BatchSkipped means the item was not processed because an earlier
+	// item failed and the batch did not continue on error.
+	BatchSkipped
+)
+
+// String returns "succeeded", "failed" or "skipped".
+func (b BatchStatus) String() string {
+	switch b {
+	case BatchSucceeded:
+		return "succeeded"
+	case BatchFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// MarshalText encodes the status as its String form.
+func (b BatchStatus) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// BatchItem reports the outcome of one request of a batch operation.
+type BatchItem struct {
+	Request  Request       // Request as given
+	Version  string        // Version the request resolved to, if it got that far
+	Status   BatchStatus   // Outcome of the item
+	Err      error         // Why the item failed, for BatchFailed
+	Duration time.Duration // Time spent on the item
+	Bytes    int64         // Size of the provider binary downloaded for the item, or 0 if it was cached or not needed
+}
+
+// BatchResult reports the outcome of a batch operation, with one item per
+// request in the order given.
+type BatchResult struct {
+	Items []BatchItem
+}
+
+// Failed returns the items that failed.
+func (r *BatchResult) Failed() []BatchItem {
+	var failed []BatchItem
+	for _, item := range r.Items {
+		if item.Status == BatchFailed {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors of the failed items joined together, or nil if no
+// item failed.
+func (r *BatchResult) Err() error {
+	var errs []error
+	for _, item := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s/%s: %w", item.Request.Namespace, item.Request.Name, item.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// BatchOption configures a batch operation such as Warm.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	continueOnError bool
+	concurrency     int
+}
+
+// WithContinueOnError makes a batch operation process every request even
+// after one fails, instead of skipping the requests not yet started.
+func WithContinueOnError(enabled bool) BatchOption {
+	return func(o *batchOptions) {
+		o.continueOnError = enabled
+	}
+}
+
+// WithBatchConcurrency processes up to n requests of a batch operation at
+// the same time. The default is 1; values below 1 are ignored.
+func WithBatchConcurrency(n int) BatchOption {
+	return func(o *batchOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// Warm loads the schema of every request into the Server, downloading the
+// providers that are not cached, so that later calls for them are served
+// from memory. Requests that fail are reported in the result rather than
+// aborting the ones already in progress; unless WithContinueOnError is set,
+// the requests not yet started are then skipped.
+//
+// The result always has one item per request. The error is the result's
+// Err, set when any request failed.
+func (s *Server) Warm(requests []Request, opts ...BatchOption) (*BatchResult, error) {
+	o := batchOptions{concurrency: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	result := &BatchResult{Items: make([]BatchItem, len(requests))}
+	var (
+		stop atomic.Bool
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for range min(o.concurrency, len(requests)) {
+		wg.Go(func() {
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(requests) {
+					return
+				}
+				item := &result.Items[i]
+				item.Request = requests[i]
+				if stop.Load() {
+					item.Status = BatchSkipped
+					continue
+				}
+				s.warmOne(item)
+				if item.Status == BatchFailed && !o.continueOnError {
+					stop.Store(true)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return result, result.Err()
+}
+
+// warmOne loads the schema of item.Request and records the outcome in item.
+func (s *Server) warmOne(item *BatchItem) {
+	start := time.Now()
+	defer func() { item.Duration = time.Since(start) }()
+	defer s.flushLogSummary(item.Request)
+
+	request, err := s.prepareRequest(item.Request)
+	if err != nil {
+		item.Status, item.Err = BatchFailed, err
+		return
+	}
+	item.Version = request.Version
+
+	extractDir := cacheProviderDir(s.cacheDir, request)
+	_, cached := findProviderBinary(extractDir, request.Name)
+	if _, err := s.getSchema(request); err != nil {
+		item.Status, item.Err = BatchFailed, fmt.Errorf("failed to read provider schema: %w", err)
+		return
+	}
+	if !cached || s.forceFetch {
+		s.mu.RLock()
+		path, downloaded := s.dlc[cacheKey(request)]
+		s.mu.RUnlock()
+		if downloaded {
+			item.Bytes = fileSize(path)
+		}
+	}
+	item.Status = BatchSucceeded
+}
@@ -0,0 +1,78 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Warm(t *testing.T) {
+	bundle := fstest.MapFS{
+		"opentofu/example/one/1.0.0.json":   {Data: []byte(describedSchema)},
+		"opentofu/example/three/2.0.0.json": {Data: []byte(describedSchema)},
+	}
+	requests := []Request{
+		{Namespace: "example", Name: "one", Version: "1.0.0"},
+		{Namespace: "example", Name: "two", Version: "1.0.0"},
+		{Namespace: "example", Name: "three", Version: "2.0.0"},
+	}
+	newTestServer := func(t *testing.T) *Server {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+		t.Cleanup(func() { _ = s.Cleanup() })
+		return s
+	}
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		s := newTestServer(t)
+		result, err := s.Warm(requests)
+		require.ErrorIs(t, err, ErrSchemaNotBundled)
+		assert.ErrorContains(t, err, "example/two: ")
+		require.Len(t, result.Items, 3)
+
+		assert.Equal(t, BatchSucceeded, result.Items[0].Status)
+		assert.Equal(t, "1.0.0", result.Items[0].Version)
+		assert.Positive(t, result.Items[0].Duration)
+		assert.Zero(t, result.Items[0].Bytes, "bundled schemas download nothing")
+		assert.Equal(t, BatchFailed, result.Items[1].Status)
+		assert.ErrorIs(t, result.Items[1].Err, ErrSchemaNotBundled)
+		assert.Equal(t, BatchSkipped, result.Items[2].Status)
+		assert.Equal(t, requests[2], result.Items[2].Request)
+		assert.Len(t, result.Failed(), 1)
+	})
+
+	t.Run("continues on error", func(t *testing.T) {
+		s := newTestServer(t)
+		result, err := s.Warm(requests, WithContinueOnError(true), WithBatchConcurrency(2))
+		require.ErrorIs(t, err, ErrSchemaNotBundled)
+		assert.Equal(t, BatchSucceeded, result.Items[0].Status)
+		assert.Equal(t, BatchFailed, result.Items[1].Status)
+		assert.Equal(t, BatchSucceeded, result.Items[2].Status)
+
+		// Warmed schemas are served from memory.
+		stats, err := s.CacheStats()
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.CachedSchemas)
+	})
+
+	t.Run("all succeed", func(t *testing.T) {
+		s := newTestServer(t)
+		result, err := s.Warm([]Request{requests[0], requests[2]})
+		require.NoError(t, err)
+		assert.Empty(t, result.Failed())
+		assert.NoError(t, result.Err())
+	})
+
+	t.Run("no requests", func(t *testing.T) {
+		result, err := newTestServer(t).Warm(nil)
+		require.NoError(t, err)
+		assert.Empty(t, result.Items)
+	})
+}
+
+func TestBatchStatus_MarshalText(t *testing.T) {
+	b, err := BatchSkipped.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "skipped", string(b))
+}
@@ -1,12 +1,16 @@
 package tfpluginschema
 
 import (
+	"fmt"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
 )
 
 // EnvCacheDir is the environment variable used to override the provider cache
@@ -80,6 +84,32 @@ func WithHTTPClient(c *http.Client) ServerOption {
 	}
 }
 
+// WithTimeout bounds the duration of every HTTP request the Server makes,
+// including reading the response body, so it must allow for the largest
+// provider archive expected. It applies to the client set with
+// WithHTTPClient without modifying it. A value <= 0 is ignored.
+func WithTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		if d > 0 {
+			s.httpTimeout = d
+		}
+	}
+}
+
+// WithMaxRedirects limits the number of redirects followed by any HTTP
+// request, such as the registry → GitHub → signed storage URL chain of a
+// provider download. Exceeding the limit fails the request with
+// ErrTooManyRedirects. It applies to the client set with WithHTTPClient
+// without modifying it, after any redirect policy of that client. A value
+// <= 0 keeps the client's policy, which for the default client allows 10.
+func WithMaxRedirects(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxRedirects = n
+		}
+	}
+}
+
 // WithCacheStatusFunc installs a callback invoked after the Server resolves a
 // provider to indicate whether the cache was hit or the provider was
 // downloaded. Useful for CLIs wishing to report download/cache activity.
@@ -117,9 +147,12 @@ func cachePathSegment(value string) string {
 
 // normalizedRegistryType returns the RegistryType to use for cache path
 // construction, treating empty/unknown values the same way BaseURL does —
-// as RegistryTypeOpenTofu. This keeps the cache layout consistent with the
-// actual registry that will be queried.
+// as RegistryTypeOpenTofu. Registry hosts are lower-cased. This keeps the
+// cache layout consistent with the actual registry that will be queried.
 func normalizedRegistryType(r RegistryType) RegistryType {
+	if host, ok := r.customHost(); ok {
+		return RegistryType(host)
+	}
 	switch r {
 	case RegistryTypeTerraform:
 		return RegistryTypeTerraform
@@ -128,13 +161,79 @@ func normalizedRegistryType(r RegistryType) RegistryType {
 	}
 }
 
+// providerKey is the canonical form of a request used to key the Server's
+// in-memory caches. It is kept distinct from the user-facing Request so that
+// requests differing only cosmetically share entries: registries treat
+// namespace and name case-insensitively ("Azure/azapi" and "azure/azapi" are
+// the same provider), an empty or unknown RegistryType resolves to the same
+// registry as RegistryTypeOpenTofu, and "v1.2.0", "1.2" and "1.2.0" are the
+// same version. Callers keep using the original request for registry URLs
+// and cache-status callbacks so that display casing is preserved.
+type providerKey struct {
+	host      string // registry host, e.g. "registry.opentofu.org"
+	namespace string // lower-cased
+	name      string // lower-cased
+	version   string // canonical concrete version; empty for a versions list
+	platform  string // "<os>_<arch>"; empty for a versions list
+}
+
+// String returns k as a single string, used to key in-flight operations.
+func (k providerKey) String() string {
+	return strings.Join([]string{k.host, k.namespace, k.name, k.version, k.platform}, "/")
+}
+
+// cacheKey returns the key of request in the download and schema caches.
+// request.Version must already be a concrete version.
+func cacheKey(request Request) providerKey {
+	return providerKey{
+		host:      normalizedRegistryType(request.RegistryType).host(),
+		namespace: strings.ToLower(request.Namespace),
+		name:      strings.ToLower(request.Name),
+		version:   canonicalVersion(request.Version),
+		platform:  runtime.GOOS + "_" + runtime.GOARCH,
+	}
+}
+
+// canonicalVersion returns version as go-version prints it, so that "1.0"
+// and "v1.0.0" both become "1.0.0". A version that does not parse, such as
+// a constraint, is returned as is.
+func canonicalVersion(version string) string {
+	if v, err := goversion.NewVersion(version); err == nil {
+		return v.String()
+	}
+	return version
+}
+
+// versionsCacheKey returns the key of request in the versions cache.
+func versionsCacheKey(request VersionsRequest) providerKey {
+	return providerKey{
+		host:      normalizedRegistryType(request.RegistryType).host(),
+		namespace: strings.ToLower(request.Namespace),
+		name:      strings.ToLower(request.Name),
+	}
+}
+
+// normalizedRequest returns request with the namespace and name lower-cased
+// and RegistryType normalized the same way BaseURL resolves it. It is the
+// form used to name on-disk cache entries and temporary files, so that
+// requests differing only in case share them.
+func normalizedRequest(request Request) Request {
+	request.Namespace = strings.ToLower(request.Namespace)
+	request.Name = strings.ToLower(request.Name)
+	request.RegistryType = normalizedRegistryType(request.RegistryType)
+	return request
+}
+
 // cacheProviderDir returns the predictable cache directory for a given
 // provider request. The layout is:
 //
 //	<cacheDir>/<registry-type>/<namespace>/terraform-provider-<name>/<version>/<os>_<arch>
 //
+// Namespace and name are lower-cased (see normalizedRequest) so requests
+// differing only in case share a single cache entry.
 // The request version must be a concrete version (not a constraint).
 func cacheProviderDir(cacheDir string, request Request) string {
+	request = normalizedRequest(request)
 	return filepath.Join(
 		cacheDir,
 		cachePathSegment(string(normalizedRegistryType(request.RegistryType))),
@@ -145,11 +244,54 @@ func cacheProviderDir(cacheDir string, request Request) string {
 	)
 }
 
+// legacyCacheProviderDir returns the cache directory of request in the
+// layout used before namespace and name were lower-cased, which kept the
+// casing of the request. It is cacheProviderDir for a lower-case request.
+func legacyCacheProviderDir(cacheDir string, request Request) string {
+	return filepath.Join(
+		cacheDir,
+		cachePathSegment(string(normalizedRegistryType(request.RegistryType))),
+		cachePathSegment(request.Namespace),
+		providerFileNamePrefix+cachePathSegment(request.Name),
+		cachePathSegment(request.Version),
+		runtime.GOOS+"_"+runtime.GOARCH,
+	)
+}
+
+// migrateLegacyCacheEntry renames the cache entry of request from its
+// legacy mixed-case directory (see legacyCacheProviderDir) to
+// cacheProviderDir, so that entries extracted by earlier versions are used
+// rather than downloaded again. It reports whether an entry was moved, and
+// does nothing when the current directory already exists or there is no
+// legacy entry.
+func migrateLegacyCacheEntry(cacheDir string, request Request) (bool, error) {
+	dir, legacy := cacheProviderDir(cacheDir, request), legacyCacheProviderDir(cacheDir, request)
+	if legacy == dir {
+		return false, nil
+	}
+	if _, err := os.Lstat(dir); err == nil {
+		return false, nil
+	}
+	if _, ok := findProviderBinary(legacy, request.Name); !ok {
+		return false, nil
+	}
+	if err := ensureWithinBaseDir(cacheDir, legacy); err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return false, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.Rename(legacy, dir); err != nil {
+		return false, fmt.Errorf("failed to move legacy cache entry: %w", err)
+	}
+	return true, nil
+}
+
 // findProviderBinary walks dir looking for a file whose name starts with
-// "terraform-provider-<name>". It returns the absolute path to the first
+// "terraform-provider-<name>", compared case-insensitively. It returns the absolute path to the first
 // match, or ("", false) if none is found or the directory does not exist.
 func findProviderBinary(dir, providerName string) (string, bool) {
-	wantPrefix := providerFileNamePrefix + providerName
+	wantPrefix := strings.ToLower(providerFileNamePrefix + providerName)
 	if _, err := os.Stat(dir); err != nil {
 		return "", false
 	}
@@ -161,7 +303,7 @@ func findProviderBinary(dir, providerName string) (string, bool) {
 		if d.IsDir() {
 			return nil
 		}
-		if strings.HasPrefix(d.Name(), wantPrefix) {
+		if strings.HasPrefix(strings.ToLower(d.Name()), wantPrefix) {
 			found = filepath.Join(dir, path)
 			return fs.SkipAll
 		}
@@ -170,5 +312,9 @@ func findProviderBinary(dir, providerName string) (string, bool) {
 	if found == "" {
 		return "", false
 	}
+	// A binary linked from the content store may have been pruned.
+	if _, err := os.Stat(found); err != nil {
+		return "", false
+	}
 	return found, true
 }
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -108,6 +109,20 @@ func TestCacheProviderDir_IsolatesNamespacesAndRegistries(t *testing.T) {
 		"different registry types must map to different cache dirs")
 }
 
+func TestCacheKey_FoldsNamespaceAndNameCase(t *testing.T) {
+	a := Request{Namespace: "Azure", Name: "AzAPI", Version: "2.5.0"}
+	b := Request{Namespace: "azure", Name: "azapi", Version: "2.5.0", RegistryType: RegistryTypeOpenTofu}
+	assert.Equal(t, cacheKey(a), cacheKey(b))
+	assert.Equal(t, cacheProviderDir("/tmp/root", a), cacheProviderDir("/tmp/root", b))
+
+	// Version is not case-folded; it is not part of the case-insensitive identity.
+	assert.Equal(t, "2.5.0", cacheKey(a).version)
+	assert.Equal(t,
+		versionsCacheKey(VersionsRequest{Namespace: "Azure", Name: "azapi"}),
+		versionsCacheKey(VersionsRequest{Namespace: "azure", Name: "AZAPI", RegistryType: RegistryTypeOpenTofu}),
+	)
+}
+
 func TestFindProviderBinary_FindsFile(t *testing.T) {
 	dir := t.TempDir()
 	req := Request{Name: "aws", Version: "1.0.0"}
@@ -150,15 +165,146 @@ func TestServer_Get_CacheHit(t *testing.T) {
 			gotReq = r
 		}),
 	)
-	t.Cleanup(s.Cleanup)
+	t.Cleanup(func() { _ = s.Cleanup() })
 
 	require.NoError(t, s.Get(req))
-	assert.Equal(t, bin, s.dlc[req], "cache hit should populate download cache with cached path")
+	assert.Equal(t, bin, s.dlc[cacheKey(req)], "cache hit should populate download cache with cached path")
 	assert.Equal(t, 1, called)
 	assert.Equal(t, CacheStatusHit, gotStatus)
 	assert.Equal(t, req, gotReq)
 }
 
+func TestCacheKey_Canonical(t *testing.T) {
+	a := Request{Namespace: "hashicorp", Name: "aws", Version: "v5.0"}
+	b := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: "unknown"}
+	assert.Equal(t, cacheKey(a), cacheKey(b))
+	assert.Equal(t, providerKey{
+		host:      "registry.opentofu.org",
+		namespace: "hashicorp",
+		name:      "aws",
+		version:   "5.0.0",
+		platform:  runtime.GOOS + "_" + runtime.GOARCH,
+	}, cacheKey(a))
+
+	tf := cacheKey(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeTerraform})
+	assert.Equal(t, "registry.terraform.io", tf.host)
+	assert.NotEqual(t, cacheKey(b), tf)
+}
+
+func TestServer_Get_MixedCaseRequestsShareCache(t *testing.T) {
+	cacheRoot := t.TempDir()
+	lower := Request{Namespace: "azure", Name: "azapi", Version: "2.5.0"}
+	bin := writeFakeProviderBinary(t, cacheRoot, lower)
+
+	var reported []Request
+	s := NewServer(nil,
+		WithCacheDir(cacheRoot),
+		WithCacheStatusFunc(func(r Request, _ CacheStatus) {
+			reported = append(reported, r)
+		}),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	mixed := Request{Namespace: "Azure", Name: "AzAPI", Version: "2.5.0"}
+	require.NoError(t, s.Get(mixed))
+	require.NoError(t, s.Get(lower))
+
+	assert.Len(t, s.dlc, 1, "differently-cased requests must share one download cache entry")
+	assert.Equal(t, bin, s.dlc[cacheKey(mixed)])
+	require.Len(t, reported, 1, "second request should be served from the in-memory cache")
+	assert.Equal(t, "Azure", reported[0].Namespace, "callback should see the caller's casing")
+	assert.Equal(t, "AzAPI", reported[0].Name, "callback should see the caller's casing")
+}
+
+func TestServer_Get_CanonicalVersionSharesCache(t *testing.T) {
+	cacheRoot := t.TempDir()
+	canonical := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	bin := writeFakeProviderBinary(t, cacheRoot, canonical)
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(bin), hashesFileName), []byte("h1:recorded\n"), 0o644))
+
+	s := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(failingRegistryClient(t)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	short := Request{Namespace: "hashicorp", Name: "aws", Version: "v5.0", Hashes: []string{"h1:recorded"}}
+	plan, err := s.Plan(short)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusHit, plan.CacheStatus)
+	assert.Equal(t, "5.0.0", plan.Request.Version)
+	require.NoError(t, s.Get(short), "the on-disk entry and its recorded hashes are found under the canonical version")
+	assert.Equal(t, bin, s.dlc[cacheKey(canonical)])
+
+	short.Hashes = []string{"h1:other"}
+	assert.ErrorIs(t, s.Get(short), ErrHashMismatch)
+}
+
+func TestServer_Get_MovesLegacyMixedCaseEntry(t *testing.T) {
+	cacheRoot := t.TempDir()
+	mixed := Request{Namespace: "Azure", Name: "AzAPI", Version: "2.5.0", RegistryType: RegistryTypeOpenTofu}
+	legacy := legacyCacheProviderDir(cacheRoot, mixed)
+	require.NoError(t, os.MkdirAll(legacy, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(legacy, "terraform-provider-AzAPI_v2.5.0"), []byte("fake"), 0o644))
+	if _, err := os.Stat(cacheProviderDir(cacheRoot, mixed)); err == nil {
+		t.Skip("the file system is case-insensitive")
+	}
+
+	s := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(failingRegistryClient(t)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	plan, err := s.Plan(mixed)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusHit, plan.CacheStatus)
+	assert.DirExists(t, legacy, "Plan leaves the cache alone")
+
+	require.NoError(t, s.Get(mixed))
+	assert.NoDirExists(t, legacy)
+	bin, ok := findProviderBinary(cacheProviderDir(cacheRoot, mixed), mixed.Name)
+	require.True(t, ok)
+	assert.Equal(t, bin, s.dlc[cacheKey(mixed)])
+
+	lower := Request{Namespace: "azure", Name: "azapi", Version: "2.5.0", RegistryType: RegistryTypeOpenTofu}
+	assert.Equal(t, cacheProviderDir(cacheRoot, lower), legacyCacheProviderDir(cacheRoot, lower))
+}
+
+func TestServer_GetResourceSchema_MixedCaseHitsSchemaCache(t *testing.T) {
+	s := NewServer(nil)
+	t.Cleanup(func() { _ = s.Cleanup() })
+	s.sc[cacheKey(Request{Namespace: "azure", Name: "azapi", Version: "2.5.0"})] = newConvertedSchema(&tfjson.ProviderSchema{
+		ResourceSchemas: map[string]*tfjson.Schema{
+			"azapi_resource": {Block: &tfjson.SchemaBlock{}},
+		},
+	})
+
+	got, err := s.GetResourceSchema(Request{Namespace: "Azure", Name: "AzAPI", Version: "2.5.0"}, "azapi_resource")
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+}
+
+func TestServer_GetAvailableVersions_MixedCaseSharesCache(t *testing.T) {
+	var calls int
+	var gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"},{"version":"2.0.0"}]}`))
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	s := NewServer(nil, WithHTTPClient(&http.Client{
+		Transport: &rewriteHostTransport{host: tsURL.Host, scheme: tsURL.Scheme, wrapped: http.DefaultTransport},
+	}))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err = s.GetAvailableVersions(VersionsRequest{Namespace: "Azure", Name: "azapi"})
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/providers/Azure/azapi/versions", gotPath, "registry URL should preserve the caller's casing")
+
+	vers, err := s.GetAvailableVersions(VersionsRequest{Namespace: "azure", Name: "AZAPI"})
+	require.NoError(t, err)
+	assert.Len(t, vers, 2)
+	assert.Equal(t, 1, calls, "differently-cased requests must share one versions cache entry")
+}
+
 func TestServer_Get_CacheHitSkippedByForceFetch(t *testing.T) {
 	cacheRoot := t.TempDir()
 	req := Request{
@@ -199,7 +345,7 @@ func TestServer_Get_CacheHitSkippedByForceFetch(t *testing.T) {
 			gotStatus = st
 		}),
 	)
-	t.Cleanup(s.Cleanup)
+	t.Cleanup(func() { _ = s.Cleanup() })
 
 	err = s.Get(req)
 	assert.Error(t, err, "force-fetch should attempt a download; stubbed 404 must surface as an error")
@@ -218,7 +364,7 @@ func TestServer_CacheStatusFunc_PanicIsContained(t *testing.T) {
 			panic("boom")
 		}),
 	)
-	t.Cleanup(s.Cleanup)
+	t.Cleanup(func() { _ = s.Cleanup() })
 
 	// Panic in the callback must not propagate from Get.
 	assert.NotPanics(t, func() {
@@ -255,3 +401,17 @@ func (t *rewriteHostTransport) RoundTrip(req *http.Request) (*http.Response, err
 	clone.Host = ""
 	return t.wrapped.RoundTrip(clone)
 }
+
+// stubRegistryClient starts an httptest server running h and returns an HTTP
+// client whose requests are all routed to it via rewriteHostTransport.
+func stubRegistryClient(t testing.TB, h http.Handler) *http.Client {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	tsURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &rewriteHostTransport{host: tsURL.Host, scheme: tsURL.Scheme, wrapped: http.DefaultTransport},
+	}
+}
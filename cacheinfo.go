@@ -0,0 +1,189 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cacheAccessInterval limits how often a cache hit refreshes an entry's
+// modification time, which CacheEntries reports as LastAccess.
+const cacheAccessInterval = time.Hour
+
+// CacheEntry describes one provider held in the Server's on-disk cache.
+type CacheEntry struct {
+	// Request identifies the provider. Namespace and Name are lower-cased,
+	// as in the cache layout.
+	Request Request
+	// Platform is the "<os>_<arch>" the provider was built for.
+	Platform string
+	// Path is the directory holding the extracted provider.
+	Path string
+	// Size is the total size in bytes of the files under Path.
+	Size int64
+	// LastAccess is when a Server last extracted or loaded the provider
+	// from the cache, to within an hour.
+	LastAccess time.Time
+	// Loaded reports whether this Server has the provider in its in-memory
+	// download cache.
+	Loaded bool
+	// SchemaCached reports whether this Server holds the provider's schema
+	// in memory.
+	SchemaCached bool
+}
+
+// CacheStats summarises the Server's on-disk and in-memory caches.
+type CacheStats struct {
+	// Entries and SizeOnDisk cover every provider in the on-disk cache.
+	Entries    int
+	SizeOnDisk int64
+	// LoadedProviders, CachedSchemas and CachedVersionLists count the
+	// Server's in-memory cache entries.
+	LoadedProviders    int
+	CachedSchemas      int
+	CachedVersionLists int
+	// Hits and Misses count the cache statuses reported by this Server
+	// (see CacheStatusFunc) since it was created.
+	Hits   uint64
+	Misses uint64
+}
+
+// CacheEntries lists the providers in the Server's on-disk cache, sorted by
+// registry, namespace, name, version and platform. A cache directory that
+// does not exist yet yields no entries.
+func (s *Server) CacheEntries() ([]CacheEntry, error) {
+	var entries []CacheEntry
+	for _, registry := range []RegistryType{RegistryTypeOpenTofu, RegistryTypeTerraform} {
+		root := filepath.Join(s.cacheDir, string(registry))
+		// <registry>/<namespace>/terraform-provider-<name>/<version>/<os>_<arch>
+		matches, err := filepath.Glob(filepath.Join(root, "*", providerFileNamePrefix+"*", "*", "*"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cache directory %s: %w", root, err)
+		}
+		for _, dir := range matches {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				continue
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			platform := parts[3]
+			// Skip staging directories left by interrupted extractions.
+			if strings.Contains(platform, ".") {
+				continue
+			}
+			info, err := os.Stat(dir)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			size, err := dirSize(dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure cache entry %s: %w", dir, err)
+			}
+			entries = append(entries, CacheEntry{
+				Request: Request{
+					Namespace:    parts[0],
+					Name:         strings.TrimPrefix(parts[1], providerFileNamePrefix),
+					Version:      parts[2],
+					RegistryType: registry,
+				},
+				Platform:   platform,
+				Path:       dir,
+				Size:       size,
+				LastAccess: info.ModTime(),
+			})
+		}
+	}
+
+	s.mu.RLock()
+	for i := range entries {
+		e := &entries[i]
+		if e.Platform != runtime.GOOS+"_"+runtime.GOARCH {
+			continue
+		}
+		_, e.Loaded = s.dlc[cacheKey(e.Request)]
+		_, e.SchemaCached = s.sc[cacheKey(e.Request)]
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b CacheEntry) int {
+		return strings.Compare(
+			strings.Join([]string{string(a.Request.RegistryType), a.Request.Namespace, a.Request.Name, a.Request.Version, a.Platform}, "\x00"),
+			strings.Join([]string{string(b.Request.RegistryType), b.Request.Namespace, b.Request.Name, b.Request.Version, b.Platform}, "\x00"),
+		)
+	})
+	return entries, nil
+}
+
+// CacheStats reports the size of the Server's caches and its hit and miss
+// counts.
+func (s *Server) CacheStats() (CacheStats, error) {
+	entries, err := s.CacheEntries()
+	if err != nil {
+		return CacheStats{}, err
+	}
+	stats := CacheStats{
+		Entries: len(entries),
+		Hits:    s.cacheHits.Load(),
+		Misses:  s.cacheMisses.Load(),
+	}
+	for _, e := range entries {
+		stats.SizeOnDisk += e.Size
+	}
+	s.mu.RLock()
+	stats.LoadedProviders = len(s.dlc)
+	stats.CachedSchemas = len(s.sc)
+	stats.CachedVersionLists = len(s.versionsc)
+	s.mu.RUnlock()
+	return stats, nil
+}
+
+// recordCacheStatus counts a cache hit or miss for CacheStats.
+func (s *Server) recordCacheStatus(status CacheStatus) {
+	switch status {
+	case CacheStatusHit:
+		s.cacheHits.Add(1)
+	case CacheStatusMiss:
+		s.cacheMisses.Add(1)
+	}
+}
+
+// touchCacheEntry refreshes dir's modification time, which CacheEntries
+// reports as LastAccess, if it is older than cacheAccessInterval. Failures
+// are ignored: the cache may be read-only.
+func touchCacheEntry(dir string) {
+	info, err := os.Stat(dir)
+	if err != nil || time.Since(info.ModTime()) < cacheAccessInterval {
+		return
+	}
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
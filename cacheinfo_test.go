@@ -0,0 +1,100 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_CacheEntries(t *testing.T) {
+	cacheRoot := t.TempDir()
+	aws := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeOpenTofu}
+	azapi := Request{Namespace: "Azure", Name: "azapi", Version: "2.5.0", RegistryType: RegistryTypeTerraform}
+	writeFakeProviderBinary(t, cacheRoot, aws)
+	writeFakeProviderBinary(t, cacheRoot, azapi)
+
+	// Another platform's build and an interrupted extraction.
+	other := filepath.Join(filepath.Dir(cacheProviderDir(cacheRoot, aws)), "plan9_386")
+	require.NoError(t, os.MkdirAll(other, 0o755))
+	require.NoError(t, os.MkdirAll(cacheProviderDir(cacheRoot, Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"})+".partial", 0o755))
+
+	s := NewServer(nil, WithCacheDir(cacheRoot))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(aws))
+	s.sc[cacheKey(aws)] = newConvertedSchema(&tfjson.ProviderSchema{})
+
+	entries, err := s.CacheEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	byPlatform := map[string]CacheEntry{entries[0].Platform: entries[0], entries[1].Platform: entries[1]}
+	current := byPlatform[runtime.GOOS+"_"+runtime.GOARCH]
+	assert.Equal(t, aws, current.Request)
+	assert.Equal(t, cacheProviderDir(cacheRoot, aws), current.Path)
+	assert.Equal(t, int64(len("fake")), current.Size)
+	assert.True(t, current.Loaded)
+	assert.True(t, current.SchemaCached)
+	assert.WithinDuration(t, time.Now(), current.LastAccess, time.Minute)
+
+	foreign := byPlatform["plan9_386"]
+	assert.Equal(t, aws, foreign.Request)
+	assert.False(t, foreign.Loaded, "in-memory state only applies to this platform")
+
+	assert.Equal(t, normalizedRequest(azapi), entries[2].Request, "names are reported lower-cased")
+	assert.False(t, entries[2].Loaded)
+	assert.False(t, entries[2].SchemaCached)
+}
+
+func TestServer_CacheEntries_MissingCacheDir(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(filepath.Join(t.TempDir(), "missing")))
+	entries, err := s.CacheEntries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestServer_CacheStats(t *testing.T) {
+	cacheRoot := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeOpenTofu}
+	writeFakeProviderBinary(t, cacheRoot, req)
+
+	s := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(stubRegistryClient(t, http.NotFoundHandler())))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+	require.NoError(t, s.Get(req), "an in-memory hit is not counted again")
+	require.Error(t, s.Get(Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}))
+
+	stats, err := s.CacheStats()
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{
+		Entries:         1,
+		SizeOnDisk:      int64(len("fake")),
+		LoadedProviders: 1,
+		Hits:            1,
+		Misses:          1,
+	}, stats)
+}
+
+func TestTouchCacheEntry(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * cacheAccessInterval)
+	require.NoError(t, os.Chtimes(dir, old, old))
+
+	touchCacheEntry(dir)
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), info.ModTime(), time.Minute)
+
+	recent := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(dir, recent, recent))
+	touchCacheEntry(dir)
+	info, err = os.Stat(dir)
+	require.NoError(t, err)
+	assert.WithinDuration(t, recent, info.ModTime(), time.Second, "recent entries are not rewritten")
+}
@@ -0,0 +1,115 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// MarshalCanonical encodes a copy of ps, normalized as by Normalize, as
+// indented JSON for committing to version control: object keys are sorted,
+// entries are indented by two spaces, characters such as < and & are not
+// escaped, and the document ends with a newline. The same schema always
+// gives the same bytes, and a change to one attribute changes only the
+// lines of that attribute, so diffs between runs are meaningful. ps is not
+// modified.
+func MarshalCanonical(ps *tfjson.ProviderSchema) ([]byte, error) {
+	if ps != nil {
+		ps = cloneProviderSchema(ps)
+		Normalize(ps)
+	}
+	return marshalCanonical(ps)
+}
+
+// marshalCanonicalSchema encodes a normalized copy of one resource, data
+// source or provider configuration schema as MarshalCanonical does.
+func marshalCanonicalSchema(s *tfjson.Schema) ([]byte, error) {
+	s = CloneSchema(s)
+	normalizeSchema(s)
+	return marshalCanonical(s)
+}
+
+// marshalCanonicalFunction encodes a normalized copy of a function
+// signature as MarshalCanonical does.
+func marshalCanonicalFunction(f *tfjson.FunctionSignature) ([]byte, error) {
+	if f = CloneFunctionSignature(f); f != nil {
+		normalizeFunction(f)
+	}
+	return marshalCanonical(f)
+}
+
+// marshalCanonical encodes v as indented JSON without HTML escapes.
+func marshalCanonical(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return unescapeHTML(buf.Bytes()), nil
+}
+
+// unescapeHTML replaces the \u003c, \u003e and \u0026 escapes in the
+// strings of a JSON document with the characters they stand for. The
+// tfjson types marshal themselves with json.Marshal, which escapes them
+// regardless of the Encoder's SetEscapeHTML.
+func unescapeHTML(data []byte) []byte {
+	out := data[:0]
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\\' || i+1 >= len(data) {
+			out = append(out, data[i])
+			continue
+		}
+		if i+6 <= len(data) {
+			switch string(data[i : i+6]) {
+			case `\u003c`:
+				out, i = append(out, '<'), i+5
+				continue
+			case `\u003e`:
+				out, i = append(out, '>'), i+5
+				continue
+			case `\u0026`:
+				out, i = append(out, '&'), i+5
+				continue
+			}
+		}
+		// Keep any other escape, including an escaped backslash, whole.
+		out = append(out, data[i], data[i+1])
+		i++
+	}
+	return out
+}
+
+// cloneProviderSchema returns a copy of ps deep enough for Normalize to
+// modify. Resource identity schemas, which Normalize leaves alone, are
+// shared.
+func cloneProviderSchema(ps *tfjson.ProviderSchema) *tfjson.ProviderSchema {
+	c := *ps
+	c.ConfigSchema = CloneSchema(ps.ConfigSchema)
+	c.ResourceSchemas = cloneSchemaMap(ps.ResourceSchemas)
+	c.DataSourceSchemas = cloneSchemaMap(ps.DataSourceSchemas)
+	c.EphemeralResourceSchemas = cloneSchemaMap(ps.EphemeralResourceSchemas)
+	c.ListResourceSchemas = cloneSchemaMap(ps.ListResourceSchemas)
+	c.ResourceIdentitySchemas = maps.Clone(ps.ResourceIdentitySchemas)
+	if ps.Functions != nil {
+		c.Functions = make(map[string]*tfjson.FunctionSignature, len(ps.Functions))
+		for name, f := range ps.Functions {
+			c.Functions[name] = CloneFunctionSignature(f)
+		}
+	}
+	return &c
+}
+
+func cloneSchemaMap(m map[string]*tfjson.Schema) map[string]*tfjson.Schema {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]*tfjson.Schema, len(m))
+	for name, s := range m {
+		c[name] = CloneSchema(s)
+	}
+	return c
+}
@@ -0,0 +1,138 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func canonicalTestSchema() *tfjson.ProviderSchema {
+	return &tfjson.ProviderSchema{
+		ConfigSchema: &tfjson.Schema{Block: &tfjson.SchemaBlock{}},
+		ResourceSchemas: map[string]*tfjson.Schema{
+			"example_b": {Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{
+				"z": {AttributeType: cty.String, Optional: true, Description: "Use <b> & <i>.  \r\n"},
+				"a": {AttributeType: cty.Object(map[string]cty.Type{"y": cty.Number, "x": cty.Bool}), Computed: true},
+			}}},
+			"example_a": {Block: &tfjson.SchemaBlock{NestedBlocks: map[string]*tfjson.SchemaBlockType{}}},
+			"example_c": nil,
+		},
+		Functions: map[string]*tfjson.FunctionSignature{
+			"f": {ReturnType: cty.String, Summary: " trimmed "},
+		},
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	ps := canonicalTestSchema()
+	data, err := MarshalCanonical(ps)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{
+  "provider": {
+    "version": 0,
+    "block": {}
+  },
+  "resource_schemas": {
+    "example_a": {
+      "version": 0,
+      "block": {}
+    },
+    "example_b": {
+      "version": 0,
+      "block": {
+        "attributes": {
+          "a": {
+            "type": [
+              "object",
+              {
+                "x": "bool",
+                "y": "number"
+              }
+            ],
+            "computed": true
+          },
+          "z": {
+            "type": "string",
+            "description": "Use <b> & <i>.",
+            "description_kind": "plain",
+            "optional": true
+          }
+        }
+      }
+    }
+  },
+  "functions": {
+    "f": {
+      "summary": "trimmed",
+      "return_type": "string"
+    }
+  }
+}
+`, string(data))
+
+	assert.Equal(t, canonicalTestSchema(), ps, "the schema is not modified")
+	again, err := MarshalCanonical(canonicalTestSchema())
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	var decoded tfjson.ProviderSchema
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "Use <b> & <i>.", decoded.ResourceSchemas["example_b"].Block.Attributes["z"].Description)
+
+	data, err = MarshalCanonical(&tfjson.ProviderSchema{ResourceSchemas: map[string]*tfjson.Schema{
+		"example_a": {Block: &tfjson.SchemaBlock{Description: `Paths like C:\u003c and <dir>`}},
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"description": "Paths like C:\\u003c and <dir>"`, "escaped backslashes are kept")
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, `Paths like C:\u003c and <dir>`, decoded.ResourceSchemas["example_a"].Block.Description)
+
+	data, err = MarshalCanonical(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "null\n", string(data))
+}
+
+func TestServer_WriteSchemaBundle_Canonical(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	path, err := s.WriteSchemaBundle(req, t.TempDir())
+	require.NoError(t, err)
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(written), "{\n  \""), "the file is indented")
+	var ps tfjson.ProviderSchema
+	require.NoError(t, json.Unmarshal(written, &ps))
+	again, err := MarshalCanonical(&ps)
+	require.NoError(t, err)
+	assert.Equal(t, string(written), string(again))
+}
+
+func TestMarshalCanonicalFunction(t *testing.T) {
+	f := &tfjson.FunctionSignature{Summary: " Adds <x>. ", ReturnType: cty.Number, Parameters: []*tfjson.FunctionParameter{{Name: "x", Type: cty.Number}}}
+	data, err := marshalCanonicalFunction(f)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "summary": "Adds <x>.",
+  "return_type": "number",
+  "parameters": [
+    {
+      "name": "x",
+      "type": "number"
+    }
+  ]
+}
+`, string(data))
+	assert.Equal(t, " Adds <x>. ", f.Summary, "the signature is not modified")
+}
@@ -0,0 +1,173 @@
+package tfpluginschema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// contentStoreDir is the directory, relative to the cache root, holding
+// provider binaries named by their SHA-256. It cannot collide with the
+// provider layout, whose top-level entries are registry type names or
+// registry hosts.
+const contentStoreDir = "blobs/sha256"
+
+// ErrBinaryIntegrity is returned when a provider binary in the content
+// store no longer matches the digest it is stored under.
+var ErrBinaryIntegrity = errors.New("provider binary failed integrity check")
+
+// WithContentStore stores extracted provider binaries once under their
+// SHA-256 in <cacheDir>/blobs/sha256, and links them into the usual cache
+// layout with a relative symlink. Identical binaries published under
+// several versions or registries then occupy disk once. Before each
+// execution the binary is hashed again and compared with its name; a
+// mismatch fails with ErrBinaryIntegrity instead of running a modified
+// binary. Where symlinks cannot be created (for example on Windows without
+// the privilege), a hard link is used instead, which still saves space but
+// cannot be re-verified. Use PruneContentStore to remove binaries no
+// longer linked from the cache.
+func WithContentStore(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.contentStore = enabled
+	}
+}
+
+// hashFile returns the hex SHA-256 of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// storeBinaryContent moves the binary at stagingDir/binaryPath into the
+// content store and replaces it with a link. The link is made relative to
+// extractDir, the location stagingDir is published to, which is a sibling
+// of stagingDir.
+func (s *Server) storeBinaryContent(stagingDir, binaryPath, extractDir string) error {
+	staged := filepath.Join(stagingDir, binaryPath)
+	digest, err := hashFile(staged)
+	if err != nil {
+		return fmt.Errorf("failed to hash provider binary: %w", err)
+	}
+	blob := filepath.Join(s.cacheDir, filepath.FromSlash(contentStoreDir), digest)
+	if err := ensureWithinBaseDir(s.cacheDir, blob); err != nil {
+		return err
+	}
+
+	// Keep an existing blob only if it is intact, so that a damaged one is
+	// repaired by the next download of any provider sharing it.
+	if existing, err := hashFile(blob); err == nil && existing == digest {
+		if err := os.Remove(staged); err != nil {
+			return fmt.Errorf("failed to remove duplicate provider binary: %w", err)
+		}
+		s.logger(logComponentExtract).Debug("Provider binary already in content store", "sha256", digest)
+	} else if err := os.Rename(staged, blob); err != nil {
+		return fmt.Errorf("failed to move provider binary into content store: %w", err)
+	}
+
+	target, err := filepath.Rel(filepath.Join(extractDir, filepath.Dir(binaryPath)), blob)
+	if err != nil {
+		return fmt.Errorf("failed to compute content store link: %w", err)
+	}
+	if err := os.Symlink(target, staged); err != nil {
+		if linkErr := os.Link(blob, staged); linkErr != nil {
+			return fmt.Errorf("failed to link provider binary from content store: %w", errors.Join(err, linkErr))
+		}
+	}
+	return nil
+}
+
+// verifyProviderBinary re-hashes a provider binary linked from the content
+// store and compares it with the digest it is stored under. Binaries that
+// are not symlinks into the content store are not checked.
+func (s *Server) verifyProviderBinary(path string) error {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	storeRoot := filepath.Join(s.cacheDir, filepath.FromSlash(contentStoreDir))
+	if filepath.Dir(filepath.Clean(target)) != filepath.Clean(storeRoot) {
+		return nil
+	}
+	want := filepath.Base(target)
+	got, err := hashFile(target)
+	if err != nil {
+		return fmt.Errorf("failed to hash provider binary: %w", err)
+	}
+	if got != want {
+		return fmt.Errorf("%w: %s has sha256 %s, want %s", ErrBinaryIntegrity, target, got, want)
+	}
+	return nil
+}
+
+// PruneContentStore removes binaries from the content store that are no
+// longer linked from any provider in the cache, for example after
+// CleanupRequest or manual removal of cache entries, and returns how many
+// were removed. Only symlinks count as references: a binary hard-linked
+// into the cache keeps its data through that link when the store's copy
+// is removed.
+func (s *Server) PruneContentStore() (int, error) {
+	storeRoot := filepath.Join(s.cacheDir, filepath.FromSlash(contentStoreDir))
+	blobs, err := os.ReadDir(storeRoot)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read content store: %w", err)
+	}
+
+	linked := make(map[string]bool)
+	err = filepath.WalkDir(s.cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path == filepath.Dir(storeRoot) {
+			return fs.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		if filepath.Dir(filepath.Clean(target)) == filepath.Clean(storeRoot) {
+			linked[filepath.Base(target)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+
+	removed := 0
+	for _, b := range blobs {
+		if b.IsDir() || linked[b.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(storeRoot, b.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove unused provider binary: %w", err)
+		}
+		removed++
+	}
+	s.logger(logComponentCache).Debug("Pruned content store", "removed", removed)
+	return removed, nil
+}
@@ -0,0 +1,92 @@
+package tfpluginschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Get_ContentStore(t *testing.T) {
+	cacheDir := t.TempDir()
+	archive := providerArchive(t, "null")
+	reqs := []Request{
+		{Namespace: "hashicorp", Name: "null", Version: "1.0.0"},
+		{Namespace: "hashicorp", Name: "null", Version: "1.1.0"},
+	}
+
+	var paths []string
+	for _, req := range reqs {
+		s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubProviderRegistry(t, req, archive)), WithContentStore(true))
+		t.Cleanup(func() { _ = s.Cleanup() })
+		require.NoError(t, s.Get(req))
+
+		path, ok := findProviderBinary(cacheProviderDir(cacheDir, req), req.Name)
+		require.True(t, ok)
+		info, err := os.Lstat(path)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink, "cache entry should link into the content store")
+		require.NoError(t, s.verifyProviderBinary(path))
+		paths = append(paths, path)
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(cacheDir, contentStoreDir))
+	require.NoError(t, err)
+	require.Len(t, blobs, 1, "identical binaries should be stored once")
+
+	data, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(data))
+}
+
+func TestServer_VerifyProviderBinary_Tampered(t *testing.T) {
+	cacheDir := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubProviderRegistry(t, req, providerArchive(t, "null"))), WithContentStore(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+
+	path, ok := findProviderBinary(cacheProviderDir(cacheDir, req), req.Name)
+	require.True(t, ok)
+	target, err := filepath.EvalSymlinks(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(target, []byte("modified"), 0o755))
+
+	assert.ErrorIs(t, s.verifyProviderBinary(path), ErrBinaryIntegrity)
+}
+
+func TestServer_PruneContentStore(t *testing.T) {
+	cacheDir := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubProviderRegistry(t, req, providerArchive(t, "null"))), WithContentStore(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+
+	unused := filepath.Join(cacheDir, contentStoreDir, "0000")
+	require.NoError(t, os.WriteFile(unused, []byte("orphan"), 0o755))
+
+	removed, err := s.PruneContentStore()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NoFileExists(t, unused)
+
+	extractDir := cacheProviderDir(cacheDir, req)
+	_, ok := findProviderBinary(extractDir, req.Name)
+	require.True(t, ok, "linked binaries must survive pruning")
+
+	require.NoError(t, os.RemoveAll(extractDir))
+	removed, err = s.PruneContentStore()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
+
+func TestServer_PruneContentStore_NoStore(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	removed, err := s.PruneContentStore()
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
@@ -0,0 +1,223 @@
+package tfpluginschema
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	githubAPIURL     = "https://api.github.com"
+	githubRawURL     = "https://raw.githubusercontent.com"
+	changelogFile    = "CHANGELOG.md"
+	maxChangelogSize = 16 << 20
+)
+
+// ErrChangelogNotFound is returned by GetChangelog when neither the release
+// nor the CHANGELOG.md of the provider's source repository has an entry for
+// the version.
+var ErrChangelogNotFound = errors.New("changelog entry not found")
+
+// errNotGitHub is returned by providerRepository for providers whose source
+// repository is not on GitHub.
+var errNotGitHub = errors.New("source repository is not on GitHub")
+
+// ChangelogEntry holds the release notes of one provider version.
+type ChangelogEntry struct {
+	Version string // Version the notes are for, e.g. "5.50.0"
+	Notes   string // Release notes as markdown
+	URL     string // Page the notes were taken from
+}
+
+// Reference returns a pointer to the entry for use in reports, such as
+// "see CHANGELOG entry for 5.50.0 (https://...)".
+func (e ChangelogEntry) Reference() string {
+	if e.URL == "" {
+		return "see CHANGELOG entry for " + e.Version
+	}
+	return fmt.Sprintf("see CHANGELOG entry for %s (%s)", e.Version, e.URL)
+}
+
+// WithGitHubToken sets the token sent to the GitHub API by GetChangelog,
+// which raises GitHub's rate limit for unauthenticated requests and gives
+// access to private repositories.
+func WithGitHubToken(token string) ServerOption {
+	return func(s *Server) {
+		s.githubToken = token
+	}
+}
+
+// GetChangelog returns the release notes of the provider version selected by
+// request. The provider's source repository is the one its registry lists,
+// or else github.com/<namespace>/terraform-provider-<name>, the layout the
+// OpenTofu registry is built from. The notes are the body of the repository's
+// GitHub release for the version, or, for releases without one, the
+// version's section of the CHANGELOG.md at its tag.
+//
+// Only GitHub repositories are supported. Registry headers and tokens are
+// not sent to GitHub; use WithGitHubToken.
+func (s *Server) GetChangelog(request Request) (ChangelogEntry, error) {
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return ChangelogEntry{}, err
+	}
+	owner, repo, err := s.providerRepository(request)
+	if errors.Is(err, errNotGitHub) {
+		return ChangelogEntry{}, fmt.Errorf("%w: %w", ErrChangelogNotFound, err)
+	}
+	if err != nil {
+		return ChangelogEntry{}, err
+	}
+	l := s.logger(logComponentRegistry).With(s.requestLogAttrs(request)...)
+	l.Debug("Fetching changelog", "repository", owner+"/"+repo)
+
+	entry := ChangelogEntry{Version: request.Version}
+	tag := "v" + request.Version
+	var release struct {
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	}
+	found, err := s.githubGet(fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", githubAPIURL, owner, repo, url.PathEscape(tag)), &release, nil)
+	if err != nil {
+		return ChangelogEntry{}, err
+	}
+	if found && strings.TrimSpace(release.Body) != "" {
+		entry.Notes, entry.URL = strings.TrimSpace(release.Body), release.HTMLURL
+		return entry, nil
+	}
+
+	var changelog string
+	found, err = s.githubGet(fmt.Sprintf("%s/%s/%s/%s/%s", githubRawURL, owner, repo, url.PathEscape(tag), changelogFile), nil, &changelog)
+	if err != nil {
+		return ChangelogEntry{}, err
+	}
+	if found {
+		entry.Notes = changelogSection(changelog, request.Version)
+	}
+	if entry.Notes == "" {
+		return ChangelogEntry{}, fmt.Errorf("%w: %s/%s %s in github.com/%s/%s", ErrChangelogNotFound, request.Namespace, request.Name, request.Version, owner, repo)
+	}
+	entry.URL = fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s", owner, repo, tag, changelogFile)
+	return entry, nil
+}
+
+// providerRepository returns the GitHub owner and repository of the
+// provider of request, which must be prepared.
+func (s *Server) providerRepository(request Request) (string, string, error) {
+	owner, repo := request.Namespace, "terraform-provider-"+request.Name
+
+	u, err := s.registryURL(request.RegistryType, request.RegistryType.BaseURL()+"/"+request.Namespace+"/"+request.Name)
+	if err != nil {
+		return "", "", err
+	}
+	body, status, err := s.registryGet(u)
+	if err != nil || status != http.StatusOK {
+		// Not every registry serves provider details; fall back to the
+		// conventional repository.
+		return owner, repo, nil
+	}
+	var details struct {
+		Source string `json:"source"`
+	}
+	if json.Unmarshal(body, &details) != nil || details.Source == "" {
+		return owner, repo, nil
+	}
+	source, err := url.Parse(details.Source)
+	var parts []string
+	if err == nil && strings.EqualFold(source.Host, "github.com") {
+		parts = strings.Split(strings.Trim(source.Path, "/"), "/")
+	}
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("%w: %s/%s has %q", errNotGitHub, request.Namespace, request.Name, details.Source)
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), nil
+}
+
+// githubGet fetches u from GitHub, decoding a JSON response into v or
+// storing the text of the response in text. It reports false if GitHub
+// answers 404.
+func (s *Server) githubGet(u string, v any, text *string) (bool, error) {
+	if s.offline {
+		return false, fmt.Errorf("%w: %s", ErrOffline, u)
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request for changelog: %w", err)
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	if s.githubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.githubToken)
+	}
+	if v != nil {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch changelog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to fetch changelog: %s => %d", u, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChangelogSize))
+	if err != nil {
+		return false, fmt.Errorf("failed to read changelog: %w", err)
+	}
+	if text != nil {
+		*text = string(body)
+		return true, nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return false, fmt.Errorf("failed to decode changelog response from %s: %w", u, err)
+	}
+	return true, nil
+}
+
+// changelogSection returns the section of the markdown changelog md whose
+// heading names version, such as "## 5.50.0 (May 16, 2024)" or
+// "## [v5.50.0]", up to the next heading of the same or a higher level, or
+// "" if there is none.
+func changelogSection(md, version string) string {
+	var (
+		section []string
+		level   int
+	)
+	sc := bufio.NewScanner(strings.NewReader(md))
+	sc.Buffer(nil, maxChangelogSize)
+	for sc.Scan() {
+		line := sc.Text()
+		hashes := len(line) - len(strings.TrimLeft(line, "#"))
+		if level > 0 && hashes > 0 && hashes <= level {
+			break
+		}
+		if level > 0 {
+			section = append(section, line)
+			continue
+		}
+		if hashes > 0 && headingNamesVersion(line[hashes:], version) {
+			level = hashes
+		}
+	}
+	return strings.TrimSpace(strings.Join(section, "\n"))
+}
+
+// headingNamesVersion reports whether the heading text starts with version,
+// optionally prefixed by "v" and enclosed in brackets or a markdown link.
+func headingNamesVersion(heading, version string) bool {
+	first, _, _ := strings.Cut(strings.TrimSpace(heading), " ")
+	first = strings.TrimPrefix(first, "[")
+	if i := strings.IndexAny(first, "]("); i >= 0 {
+		first = first[:i]
+	}
+	return strings.TrimPrefix(first, "v") == version
+}
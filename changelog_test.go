@@ -0,0 +1,96 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChangelog = `# Changelog
+
+## 5.51.0 (Unreleased)
+
+FEATURES:
+
+* Upcoming
+
+## [5.50.0](https://github.com/example/terraform-provider-cloud/compare/v5.49.0...v5.50.0) (May 16, 2024)
+
+BUG FIXES:
+
+* Fixed a thing
+
+### Notes
+
+* Read this
+
+## 5.49.0 (May 9, 2024)
+
+* Older
+`
+
+func TestServer_GetChangelog(t *testing.T) {
+	var authorization []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/example/cloud":
+			fmt.Fprint(w, `{"source":"https://github.com/example-org/terraform-provider-cloud"}`)
+		case "/repos/example-org/terraform-provider-cloud/releases/tags/v5.50.0",
+			"/repos/example/terraform-provider-other/releases/tags/v1.0.0":
+			authorization = append(authorization, r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"body":"","html_url":"https://github.com/example-org/terraform-provider-cloud/releases/tag/v5.50.0"}`)
+		case "/repos/example/terraform-provider-notes/releases/tags/v2.0.0":
+			fmt.Fprint(w, `{"body":"## 2.0.0\n\n* Breaking\n","html_url":"https://github.com/example/terraform-provider-notes/releases/tag/v2.0.0"}`)
+		case "/example-org/terraform-provider-cloud/v5.50.0/CHANGELOG.md":
+			fmt.Fprint(w, testChangelog)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithGitHubToken("gh-token"), WithRegistryToken("registry.terraform.io", "registry-token"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	t.Run("CHANGELOG.md section", func(t *testing.T) {
+		entry, err := s.GetChangelog(Request{Namespace: "example", Name: "cloud", Version: "5.50.0", RegistryType: RegistryTypeTerraform})
+		require.NoError(t, err)
+		assert.Equal(t, "BUG FIXES:\n\n* Fixed a thing\n\n### Notes\n\n* Read this", entry.Notes)
+		assert.Equal(t, "https://github.com/example-org/terraform-provider-cloud/blob/v5.50.0/CHANGELOG.md", entry.URL)
+		assert.Equal(t, "see CHANGELOG entry for 5.50.0 (https://github.com/example-org/terraform-provider-cloud/blob/v5.50.0/CHANGELOG.md)", entry.Reference())
+		assert.Equal(t, []string{"Bearer gh-token"}, authorization, "only the GitHub token goes to GitHub")
+	})
+
+	t.Run("release notes", func(t *testing.T) {
+		entry, err := s.GetChangelog(Request{Namespace: "example", Name: "notes", Version: "2.0.0"})
+		require.NoError(t, err)
+		assert.Equal(t, "## 2.0.0\n\n* Breaking", entry.Notes)
+		assert.Equal(t, "https://github.com/example/terraform-provider-notes/releases/tag/v2.0.0", entry.URL)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := s.GetChangelog(Request{Namespace: "example", Name: "other", Version: "1.0.0"})
+		require.ErrorIs(t, err, ErrChangelogNotFound)
+		assert.ErrorContains(t, err, "github.com/example/terraform-provider-other")
+	})
+}
+
+func TestServer_GetChangelog_NotGitHub(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"source":"https://gitlab.com/example/terraform-provider-cloud"}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetChangelog(Request{Namespace: "example", Name: "cloud", Version: "1.0.0"})
+	require.ErrorIs(t, err, ErrChangelogNotFound)
+	assert.ErrorContains(t, err, "source repository is not on GitHub: example/cloud has \"https://gitlab.com/example/terraform-provider-cloud\"")
+}
+
+func TestChangelogSection(t *testing.T) {
+	assert.Equal(t, "* Older", changelogSection(testChangelog, "5.49.0"))
+	assert.Equal(t, "FEATURES:\n\n* Upcoming", changelogSection(testChangelog, "5.51.0"))
+	assert.Empty(t, changelogSection(testChangelog, "5.5.0"))
+	assert.Equal(t, "* Fixed", changelogSection("## v1.2.3\n* Fixed\n", "1.2.3"))
+}
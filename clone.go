@@ -0,0 +1,94 @@
+package tfpluginschema
+
+import (
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// WithCloneSchemas makes the Get*Schema methods return deep copies instead
+// of the Server's cached values. By default the returned pointers are shared
+// with the cache and with every other caller, so they must be treated as
+// read-only; enable this, or use CloneSchema on individual results, if the
+// caller needs to modify them.
+func WithCloneSchemas(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.cloneSchemas = enabled
+	}
+}
+
+// CloneSchema returns a deep copy of s. cty.Type values are immutable and are
+// shared rather than copied.
+func CloneSchema(s *tfjson.Schema) *tfjson.Schema {
+	if s == nil {
+		return nil
+	}
+	return &tfjson.Schema{
+		Version: s.Version,
+		Block:   cloneSchemaBlock(s.Block),
+	}
+}
+
+// CloneFunctionSignature returns a deep copy of f.
+func CloneFunctionSignature(f *tfjson.FunctionSignature) *tfjson.FunctionSignature {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	if f.Parameters != nil {
+		c.Parameters = make([]*tfjson.FunctionParameter, len(f.Parameters))
+		for i, p := range f.Parameters {
+			c.Parameters[i] = cloneFunctionParameter(p)
+		}
+	}
+	c.VariadicParameter = cloneFunctionParameter(f.VariadicParameter)
+	return &c
+}
+
+func cloneFunctionParameter(p *tfjson.FunctionParameter) *tfjson.FunctionParameter {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+func cloneSchemaBlock(b *tfjson.SchemaBlock) *tfjson.SchemaBlock {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	c.Attributes = cloneSchemaAttributes(b.Attributes)
+	if b.NestedBlocks != nil {
+		c.NestedBlocks = make(map[string]*tfjson.SchemaBlockType, len(b.NestedBlocks))
+		for name, nb := range b.NestedBlocks {
+			if nb == nil {
+				c.NestedBlocks[name] = nil
+				continue
+			}
+			nbc := *nb
+			nbc.Block = cloneSchemaBlock(nb.Block)
+			c.NestedBlocks[name] = &nbc
+		}
+	}
+	return &c
+}
+
+func cloneSchemaAttributes(attrs map[string]*tfjson.SchemaAttribute) map[string]*tfjson.SchemaAttribute {
+	if attrs == nil {
+		return nil
+	}
+	c := make(map[string]*tfjson.SchemaAttribute, len(attrs))
+	for name, a := range attrs {
+		if a == nil {
+			c[name] = nil
+			continue
+		}
+		ac := *a
+		if a.AttributeNestedType != nil {
+			nt := *a.AttributeNestedType
+			nt.Attributes = cloneSchemaAttributes(a.AttributeNestedType.Attributes)
+			ac.AttributeNestedType = &nt
+		}
+		c[name] = &ac
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+package tfpluginschema
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func testSchema() *tfjson.Schema {
+	return &tfjson.Schema{
+		Version: 2,
+		Block: &tfjson.SchemaBlock{
+			Description: "d",
+			Attributes: map[string]*tfjson.SchemaAttribute{
+				"name": {AttributeType: cty.String, Required: true},
+				"nested": {AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+					NestingMode: tfjson.SchemaNestingModeList,
+					Attributes:  map[string]*tfjson.SchemaAttribute{"x": {AttributeType: cty.Number}},
+				}},
+			},
+			NestedBlocks: map[string]*tfjson.SchemaBlockType{
+				"timeouts": {NestingMode: tfjson.SchemaNestingModeSingle, Block: &tfjson.SchemaBlock{
+					Attributes: map[string]*tfjson.SchemaAttribute{"create": {AttributeType: cty.String, Optional: true}},
+				}},
+			},
+		},
+	}
+}
+
+func TestCloneSchema(t *testing.T) {
+	orig := testSchema()
+	c := CloneSchema(orig)
+	require.Equal(t, orig, c)
+
+	c.Block.Description = "changed"
+	c.Block.Attributes["name"].Required = false
+	c.Block.Attributes["nested"].AttributeNestedType.Attributes["x"].Computed = true
+	c.Block.NestedBlocks["timeouts"].Block.Attributes["create"].Optional = false
+	delete(c.Block.Attributes, "nested")
+
+	assert.Equal(t, testSchema(), orig, "modifying the clone must not affect the original")
+	assert.Nil(t, CloneSchema(nil))
+}
+
+func TestCloneFunctionSignature(t *testing.T) {
+	orig := &tfjson.FunctionSignature{
+		Summary:           "s",
+		ReturnType:        cty.String,
+		Parameters:        []*tfjson.FunctionParameter{{Name: "a", Type: cty.String}},
+		VariadicParameter: &tfjson.FunctionParameter{Name: "rest", Type: cty.Number},
+	}
+	c := CloneFunctionSignature(orig)
+	require.Equal(t, orig, c)
+
+	c.Parameters[0].Name = "b"
+	c.VariadicParameter.IsNullable = true
+	assert.Equal(t, "a", orig.Parameters[0].Name)
+	assert.False(t, orig.VariadicParameter.IsNullable)
+	assert.Nil(t, CloneFunctionSignature(nil))
+}
+
+func TestWithCloneSchemas(t *testing.T) {
+	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
+	cached := newConvertedSchema(&tfjson.ProviderSchema{
+		ResourceSchemas: map[string]*tfjson.Schema{"r": testSchema()},
+		Functions:       map[string]*tfjson.FunctionSignature{"f": {ReturnType: cty.Bool}},
+	})
+
+	shared := NewServer(nil)
+	shared.sc[cacheKey(req)] = cached
+	a, err := shared.GetResourceSchema(req, "r")
+	require.NoError(t, err)
+	b, err := shared.GetResourceSchema(req, "r")
+	require.NoError(t, err)
+	assert.Same(t, a, b, "by default callers share the cached schema")
+
+	cloning := NewServer(nil, WithCloneSchemas(true))
+	cloning.sc[cacheKey(req)] = cached
+	c, err := cloning.GetResourceSchema(req, "r")
+	require.NoError(t, err)
+	assert.NotSame(t, a, c)
+	c.Block.Attributes["name"].Required = false
+	assert.True(t, a.Block.Attributes["name"].Required, "the cache is unaffected")
+
+	f1, err := cloning.GetFunctionSchema(req, "f")
+	require.NoError(t, err)
+	f2, err := cloning.GetFunctionSchema(req, "f")
+	require.NoError(t, err)
+	assert.NotSame(t, f1, f2)
+}
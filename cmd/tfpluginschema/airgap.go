@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// --- export-bundle / import-bundle ---
+
+func exportBundleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export-bundle",
+		Usage:     "Package providers and their schemas with a signed manifest for a disconnected network",
+		ArgsUsage: "SOURCE...",
+		Description: "Each SOURCE is a provider address with an optional version, such as hashicorp/aws@~>5.0.\n" +
+			"The bundle holds the provider binaries for this platform. --signing-key is an Ed25519\n" +
+			"private key in PKCS #8 PEM form, as made by 'openssl genpkey -algorithm ed25519'.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "Bundle file to write",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "signing-key",
+				Usage:    "PEM file holding the Ed25519 private key that signs the manifest",
+				Required: true,
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() == 0 {
+				return usageErrorf("at least one provider SOURCE is required")
+			}
+			requests, err := requestsFromArgs(cmd)
+			if err != nil {
+				return err
+			}
+			key, err := readSigningKey(cmd.String("signing-key"))
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			path := cmd.String("output")
+			f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(f.Name())
+			manifest, err := s.ExportBundle(f, requests, key)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Rename(f.Name(), path); err != nil {
+				return err
+			}
+			return printBundleManifest(manifest, "")
+		},
+	}
+}
+
+func importBundleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import-bundle",
+		Usage:     "Verify a bundle made by export-bundle and install its providers into the cache",
+		ArgsUsage: "FILE",
+		Description: "Nothing is installed unless the manifest signature and every file verify.\n" +
+			"--verify-key is the Ed25519 public key in PEM form, as made by 'openssl pkey -pubout'.\n" +
+			"Schemas are written to --schema-dir, for use with --schema-bundle.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "verify-key",
+				Usage:    "PEM file holding the Ed25519 public key that the manifest must be signed with",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "schema-dir",
+				Usage: "Schema bundle directory to write the schemas to",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() != 1 {
+				return usageErrorf("exactly one bundle FILE is required")
+			}
+			key, err := readVerifyKey(cmd.String("verify-key"))
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+			f, err := os.Open(cmd.Args().First())
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+			defer f.Close()
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			manifest, err := s.ImportBundle(f, key, cmd.String("schema-dir"))
+			if err != nil {
+				return err
+			}
+			return printBundleManifest(manifest, tfpluginschema.CurrentPlatform().String())
+		},
+	}
+}
+
+// printBundleManifest writes one row per provider of manifest to stdout.
+// With platform set, it notes the binaries not installed because they were
+// built for another platform.
+func printBundleManifest(manifest *tfpluginschema.BundleManifest, platform string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tVERSION\tPLATFORM\tBINARY SHA-256")
+	for _, p := range manifest.Providers {
+		note := ""
+		if platform != "" && p.Platform != platform {
+			note = " (not installed)"
+		}
+		fmt.Fprintf(w, "%s/%s/%s\t%s\t%s%s\t%s\n", p.Registry, p.Namespace, p.Name, p.Version, p.Platform, note, p.Binary.SHA256)
+	}
+	return w.Flush()
+}
+
+// schemaBundleOptions returns the options serving the schema bundle in
+// dir. With keyPath set, the bundle is only used if its manifest verifies
+// against the public key in that file; otherwise it is reported and
+// skipped, and schemas are read from the providers as usual.
+func schemaBundleOptions(dir, keyPath string) []tfpluginschema.ServerOption {
+	if keyPath == "" {
+		return []tfpluginschema.ServerOption{tfpluginschema.WithSchemaBundleDir(dir)}
+	}
+	key, err := readVerifyKey(keyPath)
+	if err == nil {
+		var bundle fs.FS
+		if bundle, err = tfpluginschema.VerifySchemaBundle(os.DirFS(dir), key); err == nil {
+			return []tfpluginschema.ServerOption{tfpluginschema.WithSchemaBundle(bundle)}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "warning: ignoring schema bundle %s: %v\n", dir, err)
+	return nil
+}
+
+// readSigningKey reads an Ed25519 private key in PKCS #8 PEM form.
+func readSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid signing key %s: not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// readVerifyKey reads an Ed25519 public key in PKIX PEM form.
+func readVerifyKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification key %s: %w", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid verification key %s: not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+// readPEM reads the first PEM block of the file at path.
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(path + " holds no PEM data")
+	}
+	return block, nil
+}
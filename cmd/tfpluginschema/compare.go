@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	cli "github.com/urfave/cli/v3"
+)
+
+// --- compare ---
+
+func compareCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "compare",
+		Usage:     "Compare the schemas of provider versions side by side",
+		ArgsUsage: "SOURCE SOURCE...",
+		Description: "Each SOURCE is a provider address with a version, such as hashicorp/aws@~>5.0,\n" +
+			"or the name of a version alias from the configuration file, such as aws@next.\n" +
+			"The providers are fetched at the same time. Only the entries that differ are\n" +
+			"listed unless --all is given.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "List the entries that are the same in every version too",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the comparison as JSON",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() < 2 {
+				return usageErrorf("at least two provider SOURCEs are required")
+			}
+			requests, err := requestsFromArgs(cmd)
+			if err != nil {
+				return err
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			c, err := s.CompareVersions(cmd.Args().Slice(), requests)
+			if err != nil {
+				return err
+			}
+			if !cmd.Bool("all") {
+				c.Entries = c.Differences()
+			}
+			if cmd.Bool("json") {
+				return printJSON(c)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			header := []string{"KIND", "NAME"}
+			for i, label := range c.Labels {
+				header = append(header, fmt.Sprintf("%s (%s)", label, c.Versions[i]))
+			}
+			fmt.Fprintln(w, strings.Join(header, "\t"))
+			for _, e := range c.Entries {
+				row := []string{string(e.Kind), e.Name}
+				for _, present := range e.Present {
+					switch {
+					case !present:
+						row = append(row, "-")
+					case e.Changed:
+						row = append(row, "changed")
+					default:
+						row = append(row, "yes")
+					}
+				}
+				fmt.Fprintln(w, strings.Join(row, "\t"))
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// completionCacheTTL is how long registry lookups made for shell completion
+// are reused before the registry is queried again.
+const completionCacheTTL = time.Hour
+
+// completionCacheDirName is the directory under the provider cache that holds
+// registry lookups made for shell completion.
+const completionCacheDirName = "completion"
+
+// completeFlagValues completes the value of the provider flags from the
+// registry and the local cache, and otherwise falls back to the default
+// completion of subcommands and flag names.
+//
+// The shell completion scripts always append --generate-shell-completion, so
+// the word before it in os.Args is the one preceding the cursor.
+func completeFlagValues(ctx context.Context, cmd *cli.Command) {
+	prev := ""
+	if len(os.Args) >= 2 {
+		prev = os.Args[len(os.Args)-2]
+	}
+
+	var values []string
+	switch prev {
+	case "--registry", "-r":
+		values = []string{string(tfpluginschema.RegistryTypeOpenTofu), string(tfpluginschema.RegistryTypeTerraform)}
+	case "--namespace", "--ns":
+		values = completeNamespaces(cmd)
+	case "--name", "-n":
+		values = completeNames(cmd)
+	case "--version-constraint", "--vc":
+		values = completeVersions(cmd)
+	default:
+		cli.DefaultCompleteWithFlags(ctx, cmd)
+		return
+	}
+	for _, v := range values {
+		fmt.Fprintln(cmd.Root().Writer, v)
+	}
+}
+
+// completeNamespaces returns the namespaces present in the local cache for
+// the selected registry. The registry has no API to enumerate namespaces.
+func completeNamespaces(cmd *cli.Command) []string {
+	s := newServer(cmd)
+	defer closeServer(cmd, s)
+
+	entries, err := s.CacheEntries()
+	if err != nil {
+		return nil
+	}
+	registry := registryFromCmd(cmd)
+	var namespaces []string
+	for _, e := range entries {
+		if e.Request.RegistryType == registry {
+			namespaces = append(namespaces, e.Request.Namespace)
+		}
+	}
+	slices.Sort(namespaces)
+	return slices.Compact(namespaces)
+}
+
+// completeNames returns the providers in the selected namespace, as listed
+// by the registry, plus any cached locally.
+func completeNames(cmd *cli.Command) []string {
+	namespace := namespaceFromCmd(cmd)
+	if namespace == "" {
+		return nil
+	}
+	s := newServer(cmd)
+	defer closeServer(cmd, s)
+
+	registry := registryFromCmd(cmd)
+	names := cachedCompletions(s, []string{string(registry), namespace}, func() ([]string, error) {
+		providers, err := s.ListProviders(tfpluginschema.ProvidersRequest{
+			Namespace:    namespace,
+			RegistryType: registry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(providers))
+		for i, p := range providers {
+			names[i] = p.Name
+		}
+		return names, nil
+	})
+
+	if entries, err := s.CacheEntries(); err == nil {
+		for _, e := range entries {
+			if e.Request.RegistryType == registry && e.Request.Namespace == namespace {
+				names = append(names, e.Request.Name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// completeVersions returns the versions the registry advertises for the
+// selected provider, newest first.
+func completeVersions(cmd *cli.Command) []string {
+	req := versionsRequestFromCmd(cmd)
+	if req.Namespace == "" || req.Name == "" {
+		return nil
+	}
+	s := newServer(cmd)
+	defer closeServer(cmd, s)
+
+	return cachedCompletions(s, []string{string(req.RegistryType), req.Namespace, req.Name}, func() ([]string, error) {
+		versions, err := s.GetAvailableVersions(req)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, 0, len(versions))
+		for i := len(versions) - 1; i >= 0; i-- {
+			out = append(out, versions[i].Original())
+		}
+		return out, nil
+	})
+}
+
+// cachedCompletions returns the values stored under key in the completion
+// cache if they are younger than completionCacheTTL, and otherwise calls
+// fetch and stores its result. Completion must never fail loudly, so errors
+// yield no values and cache write failures are ignored.
+func cachedCompletions(s *tfpluginschema.Server, key []string, fetch func() ([]string, error)) []string {
+	rel := filepath.Join(key...) + ".json"
+	if !filepath.IsLocal(rel) {
+		return nil
+	}
+	path := filepath.Join(s.CacheDir(), completionCacheDirName, rel)
+
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < completionCacheTTL {
+		if data, err := os.ReadFile(path); err == nil {
+			var values []string
+			if json.Unmarshal(data, &values) == nil {
+				return values
+			}
+		}
+	}
+
+	values, err := fetch()
+	if err != nil {
+		return nil
+	}
+	if data, err := json.Marshal(values); err == nil {
+		if os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+			_ = os.WriteFile(path, data, 0o644)
+		}
+	}
+	return values
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// runCompletion runs the CLI as the shell completion scripts do, with
+// --generate-shell-completion appended to args, and returns what it printed.
+func runCompletion(t *testing.T, args ...string) []string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	args = append(append([]string{"tfpluginschema"}, args...), "--generate-shell-completion")
+	oldArgs := os.Args
+	os.Args = args
+	t.Cleanup(func() { os.Args = oldArgs })
+
+	var out bytes.Buffer
+	cmd := buildRootCommand()
+	cmd.Writer = &out
+	require.NoError(t, cmd.Run(context.Background(), args))
+	return strings.Fields(out.String())
+}
+
+func TestCompleteFlagValues(t *testing.T) {
+	cacheDir := t.TempDir()
+	for _, dir := range []string{
+		"opentofu/hashicorp/terraform-provider-null/3.2.2/linux_amd64",
+		"opentofu/example/terraform-provider-widget/1.0.0/linux_amd64",
+		"terraform/other/terraform-provider-widget/1.0.0/linux_amd64",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, filepath.FromSlash(dir)), 0o755))
+	}
+
+	assert.Equal(t, []string{"opentofu", "terraform"}, runCompletion(t, "--registry"))
+	assert.Equal(t, []string{"opentofu", "terraform"}, runCompletion(t, "-r"))
+	assert.Equal(t, []string{"example", "hashicorp"}, runCompletion(t, "--cache-dir", cacheDir, "--namespace"))
+	assert.Equal(t, []string{"other"}, runCompletion(t, "--cache-dir", cacheDir, "--registry", "terraform", "--ns"))
+	assert.Empty(t, runCompletion(t, "--cache-dir", cacheDir, "--name"), "names need a namespace")
+	assert.Empty(t, runCompletion(t, "--cache-dir", cacheDir, "--namespace", "example", "--version-constraint"), "versions need a name")
+
+	// Other words fall back to completing subcommands.
+	assert.Contains(t, runCompletion(t), "compare")
+}
+
+func TestCachedCompletions(t *testing.T) {
+	s := tfpluginschema.NewServer(nil, tfpluginschema.WithCacheDir(t.TempDir()))
+	key := []string{"opentofu", "hashicorp"}
+	var calls int
+	fetch := func() ([]string, error) {
+		calls++
+		return []string{"aws", "null"}, nil
+	}
+
+	assert.Equal(t, []string{"aws", "null"}, cachedCompletions(s, key, fetch))
+	assert.Equal(t, []string{"aws", "null"}, cachedCompletions(s, key, fetch))
+	assert.Equal(t, 1, calls, "the second lookup is served from the cache")
+
+	path := filepath.Join(s.CacheDir(), completionCacheDirName, "opentofu", "hashicorp.json")
+	require.FileExists(t, path)
+	expired := time.Now().Add(-completionCacheTTL - time.Minute)
+	require.NoError(t, os.Chtimes(path, expired, expired))
+	assert.Equal(t, []string{"aws", "null"}, cachedCompletions(s, key, fetch))
+	assert.Equal(t, 2, calls, "an expired entry is fetched again")
+
+	// A corrupt entry is fetched again and replaced.
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	assert.Equal(t, []string{"aws", "null"}, cachedCompletions(s, key, fetch))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{"aws", "null"}, cachedCompletions(s, key, fetch))
+	assert.Equal(t, 3, calls)
+
+	failing := func() ([]string, error) {
+		calls++
+		return []string{"partial"}, errors.New("registry unavailable")
+	}
+	assert.Nil(t, cachedCompletions(s, []string{"opentofu", "example"}, failing), "errors yield no values")
+	assert.NoFileExists(t, filepath.Join(s.CacheDir(), completionCacheDirName, "opentofu", "example.json"))
+
+	calls = 0
+	assert.Nil(t, cachedCompletions(s, []string{"..", "escape"}, fetch), "keys must stay inside the cache")
+	assert.Zero(t, calls)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// configMetadataKey is the root command Metadata key holding the loadedConfig.
+const configMetadataKey = "config"
+
+// loadedConfig is the configuration file in effect for an invocation.
+type loadedConfig struct {
+	*tfpluginschema.Config
+	// serverOptions are the Server options derived from the file, resolved
+	// once so that credential errors are reported before any command runs.
+	serverOptions []tfpluginschema.ServerOption
+}
+
+// loadConfigFile is the root command's Before hook. It loads the file named
+// by --config, or else the one found by tfpluginschema.FindConfigFile.
+func loadConfigFile(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	c, err := readConfig(cmd)
+	if err != nil {
+		return ctx, err
+	}
+	root := cmd.Root()
+	if root.Metadata == nil {
+		root.Metadata = make(map[string]any)
+	}
+	root.Metadata[configMetadataKey] = c
+	return ctx, nil
+}
+
+// readConfig loads and resolves the configuration file, returning an empty
+// configuration when there is none.
+func readConfig(cmd *cli.Command) (*loadedConfig, error) {
+	path := cmd.String("config")
+	if path == "" {
+		path = tfpluginschema.FindConfigFile()
+	}
+	if path == "" {
+		return &loadedConfig{Config: &tfpluginschema.Config{}}, nil
+	}
+	c, err := tfpluginschema.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := c.ServerOptions()
+	if err != nil {
+		return nil, err
+	}
+	return &loadedConfig{Config: c, serverOptions: opts}, nil
+}
+
+// configFromCmd returns the configuration loaded by loadConfigFile. Before
+// hooks do not run during shell completion, so the file is read here if it
+// has not been already, and a broken file is treated as absent.
+func configFromCmd(cmd *cli.Command) *loadedConfig {
+	if c, ok := cmd.Root().Metadata[configMetadataKey].(*loadedConfig); ok {
+		return c
+	}
+	c, err := readConfig(cmd)
+	if err != nil {
+		return &loadedConfig{Config: &tfpluginschema.Config{}}
+	}
+	return c
+}
+
+// registryFromCmd returns the registry selected by --registry, falling back
+// to the configuration file when the flag is not given.
+func registryFromCmd(cmd *cli.Command) tfpluginschema.RegistryType {
+	if !cmd.IsSet("registry") {
+		if r := configFromCmd(cmd).Registry; r != "" {
+			return r
+		}
+	}
+	return registryTypeFromString(cmd.String("registry"))
+}
+
+// namespaceFromCmd returns the --namespace flag, falling back to the
+// configuration file's default_namespace.
+func namespaceFromCmd(cmd *cli.Command) string {
+	if ns := cmd.String("namespace"); ns != "" {
+		return ns
+	}
+	return configFromCmd(cmd).DefaultNamespace
+}
+
+// requestsFromArgs parses the SOURCE arguments of cmd, resolving the
+// version aliases of the configuration file, and applies --registry to
+// those that name no host.
+func requestsFromArgs(cmd *cli.Command) ([]tfpluginschema.Request, error) {
+	aliases := configFromCmd(cmd).VersionAliases
+	requests := make([]tfpluginschema.Request, 0, cmd.Args().Len())
+	for _, arg := range cmd.Args().Slice() {
+		req, err := aliases.ParseRequest(arg)
+		if err != nil {
+			return nil, usageErrorf("invalid provider %q: %v", arg, err)
+		}
+		if req.RegistryType == "" {
+			req.RegistryType = registryFromCmd(cmd)
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+	"github.com/matt-FFFFFF/tfpluginschema/docgen"
+)
+
+func docCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "doc",
+		Usage:     "Render Markdown documentation for a resource, or all resources with --all",
+		ArgsUsage: "<provider-source> [resource-name]",
+		Description: "provider-source is a provider address such as Azure/azapi or\n" +
+			"registry.terraform.io/hashicorp/aws@~>5.0. The version is taken from an @version\n" +
+			"suffix or --version-constraint, which takes precedence, and, unless the address\n" +
+			"names a registry host, the registry from --registry.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "Render every resource of the provider",
+			},
+			&cli.StringFlag{
+				Name:    "output-dir",
+				Aliases: []string{"o"},
+				Usage:   "Write one <resource-name>.md file per resource to this directory instead of stdout",
+			},
+			&cli.BoolFlag{
+				Name:  "examples",
+				Usage: "Add the examples of the provider's published documentation pages",
+			},
+			&cli.StringFlag{
+				Name:    "github-token",
+				Usage:   "Token for the GitHub API, used by --examples",
+				Sources: cli.EnvVars("GITHUB_TOKEN"),
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			all := cmd.Bool("all")
+			switch {
+			case len(args) == 0:
+				return usageErrorf("expected a provider source argument")
+			case len(args) > 2:
+				return usageErrorf("expected at most 1 resource name, got %d", len(args)-1)
+			case len(args) == 1 && !all:
+				return usageErrorf("expected a resource name argument or --all")
+			case len(args) == 2 && all:
+				return usageErrorf("--all cannot be combined with a resource name argument")
+			}
+
+			req, err := tfpluginschema.ParseRequest(args[0])
+			if err != nil {
+				return err
+			}
+			if vc := cmd.String("version-constraint"); vc != "" {
+				req.Version = vc
+			}
+			if req.RegistryType == "" {
+				req.RegistryType = registryFromCmd(cmd)
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			names := args[1:]
+			if all {
+				if names, err = s.ListResources(req); err != nil {
+					return err
+				}
+			}
+
+			dir := cmd.String("output-dir")
+			if dir != "" {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return fmt.Errorf("failed to create output directory: %w", err)
+				}
+			}
+			for i, name := range names {
+				schema, err := s.GetResourceSchema(req, name)
+				if err != nil {
+					return err
+				}
+				var examples []string
+				if cmd.Bool("examples") {
+					page, err := s.GetResourceDoc(req, name)
+					switch {
+					case errors.Is(err, tfpluginschema.ErrDocNotFound):
+						fmt.Fprintf(os.Stderr, "no examples: %v\n", err)
+					case err != nil:
+						return err
+					default:
+						examples = docgen.Examples(page)
+					}
+				}
+				doc := docgen.ResourceWithExamples(name, schema, examples)
+				if dir == "" {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Print(doc)
+					continue
+				}
+				path := filepath.Join(dir, name+".md")
+				if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func functionDocCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "doc",
+		Usage:     "Render documentation for one function, or all when no name given",
+		ArgsUsage: "[function-name]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "markdown",
+				Usage: "Output format: markdown or json",
+			},
+			&cli.StringFlag{
+				Name:    "output-dir",
+				Aliases: []string{"o"},
+				Usage:   "Write one <function-name>.md or .json file per function to this directory instead of stdout",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			if len(args) > 1 {
+				return usageErrorf("expected at most 1 function name, got %d", len(args))
+			}
+			format := cmd.String("format")
+			if format != "markdown" && format != "json" {
+				return usageErrorf("unsupported format %q: expected markdown or json", format)
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+			req := requestFromCmd(cmd)
+
+			names := args
+			if len(names) == 0 {
+				var err error
+				if names, err = s.ListFunctions(req); err != nil {
+					return err
+				}
+			}
+			ps := &tfjson.ProviderSchema{Functions: make(map[string]*tfjson.FunctionSignature, len(names))}
+			for _, name := range names {
+				f, err := s.GetFunctionSchema(req, name)
+				if err != nil {
+					return err
+				}
+				ps.Functions[name] = f
+			}
+			docs := docgen.Functions(ps)
+
+			dir := cmd.String("output-dir")
+			if dir == "" {
+				if format == "json" {
+					if len(args) == 1 && len(docs) == 1 {
+						return printJSON(docs[0])
+					}
+					return printJSON(docs)
+				}
+				for i, d := range docs {
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Print(d.Markdown())
+				}
+				return nil
+			}
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			for _, d := range docs {
+				data, ext := []byte(d.Markdown()), ".md"
+				if format == "json" {
+					var err error
+					if data, err = json.MarshalIndent(d, "", "  "); err != nil {
+						return err
+					}
+					data, ext = append(data, '\n'), ".json"
+				}
+				path := filepath.Join(dir, d.Name+ext)
+				if err := os.WriteFile(path, data, 0o644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// --- doctor ---
+
+func doctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "Check registry access, the cache and temporary directories and provider execution",
+		Description: "Each check is reported as ok, warning or error, with a hint for every problem.\n" +
+			"The command fails if any check reports an error.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "probe",
+				Usage: "Provider to download and execute, as namespace/name[@version]",
+				Value: "hashicorp/null",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-exec",
+				Usage: "Do not download and execute the probe provider",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the findings as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			probe, err := tfpluginschema.ParseRequest(cmd.String("probe"))
+			if err != nil {
+				return usageErrorf("invalid --probe: %v", err)
+			}
+			if probe.RegistryType == "" {
+				probe.RegistryType = registryFromCmd(cmd)
+			}
+			opts := []tfpluginschema.DiagnoseOption{tfpluginschema.WithDiagnoseProbe(probe)}
+			if cmd.IsSet("registry") {
+				opts = append(opts, tfpluginschema.WithDiagnoseRegistries(registryFromCmd(cmd)))
+			}
+			if cmd.Bool("skip-exec") {
+				opts = append(opts, tfpluginschema.WithoutDiagnoseExec())
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			diags, err := s.Diagnose(ctx, opts...)
+			if cmd.Bool("json") {
+				if perr := printJSON(diags); perr != nil {
+					return perr
+				}
+			} else {
+				printDiagnostics(os.Stdout, diags)
+			}
+			if err != nil {
+				return err
+			}
+			if tfpluginschema.DiagnosticsFailed(diags) {
+				return errors.New("doctor found problems")
+			}
+			return nil
+		},
+	}
+}
+
+// printDiagnostics writes one line per finding to w, followed by its hint.
+func printDiagnostics(w io.Writer, diags []tfpluginschema.Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintf(w, "%-7s %s: %s\n", d.Severity, d.Check, d.Detail)
+		if d.Hint != "" {
+			fmt.Fprintf(w, "        %s\n", d.Hint)
+		}
+	}
+}
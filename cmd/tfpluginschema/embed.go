@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"go/token"
+	"os"
+	"path/filepath"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema/embedgen"
+)
+
+// --- embed ---
+
+func embedCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "embed",
+		Usage:     "Write a Go file that compiles the schemas of providers into a program",
+		ArgsUsage: "SOURCE...",
+		Description: "Each SOURCE is a provider address with an optional version, such as hashicorp/aws@~>5.0.\n" +
+			"The file declares a tfpluginschema.SchemaSource holding the compressed schemas, for\n" +
+			"use with tfpluginschema.WithSchemaSources.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Go file to write instead of stdout",
+			},
+			&cli.StringFlag{
+				Name:     "package",
+				Usage:    "Package name of the generated file",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "var",
+				Usage: "Name of the declared schema source",
+				Value: embedgen.DefaultVariable,
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() == 0 {
+				return usageErrorf("at least one provider SOURCE is required")
+			}
+			requests, err := requestsFromArgs(cmd)
+			if err != nil {
+				return err
+			}
+			opts := embedgen.Options{Package: cmd.String("package"), Variable: cmd.String("var")}
+			if !token.IsIdentifier(opts.Package) || !token.IsIdentifier(opts.Variable) {
+				return usageErrorf("--package and --var must be Go identifiers")
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			dir, err := os.MkdirTemp("", "tfpluginschema-embed-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			for _, req := range requests {
+				if _, err := s.WriteSchemaBundle(req, dir); err != nil {
+					return err
+				}
+			}
+
+			path := cmd.String("output")
+			if path == "" {
+				return embedgen.Generate(os.Stdout, os.DirFS(dir), opts)
+			}
+			f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(f.Name())
+			err = embedgen.Generate(f, os.DirFS(dir), opts)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+			return os.Rename(f.Name(), path)
+		},
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+
+	"github.com/hashicorp/hcl/v2"
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// Exit codes are part of the CLI's scripting contract: existing values must
+// not be renumbered.
+const (
+	exitError                 = 1 // Any failure not covered below
+	exitNotFound              = 2 // Provider, version or schema does not exist
+	exitNetwork               = 3 // Registry unreachable or returned an error
+	exitValidationFailed      = 4 // validate found errors in the module
+	exitUsage                 = 5 // Invalid arguments or flags
+	exitInsufficientDiskSpace = 6 // Not enough space to download and extract the provider
+)
+
+// errorCodes maps exit codes to the stable code names used by --json-errors.
+var errorCodes = map[int]string{
+	exitError:                 "error",
+	exitNotFound:              "not_found",
+	exitNetwork:               "network",
+	exitValidationFailed:      "validation_failed",
+	exitUsage:                 "usage",
+	exitInsufficientDiskSpace: "insufficient_disk_space",
+}
+
+// usageError marks an error caused by invalid arguments or flags.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// onUsageError is the OnUsageError hook for every command. It reports flag
+// parsing errors as usage errors instead of printing the help text.
+func onUsageError(_ context.Context, _ *cli.Command, err error, _ bool) error {
+	return usageError{err}
+}
+
+// validationError is returned by the validate command when the module has
+// errors. The diagnostics include any warnings.
+type validationError struct {
+	dir   string
+	diags hcl.Diagnostics
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("validation of %s failed with %d error(s)", e.dir, len(e.diags.Errs()))
+}
+
+// exitCode classifies err into one of the documented exit codes.
+func exitCode(err error) int {
+	var usage usageError
+	var validation *validationError
+	var urlErr *url.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &validation):
+		return exitValidationFailed
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, tfpluginschema.ErrPluginNotFound),
+		errors.Is(err, tfpluginschema.ErrSchemaNotFound),
+		errors.Is(err, tfpluginschema.ErrNoMatchingVersion),
+		errors.Is(err, tfpluginschema.ErrBuiltInProvider),
+		errors.Is(err, tfpluginschema.ErrSchemaNotBundled):
+		return exitNotFound
+	case errors.Is(err, tfpluginschema.ErrPluginApi),
+		errors.Is(err, tfpluginschema.ErrOffline),
+		errors.As(err, &urlErr),
+		errors.As(err, &netErr):
+		return exitNetwork
+	case errors.Is(err, tfpluginschema.ErrInsufficientDiskSpace):
+		return exitInsufficientDiskSpace
+	default:
+		return exitError
+	}
+}
+
+// jsonError is the object written to stderr for a failed command when
+// --json-errors is set.
+type jsonError struct {
+	Error struct {
+		Code        string           `json:"code"`
+		ExitCode    int              `json:"exit_code"`
+		Message     string           `json:"message"`
+		Diagnostics []jsonDiagnostic `json:"diagnostics,omitempty"`
+	} `json:"error"`
+}
+
+type jsonDiagnostic struct {
+	Severity string     `json:"severity"`
+	Summary  string     `json:"summary"`
+	Detail   string     `json:"detail,omitempty"`
+	Range    *jsonRange `json:"range,omitempty"`
+}
+
+type jsonRange struct {
+	Filename string  `json:"filename"`
+	Start    jsonPos `json:"start"`
+	End      jsonPos `json:"end"`
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// reportError writes err to w, as text or as a jsonError, and returns the
+// exit code for it.
+func reportError(w io.Writer, err error, asJSON bool) int {
+	code := exitCode(err)
+	if !asJSON {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return code
+	}
+
+	var out jsonError
+	out.Error.Code = errorCodes[code]
+	out.Error.ExitCode = code
+	out.Error.Message = err.Error()
+	var validation *validationError
+	if errors.As(err, &validation) {
+		out.Error.Diagnostics = jsonDiagnostics(validation.diags)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(out); encErr != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+	}
+	return code
+}
+
+func jsonDiagnostics(diags hcl.Diagnostics) []jsonDiagnostic {
+	out := make([]jsonDiagnostic, 0, len(diags))
+	for _, d := range diags {
+		jd := jsonDiagnostic{
+			Severity: "error",
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Severity == hcl.DiagWarning {
+			jd.Severity = "warning"
+		}
+		if d.Subject != nil {
+			jd.Range = &jsonRange{
+				Filename: d.Subject.Filename,
+				Start:    jsonPos{Line: d.Subject.Start.Line, Column: d.Subject.Start.Column, Byte: d.Subject.Start.Byte},
+				End:      jsonPos{Line: d.Subject.End.Line, Column: d.Subject.End.Column, Byte: d.Subject.End.Byte},
+			}
+		}
+		out = append(out, jd)
+	}
+	return out
+}
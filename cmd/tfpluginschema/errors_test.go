@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitError},
+		{fmt.Errorf("failed: %w", tfpluginschema.ErrPluginNotFound), exitNotFound},
+		{fmt.Errorf("resource %w: x", tfpluginschema.ErrSchemaNotFound), exitNotFound},
+		{fmt.Errorf("wrap: %w", tfpluginschema.ErrNoMatchingVersion), exitNotFound},
+		{tfpluginschema.ErrBuiltInProvider, exitNotFound},
+		{fmt.Errorf("%w: example", tfpluginschema.ErrSchemaNotBundled), exitNotFound},
+		{fmt.Errorf("%w: 500", tfpluginschema.ErrPluginApi), exitNetwork},
+		{fmt.Errorf("%w: https://example.com", tfpluginschema.ErrOffline), exitNetwork},
+		{fmt.Errorf("send: %w", &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("refused")}), exitNetwork},
+		{&validationError{dir: "."}, exitValidationFailed},
+		{usageErrorf("bad flag"), exitUsage},
+		{fmt.Errorf("%w: need more", tfpluginschema.ErrInsufficientDiskSpace), exitInsufficientDiskSpace},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, exitCode(tt.err), tt.err.Error())
+	}
+}
+
+func TestReportError_JSON(t *testing.T) {
+	err := &validationError{dir: "mod", diags: hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported argument",
+			Subject:  &hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 2, Column: 3, Byte: 10}, End: hcl.Pos{Line: 2, Column: 8, Byte: 15}},
+		},
+		{Severity: hcl.DiagWarning, Summary: "Deprecated attribute"},
+	}}
+
+	var buf bytes.Buffer
+	code := reportError(&buf, err, true)
+	assert.Equal(t, exitValidationFailed, code)
+
+	var got jsonError
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "validation_failed", got.Error.Code)
+	assert.Equal(t, exitValidationFailed, got.Error.ExitCode)
+	assert.Equal(t, "validation of mod failed with 1 error(s)", got.Error.Message)
+	require.Len(t, got.Error.Diagnostics, 2)
+	assert.Equal(t, "error", got.Error.Diagnostics[0].Severity)
+	assert.Equal(t, jsonPos{Line: 2, Column: 3, Byte: 10}, got.Error.Diagnostics[0].Range.Start)
+	assert.Equal(t, "warning", got.Error.Diagnostics[1].Severity)
+	assert.Nil(t, got.Error.Diagnostics[1].Range)
+}
+
+func TestReportError_Text(t *testing.T) {
+	var buf bytes.Buffer
+	code := reportError(&buf, usageErrorf("expected %d", 1), false)
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, "error: expected 1\n", buf.String())
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema/validate"
+)
+
+func healthCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "health",
+		Usage:     "Report the deprecated and missing arguments of a module, and the provider features it uses",
+		ArgsUsage: "[module-dir]",
+		Description: "Providers are resolved as for validate. Deprecated covers resource types, arguments\n" +
+			"and nested blocks, and attributes referenced from expressions such as outputs.\n" +
+			"The report is written to stdout; it does not fail the command.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "features",
+				Usage: "Also report the ephemeral resources, write-only arguments and provider-defined functions the module uses",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the report as JSON",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			if len(args) > 1 {
+				return usageErrorf("expected at most 1 module directory, got %d", len(args))
+			}
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+
+			m, diags := validate.LoadModule(dir)
+			var h *validate.Health
+			if m != nil && !diags.HasErrors() {
+				s := newServer(cmd)
+				defer closeServer(cmd, s)
+				registry := registryFromCmd(cmd)
+				var healthDiags hcl.Diagnostics
+				h, healthDiags = m.Health(func(p validate.ProviderRequirement, kind validate.BlockKind, typ string) (*tfjson.Schema, error) {
+					return lookupBlockSchema(s, registry, p, kind, typ)
+				})
+				diags = append(diags, healthDiags...)
+			}
+			if diags.HasErrors() {
+				if !cmd.Bool("json-errors") {
+					var files map[string]*hcl.File
+					if m != nil {
+						files = m.Files
+					}
+					w := hcl.NewDiagnosticTextWriter(os.Stderr, files, 0, false)
+					if err := w.WriteDiagnostics(diags); err != nil {
+						return err
+					}
+				}
+				return &validationError{dir: dir, diags: diags}
+			}
+
+			if !cmd.Bool("features") {
+				h.Features = nil
+			}
+			if cmd.Bool("json") {
+				return printJSON(healthJSON(h))
+			}
+			return printHealth(h)
+		},
+	}
+}
+
+// jsonReference is a validate.Reference as printed by health --json.
+type jsonReference struct {
+	Address    string    `json:"address"`
+	Path       string    `json:"path,omitempty"`
+	Expression bool      `json:"expression,omitempty"`
+	Range      jsonRange `json:"range"`
+}
+
+type jsonFeatureUse struct {
+	Provider string `json:"provider"`
+	Feature  string `json:"feature"`
+	Count    int    `json:"count"`
+}
+
+type jsonHealth struct {
+	Deprecated      []jsonReference  `json:"deprecated"`
+	MissingRequired []jsonReference  `json:"missing_required"`
+	Features        []jsonFeatureUse `json:"features,omitempty"`
+}
+
+func healthJSON(h *validate.Health) jsonHealth {
+	refs := func(in []validate.Reference) []jsonReference {
+		out := make([]jsonReference, 0, len(in))
+		for _, r := range in {
+			out = append(out, jsonReference{
+				Address:    blockAddress(r),
+				Path:       strings.Join(r.Path, "."),
+				Expression: r.Expression,
+				Range: jsonRange{
+					Filename: r.Range.Filename,
+					Start:    jsonPos{Line: r.Range.Start.Line, Column: r.Range.Start.Column, Byte: r.Range.Start.Byte},
+					End:      jsonPos{Line: r.Range.End.Line, Column: r.Range.End.Column, Byte: r.Range.End.Byte},
+				},
+			})
+		}
+		return out
+	}
+	out := jsonHealth{Deprecated: refs(h.Deprecated), MissingRequired: refs(h.MissingRequired)}
+	for _, f := range h.Features {
+		out.Features = append(out.Features, jsonFeatureUse{Provider: f.Provider, Feature: f.Feature, Count: f.Count})
+	}
+	return out
+}
+
+// printHealth writes the report as sections of "file:line: address path"
+// lines, followed by a table of the features used, if any.
+func printHealth(h *validate.Health) error {
+	for _, section := range []struct {
+		title string
+		refs  []validate.Reference
+	}{
+		{"Deprecated", h.Deprecated},
+		{"Missing required", h.MissingRequired},
+	} {
+		fmt.Printf("%s: %d\n", section.title, len(section.refs))
+		for _, r := range section.refs {
+			fmt.Printf("  %s:%d: %s %s\n", r.Range.Filename, r.Range.Start.Line, blockAddress(r), strings.Join(r.Path, "."))
+		}
+	}
+	if len(h.Features) == 0 {
+		return nil
+	}
+	fmt.Println("Features:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PROVIDER\tFEATURE\tCOUNT")
+	for _, f := range h.Features {
+		fmt.Fprintf(w, "  %s\t%s\t%d\n", f.Provider, f.Feature, f.Count)
+	}
+	return w.Flush()
+}
+
+// blockAddress returns the Terraform address of the block a reference is
+// in or to, such as data.aws_ami.ubuntu.
+func blockAddress(r validate.Reference) string {
+	addr := r.Type + "." + r.Name
+	if r.Kind != validate.BlockKindResource {
+		addr = string(r.Kind) + "." + addr
+	}
+	return addr
+}
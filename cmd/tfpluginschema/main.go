@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	goversion "github.com/hashicorp/go-version"
 	tfjson "github.com/hashicorp/terraform-json"
 	cli "github.com/urfave/cli/v3"
 
@@ -20,29 +27,35 @@ var version = "dev"
 func main() {
 	cmd := buildRootCommand()
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Exit(reportError(os.Stderr, err, cmd.Bool("json-errors")))
 	}
 }
 
 // buildRootCommand constructs the full CLI command tree.
 func buildRootCommand() *cli.Command {
-	return &cli.Command{
-		Name:    "tfpluginschema",
-		Usage:   "Query Terraform/OpenTofu provider schemas from the registry",
-		Version: version,
+	cmd := &cli.Command{
+		Name:                  "tfpluginschema",
+		Usage:                 "Query Terraform/OpenTofu provider schemas from the registry",
+		Version:               version,
+		EnableShellCompletion: true,
+		ConfigureShellCompletionCommand: func(c *cli.Command) {
+			c.Hidden = false
+		},
+		Before: loadConfigFile,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "namespace",
-				Aliases:  []string{"ns"},
-				Usage:    "Provider namespace (e.g. hashicorp, Azure)",
-				Required: true,
+				Name:  "config",
+				Usage: "Configuration file (default: ./" + tfpluginschema.ConfigFileName + ", then the user config directory)",
+			},
+			&cli.StringFlag{
+				Name:    "namespace",
+				Aliases: []string{"ns"},
+				Usage:   "Provider namespace (e.g. hashicorp, Azure); defaults to the config file's default_namespace, then hashicorp",
 			},
 			&cli.StringFlag{
-				Name:     "name",
-				Aliases:  []string{"n"},
-				Usage:    "Provider name (e.g. aws, azapi)",
-				Required: true,
+				Name:    "name",
+				Aliases: []string{"n"},
+				Usage:   "Provider name (e.g. aws, azapi); required by provider queries",
 			},
 			&cli.StringFlag{
 				Name:    "version-constraint",
@@ -52,22 +65,171 @@ func buildRootCommand() *cli.Command {
 			&cli.StringFlag{
 				Name:    "registry",
 				Aliases: []string{"r"},
-				Usage:   "Registry type: opentofu (default) or terraform",
+				Usage:   "Registry: opentofu (default), terraform or a private registry host such as app.terraform.io; overrides the configuration file",
 				Value:   "opentofu",
+				Sources: cli.EnvVars(tfpluginschema.EnvRegistry),
+				Validator: func(v string) error {
+					_, err := tfpluginschema.ParseRegistryType(v)
+					return err
+				},
 			},
 			&cli.StringFlag{
 				Name:    "cache-dir",
 				Usage:   "Directory used to cache downloaded providers (overrides $" + tfpluginschema.EnvCacheDir + ")",
 				Sources: cli.EnvVars(tfpluginschema.EnvCacheDir),
 			},
+			&cli.StringFlag{
+				Name:  "schema-bundle",
+				Usage: "Directory of pre-generated provider schemas to serve before executing providers (see provider bundle)",
+			},
+			&cli.StringFlag{
+				Name:  "schema-bundle-key",
+				Usage: "PEM file holding the Ed25519 public key the --schema-bundle manifest must be signed with; an unverified bundle is not used",
+			},
+			&cli.StringSliceFlag{
+				Name:  "schema-json",
+				Usage: "Output of \"terraform providers schema -json\" to serve schemas from, for any version of the providers in it (repeatable)",
+			},
+			&cli.StringFlag{
+				Name:  "snapshot",
+				Usage: "File to restore the in-memory caches from at start and save them to on exit, for warm starts in CI",
+			},
+			&cli.StringFlag{
+				Name:  "schema-compression",
+				Usage: "Compress written snapshots and schema bundle files with zstd: none, fastest, default, better or best",
+				Value: tfpluginschema.CompressionNone.String(),
+				Validator: func(v string) error {
+					_, err := tfpluginschema.ParseCompressionLevel(v)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "no-descriptions",
+				Usage: "Omit descriptions from printed schemas, for consumers that only need their structure",
+			},
+			&cli.BoolFlag{
+				Name:  "plain-descriptions",
+				Usage: "Render markdown descriptions in printed schemas as plain text",
+			},
+			&cli.BoolFlag{
+				Name:  "nested-object-types",
+				Usage: "Print object-typed attributes as nested attributes, as protocol v6 providers describe them",
+			},
 			&cli.BoolFlag{
 				Name:  "force-fetch",
 				Usage: "Always download the provider, bypassing the local cache",
 			},
+			&cli.BoolFlag{
+				Name:    "offline",
+				Usage:   "Serve only from the local cache and never access the network",
+				Sources: cli.EnvVars(tfpluginschema.EnvOffline),
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Usage:   "Maximum duration of each HTTP request, including downloads (e.g. 30s; 0 for none)",
+				Sources: cli.EnvVars(tfpluginschema.EnvTimeout),
+			},
+			&cli.IntFlag{
+				Name:  "max-redirects",
+				Usage: "Maximum number of redirects followed by each HTTP request (0 keeps the default of 10)",
+			},
+			&cli.StringFlag{
+				Name:    "log-level",
+				Usage:   "Log level for library diagnostics on stderr: debug, info, warn or error",
+				Value:   "error",
+				Sources: cli.EnvVars(tfpluginschema.EnvLogLevel),
+				Validator: func(v string) error {
+					_, err := parseLogLevel(v)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Usage: "Format of the logs on stderr: text or json",
+				Value: "text",
+				Validator: func(v string) error {
+					if v != "text" && v != "json" {
+						return fmt.Errorf("invalid log format %q: expected text or json", v)
+					}
+					return nil
+				},
+			},
+			&cli.StringSliceFlag{
+				Name:  "log-attr",
+				Usage: "Attribute added to every log line, as 'key=value' (repeatable)",
+				Validator: func(values []string) error {
+					_, err := parseLogAttrs(values)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "log-summary",
+				Usage: "Log one summary line per provider, with message counts, instead of its info lines",
+			},
 			&cli.BoolFlag{
 				Name:  "quiet",
 				Usage: "Suppress cache hit/miss status messages on stderr",
 			},
+			&cli.BoolFlag{
+				Name:  "json-errors",
+				Usage: "Write errors to stderr as JSON objects with a stable code",
+			},
+			&cli.BoolFlag{
+				Name:  "registry-compat",
+				Usage: "Work around proxying registries (Artifactory, Nexus): resolve relative download URLs and send registry headers to same-host downloads",
+			},
+			&cli.BoolFlag{
+				Name:  "content-store",
+				Usage: "Store provider binaries once by SHA-256 and verify them before each execution",
+			},
+			&cli.BoolFlag{
+				Name:  "verify-cache",
+				Usage: "Re-hash cached provider binaries before each execution and download corrupted ones again",
+			},
+			&cli.StringFlag{
+				Name:  "audit-log",
+				Usage: "Append a JSON line to this file for every provider binary executed",
+			},
+			&cli.StringFlag{
+				Name:  "audit-tag",
+				Usage: "Tag recorded in every audit log entry, such as a CI job identifier",
+			},
+			&cli.IntFlag{
+				Name:  "grpc-max-recv-msg-size",
+				Usage: "Largest gRPC message in bytes accepted from the provider plugin (0 keeps the default)",
+			},
+			&cli.StringSliceFlag{
+				Name:  "header",
+				Usage: "Extra header sent with registry API requests, as 'Name: value' (repeatable)",
+				Validator: func(values []string) error {
+					_, err := parseHeaders(values)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "verify-gpg",
+				Usage: "Verify the GPG signatures of provider checksum lists with the keys the registry reports before extracting them",
+			},
+			&cli.StringFlag{
+				Name:  "verify-sigstore",
+				Usage: "Verify the Sigstore bundles of providers that publish them with cosign before extracting them; the value is a regular expression the signing certificate's identity must match",
+			},
+			&cli.BoolFlag{
+				Name:  "require-verification",
+				Usage: "Refuse to execute providers that passed no verification method; implies --verify-gpg",
+			},
+			&cli.BoolFlag{
+				Name:  "strict-versions",
+				Usage: "Fail when a registry lists an entry that is not a valid version, rather than skipping it",
+			},
+			&cli.StringSliceFlag{
+				Name:  "endpoint-override",
+				Usage: "Send requests for a registry host to another base URL, as 'host=url' (repeatable)",
+				Validator: func(values []string) error {
+					_, err := parseEndpointOverrides(values)
+					return err
+				},
+			},
 		},
 		Commands: []*cli.Command{
 			providerCommand(),
@@ -76,49 +238,146 @@ func buildRootCommand() *cli.Command {
 			functionCommand(),
 			ephemeralCommand(),
 			versionCommand(),
+			moduleCommand(),
+			schemaCommand(),
+			docCommand(),
+			validateCommand(),
+			healthCommand(),
+			cacheCommand(),
+			doctorCommand(),
+			warmCommand(),
+			compareCommand(),
+			snapshotCommand(),
+			exportBundleCommand(),
+			importBundleCommand(),
+			embedCommand(),
 		},
 	}
+	configureCommands(cmd)
+	return cmd
+}
+
+// configureCommands applies the settings shared by every command in the tree:
+// completion of provider flag values, and reporting flag errors as usage
+// errors.
+func configureCommands(cmd *cli.Command) {
+	cmd.ShellComplete = completeFlagValues
+	cmd.OnUsageError = onUsageError
+	for _, sub := range cmd.Commands {
+		configureCommands(sub)
+	}
+}
+
+// requireProvider is a Before hook for commands that query a provider. The
+// --name flag is not marked Required on the root command because commands
+// such as "cache" do not need it. Without --namespace, the configuration
+// file's default_namespace or else the Server's default, hashicorp, is used.
+func requireProvider(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	if cmd.String("name") == "" {
+		return ctx, usageErrorf("required flag %q not set", "name")
+	}
+	return ctx, nil
 }
 
 // requestFromCmd builds a tfpluginschema.Request from the CLI flags.
 func requestFromCmd(cmd *cli.Command) tfpluginschema.Request {
 	return tfpluginschema.Request{
-		Namespace:    cmd.String("namespace"),
+		Namespace:    namespaceFromCmd(cmd),
 		Name:         cmd.String("name"),
 		Version:      cmd.String("version-constraint"),
-		RegistryType: registryTypeFromString(cmd.String("registry")),
+		RegistryType: registryFromCmd(cmd),
 	}
 }
 
 // versionsRequestFromCmd builds a tfpluginschema.VersionsRequest from the CLI flags.
 func versionsRequestFromCmd(cmd *cli.Command) tfpluginschema.VersionsRequest {
 	return tfpluginschema.VersionsRequest{
-		Namespace:    cmd.String("namespace"),
+		Namespace:    namespaceFromCmd(cmd),
 		Name:         cmd.String("name"),
-		RegistryType: registryTypeFromString(cmd.String("registry")),
+		RegistryType: registryFromCmd(cmd),
 	}
 }
 
-// registryTypeFromString converts a string to a RegistryType.
+// schemaOptions returns the per-call schema options selected by the flags.
+func schemaOptions(cmd *cli.Command) []tfpluginschema.SchemaOption {
+	var opts []tfpluginschema.SchemaOption
+	if cmd.Bool("no-descriptions") {
+		opts = append(opts, tfpluginschema.WithoutDescriptions())
+	}
+	if cmd.Bool("plain-descriptions") {
+		opts = append(opts, tfpluginschema.WithPlaintextDescriptions())
+	}
+	if cmd.Bool("nested-object-types") {
+		opts = append(opts, tfpluginschema.WithNestedObjectTypes())
+	}
+	return opts
+}
+
+// registryTypeFromString converts a string to a RegistryType, falling back
+// to the OpenTofu registry for values rejected by ParseRegistryType.
 func registryTypeFromString(s string) tfpluginschema.RegistryType {
-	switch strings.ToLower(s) {
-	case "terraform":
-		return tfpluginschema.RegistryTypeTerraform
-	default:
+	r, err := tfpluginschema.ParseRegistryType(s)
+	if err != nil {
 		return tfpluginschema.RegistryTypeOpenTofu
 	}
+	return r
 }
 
-// newServer creates a new tfpluginschema.Server with minimal logging and
-// configures it from the CLI flags (cache dir, force fetch, status reporting).
+// newServer creates a new tfpluginschema.Server logging at --log-level and
+// configures it from the configuration file and the CLI flags (cache dir,
+// force fetch, status reporting). Flags take precedence over the file.
 func newServer(cmd *cli.Command) *tfpluginschema.Server {
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: slog.LevelError,
-	}))
+	// --log-level was checked by the flag's Validator.
+	level, _ := parseLogLevel(cmd.String("log-level"))
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	if cmd.String("log-format") == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	}
+	logger := slog.New(handler)
+	// --log-attr values were checked by the flag's Validator.
+	logAttrs, _ := parseLogAttrs(cmd.StringSlice("log-attr"))
 
-	opts := []tfpluginschema.ServerOption{
+	// --header values were checked by the flag's Validator.
+	headers, _ := parseHeaders(cmd.StringSlice("header"))
+	// --endpoint-override values were checked by the flag's Validator.
+	overrides, _ := parseEndpointOverrides(cmd.StringSlice("endpoint-override"))
+	// --schema-compression was checked by the flag's Validator.
+	compression, _ := tfpluginschema.ParseCompressionLevel(cmd.String("schema-compression"))
+
+	opts := slices.Clone(configFromCmd(cmd).serverOptions)
+	opts = append(opts,
 		tfpluginschema.WithCacheDir(cmd.String("cache-dir")),
 		tfpluginschema.WithForceFetch(cmd.Bool("force-fetch")),
+		tfpluginschema.WithOffline(cmd.Bool("offline")),
+		tfpluginschema.WithTimeout(cmd.Duration("timeout")),
+		tfpluginschema.WithMaxRedirects(cmd.Int("max-redirects")),
+		tfpluginschema.WithUserAgent("tfpluginschema/"+version),
+		tfpluginschema.WithTerraformCredentials(true),
+		tfpluginschema.WithRegistryCompat(cmd.Bool("registry-compat")),
+		tfpluginschema.WithContentStore(cmd.Bool("content-store")),
+		tfpluginschema.WithIntegrityCheck(cmd.Bool("verify-cache")),
+		tfpluginschema.WithAuditLog(cmd.String("audit-log")),
+		tfpluginschema.WithAuditTag(cmd.String("audit-tag")),
+		tfpluginschema.WithRequestHeaders(headers),
+		tfpluginschema.WithGRPCMaxRecvMsgSize(cmd.Int("grpc-max-recv-msg-size")),
+		tfpluginschema.WithLogAttrs(logAttrs...),
+		tfpluginschema.WithLogSummaries(cmd.Bool("log-summary")),
+		tfpluginschema.WithSkipInvalidVersions(!cmd.Bool("strict-versions")),
+		tfpluginschema.WithGitHubToken(cmd.String("github-token")),
+		tfpluginschema.WithRequireVerification(cmd.Bool("require-verification")),
+		tfpluginschema.WithSchemaCompression(compression),
+	)
+	if cmd.Bool("verify-gpg") || cmd.Bool("require-verification") {
+		opts = append(opts, tfpluginschema.WithGPGVerification())
+	}
+	if identity := cmd.String("verify-sigstore"); identity != "" {
+		opts = append(opts, tfpluginschema.WithSigstoreVerification(tfpluginschema.SigstoreOptions{CertificateIdentityRegexp: identity}))
+	}
+	for _, o := range overrides {
+		opts = append(opts, tfpluginschema.WithEndpointOverride(o[0], o[1]))
+	}
+	if dir := cmd.String("schema-bundle"); dir != "" {
+		opts = append(opts, schemaBundleOptions(dir, cmd.String("schema-bundle-key"))...)
 	}
 	if !cmd.Bool("quiet") {
 		opts = append(opts, tfpluginschema.WithCacheStatusFunc(func(req tfpluginschema.Request, status tfpluginschema.CacheStatus) {
@@ -130,7 +389,93 @@ func newServer(cmd *cli.Command) *tfpluginschema.Server {
 			}
 		}))
 	}
-	return tfpluginschema.NewServer(logger, opts...)
+	s := tfpluginschema.NewServer(logger, opts...)
+	restoreSnapshot(cmd, s)
+	loadSchemaJSON(cmd, s)
+	return s
+}
+
+// parseLogLevel converts a level name such as "debug" into a slog.Level.
+func parseLogLevel(v string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(v)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", v)
+	}
+	return level, nil
+}
+
+// parseLogAttrs converts "key=value" strings into string attributes.
+func parseLogAttrs(values []string) ([]slog.Attr, error) {
+	attrs := make([]slog.Attr, 0, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid log attribute %q: expected 'key=value'", v)
+		}
+		attrs = append(attrs, slog.String(strings.TrimSpace(key), value))
+	}
+	return attrs, nil
+}
+
+// parseHeaders converts "Name: value" strings into an http.Header.
+func parseHeaders(values []string) (http.Header, error) {
+	headers := make(http.Header, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q: expected 'Name: value'", v)
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// parseEndpointOverrides converts "host=url" strings into host and base URL
+// pairs.
+func parseEndpointOverrides(values []string) ([][2]string, error) {
+	overrides := make([][2]string, 0, len(values))
+	for _, v := range values {
+		host, baseURL, ok := strings.Cut(v, "=")
+		host = strings.TrimSpace(host)
+		if !ok || host == "" || strings.TrimSpace(baseURL) == "" {
+			return nil, fmt.Errorf("invalid endpoint override %q: expected 'host=url'", v)
+		}
+		overrides = append(overrides, [2]string{host, strings.TrimSpace(baseURL)})
+	}
+	return overrides, nil
+}
+
+// listFlags returns the filtering and paging flags of the list commands.
+func listFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "prefix",
+			Usage: "List only names starting with this prefix",
+		},
+		&cli.StringFlag{
+			Name:  "regex",
+			Usage: "List only names matching this regular expression",
+		},
+		&cli.IntFlag{
+			Name:  "offset",
+			Usage: "Skip this many matching names",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "List at most this many names (0 for all)",
+		},
+	}
+}
+
+// listOptionsFromCmd builds a tfpluginschema.ListOptions from the list flags.
+func listOptionsFromCmd(cmd *cli.Command) tfpluginschema.ListOptions {
+	return tfpluginschema.ListOptions{
+		Prefix: cmd.String("prefix"),
+		Regex:  cmd.String("regex"),
+		Offset: cmd.Int("offset"),
+		Limit:  cmd.Int("limit"),
+	}
 }
 
 // printJSON marshals v as indented JSON and writes it to stdout.
@@ -140,6 +485,30 @@ func printJSON(v any) error {
 	return enc.Encode(v)
 }
 
+// printFeatureMatrix writes one row per feature of m to stdout.
+func printFeatureMatrix(m tfpluginschema.FeatureMatrix) error {
+	protocol := "unknown"
+	if m.Protocol != 0 {
+		protocol = strconv.Itoa(m.Protocol)
+	}
+	fmt.Printf("Protocol: %s\n", protocol)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FEATURE\tSUPPORT\tCOUNT")
+	for _, f := range []struct {
+		name   string
+		status tfpluginschema.FeatureStatus
+	}{
+		{"functions", m.Functions},
+		{"ephemeral resources", m.EphemeralResources},
+		{"resource identities", m.ResourceIdentities},
+		{"actions", m.Actions},
+		{"write-only attributes", m.WriteOnlyAttributes},
+	} {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", f.name, f.status.Support, f.status.Count)
+	}
+	return w.Flush()
+}
+
 // printList writes each string in items to stdout, one per line.
 func printList(items []string) {
 	for _, item := range items {
@@ -151,24 +520,136 @@ func printList(items []string) {
 
 func providerCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "provider",
-		Usage: "Query the provider configuration schema",
+		Name:   "provider",
+		Usage:  "Query the provider configuration schema",
+		Before: requireProvider,
 		Commands: []*cli.Command{
 			{
 				Name:  "schema",
 				Usage: "Get the provider configuration schema",
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 
 					req := requestFromCmd(cmd)
-					schema, err := s.GetProviderSchema(req)
+					schema, err := s.GetProviderSchema(req, schemaOptions(cmd)...)
 					if err != nil {
 						return err
 					}
 					return printJSON(schema)
 				},
 			},
+			{
+				Name:        "changelog",
+				Usage:       "Print the release notes of the provider version",
+				Description: "The notes come from the GitHub release of the version or the CHANGELOG.md at its tag, in the provider's source repository.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "github-token",
+						Usage:   "Token for the GitHub API",
+						Sources: cli.EnvVars("GITHUB_TOKEN"),
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the version, notes and URL as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					entry, err := s.GetChangelog(requestFromCmd(cmd))
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("json") {
+						return printJSON(map[string]string{"version": entry.Version, "notes": entry.Notes, "url": entry.URL})
+					}
+					fmt.Println(entry.Notes)
+					fmt.Println()
+					fmt.Println(entry.Reference())
+					return nil
+				},
+			},
+			{
+				Name:        "completion-index",
+				Usage:       "Print the provider's completion index as compact JSON, for editor plugins",
+				Description: "The index holds type and function names as tries and the attributes and nested blocks of every block, without descriptions.",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					index, err := s.GetCompletionIndex(requestFromCmd(cmd))
+					if err != nil {
+						return err
+					}
+					return json.NewEncoder(os.Stdout).Encode(index)
+				},
+			},
+			{
+				Name:  "features",
+				Usage: "Report which optional protocol features the provider supports",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the feature matrix as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					m, err := s.GetFeatureSupport(requestFromCmd(cmd))
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("json") {
+						return printJSON(m)
+					}
+					return printFeatureMatrix(m)
+				},
+			},
+			{
+				Name:        "bundle",
+				Usage:       "Write the provider's full schema into a schema bundle directory",
+				Description: "The directory can then be passed to --schema-bundle where providers cannot be executed.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "output-dir",
+						Aliases:  []string{"o"},
+						Usage:    "Schema bundle directory to write to",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "signing-key",
+						Usage: "PEM file holding the Ed25519 private key to sign the bundle's manifest with, for --schema-bundle-key",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					var key ed25519.PrivateKey
+					if path := cmd.String("signing-key"); path != "" {
+						var err error
+						if key, err = readSigningKey(path); err != nil {
+							return usageErrorf("%v", err)
+						}
+					}
+
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					path, err := s.WriteSchemaBundle(requestFromCmd(cmd), cmd.String("output-dir"))
+					if err != nil {
+						return err
+					}
+					if key != nil {
+						if _, err := tfpluginschema.SignSchemaBundle(cmd.String("output-dir"), key); err != nil {
+							return err
+						}
+					}
+					fmt.Println(path)
+					return nil
+				},
+			},
 		},
 	}
 }
@@ -177,8 +658,9 @@ func providerCommand() *cli.Command {
 
 func resourceCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "resource",
-		Usage: "Query resource schemas",
+		Name:   "resource",
+		Usage:  "Query resource schemas",
+		Before: requireProvider,
 		Commands: []*cli.Command{
 			{
 				Name:      "schema",
@@ -187,28 +669,28 @@ func resourceCommand() *cli.Command {
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					args := cmd.Args().Slice()
 					if len(args) > 1 {
-						return fmt.Errorf("expected at most 1 resource name, got %d", len(args))
+						return usageErrorf("expected at most 1 resource name, got %d", len(args))
 					}
 
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 					req := requestFromCmd(cmd)
 
 					if len(args) == 1 {
-						schema, err := s.GetResourceSchema(req, args[0])
+						schema, err := s.GetResourceSchema(req, args[0], schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
 						return printJSON(schema)
 					}
 
-					names, err := s.ListResources(req)
+					names, err := s.List(req, tfpluginschema.KindResource, listOptionsFromCmd(cmd))
 					if err != nil {
 						return err
 					}
 					all := make(map[string]*tfjson.Schema, len(names))
 					for _, n := range names {
-						sc, err := s.GetResourceSchema(req, n)
+						sc, err := s.GetResourceSchema(req, n, schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
@@ -219,10 +701,11 @@ func resourceCommand() *cli.Command {
 			},
 			{
 				Name:  "list",
-				Usage: "List all resource names",
+				Usage: "List resource names",
+				Flags: listFlags(),
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 
 					req := requestFromCmd(cmd)
 					resources, err := s.ListResources(req)
@@ -233,6 +716,68 @@ func resourceCommand() *cli.Command {
 					return nil
 				},
 			},
+			{
+				Name:  "write-only",
+				Usage: "List write-only attributes, whose values never reach state, as <resource>.<path>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the attributes as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					attrs, err := s.ListWriteOnlyAttributes(requestFromCmd(cmd))
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("json") {
+						if attrs == nil {
+							attrs = []tfpluginschema.WriteOnlyAttribute{}
+						}
+						return printJSON(attrs)
+					}
+					for _, a := range attrs {
+						fmt.Println(a)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "allowed-values",
+				Usage:     "List the allowed values of a resource's attributes, inferred from its descriptions and documentation page",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the allowed values as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					if cmd.NArg() != 1 {
+						return usageErrorf("expected 1 resource name, got %d", cmd.NArg())
+					}
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					e, err := s.GetExtendedResourceSchema(requestFromCmd(cmd), cmd.Args().First(), schemaOptions(cmd)...)
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("json") {
+						if e.AllowedValues == nil {
+							e.AllowedValues = []tfpluginschema.AllowedValues{}
+						}
+						return printJSON(e.AllowedValues)
+					}
+					for _, av := range e.AllowedValues {
+						fmt.Printf("%s: %s (%s)\n", av.Path, strings.Join(av.Values, ", "), av.Source)
+					}
+					return nil
+				},
+			},
 		},
 	}
 }
@@ -241,8 +786,9 @@ func resourceCommand() *cli.Command {
 
 func datasourceCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "datasource",
-		Usage: "Query data source schemas",
+		Name:   "datasource",
+		Usage:  "Query data source schemas",
+		Before: requireProvider,
 		Commands: []*cli.Command{
 			{
 				Name:      "schema",
@@ -251,28 +797,28 @@ func datasourceCommand() *cli.Command {
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					args := cmd.Args().Slice()
 					if len(args) > 1 {
-						return fmt.Errorf("expected at most 1 data source name, got %d", len(args))
+						return usageErrorf("expected at most 1 data source name, got %d", len(args))
 					}
 
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 					req := requestFromCmd(cmd)
 
 					if len(args) == 1 {
-						schema, err := s.GetDataSourceSchema(req, args[0])
+						schema, err := s.GetDataSourceSchema(req, args[0], schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
 						return printJSON(schema)
 					}
 
-					names, err := s.ListDataSources(req)
+					names, err := s.List(req, tfpluginschema.KindDataSource, listOptionsFromCmd(cmd))
 					if err != nil {
 						return err
 					}
 					all := make(map[string]*tfjson.Schema, len(names))
 					for _, n := range names {
-						sc, err := s.GetDataSourceSchema(req, n)
+						sc, err := s.GetDataSourceSchema(req, n, schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
@@ -283,10 +829,11 @@ func datasourceCommand() *cli.Command {
 			},
 			{
 				Name:  "list",
-				Usage: "List all data source names",
+				Usage: "List data source names",
+				Flags: listFlags(),
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 
 					req := requestFromCmd(cmd)
 					dataSources, err := s.ListDataSources(req)
@@ -305,8 +852,10 @@ func datasourceCommand() *cli.Command {
 
 func functionCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "function",
-		Usage: "Query provider function schemas",
+		Name:    "function",
+		Aliases: []string{"functions"},
+		Usage:   "Query provider function schemas",
+		Before:  requireProvider,
 		Commands: []*cli.Command{
 			{
 				Name:      "schema",
@@ -315,28 +864,28 @@ func functionCommand() *cli.Command {
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					args := cmd.Args().Slice()
 					if len(args) > 1 {
-						return fmt.Errorf("expected at most 1 function name, got %d", len(args))
+						return usageErrorf("expected at most 1 function name, got %d", len(args))
 					}
 
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 					req := requestFromCmd(cmd)
 
 					if len(args) == 1 {
-						schema, err := s.GetFunctionSchema(req, args[0])
+						schema, err := s.GetFunctionSchema(req, args[0], schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
 						return printJSON(schema)
 					}
 
-					names, err := s.ListFunctions(req)
+					names, err := s.List(req, tfpluginschema.KindFunction, listOptionsFromCmd(cmd))
 					if err != nil {
 						return err
 					}
 					all := make(map[string]*tfjson.FunctionSignature, len(names))
 					for _, n := range names {
-						sc, err := s.GetFunctionSchema(req, n)
+						sc, err := s.GetFunctionSchema(req, n, schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
@@ -347,10 +896,11 @@ func functionCommand() *cli.Command {
 			},
 			{
 				Name:  "list",
-				Usage: "List all function names",
+				Usage: "List function names",
+				Flags: listFlags(),
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 
 					req := requestFromCmd(cmd)
 					functions, err := s.ListFunctions(req)
@@ -361,6 +911,7 @@ func functionCommand() *cli.Command {
 					return nil
 				},
 			},
+			functionDocCommand(),
 		},
 	}
 }
@@ -369,8 +920,9 @@ func functionCommand() *cli.Command {
 
 func ephemeralCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "ephemeral",
-		Usage: "Query ephemeral resource schemas",
+		Name:   "ephemeral",
+		Usage:  "Query ephemeral resource schemas",
+		Before: requireProvider,
 		Commands: []*cli.Command{
 			{
 				Name:      "schema",
@@ -379,28 +931,28 @@ func ephemeralCommand() *cli.Command {
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					args := cmd.Args().Slice()
 					if len(args) > 1 {
-						return fmt.Errorf("expected at most 1 ephemeral resource name, got %d", len(args))
+						return usageErrorf("expected at most 1 ephemeral resource name, got %d", len(args))
 					}
 
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 					req := requestFromCmd(cmd)
 
 					if len(args) == 1 {
-						schema, err := s.GetEphemeralResourceSchema(req, args[0])
+						schema, err := s.GetEphemeralResourceSchema(req, args[0], schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
 						return printJSON(schema)
 					}
 
-					names, err := s.ListEphemeralResources(req)
+					names, err := s.List(req, tfpluginschema.KindEphemeralResource, listOptionsFromCmd(cmd))
 					if err != nil {
 						return err
 					}
 					all := make(map[string]*tfjson.Schema, len(names))
 					for _, n := range names {
-						sc, err := s.GetEphemeralResourceSchema(req, n)
+						sc, err := s.GetEphemeralResourceSchema(req, n, schemaOptions(cmd)...)
 						if err != nil {
 							return err
 						}
@@ -411,10 +963,11 @@ func ephemeralCommand() *cli.Command {
 			},
 			{
 				Name:  "list",
-				Usage: "List all ephemeral resource names",
+				Usage: "List ephemeral resource names",
+				Flags: listFlags(),
 				Action: func(_ context.Context, cmd *cli.Command) error {
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 
 					req := requestFromCmd(cmd)
 					ephemeralResources, err := s.ListEphemeralResources(req)
@@ -433,18 +986,45 @@ func ephemeralCommand() *cli.Command {
 
 func versionCommand() *cli.Command {
 	return &cli.Command{
-		Name:  "version",
-		Usage: "Query available provider versions",
+		Name:   "version",
+		Usage:  "Query available provider versions",
+		Before: requireProvider,
 		Commands: []*cli.Command{
 			{
 				Name:  "list",
-				Usage: "List available versions for the provider",
+				Usage: "List available versions for the provider, filtered by --version-constraint if given",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "List only the newest N matching versions (0 for all)",
+					},
+					&cli.BoolFlag{
+						Name:  "detailed",
+						Usage: "Print every version's protocols, platforms, publication time and size, and why it was or was not selected",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the --detailed listing as JSON",
+					},
+				},
 				Action: func(_ context.Context, cmd *cli.Command) error {
+					var constraints goversion.Constraints
+					if vc := cmd.String("version-constraint"); vc != "" {
+						c, err := goversion.NewConstraint(vc)
+						if err != nil {
+							return usageErrorf("invalid version constraint %q: %v", vc, err)
+						}
+						constraints = c
+					}
+
 					s := newServer(cmd)
-					defer s.Cleanup()
+					defer closeServer(cmd, s)
 
 					req := versionsRequestFromCmd(cmd)
-					versions, err := s.GetAvailableVersions(req)
+					if cmd.Bool("detailed") || cmd.Bool("json") {
+						return printVersionDetails(s, req, constraints, cmd.Int("limit"), cmd.Bool("json"))
+					}
+					versions, err := s.GetAvailableVersionsMatching(req, constraints, tfpluginschema.WithVersionsLimit(cmd.Int("limit")))
 					if err != nil {
 						return err
 					}
@@ -457,3 +1037,73 @@ func versionCommand() *cli.Command {
 		},
 	}
 }
+
+// --- cache ---
+
+func cacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "Inspect the local provider cache",
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List cached providers with their size and last access time",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print entries as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					entries, err := s.CacheEntries()
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("json") {
+						return printJSON(entries)
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "REGISTRY\tPROVIDER\tVERSION\tPLATFORM\tSIZE\tLAST ACCESS")
+					for _, e := range entries {
+						fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\t%d\t%s\n",
+							e.Request.RegistryType, e.Request.Namespace, e.Request.Name, e.Request.Version,
+							e.Platform, e.Size, e.LastAccess.Format(time.RFC3339))
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:  "stats",
+				Usage: "Summarise the cache as JSON",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					stats, err := s.CacheStats()
+					if err != nil {
+						return err
+					}
+					return printJSON(stats)
+				},
+			},
+			{
+				Name:  "prune",
+				Usage: "Remove content-stored provider binaries no longer linked from the cache",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					removed, err := s.PruneContentStore()
+					if err != nil {
+						return err
+					}
+					fmt.Printf("removed %d unused provider binaries\n", removed)
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// --- module ---
+
+func moduleCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "module",
+		Usage: "Query the module registry",
+		Description: "SOURCE is a module registry address such as terraform-aws-modules/vpc/aws,\n" +
+			"optionally prefixed with a registry host. --version-constraint selects the version;\n" +
+			"without it the latest is used. --registry applies when SOURCE names no host.",
+		Commands: []*cli.Command{
+			{
+				Name:      "versions",
+				Usage:     "List the published versions of a module",
+				ArgsUsage: "SOURCE",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					m, err := moduleRequestFromCmd(cmd)
+					if err != nil {
+						return err
+					}
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					versions, err := s.GetModuleVersions(m)
+					if err != nil {
+						return err
+					}
+					for _, v := range versions {
+						fmt.Println(v.Original())
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "source",
+				Usage:     "Print the address of a module's package, as returned by the registry",
+				ArgsUsage: "SOURCE",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					m, err := moduleRequestFromCmd(cmd)
+					if err != nil {
+						return err
+					}
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					source, err := s.GetModuleSource(m)
+					if err != nil {
+						return err
+					}
+					fmt.Println(source)
+					return nil
+				},
+			},
+			{
+				Name:      "details",
+				Usage:     "Print a module's provider and module dependencies as JSON (Terraform registry only)",
+				ArgsUsage: "SOURCE",
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					m, err := moduleRequestFromCmd(cmd)
+					if err != nil {
+						return err
+					}
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					details, err := s.GetModuleDetails(m)
+					if err != nil {
+						return err
+					}
+					return printJSON(details)
+				},
+			},
+			{
+				Name:      "providers",
+				Usage:     "Resolve the providers required by a module and every module it calls",
+				ArgsUsage: "[module-dir]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the result as JSON",
+					},
+				},
+				Action: func(_ context.Context, cmd *cli.Command) error {
+					args := cmd.Args().Slice()
+					if len(args) > 1 {
+						return usageErrorf("expected at most 1 module directory, got %d", len(args))
+					}
+					dir := "."
+					if len(args) == 1 {
+						dir = args[0]
+					}
+					s := newServer(cmd)
+					defer closeServer(cmd, s)
+
+					result, err := s.ResolveModuleProviders(dir)
+					if err != nil {
+						return err
+					}
+					if cmd.Bool("json") {
+						return printJSON(result)
+					}
+					for _, source := range result.Unresolved {
+						fmt.Fprintf(os.Stderr, "warning: module %s not followed\n", source)
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "REGISTRY\tPROVIDER\tVERSION")
+					for _, p := range result.Providers {
+						fmt.Fprintf(w, "%s\t%s/%s\t%s\n", p.RegistryType, p.Namespace, p.Name, p.Version)
+					}
+					return w.Flush()
+				},
+			},
+		},
+	}
+}
+
+// moduleRequestFromCmd builds a tfpluginschema.ModuleRequest from the
+// SOURCE argument and the CLI flags.
+func moduleRequestFromCmd(cmd *cli.Command) (tfpluginschema.ModuleRequest, error) {
+	if cmd.Args().Len() != 1 {
+		return tfpluginschema.ModuleRequest{}, usageErrorf("expected 1 module source, got %d", cmd.Args().Len())
+	}
+	m, err := tfpluginschema.ParseModuleSource(cmd.Args().First())
+	if err != nil {
+		return m, usageErrorf("%v", err)
+	}
+	m.Version = cmd.String("version-constraint")
+	if m.RegistryType == "" {
+		m.RegistryType = registryFromCmd(cmd)
+	}
+	return m, nil
+}
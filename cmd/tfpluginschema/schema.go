@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+	"github.com/matt-FFFFFF/tfpluginschema/render"
+)
+
+// pickerPageSize is the number of matches the interactive picker shows at once.
+const pickerPageSize = 20
+
+// schemaKinds lists the schema kinds accepted by the schema command, in the
+// order they are offered by the picker.
+var schemaKinds = []string{
+	string(tfpluginschema.KindResource),
+	string(tfpluginschema.KindDataSource),
+	string(tfpluginschema.KindEphemeralResource),
+	string(tfpluginschema.KindFunction),
+}
+
+func schemaCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "schema",
+		Usage:     "Get the schema for one resource, data source, ephemeral resource or function",
+		ArgsUsage: "[kind/name]",
+		Description: "kind is one of " + strings.Join(schemaKinds, ", ") + ".\n" +
+			"With --interactive, pick the schema from the provider with a fuzzy filter instead.\n" +
+			"--format table or tree prints the attributes of a resource, data source or ephemeral resource\n" +
+			"for reading in a terminal, in color unless stdout is not a terminal or $NO_COLOR is set.",
+		Before: requireProvider,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "interactive",
+				Aliases: []string{"i"},
+				Usage:   "Pick the schema interactively with a fuzzy filter",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "Output format: json, table or tree",
+			},
+			&cli.BoolFlag{
+				Name:  "descriptions",
+				Usage: "Add a description column to table and tree output",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			interactive := cmd.Bool("interactive")
+			switch {
+			case len(args) > 1:
+				return usageErrorf("expected at most 1 schema, got %d", len(args))
+			case len(args) == 0 && !interactive:
+				return usageErrorf("expected a kind/name argument or --interactive")
+			case len(args) == 1 && interactive:
+				return usageErrorf("--interactive cannot be combined with a kind/name argument")
+			}
+			format := cmd.String("format")
+			if !slices.Contains([]string{"json", "table", "tree"}, format) {
+				return usageErrorf("unsupported format %q: expected json, table or tree", format)
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+			req := requestFromCmd(cmd)
+
+			var target string
+			if interactive {
+				items, err := schemaItems(s, req)
+				if err != nil {
+					return err
+				}
+				if target, err = pick(os.Stdin, os.Stderr, items); err != nil {
+					return err
+				}
+			} else {
+				target = args[0]
+			}
+
+			kind, name, ok := strings.Cut(target, "/")
+			if !ok || name == "" {
+				return usageErrorf("invalid schema %q: expected kind/name", target)
+			}
+			k, err := tfpluginschema.ParseKind(kind)
+			if err != nil || !slices.Contains(schemaKinds, string(k)) {
+				return usageErrorf("invalid schema kind %q: expected one of %s", kind, strings.Join(schemaKinds, ", "))
+			}
+			if k == tfpluginschema.KindFunction && format != "json" {
+				return usageErrorf("--format %s is not supported for functions", format)
+			}
+			entry, err := s.GetSchemaByKind(req, k, name, schemaOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			if entry.Function != nil {
+				return printJSON(entry.Function)
+			}
+			return printSchema(os.Stdout, entry.Schema, format, cmd.Bool("descriptions"))
+		},
+	}
+}
+
+// printSchema writes schema to w in the given format: json, or a table or
+// tree from the render package.
+func printSchema(w *os.File, schema *tfjson.Schema, format string, descriptions bool) error {
+	opts := render.Options{
+		Color:        os.Getenv("NO_COLOR") == "" && isTerminal(w),
+		Descriptions: descriptions,
+	}
+	switch format {
+	case "table":
+		return render.Table(w, schema, opts)
+	case "tree":
+		return render.Tree(w, schema, opts)
+	}
+	return printJSON(schema)
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// schemaItems returns every schema in the provider as a "kind/name" string.
+func schemaItems(s *tfpluginschema.Server, req tfpluginschema.Request) ([]string, error) {
+	lists := map[string]func(tfpluginschema.Request) ([]string, error){
+		"resource":   s.ListResources,
+		"datasource": s.ListDataSources,
+		"ephemeral":  s.ListEphemeralResources,
+		"function":   s.ListFunctions,
+	}
+	var items []string
+	for _, kind := range schemaKinds {
+		names, err := lists[kind](req)
+		if errors.Is(err, tfpluginschema.ErrNotSupportedByProvider) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			items = append(items, kind+"/"+n)
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("the provider has no schemas to pick from")
+	}
+	return items, nil
+}
+
+// pick runs a line-based fuzzy finder over items. Each line read from in is
+// either a filter query, which lists the best matches on out, or the number
+// of a listed match, which selects it. A query matching exactly one item
+// selects it directly.
+func pick(in io.Reader, out io.Writer, items []string) (string, error) {
+	scanner := bufio.NewScanner(in)
+	matches := fuzzyFilter("", items)
+	for {
+		shown := matches[:min(len(matches), pickerPageSize)]
+		for i, m := range shown {
+			fmt.Fprintf(out, "%3d) %s\n", i+1, m)
+		}
+		if more := len(matches) - len(shown); more > 0 {
+			fmt.Fprintf(out, "     ... %d more, type to filter\n", more)
+		}
+		fmt.Fprint(out, "filter or number> ")
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", errors.New("no schema selected")
+		}
+		line := strings.TrimSpace(scanner.Text())
+
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(shown) {
+			return shown[n-1], nil
+		}
+		matches = fuzzyFilter(line, items)
+		switch len(matches) {
+		case 0:
+			fmt.Fprintf(out, "no matches for %q\n", line)
+			matches = fuzzyFilter("", items)
+		case 1:
+			fmt.Fprintf(out, "%s\n", matches[0])
+			return matches[0], nil
+		}
+	}
+}
+
+// fuzzyFilter returns the items matching query, best match first. Items that
+// score equally keep their original order, as do all items for an empty query.
+func fuzzyFilter(query string, items []string) []string {
+	if query == "" {
+		return items
+	}
+	type scored struct {
+		item  string
+		score int
+	}
+	var matches []scored
+	for _, item := range items {
+		if score, ok := fuzzyScore(query, item); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b scored) int {
+		return b.score - a.score
+	})
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.item
+	}
+	return out
+}
+
+// fuzzyScore reports whether every rune of query appears in item, in order
+// and ignoring case, and scores the match. Runs of consecutive runes and
+// runes at the start of a word score higher, and shorter items are preferred.
+func fuzzyScore(query, item string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	r := []rune(strings.ToLower(item))
+	score := -len(r)
+	qi := 0
+	prevMatch := -2
+	for i := 0; i < len(r) && qi < len(q); i++ {
+		if r[i] != q[qi] {
+			continue
+		}
+		score += 10
+		if prevMatch == i-1 {
+			score += 15
+		}
+		if i == 0 || !unicode.IsLetter(r[i-1]) && !unicode.IsDigit(r[i-1]) {
+			score += 10
+		}
+		prevMatch = i
+		qi++
+	}
+	return score, qi == len(q)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("dbx", "example_db")
+	assert.False(t, ok, "every rune of the query must match")
+	_, ok = fuzzyScore("bd", "example_db")
+	assert.False(t, ok, "runes must match in order")
+
+	score, ok := fuzzyScore("", "example_db")
+	assert.True(t, ok)
+	assert.Equal(t, -len("example_db"), score, "an empty query only prefers shorter items")
+
+	score, ok = fuzzyScore("DB", "example_db")
+	assert.True(t, ok, "matching ignores case")
+	// d: 10 plus 10 for the word start; b: 10 plus 15 for the run.
+	assert.Equal(t, 45-len("example_db"), score)
+
+	consecutive, _ := fuzzyScore("ab", "xaby")
+	scattered, _ := fuzzyScore("ab", "xaxb")
+	assert.Greater(t, consecutive, scattered, "runs score higher")
+
+	wordStart, _ := fuzzyScore("b", "a_b")
+	midWord, _ := fuzzyScore("b", "aab")
+	assert.Greater(t, wordStart, midWord, "word starts score higher")
+
+	short, _ := fuzzyScore("db", "x_db")
+	long, _ := fuzzyScore("db", "xxxxxx_db")
+	assert.Greater(t, short, long, "shorter items score higher")
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"example_database", "example_vpc", "example_db", "other_db"}
+
+	assert.Equal(t, items, fuzzyFilter("", items), "an empty query keeps every item in order")
+	assert.Equal(t, []string{"other_db", "example_db", "example_database"}, fuzzyFilter("db", items))
+	assert.Equal(t, []string{"example_vpc"}, fuzzyFilter("VPC", items))
+	assert.Empty(t, fuzzyFilter("zzz", items))
+
+	// Items that score equally keep their original order.
+	ties := []string{"b_db", "a_db", "c_db"}
+	assert.Equal(t, ties, fuzzyFilter("db", ties))
+	assert.Equal(t, []string{"c_db", "a_db", "b_db"}, fuzzyFilter("db", []string{"c_db", "a_db", "b_db"}))
+}
+
+func TestPick(t *testing.T) {
+	items := []string{"example_database", "example_vpc", "example_db"}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"number", "2\n", "example_vpc"},
+		{"single match", "vpc\n", "example_vpc"},
+		{"number after filtering", "db\n2\n", "example_database"},
+		{"number out of range", "4\n0\n3\n", "example_db"},
+		{"no matches", "zzz\n1\n", "example_database"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := pick(strings.NewReader(tt.input), &out, items)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("number out of range filters", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := pick(strings.NewReader("4\n"), &out, items)
+		require.Error(t, err)
+		assert.Contains(t, out.String(), `no matches for "4"`)
+	})
+
+	t.Run("single match is echoed", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := pick(strings.NewReader("vpc\n"), &out, items)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(out.String(), "filter or number> example_vpc\n"), out.String())
+	})
+
+	t.Run("EOF", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := pick(strings.NewReader(""), &out, items)
+		assert.EqualError(t, err, "no schema selected")
+		assert.Equal(t, "  1) example_database\n  2) example_vpc\n  3) example_db\nfilter or number> \n", out.String())
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		many := make([]string, pickerPageSize+5)
+		for i := range many {
+			many[i] = fmt.Sprintf("example_%c", 'a'+i)
+		}
+		var out bytes.Buffer
+		_, err := pick(strings.NewReader(fmt.Sprintf("%d\n", pickerPageSize+1)), &out, many)
+		require.Error(t, err, "only the shown matches can be selected by number")
+		assert.Contains(t, out.String(), fmt.Sprintf("%3d) %s\n", pickerPageSize, many[pickerPageSize-1]))
+		assert.NotContains(t, out.String(), many[pickerPageSize]+"\n")
+		assert.Contains(t, out.String(), "... 5 more, type to filter")
+	})
+}
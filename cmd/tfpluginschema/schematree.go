@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	cli "github.com/urfave/cli/v3"
+)
+
+// --- snapshot ---
+
+func snapshotCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "snapshot",
+		Usage:     "Write one canonical JSON file per resource, data source and function of providers, for review in version control",
+		ArgsUsage: "SOURCE...",
+		Description: "Each SOURCE is a provider address with an optional version, such as hashicorp/azurerm@~>4.0.\n" +
+			"Files are written to <out>/<registry>/<namespace>/<name>/<version>/, and the directory of\n" +
+			"each provider is printed. Commit them, and the diff of a provider bump shows each schema\n" +
+			"change in its own file. Not to be confused with --snapshot, which persists the caches.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "out",
+				Aliases:  []string{"o"},
+				Usage:    "Directory to write the schema files to",
+				Required: true,
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() == 0 {
+				return usageErrorf("at least one provider SOURCE is required")
+			}
+			requests, err := requestsFromArgs(cmd)
+			if err != nil {
+				return err
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			for _, req := range requests {
+				dir, err := s.WriteSchemaTree(req, cmd.String("out"))
+				if err != nil {
+					return fmt.Errorf("%s: %w", req, err)
+				}
+				fmt.Println(dir)
+			}
+			return nil
+		},
+	}
+}
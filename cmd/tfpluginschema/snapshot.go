@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// restoreSnapshot loads the --snapshot file into s. A missing file is not
+// an error: the first invocation creates it. Any other failure only costs
+// the warm start, so it is reported and otherwise ignored.
+func restoreSnapshot(cmd *cli.Command, s *tfpluginschema.Server) {
+	path := cmd.String("snapshot")
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		defer f.Close()
+		err = s.RestoreSnapshot(f)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: ignoring snapshot %s: %v\n", path, err)
+	}
+}
+
+// loadSchemaJSON loads the --schema-json files into s. A file that cannot
+// be loaded is reported and skipped; its providers are then downloaded as
+// usual.
+func loadSchemaJSON(cmd *cli.Command, s *tfpluginschema.Server) {
+	for _, path := range cmd.StringSlice("schema-json") {
+		f, err := os.Open(path)
+		if err == nil {
+			err = s.LoadTerraformSchemaJSON(f)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: ignoring schema JSON %s: %v\n", path, err)
+		}
+	}
+}
+
+// closeServer writes the --snapshot file, if any, and cleans up s. The
+// snapshot is written to a temporary file first, so that an interrupted
+// write never leaves a truncated snapshot behind.
+func closeServer(cmd *cli.Command, s *tfpluginschema.Server) {
+	defer s.Cleanup()
+	path := cmd.String("snapshot")
+	if path == "" {
+		return
+	}
+	if err := writeSnapshot(s, path); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write snapshot %s: %v\n", path, err)
+	}
+}
+
+func writeSnapshot(s *tfpluginschema.Server, path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := s.Snapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
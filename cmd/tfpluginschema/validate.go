@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+	"github.com/matt-FFFFFF/tfpluginschema/validate"
+)
+
+func validateCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Usage:     "Statically validate a module's resource, data and ephemeral blocks against provider schemas",
+		ArgsUsage: "[module-dir]",
+		Description: "Providers are resolved from the module's required_providers, using each entry's\n" +
+			"version constraint, and from --registry unless the source names a registry host.\n" +
+			"No init, backend or credentials are needed. Diagnostics are written to stderr and\n" +
+			"the command exits with status 4 if there are any errors.\n\n" +
+			"With --bump, only the blocks of the bumped providers are checked, and only the\n" +
+			"problems the proposed versions would add are reported, answering whether the\n" +
+			"module survives the upgrade: tfpluginschema validate --bump 'azurerm=~> 5.0' .",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "bump",
+				Usage: "Report what breaks if a provider's version constraint becomes this, as 'local-name=constraint' (repeatable)",
+				Validator: func(values []string) error {
+					_, err := parseBumps(values)
+					return err
+				},
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			if len(args) > 1 {
+				return usageErrorf("expected at most 1 module directory, got %d", len(args))
+			}
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+
+			bumps, err := parseBumps(cmd.StringSlice("bump"))
+			if err != nil {
+				return err
+			}
+
+			m, diags := validate.LoadModule(dir)
+			if m != nil && !diags.HasErrors() {
+				s := newServer(cmd)
+				defer closeServer(cmd, s)
+				registry := registryFromCmd(cmd)
+				lookup := func(p validate.ProviderRequirement, kind validate.BlockKind, typ string) (*tfjson.Schema, error) {
+					return lookupBlockSchema(s, registry, p, kind, typ)
+				}
+				if len(bumps) > 0 {
+					diags = append(diags, m.CheckUpgrade(bumps, lookup)...)
+				} else {
+					diags = append(diags, m.Validate(lookup)...)
+				}
+			}
+
+			// With --json-errors, failing diagnostics are reported in the
+			// error object instead.
+			if !diags.HasErrors() || !cmd.Bool("json-errors") {
+				var files map[string]*hcl.File
+				if m != nil {
+					files = m.Files
+				}
+				w := hcl.NewDiagnosticTextWriter(os.Stderr, files, 0, false)
+				if err := w.WriteDiagnostics(diags); err != nil {
+					return err
+				}
+			}
+			if diags.HasErrors() {
+				return &validationError{dir: dir, diags: diags}
+			}
+			if len(bumps) > 0 {
+				fmt.Fprintf(os.Stderr, "%s is compatible with the bumped providers\n", dir)
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "%s is valid\n", dir)
+			return nil
+		},
+	}
+}
+
+// parseBumps parses the --bump values into proposed version constraints
+// keyed by provider local name.
+func parseBumps(values []string) (map[string]string, error) {
+	bumps := make(map[string]string, len(values))
+	for _, v := range values {
+		name, constraint, ok := strings.Cut(v, "=")
+		name, constraint = strings.TrimSpace(name), strings.TrimSpace(constraint)
+		if !ok || name == "" || constraint == "" {
+			return nil, usageErrorf("invalid bump %q: expected 'local-name=constraint'", v)
+		}
+		bumps[name] = constraint
+	}
+	return bumps, nil
+}
+
+// lookupBlockSchema fetches the schema for a module block from the registry.
+// The provider's type names are listed first, so that an unknown type is
+// reported as a nil schema rather than an error.
+func lookupBlockSchema(s *tfpluginschema.Server, registry tfpluginschema.RegistryType, p validate.ProviderRequirement, kind validate.BlockKind, typ string) (*tfjson.Schema, error) {
+	req, err := tfpluginschema.ParseProviderSource(p.Source)
+	if err != nil {
+		return nil, err
+	}
+	req.Version = p.Version
+	if req.RegistryType == "" {
+		req.RegistryType = registry
+	}
+
+	var list func(tfpluginschema.Request) ([]string, error)
+	var get func(tfpluginschema.Request, string, ...tfpluginschema.SchemaOption) (*tfjson.Schema, error)
+	switch kind {
+	case validate.BlockKindResource:
+		list, get = s.ListResources, s.GetResourceSchema
+	case validate.BlockKindDataSource:
+		list, get = s.ListDataSources, s.GetDataSourceSchema
+	case validate.BlockKindEphemeralResource:
+		list, get = s.ListEphemeralResources, s.GetEphemeralResourceSchema
+	default:
+		return nil, errors.New("unsupported block kind " + string(kind))
+	}
+
+	names, err := list(req)
+	if errors.Is(err, tfpluginschema.ErrNotSupportedByProvider) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(names, typ) {
+		return nil, nil
+	}
+	return get(req, typ)
+}
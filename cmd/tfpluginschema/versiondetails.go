@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// versionDetail is the JSON form of a tfpluginschema.VersionDetails, with
+// the outcome of version selection.
+type versionDetail struct {
+	Version   string   `json:"version"`
+	Protocols []string `json:"protocols"`
+	Platforms []string `json:"platforms"`
+	Published string   `json:"published,omitempty"`
+	Size      int64    `json:"size"`
+	Selected  bool     `json:"selected"`
+	Notes     []string `json:"notes,omitempty"`
+}
+
+// printVersionDetails lists the newest limit versions of the provider, or
+// all of them, with their details and a note saying why each was or was not
+// selected by constraints, as a table or as JSON.
+func printVersionDetails(s *tfpluginschema.Server, req tfpluginschema.VersionsRequest, constraints goversion.Constraints, limit int, asJSON bool) error {
+	all, err := s.GetAvailableVersions(req)
+	if err != nil {
+		return err
+	}
+	var selected *goversion.Version
+	if len(all) > 0 {
+		// No match leaves selected nil, noted on every version.
+		selected, _ = tfpluginschema.GetLatestVersionMatch(all, constraints)
+	}
+	details, err := s.GetVersionDetails(req, nil, tfpluginschema.WithVersionsLimit(limit), tfpluginschema.WithDownloadDetails())
+	if err != nil {
+		return err
+	}
+
+	platform := tfpluginschema.CurrentPlatform()
+	items := make([]versionDetail, len(details))
+	for i, d := range details {
+		item := versionDetail{
+			Version:   d.Version.Original(),
+			Protocols: d.Protocols,
+			Platforms: make([]string, len(d.Platforms)),
+			Size:      d.Size,
+			Selected:  selected != nil && d.Version.Equal(selected),
+		}
+		for j, p := range d.Platforms {
+			item.Platforms[j] = p.String()
+		}
+		if !d.Published.IsZero() {
+			item.Published = d.Published.UTC().Format(time.RFC3339)
+		}
+		switch {
+		case item.Selected:
+			item.Notes = append(item.Notes, "selected")
+		case constraints.Len() > 0 && !constraints.Check(d.Version):
+			item.Notes = append(item.Notes, "does not match "+constraints.String())
+		case selected != nil && d.Version.LessThan(selected):
+			item.Notes = append(item.Notes, "older than "+selected.Original())
+		}
+		if !d.SupportsPlatform(platform) {
+			item.Notes = append(item.Notes, "no "+platform.String()+" build")
+		}
+		if len(d.Protocols) > 0 && !slices.ContainsFunc(d.Protocols, supportedProtocol) {
+			item.Notes = append(item.Notes, "protocol "+strings.Join(d.Protocols, ", ")+" not supported")
+		}
+		items[i] = item
+	}
+
+	if asJSON {
+		return printJSON(items)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tPROTOCOLS\tPLATFORMS\tPUBLISHED\tSIZE\tNOTE")
+	for _, item := range items {
+		size := "-"
+		if item.Size >= 0 {
+			size = strconv.FormatInt(item.Size, 10)
+		}
+		published := item.Published
+		if published == "" {
+			published = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", item.Version, strings.Join(item.Protocols, ","), len(item.Platforms), published, size, strings.Join(item.Notes, "; "))
+	}
+	return w.Flush()
+}
+
+// supportedProtocol reports whether the plugin protocol version p, such as
+// "5.0", is one the library can talk to.
+func supportedProtocol(p string) bool {
+	major, _, _ := strings.Cut(strings.TrimSpace(p), ".")
+	return major == "5" || major == "6"
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	cli "github.com/urfave/cli/v3"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// --- warm ---
+
+// warmItem is the JSON form of a tfpluginschema.BatchItem.
+type warmItem struct {
+	Provider string                     `json:"provider"`
+	Version  string                     `json:"version,omitempty"`
+	Status   tfpluginschema.BatchStatus `json:"status"`
+	Error    string                     `json:"error,omitempty"`
+	Duration string                     `json:"duration"`
+	Bytes    int64                      `json:"bytes"`
+}
+
+func warmCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "warm",
+		Usage:     "Download providers and load their schemas into the cache",
+		ArgsUsage: "SOURCE...",
+		Description: "Each SOURCE is a provider address with an optional version, such as hashicorp/aws@~>5.0.\n" +
+			"--registry applies when SOURCE names no host. Without --continue-on-error the\n" +
+			"providers not yet started are skipped after the first failure.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "continue-on-error",
+				Usage: "Keep going after a provider fails",
+			},
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "Number of providers fetched at the same time",
+				Value: 1,
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the per-provider results as JSON",
+			},
+			&cli.StringFlag{
+				Name:  "report-json",
+				Usage: "Write a supply chain report of the fetched providers to this file as JSON",
+			},
+			&cli.StringFlag{
+				Name:  "report-markdown",
+				Usage: "Write a supply chain report of the fetched providers to this file as Markdown",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			if cmd.Args().Len() == 0 {
+				return usageErrorf("at least one provider SOURCE is required")
+			}
+			requests, err := requestsFromArgs(cmd)
+			if err != nil {
+				return err
+			}
+
+			s := newServer(cmd)
+			defer closeServer(cmd, s)
+
+			result, err := s.Warm(requests,
+				tfpluginschema.WithContinueOnError(cmd.Bool("continue-on-error")),
+				tfpluginschema.WithBatchConcurrency(cmd.Int("concurrency")))
+			if perr := printWarmResult(cmd.Bool("json"), result); perr != nil {
+				return perr
+			}
+			if rerr := writeSupplyChainReport(s, cmd.String("report-json"), cmd.String("report-markdown")); rerr != nil {
+				return rerr
+			}
+			return err
+		},
+	}
+}
+
+// printWarmResult writes one row per item of result to stdout, as a table
+// or as JSON.
+func printWarmResult(asJSON bool, result *tfpluginschema.BatchResult) error {
+	items := make([]warmItem, len(result.Items))
+	for i, item := range result.Items {
+		items[i] = warmItem{
+			Provider: item.Request.Namespace + "/" + item.Request.Name,
+			Version:  item.Version,
+			Status:   item.Status,
+			Duration: item.Duration.Round(time.Millisecond).String(),
+			Bytes:    item.Bytes,
+		}
+		if item.Err != nil {
+			items[i].Error = item.Err.Error()
+		}
+	}
+	if asJSON {
+		return printJSON(items)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tVERSION\tSTATUS\tDURATION\tBYTES")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", item.Provider, item.Version, item.Status, item.Duration, item.Bytes)
+	}
+	return w.Flush()
+}
+
+// writeSupplyChainReport writes the supply chain report of s to jsonPath
+// and markdownPath, skipping empty paths.
+func writeSupplyChainReport(s *tfpluginschema.Server, jsonPath, markdownPath string) error {
+	if jsonPath == "" && markdownPath == "" {
+		return nil
+	}
+	report := s.SupplyChainReport()
+	if jsonPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(jsonPath, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	if markdownPath != "" {
+		if err := os.WriteFile(markdownPath, []byte(report.Markdown()), 0o644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// WithRegistryCompat enables workarounds for proxying registries such as
+// Artifactory and Nexus. A relative download_url in the registry's download
+// metadata is resolved against the URL of that metadata. Provider archives
+// hosted on the same host as the registry API are downloaded with the
+// registry headers: those set with WithRequestHeaders (such as a Cookie)
+// and the host's API token. Archives on other hosts are still fetched
+// without them.
+func WithRegistryCompat(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.registryCompat = enabled
+	}
+}
+
+// resolveDownloadURL returns downloadURL, resolved against metadataURL when
+// it is relative and compatibility mode is enabled. In compatibility mode,
+// the registry host is also recorded so that downloads from it carry the
+// registry headers.
+func (s *Server) resolveDownloadURL(metadataURL, downloadURL string) (string, error) {
+	if !s.registryCompat {
+		return downloadURL, nil
+	}
+	base, err := url.Parse(metadataURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry URL %q: %w", metadataURL, err)
+	}
+	ref, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download URL %q from registry: %w", downloadURL, err)
+	}
+
+	s.mu.Lock()
+	s.compatHosts[strings.ToLower(base.Host)] = struct{}{}
+	s.mu.Unlock()
+
+	if ref.IsAbs() {
+		return downloadURL, nil
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// forwardsRegistryHeaders reports whether a download from u should carry
+// the registry headers, which is only the case in compatibility mode for
+// hosts whose registry API the Server has used.
+func (s *Server) forwardsRegistryHeaders(u *url.URL) bool {
+	if !s.registryCompat {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.compatHosts[strings.ToLower(u.Host)]
+	return ok
+}
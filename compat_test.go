@@ -0,0 +1,81 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compatRegistryClient serves download metadata with the given download_url
+// and records the headers of each request by path.
+func compatRegistryClient(t *testing.T, downloadURL string, seen map[string]http.Header) *http.Client {
+	return stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen[r.URL.Path] = r.Header.Clone()
+		if r.URL.Path == "/v1/providers/hashicorp/aws/5.0.0/download/"+runtime.GOOS+"/"+runtime.GOARCH {
+			fmt.Fprintf(w, `{"filename":"aws.zip","download_url":%q}`, downloadURL)
+			return
+		}
+		w.Header().Set("Content-Length", "1")
+	}))
+}
+
+func TestServer_RegistryCompat_RelativeDownloadURL(t *testing.T) {
+	seen := map[string]http.Header{}
+	s := NewServer(nil,
+		WithCacheDir(t.TempDir()),
+		WithHTTPClient(compatRegistryClient(t, "../../../../../../files/aws.zip", seen)),
+		WithRequestHeaders(http.Header{"Cookie": {"session=abc"}}),
+		WithRegistryToken("registry.opentofu.org", "secret"),
+		WithRegistryCompat(true),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.opentofu.org/v1/files/aws.zip", plan.URL)
+
+	download := seen["/v1/files/aws.zip"]
+	require.NotNil(t, download)
+	assert.Equal(t, "session=abc", download.Get("Cookie"))
+	assert.Equal(t, "Bearer secret", download.Get("Authorization"))
+}
+
+func TestServer_RegistryCompat_OtherHost(t *testing.T) {
+	seen := map[string]http.Header{}
+	s := NewServer(nil,
+		WithCacheDir(t.TempDir()),
+		WithHTTPClient(compatRegistryClient(t, "https://releases.example.com/aws.zip", seen)),
+		WithRequestHeaders(http.Header{"Cookie": {"session=abc"}}),
+		WithRegistryCompat(true),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://releases.example.com/aws.zip", plan.URL)
+
+	download := seen["/aws.zip"]
+	require.NotNil(t, download)
+	assert.Empty(t, download.Get("Cookie"), "registry headers must not leak to other hosts")
+}
+
+func TestServer_RegistryCompat_Disabled(t *testing.T) {
+	seen := map[string]http.Header{}
+	s := NewServer(nil,
+		WithCacheDir(t.TempDir()),
+		WithHTTPClient(compatRegistryClient(t, "https://registry.opentofu.org/files/aws.zip", seen)),
+		WithRequestHeaders(http.Header{"Cookie": {"session=abc"}}),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+	require.NoError(t, err)
+
+	download := seen["/files/aws.zip"]
+	require.NotNil(t, download)
+	assert.Empty(t, download.Get("Cookie"))
+}
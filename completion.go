@@ -0,0 +1,347 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// CompletionIndex is the part of a provider schema that an editor needs to
+// offer completions, without the descriptions and type details that make
+// full schemas several megabytes. It is designed to be written as JSON,
+// with short keys, and loaded by language servers and editor plugins.
+type CompletionIndex struct {
+	// Provider and Version identify the provider, as "hashicorp/aws" and
+	// "5.40.0". They are empty for indexes built with NewCompletionIndex.
+	Provider string `json:"provider,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// Resources, DataSources, EphemeralResources and Functions hold the
+	// provider's type and function names.
+	Resources          *CompletionTrie `json:"resources"`
+	DataSources        *CompletionTrie `json:"data_sources"`
+	EphemeralResources *CompletionTrie `json:"ephemeral_resources"`
+	Functions          *CompletionTrie `json:"functions"`
+	// Blocks holds the attributes and nested blocks of every block, keyed
+	// by its path: "provider" for the provider configuration,
+	// "resource.<type>", "data.<type>" and "ephemeral.<type>" for the
+	// top-level blocks, followed by the names of nested blocks and nested
+	// attributes, as in "resource.aws_instance.ebs_block_device".
+	Blocks map[string]*CompletionBlock `json:"blocks"`
+}
+
+// CompletionBlock lists what can be written inside a block.
+type CompletionBlock struct {
+	// Attributes is keyed by attribute name.
+	Attributes map[string]CompletionAttribute `json:"a,omitempty"`
+	// Blocks holds the sorted names of the nested blocks.
+	Blocks []string `json:"b,omitempty"`
+}
+
+// CompletionAttribute describes an attribute for completion.
+type CompletionAttribute struct {
+	// Type is the type constraint, such as "string" or "list(string)".
+	// Object and tuple types are given as "object" and "tuple"; attributes
+	// with nested attributes have their own entry in Blocks.
+	Type string `json:"t,omitempty"`
+	// Mode is "r" for required, "o" for optional, "c" for computed and
+	// "oc" for optional and computed attributes.
+	Mode string `json:"m"`
+	// Hints lists the values the description names as possible, such as
+	// "Standard" and "Premium" in "Possible values are `Standard` and
+	// `Premium`.". They are a heuristic, not a validation rule.
+	Hints []string `json:"h,omitempty"`
+	// Deprecated is set for deprecated attributes, which editors usually
+	// list last or strike through.
+	Deprecated bool `json:"d,omitempty"`
+}
+
+// GetCompletionIndex returns the completion index of the provider. Every
+// schema of the provider is converted, so the first call on a large
+// provider takes a while; the index itself is small.
+func (s *Server) GetCompletionIndex(request Request) (*CompletionIndex, error) {
+	s.logger(logComponentSchema).Debug("Getting completion index", s.requestLogAttrs(request)...)
+
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := s.readSchema(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+	ps, err := ls.providerSchema()
+	if err != nil {
+		return nil, err
+	}
+	index := NewCompletionIndex(ps)
+	index.Provider = request.Namespace + "/" + request.Name
+	index.Version = request.Version
+	return index, nil
+}
+
+// NewCompletionIndex builds the completion index of a provider schema, for
+// example one read from `terraform providers schema -json` output.
+func NewCompletionIndex(ps *tfjson.ProviderSchema) *CompletionIndex {
+	index := &CompletionIndex{
+		Resources:          NewCompletionTrie(),
+		DataSources:        NewCompletionTrie(),
+		EphemeralResources: NewCompletionTrie(),
+		Functions:          NewCompletionTrie(),
+		Blocks:             make(map[string]*CompletionBlock),
+	}
+	if ps == nil {
+		return index
+	}
+	if ps.ConfigSchema != nil {
+		index.addBlock("provider", ps.ConfigSchema.Block)
+	}
+	for _, kind := range []struct {
+		prefix  string
+		trie    *CompletionTrie
+		schemas map[string]*tfjson.Schema
+	}{
+		{"resource", index.Resources, ps.ResourceSchemas},
+		{"data", index.DataSources, ps.DataSourceSchemas},
+		{"ephemeral", index.EphemeralResources, ps.EphemeralResourceSchemas},
+	} {
+		for name, schema := range kind.schemas {
+			kind.trie.Insert(name)
+			if schema != nil {
+				index.addBlock(kind.prefix+"."+name, schema.Block)
+			}
+		}
+	}
+	for name := range ps.Functions {
+		index.Functions.Insert(name)
+	}
+	return index
+}
+
+// addBlock adds b and its nested blocks and nested attributes at path.
+func (index *CompletionIndex) addBlock(path string, b *tfjson.SchemaBlock) {
+	if b == nil {
+		b = &tfjson.SchemaBlock{}
+	}
+	cb := index.addAttributes(path, b.Attributes)
+	for name, bt := range b.NestedBlocks {
+		if bt == nil {
+			continue
+		}
+		cb.Blocks = append(cb.Blocks, name)
+		index.addBlock(path+"."+name, bt.Block)
+	}
+	slices.Sort(cb.Blocks)
+}
+
+// addAttributes adds a block with the given attributes at path, and the
+// attributes of nested attributes below it.
+func (index *CompletionIndex) addAttributes(path string, attrs map[string]*tfjson.SchemaAttribute) *CompletionBlock {
+	cb := &CompletionBlock{}
+	index.Blocks[path] = cb
+	for name, a := range attrs {
+		if a == nil {
+			continue
+		}
+		if cb.Attributes == nil {
+			cb.Attributes = make(map[string]CompletionAttribute)
+		}
+		ca := CompletionAttribute{Mode: completionMode(a), Deprecated: a.Deprecated}
+		if nt := a.AttributeNestedType; nt != nil {
+			ca.Type = nestedAttributeTypeConstraint(nt)
+			index.addAttributes(path+"."+name, nt.Attributes)
+		} else {
+			ca.Type = typeConstraint(a.AttributeType)
+			if isStringish(a.AttributeType) {
+				ca.Hints = descriptionHints(a.Description)
+			}
+		}
+		cb.Attributes[name] = ca
+	}
+	return cb
+}
+
+func completionMode(a *tfjson.SchemaAttribute) string {
+	switch {
+	case a.Required:
+		return "r"
+	case a.Optional && a.Computed:
+		return "oc"
+	case a.Optional:
+		return "o"
+	}
+	return "c"
+}
+
+// typeConstraint returns t in Terraform's type constraint syntax, e.g.
+// "list(string)", without spelling out object and tuple types.
+func typeConstraint(t cty.Type) string {
+	switch {
+	case t == cty.NilType:
+		return ""
+	case t == cty.DynamicPseudoType:
+		return "any"
+	case t.IsPrimitiveType():
+		return t.FriendlyName()
+	case t.IsListType():
+		return "list(" + typeConstraint(t.ElementType()) + ")"
+	case t.IsSetType():
+		return "set(" + typeConstraint(t.ElementType()) + ")"
+	case t.IsMapType():
+		return "map(" + typeConstraint(t.ElementType()) + ")"
+	case t.IsObjectType():
+		return "object"
+	case t.IsTupleType():
+		return "tuple"
+	}
+	return t.FriendlyName()
+}
+
+func nestedAttributeTypeConstraint(nt *tfjson.SchemaNestedAttributeType) string {
+	switch nt.NestingMode {
+	case tfjson.SchemaNestingModeList:
+		return "list(object)"
+	case tfjson.SchemaNestingModeSet:
+		return "set(object)"
+	case tfjson.SchemaNestingModeMap:
+		return "map(object)"
+	}
+	return "object"
+}
+
+// isStringish reports whether t is a string or a collection of strings,
+// the types whose values descriptions enumerate.
+func isStringish(t cty.Type) bool {
+	if t.IsListType() || t.IsSetType() {
+		t = t.ElementType()
+	}
+	return t == cty.String
+}
+
+var (
+	// hintPhrase matches the phrases that introduce an enumeration of
+	// values in provider descriptions.
+	hintPhrase = regexp.MustCompile(`(?i)\b(possible|valid|allowed|accepted|supported|permitted) values\b|\bone of\b|\bcan be either\b|\bmust be either\b`)
+	// hintValue matches a value quoted with backticks or double quotes.
+	hintValue = regexp.MustCompile("`([^`\\s]{1,64})`|\"([^\"\\s]{1,64})\"")
+)
+
+// descriptionHints returns the values quoted in the sentences of desc that
+// enumerate values, in order and without duplicates.
+func descriptionHints(desc string) []string {
+	var hints []string
+	for _, sentence := range strings.FieldsFunc(desc, func(r rune) bool { return r == '\n' }) {
+		for _, part := range strings.Split(sentence, ". ") {
+			if !hintPhrase.MatchString(part) {
+				continue
+			}
+			for _, m := range hintValue.FindAllStringSubmatch(part, -1) {
+				v := m[1] + m[2]
+				if !slices.Contains(hints, v) {
+					hints = append(hints, v)
+				}
+			}
+		}
+	}
+	return hints
+}
+
+// CompletionTrie is a radix tree of names. Its JSON form is compact: each
+// node has "e": true if a name ends at it and "c" mapping edge labels, the
+// text between nodes, to child nodes. For example "aws_instance" and
+// "aws_iam_role" are stored as
+//
+//	{"c": {"aws_i": {"c": {"nstance": {"e": true}, "am_role": {"e": true}}}}}
+type CompletionTrie struct {
+	End      bool                       `json:"e,omitempty"`
+	Children map[string]*CompletionTrie `json:"c,omitempty"`
+}
+
+// NewCompletionTrie returns a trie holding names.
+func NewCompletionTrie(names ...string) *CompletionTrie {
+	t := &CompletionTrie{}
+	for _, name := range names {
+		t.Insert(name)
+	}
+	return t
+}
+
+// Insert adds name to the trie.
+func (t *CompletionTrie) Insert(name string) {
+	for {
+		if name == "" {
+			t.End = true
+			return
+		}
+		var edge string
+		for e := range t.Children {
+			if e[0] == name[0] {
+				edge = e
+				break
+			}
+		}
+		if edge == "" {
+			if t.Children == nil {
+				t.Children = make(map[string]*CompletionTrie)
+			}
+			t.Children[name] = &CompletionTrie{End: true}
+			return
+		}
+		n := commonPrefixLength(edge, name)
+		if n < len(edge) {
+			// Split the edge where name leaves it.
+			mid := &CompletionTrie{Children: map[string]*CompletionTrie{edge[n:]: t.Children[edge]}}
+			delete(t.Children, edge)
+			t.Children[edge[:n]] = mid
+			edge = edge[:n]
+		}
+		t, name = t.Children[edge], name[n:]
+	}
+}
+
+// Complete returns the sorted names in the trie that start with prefix.
+func (t *CompletionTrie) Complete(prefix string) []string {
+	base := ""
+	for t != nil && prefix != "" {
+		var next *CompletionTrie
+		for edge, child := range t.Children {
+			switch {
+			case strings.HasPrefix(prefix, edge):
+				next, base, prefix = child, base+edge, prefix[len(edge):]
+			case strings.HasPrefix(edge, prefix):
+				next, base, prefix = child, base+edge, ""
+			default:
+				continue
+			}
+			break
+		}
+		t = next
+	}
+	if t == nil {
+		return nil
+	}
+	var names []string
+	t.collect(base, &names)
+	slices.Sort(names)
+	return names
+}
+
+func (t *CompletionTrie) collect(base string, names *[]string) {
+	if t.End {
+		*names = append(*names, base)
+	}
+	for edge, child := range t.Children {
+		child.collect(base+edge, names)
+	}
+}
+
+func commonPrefixLength(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCompletionTrie(t *testing.T) {
+	trie := NewCompletionTrie("aws_instance", "aws_iam_role", "aws_iam_role_policy", "aws_s3_bucket", "aws")
+
+	assert.Equal(t, []string{"aws_iam_role", "aws_iam_role_policy", "aws_instance"}, trie.Complete("aws_i"))
+	assert.Equal(t, []string{"aws_iam_role", "aws_iam_role_policy"}, trie.Complete("aws_iam_r"))
+	assert.Equal(t, []string{"aws_iam_role_policy"}, trie.Complete("aws_iam_role_"))
+	assert.Equal(t, []string{"aws", "aws_iam_role", "aws_iam_role_policy", "aws_instance", "aws_s3_bucket"}, trie.Complete(""))
+	assert.Empty(t, trie.Complete("azurerm"))
+	assert.Empty(t, trie.Complete("aws_instances"))
+
+	data, err := json.Marshal(NewCompletionTrie("aws_instance", "aws_iam_role"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"c": {"aws_i": {"c": {"nstance": {"e": true}, "am_role": {"e": true}}}}}`, string(data))
+
+	var decoded CompletionTrie
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, []string{"aws_instance"}, decoded.Complete("aws_in"))
+}
+
+func TestNewCompletionIndex(t *testing.T) {
+	ps := &tfjson.ProviderSchema{
+		ConfigSchema: &tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{
+			"region": {AttributeType: cty.String, Optional: true},
+		}}},
+		ResourceSchemas: map[string]*tfjson.Schema{
+			"example_thing": {Block: &tfjson.SchemaBlock{
+				Attributes: map[string]*tfjson.SchemaAttribute{
+					"id":  {AttributeType: cty.String, Computed: true},
+					"sku": {AttributeType: cty.String, Required: true, Description: "The SKU. Possible values are `Standard` and `Premium`. Defaults to `Standard`."},
+					"tier": {AttributeType: cty.List(cty.String), Optional: true, Computed: true, Deprecated: true,
+						Description: "Tiers, each one of \"Basic\", \"Pro\" or `Basic`."},
+					"size": {AttributeType: cty.Number, Optional: true, Description: "Must be one of `1` or `2`."},
+					"settings": {Optional: true, AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+						NestingMode: tfjson.SchemaNestingModeSingle,
+						Attributes:  map[string]*tfjson.SchemaAttribute{"enabled": {AttributeType: cty.Bool, Required: true}},
+					}},
+				},
+				NestedBlocks: map[string]*tfjson.SchemaBlockType{
+					"rule":     {NestingMode: tfjson.SchemaNestingModeList, Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{"priority": {AttributeType: cty.Number, Optional: true}}}},
+					"timeouts": {NestingMode: tfjson.SchemaNestingModeSingle},
+				},
+			}},
+		},
+		DataSourceSchemas: map[string]*tfjson.Schema{"example_lookup": {Block: &tfjson.SchemaBlock{}}},
+		Functions:         map[string]*tfjson.FunctionSignature{"parse": {}},
+	}
+
+	index := NewCompletionIndex(ps)
+	assert.Equal(t, []string{"example_thing"}, index.Resources.Complete("ex"))
+	assert.Equal(t, []string{"example_lookup"}, index.DataSources.Complete(""))
+	assert.Empty(t, index.EphemeralResources.Complete(""))
+	assert.Equal(t, []string{"parse"}, index.Functions.Complete("p"))
+
+	assert.Equal(t, map[string]CompletionAttribute{"region": {Type: "string", Mode: "o"}}, index.Blocks["provider"].Attributes)
+
+	thing := index.Blocks["resource.example_thing"]
+	require.NotNil(t, thing)
+	assert.Equal(t, []string{"rule", "timeouts"}, thing.Blocks)
+	assert.Equal(t, CompletionAttribute{Type: "string", Mode: "c"}, thing.Attributes["id"])
+	assert.Equal(t, CompletionAttribute{Type: "string", Mode: "r", Hints: []string{"Standard", "Premium"}}, thing.Attributes["sku"])
+	assert.Equal(t, CompletionAttribute{Type: "list(string)", Mode: "oc", Hints: []string{"Basic", "Pro"}, Deprecated: true}, thing.Attributes["tier"])
+	assert.Equal(t, CompletionAttribute{Type: "number", Mode: "o"}, thing.Attributes["size"], "hints are only taken for strings")
+	assert.Equal(t, CompletionAttribute{Type: "object", Mode: "o"}, thing.Attributes["settings"])
+
+	assert.Equal(t, CompletionAttribute{Type: "bool", Mode: "r"}, index.Blocks["resource.example_thing.settings"].Attributes["enabled"])
+	assert.Equal(t, CompletionAttribute{Type: "number", Mode: "o"}, index.Blocks["resource.example_thing.rule"].Attributes["priority"])
+	assert.Contains(t, index.Blocks, "resource.example_thing.timeouts")
+	assert.Contains(t, index.Blocks, "data.example_lookup")
+}
+
+func TestServer_GetCompletionIndex(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	index, err := s.GetCompletionIndex(Request{Namespace: "example", Name: "example", Version: "1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "example/example", index.Provider)
+	assert.Equal(t, "1.0.0", index.Version)
+	assert.Equal(t, []string{"example_thing"}, index.Resources.Complete("example_"))
+	assert.Equal(t, []string{"parse"}, index.Functions.Complete(""))
+	assert.Equal(t, []string{"rule"}, index.Blocks["resource.example_thing"].Blocks)
+}
@@ -0,0 +1,154 @@
+package tfpluginschema
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdExt is the extension added to the names of compressed schema bundle
+// files.
+const zstdExt = ".zst"
+
+// zstdMagic starts every zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// maxDecompressedSchemaSize bounds the memory used to decompress a schema.
+const maxDecompressedSchemaSize = 1 << 30
+
+// CompressionLevel is how hard persisted schemas are compressed; see
+// WithSchemaCompression.
+type CompressionLevel int
+
+const (
+	// CompressionNone writes schemas as plain JSON.
+	CompressionNone CompressionLevel = iota
+	// CompressionFastest compresses quickly at the lowest ratio.
+	CompressionFastest
+	// CompressionDefault balances speed and ratio.
+	CompressionDefault
+	// CompressionBetter compresses more slowly at a better ratio.
+	CompressionBetter
+	// CompressionBest compresses slowest at the best ratio.
+	CompressionBest
+)
+
+// compressionLevelNames are the names accepted by ParseCompressionLevel.
+var compressionLevelNames = []string{"none", "fastest", "default", "better", "best"}
+
+// String returns the name of l, as accepted by ParseCompressionLevel.
+func (l CompressionLevel) String() string {
+	if l < 0 || int(l) >= len(compressionLevelNames) {
+		return fmt.Sprintf("CompressionLevel(%d)", int(l))
+	}
+	return compressionLevelNames[l]
+}
+
+// ParseCompressionLevel parses "none", "fastest", "default", "better" or
+// "best".
+func ParseCompressionLevel(s string) (CompressionLevel, error) {
+	for i, name := range compressionLevelNames {
+		if strings.EqualFold(s, name) {
+			return CompressionLevel(i), nil
+		}
+	}
+	return CompressionNone, fmt.Errorf("invalid compression level %q: must be one of %s", s, strings.Join(compressionLevelNames, ", "))
+}
+
+// WithSchemaCompression compresses the schemas the Server persists with
+// zstd: the files written by WriteSchemaBundle, which are named
+// "<version>.json.zst", and the state written by Snapshot. Schemas of large
+// providers shrink to a few percent of their size. Compressed files are
+// decompressed transparently when read, whatever this option is set to.
+// The default is CompressionNone.
+func WithSchemaCompression(level CompressionLevel) ServerOption {
+	return func(s *Server) {
+		s.schemaCompression = level
+	}
+}
+
+// zstdLevel returns the zstd encoder level for l.
+func (l CompressionLevel) zstdLevel() zstd.EncoderLevel {
+	switch l {
+	case CompressionFastest:
+		return zstd.SpeedFastest
+	case CompressionBetter:
+		return zstd.SpeedBetterCompression
+	case CompressionBest:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// CompressSchema returns data compressed with zstd at level, as
+// WriteSchemaBundle writes "<version>.json.zst" files, or data itself for
+// CompressionNone. It is also the codec of the files embedded by the
+// embedgen package.
+func CompressSchema(data []byte, level CompressionLevel) ([]byte, error) {
+	if level == CompressionNone {
+		return data, nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level.zstdLevel()))
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil), nil
+}
+
+// compressingWriter returns a writer that compresses to w at level, and a
+// function that flushes it, or w itself for CompressionNone.
+func compressingWriter(w io.Writer, level CompressionLevel) (io.Writer, func() error, error) {
+	if level == CompressionNone {
+		return w, func() error { return nil }, nil
+	}
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderLevel(level.zstdLevel()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, enc.Close, nil
+}
+
+// zstdDecoder is shared by all decompression, since DecodeAll may be
+// called concurrently.
+var zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecompressedSchemaSize))
+})
+
+// decompressSchema returns data decompressed if it is zstd-compressed, or
+// data itself otherwise.
+func decompressSchema(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, zstdMagic) {
+		return data, nil
+	}
+	dec, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	out, err := dec.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	return out, nil
+}
+
+// decompressingReader returns a reader of r decompressed if it is
+// zstd-compressed, or of r itself otherwise, and a function that releases
+// it.
+func decompressingReader(r io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(zstdMagic)); !bytes.Equal(magic, zstdMagic) {
+		return br, func() {}, nil
+	}
+	dec, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecompressedSchemaSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return dec, dec.Close, nil
+}
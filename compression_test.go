@@ -0,0 +1,130 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParseCompressionLevel(t *testing.T) {
+	for _, level := range []CompressionLevel{CompressionNone, CompressionFastest, CompressionDefault, CompressionBetter, CompressionBest} {
+		got, err := ParseCompressionLevel(level.String())
+		require.NoError(t, err)
+		assert.Equal(t, level, got)
+	}
+	got, err := ParseCompressionLevel("BEST")
+	require.NoError(t, err)
+	assert.Equal(t, CompressionBest, got)
+	_, err = ParseCompressionLevel("max")
+	assert.ErrorContains(t, err, `invalid compression level "max"`)
+	assert.Equal(t, "CompressionLevel(9)", CompressionLevel(9).String())
+}
+
+func TestServer_SnapshotCompression(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0"}
+	bundle := fstest.MapFS{"opentofu/hashicorp/aws/5.40.0.json": {Data: []byte(bundledAWSSchema)}}
+	src := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle),
+		WithSchemaCompression(CompressionFastest))
+	t.Cleanup(func() { _ = src.Cleanup() })
+	_, err := src.GetResourceSchema(req, "aws_instance")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), zstdMagic))
+
+	dst := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = dst.Cleanup() })
+	require.NoError(t, dst.RestoreSnapshot(&buf))
+	schema, err := dst.GetResourceSchema(req, "aws_instance")
+	require.NoError(t, err)
+	assert.True(t, schema.Block.Attributes["ami"].Required)
+
+	assert.ErrorContains(t, dst.RestoreSnapshot(bytes.NewReader(append(bytes.Clone(zstdMagic), "garbage"...))), "failed to read snapshot")
+}
+
+func TestServer_WriteSchemaBundle_Compressed(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0"}
+	bundle := fstest.MapFS{"opentofu/hashicorp/aws/5.40.0.json": {Data: []byte(bundledAWSSchema)}}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "opentofu", "hashicorp", "aws", "5.40.0.json")
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle),
+		WithSchemaCompression(CompressionBest))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, os.MkdirAll(filepath.Dir(plain), 0o755))
+	require.NoError(t, os.WriteFile(plain, []byte("stale"), 0o644))
+	path, err := s.WriteSchemaBundle(req, dir)
+	require.NoError(t, err)
+	assert.Equal(t, plain+".zst", path)
+	assert.NoFileExists(t, plain, "the plain file would be read instead")
+
+	served := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundleDir(dir),
+		WithPluginExec(false), WithOffline(true))
+	t.Cleanup(func() { _ = served.Cleanup() })
+	schema, err := served.GetResourceSchema(Request{Namespace: "hashicorp", Name: "aws", Version: "~> 5.0"}, "aws_instance")
+	require.NoError(t, err)
+	assert.True(t, schema.Block.Attributes["ami"].Required)
+
+	sources := NewServer(nil, WithCacheDir(t.TempDir()), WithSchemaSources(BundleSource(os.DirFS(dir))))
+	t.Cleanup(func() { _ = sources.Cleanup() })
+	_, err = sources.GetResourceSchema(req, "aws_instance")
+	require.NoError(t, err)
+
+	s.schemaCompression = CompressionNone
+	path, err = s.WriteSchemaBundle(req, dir)
+	require.NoError(t, err)
+	assert.Equal(t, plain, path)
+	assert.NoFileExists(t, plain+".zst")
+}
+
+// largeSchemaJSON returns the JSON of a provider schema with the given
+// number of resources, of a size comparable to large cloud providers.
+func largeSchemaJSON(b *testing.B, resources int) []byte {
+	b.Helper()
+	ps := &tfjson.ProviderSchema{ResourceSchemas: make(map[string]*tfjson.Schema, resources)}
+	for i := range resources {
+		attrs := make(map[string]*tfjson.SchemaAttribute, 40)
+		for j := range 40 {
+			attrs[fmt.Sprintf("attribute_%d", j)] = &tfjson.SchemaAttribute{
+				AttributeType: cty.String,
+				Optional:      true,
+				Description:   fmt.Sprintf("Attribute %d of resource %d. Changing this forces a new resource to be created.", j, i),
+			}
+		}
+		ps.ResourceSchemas[fmt.Sprintf("example_resource_%d", i)] = &tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: attrs}}
+	}
+	data, err := json.Marshal(ps)
+	require.NoError(b, err)
+	return data
+}
+
+// BenchmarkDecodeBundledSchema_Compression measures the time to load a
+// persisted schema at each compression level, reporting its size on disk.
+func BenchmarkDecodeBundledSchema_Compression(b *testing.B) {
+	data := largeSchemaJSON(b, 1000)
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	for _, level := range []CompressionLevel{CompressionNone, CompressionFastest, CompressionDefault, CompressionBetter, CompressionBest} {
+		compressed, err := CompressSchema(data, level)
+		require.NoError(b, err)
+		b.Run(level.String(), func(b *testing.B) {
+			for b.Loop() {
+				ls, err := decodeBundledSchema(compressed, req, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, _ = ls.providerSchema()
+			}
+			b.ReportMetric(float64(len(compressed)), "file-bytes")
+		})
+	}
+}
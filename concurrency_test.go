@@ -0,0 +1,205 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRegistry serves versions, download metadata and archives for
+// fake providers in the hashicorp namespace, and counts the requests for
+// each.
+type countingRegistry struct {
+	archive  []byte
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newCountingRegistry(t testing.TB, names ...string) *countingRegistry {
+	t.Helper()
+	r := &countingRegistry{requests: make(map[string]int)}
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		files[providerFileNamePrefix+name+"_v1.0.0"] = "binary"
+	}
+	r.archive = archiveBytes(t, files)
+	return r
+}
+
+func (r *countingRegistry) count(p string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[p]
+}
+
+func (r *countingRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet {
+		r.mu.Lock()
+		r.requests[req.URL.Path]++
+		r.mu.Unlock()
+	}
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/versions"):
+		fmt.Fprint(w, `{"versions":[{"version":"1.0.0"},{"version":"0.9.0"}]}`)
+	case strings.Contains(req.URL.Path, "/download/"):
+		name := strings.Split(req.URL.Path, "/")[4]
+		fmt.Fprintf(w, `{"filename":"%s%s_1.0.0.zip","download_url":"https://releases.example.com/%s.zip"}`, providerFileNamePrefix, name, name)
+	case path.Ext(req.URL.Path) == ".zip":
+		// Slow enough for concurrent callers to overlap.
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write(r.archive)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// archiveBytes returns a zip archive holding files.
+func archiveBytes(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "provider.zip")
+	createZip(t, path, files)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestServer_ConcurrentGet(t *testing.T) {
+	names := []string{"null", "random", "time", "local"}
+	registry := newCountingRegistry(t, names...)
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, registry)), WithDiskSpaceCheck(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	const callers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, callers*len(names))
+	for range callers {
+		for _, name := range names {
+			wg.Go(func() {
+				errs <- s.Get(Request{Namespace: "hashicorp", Name: name, Version: "~> 1.0"})
+			})
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	for _, name := range names {
+		assert.Equal(t, 1, registry.count("/v1/providers/hashicorp/"+name+"/versions"), "versions of %s", name)
+		assert.Equal(t, 1, registry.count(fmt.Sprintf("/v1/providers/hashicorp/%s/1.0.0/download/%s/%s", name, runtime.GOOS, runtime.GOARCH)), "metadata of %s", name)
+		assert.Equal(t, 1, registry.count("/"+name+".zip"), "archive of %s", name)
+	}
+}
+
+func TestServer_ConcurrentGetResourceSchema(t *testing.T) {
+	bundle := fstest.MapFS{}
+	names := []string{"aws", "azurerm", "google"}
+	for _, name := range names {
+		bundle["opentofu/hashicorp/"+name+"/5.40.0.json"] = &fstest.MapFile{Data: []byte(bundledAWSSchema)}
+	}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	var wg sync.WaitGroup
+	var failures atomic.Int32
+	for range 32 {
+		for _, name := range names {
+			wg.Go(func() {
+				schema, err := s.GetResourceSchema(Request{Namespace: "hashicorp", Name: name, Version: "5.40.0"}, "aws_instance")
+				if err != nil || !schema.Block.Attributes["ami"].Required {
+					failures.Add(1)
+				}
+			})
+		}
+	}
+	wg.Wait()
+	assert.Zero(t, failures.Load())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	assert.Len(t, s.sc, len(names))
+}
+
+func TestServer_Get_DoesNotHoldLockAcrossRegistryCalls(t *testing.T) {
+	// Service discovery and compatibility mode record per-host state under
+	// s.mu while Get is downloading; this must not deadlock.
+	archive := archiveBytes(t, map[string]string{providerFileNamePrefix + "null_v1.0.0": "binary"})
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == serviceDiscoveryPath:
+			fmt.Fprint(w, `{"providers.v1":"/api/registry/v1/providers/"}`)
+		case strings.HasPrefix(r.URL.Path, "/api/registry/v1/providers/acme/null/1.0.0/download/"):
+			fmt.Fprint(w, `{"filename":"terraform-provider-null_1.0.0.zip","download_url":"/archive.zip"}`)
+		case r.URL.Path == "/archive.zip":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithRegistryCompat(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Get(Request{Namespace: "acme", Name: "null", Version: "1.0.0", RegistryType: "tfe.example.com"})
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Get did not return")
+	}
+}
+
+func BenchmarkServer_GetParallel(b *testing.B) {
+	names := []string{"null", "random", "time", "local"}
+	registry := newCountingRegistry(b, names...)
+	s := NewServer(nil, WithCacheDir(b.TempDir()), WithHTTPClient(stubRegistryClient(b, registry)), WithDiskSpaceCheck(false))
+	b.Cleanup(func() { _ = s.Cleanup() })
+	for _, name := range names {
+		require.NoError(b, s.Get(Request{Namespace: "hashicorp", Name: name, Version: "1.0.0"}))
+	}
+
+	var i atomic.Uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			name := names[i.Add(1)%uint64(len(names))]
+			if err := s.Get(Request{Namespace: "hashicorp", Name: name, Version: "~> 1.0"}); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func BenchmarkServer_GetResourceSchemaParallel(b *testing.B) {
+	bundle := fstest.MapFS{}
+	names := []string{"aws", "azurerm", "google", "kubernetes"}
+	for _, name := range names {
+		bundle["opentofu/hashicorp/"+name+"/5.40.0.json"] = &fstest.MapFile{Data: []byte(bundledAWSSchema)}
+	}
+	s := NewServer(nil, WithCacheDir(b.TempDir()), WithSchemaBundle(bundle), WithPluginExec(false))
+	b.Cleanup(func() { _ = s.Cleanup() })
+
+	var i atomic.Uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			name := names[i.Add(1)%uint64(len(names))]
+			if _, err := s.GetResourceSchema(Request{Namespace: "hashicorp", Name: name, Version: "5.40.0"}, "aws_instance"); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
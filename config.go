@@ -0,0 +1,202 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the name of the configuration file looked up by
+// FindConfigFile.
+const ConfigFileName = "tfpluginschema.yaml"
+
+// Config holds defaults read from a tfpluginschema.yaml file. A file looks
+// like:
+//
+//	registry: terraform
+//	cache_dir: ~/.cache/tfpluginschema
+//	default_namespace: hashicorp
+//	credentials:
+//	  registry.terraform.io:
+//	    env: TF_TOKEN_registry_terraform_io
+//	  registry.example.com:
+//	    file: ./registry-token
+//	retry:
+//	  max_attempts: 3
+//	  initial_backoff: 500ms
+//	  max_backoff: 10s
+//	providers:
+//	  allow:
+//	    - registry.terraform.io/hashicorp/*
+//	  deny:
+//	    - "*/hashicorp/external"
+//	version_aliases:
+//	  hashicorp/aws@current: 5.40.0
+//	  hashicorp/aws@next: "~> 6.0"
+//
+// Every key is optional. Relative paths are resolved against the directory
+// containing the file, and a leading "~/" against the user's home directory.
+type Config struct {
+	// Registry is the registry used for requests that do not name one:
+	// "opentofu", "terraform" or a registry host; see ParseRegistryType.
+	Registry RegistryType `yaml:"registry"`
+	// CacheDir overrides the provider cache directory.
+	CacheDir string `yaml:"cache_dir"`
+	// DefaultNamespace is the provider namespace used for requests that do
+	// not name one.
+	DefaultNamespace string `yaml:"default_namespace"`
+	// Credentials maps registry hosts onto where their API token is kept.
+	Credentials map[string]CredentialsRef `yaml:"credentials"`
+	// Retry configures retries of registry API requests.
+	Retry RetryPolicy `yaml:"retry"`
+	// Providers restricts which providers may be used; see
+	// WithProviderRules.
+	Providers ProviderRules `yaml:"providers"`
+	// VersionAliases names provider versions, so that requests can be
+	// written as "aws@current"; see VersionAliases.ParseRequest.
+	VersionAliases VersionAliases `yaml:"version_aliases"`
+
+	// dir is the directory containing the file, for resolving relative paths.
+	dir string
+}
+
+// CredentialsRef refers to a registry API token without containing it.
+// Exactly one of Env and File must be set.
+type CredentialsRef struct {
+	// Env names an environment variable holding the token. An unset or
+	// empty variable sends no token.
+	Env string `yaml:"env"`
+	// File is the path of a file holding the token. Surrounding whitespace
+	// is ignored.
+	File string `yaml:"file"`
+}
+
+// FindConfigFile returns the path of the configuration file to use: the
+// ConfigFileName in the working directory if present, otherwise
+// tfpluginschema/tfpluginschema.yaml in the user's configuration directory
+// ($XDG_CONFIG_HOME or ~/.config on Linux). It returns "" when neither
+// exists.
+func FindConfigFile() string {
+	candidates := []string{ConfigFileName}
+	if dir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "tfpluginschema", ConfigFileName))
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads and validates the configuration file at path. Unknown
+// keys are rejected so that typos do not go unnoticed.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if c.dir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to resolve config file directory: %w", err)
+	}
+	return c, nil
+}
+
+// validate checks the values LoadConfig cannot check while decoding, and
+// normalizes Registry.
+func (c *Config) validate() error {
+	if c.Registry != "" {
+		r, err := ParseRegistryType(string(c.Registry))
+		if err != nil {
+			return err
+		}
+		c.Registry = r
+	}
+	for host, ref := range c.Credentials {
+		if (ref.Env == "") == (ref.File == "") {
+			return fmt.Errorf("credentials for %s: exactly one of env and file must be set", host)
+		}
+	}
+	if c.Retry.MaxAttempts < 0 || c.Retry.InitialBackoff < 0 || c.Retry.MaxBackoff < 0 {
+		return errors.New("retry: values must not be negative")
+	}
+	if err := c.Providers.Validate(); err != nil {
+		return fmt.Errorf("providers: %w", err)
+	}
+	if err := c.VersionAliases.Validate(); err != nil {
+		return fmt.Errorf("version_aliases: %w", err)
+	}
+	return nil
+}
+
+// ServerOptions returns the options applying c to a Server: its cache
+// directory, retry policy, registry tokens, provider rules and, if set, the
+// default registry and namespace (see WithDefaultRegistry and
+// WithDefaultNamespace). Options given after these to NewServer take
+// precedence.
+func (c *Config) ServerOptions() ([]ServerOption, error) {
+	opts := []ServerOption{
+		WithCacheDir(c.resolvePath(c.CacheDir)),
+		WithRetryPolicy(c.Retry),
+		WithProviderRules(c.Providers),
+	}
+	if c.Registry != "" {
+		opts = append(opts, WithDefaultRegistry(c.Registry))
+	}
+	if c.DefaultNamespace != "" {
+		opts = append(opts, WithDefaultNamespace(c.DefaultNamespace))
+	}
+	for host, ref := range c.Credentials {
+		token, err := c.resolveToken(ref)
+		if err != nil {
+			return nil, fmt.Errorf("credentials for %s: %w", host, err)
+		}
+		opts = append(opts, WithRegistryToken(host, token))
+	}
+	return opts, nil
+}
+
+// resolveToken reads the token ref points to.
+func (c *Config) resolveToken(ref CredentialsRef) (string, error) {
+	if ref.Env != "" {
+		return os.Getenv(ref.Env), nil
+	}
+	data, err := os.ReadFile(c.resolvePath(ref.File))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolvePath expands a leading "~/" and makes a relative path relative to
+// the directory containing the config file. An empty path is returned as is.
+func (c *Config) resolvePath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(path) || c.dir == "" {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
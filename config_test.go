@@ -0,0 +1,122 @@
+package tfpluginschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0o600))
+	t.Setenv("TEST_REGISTRY_TOKEN", "env-token")
+	path := writeConfig(t, dir, `
+registry: terraform
+cache_dir: cache
+default_namespace: hashicorp
+credentials:
+  registry.terraform.io:
+    env: TEST_REGISTRY_TOKEN
+  registry.example.com:
+    file: token
+retry:
+  max_attempts: 3
+  initial_backoff: 250ms
+  max_backoff: 2s
+providers:
+  allow: [registry.terraform.io/hashicorp/*]
+version_aliases:
+  hashicorp/aws@current: 5.40.0
+`)
+
+	c, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, RegistryTypeTerraform, c.Registry)
+	assert.Equal(t, "hashicorp", c.DefaultNamespace)
+	assert.Equal(t, VersionAliases{"hashicorp/aws@current": "5.40.0"}, c.VersionAliases)
+	assert.Equal(t, RetryPolicy{MaxAttempts: 3, InitialBackoff: 250 * time.Millisecond, MaxBackoff: 2 * time.Second}, c.Retry)
+
+	opts, err := c.ServerOptions()
+	require.NoError(t, err)
+	s := NewServer(nil, opts...)
+	t.Cleanup(func() { _ = s.Cleanup() })
+	assert.Equal(t, filepath.Join(dir, "cache"), s.CacheDir())
+	assert.Equal(t, c.Retry, s.retryPolicy)
+	assert.Equal(t, RegistryTypeTerraform, s.defaultRegistry)
+	assert.Equal(t, ProviderRules{Allow: []string{"registry.terraform.io/hashicorp/*"}}, s.providerRules)
+	assert.Equal(t, map[string]string{
+		"registry.terraform.io": "env-token",
+		"registry.example.com":  "file-token",
+	}, s.registryTokens)
+}
+
+func TestLoadConfig_Empty(t *testing.T) {
+	c, err := LoadConfig(writeConfig(t, t.TempDir(), ""))
+	require.NoError(t, err)
+	assert.Empty(t, c.Registry)
+
+	opts, err := c.ServerOptions()
+	require.NoError(t, err)
+	s := NewServer(nil, opts...)
+	t.Cleanup(func() { _ = s.Cleanup() })
+	assert.Equal(t, defaultCacheDir(), s.CacheDir())
+	assert.Empty(t, s.defaultRegistry)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":       "regsitry: terraform\n",
+		"unknown registry":  "registry: github\n",
+		"both credentials":  "credentials:\n  example.com:\n    env: A\n    file: b\n",
+		"no credentials":    "credentials:\n  example.com: {}\n",
+		"negative attempts": "retry:\n  max_attempts: -1\n",
+		"bad duration":      "retry:\n  max_backoff: soon\n",
+		"bad provider rule": "providers:\n  deny: ['[']\n",
+		"bad version alias": "version_aliases:\n  hashicorp/aws: 5.0.0\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, t.TempDir(), content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfig_ServerOptions_MissingTokenFile(t *testing.T) {
+	c, err := LoadConfig(writeConfig(t, t.TempDir(), "credentials:\n  example.com:\n    file: missing\n"))
+	require.NoError(t, err)
+	_, err = c.ServerOptions()
+	assert.ErrorContains(t, err, "credentials for example.com")
+}
+
+func TestFindConfigFile(t *testing.T) {
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("HOME", xdg)
+	t.Setenv("AppData", xdg)
+
+	if dir, err := os.UserConfigDir(); err != nil || dir != xdg {
+		t.Skip("user config directory cannot be redirected on this platform")
+	}
+	assert.Empty(t, FindConfigFile())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(xdg, "tfpluginschema"), 0o755))
+	userPath := writeConfig(t, filepath.Join(xdg, "tfpluginschema"), "")
+	assert.Equal(t, userPath, FindConfigFile())
+
+	writeConfig(t, cwd, "")
+	assert.Equal(t, ConfigFileName, FindConfigFile())
+}
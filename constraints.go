@@ -0,0 +1,189 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// Conflict is a pair of version constraints that no version satisfies
+// together. Index and OtherIndex are the positions, in the slice passed to
+// IntersectConstraints, of the constraint sets Constraint and Other came
+// from, so a caller that collected one set per module can say which module
+// demanded what. Both indexes are the same when a single set contradicts
+// itself.
+type Conflict struct {
+	Index      int
+	Constraint *goversion.Constraint
+	OtherIndex int
+	Other      *goversion.Constraint
+}
+
+// String returns the conflict as `">= 5.10" conflicts with "< 5.0"`.
+func (c Conflict) String() string {
+	return fmt.Sprintf("%q conflicts with %q", strings.TrimSpace(c.Constraint.String()), strings.TrimSpace(c.Other.String()))
+}
+
+// IntersectConstraints merges version constraint sets, such as the ones
+// several modules place on the same provider, into a single set that a
+// version satisfies only if it satisfies every input. Constraints repeated
+// across sets appear once in the result.
+//
+// The bounds the constraints place on versions are compared without a list
+// of releases, and every pair that leaves no version between them is
+// returned as a Conflict. The merged set is returned either way; it matches
+// no version when there are conflicts. An error is returned only for a
+// constraint whose operator or version cannot be interpreted.
+func IntersectConstraints(sets []goversion.Constraints) (goversion.Constraints, []Conflict, error) {
+	var (
+		merged goversion.Constraints
+		seen   = make(map[string]bool)
+		bounds []constraintBound
+	)
+	for i, set := range sets {
+		for _, c := range set {
+			b, err := boundsOf(i, c)
+			if err != nil {
+				return nil, nil, err
+			}
+			bounds = append(bounds, b...)
+			if s := strings.TrimSpace(c.String()); !seen[s] {
+				seen[s] = true
+				merged = append(merged, c)
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for _, lo := range bounds {
+		if lo.kind != boundLower {
+			continue
+		}
+		for _, hi := range bounds {
+			if hi.kind != boundUpper || hi.c == lo.c {
+				continue
+			}
+			cmp := lo.v.Compare(hi.v)
+			if cmp > 0 || (cmp == 0 && !(lo.inclusive && hi.inclusive)) {
+				conflicts = append(conflicts, Conflict{Index: lo.index, Constraint: lo.c, OtherIndex: hi.index, Other: hi.c})
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return merged, conflicts, nil
+	}
+
+	// Without a pairwise conflict the bounds leave a range of versions; it
+	// is empty only if the range is a single version that "!=" excludes.
+	lo, hi := tightest(bounds, boundLower), tightest(bounds, boundUpper)
+	if lo == nil || hi == nil || !lo.v.Equal(hi.v) {
+		return merged, nil, nil
+	}
+	for _, ex := range bounds {
+		if ex.kind == boundExcluded && ex.v.Equal(lo.v) {
+			conflicts = append(conflicts, Conflict{Index: lo.index, Constraint: lo.c, OtherIndex: ex.index, Other: ex.c})
+		}
+	}
+	return merged, conflicts, nil
+}
+
+type boundKind int
+
+const (
+	boundLower boundKind = iota
+	boundUpper
+	boundExcluded
+)
+
+// constraintBound is one bound a constraint places on versions.
+type constraintBound struct {
+	kind      boundKind
+	v         *goversion.Version
+	inclusive bool
+	index     int
+	c         *goversion.Constraint
+}
+
+// constraintOperators lists the operators go-version accepts, longest
+// first so that ">=" is not read as ">".
+var constraintOperators = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// boundsOf returns the bounds c places on versions.
+func boundsOf(index int, c *goversion.Constraint) ([]constraintBound, error) {
+	s := strings.TrimSpace(c.String())
+	op := ""
+	for _, o := range constraintOperators {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	raw := strings.TrimSpace(strings.TrimPrefix(s, op))
+	v, err := goversion.NewVersion(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+	}
+
+	bound := func(kind boundKind, v *goversion.Version, inclusive bool) constraintBound {
+		return constraintBound{kind: kind, v: v, inclusive: inclusive, index: index, c: c}
+	}
+	switch op {
+	case "", "=":
+		return []constraintBound{bound(boundLower, v, true), bound(boundUpper, v, true)}, nil
+	case "!=":
+		return []constraintBound{bound(boundExcluded, v, true)}, nil
+	case ">":
+		return []constraintBound{bound(boundLower, v, false)}, nil
+	case ">=":
+		return []constraintBound{bound(boundLower, v, true)}, nil
+	case "<":
+		return []constraintBound{bound(boundUpper, v, false)}, nil
+	case "<=":
+		return []constraintBound{bound(boundUpper, v, true)}, nil
+	}
+
+	// "~> 1.2.3" allows >= 1.2.3, < 1.3.0 and "~> 1.2" allows >= 1.2,
+	// < 2.0: the next-to-last segment written is bumped. A lone major
+	// version, "~> 1", has no upper bound.
+	bounds := []constraintBound{bound(boundLower, v, true)}
+	written := len(strings.Split(strings.FieldsFunc(raw, func(r rune) bool { return r == '-' || r == '+' })[0], "."))
+	if written < 2 {
+		return bounds, nil
+	}
+	segments := v.Segments()[:written-1]
+	segments[len(segments)-1]++
+	parts := make([]string, len(segments))
+	for i, n := range segments {
+		parts[i] = fmt.Sprint(n)
+	}
+	upper, err := goversion.NewVersion(strings.Join(parts, "."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+	}
+	return append(bounds, bound(boundUpper, upper, false)), nil
+}
+
+// tightest returns the highest lower bound or lowest upper bound, preferring
+// an exclusive bound over an inclusive one at the same version.
+func tightest(bounds []constraintBound, kind boundKind) *constraintBound {
+	var best *constraintBound
+	for i := range bounds {
+		b := &bounds[i]
+		if b.kind != kind {
+			continue
+		}
+		if best == nil {
+			best = b
+			continue
+		}
+		cmp := b.v.Compare(best.v)
+		if kind == boundUpper {
+			cmp = -cmp
+		}
+		if cmp > 0 || (cmp == 0 && !b.inclusive && best.inclusive) {
+			best = b
+		}
+	}
+	return best
+}
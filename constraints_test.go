@@ -0,0 +1,68 @@
+package tfpluginschema
+
+import (
+	"testing"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func constraintSets(t *testing.T, sets ...string) []goversion.Constraints {
+	t.Helper()
+	result := make([]goversion.Constraints, len(sets))
+	for i, s := range sets {
+		c, err := goversion.NewConstraint(s)
+		require.NoError(t, err)
+		result[i] = c
+	}
+	return result
+}
+
+func TestIntersectConstraints(t *testing.T) {
+	merged, conflicts, err := IntersectConstraints(constraintSets(t, ">= 5.0", "< 5.30, >= 5.0", "~> 5.10"))
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, ">= 5.0,< 5.30,~> 5.10", merged.String())
+	assert.True(t, merged.Check(goversion.Must(goversion.NewVersion("5.29.0"))))
+	assert.False(t, merged.Check(goversion.Must(goversion.NewVersion("5.9.0"))))
+
+	merged, conflicts, err = IntersectConstraints(nil)
+	require.NoError(t, err)
+	assert.Empty(t, merged)
+	assert.Empty(t, conflicts)
+}
+
+func TestIntersectConstraints_Conflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		sets []string
+		want []string
+		at   [][2]int
+	}{
+		{"bounds", []string{"< 5.0", ">= 5.10"}, []string{`">= 5.10" conflicts with "< 5.0"`}, [][2]int{{1, 0}}},
+		{"pessimistic", []string{"~> 4.0", ">= 5.0"}, []string{`">= 5.0" conflicts with "~> 4.0"`}, [][2]int{{1, 0}}},
+		{"pessimistic patch", []string{"~> 1.2.3", "1.3.0"}, []string{`"1.3.0" conflicts with "~> 1.2.3"`}, [][2]int{{1, 0}}},
+		{"exclusive", []string{"> 2.0", "<= 2.0"}, []string{`"> 2.0" conflicts with "<= 2.0"`}, [][2]int{{0, 1}}},
+		{"excluded", []string{">= 2.0, <= 2.0", "!= 2.0"}, []string{`">= 2.0" conflicts with "!= 2.0"`}, [][2]int{{0, 1}}},
+		{"same set", []string{"> 3.0, < 2.0"}, []string{`"> 3.0" conflicts with "< 2.0"`}, [][2]int{{0, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, conflicts, err := IntersectConstraints(constraintSets(t, tt.sets...))
+			require.NoError(t, err)
+			var got []string
+			var at [][2]int
+			for _, c := range conflicts {
+				got = append(got, c.String())
+				at = append(at, [2]int{c.Index, c.OtherIndex})
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.at, at)
+		})
+	}
+
+	_, conflicts, err := IntersectConstraints(constraintSets(t, "~> 5", ">= 9.0"))
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
@@ -0,0 +1,100 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// terraformCredentialsFileName is the file "terraform login" stores API
+// tokens in.
+const terraformCredentialsFileName = "credentials.tfrc.json"
+
+// WithTerraformCredentials makes the Server look up registry API tokens the
+// way Terraform does for hosts without a token set by WithRegistryToken:
+// first in a TF_TOKEN_<host> environment variable, with dots in the host
+// replaced by underscores and hyphens by double underscores (for example
+// TF_TOKEN_app_terraform_io), then in the credentials.tfrc.json file
+// written by "terraform login". This is what private registries such as
+// HCP Terraform and Terraform Enterprise usually need.
+func WithTerraformCredentials(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.terraformCredentials = enabled
+	}
+}
+
+// registryToken returns the API token to send to host, if any.
+func (s *Server) registryToken(host string) (string, bool) {
+	host = strings.ToLower(host)
+	if token, ok := s.registryTokens[host]; ok {
+		return token, true
+	}
+	if !s.terraformCredentials {
+		return "", false
+	}
+	if token := terraformEnvToken(host); token != "" {
+		return token, true
+	}
+	if token := terraformFileToken(host); token != "" {
+		return token, true
+	}
+	return "", false
+}
+
+// terraformEnvToken returns the TF_TOKEN_<host> variable for host.
+func terraformEnvToken(host string) string {
+	name := strings.ReplaceAll(host, ".", "_")
+	if token := os.Getenv("TF_TOKEN_" + strings.ReplaceAll(name, "-", "__")); token != "" {
+		return token
+	}
+	return os.Getenv("TF_TOKEN_" + name)
+}
+
+// terraformFileToken returns the token for host from credentials.tfrc.json.
+// A missing or unreadable file yields no token.
+func terraformFileToken(host string) string {
+	path := terraformCredentialsFile()
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var file struct {
+		Credentials map[string]struct {
+			Token string `json:"token"`
+		} `json:"credentials"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return ""
+	}
+	for h, c := range file.Credentials {
+		if strings.EqualFold(h, host) {
+			return c.Token
+		}
+	}
+	return ""
+}
+
+// terraformCredentialsFile returns the path of credentials.tfrc.json:
+// $TF_CLI_CONFIG_DIR if set, %APPDATA%\terraform.d on Windows and
+// ~/.terraform.d elsewhere.
+func terraformCredentialsFile() string {
+	if dir := os.Getenv("TF_CLI_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, terraformCredentialsFileName)
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("APPDATA"); dir != "" {
+			return filepath.Join(dir, "terraform.d", terraformCredentialsFileName)
+		}
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".terraform.d", terraformCredentialsFileName)
+}
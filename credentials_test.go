@@ -0,0 +1,36 @@
+package tfpluginschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_RegistryToken_TerraformCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TF_CLI_CONFIG_DIR", dir)
+	t.Setenv("TF_TOKEN_app_terraform_io", "env-token")
+	t.Setenv("TF_TOKEN_tfe__1_example_com", "dashed-token")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, terraformCredentialsFileName),
+		[]byte(`{"credentials":{"Registry.Example.com":{"token":"file-token"}}}`), 0o600))
+
+	s := NewServer(nil, WithTerraformCredentials(true), WithRegistryToken("explicit.example.com", "explicit"))
+
+	for host, want := range map[string]string{
+		"app.terraform.io":     "env-token",
+		"tfe-1.example.com":    "dashed-token",
+		"registry.example.com": "file-token",
+		"explicit.example.com": "explicit",
+	} {
+		token, ok := s.registryToken(host)
+		assert.True(t, ok, host)
+		assert.Equal(t, want, token, host)
+	}
+	_, ok := s.registryToken("unknown.example.com")
+	assert.False(t, ok)
+
+	_, ok = NewServer(nil).registryToken("app.terraform.io")
+	assert.False(t, ok, "Terraform credentials are only used when enabled")
+}
@@ -0,0 +1,109 @@
+package tfpluginschema
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DebugStats is a snapshot of a Server's in-memory state and of the process
+// it runs in, for diagnosing memory growth in long-running services. See
+// Server.DebugStats; the debughttp package serves it over HTTP alongside
+// the pprof profiles.
+type DebugStats struct {
+	Time time.Time `json:"time"`
+	// Goroutines, HeapAlloc and HeapObjects describe the whole process, as
+	// reported by the runtime package.
+	Goroutines  int    `json:"goroutines"`
+	HeapAlloc   uint64 `json:"heap_alloc_bytes"`
+	HeapObjects uint64 `json:"heap_objects"`
+	// LoadedProviders, CachedSchemas, CachedVersionLists and
+	// CachedMetadata count the Server's in-memory cache entries.
+	// ConvertedSchemas counts the cached schemas that have been converted
+	// in full, by GetCompletionIndex or Snapshot for instance; the others
+	// hold their entries unconverted until requested.
+	LoadedProviders    int `json:"loaded_providers"`
+	CachedSchemas      int `json:"cached_schemas"`
+	ConvertedSchemas   int `json:"converted_schemas"`
+	CachedVersionLists int `json:"cached_version_lists"`
+	CachedMetadata     int `json:"cached_metadata"`
+	// Subscribers counts the open channels of Subscribe.
+	Subscribers int `json:"subscribers"`
+	// Hits and Misses count cache statuses, as in CacheStats.
+	Hits   uint64 `json:"cache_hits"`
+	Misses uint64 `json:"cache_misses"`
+	// SchemaLoads times the provider runs that retrieved a schema, and
+	// Conversions the full conversions of provider schemas to
+	// terraform-json, since the Server was created.
+	SchemaLoads TimingStats `json:"schema_loads"`
+	Conversions TimingStats `json:"conversions"`
+}
+
+// TimingStats summarises the durations of repeated operations.
+type TimingStats struct {
+	Count uint64        `json:"count"`
+	Total time.Duration `json:"total_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// timings accumulates TimingStats. It is safe for concurrent use.
+type timings struct {
+	mu    sync.Mutex
+	stats TimingStats
+}
+
+// record adds one operation that took d.
+func (t *timings) record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Count++
+	t.stats.Total += d
+	t.stats.Max = max(t.stats.Max, d)
+}
+
+func (t *timings) snapshot() TimingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// DebugStats returns a snapshot of the Server's in-memory caches, of the
+// time spent loading and converting schemas, and of the process's
+// goroutines and heap. Unlike CacheStats it does not read the on-disk
+// cache, so it is cheap enough to poll. Reading the heap statistics stops
+// the world briefly.
+func (s *Server) DebugStats() DebugStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := DebugStats{
+		Time:        time.Now(),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapObjects: mem.HeapObjects,
+		Hits:        s.cacheHits.Load(),
+		Misses:      s.cacheMisses.Load(),
+		SchemaLoads: s.schemaLoads.snapshot(),
+		Conversions: s.conversions.snapshot(),
+	}
+
+	s.mu.RLock()
+	stats.LoadedProviders = len(s.dlc)
+	stats.CachedSchemas = len(s.sc)
+	stats.CachedVersionLists = len(s.versionsc)
+	stats.CachedMetadata = len(s.mdc)
+	schemas := make([]*lazySchema, 0, len(s.sc))
+	for _, ls := range s.sc {
+		schemas = append(schemas, ls)
+	}
+	s.mu.RUnlock()
+	for _, ls := range schemas {
+		if ls.converted() {
+			stats.ConvertedSchemas++
+		}
+	}
+
+	s.subs.mu.Lock()
+	stats.Subscribers = len(s.subs.chans)
+	s.subs.mu.Unlock()
+	return stats
+}
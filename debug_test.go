@@ -0,0 +1,58 @@
+package tfpluginschema
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_DebugStats(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)),
+		WithSchemaSources(BundleSource(bundle)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	stats := s.DebugStats()
+	assert.Positive(t, stats.Goroutines)
+	assert.Positive(t, stats.HeapAlloc)
+	assert.Zero(t, stats.CachedSchemas)
+
+	request := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	_, err := s.ListResources(request)
+	require.NoError(t, err)
+	ch, unsubscribe := s.Subscribe(1)
+	defer unsubscribe()
+	require.NotNil(t, ch)
+
+	stats = s.DebugStats()
+	assert.Equal(t, 1, stats.CachedSchemas)
+	assert.Zero(t, stats.ConvertedSchemas)
+	assert.Equal(t, 1, stats.Subscribers)
+
+	require.NoError(t, s.Snapshot(io.Discard))
+	assert.Equal(t, 1, s.DebugStats().ConvertedSchemas)
+
+	// Schemas returned by providers record how long converting them took.
+	ls, err := newLazySchemaV6(largeV6Response(2), nil)
+	require.NoError(t, err)
+	other := Request{Namespace: "example", Name: "other", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}
+	s.storeSchema(other, cacheKey(other), ls)
+	_, _ = ls.providerSchema()
+	_, _ = ls.providerSchema()
+	stats = s.DebugStats()
+	assert.Equal(t, 2, stats.ConvertedSchemas)
+	assert.Equal(t, uint64(1), stats.Conversions.Count)
+	assert.Equal(t, stats.Conversions.Total, stats.Conversions.Max)
+	assert.Zero(t, stats.SchemaLoads.Count)
+}
+
+func TestTimings(t *testing.T) {
+	var tm timings
+	tm.record(3)
+	tm.record(5)
+	tm.record(1)
+	assert.Equal(t, TimingStats{Count: 3, Total: 9, Max: 5}, tm.snapshot())
+}
@@ -0,0 +1,40 @@
+// Package debughttp serves the runtime profiles of a process and the
+// DebugStats of a tfpluginschema.Server over HTTP, for diagnosing long-lived
+// services that embed a Server. It is a separate package so that programs
+// that do not import it do not carry net/http/pprof.
+//
+// Mount the handler on a listener that only operators can reach; profiles
+// reveal the program's internals:
+//
+//	go http.ListenAndServe("localhost:6060", debughttp.Handler(s))
+package debughttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// StatsPath is where Handler serves the Server's DebugStats as JSON.
+const StatsPath = "/debug/tfpluginschema/stats"
+
+// Handler returns a handler serving the net/http/pprof profiles under
+// /debug/pprof/ and the DebugStats of s under StatsPath. Unlike importing
+// net/http/pprof, it registers nothing on http.DefaultServeMux.
+func Handler(s *tfpluginschema.Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET "+StatsPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(s.DebugStats())
+	})
+	return mux
+}
@@ -0,0 +1,44 @@
+package debughttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+func TestHandler(t *testing.T) {
+	s := tfpluginschema.NewServer(nil, tfpluginschema.WithCacheDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	srv := httptest.NewServer(Handler(s))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + StatsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var stats tfpluginschema.DebugStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.Zero(t, stats.CachedSchemas)
+
+	resp, err = http.Get(srv.URL + "/debug/pprof/goroutine?debug=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "goroutine profile")
+
+	resp, err = http.Get(srv.URL + "/debug/other")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
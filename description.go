@@ -0,0 +1,447 @@
+package tfpluginschema
+
+import (
+	"html"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// WithPlaintextDescriptions returns the schema with markdown block and
+// attribute descriptions rendered to plain text by DescriptionPlaintext,
+// and their kinds set to plain, for output that cannot render markdown such
+// as terminal tables. Function signatures carry no description kind and
+// are returned as they are; render them with DescriptionPlaintext and the
+// kinds from GetFunctionDetails. The result is always a copy.
+func WithPlaintextDescriptions() SchemaOption {
+	return func(o *schemaOptions) {
+		o.plaintextDescriptions = true
+	}
+}
+
+// DescriptionPlaintext returns desc as plain text. Plain descriptions, and
+// descriptions of no kind, are returned as they are. Markdown is rendered:
+// emphasis and code spans are reduced to their text, links to their text
+// followed by the URL in parentheses, headings to their text and fenced
+// code blocks to their contents. List markers are kept, and blocks are
+// separated by a blank line.
+//
+// The renderer covers the subset of CommonMark that provider descriptions
+// use; other markup, including inline HTML and tables, is kept as written.
+func DescriptionPlaintext(desc string, kind tfjson.SchemaDescriptionKind) string {
+	if kind != tfjson.SchemaDescriptionKindMarkdown {
+		return desc
+	}
+	return renderMarkdown(desc, false)
+}
+
+// DescriptionHTML returns desc as an HTML fragment, for editors and
+// documentation that display HTML. Markdown descriptions are rendered as
+// DescriptionPlaintext describes; plain descriptions are escaped and split
+// into paragraphs at blank lines.
+func DescriptionHTML(desc string, kind tfjson.SchemaDescriptionKind) string {
+	if kind != tfjson.SchemaDescriptionKindMarkdown {
+		var b strings.Builder
+		for i, p := range splitParagraphs(desc) {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(p), "\n", "<br>\n") + "</p>")
+		}
+		return b.String()
+	}
+	return renderMarkdown(desc, true)
+}
+
+func plaintextSchemaDescriptions(s *tfjson.Schema) {
+	if s != nil {
+		plaintextBlockDescriptions(s.Block)
+	}
+}
+
+func plaintextBlockDescriptions(b *tfjson.SchemaBlock) {
+	if b == nil {
+		return
+	}
+	b.Description, b.DescriptionKind = plaintextDescribed(b.Description, b.DescriptionKind)
+	plaintextAttributeDescriptions(b.Attributes)
+	for _, nb := range b.NestedBlocks {
+		if nb != nil {
+			plaintextBlockDescriptions(nb.Block)
+		}
+	}
+}
+
+func plaintextAttributeDescriptions(attrs map[string]*tfjson.SchemaAttribute) {
+	for _, a := range attrs {
+		if a == nil {
+			continue
+		}
+		a.Description, a.DescriptionKind = plaintextDescribed(a.Description, a.DescriptionKind)
+		if a.AttributeNestedType != nil {
+			plaintextAttributeDescriptions(a.AttributeNestedType.Attributes)
+		}
+	}
+}
+
+func plaintextDescribed(desc string, kind tfjson.SchemaDescriptionKind) (string, tfjson.SchemaDescriptionKind) {
+	if kind != tfjson.SchemaDescriptionKindMarkdown {
+		return desc, kind
+	}
+	return DescriptionPlaintext(desc, kind), tfjson.SchemaDescriptionKindPlain
+}
+
+// splitParagraphs splits s at blank lines.
+func splitParagraphs(s string) []string {
+	var paras, cur []string
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(cur) > 0 {
+				paras = append(paras, strings.Join(cur, "\n"))
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		paras = append(paras, strings.Join(cur, "\n"))
+	}
+	return paras
+}
+
+type mdBlockKind int
+
+const (
+	mdParagraph mdBlockKind = iota
+	mdHeading
+	mdListItem
+	mdCode
+	mdQuote
+)
+
+// mdBlock is a block of a markdown document.
+type mdBlock struct {
+	kind    mdBlockKind
+	level   int    // heading level
+	marker  string // list item marker, such as "-" or "2."
+	ordered bool
+	indent  string // list item indentation
+	lines   []string
+}
+
+// parseMarkdownBlocks splits a markdown document into blocks.
+func parseMarkdownBlocks(s string) []mdBlock {
+	var (
+		blocks []mdBlock
+		cur    *mdBlock
+		fence  string
+	)
+	flush := func() {
+		if cur != nil {
+			blocks = append(blocks, *cur)
+			cur = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				flush()
+				fence = ""
+				continue
+			}
+			cur.lines = append(cur.lines, line)
+			continue
+		}
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence = trimmed[:3]
+			cur = &mdBlock{kind: mdCode}
+		case headingLevel(trimmed) > 0:
+			flush()
+			level := headingLevel(trimmed)
+			text := strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#")
+			blocks = append(blocks, mdBlock{kind: mdHeading, level: level, lines: []string{strings.TrimSpace(text)}})
+		case strings.HasPrefix(trimmed, ">"):
+			if cur == nil || cur.kind != mdQuote {
+				flush()
+				cur = &mdBlock{kind: mdQuote}
+			}
+			cur.lines = append(cur.lines, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+		default:
+			if marker, ordered, rest, ok := listMarker(trimmed); ok {
+				flush()
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				cur = &mdBlock{kind: mdListItem, marker: marker, ordered: ordered, indent: indent, lines: []string{rest}}
+				continue
+			}
+			if cur == nil || cur.kind == mdQuote {
+				flush()
+				cur = &mdBlock{kind: mdParagraph}
+			}
+			cur.lines = append(cur.lines, trimmed)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// headingLevel returns the level of an ATX heading line, or 0.
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(line) && line[n] != ' ') {
+		return 0
+	}
+	return n
+}
+
+// listMarker splits a list item line into its marker and text.
+func listMarker(line string) (marker string, ordered bool, rest string, ok bool) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return line[:1], false, strings.TrimSpace(line[2:]), true
+	}
+	n := 0
+	for n < len(line) && n < 9 && line[n] >= '0' && line[n] <= '9' {
+		n++
+	}
+	if n > 0 && n+1 < len(line) && (line[n] == '.' || line[n] == ')') && line[n+1] == ' ' {
+		return line[:n+1], true, strings.TrimSpace(line[n+2:]), true
+	}
+	return "", false, "", false
+}
+
+// renderMarkdown renders a markdown document as plain text or HTML.
+func renderMarkdown(s string, asHTML bool) string {
+	blocks := parseMarkdownBlocks(s)
+	var out []string
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+		switch {
+		case b.kind == mdListItem && asHTML:
+			// Consecutive items of the same kind form one list.
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			items := []string{"<" + tag + ">"}
+			for ; i < len(blocks) && blocks[i].kind == mdListItem && blocks[i].ordered == b.ordered; i++ {
+				items = append(items, "<li>"+renderInline(strings.Join(blocks[i].lines, " "), true)+"</li>")
+			}
+			i--
+			out = append(out, strings.Join(append(items, "</"+tag+">"), "\n"))
+		case b.kind == mdListItem:
+			// Consecutive items stay together without blank lines.
+			var items []string
+			for ; i < len(blocks) && blocks[i].kind == mdListItem; i++ {
+				items = append(items, blocks[i].indent+blocks[i].marker+" "+renderInline(strings.Join(blocks[i].lines, " "), false))
+			}
+			i--
+			out = append(out, strings.Join(items, "\n"))
+		case b.kind == mdCode && asHTML:
+			out = append(out, "<pre><code>"+html.EscapeString(strings.Join(b.lines, "\n"))+"</code></pre>")
+		case b.kind == mdCode:
+			out = append(out, strings.Join(b.lines, "\n"))
+		case b.kind == mdHeading && asHTML:
+			tag := "h" + string(rune('0'+b.level))
+			out = append(out, "<"+tag+">"+renderInline(b.lines[0], true)+"</"+tag+">")
+		case b.kind == mdQuote && asHTML:
+			out = append(out, "<blockquote><p>"+renderInline(strings.Join(b.lines, " "), true)+"</p></blockquote>")
+		case b.kind == mdParagraph && asHTML:
+			out = append(out, "<p>"+renderInline(strings.Join(b.lines, " "), true)+"</p>")
+		default:
+			out = append(out, renderInline(strings.Join(b.lines, " "), false))
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// renderInline renders the inline markup of a block: backslash escapes,
+// code spans, links, autolinks, images and emphasis.
+func renderInline(s string, asHTML bool) string {
+	var b strings.Builder
+	text := func(t string) {
+		if asHTML {
+			t = html.EscapeString(t)
+		}
+		b.WriteString(t)
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(markdownPunctuation, s[i+1]) >= 0:
+			text(s[i+1 : i+2])
+			i += 2
+			continue
+		case c == '`':
+			n := runLength(s[i:], '`')
+			fence := strings.Repeat("`", n)
+			if end := strings.Index(s[i+n:], fence); end >= 0 {
+				code := s[i+n : i+n+end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				if asHTML {
+					b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				} else {
+					b.WriteString(code)
+				}
+				i += n + end + n
+				continue
+			}
+			text(fence)
+			i += n
+			continue
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if label, url, n, ok := parseLink(s[i+1:]); ok {
+				if asHTML {
+					b.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(label) + `">`)
+				} else {
+					b.WriteString(renderInline(label, false))
+				}
+				i += 1 + n
+				continue
+			}
+		case c == '[':
+			if label, url, n, ok := parseLink(s[i:]); ok {
+				rendered := renderInline(label, asHTML)
+				switch {
+				case asHTML:
+					b.WriteString(`<a href="` + html.EscapeString(url) + `">` + rendered + "</a>")
+				case rendered == url || url == "":
+					b.WriteString(rendered)
+				default:
+					b.WriteString(rendered + " (" + url + ")")
+				}
+				i += n
+				continue
+			}
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				url := s[i+1 : i+end]
+				if (strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")) && !strings.ContainsAny(url, " <") {
+					if asHTML {
+						b.WriteString(`<a href="` + html.EscapeString(url) + `">` + html.EscapeString(url) + "</a>")
+					} else {
+						b.WriteString(url)
+					}
+					i += end + 1
+					continue
+				}
+			}
+		case c == '*' || c == '_':
+			n := min(runLength(s[i:], c), 2)
+			if inner, end, ok := parseEmphasis(s, i, n); ok {
+				rendered := renderInline(inner, asHTML)
+				switch {
+				case asHTML && n == 2:
+					b.WriteString("<strong>" + rendered + "</strong>")
+				case asHTML:
+					b.WriteString("<em>" + rendered + "</em>")
+				default:
+					b.WriteString(rendered)
+				}
+				i = end
+				continue
+			}
+			text(s[i : i+n])
+			i += n
+			continue
+		}
+		text(s[i : i+1])
+		i++
+	}
+	return b.String()
+}
+
+// markdownPunctuation lists the characters a backslash escapes.
+const markdownPunctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// runLength returns the number of times c repeats at the start of s.
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// parseLink parses "[label](url)" at the start of s and returns its parts
+// and length.
+func parseLink(s string) (label, url string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return "", "", 0, false
+			}
+			end := strings.IndexByte(s[i+2:], ')')
+			if end < 0 {
+				return "", "", 0, false
+			}
+			target := strings.TrimSpace(s[i+2 : i+2+end])
+			// Drop a link title: [label](url "title").
+			if sp := strings.IndexAny(target, " \t"); sp >= 0 {
+				target = target[:sp]
+			}
+			return s[1:i], strings.Trim(target, "<>"), i + 3 + end, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// parseEmphasis parses an emphasis span opened by n delimiters at s[i] and
+// returns its content and the index after the closing delimiters. An
+// underscore only delimits emphasis at a word boundary, so identifiers
+// such as resource_group_name are left alone.
+func parseEmphasis(s string, i, n int) (inner string, end int, ok bool) {
+	c := s[i]
+	delim := s[i : i+n]
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0, false
+	}
+	start := i + n
+	if start >= len(s) || s[start] == ' ' {
+		return "", 0, false
+	}
+	for j := start + 1; j+n <= len(s); j++ {
+		if s[j] == '`' {
+			// Delimiters inside code spans do not count.
+			if k := strings.IndexByte(s[j+1:], '`'); k >= 0 {
+				j += k + 1
+				continue
+			}
+		}
+		if s[j:j+n] != delim || s[j-1] == ' ' {
+			continue
+		}
+		after := j + n
+		if after < len(s) && s[after] == c {
+			continue
+		}
+		if c == '_' && after < len(s) && isWordByte(s[after]) {
+			continue
+		}
+		return s[start:j], after, true
+	}
+	return "", 0, false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,63 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptionPlaintext(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"emphasis", "A **thing** of _some_ *kind*.", "A thing of some kind."},
+		{"identifiers", "Set resource_group_name or `storage_account_id`.", "Set resource_group_name or storage_account_id."},
+		{"link", "See [the docs](https://example.com/docs \"Docs\").", "See the docs (https://example.com/docs)."},
+		{"autolink", "See <https://example.com>.", "See https://example.com."},
+		{"escape", `Use \*literal\* stars.`, "Use *literal* stars."},
+		{"soft breaks", "One\nsentence.\n\nTwo.", "One sentence.\n\nTwo."},
+		{"heading", "## Arguments ##\nText.", "Arguments\n\nText."},
+		{"list", "Values:\n\n* `Standard`\n* `Premium` (default)\n  tier\n1. first", "Values:\n\n* Standard\n* Premium (default) tier\n1. first"},
+		{"code", "Example:\n```hcl\nname = \"x_*y*_\"\n```", "Example:\n\nname = \"x_*y*_\""},
+		{"unclosed", "2 * 3 and a_b_", "2 * 3 and a_b_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DescriptionPlaintext(tt.in, tfjson.SchemaDescriptionKindMarkdown))
+		})
+	}
+
+	assert.Equal(t, "A **thing**.", DescriptionPlaintext("A **thing**.", tfjson.SchemaDescriptionKindPlain))
+	assert.Equal(t, "A **thing**.", DescriptionPlaintext("A **thing**.", ""))
+}
+
+func TestDescriptionHTML(t *testing.T) {
+	got := DescriptionHTML("# Title\nA **bold** [link](https://example.com?a=1&b=2) & `<code>`.\n\n- one\n- _two_\n\n```\nx < y\n```", tfjson.SchemaDescriptionKindMarkdown)
+	assert.Equal(t, "<h1>Title</h1>\n\n"+
+		`<p>A <strong>bold</strong> <a href="https://example.com?a=1&amp;b=2">link</a> &amp; <code>&lt;code&gt;</code>.</p>`+"\n\n"+
+		"<ul>\n<li>one</li>\n<li><em>two</em></li>\n</ul>\n\n"+
+		"<pre><code>x &lt; y</code></pre>", got)
+
+	assert.Equal(t, "<p>a &lt; b<br>\nc</p>\n<p>**d**</p>", DescriptionHTML("a < b\nc\n\n**d**", tfjson.SchemaDescriptionKindPlain))
+}
+
+func TestServer_WithPlaintextDescriptions(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	schema, err := s.GetResourceSchema(req, "example_thing", WithPlaintextDescriptions())
+	require.NoError(t, err)
+	assert.Equal(t, "A thing.", schema.Block.Description)
+	assert.Equal(t, tfjson.SchemaDescriptionKindPlain, schema.Block.DescriptionKind)
+	assert.Equal(t, "The name.", schema.Block.Attributes["name"].Description)
+
+	full, err := s.GetResourceSchema(req, "example_thing")
+	require.NoError(t, err)
+	assert.Equal(t, "A **thing**.", full.Block.Description, "the cached schema keeps its markdown")
+	assert.Equal(t, tfjson.SchemaDescriptionKindMarkdown, full.Block.DescriptionKind)
+}
@@ -0,0 +1,318 @@
+package tfpluginschema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+)
+
+// diagnoseMinFreeSpace is the free space below which Diagnose warns about
+// the cache and temporary directories: enough to download and extract one
+// of the largest providers.
+const diagnoseMinFreeSpace = 2 << 30
+
+// DiagnosticSeverity grades a Diagnostic.
+type DiagnosticSeverity int
+
+const (
+	// DiagnosticOK means the check passed.
+	DiagnosticOK DiagnosticSeverity = iota
+	// DiagnosticWarning means the check found something that may cause
+	// failures, or could not be carried out.
+	DiagnosticWarning
+	// DiagnosticError means the check found something that will cause
+	// failures.
+	DiagnosticError
+)
+
+// String returns "ok", "warning" or "error".
+func (d DiagnosticSeverity) String() string {
+	switch d {
+	case DiagnosticOK:
+		return "ok"
+	case DiagnosticWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// MarshalText encodes the severity as its String form.
+func (d DiagnosticSeverity) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Diagnostic is the finding of one check run by Diagnose.
+type Diagnostic struct {
+	Check    string             `json:"check"`          // What was checked, e.g. "registry registry.opentofu.org"
+	Severity DiagnosticSeverity `json:"severity"`       // How the check went
+	Detail   string             `json:"detail"`         // What the check found
+	Hint     string             `json:"hint,omitempty"` // What to do about a warning or error
+}
+
+// DiagnoseOption configures Diagnose.
+type DiagnoseOption func(*diagnoseOptions)
+
+type diagnoseOptions struct {
+	registries []RegistryType
+	probe      Request
+	skipExec   bool
+}
+
+// WithDiagnoseRegistries sets the registries Diagnose checks. By default it
+// checks the Server's default registry and every host given a token with
+// WithRegistryToken.
+func WithDiagnoseRegistries(registries ...RegistryType) DiagnoseOption {
+	return func(o *diagnoseOptions) {
+		o.registries = registries
+	}
+}
+
+// WithDiagnoseProbe sets the provider Diagnose downloads and executes, and
+// whose versions it lists to check registry tokens. The default is the
+// latest hashicorp/null, one of the smallest providers, from the default
+// registry. Private registries that do not mirror it need another one.
+func WithDiagnoseProbe(request Request) DiagnoseOption {
+	return func(o *diagnoseOptions) {
+		o.probe = request
+	}
+}
+
+// WithoutDiagnoseExec skips downloading and executing the probe provider.
+func WithoutDiagnoseExec() DiagnoseOption {
+	return func(o *diagnoseOptions) {
+		o.skipExec = true
+	}
+}
+
+// Diagnose checks the Server's environment and returns one Diagnostic per
+// check, with a hint for each problem found:
+//
+//   - every registry answers service discovery, and accepts the API token
+//     configured for it;
+//   - the cache and temporary directories are writable and have room for a
+//     large provider;
+//   - a small probe provider can be downloaded and executed.
+//
+// Problems are reported as Diagnostics rather than errors. The error is
+// only set when ctx is done before all checks have run; ctx bounds registry
+// requests but does not interrupt a provider download in progress.
+func (s *Server) Diagnose(ctx context.Context, opts ...DiagnoseOption) ([]Diagnostic, error) {
+	o := diagnoseOptions{
+		probe: Request{Namespace: "hashicorp", Name: "null"},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.probe.RegistryType == "" {
+		o.probe.RegistryType = s.registryOrDefault("")
+	}
+	registries := o.registries
+	if registries == nil {
+		registries = []RegistryType{s.registryOrDefault("")}
+		for _, host := range slices.Sorted(maps.Keys(s.registryTokens)) {
+			registries = append(registries, RegistryType(host))
+		}
+	}
+
+	var diags []Diagnostic
+	seen := make(map[RegistryType]bool)
+	for _, r := range registries {
+		r = normalizedRegistryType(r)
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		if err := ctx.Err(); err != nil {
+			return diags, err
+		}
+		diags = append(diags, s.diagnoseRegistry(ctx, r, o.probe)...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return diags, err
+	}
+	tempRoot := s.tempRoot
+	if tempRoot == "" {
+		tempRoot = os.TempDir()
+	}
+	diags = append(diags,
+		diagnoseDir("cache directory", s.cacheDir, "Choose another with WithCacheDir, --cache-dir or "+EnvCacheDir+"."),
+		diagnoseDir("temporary directory", tempRoot, "Choose another with WithTempDir or TMPDIR."),
+	)
+
+	if !o.skipExec {
+		if err := ctx.Err(); err != nil {
+			return diags, err
+		}
+		diags = append(diags, s.diagnoseExec(o.probe))
+	}
+	return diags, nil
+}
+
+// diagnoseRegistry checks that registry r answers service discovery and, if
+// the Server has a token for it, that the token is accepted when listing
+// the versions of probe.
+func (s *Server) diagnoseRegistry(ctx context.Context, r RegistryType, probe Request) []Diagnostic {
+	host := r.host()
+	check := "registry " + host
+	if s.offline {
+		return []Diagnostic{{Check: check, Severity: DiagnosticWarning, Detail: "not checked while offline"}}
+	}
+
+	discoveryURL := "https://" + host + serviceDiscoveryPath
+	status, err := s.diagnoseGet(ctx, discoveryURL)
+	switch {
+	case err != nil:
+		return []Diagnostic{{Check: check, Severity: DiagnosticError,
+			Detail: fmt.Sprintf("unreachable: %v", err),
+			Hint:   "Check the network connection, proxy settings (HTTPS_PROXY) and firewall rules for " + host + "."}}
+	case status != http.StatusOK:
+		return []Diagnostic{{Check: check, Severity: DiagnosticError,
+			Detail: fmt.Sprintf("service discovery %s => %d", discoveryURL, status),
+			Hint:   "Check that " + host + " is a Terraform or OpenTofu registry."}}
+	}
+	diags := []Diagnostic{{Check: check, Severity: DiagnosticOK, Detail: "reachable"}}
+
+	authCheck := "registry " + host + " token"
+	if _, ok := s.registryToken(host); !ok {
+		if _, custom := r.customHost(); custom {
+			diags = append(diags, Diagnostic{Check: authCheck, Severity: DiagnosticWarning,
+				Detail: "no API token configured",
+				Hint:   "Private registries usually need one: run \"terraform login " + host + "\" or set the TF_TOKEN_ variable for the host."})
+		}
+		return diags
+	}
+	u, err := VersionsRequest{Namespace: probe.Namespace, Name: probe.Name, RegistryType: r}.URL()
+	if err == nil {
+		u, err = s.registryURL(r, u)
+	}
+	if err == nil {
+		status, err = s.diagnoseGet(ctx, u)
+	}
+	switch {
+	case err != nil:
+		diags = append(diags, Diagnostic{Check: authCheck, Severity: DiagnosticWarning,
+			Detail: fmt.Sprintf("not checked: %v", err)})
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		diags = append(diags, Diagnostic{Check: authCheck, Severity: DiagnosticError,
+			Detail: fmt.Sprintf("rejected: %s => %d", u, status),
+			Hint:   "The token has expired or lacks access: run \"terraform login " + host + "\" again or update the TF_TOKEN_ variable for the host."})
+	default:
+		diags = append(diags, Diagnostic{Check: authCheck, Severity: DiagnosticOK, Detail: "accepted"})
+	}
+	return diags
+}
+
+// diagnoseGet sends a registry API GET request for u bounded by ctx and
+// returns the response status.
+func (s *Server) diagnoseGet(ctx context.Context, u string) (int, error) {
+	req, err := s.newRegistryRequest(http.MethodGet, u)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.doRegistryRequest(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// diagnoseDir checks that dir can be created and written to and has room
+// for a large provider. hint says how to choose another directory.
+func diagnoseDir(check, dir, hint string) Diagnostic {
+	d := Diagnostic{Check: check + " " + dir}
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		var f *os.File
+		if f, err = os.CreateTemp(dir, ".tfpluginschema-diagnose-*"); err == nil {
+			f.Close()
+			err = os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		d.Severity, d.Detail, d.Hint = DiagnosticError, fmt.Sprintf("not writable: %v", err), hint
+		return d
+	}
+
+	free, _, err := diskFree(dir)
+	switch {
+	case err != nil:
+		d.Severity, d.Detail = DiagnosticOK, "writable; free space unknown"
+	case free < diagnoseMinFreeSpace:
+		d.Severity = DiagnosticWarning
+		d.Detail = fmt.Sprintf("writable; only %s free", formatBytes(free))
+		d.Hint = fmt.Sprintf("Large providers need up to %s to download and extract. Free some space or %s",
+			formatBytes(diagnoseMinFreeSpace), lowerFirst(hint))
+	default:
+		d.Severity, d.Detail = DiagnosticOK, fmt.Sprintf("writable; %s free", formatBytes(free))
+	}
+	return d
+}
+
+// diagnoseExec downloads probe and runs it to retrieve its schema names.
+func (s *Server) diagnoseExec(probe Request) Diagnostic {
+	d := Diagnostic{Check: "provider execution"}
+	if !s.pluginExec {
+		d.Severity = DiagnosticWarning
+		if pluginExecSupported {
+			d.Detail = "disabled"
+			d.Hint = "Only schema bundles and schema JSON can be served; remove WithPluginExec(false) to execute providers."
+		} else {
+			d.Detail = "not supported on this platform"
+			d.Hint = "Serve schemas from schema bundles generated on a supported platform."
+		}
+		return d
+	}
+
+	start := time.Now()
+	request, err := s.prepareRequest(probe)
+	if err == nil {
+		var client universalProvider
+		if client, _, err = s.startProvider(request, cacheKey(request)); err == nil {
+			_, err = client.metadata()
+			if errors.Is(err, errMetadataUnsupported) {
+				_, err = client.rawSchema()
+			}
+			client.close()
+		}
+	}
+	name := probe.Namespace + "/" + probe.Name
+	if err != nil {
+		d.Severity = DiagnosticError
+		d.Detail = fmt.Sprintf("failed to run %s: %v", name, err)
+		switch {
+		case errors.Is(err, ErrInsufficientDiskSpace):
+			d.Hint = "Free some space in the cache and temporary directories."
+		case errors.Is(err, ErrPluginNotFound), errors.Is(err, ErrNoMatchingVersion):
+			d.Hint = "The registry does not serve " + name + " for this platform; choose another probe provider."
+		default:
+			d.Hint = "Check that the temporary and cache directories allow executing files (not mounted noexec) and that no security software blocks the provider."
+		}
+		return d
+	}
+	d.Severity = DiagnosticOK
+	d.Detail = fmt.Sprintf("ran %s %s in %s", name, request.Version, time.Since(start).Round(time.Millisecond))
+	return d
+}
+
+// lowerFirst lower-cases the first letter of an ASCII sentence.
+func lowerFirst(s string) string {
+	if s == "" || s[0] < 'A' || s[0] > 'Z' {
+		return s
+	}
+	return string(s[0]+'a'-'A') + s[1:]
+}
+
+// DiagnosticsFailed reports whether any of diags is an error.
+func DiagnosticsFailed(diags []Diagnostic) bool {
+	return slices.ContainsFunc(diags, func(d Diagnostic) bool { return d.Severity == DiagnosticError })
+}
@@ -0,0 +1,102 @@
+package tfpluginschema
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// diagnosticsByCheck indexes diags by their Check.
+func diagnosticsByCheck(diags []Diagnostic) map[string]Diagnostic {
+	m := make(map[string]Diagnostic, len(diags))
+	for _, d := range diags {
+		m[d.Check] = d
+	}
+	return m
+}
+
+func TestServer_Diagnose(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == serviceDiscoveryPath:
+			_, _ = w.Write([]byte(`{"providers.v1": "/v1/providers/"}`))
+		case r.Header.Get("Authorization") == "Bearer good":
+			_, _ = w.Write([]byte(`{"versions": []}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	cacheDir, tempDir := t.TempDir(), t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithTempDir(tempDir), WithHTTPClient(client), WithPluginExec(false),
+		WithRegistryToken("registry.terraform.io", "good"), WithRegistryToken("registry.example.com", "bad"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	diags, err := s.Diagnose(context.Background())
+	require.NoError(t, err)
+	byCheck := diagnosticsByCheck(diags)
+
+	assert.Equal(t, DiagnosticOK, byCheck["registry registry.opentofu.org"].Severity)
+	assert.NotContains(t, byCheck, "registry registry.opentofu.org token", "public registries need no token")
+	assert.Equal(t, DiagnosticOK, byCheck["registry registry.terraform.io token"].Severity)
+	rejected := byCheck["registry registry.example.com token"]
+	assert.Equal(t, DiagnosticError, rejected.Severity)
+	assert.Contains(t, rejected.Detail, "=> 401")
+	assert.Contains(t, rejected.Hint, "terraform login registry.example.com")
+
+	assert.NotEqual(t, DiagnosticError, byCheck["cache directory "+cacheDir].Severity)
+	assert.NotEqual(t, DiagnosticError, byCheck["temporary directory "+tempDir].Severity)
+	assert.Equal(t, DiagnosticWarning, byCheck["provider execution"].Severity)
+	assert.True(t, DiagnosticsFailed(diags))
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the writability check cleans up after itself")
+}
+
+func TestServer_Diagnose_Problems(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o644))
+	s := NewServer(nil, WithCacheDir(filepath.Join(blocker, "cache")), WithHTTPClient(client), WithDefaultRegistry(RegistryTypeTerraform))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	diags, err := s.Diagnose(context.Background(), WithoutDiagnoseExec(), WithDiagnoseRegistries("registry.example.com"))
+	require.NoError(t, err)
+	byCheck := diagnosticsByCheck(diags)
+
+	require.Len(t, diags, 3)
+	assert.Equal(t, DiagnosticError, byCheck["registry registry.example.com"].Severity)
+	assert.Contains(t, byCheck["registry registry.example.com"].Detail, "=> 404")
+	cache := byCheck["cache directory "+filepath.Join(blocker, "cache")]
+	assert.Equal(t, DiagnosticError, cache.Severity)
+	assert.Contains(t, cache.Detail, "not writable")
+	assert.Contains(t, cache.Hint, "--cache-dir")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Diagnose(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestServer_Diagnose_Offline(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithOffline(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	diags, err := s.Diagnose(context.Background(), WithoutDiagnoseExec())
+	require.NoError(t, err)
+	assert.Equal(t, "not checked while offline", diags[0].Detail)
+	assert.False(t, DiagnosticsFailed(diags))
+}
+
+func TestDiagnosticSeverity_MarshalText(t *testing.T) {
+	b, err := DiagnosticWarning.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "warning", string(b))
+}
@@ -0,0 +1,90 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// extractionSizeMultiplier estimates the extracted size of a provider
+// archive from its compressed size. Provider binaries compress to roughly a
+// third or a quarter of their size.
+const extractionSizeMultiplier = 4
+
+// ErrInsufficientDiskSpace is returned when the preflight check finds that
+// the temporary or cache filesystem lacks room for a download and its
+// extraction.
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// WithDiskSpaceCheck enables or disables the disk space preflight check
+// performed before a provider is downloaded (enabled by default). The check
+// requires the download host to report the archive size; when it does not,
+// or when free space cannot be determined, the check is skipped.
+func WithDiskSpaceCheck(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.skipDiskSpaceCheck = !enabled
+	}
+}
+
+// checkDiskSpace verifies that tmpDir has room for download more bytes of
+// archive and cacheDir has room for the archive of size archiveSize once
+// extracted. When both directories live on the same filesystem the
+// requirements are added together. Directories whose free space cannot be
+// determined are not checked.
+func (s *Server) checkDiskSpace(tmpDir, cacheDir string, download, archiveSize int64) error {
+	if s.skipDiskSpaceCheck || archiveSize <= 0 {
+		return nil
+	}
+	extracted := uint64(archiveSize) * extractionSizeMultiplier
+	needTmp := uint64(max(download, 0))
+
+	tmpFree, tmpFS, tmpErr := diskFree(tmpDir)
+	cacheFree, cacheFS, cacheErr := diskFree(cacheDir)
+	l := s.logger(logComponentDownload)
+	if tmpErr != nil {
+		l.Debug("Could not determine free space; skipping check", "dir", tmpDir, "error", tmpErr)
+	}
+	if cacheErr != nil {
+		l.Debug("Could not determine free space; skipping check", "dir", cacheDir, "error", cacheErr)
+	}
+
+	if tmpErr == nil && cacheErr == nil && tmpFS == cacheFS {
+		if need := needTmp + extracted; cacheFree < need {
+			return fmt.Errorf("%w: %s has %s free, need %s to download and extract %s archive",
+				ErrInsufficientDiskSpace, cacheDir, formatBytes(cacheFree), formatBytes(need), formatBytes(uint64(archiveSize)))
+		}
+		return nil
+	}
+	if tmpErr == nil && tmpFree < needTmp {
+		return fmt.Errorf("%w: %s has %s free, need %s to download archive",
+			ErrInsufficientDiskSpace, tmpDir, formatBytes(tmpFree), formatBytes(needTmp))
+	}
+	if cacheErr == nil && cacheFree < extracted {
+		return fmt.Errorf("%w: %s has %s free, need %s to extract %s archive",
+			ErrInsufficientDiskSpace, cacheDir, formatBytes(cacheFree), formatBytes(extracted), formatBytes(uint64(archiveSize)))
+	}
+	return nil
+}
+
+// fileSize returns the size of the file at path, or 0 if it does not exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// formatBytes renders n using binary units, e.g. "1.5 GiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
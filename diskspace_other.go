@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || dragonfly || windows)
+
+package tfpluginschema
+
+import "errors"
+
+// diskFree is not implemented on this platform, which disables the disk
+// space preflight check.
+func diskFree(string) (uint64, string, error) {
+	return 0, "", errors.New("free space query not supported on this platform")
+}
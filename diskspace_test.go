@@ -0,0 +1,56 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.0 KiB", formatBytes(1024))
+	assert.Equal(t, "1.5 MiB", formatBytes(3<<19))
+	assert.Equal(t, "2.0 GiB", formatBytes(2<<30))
+}
+
+func TestDiskFree(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "dragonfly", "windows":
+	default:
+		t.Skip("free space query not supported on " + runtime.GOOS)
+	}
+	dir := t.TempDir()
+	free, fs, err := diskFree(dir)
+	require.NoError(t, err)
+	assert.NotZero(t, free)
+	_, fs2, err := diskFree(dir)
+	require.NoError(t, err)
+	assert.Equal(t, fs, fs2)
+}
+
+func TestServer_CheckDiskSpace(t *testing.T) {
+	if _, _, err := diskFree(t.TempDir()); err != nil {
+		t.Skip(err)
+	}
+	s := NewServer(nil)
+	t.Cleanup(func() { _ = s.Cleanup() })
+	tmp, cache := t.TempDir(), t.TempDir()
+
+	assert.NoError(t, s.checkDiskSpace(tmp, cache, 1024, 1024))
+	assert.NoError(t, s.checkDiskSpace(tmp, cache, -1, -1), "unknown size skips the check")
+
+	err := s.checkDiskSpace(tmp, cache, math.MaxInt64/8, math.MaxInt64/8)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInsufficientDiskSpace))
+	assert.Contains(t, err.Error(), "free, need")
+}
+
+func TestWithDiskSpaceCheck_Disabled(t *testing.T) {
+	s := NewServer(nil, WithDiskSpaceCheck(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	assert.NoError(t, s.checkDiskSpace(t.TempDir(), t.TempDir(), math.MaxInt64/8, math.MaxInt64/8))
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package tfpluginschema
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// diskFree returns the space available to unprivileged users on the
+// filesystem containing path, and an identifier for that filesystem.
+func diskFree(path string) (uint64, string, error) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(path, &fs); err != nil {
+		return 0, "", err
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, "", err
+	}
+	return uint64(fs.Bavail) * uint64(fs.Bsize), fmt.Sprint(st.Dev), nil
+}
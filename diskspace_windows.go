@@ -0,0 +1,24 @@
+//go:build windows
+
+package tfpluginschema
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// diskFree returns the space available to the caller on the volume
+// containing path, and the volume name as its identifier.
+func diskFree(path string) (uint64, string, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, "", err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, "", err
+	}
+	return free, strings.ToLower(filepath.VolumeName(path)), nil
+}
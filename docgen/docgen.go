@@ -0,0 +1,265 @@
+// Package docgen renders provider schemas as Markdown reference
+// documentation, in the layout used by the Terraform and OpenTofu registries.
+package docgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Resource renders the Markdown documentation for the resource type name
+// described by s.
+func Resource(name string, s *tfjson.Schema) string {
+	return render(name, "Resource", s, nil)
+}
+
+// render writes the page for a schema: a title, the block description, the
+// examples and the attribute reference, followed by one section per nested
+// schema.
+func render(name, kind string, s *tfjson.Schema, examples []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s (%s)\n", name, kind)
+
+	var block *tfjson.SchemaBlock
+	if s != nil {
+		block = s.Block
+	}
+	if block == nil {
+		block = &tfjson.SchemaBlock{}
+	}
+	if block.Deprecated {
+		fmt.Fprintf(&sb, "\n~> **Deprecated** This %s is deprecated.\n", strings.ToLower(kind))
+	}
+	if d := strings.TrimSpace(block.Description); d != "" {
+		fmt.Fprintf(&sb, "\n%s\n", d)
+	}
+	if len(examples) > 0 {
+		sb.WriteString("\n## Example Usage\n")
+		for _, e := range examples {
+			fmt.Fprintf(&sb, "\n```terraform\n%s\n```\n", strings.TrimRight(e, "\n"))
+		}
+	}
+
+	sb.WriteString("\n## Schema\n")
+	nested := writeBlock(&sb, block, nil)
+	for len(nested) > 0 {
+		n := nested[0]
+		nested = nested[1:]
+		fmt.Fprintf(&sb, "\n<a id=%q></a>\n### Nested Schema for `%s`\n", n.anchor(), strings.Join(n.path, "."))
+		if n.block != nil {
+			nested = append(nested, writeBlock(&sb, n.block, n.path)...)
+		} else {
+			nested = append(nested, writeAttributes(&sb, n.attributes, n.path)...)
+		}
+	}
+	return sb.String()
+}
+
+// nestedSchema is a nested block or nested attribute whose own section is
+// rendered after the section that references it.
+type nestedSchema struct {
+	path       []string
+	block      *tfjson.SchemaBlock
+	attributes map[string]*tfjson.SchemaAttribute
+}
+
+// anchor returns the HTML id of the nested schema's section.
+func (n nestedSchema) anchor() string {
+	prefix := "nestedatt"
+	if n.block != nil {
+		prefix = "nestedblock"
+	}
+	return prefix + "--" + strings.Join(n.path, "--")
+}
+
+// item is one attribute or nested block in an attribute reference list.
+type item struct {
+	name string
+	line string
+}
+
+// writeBlock writes the Required, Optional and Read-Only lists for b's
+// attributes and nested blocks, and returns the nested schemas it referenced.
+func writeBlock(sb *strings.Builder, b *tfjson.SchemaBlock, path []string) []nestedSchema {
+	var required, optional, readOnly []item
+	var nested []nestedSchema
+
+	for name, a := range b.Attributes {
+		if a == nil {
+			continue
+		}
+		line, n := attributeLine(name, a, path)
+		if n != nil {
+			nested = append(nested, *n)
+		}
+		switch {
+		case a.Required:
+			required = append(required, item{name, line})
+		case a.Optional:
+			optional = append(optional, item{name, line})
+		default:
+			readOnly = append(readOnly, item{name, line})
+		}
+	}
+
+	for name, bt := range b.NestedBlocks {
+		if bt == nil {
+			continue
+		}
+		n := nestedSchema{path: childPath(path, name), block: bt.Block}
+		if n.block == nil {
+			n.block = &tfjson.SchemaBlock{}
+		}
+		nested = append(nested, n)
+		line := entry(name, blockType(bt), n.block.Deprecated, n.block.Description, n.anchor())
+		if bt.MinItems > 0 {
+			required = append(required, item{name, line})
+		} else {
+			optional = append(optional, item{name, line})
+		}
+	}
+
+	writeItems(sb, "Required", required)
+	writeItems(sb, "Optional", optional)
+	writeItems(sb, "Read-Only", readOnly)
+
+	slices.SortFunc(nested, func(a, b nestedSchema) int {
+		return strings.Compare(a.anchor(), b.anchor())
+	})
+	return nested
+}
+
+// writeAttributes writes the attribute lists for the attributes of a nested
+// attribute type.
+func writeAttributes(sb *strings.Builder, attrs map[string]*tfjson.SchemaAttribute, path []string) []nestedSchema {
+	return writeBlock(sb, &tfjson.SchemaBlock{Attributes: attrs}, path)
+}
+
+// writeItems writes a titled list of items sorted by name. Empty lists are
+// omitted.
+func writeItems(sb *strings.Builder, title string, items []item) {
+	if len(items) == 0 {
+		return
+	}
+	slices.SortFunc(items, func(a, b item) int {
+		return strings.Compare(a.name, b.name)
+	})
+	fmt.Fprintf(sb, "\n### %s\n\n", title)
+	for _, it := range items {
+		sb.WriteString(it.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// attributeLine returns the list entry for an attribute and, if it has a
+// nested type, the nested schema it references.
+func attributeLine(name string, a *tfjson.SchemaAttribute, path []string) (string, *nestedSchema) {
+	var flags []string
+	var n *nestedSchema
+	if a.AttributeNestedType != nil {
+		flags = append(flags, nestedAttributeType(a.AttributeNestedType))
+		n = &nestedSchema{path: childPath(path, name), attributes: a.AttributeNestedType.Attributes}
+	} else {
+		flags = append(flags, typeName(a.AttributeType))
+	}
+	if a.Sensitive {
+		flags = append(flags, "Sensitive")
+	}
+	if a.WriteOnly {
+		flags = append(flags, "Write-only")
+	}
+
+	anchor := ""
+	if n != nil {
+		anchor = n.anchor()
+	}
+	return entry(name, strings.Join(flags, ", "), a.Deprecated, a.Description, anchor), n
+}
+
+// entry formats one list entry, linking to the nested schema section with
+// the given anchor if it is not empty.
+func entry(name, typ string, deprecated bool, description, anchor string) string {
+	parts := []string{fmt.Sprintf("- `%s` (%s)", name, typ)}
+	if deprecated {
+		parts = append(parts, "**Deprecated**")
+	}
+	if d := strings.TrimSpace(description); d != "" {
+		parts = append(parts, d)
+	}
+	if anchor != "" {
+		parts = append(parts, fmt.Sprintf("(see [below for nested schema](#%s))", anchor))
+	}
+	return strings.Join(parts, " ")
+}
+
+func childPath(path []string, name string) []string {
+	return append(slices.Clip(path), name)
+}
+
+// blockType describes a nested block's nesting mode and item limits, e.g.
+// "Block List, Min: 1, Max: 1".
+func blockType(bt *tfjson.SchemaBlockType) string {
+	s := "Block"
+	switch bt.NestingMode {
+	case tfjson.SchemaNestingModeList:
+		s = "Block List"
+	case tfjson.SchemaNestingModeSet:
+		s = "Block Set"
+	case tfjson.SchemaNestingModeMap:
+		s = "Block Map"
+	}
+	if bt.MinItems > 0 {
+		s += fmt.Sprintf(", Min: %d", bt.MinItems)
+	}
+	if bt.MaxItems > 0 {
+		s += fmt.Sprintf(", Max: %d", bt.MaxItems)
+	}
+	return s
+}
+
+// nestedAttributeType describes a nested attribute's nesting mode.
+func nestedAttributeType(nt *tfjson.SchemaNestedAttributeType) string {
+	switch nt.NestingMode {
+	case tfjson.SchemaNestingModeList:
+		return "Attributes List"
+	case tfjson.SchemaNestingModeSet:
+		return "Attributes Set"
+	case tfjson.SchemaNestingModeMap:
+		return "Attributes Map"
+	default:
+		return "Attributes"
+	}
+}
+
+// typeName returns a human-readable name for an attribute type, e.g.
+// "List of String".
+func typeName(t cty.Type) string {
+	switch {
+	case t == cty.NilType:
+		return "Unknown"
+	case t == cty.DynamicPseudoType:
+		return "Dynamic"
+	case t == cty.String:
+		return "String"
+	case t == cty.Number:
+		return "Number"
+	case t == cty.Bool:
+		return "Boolean"
+	case t.IsListType():
+		return "List of " + typeName(t.ElementType())
+	case t.IsSetType():
+		return "Set of " + typeName(t.ElementType())
+	case t.IsMapType():
+		return "Map of " + typeName(t.ElementType())
+	case t.IsObjectType():
+		return "Object"
+	case t.IsTupleType():
+		return "Tuple"
+	default:
+		return t.FriendlyName()
+	}
+}
@@ -0,0 +1,116 @@
+package docgen
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestResource(t *testing.T) {
+	s := &tfjson.Schema{Block: &tfjson.SchemaBlock{
+		Description: "Manages a widget.",
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"name":     {AttributeType: cty.String, Required: true, Description: "The widget name."},
+			"tags":     {AttributeType: cty.Map(cty.String), Optional: true},
+			"id":       {AttributeType: cty.String, Computed: true},
+			"password": {AttributeType: cty.String, Optional: true, Sensitive: true, Deprecated: true},
+			"settings": {
+				Optional: true,
+				AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+					NestingMode: tfjson.SchemaNestingModeSingle,
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"enabled": {AttributeType: cty.Bool, Required: true},
+					},
+				},
+			},
+		},
+		NestedBlocks: map[string]*tfjson.SchemaBlockType{
+			"rule": {
+				NestingMode: tfjson.SchemaNestingModeList,
+				MinItems:    1,
+				Block: &tfjson.SchemaBlock{
+					Description: "A rule.",
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"ports": {AttributeType: cty.List(cty.Number), Optional: true},
+					},
+				},
+			},
+		},
+	}}
+
+	want := "# example_widget (Resource)\n" +
+		"\n" +
+		"Manages a widget.\n" +
+		"\n" +
+		"## Schema\n" +
+		"\n" +
+		"### Required\n" +
+		"\n" +
+		"- `name` (String) The widget name.\n" +
+		"- `rule` (Block List, Min: 1) A rule. (see [below for nested schema](#nestedblock--rule))\n" +
+		"\n" +
+		"### Optional\n" +
+		"\n" +
+		"- `password` (String, Sensitive) **Deprecated**\n" +
+		"- `settings` (Attributes) (see [below for nested schema](#nestedatt--settings))\n" +
+		"- `tags` (Map of String)\n" +
+		"\n" +
+		"### Read-Only\n" +
+		"\n" +
+		"- `id` (String)\n" +
+		"\n" +
+		"<a id=\"nestedatt--settings\"></a>\n" +
+		"### Nested Schema for `settings`\n" +
+		"\n" +
+		"### Required\n" +
+		"\n" +
+		"- `enabled` (Boolean)\n" +
+		"\n" +
+		"<a id=\"nestedblock--rule\"></a>\n" +
+		"### Nested Schema for `rule`\n" +
+		"\n" +
+		"### Optional\n" +
+		"\n" +
+		"- `ports` (List of Number)\n"
+
+	assert.Equal(t, want, Resource("example_widget", s))
+}
+
+func TestResource_NestedPaths(t *testing.T) {
+	s := &tfjson.Schema{Block: &tfjson.SchemaBlock{
+		NestedBlocks: map[string]*tfjson.SchemaBlockType{
+			"outer": {
+				NestingMode: tfjson.SchemaNestingModeSingle,
+				Block: &tfjson.SchemaBlock{
+					NestedBlocks: map[string]*tfjson.SchemaBlockType{
+						"inner": {NestingMode: tfjson.SchemaNestingModeSet, MaxItems: 2},
+					},
+				},
+			},
+		},
+	}}
+
+	got := Resource("r", s)
+	assert.Contains(t, got, "- `inner` (Block Set, Max: 2) (see [below for nested schema](#nestedblock--outer--inner))")
+	assert.Contains(t, got, "<a id=\"nestedblock--outer--inner\"></a>\n### Nested Schema for `outer.inner`\n")
+}
+
+func TestResource_EmptySchema(t *testing.T) {
+	assert.Equal(t, "# r (Resource)\n\n## Schema\n", Resource("r", nil))
+}
+
+func TestTypeName(t *testing.T) {
+	tests := map[string]cty.Type{
+		"Dynamic":               cty.DynamicPseudoType,
+		"Map of Dynamic":        cty.Map(cty.DynamicPseudoType),
+		"Set of List of String": cty.Set(cty.List(cty.String)),
+		"Object":                cty.Object(map[string]cty.Type{"a": cty.String}),
+		"Tuple":                 cty.Tuple([]cty.Type{cty.String}),
+		"Unknown":               cty.NilType,
+	}
+	for want, ty := range tests {
+		assert.Equal(t, want, typeName(ty))
+	}
+}
@@ -0,0 +1,56 @@
+package docgen
+
+import (
+	"bufio"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ResourceWithExamples renders the Markdown documentation for the resource
+// type name described by s, like Resource, with an "Example Usage" section
+// holding examples, such as those extracted from the provider's published
+// documentation with Examples.
+func ResourceWithExamples(name string, s *tfjson.Schema, examples []string) string {
+	return render(name, "Resource", s, examples)
+}
+
+// Examples returns the HCL code blocks, fenced as terraform, hcl or tf, of
+// the level-2 sections of a registry documentation page whose heading
+// starts with "Example", such as "## Example Usage".
+func Examples(markdown string) []string {
+	var (
+		examples []string
+		code     []string
+		inCode   bool
+		keep     bool
+		inUsage  bool
+	)
+	sc := bufio.NewScanner(strings.NewReader(markdown))
+	sc.Buffer(nil, len(markdown)+1)
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if inCode {
+			if strings.HasPrefix(trimmed, "```") {
+				inCode = false
+				if keep {
+					examples = append(examples, strings.Join(code, "\n")+"\n")
+				}
+				continue
+			}
+			code = append(code, line)
+			continue
+		}
+		if fence, ok := strings.CutPrefix(trimmed, "```"); ok {
+			lang := strings.ToLower(strings.TrimSpace(fence))
+			inCode, code = true, nil
+			keep = inUsage && (lang == "terraform" || lang == "hcl" || lang == "tf")
+			continue
+		}
+		if heading, ok := strings.CutPrefix(trimmed, "## "); ok {
+			inUsage = strings.HasPrefix(strings.TrimSpace(heading), "Example")
+		}
+	}
+	return examples
+}
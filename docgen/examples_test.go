@@ -0,0 +1,80 @@
+package docgen
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const registryPage = "---\n" +
+	"subcategory: \"Compute\"\n" +
+	"---\n" +
+	"\n" +
+	"# Resource: example_widget\n" +
+	"\n" +
+	"```terraform\n" +
+	"# not an example\n" +
+	"```\n" +
+	"\n" +
+	"## Example Usage\n" +
+	"\n" +
+	"### Basic\n" +
+	"\n" +
+	"```terraform\n" +
+	"resource \"example_widget\" \"this\" {\n" +
+	"  name = \"a\"\n" +
+	"}\n" +
+	"```\n" +
+	"\n" +
+	"```shell\n" +
+	"terraform apply\n" +
+	"```\n" +
+	"\n" +
+	"## Example Usage with Rules\n" +
+	"\n" +
+	"```hcl\n" +
+	"resource \"example_widget\" \"rules\" {}\n" +
+	"```\n" +
+	"\n" +
+	"## Import\n" +
+	"\n" +
+	"```terraform\n" +
+	"import {}\n" +
+	"```\n"
+
+func TestExamples(t *testing.T) {
+	assert.Equal(t, []string{
+		"resource \"example_widget\" \"this\" {\n  name = \"a\"\n}\n",
+		"resource \"example_widget\" \"rules\" {}\n",
+	}, Examples(registryPage))
+	assert.Empty(t, Examples("# example_widget\n\nNo examples.\n"))
+}
+
+func TestResourceWithExamples(t *testing.T) {
+	s := &tfjson.Schema{Block: &tfjson.SchemaBlock{
+		Description: "Manages a widget.",
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"name": {AttributeType: cty.String, Required: true},
+		},
+	}}
+
+	want := "# example_widget (Resource)\n" +
+		"\n" +
+		"Manages a widget.\n" +
+		"\n" +
+		"## Example Usage\n" +
+		"\n" +
+		"```terraform\n" +
+		"resource \"example_widget\" \"this\" {}\n" +
+		"```\n" +
+		"\n" +
+		"## Schema\n" +
+		"\n" +
+		"### Required\n" +
+		"\n" +
+		"- `name` (String)\n"
+	assert.Equal(t, want, ResourceWithExamples("example_widget", s, []string{"resource \"example_widget\" \"this\" {}\n"}))
+	assert.Equal(t, Resource("example_widget", s), ResourceWithExamples("example_widget", s, nil))
+}
@@ -0,0 +1,144 @@
+package docgen
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FunctionDoc is the reference documentation for one provider-defined
+// function. It marshals to JSON for tools that render their own pages;
+// Markdown renders it in the layout used by the registries.
+type FunctionDoc struct {
+	Name               string         `json:"name"`
+	Summary            string         `json:"summary,omitempty"`
+	Description        string         `json:"description,omitempty"`
+	DeprecationMessage string         `json:"deprecation_message,omitempty"`
+	Signature          string         `json:"signature"`
+	Parameters         []ParameterDoc `json:"parameters,omitempty"`
+	VariadicParameter  *ParameterDoc  `json:"variadic_parameter,omitempty"`
+	ReturnType         string         `json:"return_type"`
+	// Example is a placeholder usage example, to be replaced by a real one.
+	// PROVIDER stands for the provider's local name.
+	Example string `json:"example"`
+}
+
+// ParameterDoc documents one function parameter.
+type ParameterDoc struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Nullable    bool   `json:"nullable,omitempty"`
+}
+
+// Functions returns the documentation for every function in ps, sorted by
+// name.
+func Functions(ps *tfjson.ProviderSchema) []FunctionDoc {
+	if ps == nil {
+		return nil
+	}
+	docs := make([]FunctionDoc, 0, len(ps.Functions))
+	for _, name := range slices.Sorted(maps.Keys(ps.Functions)) {
+		if f := ps.Functions[name]; f != nil {
+			docs = append(docs, Function(name, f))
+		}
+	}
+	return docs
+}
+
+// Function returns the documentation for the function name with signature f.
+func Function(name string, f *tfjson.FunctionSignature) FunctionDoc {
+	d := FunctionDoc{
+		Name:               name,
+		Summary:            strings.TrimSpace(f.Summary),
+		Description:        strings.TrimSpace(f.Description),
+		DeprecationMessage: strings.TrimSpace(f.DeprecationMessage),
+		ReturnType:         typeName(f.ReturnType),
+	}
+	var params, args []string
+	for _, p := range f.Parameters {
+		if p == nil {
+			continue
+		}
+		d.Parameters = append(d.Parameters, parameterDoc(p))
+		params = append(params, p.Name+" "+constraintName(p.Type))
+		args = append(args, p.Name)
+	}
+	if vp := f.VariadicParameter; vp != nil {
+		pd := parameterDoc(vp)
+		d.VariadicParameter = &pd
+		params = append(params, vp.Name+" ..."+constraintName(vp.Type))
+		args = append(args, vp.Name)
+	}
+	d.Signature = fmt.Sprintf("%s(%s) %s", name, strings.Join(params, ", "), constraintName(f.ReturnType))
+	d.Example = fmt.Sprintf("# TODO: replace with a real example\noutput %q {\n  value = provider::PROVIDER::%s(%s)\n}\n", name, name, strings.Join(args, ", "))
+	return d
+}
+
+func parameterDoc(p *tfjson.FunctionParameter) ParameterDoc {
+	return ParameterDoc{
+		Name:        p.Name,
+		Type:        typeName(p.Type),
+		Description: strings.TrimSpace(p.Description),
+		Nullable:    p.IsNullable,
+	}
+}
+
+// constraintName returns the type as written in function signatures, e.g.
+// "list of string".
+func constraintName(t cty.Type) string {
+	if t == cty.NilType {
+		return "unknown"
+	}
+	return t.FriendlyNameForConstraint()
+}
+
+// Markdown renders d as a registry function page: title, summary and
+// description, an example, the signature and the numbered argument list.
+func (d FunctionDoc) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# function: %s\n", d.Name)
+	if d.DeprecationMessage != "" {
+		fmt.Fprintf(&sb, "\n~> **Deprecated** %s\n", d.DeprecationMessage)
+	}
+	if d.Summary != "" {
+		fmt.Fprintf(&sb, "\n%s\n", d.Summary)
+	}
+	if d.Description != "" && d.Description != d.Summary {
+		fmt.Fprintf(&sb, "\n%s\n", d.Description)
+	}
+
+	fmt.Fprintf(&sb, "\n## Example Usage\n\n```terraform\n%s```\n", d.Example)
+	fmt.Fprintf(&sb, "\n## Signature\n\n```text\n%s\n```\n", d.Signature)
+
+	if len(d.Parameters) > 0 || d.VariadicParameter != nil {
+		sb.WriteString("\n## Arguments\n\n")
+		for i, p := range d.Parameters {
+			fmt.Fprintf(&sb, "%d. %s\n", i+1, parameterLine(p, false))
+		}
+		if d.VariadicParameter != nil {
+			fmt.Fprintf(&sb, "%d. %s\n", len(d.Parameters)+1, parameterLine(*d.VariadicParameter, true))
+		}
+	}
+	return sb.String()
+}
+
+// parameterLine formats one entry of the argument list.
+func parameterLine(p ParameterDoc, variadic bool) string {
+	flags := []string{p.Type}
+	if variadic {
+		flags = append([]string{"Variadic"}, flags...)
+	}
+	if p.Nullable {
+		flags = append(flags, "Nullable")
+	}
+	line := fmt.Sprintf("`%s` (%s)", p.Name, strings.Join(flags, ", "))
+	if p.Description != "" {
+		line += " " + p.Description
+	}
+	return line
+}
@@ -0,0 +1,61 @@
+package docgen
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFunctions(t *testing.T) {
+	ps := &tfjson.ProviderSchema{Functions: map[string]*tfjson.FunctionSignature{
+		"parse": {
+			Summary:     "Parses an ARN.",
+			Description: "Parses an ARN into its components.",
+			Parameters: []*tfjson.FunctionParameter{
+				{Name: "arn", Type: cty.String, Description: "The ARN to parse."},
+			},
+			VariadicParameter: &tfjson.FunctionParameter{Name: "parts", Type: cty.List(cty.String), IsNullable: true},
+			ReturnType:        cty.Object(map[string]cty.Type{"region": cty.String}),
+		},
+		"noop": {ReturnType: cty.Bool, DeprecationMessage: "Use something else."},
+		"nil":  nil,
+	}}
+
+	docs := Functions(ps)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "noop", docs[0].Name)
+	assert.Equal(t, "parse", docs[1].Name)
+
+	parse := docs[1]
+	assert.Equal(t, "parse(arn string, parts ...list of string) object", parse.Signature)
+	assert.Equal(t, "Object", parse.ReturnType)
+	assert.Equal(t, []ParameterDoc{{Name: "arn", Type: "String", Description: "The ARN to parse."}}, parse.Parameters)
+	assert.Equal(t, &ParameterDoc{Name: "parts", Type: "List of String", Nullable: true}, parse.VariadicParameter)
+	assert.Contains(t, parse.Example, "provider::PROVIDER::parse(arn, parts)")
+
+	assert.Equal(t, "# function: parse\n"+
+		"\nParses an ARN.\n"+
+		"\nParses an ARN into its components.\n"+
+		"\n## Example Usage\n\n```terraform\n"+
+		"# TODO: replace with a real example\noutput \"parse\" {\n  value = provider::PROVIDER::parse(arn, parts)\n}\n"+
+		"```\n"+
+		"\n## Signature\n\n```text\nparse(arn string, parts ...list of string) object\n```\n"+
+		"\n## Arguments\n\n"+
+		"1. `arn` (String) The ARN to parse.\n"+
+		"2. `parts` (Variadic, List of String, Nullable)\n", parse.Markdown())
+
+	noop := docs[0].Markdown()
+	assert.Contains(t, noop, "~> **Deprecated** Use something else.\n")
+	assert.Contains(t, noop, "noop() bool")
+	assert.NotContains(t, noop, "## Arguments")
+
+	buf, err := json.Marshal(docs[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), `"signature":"parse(arn string, parts ...list of string) object"`)
+
+	assert.Empty(t, Functions(nil))
+}
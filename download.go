@@ -0,0 +1,469 @@
+package tfpluginschema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxConnsPerHost bounds idle connections kept per host, so
+	// ranged download parts and repeated registry calls reuse connections
+	// rather than re-handshaking.
+	defaultMaxConnsPerHost = 8
+	// defaultReadBufferSize is the transport read buffer size. Provider
+	// archives run to hundreds of megabytes, for which Go's 4 KiB default
+	// costs a measurable number of extra syscalls.
+	defaultReadBufferSize = 256 << 10
+	// defaultParallelDownloadMinSize is the archive size below which
+	// WithParallelDownload falls back to a single stream.
+	defaultParallelDownloadMinSize = 64 << 20
+	// partialDownloadsDir is the directory, relative to the cache root,
+	// holding archives whose download failed partway.
+	partialDownloadsDir = "partial-downloads"
+)
+
+// newDefaultHTTPClient returns the HTTP client used when WithHTTPClient is
+// not given. It is a clone of http.DefaultTransport tuned for large
+// downloads: HTTP/2 is attempted even with the customised transport, more
+// idle connections are kept per host, and the read buffer is enlarged.
+func newDefaultHTTPClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ForceAttemptHTTP2 = true
+	t.MaxIdleConnsPerHost = defaultMaxConnsPerHost
+	t.ReadBufferSize = defaultReadBufferSize
+	return &http.Client{Transport: t}
+}
+
+// WithParallelDownload makes the Server fetch provider archives of at least
+// minSize bytes as parts concurrent HTTP range requests, which can speed up
+// multi-hundred-megabyte downloads over high-latency links. The download
+// host must advertise "Accept-Ranges: bytes" and a Content-Length;
+// otherwise, and whenever a ranged download fails, the archive is fetched
+// as a single stream. parts < 2 disables parallel downloads (the default).
+// A minSize <= 0 selects a 64 MiB threshold.
+func WithParallelDownload(parts int, minSize int64) ServerOption {
+	return func(s *Server) {
+		if minSize <= 0 {
+			minSize = defaultParallelDownloadMinSize
+		}
+		s.downloadParts = parts
+		s.parallelMinSize = minSize
+	}
+}
+
+// ErrTooManyRedirects is returned when a request is redirected more times
+// than allowed by WithMaxRedirects.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// limitRedirects returns a redirect policy that stops after n redirects and
+// otherwise defers to next, if set.
+func limitRedirects(n int, next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > n {
+			return fmt.Errorf("%w: stopped after %d redirects from %s", ErrTooManyRedirects, n, via[0].URL)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+}
+
+// downloadStatusError reports an unexpected HTTP status from a download
+// host.
+type downloadStatusError struct {
+	url    string
+	status int
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("failed to download plugin: %s => %d", e.url, e.status)
+}
+
+// downloadLinkRejected reports whether err shows the download host refusing
+// the link itself, as storage services do once a signed URL has expired.
+func downloadLinkRejected(err error) bool {
+	var statusErr *downloadStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// downloadInfo is what a HEAD request reveals about a download URL.
+type downloadInfo struct {
+	size     int64     // Content-Length, or -1 if unknown
+	ranges   bool      // whether byte-range requests are supported
+	modified time.Time // Last-Modified, or zero if unknown
+}
+
+// probeDownload issues a HEAD request for u. Hosts that reject HEAD with
+// 405 or 501 are reported as having an unknown size.
+func (s *Server) probeDownload(u string) (downloadInfo, error) {
+	info := downloadInfo{size: -1}
+	headRequest, err := s.newDownloadRequest(http.MethodHead, u)
+	if err != nil {
+		return info, fmt.Errorf("failed to create HTTP request for plugin download: %w", err)
+	}
+	resp, err := s.httpClient.Do(headRequest)
+	if err != nil {
+		return info, fmt.Errorf("failed to query plugin download size: %w", err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		info.size = resp.ContentLength
+		info.ranges = resp.Header.Get("Accept-Ranges") == "bytes"
+		info.modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+		return info, nil
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		// Some hosts do not support HEAD; the size is simply unknown.
+		return info, nil
+	default:
+		return info, fmt.Errorf("failed to query plugin download size: %s => %d", u, resp.StatusCode)
+	}
+}
+
+// fetchArchive downloads the archive at u to path and verifies it against
+// shasum, the hex SHA-256 reported by the registry (verification is skipped
+// when shasum is empty). If path holds a partial download left by an
+// earlier failed attempt, the download resumes from its end with a Range
+// request. The partial file is kept when the download fails, so that the
+// next attempt can resume it, and is removed when it fails verification.
+// A resumed download that fails verification is restarted from scratch
+// once, in case the remote archive changed in between.
+//
+// It returns the archive size and the offset the download resumed from.
+func (s *Server) fetchArchive(u, path, shasum string, info downloadInfo) (int64, int64, error) {
+	size, resumedFrom, err := s.fetchArchiveOnce(u, path, info)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = verifyArchiveChecksum(path, shasum)
+	if err != nil && resumedFrom > 0 {
+		s.logger(logComponentDownload).Warn("Resumed download failed verification; restarting", "url", u, "error", err)
+		if rmErr := os.Remove(path); rmErr != nil {
+			return 0, 0, fmt.Errorf("failed to remove partial plugin file: %w", rmErr)
+		}
+		if size, resumedFrom, err = s.fetchArchiveOnce(u, path, info); err != nil {
+			return 0, 0, err
+		}
+		err = verifyArchiveChecksum(path, shasum)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return 0, 0, err
+	}
+	return size, resumedFrom, nil
+}
+
+// fetchArchiveOnce makes a single attempt at completing the file at path.
+func (s *Server) fetchArchiveOnce(u, path string, info downloadInfo) (int64, int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create plugin file: %w", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, 0, fmt.Errorf("failed to stat plugin file: %w", err)
+	}
+
+	var size int64
+	offset := stat.Size()
+	if offset > 0 {
+		s.logger(logComponentDownload).Info("Resuming partial download", "url", u, "offset", offset)
+		size, err = s.downloadResume(u, file, offset)
+	} else {
+		size, err = s.downloadArchive(u, file, info)
+	}
+	if err != nil {
+		file.Close()
+		return 0, 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, 0, fmt.Errorf("failed to close plugin file: %w", err)
+	}
+	return size, offset, nil
+}
+
+// downloadResume continues a download into file from offset. If the host
+// ignores the Range header, or answers with a range that does not start at
+// offset, the file is rewritten from the start; if it reports the range as
+// unsatisfiable the file is assumed to be complete and left for checksum
+// verification to confirm.
+func (s *Server) downloadResume(u string, file *os.File, offset int64) (int64, error) {
+	req, err := s.newDownloadRequest(http.MethodGet, u)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request for plugin download: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download plugin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			// Appending a range other than the one requested would corrupt
+			// the file; start again from the beginning instead.
+			s.logger(logComponentDownload).Warn("Resumed download returned an unexpected range; restarting", "url", u, "offset", offset, "content_range", resp.Header.Get("Content-Range"))
+			resp.Body.Close()
+			if err := file.Truncate(0); err != nil {
+				return 0, fmt.Errorf("failed to reset plugin file: %w", err)
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return 0, fmt.Errorf("failed to reset plugin file: %w", err)
+			}
+			return s.downloadStream(u, file)
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("failed to seek plugin file: %w", err)
+		}
+	case http.StatusOK:
+		if err := file.Truncate(0); err != nil {
+			return 0, fmt.Errorf("failed to reset plugin file: %w", err)
+		}
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		return offset, nil
+	default:
+		return 0, &downloadStatusError{url: u, status: resp.StatusCode}
+	}
+
+	written, err := file.ReadFrom(resp.Body)
+	if err != nil {
+		return offset + written, fmt.Errorf("failed to read plugin data into file: %w", err)
+	}
+	return offset + written, nil
+}
+
+// contentRangeStart returns the first byte position of a Content-Range
+// header of the form "bytes first-last/length".
+func contentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil || start < 0 {
+		return 0, false
+	}
+	return start, true
+}
+
+// partialArchivePath returns where the partial download of the archive
+// name is kept between runs, under the cache directory.
+func (s *Server) partialArchivePath(name string) string {
+	return filepath.Join(s.cacheDir, partialDownloadsDir, name)
+}
+
+// claimPartialArchive moves the partial download kept at parked to path,
+// so that the download into path resumes it. Nothing is done if path
+// already holds a partial download or none is kept. A failure only means
+// the download starts from the beginning, so it is logged and ignored.
+func (s *Server) claimPartialArchive(parked, path string) {
+	if fileSize(path) > 0 {
+		return
+	}
+	if _, err := os.Lstat(parked); err != nil {
+		return
+	}
+	l := s.logger(logComponentDownload)
+	// Renaming claims the file atomically, so concurrent downloads of the
+	// same archive do not both append to it. Across file systems it is
+	// copied instead, and each copy resumes separately.
+	if err := moveFile(parked, path); err != nil {
+		l.Debug("Failed to claim partial download", "path", parked, "error", err)
+		_ = os.Remove(path)
+		return
+	}
+	_ = os.Remove(parked)
+	l.Debug("Claimed partial download", "path", parked, logKeyBytes, fileSize(path))
+}
+
+// parkPartialArchive moves the partial download at path to parked, under
+// the cache directory, so that a later Get, in this process or another,
+// can resume it. A copy across file systems is written under a temporary
+// name first, so that claimPartialArchive never sees half of it.
+func (s *Server) parkPartialArchive(path, parked string) {
+	if fileSize(path) == 0 {
+		return
+	}
+	if err := s.movePartialArchive(path, parked); err != nil {
+		s.logger(logComponentDownload).Debug("Failed to keep partial download", "path", path, "error", err)
+		return
+	}
+	s.logger(logComponentDownload).Debug("Kept partial download for a later attempt", "path", parked)
+}
+
+func (s *Server) movePartialArchive(path, parked string) error {
+	if err := ensureWithinBaseDir(s.cacheDir, parked); err != nil {
+		return err
+	}
+	if err := os.Rename(path, parked); err == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(parked), ".partial-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	if err := moveFile(path, tmp.Name()); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), parked); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Remove(path)
+}
+
+// verifyArchiveChecksum compares the SHA-256 of the file at path with the
+// hex digest want. An empty want skips verification.
+func verifyArchiveChecksum(path, want string) error {
+	if want == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin file for verification: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash plugin file: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch for downloaded plugin: got sha256 %s, want %s", got, want)
+	}
+	return nil
+}
+
+// downloadArchive writes the archive at u into file, returning the number
+// of bytes written. Ranged parallel download is attempted when enabled with
+// WithParallelDownload and info, from probeDownload, shows the host
+// supports it.
+func (s *Server) downloadArchive(u string, file *os.File, info downloadInfo) (int64, error) {
+	if s.downloadParts > 1 {
+		l := s.logger(logComponentDownload).With("url", u)
+		switch {
+		case !info.ranges || info.size < s.parallelMinSize:
+			l.Debug("Using a single stream", logKeyBytes, info.size, "ranges", info.ranges)
+		default:
+			err := s.downloadRanged(u, file, info.size, s.downloadParts)
+			if err == nil {
+				l.Debug("Downloaded archive in parallel", logKeyBytes, info.size, "parts", s.downloadParts)
+				return info.size, nil
+			}
+			l.Warn("Parallel download failed; retrying as a single stream", "error", err)
+			if err := file.Truncate(0); err != nil {
+				return 0, fmt.Errorf("failed to reset plugin file: %w", err)
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return 0, fmt.Errorf("failed to reset plugin file: %w", err)
+			}
+		}
+	}
+	return s.downloadStream(u, file)
+}
+
+// downloadStream fetches u with a single GET.
+func (s *Server) downloadStream(u string, file *os.File) (int64, error) {
+	downloadRequest, err := s.newDownloadRequest(http.MethodGet, u)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request for plugin download: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(downloadRequest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download plugin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, &downloadStatusError{url: u, status: resp.StatusCode}
+	}
+
+	written, err := file.ReadFrom(resp.Body)
+	if err != nil {
+		return written, fmt.Errorf("failed to read plugin data into file: %w", err)
+	}
+	return written, nil
+}
+
+// downloadRanged fetches u as parts concurrent range requests, each written
+// at its own offset in file.
+func (s *Server) downloadRanged(u string, file *os.File, size int64, parts int) error {
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to allocate plugin file: %w", err)
+	}
+	partSize := (size + int64(parts) - 1) / int64(parts)
+
+	var wg sync.WaitGroup
+	errs := make([]error, parts)
+	for i := range parts {
+		start := int64(i) * partSize
+		if start >= size {
+			break
+		}
+		end := min(start+partSize, size) - 1
+		wg.Go(func() {
+			errs[i] = s.downloadRange(u, file, start, end)
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// downloadRange fetches bytes [start, end] of u into file at offset start.
+func (s *Server) downloadRange(u string, file *os.File, start, end int64) error {
+	req, err := s.newDownloadRequest(http.MethodGet, u)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request for plugin download: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download plugin range %d-%d: %w", start, end, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("failed to download plugin range %d-%d: %s => %d", start, end, u, resp.StatusCode)
+	}
+
+	want := end - start + 1
+	n, err := io.Copy(io.NewOffsetWriter(file, start), io.LimitReader(resp.Body, want))
+	if err != nil {
+		return fmt.Errorf("failed to write plugin range %d-%d: %w", start, end, err)
+	}
+	if n != want {
+		return fmt.Errorf("short read for plugin range %d-%d: got %d bytes, want %d", start, end, n, want)
+	}
+	return nil
+}
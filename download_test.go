@@ -0,0 +1,414 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveBlob returns a handler serving blob with range support (via
+// http.ServeContent) that counts ranged GET requests.
+func serveBlob(blob []byte, ranged *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+			ranged.Add(1)
+		}
+		http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(blob))
+	})
+}
+
+func randomBlob(t testing.TB, n int) []byte {
+	t.Helper()
+	blob := make([]byte, n)
+	_, err := rand.Read(blob)
+	require.NoError(t, err)
+	return blob
+}
+
+func downloadToTemp(t testing.TB, s *Server, u string) ([]byte, int64) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "archive.zip"))
+	require.NoError(t, err)
+	info, err := s.probeDownload(u)
+	require.NoError(t, err)
+	n, err := s.downloadArchive(u, f, info)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	got, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	return got, n
+}
+
+func TestNewDefaultHTTPClient(t *testing.T) {
+	c := newDefaultHTTPClient()
+	tr, ok := c.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.True(t, tr.ForceAttemptHTTP2)
+	assert.Equal(t, defaultMaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, defaultReadBufferSize, tr.ReadBufferSize)
+	assert.NotSame(t, http.DefaultTransport, c.Transport)
+}
+
+func TestServer_DownloadArchive_Parallel(t *testing.T) {
+	blob := randomBlob(t, 1<<20+7)
+	var ranged atomic.Int32
+	client := stubRegistryClient(t, serveBlob(blob, &ranged))
+	s := NewServer(nil, WithHTTPClient(client), WithParallelDownload(4, 1024))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, n := downloadToTemp(t, s, "https://releases.example.com/archive.zip")
+	assert.Equal(t, int64(len(blob)), n)
+	assert.Equal(t, blob, got)
+	assert.Equal(t, int32(4), ranged.Load())
+}
+
+func TestServer_DownloadArchive_BelowThresholdSingleStream(t *testing.T) {
+	blob := randomBlob(t, 4096)
+	var ranged atomic.Int32
+	client := stubRegistryClient(t, serveBlob(blob, &ranged))
+	s := NewServer(nil, WithHTTPClient(client), WithParallelDownload(4, 1<<20))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, _ := downloadToTemp(t, s, "https://releases.example.com/archive.zip")
+	assert.Equal(t, blob, got)
+	assert.Zero(t, ranged.Load())
+}
+
+func TestServer_DownloadArchive_NoRangeSupport(t *testing.T) {
+	blob := randomBlob(t, 64<<10)
+	var gets atomic.Int32
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		_, _ = w.Write(blob)
+	}))
+	s := NewServer(nil, WithHTTPClient(client), WithParallelDownload(4, 1))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, _ := downloadToTemp(t, s, "https://releases.example.com/archive.zip")
+	assert.Equal(t, blob, got)
+	assert.Equal(t, int32(1), gets.Load())
+}
+
+func TestServer_DownloadArchive_PartFailureFallsBack(t *testing.T) {
+	blob := randomBlob(t, 64<<10)
+	var ranged atomic.Int32
+	inner := serveBlob(blob, &ranged)
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	}))
+	s := NewServer(nil, WithHTTPClient(client), WithParallelDownload(2, 1))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, n := downloadToTemp(t, s, "https://releases.example.com/archive.zip")
+	assert.Equal(t, int64(len(blob)), n)
+	assert.Equal(t, blob, got)
+}
+
+func BenchmarkDownloadArchive(b *testing.B) {
+	blob := randomBlob(b, 32<<20)
+	var ranged atomic.Int32
+	client := stubRegistryClient(b, serveBlob(blob, &ranged))
+	for _, parts := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("parts=%d", parts), func(b *testing.B) {
+			s := NewServer(nil, WithHTTPClient(client), WithParallelDownload(parts, 1))
+			b.Cleanup(func() { _ = s.Cleanup() })
+			b.SetBytes(int64(len(blob)))
+			for b.Loop() {
+				downloadToTemp(b, s, "https://releases.example.com/archive.zip")
+			}
+		})
+	}
+}
+
+// BenchmarkDownloadArchive_AzureRM downloads the real azurerm archive
+// (several hundred MB) and therefore needs network access.
+func BenchmarkDownloadArchive_AzureRM(b *testing.B) {
+	s := NewServer(nil, WithCacheDir(b.TempDir()))
+	b.Cleanup(func() { _ = s.Cleanup() })
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "azurerm", Version: "4.37.0"})
+	if err != nil {
+		b.Skipf("registry unavailable: %v", err)
+	}
+	b.Logf("%s/%s: %d bytes", runtime.GOOS, runtime.GOARCH, plan.Size)
+
+	for _, parts := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("parts=%d", parts), func(b *testing.B) {
+			s := NewServer(nil, WithParallelDownload(parts, 1))
+			b.Cleanup(func() { _ = s.Cleanup() })
+			if plan.Size > 0 {
+				b.SetBytes(plan.Size)
+			}
+			for b.Loop() {
+				downloadToTemp(b, s, plan.URL)
+			}
+		})
+	}
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestServer_FetchArchive_ResumesAfterFailure(t *testing.T) {
+	blob := randomBlob(t, 256<<10)
+	var fail atomic.Bool
+	fail.Store(true)
+	var ranges []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if fail.Load() {
+			// Advertise the full length but drop the connection halfway.
+			w.Header().Set("Content-Length", fmt.Sprint(len(blob)))
+			_, _ = w.Write(blob[:len(blob)/2])
+			return
+		}
+		http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(blob))
+	}))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	path := filepath.Join(t.TempDir(), "archive.zip")
+
+	_, _, err := s.fetchArchive("https://releases.example.com/archive.zip", path, sha256Hex(blob), downloadInfo{size: -1})
+	require.Error(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err, "partial download must be kept")
+	assert.Equal(t, int64(len(blob)/2), info.Size())
+
+	fail.Store(false)
+	size, resumedFrom, err := s.fetchArchive("https://releases.example.com/archive.zip", path, sha256Hex(blob), downloadInfo{size: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(blob)), size)
+	assert.Equal(t, int64(len(blob)/2), resumedFrom)
+	assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(blob)/2)}, ranges)
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, blob, got)
+}
+
+func TestServer_FetchArchive_RestartsWhenResumedChecksumFails(t *testing.T) {
+	blob := randomBlob(t, 64<<10)
+	var ranged atomic.Int32
+	client := stubRegistryClient(t, serveBlob(blob, &ranged))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	// A stale partial whose prefix no longer matches the remote archive.
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{0xff}, 1024), 0o644))
+
+	size, resumedFrom, err := s.fetchArchive("https://releases.example.com/archive.zip", path, sha256Hex(blob), downloadInfo{size: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(blob)), size)
+	assert.Zero(t, resumedFrom)
+	assert.Equal(t, int32(1), ranged.Load())
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, blob, got)
+}
+
+func TestServer_FetchArchive_ChecksumMismatch(t *testing.T) {
+	blob := randomBlob(t, 4096)
+	var ranged atomic.Int32
+	client := stubRegistryClient(t, serveBlob(blob, &ranged))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	path := filepath.Join(t.TempDir(), "archive.zip")
+
+	_, _, err := s.fetchArchive("https://releases.example.com/archive.zip", path, sha256Hex([]byte("other")), downloadInfo{size: -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "corrupt archive must be removed")
+}
+
+func TestServer_FetchArchive_RangeNotSatisfiable(t *testing.T) {
+	blob := randomBlob(t, 4096)
+	var ranged atomic.Int32
+	client := stubRegistryClient(t, serveBlob(blob, &ranged))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	// The previous attempt completed the file but failed afterwards.
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	require.NoError(t, os.WriteFile(path, blob, 0o644))
+
+	size, _, err := s.fetchArchive("https://releases.example.com/archive.zip", path, sha256Hex(blob), downloadInfo{size: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(blob)), size)
+}
+
+func TestServer_FetchArchive_UnexpectedContentRange(t *testing.T) {
+	blob := randomBlob(t, 64<<10)
+	var ranges []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			// A host that answers every range from the first byte.
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(blob)-1, len(blob)))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(blob)
+			return
+		}
+		_, _ = w.Write(blob)
+	}))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	require.NoError(t, os.WriteFile(path, blob[:1024], 0o644))
+
+	// Without a checksum, nothing else would catch the misplaced range.
+	size, _, err := s.fetchArchive("https://releases.example.com/archive.zip", path, "", downloadInfo{size: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(blob)), size)
+	assert.Equal(t, []string{"bytes=1024-", ""}, ranges, "the download restarts from the beginning")
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, blob, got)
+}
+
+func TestContentRangeStart(t *testing.T) {
+	for header, want := range map[string]int64{"bytes 100-199/200": 100, "bytes 0-9/*": 0} {
+		got, ok := contentRangeStart(header)
+		assert.True(t, ok, header)
+		assert.Equal(t, want, got, header)
+	}
+	for _, header := range []string{"", "bytes */200", "items 1-2/3", "bytes -5-9/10"} {
+		_, ok := contentRangeStart(header)
+		assert.False(t, ok, header)
+	}
+}
+
+func TestServer_Get_ResumesPartialDownloadAcrossServers(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "aws.zip")
+	createZip(t, archive, map[string]string{"terraform-provider-aws_v5.0.0": strings.Repeat("binary", 4096)})
+	blob, err := os.ReadFile(archive)
+	require.NoError(t, err)
+
+	var fail atomic.Bool
+	fail.Store(true)
+	var ranges []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/5.0.0/download/" + runtime.GOOS + "/" + runtime.GOARCH:
+			fmt.Fprintf(w, `{"filename":"aws.zip","download_url":"https://storage.example.com/aws.zip","shasum":%q}`, sha256Hex(blob))
+		case "/aws.zip":
+			if r.Method != http.MethodGet {
+				http.ServeContent(w, r, "aws.zip", time.Time{}, bytes.NewReader(blob))
+				return
+			}
+			ranges = append(ranges, r.Header.Get("Range"))
+			if fail.Load() {
+				w.Header().Set("Content-Length", fmt.Sprint(len(blob)))
+				_, _ = w.Write(blob[:len(blob)/2])
+				return
+			}
+			http.ServeContent(w, r, "aws.zip", time.Time{}, bytes.NewReader(blob))
+		}
+	}))
+	cacheDir := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	parked := filepath.Join(cacheDir, partialDownloadsDir, "opentofu-aws.zip")
+
+	first := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	require.Error(t, first.Get(req))
+	require.NoError(t, first.Cleanup())
+	info, err := os.Stat(parked)
+	require.NoError(t, err, "the partial download is kept in the cache directory")
+	assert.Equal(t, int64(len(blob)/2), info.Size())
+
+	fail.Store(false)
+	second := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = second.Cleanup() })
+	require.NoError(t, second.Get(req))
+	assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(blob)/2)}, ranges, "another Server resumes the download")
+	assert.NoFileExists(t, parked)
+}
+
+func TestServer_Get_RefreshesRejectedDownloadLink(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "aws.zip")
+	createZip(t, archive, map[string]string{"terraform-provider-aws_v5.0.0": "binary"})
+	blob, err := os.ReadFile(archive)
+	require.NoError(t, err)
+
+	var links atomic.Int32
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/5.0.0/download/" + runtime.GOOS + "/" + runtime.GOARCH:
+			w.Header().Set("ETag", fmt.Sprintf(`"%d"`, links.Load()))
+			fmt.Fprintf(w, `{"filename":"aws.zip","download_url":"https://storage.example.com/aws.zip?sig=%d","shasum":%q}`, links.Add(1), sha256Hex(blob))
+		case "/aws.zip":
+			if r.URL.Query().Get("sig") != "2" {
+				http.Error(w, "expired", http.StatusForbidden)
+				return
+			}
+			http.ServeContent(w, r, "aws.zip", time.Time{}, bytes.NewReader(blob))
+		}
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	require.NoError(t, s.Get(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}))
+	assert.Equal(t, int32(2), links.Load(), "download metadata is fetched again once")
+}
+
+func TestServer_Get_RejectedDownloadLinkRefreshedOnce(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/providers/") {
+			fmt.Fprint(w, `{"filename":"aws.zip","download_url":"https://storage.example.com/aws.zip"}`)
+			return
+		}
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	err := s.Get(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+	require.Error(t, err)
+	assert.True(t, downloadLinkRejected(err), "got %v", err)
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	var hops atomic.Int32
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := hops.Add(1); n <= 3 {
+			http.Redirect(w, r, fmt.Sprintf("https://hop%d.example.com/aws.zip", n), http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("archive"))
+	}))
+
+	s := NewServer(nil, WithHTTPClient(client), WithMaxRedirects(3))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	got, _ := downloadToTemp(t, s, "https://registry.example.com/aws.zip")
+	assert.Equal(t, "archive", string(got))
+	assert.Nil(t, client.CheckRedirect, "the caller's client is not modified")
+
+	hops.Store(0)
+	s = NewServer(nil, WithHTTPClient(client), WithMaxRedirects(2))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	_, err := s.probeDownload("https://registry.example.com/aws.zip")
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+}
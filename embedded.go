@@ -0,0 +1,90 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// EmbeddedSource returns a source that serves the schema bundle files in
+// files, compressed with CompressSchema, keyed by their uncompressed path in the
+// layout described for WithSchemaBundle. It is used by the Go files
+// written by the embedgen package, which compile provider schemas into a
+// binary:
+//
+//	s := tfpluginschema.NewServer(nil, tfpluginschema.WithSchemaSources(schemas.Source))
+//
+// Files are decompressed when their schema is requested.
+func EmbeddedSource(files map[string]string) SchemaSource {
+	return embeddedSource{files: files}
+}
+
+type embeddedSource struct {
+	files map[string]string
+	l     *slog.Logger
+}
+
+func (e embeddedSource) bind(s *Server) SchemaSource {
+	e.l = s.logger(logComponentCache)
+	return e
+}
+
+func (e embeddedSource) Resolve(req VersionsRequest) (goversion.Collection, error) {
+	key := normalizedRequest(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType})
+	dir := path.Join(
+		cachePathSegment(string(key.RegistryType)),
+		cachePathSegment(key.Namespace),
+		cachePathSegment(key.Name),
+	) + "/"
+
+	var versions goversion.Collection
+	for p := range e.files {
+		name, ok := strings.CutPrefix(p, dir)
+		if !ok {
+			continue
+		}
+		if name, ok = strings.CutSuffix(name, schemaBundleExt); !ok || strings.Contains(name, "/") {
+			continue
+		}
+		if v, err := goversion.NewVersion(name); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+func (e embeddedSource) Fetch(request Request) error {
+	if p := schemaBundlePath(request); e.files[p] == "" {
+		return fmt.Errorf("%w: %s not embedded", ErrSourceMiss, p)
+	}
+	return nil
+}
+
+func (e embeddedSource) Schema(request Request) (*tfjson.ProviderSchema, error) {
+	ls, err := e.lazySchema(request)
+	if err != nil {
+		return nil, err
+	}
+	return ls.providerSchema()
+}
+
+func (e embeddedSource) lazySchema(request Request) (*lazySchema, error) {
+	p := schemaBundlePath(request)
+	compressed, ok := e.files[p]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s not embedded", ErrSourceMiss, p)
+	}
+	data, err := decompressSchema([]byte(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress embedded schema %s: %w", p, err)
+	}
+	ls, err := decodeBundledSchema(data, request, e.l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode embedded schema %s: %w", p, err)
+	}
+	return ls, nil
+}
@@ -0,0 +1,45 @@
+package tfpluginschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressString returns s compressed with CompressSchema.
+func compressString(t *testing.T, s string) string {
+	t.Helper()
+	data, err := CompressSchema([]byte(s), CompressionBest)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestServer_EmbeddedSource(t *testing.T) {
+	src := EmbeddedSource(map[string]string{
+		"opentofu/example/example/1.0.0.json":  compressString(t, writeOnlySchema),
+		"opentofu/example/example/1.1.0.json":  compressString(t, describedSchema),
+		"opentofu/example/example/broken.json": compressString(t, "{}"),
+		"terraform/example/example/9.0.0.json": compressString(t, describedSchema),
+		"opentofu/example/examples/2.0.0.json": compressString(t, describedSchema),
+		"opentofu/example/example/1.2.0.json":  string(zstdMagic) + "not zstd",
+	})
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaSources(src))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "Example", Name: "example"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0", "1.2.0"}, versionStrings(versions))
+
+	names, err := s.ListResources(Request{Namespace: "example", Name: "example", Version: "1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_bucket", "example_db"}, names)
+	names, err = s.ListResources(Request{Namespace: "example", Name: "example", Version: "~> 1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_bucket", "example_db"}, names)
+
+	_, err = s.ListResources(Request{Namespace: "example", Name: "example", Version: "1.2.0"})
+	assert.ErrorContains(t, err, "failed to decompress embedded schema opentofu/example/example/1.2.0.json")
+	_, err = s.ListResources(Request{Namespace: "example", Name: "example", Version: "3.0.0"})
+	assert.ErrorIs(t, err, ErrSourceMiss)
+}
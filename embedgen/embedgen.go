@@ -0,0 +1,130 @@
+// Package embedgen writes Go source files that compile provider schemas into
+// a binary, so that a program can serve them with no downloads at run time.
+//
+// The generated file declares a tfpluginschema.SchemaSource holding the
+// files of a schema bundle, compressed with tfpluginschema.CompressSchema as
+// for a compressed bundle, for use with
+// tfpluginschema.WithSchemaSources:
+//
+//	//go:generate tfpluginschema embed --package schemas -o schemas_gen.go hashicorp/aws@5.40.0
+//
+//	s := tfpluginschema.NewServer(nil, tfpluginschema.WithSchemaSources(schemas.Source))
+package embedgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// zstdExt is the extension of zstd-compressed schema bundle files, which
+// are embedded as they are.
+const zstdExt = ".zst"
+
+// DefaultVariable is the name of the declared source when Options.Variable
+// is empty.
+const DefaultVariable = "Source"
+
+// Options configure Generate.
+type Options struct {
+	// Package is the name of the package the file belongs to.
+	Package string
+	// Variable is the name of the declared SchemaSource; DefaultVariable if
+	// empty.
+	Variable string
+}
+
+// Generate writes a Go file holding every schema of the schema bundle in
+// bundle, laid out as described for tfpluginschema.WithSchemaBundle and as
+// written by Server.WriteSchemaBundle. The output does not depend on file
+// modification times, so regenerating unchanged schemas gives the same file.
+func Generate(w io.Writer, bundle fs.FS, opts Options) error {
+	if !token.IsIdentifier(opts.Package) {
+		return fmt.Errorf("invalid package name %q", opts.Package)
+	}
+	if opts.Variable == "" {
+		opts.Variable = DefaultVariable
+	}
+	if !token.IsIdentifier(opts.Variable) {
+		return fmt.Errorf("invalid variable name %q", opts.Variable)
+	}
+
+	paths, err := bundleFiles(bundle)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no schemas in the schema bundle")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by tfpluginschema embedgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	src.WriteString("import \"github.com/matt-FFFFFF/tfpluginschema\"\n\n")
+	fmt.Fprintf(&src, "// %s serves the provider schemas embedded in this file; pass it to\n", opts.Variable)
+	src.WriteString("// tfpluginschema.WithSchemaSources. It holds:\n//\n")
+	for _, p := range paths {
+		fmt.Fprintf(&src, "//   - %s\n", strings.TrimSuffix(strings.TrimSuffix(p, zstdExt), ".json"))
+	}
+	fmt.Fprintf(&src, "var %s = tfpluginschema.EmbeddedSource(map[string]string{\n", opts.Variable)
+	for _, p := range paths {
+		data, err := fs.ReadFile(bundle, p)
+		if err != nil {
+			return fmt.Errorf("failed to read schema bundle file %s: %w", p, err)
+		}
+		key, compressed := strings.CutSuffix(p, zstdExt)
+		if !compressed {
+			if data, err = tfpluginschema.CompressSchema(data, tfpluginschema.CompressionBest); err != nil {
+				return fmt.Errorf("failed to compress schema bundle file %s: %w", p, err)
+			}
+		}
+		fmt.Fprintf(&src, "\t%q: %s,\n", key, strconv.Quote(string(data)))
+	}
+	src.WriteString("})\n")
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated source: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// bundleFiles returns the paths of the schemas in bundle, sorted, skipping
+// files that are not laid out as <registry-type>/<namespace>/<name>/<version>.json
+// or its zstd-compressed form <version>.json.zst. Where both forms exist,
+// only the plain one is returned, as it is the one a Server reads.
+func bundleFiles(bundle fs.FS) ([]string, error) {
+	var paths []string
+	err := fs.WalkDir(bundle, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && strings.HasSuffix(strings.TrimSuffix(p, zstdExt), ".json") && strings.Count(p, "/") == 3 {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema bundle: %w", err)
+	}
+	plain := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		plain[p] = true
+	}
+	paths = slices.DeleteFunc(paths, func(p string) bool {
+		p, ok := strings.CutSuffix(p, zstdExt)
+		return ok && plain[p]
+	})
+	slices.Sort(paths)
+	return paths, nil
+}
@@ -0,0 +1,101 @@
+package embedgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+const schema = `{"resource_schemas": {"example_thing": {"version": 0, "block": {}}}}`
+
+// embeddedFiles parses the generated file src and returns the map literal
+// passed to tfpluginschema.EmbeddedSource.
+func embeddedFiles(t *testing.T, src []byte) (*ast.File, map[string]string) {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "gen.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	files := map[string]string{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+		k, err := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+		require.NoError(t, err)
+		v, err := strconv.Unquote(kv.Value.(*ast.BasicLit).Value)
+		require.NoError(t, err)
+		files[k] = v
+		return false
+	})
+	return f, files
+}
+
+func TestGenerate(t *testing.T) {
+	bundle := fstest.MapFS{
+		"opentofu/example/example/1.0.0.json": {Data: []byte(schema)},
+		"opentofu/example/example/README.md":  {Data: []byte("not a schema")},
+		"opentofu/example/extra.json":         {Data: []byte("not a schema")},
+	}
+	var out bytes.Buffer
+	require.NoError(t, Generate(&out, bundle, Options{Package: "schemas"}))
+
+	f, files := embeddedFiles(t, out.Bytes())
+	assert.Equal(t, "schemas", f.Name.Name)
+	assert.Contains(t, out.String(), "// Code generated by tfpluginschema embedgen. DO NOT EDIT.\n")
+	assert.Contains(t, out.String(), "//   - opentofu/example/example/1.0.0\nvar Source = tfpluginschema.EmbeddedSource(")
+	require.Len(t, files, 1)
+	want, err := tfpluginschema.CompressSchema([]byte(schema), tfpluginschema.CompressionBest)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), files["opentofu/example/example/1.0.0.json"], "files are compressed as for a compressed bundle")
+
+	var again bytes.Buffer
+	require.NoError(t, Generate(&again, bundle, Options{Package: "schemas"}))
+	assert.Equal(t, out.String(), again.String(), "output is reproducible")
+
+	s := tfpluginschema.NewServer(nil, tfpluginschema.WithCacheDir(t.TempDir()), tfpluginschema.WithPluginExec(false),
+		tfpluginschema.WithSchemaSources(tfpluginschema.EmbeddedSource(files)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	names, err := s.ListResources(tfpluginschema.Request{Namespace: "example", Name: "example", Version: "~> 1.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_thing"}, names)
+}
+
+func TestGenerate_Compressed(t *testing.T) {
+	compressed, err := tfpluginschema.CompressSchema([]byte(schema), tfpluginschema.CompressionFastest)
+	require.NoError(t, err)
+	bundle := fstest.MapFS{
+		"opentofu/example/example/1.0.0.json":     {Data: []byte(schema)},
+		"opentofu/example/example/1.0.0.json.zst": {Data: []byte("stale")},
+		"opentofu/example/example/2.0.0.json.zst": {Data: compressed},
+	}
+	var out bytes.Buffer
+	require.NoError(t, Generate(&out, bundle, Options{Package: "schemas"}))
+	_, files := embeddedFiles(t, out.Bytes())
+	assert.Equal(t, string(compressed), files["opentofu/example/example/2.0.0.json"], "compressed files are embedded as they are")
+	require.Len(t, files, 2)
+
+	s := tfpluginschema.NewServer(nil, tfpluginschema.WithCacheDir(t.TempDir()), tfpluginschema.WithPluginExec(false),
+		tfpluginschema.WithSchemaSources(tfpluginschema.EmbeddedSource(files)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		names, err := s.ListResources(tfpluginschema.Request{Namespace: "example", Name: "example", Version: version})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"example_thing"}, names)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(schema)}}
+	assert.ErrorContains(t, Generate(&bytes.Buffer{}, bundle, Options{}), `invalid package name ""`)
+	assert.ErrorContains(t, Generate(&bytes.Buffer{}, bundle, Options{Package: "schemas", Variable: "a-b"}), `invalid variable name "a-b"`)
+	assert.ErrorContains(t, Generate(&bytes.Buffer{}, fstest.MapFS{}, Options{Package: "schemas"}), "no schemas in the schema bundle")
+}
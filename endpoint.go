@@ -0,0 +1,86 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithEndpointOverride sends every request for host, such as
+// "registry.terraform.io", to baseURL instead, such as
+// "https://registry-mirror.corp.example" or the URL of an httptest.Server.
+// The request path is appended to the path of baseURL. It applies to
+// registry API requests, service discovery, provider downloads and the
+// redirects they follow alike, so tests and split-horizon networks can
+// redirect a registry without editing /etc/hosts. Tokens and registry
+// headers are still chosen by the original host.
+//
+// The option may be given once per host; an empty baseURL removes an
+// earlier override. A malformed baseURL is reported when a request for
+// host is sent. The TLS certificate must be valid for the host of baseURL;
+// use WithHTTPClient to trust another one.
+func WithEndpointOverride(host, baseURL string) ServerOption {
+	return func(s *Server) {
+		host = strings.ToLower(host)
+		if baseURL == "" {
+			delete(s.endpointOverrides, host)
+			return
+		}
+		if s.endpointOverrides == nil {
+			s.endpointOverrides = make(map[string]string)
+		}
+		s.endpointOverrides[host] = baseURL
+	}
+}
+
+// endpointTransport rewrites requests for the hosts in overrides, keyed by
+// lower-cased host[:port], onto their base URLs before passing them to
+// wrapped.
+type endpointTransport struct {
+	overrides map[string]string
+	wrapped   http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	baseURL, ok := t.overrides[strings.ToLower(req.URL.Host)]
+	if !ok {
+		return t.wrapped.RoundTrip(req)
+	}
+	base, err := parseEndpointOverride(baseURL)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("invalid endpoint override for %s: %w", req.URL.Host, err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Host = ""
+	req.URL.Scheme = base.Scheme
+	req.URL.Host = base.Host
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + req.URL.RawPath
+	}
+	req.URL.Path = strings.TrimSuffix(base.Path, "/") + req.URL.Path
+	return t.wrapped.RoundTrip(req)
+}
+
+// parseEndpointOverride parses the base URL of an endpoint override.
+func parseEndpointOverride(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("%q must use https or http", baseURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", baseURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("base URL must not have a query or fragment")
+	}
+	return u, nil
+}
@@ -0,0 +1,71 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEndpointOverride(t *testing.T) {
+	var paths, tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/mirror" + serviceDiscoveryPath:
+			fmt.Fprint(w, `{"providers.v1": "/v1/providers/"}`)
+		case "/mirror/v1/providers/hashicorp/aws/versions":
+			fmt.Fprint(w, `{"versions":[{"version":"5.0.0"}]}`)
+		case "/redirect":
+			http.Redirect(w, r, "https://registry.terraform.io/v1/providers/hashicorp/aws/versions", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()),
+		WithEndpointOverride("Registry.Terraform.io", ts.URL+"/mirror/"),
+		WithRegistryToken("registry.terraform.io", "secret"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, []string{"/mirror/v1/providers/hashicorp/aws/versions"}, paths)
+	assert.Equal(t, []string{"Bearer secret"}, tokens, "the token is chosen by the original host")
+
+	status, err := s.diagnoseGet(t.Context(), "https://registry.terraform.io"+serviceDiscoveryPath)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/mirror"+serviceDiscoveryPath, paths[1])
+
+	// Redirects onto an overridden host are rewritten too.
+	redirecting := NewServer(nil, WithCacheDir(t.TempDir()),
+		WithEndpointOverride("registry.terraform.io", ts.URL+"/mirror"),
+		WithEndpointOverride("redirector.example", ts.URL))
+	t.Cleanup(func() { _ = redirecting.Cleanup() })
+	resp, err := redirecting.httpClient.Get("https://redirector.example/redirect")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/mirror/v1/providers/hashicorp/aws/versions", paths[len(paths)-1])
+}
+
+func TestWithEndpointOverride_Invalid(t *testing.T) {
+	for _, baseURL := range []string{"ftp://mirror.example", "https://", "https://mirror.example/?q=1", "://"} {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithEndpointOverride("registry.opentofu.org", baseURL))
+		_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+		assert.ErrorContains(t, err, "invalid endpoint override for registry.opentofu.org", baseURL)
+		_ = s.Cleanup()
+	}
+
+	// An empty base URL removes the override.
+	s := NewServer(nil, WithEndpointOverride("registry.opentofu.org", "ftp://x"), WithEndpointOverride("registry.opentofu.org", ""))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	assert.Empty(t, s.endpointOverrides)
+}
@@ -0,0 +1,242 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// AllowedValues is the set of values documented for an attribute, which
+// the schema itself cannot express.
+type AllowedValues struct {
+	// Path is the dot-separated path of the attribute within the resource,
+	// through any nested blocks and nested attributes, as for
+	// WriteOnlyAttribute.
+	Path   string   `json:"path"`
+	Values []string `json:"values"`
+	// Source is where the values were found: "description" for the
+	// attribute's schema description, "documentation" for the resource's
+	// published documentation page.
+	Source string `json:"source"`
+}
+
+// Sources of AllowedValues.
+const (
+	AllowedValuesFromDescription   = "description"
+	AllowedValuesFromDocumentation = "documentation"
+)
+
+// ExtendedSchema is a resource schema with the constraints inferred from
+// its documentation, for code generators and validators.
+type ExtendedSchema struct {
+	Schema *tfjson.Schema `json:"schema"`
+	// AllowedValues holds the inferred enums, sorted by path.
+	AllowedValues []AllowedValues `json:"allowed_values"`
+}
+
+// Values returns the allowed values inferred for the attribute at path,
+// or nil if none were.
+func (e *ExtendedSchema) Values(path string) []string {
+	for _, av := range e.AllowedValues {
+		if av.Path == path {
+			return av.Values
+		}
+	}
+	return nil
+}
+
+// GetExtendedResourceSchema returns the schema of resource with the
+// allowed values of its attributes inferred, as ExtendSchema does, from
+// their descriptions and from the page GetResourceDoc returns. A resource
+// without a published page is extended from its descriptions alone.
+func (s *Server) GetExtendedResourceSchema(request Request, resource string, opts ...SchemaOption) (*ExtendedSchema, error) {
+	schema, err := s.GetResourceSchema(request, resource, opts...)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.GetResourceDoc(request, resource)
+	if err != nil && !errors.Is(err, ErrDocNotFound) {
+		return nil, err
+	}
+	return ExtendSchema(schema, page), nil
+}
+
+// ExtendSchema infers the allowed values of the attributes of schema with
+// InferAllowedValues, first from each attribute's description and then,
+// for attributes whose descriptions give none, from page, a resource's
+// documentation page in Markdown (see GetResourceDoc), which may be empty.
+// In the page, the items of the argument and attribute lists, such as
+// "* `sku` - (Required) ... Possible values are `Basic` and `Standard`.",
+// are matched to attributes by name, within the nested block named by the
+// last line like "A `rule` block supports the following:". The schema is
+// not modified.
+func ExtendSchema(schema *tfjson.Schema, page string) *ExtendedSchema {
+	e := &ExtendedSchema{Schema: schema}
+	if schema == nil || schema.Block == nil {
+		return e
+	}
+	found := make(map[string]AllowedValues)
+	walkDescribedAttributes(schema.Block, nil, func(path string, a *tfjson.SchemaAttribute) {
+		if values := InferAllowedValues(a.Description); len(values) > 0 {
+			found[path] = AllowedValues{Path: path, Values: values, Source: AllowedValuesFromDescription}
+		}
+	})
+	for path, text := range documentedAttributes(schema.Block, page) {
+		if _, ok := found[path]; ok {
+			continue
+		}
+		if values := InferAllowedValues(text); len(values) > 0 {
+			found[path] = AllowedValues{Path: path, Values: values, Source: AllowedValuesFromDocumentation}
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(found)) {
+		e.AllowedValues = append(e.AllowedValues, found[path])
+	}
+	return e
+}
+
+// walkDescribedAttributes calls f with the path of every attribute in b,
+// through nested blocks and nested attributes.
+func walkDescribedAttributes(b *tfjson.SchemaBlock, prefix []string, f func(path string, a *tfjson.SchemaAttribute)) {
+	if b == nil {
+		return
+	}
+	var attrs func(map[string]*tfjson.SchemaAttribute, []string)
+	attrs = func(m map[string]*tfjson.SchemaAttribute, prefix []string) {
+		for name, a := range m {
+			if a == nil {
+				continue
+			}
+			path := append(slices.Clip(prefix), name)
+			f(strings.Join(path, "."), a)
+			if a.AttributeNestedType != nil {
+				attrs(a.AttributeNestedType.Attributes, path)
+			}
+		}
+	}
+	attrs(b.Attributes, prefix)
+	for name, nb := range b.NestedBlocks {
+		if nb != nil {
+			walkDescribedAttributes(nb.Block, append(slices.Clip(prefix), name), f)
+		}
+	}
+}
+
+var (
+	// docBlockLine matches the line introducing the arguments or attributes
+	// of a nested block in a documentation page.
+	docBlockLine = regexp.MustCompile("^(?:An?|The|Each)\\s+`([A-Za-z0-9_]+)`\\s+(?:\\w+\\s+)?blocks?\\b")
+	// docItemLine matches an item of an argument or attribute list.
+	docItemLine = regexp.MustCompile("^\\s*[*-]\\s+`([A-Za-z0-9_]+)`\\s+-\\s+(.*)$")
+)
+
+// documentedAttributes returns the text of each item of the argument and
+// attribute lists of page that names an attribute of b, keyed by the
+// attribute's path. Items naming no attribute are skipped.
+func documentedAttributes(b *tfjson.SchemaBlock, page string) map[string]string {
+	docs := make(map[string]string)
+	var prefix []string
+	current := b
+	for _, line := range strings.Split(strings.ReplaceAll(page, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			prefix, current = nil, b
+			continue
+		}
+		if m := docBlockLine.FindStringSubmatch(line); m != nil {
+			prefix, current = findNestedBlock(b, m[1], nil)
+			continue
+		}
+		m := docItemLine.FindStringSubmatch(line)
+		if m == nil || current == nil {
+			continue
+		}
+		if a := current.Attributes[m[1]]; a != nil {
+			docs[strings.Join(append(slices.Clip(prefix), m[1]), ".")] = m[2]
+		}
+	}
+	return docs
+}
+
+// findNestedBlock returns the path and schema of the first nested block
+// named name in b, searching breadth first in name order, or a nil schema.
+func findNestedBlock(b *tfjson.SchemaBlock, name string, prefix []string) ([]string, *tfjson.SchemaBlock) {
+	if b == nil {
+		return nil, nil
+	}
+	if nb := b.NestedBlocks[name]; nb != nil {
+		return append(slices.Clip(prefix), name), nb.Block
+	}
+	for _, child := range slices.Sorted(maps.Keys(b.NestedBlocks)) {
+		if nb := b.NestedBlocks[child]; nb != nil {
+			if path, found := findNestedBlock(nb.Block, name, append(slices.Clip(prefix), child)); found != nil {
+				return path, found
+			}
+		}
+	}
+	return nil, nil
+}
+
+// allowedValuesPhrase matches the phrases documentation uses to introduce
+// the values an attribute accepts.
+var allowedValuesPhrase = regexp.MustCompile(`(?i)\b(?:(?:possible|valid|allowed|accepted|supported|permitted)\s+values\s+(?:are|include|is)|(?:must|can|should)\s+be\s+(?:one\s+of(?:\s+the\s+following)?|either))\b:?`)
+
+// InferAllowedValues returns the values that text, an attribute's
+// description or documentation, lists as the ones the attribute accepts,
+// in the order listed. It recognises sentences such as "Possible values
+// are `Basic`, `Standard` and `Premium`.", "Valid values include "a" or
+// "b"." and "Must be one of: `x`, `y`.", taking the code spans, or failing
+// those the double-quoted strings, up to the end of the sentence. It
+// returns nil if text lists no values this way; values written as plain
+// words are not recognised, since they cannot be told apart from prose.
+func InferAllowedValues(text string) []string {
+	for _, loc := range allowedValuesPhrase.FindAllStringIndex(text, -1) {
+		if values := listedValues(sentenceFrom(text[loc[1]:])); len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+// sentenceFrom returns s up to the end of its first sentence, ignoring
+// full stops inside code spans and quoted strings.
+func sentenceFrom(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '`' || c == '"':
+			quote = c
+		case c == ';' || c == '\n' && i+1 < len(s) && s[i+1] == '\n':
+			return s[:i]
+		case c == '.' && (i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\n'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// listedValues returns the code spans of s or, if it has none, its
+// double-quoted strings, without duplicates.
+func listedValues(s string) []string {
+	for _, delim := range []string{"`", `"`} {
+		var values []string
+		parts := strings.Split(s, delim)
+		for i := 1; i < len(parts)-1; i += 2 {
+			if v := parts[i]; !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
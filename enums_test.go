@@ -0,0 +1,115 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestInferAllowedValues(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The SKU. Possible values are `Basic`, `Standard` and `Premium`.", []string{"Basic", "Standard", "Premium"}},
+		{"Possible values include `v1.0` or `v1.1`. Defaults to `v1.0`.", []string{"v1.0", "v1.1"}},
+		{`Valid values are "tcp", "udp" and "tcp".`, []string{"tcp", "udp"}},
+		{"Must be one of: `a`, `b`; changing this forces a new resource.", []string{"a", "b"}},
+		{"The mode, which can be either `Auto` or `Manual`", []string{"Auto", "Manual"}},
+		{"Allowed values are Basic and Standard.", nil},
+		{"Possible values are described below. The default is `x`.", nil},
+		{"The name of the resource.", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, InferAllowedValues(tt.text))
+		})
+	}
+}
+
+const enumPage = "# cloud_widget\n\n" +
+	"## Argument Reference\n\n" +
+	"* `sku` - (Required) The SKU. Possible values are `Basic` and `Standard`.\n" +
+	"* `tier` - (Optional) The tier. Possible values are `Free` and `Paid`.\n" +
+	"* `rule` - (Optional) A `rule` block as defined below.\n" +
+	"* `unknown` - (Optional) Possible values are `x`.\n\n" +
+	"---\n\n" +
+	"A `rule` block supports the following:\n\n" +
+	"* `protocol` - (Required) Valid values are `Tcp` and `Udp`.\n\n" +
+	"## Attributes Reference\n\n" +
+	"* `protocol` - Possible values are `ignored`, as widgets have no such attribute.\n"
+
+func TestExtendSchema(t *testing.T) {
+	schema := &tfjson.Schema{Block: &tfjson.SchemaBlock{
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"sku":  {AttributeType: cty.String, Required: true},
+			"tier": {AttributeType: cty.String, Optional: true, Description: "The tier. Possible values are `Free`, `Paid` and `Enterprise`."},
+			"settings": {Optional: true, AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+				NestingMode: tfjson.SchemaNestingModeSingle,
+				Attributes: map[string]*tfjson.SchemaAttribute{
+					"mode": {AttributeType: cty.String, Optional: true, Description: "Must be one of `On` or `Off`."},
+				},
+			}},
+		},
+		NestedBlocks: map[string]*tfjson.SchemaBlockType{
+			"rule": {NestingMode: tfjson.SchemaNestingModeList, Block: &tfjson.SchemaBlock{
+				Attributes: map[string]*tfjson.SchemaAttribute{"protocol": {AttributeType: cty.String, Required: true}},
+			}},
+		},
+	}}
+
+	e := ExtendSchema(schema, enumPage)
+	assert.Same(t, schema, e.Schema)
+	assert.Equal(t, []AllowedValues{
+		{Path: "rule.protocol", Values: []string{"Tcp", "Udp"}, Source: AllowedValuesFromDocumentation},
+		{Path: "settings.mode", Values: []string{"On", "Off"}, Source: AllowedValuesFromDescription},
+		{Path: "sku", Values: []string{"Basic", "Standard"}, Source: AllowedValuesFromDocumentation},
+		{Path: "tier", Values: []string{"Free", "Paid", "Enterprise"}, Source: AllowedValuesFromDescription},
+	}, e.AllowedValues, "descriptions take precedence over the page")
+	assert.Equal(t, []string{"Tcp", "Udp"}, e.Values("rule.protocol"))
+	assert.Nil(t, e.Values("unknown"))
+
+	e = ExtendSchema(schema, "")
+	assert.Len(t, e.AllowedValues, 2)
+	assert.Empty(t, ExtendSchema(nil, enumPage).AllowedValues)
+}
+
+func TestServer_GetExtendedResourceSchema(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/example/cloud":
+			fmt.Fprint(w, `{"source":"https://github.com/example/terraform-provider-cloud"}`)
+		case "/example/terraform-provider-cloud/v1.0.0/docs/resources/widget.md":
+			fmt.Fprint(w, enumPage)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	bundle := fstest.MapFS{"opentofu/example/cloud/1.0.0.json": {Data: []byte(`{"resource_schemas": {
+		"cloud_widget": {"version": 0, "block": {"attributes": {"sku": {"type": "string", "required": true}}}},
+		"cloud_gadget": {"version": 0, "block": {"attributes": {
+			"size": {"type": "string", "optional": true, "description": "Possible values are ` + "`S` and `M`" + `."}
+		}}}
+	}}`)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "cloud", Version: "1.0.0"}
+
+	e, err := s.GetExtendedResourceSchema(req, "cloud_widget")
+	require.NoError(t, err)
+	assert.Equal(t, []AllowedValues{{Path: "sku", Values: []string{"Basic", "Standard"}, Source: AllowedValuesFromDocumentation}}, e.AllowedValues)
+
+	e, err = s.GetExtendedResourceSchema(req, "cloud_gadget")
+	require.NoError(t, err, "a resource without a page is extended from its descriptions")
+	assert.Equal(t, []string{"S", "M"}, e.Values("size"))
+
+	_, err = s.GetExtendedResourceSchema(req, "cloud_missing")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
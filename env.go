@@ -0,0 +1,87 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by NewServerFromEnv. EnvCacheDir is also
+// honoured by NewServer.
+const (
+	// EnvRegistry selects the registry for requests that do not name one:
+	// "opentofu", "terraform" or a registry host; see ParseRegistryType.
+	EnvRegistry = "TFPLUGINSCHEMA_REGISTRY"
+	// EnvOffline disables network access when set to a true value accepted
+	// by strconv.ParseBool ("1", "true", ...); see WithOffline.
+	EnvOffline = "TFPLUGINSCHEMA_OFFLINE"
+	// EnvLogLevel sets the minimum log level: "debug", "info", "warn" or
+	// "error"; see WithLogLevel.
+	EnvLogLevel = "TFPLUGINSCHEMA_LOG_LEVEL"
+	// EnvTimeout bounds each HTTP request, as a Go duration such as "30s"
+	// or "2m"; see WithTimeout.
+	EnvTimeout = "TFPLUGINSCHEMA_TIMEOUT"
+)
+
+// NewServerFromEnv creates a Server configured by opts and then by the
+// TFPLUGINSCHEMA_* environment variables, so that a containerised
+// application can be reconfigured without code changes. Variables that are
+// unset or empty leave the corresponding setting alone; set variables take
+// precedence over opts. An invalid value is reported as an error.
+//
+// When l is nil and TFPLUGINSCHEMA_LOG_LEVEL is set, logs are written as
+// text to stderr at that level, rather than discarded.
+func NewServerFromEnv(l *slog.Logger, opts ...ServerOption) (*Server, error) {
+	envOpts, level, err := envServerOptions()
+	if err != nil {
+		return nil, err
+	}
+	if l == nil && level != nil {
+		l = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+	return NewServer(l, append(opts, envOpts...)...), nil
+}
+
+// envServerOptions converts the TFPLUGINSCHEMA_* environment variables into
+// Server options. The log level is also returned, or nil if it is unset.
+func envServerOptions() ([]ServerOption, slog.Leveler, error) {
+	var (
+		opts  []ServerOption
+		level slog.Leveler
+	)
+	if v := os.Getenv(EnvRegistry); v != "" {
+		r, err := ParseRegistryType(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", EnvRegistry, err)
+		}
+		opts = append(opts, WithDefaultRegistry(r))
+	}
+	if v := os.Getenv(EnvCacheDir); v != "" {
+		opts = append(opts, WithCacheDir(v))
+	}
+	if v := os.Getenv(EnvOffline); v != "" {
+		offline, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s %q: expected a boolean", EnvOffline, v)
+		}
+		opts = append(opts, WithOffline(offline))
+	}
+	if v := os.Getenv(EnvLogLevel); v != "" {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(v)); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s %q: expected debug, info, warn or error", EnvLogLevel, v)
+		}
+		level = lvl
+		opts = append(opts, WithLogLevel(lvl))
+	}
+	if v := os.Getenv(EnvTimeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, nil, fmt.Errorf("invalid %s %q: expected a positive duration such as 30s", EnvTimeout, v)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+	return opts, level, nil
+}
@@ -0,0 +1,76 @@
+package tfpluginschema
+
+import (
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServerFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvRegistry, "Terraform")
+	t.Setenv(EnvCacheDir, dir)
+	t.Setenv(EnvOffline, "true")
+	t.Setenv(EnvLogLevel, "warn")
+	t.Setenv(EnvTimeout, "45s")
+
+	client := &http.Client{}
+	s, err := NewServerFromEnv(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	assert.Equal(t, RegistryTypeTerraform, s.defaultRegistry)
+	assert.Equal(t, dir, s.CacheDir(), "environment takes precedence over options")
+	assert.True(t, s.offline)
+	assert.Equal(t, slog.LevelWarn, s.logLevel)
+	assert.Equal(t, 45*time.Second, s.httpClient.Timeout)
+	assert.Zero(t, client.Timeout, "the caller's client must not be modified")
+}
+
+func TestNewServerFromEnv_Unset(t *testing.T) {
+	for _, env := range []string{EnvRegistry, EnvCacheDir, EnvOffline, EnvLogLevel, EnvTimeout} {
+		t.Setenv(env, "")
+	}
+	s, err := NewServerFromEnv(nil, WithOffline(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	assert.Empty(t, s.defaultRegistry)
+	assert.True(t, s.offline)
+	assert.Nil(t, s.logLevel)
+	assert.Zero(t, s.httpClient.Timeout)
+}
+
+func TestNewServerFromEnv_Invalid(t *testing.T) {
+	tests := map[string]string{
+		EnvRegistry: "github",
+		EnvOffline:  "maybe",
+		EnvLogLevel: "loud",
+		EnvTimeout:  "-5s",
+	}
+	for env, value := range tests {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := NewServerFromEnv(nil)
+			assert.ErrorContains(t, err, env)
+		})
+	}
+}
+
+func TestWithDefaultRegistry(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"}]}`))
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithDefaultRegistry(RegistryTypeTerraform))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+
+	_, ok := s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform})]
+	assert.True(t, ok, "requests without a registry use the default")
+}
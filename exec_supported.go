@@ -0,0 +1,7 @@
+//go:build !(js || wasip1)
+
+package tfpluginschema
+
+// pluginExecSupported reports whether this platform can start provider
+// plugins as subprocesses.
+const pluginExecSupported = true
@@ -0,0 +1,7 @@
+//go:build js || wasip1
+
+package tfpluginschema
+
+// pluginExecSupported is false on platforms without subprocesses, where
+// schemas can only be served from a schema bundle.
+const pluginExecSupported = false
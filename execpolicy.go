@@ -0,0 +1,47 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExecDenied is returned when the ExecPolicy set with WithExecPolicy
+// refuses to let a provider binary run.
+var ErrExecDenied = errors.New("provider execution denied by policy")
+
+// ExecPolicy decides whether the provider binary at binaryPath, with the hex
+// SHA-256 digest sha256, may be executed on behalf of req. Returning an
+// error stops the execution; the error is wrapped in ErrExecDenied. req
+// carries a concrete version and its registry type.
+type ExecPolicy func(req Request, binaryPath, sha256 string) error
+
+// WithExecPolicy makes the Server call policy before spawning any provider
+// binary, after downloading it and before writing its audit log entry, so
+// that organizations can enforce allowlists and denylists or require the
+// checksum to be known to an internal attestation service. The policy may
+// be called concurrently. Binaries are hashed each time they are about to
+// be executed, so a cached binary changed on disk is seen with its new
+// digest.
+func WithExecPolicy(policy ExecPolicy) ServerOption {
+	return func(s *Server) {
+		s.execPolicy = policy
+	}
+}
+
+// checkExecPolicy applies the exec policy, if any, to the binary at
+// providerPath with the given digest. A panicking policy denies execution.
+func (s *Server) checkExecPolicy(request Request, providerPath, digest string) (err error) {
+	if s.execPolicy == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s/%s %s: policy panicked: %v", ErrExecDenied, request.Namespace, request.Name, request.Version, r)
+		}
+	}()
+	if perr := s.execPolicy(request, providerPath, digest); perr != nil {
+		s.logger(logComponentPlugin).Warn("Provider execution denied by policy", append(s.requestLogAttrs(request), "path", providerPath, "sha256", digest, "error", perr)...)
+		return fmt.Errorf("%w: %s/%s %s: %w", ErrExecDenied, request.Namespace, request.Name, request.Version, perr)
+	}
+	return nil
+}
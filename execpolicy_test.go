@@ -0,0 +1,66 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithExecPolicy(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, providerFileNamePrefix+"null")
+	require.NoError(t, os.WriteFile(binary, []byte("binary"), 0o755))
+	logPath := filepath.Join(dir, "audit.jsonl")
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}
+
+	t.Run("denied", func(t *testing.T) {
+		errNotAllowed := errors.New("not on the allowlist")
+		var gotReq Request
+		var gotPath, gotDigest string
+		s := NewServer(nil, WithCacheDir(dir), WithAuditLog(logPath), WithExecPolicy(func(req Request, binaryPath, sha256 string) error {
+			gotReq, gotPath, gotDigest = req, binaryPath, sha256
+			return errNotAllowed
+		}))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		_, err := s.startProviderBinary(req, binary)
+		require.ErrorIs(t, err, ErrExecDenied)
+		require.ErrorIs(t, err, errNotAllowed)
+		assert.ErrorContains(t, err, "hashicorp/null 1.0.0")
+		assert.Equal(t, req, gotReq)
+		assert.Equal(t, binary, gotPath)
+		assert.Equal(t, sha256Hex([]byte("binary")), gotDigest)
+		assert.NoFileExists(t, logPath, "denied binaries are not audited")
+	})
+
+	t.Run("panicking policy denies", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(dir), WithExecPolicy(func(Request, string, string) error {
+			panic("boom")
+		}))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		_, err := s.startProviderBinary(req, binary)
+		require.ErrorIs(t, err, ErrExecDenied)
+		assert.ErrorContains(t, err, "policy panicked: boom")
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		var calls int
+		s := NewServer(nil, WithCacheDir(dir), WithAuditLog(logPath), WithExecPolicy(func(Request, string, string) error {
+			calls++
+			return nil
+		}))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		// The fake binary cannot be started, but gets past the policy.
+		_, err := s.startProviderBinary(req, binary)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrExecDenied)
+		assert.Equal(t, 1, calls)
+		assert.FileExists(t, logPath)
+	})
+}
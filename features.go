@@ -0,0 +1,227 @@
+package tfpluginschema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotSupportedByProvider is returned when listing the functions or
+// ephemeral resources of a provider built before the plugin protocol had
+// them (protocol 5.5 and 6.5 for functions, 5.7 and 6.7 for ephemeral
+// resources), to tell it apart from a provider that supports them but
+// declares none.
+var ErrNotSupportedByProvider = errors.New("not supported by the provider")
+
+// featureSupport is whether a provider implements an optional part of the
+// plugin protocol.
+type featureSupport uint8
+
+const (
+	featureUnknown featureSupport = iota
+	featureSupported
+	featureUnsupported
+)
+
+// providerFeatures records which optional parts of the plugin protocol a
+// provider implements. They are probed when the provider is run; schemas
+// read from bundles, snapshots or other sources leave them unknown.
+type providerFeatures struct {
+	functions          featureSupport
+	ephemeralResources featureSupport
+	identities         featureSupport
+	// identityCount is the number of resource identity schemas the
+	// provider declares, when identities are supported.
+	identityCount int
+}
+
+// support returns whether the provider implements entries of kind.
+func (f providerFeatures) support(kind Kind) featureSupport {
+	switch kind {
+	case KindFunction:
+		return f.functions
+	case KindEphemeralResource:
+		return f.ephemeralResources
+	}
+	return featureUnknown
+}
+
+// featureClient is implemented by schema clients that can probe the
+// optional RPCs of a provider.
+type featureClient interface {
+	probeFeatures(ctx context.Context, opts ...grpc.CallOption) providerFeatures
+}
+
+// probeFeatures calls GetFunctions, GetResourceIdentitySchemas and, with
+// no type name, ValidateEphemeralResourceConfig on the V5 client. Providers
+// that predate them answer Unimplemented; others answer, with error
+// diagnostics in the last case.
+func (c v5SchemaClient) probeFeatures(ctx context.Context, opts ...grpc.CallOption) providerFeatures {
+	_, fnErr := c.client.GetFunctions(ctx, &tfplugin5.GetFunctions_Request{}, opts...)
+	_, ephErr := c.client.ValidateEphemeralResourceConfig(ctx, &tfplugin5.ValidateEphemeralResourceConfig_Request{}, opts...)
+	identities, idErr := c.client.GetResourceIdentitySchemas(ctx, &tfplugin5.GetResourceIdentitySchemas_Request{}, opts...)
+	return providerFeatures{
+		functions:          probedFeature(fnErr),
+		ephemeralResources: probedFeature(ephErr),
+		identities:         probedFeature(idErr),
+		identityCount:      len(identities.GetIdentitySchemas()),
+	}
+}
+
+// probeFeatures is the V6 equivalent of v5SchemaClient.probeFeatures.
+func (c v6SchemaClient) probeFeatures(ctx context.Context, opts ...grpc.CallOption) providerFeatures {
+	_, fnErr := c.client.GetFunctions(ctx, &tfplugin6.GetFunctions_Request{}, opts...)
+	_, ephErr := c.client.ValidateEphemeralResourceConfig(ctx, &tfplugin6.ValidateEphemeralResourceConfig_Request{}, opts...)
+	identities, idErr := c.client.GetResourceIdentitySchemas(ctx, &tfplugin6.GetResourceIdentitySchemas_Request{}, opts...)
+	return providerFeatures{
+		functions:          probedFeature(fnErr),
+		ephemeralResources: probedFeature(ephErr),
+		identities:         probedFeature(idErr),
+		identityCount:      len(identities.GetIdentitySchemas()),
+	}
+}
+
+// probedFeature interprets the error of a probing call. Errors other than
+// Unimplemented, such as a crashed provider, leave the feature unknown.
+func probedFeature(err error) featureSupport {
+	switch {
+	case err == nil:
+		return featureSupported
+	case status.Code(err) == codes.Unimplemented:
+		return featureUnsupported
+	}
+	return featureUnknown
+}
+
+// Features probes the optional RPCs of the provider.
+func (c *providerGRPCClient[TReq, TResp]) Features() providerFeatures {
+	fc, ok := c.grpcClient.(featureClient)
+	if !ok {
+		return providerFeatures{}
+	}
+	return fc.probeFeatures(context.Background())
+}
+
+// features probes the provider with whichever protocol was negotiated.
+func (c *universalProviderClient) features() providerFeatures {
+	switch {
+	case c.v6 != nil:
+		return c.v6.Features()
+	case c.v5 != nil:
+		return c.v5.Features()
+	}
+	return providerFeatures{}
+}
+
+// errNotSupported reports that the provider of request predates entries of
+// kind.
+func errNotSupported(request Request, kind Kind) error {
+	return fmt.Errorf("%w: %s predates %s support in the plugin protocol", ErrNotSupportedByProvider, request.String(), kindPlural(kind))
+}
+
+// kindPlural names the entries of kind in messages.
+func kindPlural(kind Kind) string {
+	switch kind {
+	case KindFunction:
+		return "provider-defined functions"
+	case KindEphemeralResource:
+		return "ephemeral resources"
+	}
+	return string(kind) + " entries"
+}
+
+// FeatureSupport is whether a provider supports a part of the plugin
+// protocol.
+type FeatureSupport string
+
+const (
+	// FeatureSupported means the provider declares entries of the feature,
+	// or implements the protocol calls behind it.
+	FeatureSupported FeatureSupport = "supported"
+	// FeatureUnsupported means the provider predates the feature.
+	FeatureUnsupported FeatureSupport = "unsupported"
+	// FeatureUnknown means neither could be established, for example for
+	// a schema read from a schema bundle, which records no protocol calls.
+	FeatureUnknown FeatureSupport = "unknown"
+)
+
+// FeatureStatus describes a provider's support for one feature.
+type FeatureStatus struct {
+	Support FeatureSupport `json:"support"`
+	// Count is the number of entries of the feature the provider declares.
+	Count int `json:"count"`
+}
+
+// FeatureMatrix reports which optional parts of the plugin protocol a
+// provider supports; see Server.GetFeatureSupport.
+type FeatureMatrix struct {
+	// Protocol is the major plugin protocol version the provider speaks,
+	// or 0 if its schema was not retrieved by running it.
+	Protocol int `json:"protocol"`
+	// Functions counts provider-defined functions (protocol 5.5 and 6.5).
+	Functions FeatureStatus `json:"functions"`
+	// EphemeralResources counts ephemeral resources (5.7 and 6.7).
+	EphemeralResources FeatureStatus `json:"ephemeral_resources"`
+	// ResourceIdentities counts resource identity schemas (6.9).
+	ResourceIdentities FeatureStatus `json:"resource_identities"`
+	// Actions is always FeatureUnknown: the protocol versions this package
+	// speaks have no actions.
+	Actions FeatureStatus `json:"actions"`
+	// WriteOnlyAttributes counts write-only attributes of managed
+	// resources (6.10), as listed by ListWriteOnlyAttributes.
+	WriteOnlyAttributes FeatureStatus `json:"write_only_attributes"`
+}
+
+// GetFeatureSupport reports which optional parts of the plugin protocol
+// the provider of request supports. A feature is supported if the provider
+// declares entries of it. Otherwise the answer comes from the protocol
+// calls probed when the provider was run, and from the order in which
+// features were added to the protocol: a provider that predates ephemeral
+// resources or resource identities also predates write-only attributes.
+// Like ListWriteOnlyAttributes, it converts the schema of every resource.
+func (s *Server) GetFeatureSupport(request Request) (FeatureMatrix, error) {
+	s.logger(logComponentSchema).Debug("Getting feature support", s.requestLogAttrs(request)...)
+
+	ls, err := s.readSchema(request)
+	if err != nil {
+		return FeatureMatrix{}, err
+	}
+	writeOnly, err := s.ListWriteOnlyAttributes(request)
+	if err != nil {
+		return FeatureMatrix{}, err
+	}
+
+	f := ls.features
+	m := FeatureMatrix{
+		Protocol:           ls.protocol,
+		Functions:          featureStatus(len(schemaNames(ls, ls.functions)), f.functions),
+		EphemeralResources: featureStatus(len(schemaNames(ls, ls.ephemeralResources)), f.ephemeralResources),
+		ResourceIdentities: featureStatus(f.identityCount, f.identities),
+		Actions:            FeatureStatus{Support: FeatureUnknown},
+	}
+	writeOnlySupport := featureUnknown
+	if f.ephemeralResources == featureUnsupported || f.identities == featureUnsupported {
+		writeOnlySupport = featureUnsupported
+	}
+	m.WriteOnlyAttributes = featureStatus(len(writeOnly), writeOnlySupport)
+	return m, nil
+}
+
+// featureStatus returns the status of a feature with count entries and
+// the given probed support.
+func featureStatus(count int, probed featureSupport) FeatureStatus {
+	status := FeatureStatus{Support: FeatureUnknown, Count: count}
+	switch {
+	case count > 0 || probed == featureSupported:
+		status.Support = FeatureSupported
+	case probed == featureUnsupported:
+		status.Support = FeatureUnsupported
+	}
+	return status
+}
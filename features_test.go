@@ -0,0 +1,189 @@
+package tfpluginschema
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// featureV6ProviderClient answers the calls probing features; calling any
+// other method panics.
+type featureV6ProviderClient struct {
+	tfplugin6.ProviderClient
+	functionsErr error
+	ephemeralErr error
+	identities   map[string]*tfplugin6.ResourceIdentitySchema
+	identityErr  error
+}
+
+func (c featureV6ProviderClient) GetFunctions(context.Context, *tfplugin6.GetFunctions_Request, ...grpc.CallOption) (*tfplugin6.GetFunctions_Response, error) {
+	return &tfplugin6.GetFunctions_Response{}, c.functionsErr
+}
+
+func (c featureV6ProviderClient) ValidateEphemeralResourceConfig(context.Context, *tfplugin6.ValidateEphemeralResourceConfig_Request, ...grpc.CallOption) (*tfplugin6.ValidateEphemeralResourceConfig_Response, error) {
+	return &tfplugin6.ValidateEphemeralResourceConfig_Response{
+		Diagnostics: []*tfplugin6.Diagnostic{{Severity: tfplugin6.Diagnostic_ERROR, Summary: "Ephemeral Resource Type Not Found"}},
+	}, c.ephemeralErr
+}
+
+func (c featureV6ProviderClient) GetResourceIdentitySchemas(context.Context, *tfplugin6.GetResourceIdentitySchemas_Request, ...grpc.CallOption) (*tfplugin6.GetResourceIdentitySchemas_Response, error) {
+	if c.identityErr != nil {
+		return nil, c.identityErr
+	}
+	return &tfplugin6.GetResourceIdentitySchemas_Response{IdentitySchemas: c.identities}, nil
+}
+
+type featureV5ProviderClient struct {
+	tfplugin5.ProviderClient
+}
+
+func (featureV5ProviderClient) GetFunctions(context.Context, *tfplugin5.GetFunctions_Request, ...grpc.CallOption) (*tfplugin5.GetFunctions_Response, error) {
+	return nil, status.Error(codes.Unimplemented, "unknown method GetFunctions")
+}
+
+func (featureV5ProviderClient) ValidateEphemeralResourceConfig(context.Context, *tfplugin5.ValidateEphemeralResourceConfig_Request, ...grpc.CallOption) (*tfplugin5.ValidateEphemeralResourceConfig_Response, error) {
+	return nil, status.Error(codes.Unimplemented, "unknown method ValidateEphemeralResourceConfig")
+}
+
+func (featureV5ProviderClient) GetResourceIdentitySchemas(context.Context, *tfplugin5.GetResourceIdentitySchemas_Request, ...grpc.CallOption) (*tfplugin5.GetResourceIdentitySchemas_Response, error) {
+	return nil, status.Error(codes.Unimplemented, "unknown method GetResourceIdentitySchemas")
+}
+
+func TestUniversalProviderClient_Features(t *testing.T) {
+	unimplemented := status.Error(codes.Unimplemented, "unknown method")
+	tests := []struct {
+		name   string
+		client *universalProviderClient
+		want   providerFeatures
+	}{
+		{
+			name: "v6 with all",
+			client: newV6MetadataClient(featureV6ProviderClient{identities: map[string]*tfplugin6.ResourceIdentitySchema{
+				"test_a": {}, "test_b": {},
+			}}),
+			want: providerFeatures{functions: featureSupported, ephemeralResources: featureSupported, identities: featureSupported, identityCount: 2},
+		},
+		{
+			name:   "v6 with functions only",
+			client: newV6MetadataClient(featureV6ProviderClient{ephemeralErr: unimplemented, identityErr: unimplemented}),
+			want:   providerFeatures{functions: featureSupported, ephemeralResources: featureUnsupported, identities: featureUnsupported},
+		},
+		{
+			name: "v6 failing",
+			client: newV6MetadataClient(featureV6ProviderClient{
+				functionsErr: errors.New("connection reset"),
+				ephemeralErr: status.Error(codes.Unavailable, "gone"),
+				identityErr:  status.Error(codes.Unavailable, "gone"),
+			}),
+			want: providerFeatures{},
+		},
+		{
+			name: "v5 with neither",
+			client: &universalProviderClient{v5: &providerGRPCClientV5{
+				providerGRPCClient: &providerGRPCClient[*tfplugin5.GetProviderSchema_Request, *tfplugin5.GetProviderSchema_Response]{
+					grpcClient: v5SchemaClient{client: featureV5ProviderClient{}},
+				},
+			}},
+			want: providerFeatures{functions: featureUnsupported, ephemeralResources: featureUnsupported, identities: featureUnsupported},
+		},
+		{
+			name: "schema client without probes",
+			client: &universalProviderClient{v6: &providerGRPCClientV6{
+				providerGRPCClient: &providerGRPCClient[*tfplugin6.GetProviderSchema_Request, *tfplugin6.GetProviderSchema_Response]{grpcClient: &mockV6SchemaClient{}},
+			}},
+			want: providerFeatures{},
+		},
+		{
+			name:   "no protocol",
+			client: &universalProviderClient{},
+			want:   providerFeatures{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.client.features())
+		})
+	}
+}
+
+func TestServer_List_NotSupportedByProvider(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	legacy := Request{Namespace: "example", Name: "legacy", Version: "1.0.0"}
+	s.mdc[cacheKey(legacy)] = &providerMetadata{
+		resources: []string{"legacy_thing"},
+		features:  providerFeatures{functions: featureUnsupported, ephemeralResources: featureUnsupported},
+	}
+	modern := Request{Namespace: "example", Name: "modern", Version: "1.0.0"}
+	s.mdc[cacheKey(modern)] = &providerMetadata{
+		resources: []string{"modern_thing"},
+		features:  providerFeatures{functions: featureSupported, ephemeralResources: featureSupported},
+	}
+	unknown := Request{Namespace: "example", Name: "bundled", Version: "1.0.0"}
+	s.mdc[cacheKey(unknown)] = &providerMetadata{resources: []string{"bundled_thing"}}
+
+	_, err := s.ListFunctions(legacy)
+	require.ErrorIs(t, err, ErrNotSupportedByProvider)
+	assert.ErrorContains(t, err, "provider-defined functions")
+	_, err = s.ListEphemeralResources(legacy)
+	require.ErrorIs(t, err, ErrNotSupportedByProvider)
+	assert.ErrorContains(t, err, "ephemeral resources")
+	names, err := s.ListResources(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"legacy_thing"}, names)
+
+	for _, request := range []Request{modern, unknown} {
+		names, err = s.ListFunctions(request)
+		require.NoError(t, err)
+		assert.Empty(t, names)
+		names, err = s.ListEphemeralResources(request)
+		require.NoError(t, err)
+		assert.Empty(t, names)
+	}
+}
+
+func TestServer_GetFeatureSupport(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaSources(BundleSource(bundle)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	m, err := s.GetFeatureSupport(Request{Namespace: "example", Name: "example", Version: "1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, FeatureMatrix{
+		Functions:           FeatureStatus{Support: FeatureUnknown},
+		EphemeralResources:  FeatureStatus{Support: FeatureSupported, Count: 1},
+		ResourceIdentities:  FeatureStatus{Support: FeatureUnknown},
+		Actions:             FeatureStatus{Support: FeatureUnknown},
+		WriteOnlyAttributes: FeatureStatus{Support: FeatureSupported, Count: 3},
+	}, m)
+
+	// A provider run and probed.
+	legacy := Request{Namespace: "example", Name: "legacy", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}
+	ls, err := newLazySchemaV5(&tfplugin5.GetProviderSchema_Response{
+		ResourceSchemas: map[string]*tfplugin5.Schema{"legacy_thing": {Block: &tfplugin5.Schema_Block{}}},
+	}, nil)
+	require.NoError(t, err)
+	ls.features = providerFeatures{functions: featureSupported, ephemeralResources: featureUnsupported, identities: featureUnsupported}
+	s.storeSchema(legacy, cacheKey(legacy), ls)
+
+	m, err = s.GetFeatureSupport(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, FeatureMatrix{
+		Protocol:            5,
+		Functions:           FeatureStatus{Support: FeatureSupported},
+		EphemeralResources:  FeatureStatus{Support: FeatureUnsupported},
+		ResourceIdentities:  FeatureStatus{Support: FeatureUnsupported},
+		Actions:             FeatureStatus{Support: FeatureUnknown},
+		WriteOnlyAttributes: FeatureStatus{Support: FeatureUnsupported},
+	}, m)
+}
@@ -0,0 +1,158 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"slices"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+)
+
+// FunctionDetails is a provider function's signature together with the
+// details of the plugin protocol that tfjson.FunctionSignature has no
+// fields for, for documentation generators that render markdown
+// descriptions or note which parameters accept unknown values.
+//
+// Details are only known for schemas retrieved from a provider, or
+// restored from a snapshot of one; for bundled schemas every detail is
+// the zero value.
+type FunctionDetails struct {
+	Signature *tfjson.FunctionSignature `json:"-"`
+	// DescriptionKind is the format of Signature.Description.
+	DescriptionKind tfjson.SchemaDescriptionKind `json:"description_kind,omitempty"`
+	// Parameters holds the details of Signature.Parameters, in the same
+	// order.
+	Parameters []ParameterDetails `json:"parameters,omitempty"`
+	// VariadicParameter holds the details of Signature.VariadicParameter.
+	VariadicParameter *ParameterDetails `json:"variadic_parameter,omitempty"`
+}
+
+// ParameterDetails is the part of a function parameter that
+// tfjson.FunctionParameter has no fields for.
+type ParameterDetails struct {
+	// DescriptionKind is the format of the parameter's description.
+	DescriptionKind tfjson.SchemaDescriptionKind `json:"description_kind,omitempty"`
+	// AllowUnknownValues reports whether the function is called with
+	// unknown values for the parameter instead of returning unknown.
+	AllowUnknownValues bool `json:"allow_unknown_values,omitempty"`
+}
+
+// GetFunctionDetails retrieves the signature of a specific function from the
+// provider together with its FunctionDetails. The signature is returned as
+// by GetFunctionSchema; with WithoutDescriptions the description kinds are
+// left out too.
+func (s *Server) GetFunctionDetails(request Request, function string, opts ...SchemaOption) (*FunctionDetails, error) {
+	s.logger(logComponentSchema).Debug("Getting function details", append(s.requestLogAttrs(request), "function", function)...)
+
+	schemaResp, err := s.readSchema(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+
+	signature, ok, err := schemaResp.function(function)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("function %w: %s", ErrSchemaNotFound, function)
+	}
+	details := schemaResp.functionDetailsOf(function)
+	details.Signature = s.returnFunction(signature, opts)
+	if len(details.Parameters) != len(signature.Parameters) {
+		details.Parameters = make([]ParameterDetails, len(signature.Parameters))
+	}
+	if details.VariadicParameter == nil && signature.VariadicParameter != nil {
+		details.VariadicParameter = &ParameterDetails{}
+	}
+	if newSchemaOptions(opts).withoutDescriptions {
+		details.DescriptionKind = ""
+		for i := range details.Parameters {
+			details.Parameters[i].DescriptionKind = ""
+		}
+		if details.VariadicParameter != nil {
+			details.VariadicParameter.DescriptionKind = ""
+		}
+	}
+	return &details, nil
+}
+
+// functionDetailsOf returns a copy of the recorded details of function,
+// without its Signature.
+func (ls *lazySchema) functionDetailsOf(function string) FunctionDetails {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	details := ls.functionDetails[function]
+	details.Parameters = slices.Clone(details.Parameters)
+	if details.VariadicParameter != nil {
+		vp := *details.VariadicParameter
+		details.VariadicParameter = &vp
+	}
+	return details
+}
+
+func v6FunctionDetails(functions map[string]*tfplugin6.Function) map[string]FunctionDetails {
+	if len(functions) == 0 {
+		return nil
+	}
+	details := make(map[string]FunctionDetails, len(functions))
+	for name, f := range functions {
+		if f == nil {
+			continue
+		}
+		d := FunctionDetails{DescriptionKind: v6DescriptionKind(f.GetDescriptionKind())}
+		for _, p := range f.GetParameters() {
+			d.Parameters = append(d.Parameters, v6ParameterDetails(p))
+		}
+		if vp := f.GetVariadicParameter(); vp != nil {
+			pd := v6ParameterDetails(vp)
+			d.VariadicParameter = &pd
+		}
+		details[name] = d
+	}
+	return details
+}
+
+func v6ParameterDetails(p *tfplugin6.Function_Parameter) ParameterDetails {
+	return ParameterDetails{DescriptionKind: v6DescriptionKind(p.GetDescriptionKind()), AllowUnknownValues: p.GetAllowUnknownValues()}
+}
+
+func v6DescriptionKind(k tfplugin6.StringKind) tfjson.SchemaDescriptionKind {
+	if k == tfplugin6.StringKind_MARKDOWN {
+		return tfjson.SchemaDescriptionKindMarkdown
+	}
+	return tfjson.SchemaDescriptionKindPlain
+}
+
+func v5FunctionDetails(functions map[string]*tfplugin5.Function) map[string]FunctionDetails {
+	if len(functions) == 0 {
+		return nil
+	}
+	details := make(map[string]FunctionDetails, len(functions))
+	for name, f := range functions {
+		if f == nil {
+			continue
+		}
+		d := FunctionDetails{DescriptionKind: v5DescriptionKind(f.GetDescriptionKind())}
+		for _, p := range f.GetParameters() {
+			d.Parameters = append(d.Parameters, v5ParameterDetails(p))
+		}
+		if vp := f.GetVariadicParameter(); vp != nil {
+			pd := v5ParameterDetails(vp)
+			d.VariadicParameter = &pd
+		}
+		details[name] = d
+	}
+	return details
+}
+
+func v5ParameterDetails(p *tfplugin5.Function_Parameter) ParameterDetails {
+	return ParameterDetails{DescriptionKind: v5DescriptionKind(p.GetDescriptionKind()), AllowUnknownValues: p.GetAllowUnknownValues()}
+}
+
+func v5DescriptionKind(k tfplugin5.StringKind) tfjson.SchemaDescriptionKind {
+	if k == tfplugin5.StringKind_MARKDOWN {
+		return tfjson.SchemaDescriptionKindMarkdown
+	}
+	return tfjson.SchemaDescriptionKindPlain
+}
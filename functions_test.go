@@ -0,0 +1,120 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverWithSchema returns a Server that cannot run providers, with schema
+// cached for request.
+func serverWithSchema(t *testing.T, request Request, schema *lazySchema) *Server {
+	t.Helper()
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	s.storeSchema(request, cacheKey(request), schema)
+	return s
+}
+
+func TestServer_GetFunctionDetails_V6(t *testing.T) {
+	schema, err := newLazySchemaV6(&tfplugin6.GetProviderSchema_Response{Functions: map[string]*tfplugin6.Function{
+		"parse": {
+			Description:     "Parses **input**.",
+			DescriptionKind: tfplugin6.StringKind_MARKDOWN,
+			Parameters: []*tfplugin6.Function_Parameter{
+				{Name: "input", Type: []byte(`"string"`), Description: "The `input`.", DescriptionKind: tfplugin6.StringKind_MARKDOWN, AllowUnknownValues: true},
+				{Name: "strict", Type: []byte(`"bool"`)},
+			},
+			VariadicParameter: &tfplugin6.Function_Parameter{Name: "extra", Type: []byte(`"string"`), AllowUnknownValues: true},
+			Return:            &tfplugin6.Function_Return{Type: []byte(`"string"`)},
+		},
+	}}, nil)
+	require.NoError(t, err)
+	request := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	s := serverWithSchema(t, request, schema)
+
+	details, err := s.GetFunctionDetails(request, "parse")
+	require.NoError(t, err)
+	assert.Equal(t, "Parses **input**.", details.Signature.Description)
+	assert.Equal(t, tfjson.SchemaDescriptionKindMarkdown, details.DescriptionKind)
+	require.Len(t, details.Parameters, 2)
+	assert.Equal(t, ParameterDetails{DescriptionKind: tfjson.SchemaDescriptionKindMarkdown, AllowUnknownValues: true}, details.Parameters[0])
+	assert.Equal(t, ParameterDetails{DescriptionKind: tfjson.SchemaDescriptionKindPlain}, details.Parameters[1])
+	assert.Equal(t, &ParameterDetails{DescriptionKind: tfjson.SchemaDescriptionKindPlain, AllowUnknownValues: true}, details.VariadicParameter)
+
+	// Callers get their own copy of the details.
+	details.Parameters[0].AllowUnknownValues = false
+	again, err := s.GetFunctionDetails(request, "parse")
+	require.NoError(t, err)
+	assert.True(t, again.Parameters[0].AllowUnknownValues)
+
+	stripped, err := s.GetFunctionDetails(request, "parse", WithoutDescriptions())
+	require.NoError(t, err)
+	assert.Empty(t, stripped.Signature.Description)
+	assert.Empty(t, stripped.DescriptionKind)
+	assert.Empty(t, stripped.Parameters[0].DescriptionKind)
+	assert.True(t, stripped.Parameters[0].AllowUnknownValues)
+
+	_, err = s.GetFunctionDetails(request, "missing")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
+
+func TestServer_GetFunctionDetails_V5(t *testing.T) {
+	schema, err := newLazySchemaV5(&tfplugin5.GetProviderSchema_Response{Functions: map[string]*tfplugin5.Function{
+		"parse": {
+			Parameters: []*tfplugin5.Function_Parameter{
+				{Name: "input", Type: []byte(`"string"`), DescriptionKind: tfplugin5.StringKind_MARKDOWN, AllowUnknownValues: true},
+			},
+			Return: &tfplugin5.Function_Return{Type: []byte(`"string"`)},
+		},
+	}}, nil)
+	require.NoError(t, err)
+	request := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	s := serverWithSchema(t, request, schema)
+
+	details, err := s.GetFunctionDetails(request, "parse")
+	require.NoError(t, err)
+	assert.Equal(t, tfjson.SchemaDescriptionKindPlain, details.DescriptionKind)
+	assert.Equal(t, []ParameterDetails{{DescriptionKind: tfjson.SchemaDescriptionKindMarkdown, AllowUnknownValues: true}}, details.Parameters)
+	assert.Nil(t, details.VariadicParameter)
+}
+
+func TestServer_GetFunctionDetails_Bundled(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	details, err := s.GetFunctionDetails(Request{Namespace: "example", Name: "example", Version: "1.0.0"}, "parse")
+	require.NoError(t, err)
+	assert.Equal(t, "Parse.", details.Signature.Summary)
+	assert.Empty(t, details.DescriptionKind)
+	assert.Equal(t, []ParameterDetails{{}}, details.Parameters, "one zero value per parameter")
+}
+
+func TestServer_Snapshot_KeepsFunctionDetails(t *testing.T) {
+	schema, err := newLazySchemaV6(&tfplugin6.GetProviderSchema_Response{Functions: map[string]*tfplugin6.Function{
+		"parse": {
+			Parameters: []*tfplugin6.Function_Parameter{{Name: "input", Type: []byte(`"string"`), AllowUnknownValues: true}},
+			Return:     &tfplugin6.Function_Return{Type: []byte(`"string"`)},
+		},
+	}}, nil)
+	require.NoError(t, err)
+	request := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	src := serverWithSchema(t, request, schema)
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf))
+	dst := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = dst.Cleanup() })
+	require.NoError(t, dst.RestoreSnapshot(&buf))
+
+	details, err := dst.GetFunctionDetails(request, "parse")
+	require.NoError(t, err)
+	assert.True(t, details.Parameters[0].AllowUnknownValues)
+}
@@ -0,0 +1,209 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// cacheMetadataPrefix starts the names of the files this library adds to
+// a cache entry next to the extracted provider package.
+const cacheMetadataPrefix = ".tfpluginschema-"
+
+// hashesFileName is the file in each cache entry listing the package
+// hashes of the archive it was extracted from, one per line.
+const hashesFileName = ".tfpluginschema-hashes"
+
+// hashSchemeZip prefixes the hex SHA-256 of a provider archive in the
+// "zh:" hashes of dependency lock files. "h1:" hashes are computed by
+// HashPackageDir.
+const hashSchemeZip = "zh:"
+
+// ErrHashMismatch is returned when a provider archive, or the cache entry
+// extracted from one, matches none of the hashes of a Request.
+var ErrHashMismatch = errors.New("provider package does not match any expected hash")
+
+// packageHashes returns the zh: hash of the provider archive at path and
+// the h1: hash of the files extracted from it into dir.
+func packageHashes(path, dir string) ([]string, error) {
+	zh, err := hashFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash provider archive: %w", err)
+	}
+	h1, err := HashPackageDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return []string{hashSchemeZip + zh, h1}, nil
+}
+
+// HashPackageDir returns the "h1:" hash of the provider package extracted
+// into dir, as Terraform computes it for dependency lock files: the base64
+// SHA-256 of a listing of the SHA-256 and slash-separated path of every
+// file under dir, sorted by path. Symbolic links are followed. The files
+// this library adds to its cache entries are left out, so the hash of a
+// cache entry is that of the archive it was extracted from.
+func HashPackageDir(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == d.Name() && strings.HasPrefix(rel, cacheMetadataPrefix) {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list provider package files: %w", err)
+	}
+	h1, err := dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash provider package: %w", err)
+	}
+	return h1, nil
+}
+
+// PackageHashes returns the hashes of the provider package of request, as
+// a dependency lock file records them, sorted: its h1: hash and, if the
+// archive was downloaded by a version of this library that records it, its
+// zh: hash. The provider is downloaded first unless it is cached, and a
+// version constraint is resolved to the latest matching version.
+func (s *Server) PackageHashes(request Request) ([]string, error) {
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.get(request); err != nil {
+		return nil, err
+	}
+	dir := cacheProviderDir(s.cacheDir, request)
+	hashes := recordedHashes(dir)
+	if len(hashes) == 0 {
+		h1, err := HashPackageDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		hashes = []string{h1}
+	}
+	slices.Sort(hashes)
+	return hashes, nil
+}
+
+// matchHashes returns an error wrapping ErrHashMismatch unless one of got
+// is in want. Hashes in an unknown scheme never match, and zh: digests are
+// compared case-insensitively.
+func matchHashes(got, want []string) error {
+	for _, w := range want {
+		w = strings.TrimSpace(w)
+		if hex, ok := strings.CutPrefix(w, hashSchemeZip); ok {
+			w = hashSchemeZip + strings.ToLower(hex)
+		}
+		if slices.Contains(got, w) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: package has %s, want one of %s", ErrHashMismatch, strings.Join(got, ", "), strings.Join(want, ", "))
+}
+
+// recordPackageHashes writes hashes to the hashes file in dir.
+func recordPackageHashes(dir string, hashes []string) error {
+	if err := os.WriteFile(filepath.Join(dir, hashesFileName), []byte(strings.Join(hashes, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record provider package hashes: %w", err)
+	}
+	return nil
+}
+
+// recordedHashes returns the package hashes recorded in the cache entry in
+// dir, or nil if it records none.
+func recordedHashes(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, hashesFileName))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// checkRecordedHashes compares the package hashes recorded in the cache
+// entry in dir with want. It returns nil when want is empty. An entry that
+// records no hashes, as those extracted by versions of this library that
+// did not record them or installed by ImportBundle do not, is hashed with
+// HashPackageDir and can only match an h1: hash.
+func checkRecordedHashes(dir string, want []string) error {
+	if len(want) == 0 {
+		return nil
+	}
+	hashes := recordedHashes(dir)
+	if len(hashes) == 0 {
+		h1, err := HashPackageDir(dir)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrHashMismatch, err)
+		}
+		hashes = []string{h1}
+	}
+	return matchHashes(hashes, want)
+}
+
+// ParseLockFile reads the providers pinned by a dependency lock file, such
+// as .terraform.lock.hcl, and returns a Request for each with its locked
+// version and the hashes recorded for it, ready to pass to the Server.
+// filename is only used in error messages. Providers are returned in the
+// order the file lists them.
+func ParseLockFile(data []byte, filename string) ([]Request, error) {
+	file, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse lock file: %w", diags)
+	}
+	var requests []Request
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "provider" || len(block.Labels) != 1 {
+			continue
+		}
+		req, err := ParseProviderSource(block.Labels[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid lock file %s: %w", filename, err)
+		}
+		if attr := block.Body.Attributes["version"]; attr != nil {
+			v, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || v.Type() != cty.String || v.IsNull() {
+				return nil, fmt.Errorf("invalid lock file %s: version of %s must be a string", filename, block.Labels[0])
+			}
+			req.Version = v.AsString()
+		}
+		if attr := block.Body.Attributes["hashes"]; attr != nil {
+			v, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || v.IsNull() || !v.CanIterateElements() {
+				return nil, fmt.Errorf("invalid lock file %s: hashes of %s must be a list of strings", filename, block.Labels[0])
+			}
+			for it := v.ElementIterator(); it.Next(); {
+				_, h := it.Element()
+				if h.Type() != cty.String || h.IsNull() {
+					return nil, fmt.Errorf("invalid lock file %s: hashes of %s must be a list of strings", filename, block.Labels[0])
+				}
+				req.Hashes = append(req.Hashes, h.AsString())
+			}
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
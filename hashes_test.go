@@ -0,0 +1,147 @@
+package tfpluginschema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+func TestServer_Get_Hashes(t *testing.T) {
+	archive := providerArchive(t, "null")
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	require.NoError(t, os.WriteFile(path, archive, 0o644))
+	h1, err := dirhash.HashZip(path, dirhash.Hash1)
+	require.NoError(t, err)
+	zh := "zh:" + strings.ToUpper(sha256Hex(archive))
+	other := "zh:" + sha256Hex([]byte("other"))
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	pinned := func(hashes ...string) Request {
+		r := req
+		r.Hashes = hashes
+		return r
+	}
+
+	for name, hashes := range map[string][]string{"zh": {other, zh}, "h1": {h1}} {
+		t.Run(name, func(t *testing.T) {
+			s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubSignedRegistry(t, archive, nil, nil)))
+			t.Cleanup(func() { _ = s.Cleanup() })
+			require.NoError(t, s.Get(pinned(hashes...)))
+		})
+	}
+
+	t.Run("mismatch", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubSignedRegistry(t, archive, nil, nil)))
+		t.Cleanup(func() { _ = s.Cleanup() })
+		err := s.Get(pinned(other, "h1:tampered"))
+		assert.ErrorIs(t, err, ErrHashMismatch)
+		_, ok := findProviderBinary(cacheProviderDir(cacheDir, req), req.Name)
+		assert.False(t, ok, "the archive is not extracted")
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		var downloads atomic.Int32
+		client := stubSignedRegistry(t, archive, nil, &downloads)
+		s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+		t.Cleanup(func() { _ = s.Cleanup() })
+		require.NoError(t, s.Get(req))
+		assert.ErrorIs(t, s.Get(pinned(other)), ErrHashMismatch, "the provider in use does not match")
+		require.NoError(t, s.Get(pinned(h1)))
+
+		s2 := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+		t.Cleanup(func() { _ = s2.Cleanup() })
+		require.NoError(t, s2.Get(pinned(zh)))
+		assert.EqualValues(t, 1, downloads.Load(), "a cache entry with matching hashes is used")
+
+		require.NoError(t, os.Remove(filepath.Join(cacheProviderDir(cacheDir, req), hashesFileName)))
+		offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true))
+		t.Cleanup(func() { _ = offline.Cleanup() })
+		assert.ErrorIs(t, offline.Get(pinned(zh)), ErrHashMismatch)
+		offline2 := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true))
+		t.Cleanup(func() { _ = offline2.Cleanup() })
+		require.NoError(t, offline2.Get(pinned(h1)), "an entry without recorded hashes is hashed")
+
+		s3 := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+		t.Cleanup(func() { _ = s3.Cleanup() })
+		require.NoError(t, s3.Get(pinned(zh)))
+		assert.EqualValues(t, 2, downloads.Load(), "an entry without recorded hashes is downloaded again")
+	})
+}
+
+func TestHashPackageDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform-provider-null_v1.0.0"), []byte("binary"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "README.md"), []byte("readme"), 0o644))
+	want, err := dirhash.HashDir(dir, "", dirhash.Hash1)
+	require.NoError(t, err)
+
+	require.NoError(t, recordBinaryChecksum(dir, "terraform-provider-null_v1.0.0"))
+	require.NoError(t, recordPackageHashes(dir, []string{"zh:00"}))
+	got, err := HashPackageDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, want, got, "cache metadata is left out")
+
+	_, err = HashPackageDir(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestServer_PackageHashes(t *testing.T) {
+	archive := providerArchive(t, "null")
+	cacheDir := t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubSignedRegistry(t, archive, nil, nil)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+
+	hashes, err := s.PackageHashes(req)
+	require.NoError(t, err)
+	require.Len(t, hashes, 2)
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	require.NoError(t, os.WriteFile(path, archive, 0o644))
+	h1, err := dirhash.HashZip(path, dirhash.Hash1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{h1, "zh:" + sha256Hex(archive)}, hashes)
+
+	require.NoError(t, os.Remove(filepath.Join(cacheProviderDir(cacheDir, req), hashesFileName)))
+	hashes, err = s.PackageHashes(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{h1}, hashes)
+}
+
+func TestParseLockFile(t *testing.T) {
+	const lock = `# This file is maintained automatically by "terraform init".
+
+provider "registry.terraform.io/hashicorp/azurerm" {
+  version     = "3.1.0"
+  constraints = "~> 3.0"
+  hashes = [
+    "h1:abc=",
+    "zh:0123",
+  ]
+}
+
+provider "registry.opentofu.org/hashicorp/null" {
+  version = "3.2.2"
+}
+`
+	requests, err := ParseLockFile([]byte(lock), ".terraform.lock.hcl")
+	require.NoError(t, err)
+	assert.Equal(t, []Request{
+		{Namespace: "hashicorp", Name: "azurerm", Version: "3.1.0", RegistryType: RegistryTypeTerraform, Hashes: []string{"h1:abc=", "zh:0123"}},
+		{Namespace: "hashicorp", Name: "null", Version: "3.2.2", RegistryType: RegistryTypeOpenTofu},
+	}, requests)
+
+	_, err = ParseLockFile([]byte(`provider "hashicorp/null" { hashes = "h1:abc=" }`), "lock.hcl")
+	assert.ErrorContains(t, err, "hashes of hashicorp/null must be a list of strings")
+	_, err = ParseLockFile([]byte(`provider "a/b/c/d" {}`), "lock.hcl")
+	assert.Error(t, err)
+	_, err = ParseLockFile([]byte(`provider {`), "lock.hcl")
+	assert.ErrorContains(t, err, "failed to parse lock file")
+}
@@ -0,0 +1,132 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// modulePath is this module's import path, used to look up its version in
+// the build information of the running binary.
+const modulePath = "github.com/matt-FFFFFF/tfpluginschema"
+
+// defaultUserAgent returns "tfpluginschema/<version>", where version is
+// this module's version as recorded in the binary's build information, or
+// "dev" when it is unavailable (e.g. under go test or go run).
+var defaultUserAgent = sync.OnceValue(func() string {
+	return "tfpluginschema/" + moduleVersion()
+})
+
+// moduleVersion returns the version of this module linked into the running
+// binary.
+func moduleVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	version := bi.Main.Version
+	if bi.Main.Path != modulePath {
+		version = ""
+		for _, dep := range bi.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+				break
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return "dev"
+	}
+	return version
+}
+
+// WithUserAgent overrides the User-Agent header sent with every request the
+// Server makes. The default is "tfpluginschema/<version>"; registries
+// rate-limit clients presenting Go's default User-Agent more aggressively,
+// so applications embedding the library are encouraged to identify
+// themselves. An empty value is ignored.
+func WithUserAgent(userAgent string) ServerOption {
+	return func(s *Server) {
+		if userAgent != "" {
+			s.userAgent = userAgent
+		}
+	}
+}
+
+// WithRequestHeaders adds headers to every registry API request (version
+// listings, download metadata and provider listings), for example a
+// correlation ID or a registry-specific header. They are not sent when
+// fetching provider archives, which are usually hosted elsewhere. Values for
+// a key replace any earlier value for that key, including User-Agent.
+// The option may be given more than once.
+func WithRequestHeaders(headers http.Header) ServerOption {
+	return func(s *Server) {
+		if s.requestHeaders == nil {
+			s.requestHeaders = make(http.Header, len(headers))
+		}
+		for k, v := range headers {
+			s.requestHeaders[http.CanonicalHeaderKey(k)] = slices.Clone(v)
+		}
+	}
+}
+
+// WithRegistryToken sends token as a bearer token with registry API
+// requests to host (e.g. "registry.terraform.io"), unless an Authorization
+// header was set with WithRequestHeaders. Like other registry headers, the
+// token is never sent to download hosts. The option may be given once per
+// host; an empty token removes an earlier one.
+func WithRegistryToken(host, token string) ServerOption {
+	return func(s *Server) {
+		host = strings.ToLower(host)
+		if token == "" {
+			delete(s.registryTokens, host)
+			return
+		}
+		if s.registryTokens == nil {
+			s.registryTokens = make(map[string]string)
+		}
+		s.registryTokens[host] = token
+	}
+}
+
+// newRegistryRequest creates a request to a registry API endpoint carrying
+// the Server's User-Agent, any headers set with WithRequestHeaders and the
+// token for the host; see WithRegistryToken and WithTerraformCredentials.
+func (s *Server) newRegistryRequest(method, u string) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	s.setRegistryHeaders(req)
+	return req, nil
+}
+
+// setRegistryHeaders adds the headers set with WithRequestHeaders and the
+// host's API token to req.
+func (s *Server) setRegistryHeaders(req *http.Request) {
+	for k, v := range s.requestHeaders {
+		req.Header[k] = slices.Clone(v)
+	}
+	if token, ok := s.registryToken(req.URL.Hostname()); ok && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// newDownloadRequest creates a request for a provider archive. Only the
+// User-Agent is set; caller-supplied registry headers are deliberately not
+// forwarded to third-party download hosts. WithRegistryCompat makes an
+// exception for archives on the registry's own host.
+func (s *Server) newDownloadRequest(method, u string) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	if s.forwardsRegistryHeaders(req.URL) {
+		s.setRegistryHeaders(req)
+	}
+	return req, nil
+}
@@ -0,0 +1,107 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultUserAgent(t *testing.T) {
+	ua := defaultUserAgent()
+	assert.True(t, strings.HasPrefix(ua, "tfpluginschema/"), ua)
+	assert.NotEqual(t, "tfpluginschema/", ua)
+}
+
+func TestServer_RequestHeaders(t *testing.T) {
+	seen := map[string]http.Header{}
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen[r.URL.Path] = r.Header.Clone()
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/5.0.0/download/" + runtime.GOOS + "/" + runtime.GOARCH:
+			fmt.Fprint(w, `{"filename":"aws.zip","download_url":"https://releases.example.com/aws.zip"}`)
+		case "/aws.zip":
+			w.Header().Set("Content-Length", "1")
+		}
+	}))
+
+	s := NewServer(nil,
+		WithCacheDir(t.TempDir()),
+		WithHTTPClient(client),
+		WithUserAgent("myapp/1.2"),
+		WithRequestHeaders(http.Header{"x-correlation-id": {"abc"}}),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+	require.NoError(t, err)
+
+	registry := seen["/v1/providers/hashicorp/aws/5.0.0/download/"+runtime.GOOS+"/"+runtime.GOARCH]
+	require.NotNil(t, registry)
+	assert.Equal(t, "myapp/1.2", registry.Get("User-Agent"))
+	assert.Equal(t, "abc", registry.Get("X-Correlation-Id"))
+
+	download := seen["/aws.zip"]
+	require.NotNil(t, download)
+	assert.Equal(t, "myapp/1.2", download.Get("User-Agent"))
+	assert.Empty(t, download.Get("X-Correlation-Id"), "registry headers must not leak to download hosts")
+}
+
+func TestServer_DefaultUserAgentSent(t *testing.T) {
+	var ua string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua = r.Header.Get("User-Agent")
+		fmt.Fprint(w, `{"versions":[{"version":"1.0.0"}]}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultUserAgent(), ua)
+}
+
+func TestWithRequestHeaders_OverridesUserAgent(t *testing.T) {
+	s := NewServer(nil,
+		WithUserAgent(""), // ignored
+		WithRequestHeaders(http.Header{"User-Agent": {"override"}}),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	req, err := s.newRegistryRequest(http.MethodGet, "https://registry.opentofu.org/v1/providers")
+	require.NoError(t, err)
+	assert.Equal(t, "override", req.Header.Get("User-Agent"))
+}
+
+func TestWithRegistryToken(t *testing.T) {
+	s := NewServer(nil, WithRegistryToken("Registry.Example.com", "secret"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	req, err := s.newRegistryRequest(http.MethodGet, "https://registry.example.com/v1/providers")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+
+	req, err = s.newRegistryRequest(http.MethodGet, "https://registry.opentofu.org/v1/providers")
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"), "tokens must only be sent to their host")
+
+	req, err = s.newDownloadRequest(http.MethodGet, "https://registry.example.com/aws.zip")
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"), "tokens must not be sent with downloads")
+}
+
+func TestWithRegistryToken_RequestHeaderWins(t *testing.T) {
+	s := NewServer(nil,
+		WithRegistryToken("registry.example.com", "secret"),
+		WithRequestHeaders(http.Header{"Authorization": {"Basic abc"}}),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	req, err := s.newRegistryRequest(http.MethodGet, "https://registry.example.com/v1/providers")
+	require.NoError(t, err)
+	assert.Equal(t, "Basic abc", req.Header.Get("Authorization"))
+}
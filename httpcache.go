@@ -0,0 +1,163 @@
+package tfpluginschema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// registryResponseCacheDir is the directory, relative to the cache root, in
+// which registry API responses are stored together with their validators.
+// It cannot collide with the provider layout, whose top-level entries are
+// registry type names.
+const registryResponseCacheDir = "registry-responses"
+
+// maxRegistryResponseSize bounds the registry API responses read into
+// memory. The largest version listings are a few megabytes.
+const maxRegistryResponseSize = 32 << 20
+
+// registryResponse is a registry API response body persisted alongside the
+// validators needed to revalidate it with a conditional request.
+type registryResponse struct {
+	URL          string          `json:"url"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Body         json.RawMessage `json:"body"`
+}
+
+// registryResponsePath returns the on-disk location of the stored response
+// for u.
+func (s *Server) registryResponsePath(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(s.cacheDir, registryResponseCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadRegistryResponse returns the stored response for u, if any.
+func (s *Server) loadRegistryResponse(u string) (registryResponse, bool) {
+	var stored registryResponse
+	data, err := os.ReadFile(s.registryResponsePath(u))
+	if err != nil {
+		return stored, false
+	}
+	if err := json.Unmarshal(data, &stored); err != nil || stored.URL != u {
+		return stored, false
+	}
+	return stored, true
+}
+
+// forgetRegistryResponse removes the stored response for u, if any, so the
+// next registryGet fetches it afresh.
+func (s *Server) forgetRegistryResponse(u string) {
+	if err := os.Remove(s.registryResponsePath(u)); err != nil && !os.IsNotExist(err) {
+		s.logger(logComponentCache).Debug("Failed to remove stored registry response", "url", u, "error", err)
+	}
+}
+
+// storeRegistryResponse persists a response and its validators. The file is
+// written to a temporary name and renamed into place so concurrent readers
+// never observe a partial entry.
+func (s *Server) storeRegistryResponse(stored registryResponse) error {
+	path := s.registryResponsePath(stored.URL)
+	if err := ensureWithinBaseDir(s.cacheDir, path); err != nil {
+		return err
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode registry response: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".response-*")
+	if err != nil {
+		return fmt.Errorf("failed to create registry response file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write registry response file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close registry response file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to publish registry response file: %w", err)
+	}
+	return nil
+}
+
+// registryGet fetches a registry API URL, revalidating any stored copy with
+// If-None-Match / If-Modified-Since. A 304 response is served from the
+// stored body and reported as http.StatusOK. For any status other than 200
+// the body is nil and the status is returned for the caller to interpret.
+//
+// Responses carrying an ETag or Last-Modified header are stored under the
+// cache directory. WithForceFetch disables revalidation, although fresh
+// responses are still stored. Failures to read or write stored responses
+// are logged and otherwise ignored.
+func (s *Server) registryGet(u string) ([]byte, int, error) {
+	return s.registryFetch(u, true)
+}
+
+// registryFetch is registryGet, storing a fresh response only if store is
+// set. A stored copy is still revalidated and served.
+func (s *Server) registryFetch(u string, store bool) ([]byte, int, error) {
+	l := s.logger(logComponentRegistry).With("url", u)
+
+	req, err := s.newRegistryRequest(http.MethodGet, u)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create HTTP request for registry API: %w", err)
+	}
+
+	stored, haveStored := registryResponse{}, false
+	if !s.forceFetch || s.offline {
+		stored, haveStored = s.loadRegistryResponse(u)
+	}
+	if s.offline {
+		if !haveStored {
+			return nil, 0, fmt.Errorf("%w: %s", ErrOffline, u)
+		}
+		l.Debug("Offline; using stored registry response")
+		return stored.Body, http.StatusOK, nil
+	}
+	if haveStored {
+		if stored.ETag != "" {
+			req.Header.Set("If-None-Match", stored.ETag)
+		}
+		if stored.LastModified != "" {
+			req.Header.Set("If-Modified-Since", stored.LastModified)
+		}
+	}
+
+	resp, err := s.doRegistryRequest(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send HTTP request to registry API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && haveStored {
+		l.Debug("Registry response not modified; using stored copy")
+		return stored.Body, http.StatusOK, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRegistryResponseSize+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read registry API response: %w", err)
+	}
+	if len(body) > maxRegistryResponseSize {
+		return nil, 0, fmt.Errorf("registry API response from %s is larger than %d bytes", u, maxRegistryResponseSize)
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if store && (etag != "" || lastModified != "") && json.Valid(body) {
+		if err := s.storeRegistryResponse(registryResponse{URL: u, ETag: etag, LastModified: lastModified, Body: body}); err != nil {
+			l.Debug("Failed to store registry response", "error", err)
+		}
+	}
+	return body, http.StatusOK, nil
+}
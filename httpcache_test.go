@@ -0,0 +1,136 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_RegistryGet_RevalidatesWithETag(t *testing.T) {
+	var conditional []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"versions":[{"version":"1.0.0"},{"version":"1.1.0"}]}`)
+	}))
+	cacheDir := t.TempDir()
+	req := VersionsRequest{Namespace: "hashicorp", Name: "aws"}
+
+	s1 := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s1.Cleanup() })
+	v1, err := s1.GetAvailableVersions(req)
+	require.NoError(t, err)
+
+	// A fresh Server has an empty in-memory cache, so it revalidates the
+	// stored response and is served from it on 304.
+	s2 := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s2.Cleanup() })
+	v2, err := s2.GetAvailableVersions(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", `"v1"`}, conditional)
+	assert.Equal(t, v1.Len(), v2.Len())
+	assert.Equal(t, "1.1.0", v2[1].String())
+}
+
+func TestServer_RegistryGet_RevalidatesWithLastModified(t *testing.T) {
+	const lastModified = "Wed, 21 Oct 2015 07:28:00 GMT"
+	calls := 0
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	const u = "https://registry.opentofu.org/v1/providers/hashicorp/aws/versions"
+	for range 2 {
+		body, status, err := s.registryGet(u)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `{"ok":true}`, string(body))
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestServer_RegistryGet_NoValidatorsNotStored(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		fmt.Fprint(w, `{}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	const u = "https://registry.opentofu.org/v1/providers/hashicorp/aws/versions"
+	_, _, err := s.registryGet(u)
+	require.NoError(t, err)
+	_, err = os.Stat(s.registryResponsePath(u))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestServer_RegistryGet_ForceFetchSkipsRevalidation(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v2"`)
+		fmt.Fprint(w, `{"fresh":true}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithForceFetch(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	const u = "https://registry.opentofu.org/v1/providers/hashicorp/aws/versions"
+	require.NoError(t, s.storeRegistryResponse(registryResponse{URL: u, ETag: `"v1"`, Body: []byte(`{"fresh":false}`)}))
+
+	body, _, err := s.registryGet(u)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fresh":true}`, string(body))
+
+	stored, ok := s.loadRegistryResponse(u)
+	require.True(t, ok)
+	assert.Equal(t, `"v2"`, stored.ETag)
+}
+
+func TestServer_RegistryGet_ErrorStatus(t *testing.T) {
+	client := stubRegistryClient(t, http.NotFoundHandler())
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	body, status, err := s.registryGet("https://registry.opentofu.org/v1/providers/x/y/versions")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Nil(t, body)
+}
+
+func TestServer_RegistryGet_TooLarge(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.Copy(w, io.LimitReader(neverEnding('x'), maxRegistryResponseSize+1))
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, _, err := s.registryGet("https://registry.opentofu.org/v1/providers/x/y/versions")
+	assert.ErrorContains(t, err, "larger than")
+}
+
+// neverEnding is an endless stream of one byte.
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,103 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumFileName is the file in each cache entry recording the SHA-256
+// of the provider binary at extraction time. Its name cannot be mistaken
+// for a provider binary by findProviderBinary.
+const checksumFileName = ".tfpluginschema-sha256"
+
+// ErrCacheCorrupted is returned when a cached provider binary no longer
+// matches the checksum recorded when it was extracted.
+var ErrCacheCorrupted = errors.New("cached provider binary is corrupted")
+
+// WithIntegrityCheck makes the Server re-hash a cached provider binary
+// before each execution and compare it with the checksum recorded when it
+// was extracted. A binary that was modified, truncated or removed is
+// treated as ErrCacheCorrupted: its cache entry is removed and the provider
+// downloaded again. The error is only returned if the new download fails
+// or is corrupted too. Entries extracted by versions of this library that
+// did not record a checksum are not checked.
+func WithIntegrityCheck(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.integrityCheck = enabled
+	}
+}
+
+// recordBinaryChecksum writes the SHA-256 of dir/binaryPath to the
+// checksum file in dir, in the format of sha256sum.
+func recordBinaryChecksum(dir, binaryPath string) error {
+	digest, err := hashFile(filepath.Join(dir, binaryPath))
+	if err != nil {
+		return fmt.Errorf("failed to hash provider binary: %w", err)
+	}
+	line := digest + "  " + filepath.ToSlash(binaryPath) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, checksumFileName), []byte(line), 0o644); err != nil {
+		return fmt.Errorf("failed to record provider binary checksum: %w", err)
+	}
+	return nil
+}
+
+// checkBinaryChecksum compares the provider binary in extractDir with its
+// recorded checksum. It returns nil when no checksum was recorded.
+func checkBinaryChecksum(extractDir string) error {
+	data, err := os.ReadFile(filepath.Join(extractDir, checksumFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read provider binary checksum: %w", err)
+	}
+	want, binaryPath, ok := strings.Cut(strings.TrimSpace(string(data)), "  ")
+	if !ok || !filepath.IsLocal(filepath.FromSlash(binaryPath)) {
+		return fmt.Errorf("%w: malformed checksum file in %s", ErrCacheCorrupted, extractDir)
+	}
+	got, err := hashFile(filepath.Join(extractDir, filepath.FromSlash(binaryPath)))
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrCacheCorrupted, binaryPath, err)
+	}
+	if got != want {
+		return fmt.Errorf("%w: %s has sha256 %s, want %s", ErrCacheCorrupted, filepath.Join(extractDir, binaryPath), got, want)
+	}
+	return nil
+}
+
+// verifyCachedProvider checks the cached binary of request and returns the
+// path to execute. A corrupted entry is removed and downloaded once more.
+func (s *Server) verifyCachedProvider(request Request, key providerKey, providerPath string) (string, error) {
+	extractDir := cacheProviderDir(s.cacheDir, request)
+	err := checkBinaryChecksum(extractDir)
+	if err == nil || !errors.Is(err, ErrCacheCorrupted) {
+		return providerPath, err
+	}
+
+	s.logger(logComponentCache).Warn("Cached provider binary is corrupted, downloading it again",
+		append(s.requestLogAttrs(request), "error", err)...)
+	s.mu.Lock()
+	delete(s.dlc, key)
+	s.mu.Unlock()
+	if rmErr := os.RemoveAll(extractDir); rmErr != nil {
+		return "", fmt.Errorf("%w; removing the cache entry failed: %w", err, rmErr)
+	}
+	if getErr := s.get(request); getErr != nil {
+		return "", fmt.Errorf("%w; downloading it again failed: %w", err, getErr)
+	}
+	if err := checkBinaryChecksum(extractDir); err != nil {
+		return "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	providerPath, ok := s.dlc[key]
+	if !ok {
+		return "", fmt.Errorf("provider not found in cache: %s", request.String())
+	}
+	return providerPath, nil
+}
@@ -0,0 +1,88 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBinaryChecksum(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0o755))
+	binary := filepath.Join(dir, "bin", providerFileNamePrefix+"null")
+	require.NoError(t, os.WriteFile(binary, []byte("binary"), 0o755))
+
+	assert.NoError(t, checkBinaryChecksum(dir), "entries without a checksum are not checked")
+
+	require.NoError(t, recordBinaryChecksum(dir, filepath.Join("bin", providerFileNamePrefix+"null")))
+	assert.NoError(t, checkBinaryChecksum(dir))
+
+	require.NoError(t, os.WriteFile(binary, []byte("bin"), 0o755))
+	assert.ErrorIs(t, checkBinaryChecksum(dir), ErrCacheCorrupted)
+
+	require.NoError(t, os.Remove(binary))
+	assert.ErrorIs(t, checkBinaryChecksum(dir), ErrCacheCorrupted)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, checksumFileName), []byte("abc  ../outside\n"), 0o644))
+	assert.ErrorIs(t, checkBinaryChecksum(dir), ErrCacheCorrupted)
+}
+
+func TestServer_VerifyCachedProvider_Redownloads(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	archive := providerArchive(t, "null")
+	var downloads atomic.Int32
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/providers/hashicorp/null/1.0.0/download/%s/%s", runtime.GOOS, runtime.GOARCH):
+			fmt.Fprint(w, `{"filename":"terraform-provider-null_1.0.0.zip","download_url":"https://releases.example.com/archive.zip"}`)
+		case "/archive.zip":
+			if r.Method == http.MethodGet {
+				downloads.Add(1)
+			}
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	cacheDir := t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client), WithIntegrityCheck(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+	require.EqualValues(t, 1, downloads.Load())
+
+	key := cacheKey(normalizedRequest(req))
+	path := s.dlc[key]
+	require.NoError(t, os.WriteFile(path, []byte("tampered"), 0o755))
+
+	got, err := s.verifyCachedProvider(normalizedRequest(req), key, path)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, downloads.Load())
+	data, err := os.ReadFile(got)
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(data))
+}
+
+func TestServer_VerifyCachedProvider_Offline(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	cacheDir := t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubProviderRegistry(t, req, providerArchive(t, "null"))))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+	key := cacheKey(normalizedRequest(req))
+	path := s.dlc[key]
+	require.NoError(t, os.WriteFile(path, []byte("tampered"), 0o755))
+
+	offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true), WithIntegrityCheck(true))
+	t.Cleanup(func() { _ = offline.Cleanup() })
+	_, err := offline.verifyCachedProvider(normalizedRequest(req), key, path)
+	assert.ErrorIs(t, err, ErrCacheCorrupted)
+	assert.ErrorIs(t, err, ErrOffline)
+}
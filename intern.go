@@ -0,0 +1,152 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"hash/maphash"
+	"maps"
+	"slices"
+	"sync"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// WithSchemaInterning makes the Server share identical parts of the schemas
+// it holds, such as the timeouts blocks and tags attributes that most
+// resources of a cloud provider repeat, so that each distinct attribute,
+// nested block and attribute map is held in memory once per provider. This
+// cuts the memory used by very large providers at some cost to the time
+// taken to convert them. It defaults to false.
+//
+// Returned schemas are read-only either way (see WithCloneSchemas); with
+// interning, modifying one also changes every other resource sharing the
+// modified part.
+func WithSchemaInterning(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.internSchemas = enabled
+	}
+}
+
+// schemaInterner replaces parts of schemas by identical parts seen before.
+// Parts are interned from the leaves up, so that a part's key can refer to
+// its already interned children by address; the interner keeps every part
+// it has seen, so addresses are not reused. Keys are 128-bit hashes, so
+// that the interner does not hold a second copy of every description. It is
+// safe for concurrent use.
+type schemaInterner struct {
+	mu            sync.Mutex
+	seeds         [2]maphash.Seed
+	attributes    map[internKey]*tfjson.SchemaAttribute
+	attributeMaps map[internKey]map[string]*tfjson.SchemaAttribute
+	nestedTypes   map[internKey]*tfjson.SchemaNestedAttributeType
+	blocks        map[internKey]*tfjson.SchemaBlock
+	blockTypes    map[internKey]*tfjson.SchemaBlockType
+	blockTypeMaps map[internKey]map[string]*tfjson.SchemaBlockType
+}
+
+type internKey [2]uint64
+
+func newSchemaInterner() *schemaInterner {
+	return &schemaInterner{
+		seeds:         [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		attributes:    make(map[internKey]*tfjson.SchemaAttribute),
+		attributeMaps: make(map[internKey]map[string]*tfjson.SchemaAttribute),
+		nestedTypes:   make(map[internKey]*tfjson.SchemaNestedAttributeType),
+		blocks:        make(map[internKey]*tfjson.SchemaBlock),
+		blockTypes:    make(map[internKey]*tfjson.SchemaBlockType),
+		blockTypeMaps: make(map[internKey]map[string]*tfjson.SchemaBlockType),
+	}
+}
+
+// key hashes the description of a part.
+func (in *schemaInterner) key(b []byte) internKey {
+	return internKey{maphash.Bytes(in.seeds[0], b), maphash.Bytes(in.seeds[1], b)}
+}
+
+// internValue returns the value stored under the key of desc in m, storing
+// v there if there is none.
+func internValue[V any](in *schemaInterner, m map[internKey]V, desc []byte, v V) V {
+	key := in.key(desc)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if existing, ok := m[key]; ok {
+		return existing
+	}
+	m[key] = v
+	return v
+}
+
+// schema interns the parts of s in place and returns s.
+func (in *schemaInterner) schema(s *tfjson.Schema) *tfjson.Schema {
+	if s != nil {
+		s.Block = in.block(s.Block)
+	}
+	return s
+}
+
+func (in *schemaInterner) block(b *tfjson.SchemaBlock) *tfjson.SchemaBlock {
+	if b == nil {
+		return nil
+	}
+	b.Attributes = in.attributeMap(b.Attributes)
+	b.NestedBlocks = in.blockTypeMap(b.NestedBlocks)
+	desc := fmt.Appendf(nil, "%q %s %t %p %p", b.Description, b.DescriptionKind, b.Deprecated, b.Attributes, b.NestedBlocks)
+	return internValue(in, in.blocks, desc, b)
+}
+
+func (in *schemaInterner) blockTypeMap(m map[string]*tfjson.SchemaBlockType) map[string]*tfjson.SchemaBlockType {
+	if len(m) == 0 {
+		return m
+	}
+	for name, bt := range m {
+		if bt == nil {
+			continue
+		}
+		bt.Block = in.block(bt.Block)
+		desc := fmt.Appendf(nil, "%s %d %d %p", bt.NestingMode, bt.MinItems, bt.MaxItems, bt.Block)
+		m[name] = internValue(in, in.blockTypes, desc, bt)
+	}
+	return internValue(in, in.blockTypeMaps, mapDescription(m), m)
+}
+
+func (in *schemaInterner) attributeMap(m map[string]*tfjson.SchemaAttribute) map[string]*tfjson.SchemaAttribute {
+	if len(m) == 0 {
+		return m
+	}
+	for name, a := range m {
+		if a != nil {
+			m[name] = in.attribute(a)
+		}
+	}
+	return internValue(in, in.attributeMaps, mapDescription(m), m)
+}
+
+func (in *schemaInterner) attribute(a *tfjson.SchemaAttribute) *tfjson.SchemaAttribute {
+	if n := a.AttributeNestedType; n != nil {
+		n.Attributes = in.attributeMap(n.Attributes)
+		desc := fmt.Appendf(nil, "%s %d %d %p", n.NestingMode, n.MinItems, n.MaxItems, n.Attributes)
+		a.AttributeNestedType = internValue(in, in.nestedTypes, desc, n)
+	}
+	var typ []byte
+	if a.AttributeType != cty.NilType {
+		var err error
+		if typ, err = ctyjson.MarshalType(a.AttributeType); err != nil {
+			// A type that cannot be encoded cannot be compared either.
+			return a
+		}
+	}
+	desc := fmt.Appendf(nil, "%s %p %q %s %t %t %t %t %t %t", typ, a.AttributeNestedType, a.Description, a.DescriptionKind,
+		a.Deprecated, a.Required, a.Optional, a.Computed, a.Sensitive, a.WriteOnly)
+	return internValue(in, in.attributes, desc, a)
+}
+
+// mapDescription describes the entries of m, whose values must already be
+// interned.
+func mapDescription[V any](m map[string]*V) []byte {
+	var desc []byte
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		desc = fmt.Appendf(desc, "%q=%p ", name, m[name])
+	}
+	return desc
+}
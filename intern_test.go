@@ -0,0 +1,112 @@
+package tfpluginschema
+
+import (
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazySchema_Intern(t *testing.T) {
+	resp := largeV6Response(4)
+	plain, err := newLazySchemaV6(resp, nil)
+	require.NoError(t, err)
+	interned, err := newLazySchemaV6(resp, nil)
+	require.NoError(t, err)
+
+	// One entry is converted before interning, the rest after.
+	first, ok, err := interned.resource("example_resource_0")
+	require.NoError(t, err)
+	require.True(t, ok)
+	interned.intern()
+	second, ok, err := interned.resource("example_resource_1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	want, err := plain.providerSchema()
+	require.NoError(t, err)
+	got, err := interned.providerSchema()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Same(t, first.Block.Attributes["attr_0"], second.Block.Attributes["attr_0"])
+	assert.Same(t, first.Block.NestedBlocks["block_0"], second.Block.NestedBlocks["block_1"])
+	assert.Same(t, first.Block, second.Block)
+	data, ok, err := interned.dataSource("example_data_0")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Same(t, first.Block, data.Block)
+}
+
+func TestSchemaInterner_KeepsDifferences(t *testing.T) {
+	attr := func(desc string, typ string) *tfjson.SchemaAttribute {
+		a := &tfjson.SchemaAttribute{Description: desc, Optional: true}
+		require.NoError(t, a.AttributeType.UnmarshalJSON([]byte(typ)))
+		return a
+	}
+	in := newSchemaInterner()
+	a := in.schema(&tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{
+		"name": attr("The name.", `"string"`),
+		"tags": attr("Tags.", `["map","string"]`),
+	}}})
+	b := in.schema(&tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{
+		"name": attr("The name of the bucket.", `"string"`),
+		"tags": attr("Tags.", `["map","string"]`),
+		"size": attr("Tags.", `["map","number"]`),
+	}}})
+
+	assert.Same(t, a.Block.Attributes["tags"], b.Block.Attributes["tags"])
+	assert.NotSame(t, a.Block.Attributes["name"], b.Block.Attributes["name"])
+	assert.NotSame(t, b.Block.Attributes["tags"], b.Block.Attributes["size"])
+	assert.Equal(t, "The name of the bucket.", b.Block.Attributes["name"].Description)
+	assert.NotSame(t, a.Block, b.Block)
+}
+
+func TestServer_WithSchemaInterning(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	plain := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)),
+		WithSchemaSources(BundleSource(bundle)))
+	t.Cleanup(func() { _ = plain.Cleanup() })
+	interned := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)),
+		WithSchemaSources(BundleSource(bundle)), WithSchemaInterning(true))
+	t.Cleanup(func() { _ = interned.Cleanup() })
+
+	request := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	want, err := plain.GetProviderSchema(request)
+	require.NoError(t, err)
+	got, err := interned.GetProviderSchema(request)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+// BenchmarkSchemaInterning reports the heap retained by a fully converted
+// large provider schema, with and without interning.
+func BenchmarkSchemaInterning(b *testing.B) {
+	resp := largeV6Response(1000)
+	for _, bc := range []struct {
+		name   string
+		intern bool
+	}{{"plain", false}, {"interned", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			var retained int64
+			for b.Loop() {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				ls, err := newLazySchemaV6(resp, nil)
+				require.NoError(b, err)
+				if bc.intern {
+					ls.intern()
+				}
+				_, _ = ls.providerSchema()
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained = int64(after.HeapAlloc) - int64(before.HeapAlloc)
+				runtime.KeepAlive(ls)
+			}
+			b.ReportMetric(float64(retained), "retained-B")
+		})
+	}
+}
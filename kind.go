@@ -0,0 +1,158 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Kind is a kind of schema entry in a provider schema. The values are
+// stable and are also the names the CLI uses.
+type Kind string
+
+const (
+	// KindResource is a managed resource type.
+	KindResource Kind = "resource"
+	// KindDataSource is a data source.
+	KindDataSource Kind = "datasource"
+	// KindEphemeralResource is an ephemeral resource type.
+	KindEphemeralResource Kind = "ephemeral"
+	// KindFunction is a provider-defined function.
+	KindFunction Kind = "function"
+	// KindProviderConfig is the provider configuration block. It has no
+	// name.
+	KindProviderConfig Kind = "provider"
+	// KindIdentity is the identity schema of a managed resource type,
+	// added in protocol 6.9. It is not read from providers yet, and
+	// GetSchemaByKind returns ErrNotImplemented for it.
+	KindIdentity Kind = "identity"
+	// KindAction is an action type. The plugin protocol versions this
+	// package speaks have no actions, and GetSchemaByKind returns
+	// ErrNotImplemented for it.
+	KindAction Kind = "action"
+)
+
+// Kinds returns every Kind, in the order above.
+func Kinds() []Kind {
+	return []Kind{KindResource, KindDataSource, KindEphemeralResource, KindFunction, KindProviderConfig, KindIdentity, KindAction}
+}
+
+// ParseKind returns the Kind named s. Besides the Kind values it accepts
+// the spellings Terraform uses: "data", "data_source", "ephemeral_resource"
+// and "provider_config". Case is ignored.
+func ParseKind(s string) (Kind, error) {
+	switch k := Kind(strings.ToLower(strings.TrimSpace(s))); k {
+	case "data", "data_source":
+		return KindDataSource, nil
+	case "ephemeral_resource":
+		return KindEphemeralResource, nil
+	case "provider_config":
+		return KindProviderConfig, nil
+	case KindResource, KindDataSource, KindEphemeralResource, KindFunction, KindProviderConfig, KindIdentity, KindAction:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown schema kind %q", s)
+}
+
+// label returns the kind as used in error messages, e.g. "data source".
+func (k Kind) label() string {
+	switch k {
+	case KindDataSource:
+		return "data source"
+	case KindEphemeralResource:
+		return "ephemeral resource"
+	case KindProviderConfig:
+		return "provider configuration"
+	}
+	return string(k)
+}
+
+// SchemaEntry is a schema returned by GetSchemaByKind. Function is set for
+// KindFunction and Schema for every other kind.
+type SchemaEntry struct {
+	Kind     Kind                      `json:"kind"`
+	Name     string                    `json:"name,omitempty"`
+	Schema   *tfjson.Schema            `json:"schema,omitempty"`
+	Function *tfjson.FunctionSignature `json:"function,omitempty"`
+}
+
+// GetSchemaByKind retrieves the schema of the entry of the given kind and
+// name, and is what the kind-specific Get*Schema methods call. The name is
+// ignored for KindProviderConfig. An entry the provider does not have
+// fails with ErrSchemaNotFound. As with GetResourceSchema, the schema is
+// shared with the Server's cache unless WithCloneSchemas or an option that
+// changes it is set.
+func (s *Server) GetSchemaByKind(request Request, kind Kind, name string, opts ...SchemaOption) (*SchemaEntry, error) {
+	entry := &SchemaEntry{Kind: kind, Name: name}
+	if kind == KindProviderConfig {
+		entry.Name = ""
+	}
+	var err error
+	if kind == KindFunction {
+		entry.Function, err = s.getFunction(request, name, opts)
+	} else {
+		entry.Schema, err = s.getKindSchema(request, kind, name, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// getKindSchema returns the schema of a block-shaped kind.
+func (s *Server) getKindSchema(request Request, kind Kind, name string, opts []SchemaOption) (*tfjson.Schema, error) {
+	var lookup func(*lazySchema, string) (*tfjson.Schema, bool, error)
+	switch kind {
+	case KindResource:
+		lookup = (*lazySchema).resource
+	case KindDataSource:
+		lookup = (*lazySchema).dataSource
+	case KindEphemeralResource:
+		lookup = (*lazySchema).ephemeralResource
+	case KindProviderConfig:
+		lookup = func(ls *lazySchema, _ string) (*tfjson.Schema, bool, error) {
+			c, err := ls.configSchema()
+			return c, true, err
+		}
+	case KindIdentity, KindAction:
+		return nil, fmt.Errorf("%s schemas: %w", kind, ErrNotImplemented)
+	default:
+		return nil, fmt.Errorf("unknown schema kind %q", kind)
+	}
+
+	s.logger(logComponentSchema).Debug("Getting "+kind.label()+" schema", append(s.requestLogAttrs(request), "name", name)...)
+
+	schemaResp, err := s.readSchema(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+
+	schema, ok, err := lookup(schemaResp, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s %w: %s", kind.label(), ErrSchemaNotFound, name)
+	}
+	return s.returnSchema(schema, opts), nil
+}
+
+// getFunction returns the signature of a provider-defined function.
+func (s *Server) getFunction(request Request, name string, opts []SchemaOption) (*tfjson.FunctionSignature, error) {
+	s.logger(logComponentSchema).Debug("Getting function schema", append(s.requestLogAttrs(request), "function", name)...)
+
+	schemaResp, err := s.readSchema(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+
+	schemaFunction, ok, err := schemaResp.function(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s %w: %s", KindFunction.label(), ErrSchemaNotFound, name)
+	}
+	return s.returnFunction(schemaFunction, opts), nil
+}
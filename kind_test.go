@@ -0,0 +1,64 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKind(t *testing.T) {
+	for _, k := range Kinds() {
+		got, err := ParseKind(string(k))
+		require.NoError(t, err)
+		assert.Equal(t, k, got)
+	}
+	for s, want := range map[string]Kind{
+		"data":               KindDataSource,
+		"data_source":        KindDataSource,
+		"Ephemeral_Resource": KindEphemeralResource,
+		"provider_config":    KindProviderConfig,
+	} {
+		got, err := ParseKind(s)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, s)
+	}
+	_, err := ParseKind("module")
+	assert.Error(t, err)
+}
+
+func TestServer_GetSchemaByKind(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	entry, err := s.GetSchemaByKind(req, KindResource, "example_thing")
+	require.NoError(t, err)
+	assert.Equal(t, KindResource, entry.Kind)
+	assert.Equal(t, "example_thing", entry.Name)
+	assert.Equal(t, "A **thing**.", entry.Schema.Block.Description)
+	assert.Nil(t, entry.Function)
+
+	entry, err = s.GetSchemaByKind(req, KindProviderConfig, "ignored", WithoutDescriptions())
+	require.NoError(t, err)
+	assert.Empty(t, entry.Name)
+	assert.Empty(t, entry.Schema.Block.Description)
+
+	entry, err = s.GetSchemaByKind(req, KindFunction, "parse")
+	require.NoError(t, err)
+	assert.Equal(t, "Parse.", entry.Function.Summary)
+	assert.Nil(t, entry.Schema)
+
+	_, err = s.GetSchemaByKind(req, KindDataSource, "example_thing")
+	require.ErrorIs(t, err, ErrSchemaNotFound)
+	assert.ErrorContains(t, err, "data source schema not found: example_thing")
+
+	_, err = s.GetSchemaByKind(req, KindIdentity, "example_thing")
+	require.ErrorIs(t, err, ErrNotImplemented)
+	_, err = s.GetSchemaByKind(req, KindAction, "example_thing")
+	require.ErrorIs(t, err, ErrNotImplemented)
+	_, err = s.GetSchemaByKind(req, Kind("module"), "x")
+	assert.ErrorContains(t, err, `unknown schema kind "module"`)
+}
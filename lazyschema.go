@@ -0,0 +1,386 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+)
+
+// ErrSchemaCorrupted is returned when an entry of a persisted or bundled
+// provider schema cannot be decoded.
+var ErrSchemaCorrupted = errors.New("persisted provider schema is corrupted")
+
+// lazyMap holds the raw proto values of one schema map (resources, data
+// sources, ...) and converts each to terraform-json on first access.
+type lazyMap[V, R any] struct {
+	raw     map[string]V
+	convert func(V) R
+	// decode, when set, is used instead of convert for values that may
+	// fail to decode. Entries that fail are not kept.
+	decode    func(V) (R, error)
+	converted map[string]R
+}
+
+// schemaMap is the protocol-independent view of a lazyMap.
+type schemaMap[R any] interface {
+	get(name string) (R, bool, error)
+	peek(name string) (R, bool, error)
+	all() (map[string]R, error)
+	names() []string
+	transform(f func(R) R)
+}
+
+func newLazyMap[V, R any](raw map[string]V, convert func(V) R) *lazyMap[V, R] {
+	return &lazyMap[V, R]{raw: raw, convert: convert, converted: make(map[string]R)}
+}
+
+// newDecodingMap is newLazyMap for values whose conversion can fail.
+func newDecodingMap[V, R any](raw map[string]V, decode func(V) (R, error)) *lazyMap[V, R] {
+	return &lazyMap[V, R]{raw: raw, decode: decode, converted: make(map[string]R)}
+}
+
+// convertedMap wraps an already converted map.
+func convertedMap[R any](m map[string]R) *lazyMap[R, R] {
+	if m == nil {
+		m = make(map[string]R)
+	}
+	return &lazyMap[R, R]{converted: m}
+}
+
+func (m *lazyMap[V, R]) get(name string) (R, bool, error) {
+	if r, ok := m.converted[name]; ok {
+		return r, true, nil
+	}
+	r, ok, err := m.peek(name)
+	if ok && err == nil {
+		m.converted[name] = r
+	}
+	return r, ok, err
+}
+
+// peek is get without keeping the conversion: an entry not converted yet
+// is converted for the caller alone, so walking every entry does not grow
+// the map.
+func (m *lazyMap[V, R]) peek(name string) (R, bool, error) {
+	if r, ok := m.converted[name]; ok {
+		return r, true, nil
+	}
+	v, ok := m.raw[name]
+	if !ok {
+		var zero R
+		return zero, false, nil
+	}
+	if m.decode == nil {
+		return m.convert(v), true, nil
+	}
+	r, err := m.decode(v)
+	if err != nil {
+		return r, true, fmt.Errorf("%q: %w", name, err)
+	}
+	return r, true, nil
+}
+
+// all converts every remaining entry and releases the raw values. For a
+// map of decoded values, it returns the error of the first entry, in name
+// order, that fails to decode and keeps the raw values.
+func (m *lazyMap[V, R]) all() (map[string]R, error) {
+	if len(m.raw) == 0 {
+		return m.converted, nil
+	}
+	if m.decode != nil {
+		for _, name := range m.names() {
+			if _, _, err := m.get(name); err != nil {
+				return nil, err
+			}
+		}
+		m.raw = nil
+		return m.converted, nil
+	}
+	pending := maps.Clone(m.raw)
+	maps.DeleteFunc(pending, func(name string, _ V) bool {
+		_, done := m.converted[name]
+		return done
+	})
+	maps.Copy(m.converted, convertMap(pending, m.convert))
+	m.raw = nil
+	return m.converted, nil
+}
+
+// transform applies f to every entry converted so far and to every entry
+// converted from now on.
+func (m *lazyMap[V, R]) transform(f func(R) R) {
+	for name, r := range m.converted {
+		m.converted[name] = f(r)
+	}
+	if convert := m.convert; convert != nil {
+		m.convert = func(v V) R { return f(convert(v)) }
+	}
+	if decode := m.decode; decode != nil {
+		m.decode = func(v V) (R, error) {
+			r, err := decode(v)
+			if err != nil {
+				return r, err
+			}
+			return f(r), nil
+		}
+	}
+}
+
+func (m *lazyMap[V, R]) names() []string {
+	names := make([]string, 0, max(len(m.raw), len(m.converted)))
+	if len(m.raw) == 0 {
+		names = slices.AppendSeq(names, maps.Keys(m.converted))
+	} else {
+		names = slices.AppendSeq(names, maps.Keys(m.raw))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// lazySchema is a provider schema as returned by the plugin. Individual
+// entries are converted to terraform-json when first requested, so looking
+// up one resource of a provider with thousands does not pay for converting
+// them all. It is safe for concurrent use.
+type lazySchema struct {
+	mu                 sync.Mutex
+	protocol           int
+	config             func() (*tfjson.Schema, error)
+	resources          schemaMap[*tfjson.Schema]
+	dataSources        schemaMap[*tfjson.Schema]
+	ephemeralResources schemaMap[*tfjson.Schema]
+	functions          schemaMap[*tfjson.FunctionSignature]
+	// functionDetails holds the FunctionDetails of each function, without
+	// their Signature. Functions are few, so they are extracted up-front.
+	functionDetails map[string]FunctionDetails
+	// features records the optional protocol features of the provider,
+	// when it was run to retrieve the schema.
+	features providerFeatures
+	full     *tfjson.ProviderSchema
+	l        *slog.Logger
+	// conversions, when set, records the time taken by providerSchema.
+	conversions *timings
+}
+
+func newLazySchemaV6(resp *tfplugin6.GetProviderSchema_Response, l *slog.Logger) (*lazySchema, error) {
+	if resp == nil {
+		return nil, fmt.Errorf("nil v6 response")
+	}
+	return &lazySchema{
+		protocol:           6,
+		config:             sync.OnceValues(func() (*tfjson.Schema, error) { return convertV6SchemaToTFJSON(resp.Provider), nil }),
+		resources:          newLazyMap(resp.ResourceSchemas, convertV6SchemaToTFJSON),
+		dataSources:        newLazyMap(resp.DataSourceSchemas, convertV6SchemaToTFJSON),
+		ephemeralResources: newLazyMap(resp.EphemeralResourceSchemas, convertV6SchemaToTFJSON),
+		functions:          newLazyMap(resp.Functions, convertV6FunctionToTFJSON),
+		functionDetails:    v6FunctionDetails(resp.Functions),
+		l:                  l,
+	}, nil
+}
+
+func newLazySchemaV5(resp *tfplugin5.GetProviderSchema_Response, l *slog.Logger) (*lazySchema, error) {
+	if resp == nil {
+		return nil, fmt.Errorf("nil v5 response")
+	}
+	return &lazySchema{
+		protocol:           5,
+		config:             sync.OnceValues(func() (*tfjson.Schema, error) { return convertV5SchemaToTFJSON(resp.Provider), nil }),
+		resources:          newLazyMap(resp.ResourceSchemas, convertV5SchemaToTFJSON),
+		dataSources:        newLazyMap(resp.DataSourceSchemas, convertV5SchemaToTFJSON),
+		ephemeralResources: newLazyMap(resp.EphemeralResourceSchemas, convertV5SchemaToTFJSON),
+		functions:          newLazyMap(resp.Functions, convertV5FunctionToTFJSON),
+		functionDetails:    v5FunctionDetails(resp.Functions),
+		l:                  l,
+	}, nil
+}
+
+// newConvertedSchema wraps an already converted provider schema.
+func newConvertedSchema(ps *tfjson.ProviderSchema) *lazySchema {
+	return &lazySchema{
+		config:             func() (*tfjson.Schema, error) { return ps.ConfigSchema, nil },
+		resources:          convertedMap(ps.ResourceSchemas),
+		dataSources:        convertedMap(ps.DataSourceSchemas),
+		ephemeralResources: convertedMap(ps.EphemeralResourceSchemas),
+		functions:          convertedMap(ps.Functions),
+	}
+}
+
+// providerSchemaJSON is the JSON form of a provider schema, as persisted in
+// schema bundles, with its entries left undecoded.
+type providerSchemaJSON struct {
+	ConfigSchema             json.RawMessage            `json:"provider"`
+	ResourceSchemas          map[string]json.RawMessage `json:"resource_schemas"`
+	DataSourceSchemas        map[string]json.RawMessage `json:"data_source_schemas"`
+	EphemeralResourceSchemas map[string]json.RawMessage `json:"ephemeral_resource_schemas"`
+	Functions                map[string]json.RawMessage `json:"functions"`
+}
+
+// newLazySchemaJSON wraps a provider schema document whose entries are
+// decoded when first requested, so that listing the resources of a large
+// persisted schema or looking up one of them does not decode the others.
+// Looking up an entry that fails to decode returns an error wrapping
+// ErrSchemaCorrupted.
+func newLazySchemaJSON(doc *providerSchemaJSON, l *slog.Logger) *lazySchema {
+	return &lazySchema{
+		config: sync.OnceValues(func() (*tfjson.Schema, error) {
+			if len(doc.ConfigSchema) == 0 {
+				return nil, nil
+			}
+			return decodeSchemaJSON[tfjson.Schema](KindProviderConfig)(doc.ConfigSchema)
+		}),
+		resources:          newDecodingMap(doc.ResourceSchemas, decodeSchemaJSON[tfjson.Schema](KindResource)),
+		dataSources:        newDecodingMap(doc.DataSourceSchemas, decodeSchemaJSON[tfjson.Schema](KindDataSource)),
+		ephemeralResources: newDecodingMap(doc.EphemeralResourceSchemas, decodeSchemaJSON[tfjson.Schema](KindEphemeralResource)),
+		functions:          newDecodingMap(doc.Functions, decodeSchemaJSON[tfjson.FunctionSignature](KindFunction)),
+		l:                  l,
+	}
+}
+
+// decodeSchemaJSON returns a function that decodes one entry of a
+// providerSchemaJSON of the given kind.
+func decodeSchemaJSON[R any](kind Kind) func(json.RawMessage) (*R, error) {
+	return func(raw json.RawMessage) (*R, error) {
+		r := new(R)
+		if err := json.Unmarshal(raw, r); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrSchemaCorrupted, kind.label(), err)
+		}
+		return r, nil
+	}
+}
+
+// intern makes the schema share identical parts between its entries, as
+// they are converted; see WithSchemaInterning.
+func (ls *lazySchema) intern() {
+	in := newSchemaInterner()
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	config := ls.config
+	ls.config = sync.OnceValues(func() (*tfjson.Schema, error) {
+		c, err := config()
+		if err != nil {
+			return nil, err
+		}
+		return in.schema(c), nil
+	})
+	ls.resources.transform(in.schema)
+	ls.dataSources.transform(in.schema)
+	ls.ephemeralResources.transform(in.schema)
+	if ls.full != nil {
+		ls.full.ConfigSchema, _ = ls.config()
+	}
+}
+
+// timeConversions makes full conversions of the schema record their
+// duration in t.
+func (ls *lazySchema) timeConversions(t *timings) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.conversions = t
+}
+
+// converted reports whether providerSchema has converted every entry.
+func (ls *lazySchema) converted() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.full != nil
+}
+
+func (ls *lazySchema) configSchema() (*tfjson.Schema, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	c, err := ls.config()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c, nil
+	}
+	return &tfjson.Schema{}, nil
+}
+
+func (ls *lazySchema) resource(name string) (*tfjson.Schema, bool, error) {
+	return lookupSchema(ls, ls.resources, name)
+}
+
+func (ls *lazySchema) dataSource(name string) (*tfjson.Schema, bool, error) {
+	return lookupSchema(ls, ls.dataSources, name)
+}
+
+func (ls *lazySchema) ephemeralResource(name string) (*tfjson.Schema, bool, error) {
+	return lookupSchema(ls, ls.ephemeralResources, name)
+}
+
+func (ls *lazySchema) function(name string) (*tfjson.FunctionSignature, bool, error) {
+	return lookupSchema(ls, ls.functions, name)
+}
+
+// lookupSchema looks up name in m. An entry that fails to decode is
+// reported as found, with an error wrapping ErrSchemaCorrupted.
+func lookupSchema[R any](ls *lazySchema, m schemaMap[R], name string) (R, bool, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return m.get(name)
+}
+
+// peekSchema looks up name in m without caching its conversion.
+func peekSchema[R any](ls *lazySchema, m schemaMap[R], name string) (R, bool, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return m.peek(name)
+}
+
+// schemaNames returns the sorted names in m without converting any schemas.
+func schemaNames[R any](ls *lazySchema, m schemaMap[R]) []string {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return m.names()
+}
+
+// providerSchema converts every remaining entry and returns the complete
+// schema. Maps are never nil and ConfigSchema is never nil. If an entry
+// fails to decode, providerSchema returns an error wrapping
+// ErrSchemaCorrupted.
+func (ls *lazySchema) providerSchema() (*tfjson.ProviderSchema, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.full != nil {
+		return ls.full, nil
+	}
+	start := time.Now()
+	config, err := ls.config()
+	if err != nil {
+		return nil, err
+	}
+	ps := &tfjson.ProviderSchema{ConfigSchema: config}
+	for _, all := range []func() error{
+		func() (err error) { ps.ResourceSchemas, err = ls.resources.all(); return err },
+		func() (err error) { ps.DataSourceSchemas, err = ls.dataSources.all(); return err },
+		func() (err error) { ps.EphemeralResourceSchemas, err = ls.ephemeralResources.all(); return err },
+		func() (err error) { ps.Functions, err = ls.functions.all(); return err },
+	} {
+		if err := all(); err != nil {
+			return nil, err
+		}
+	}
+	if ps.ConfigSchema == nil {
+		ps.ConfigSchema = &tfjson.Schema{}
+	}
+	if ls.protocol != 0 {
+		if ls.l != nil {
+			ls.l.Debug("Converted provider schema", "protocol", ls.protocol, durationLogAttr(time.Since(start)))
+		}
+		if ls.conversions != nil {
+			ls.conversions.record(time.Since(start))
+		}
+	}
+	ls.full = ps
+	return ps, nil
+}
@@ -0,0 +1,169 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyMap_ConvertsOnFirstAccess(t *testing.T) {
+	calls := map[string]int{}
+	m := newLazyMap(map[string]string{"a": "A", "b": "B", "c": "C"}, func(v string) string {
+		calls[v]++
+		return v + "!"
+	})
+
+	got, ok, err := m.get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "A!", got)
+	_, _, _ = m.get("a")
+	_, ok, _ = m.get("missing")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{"A": 1}, calls, "only the requested entry is converted, once")
+
+	assert.Equal(t, []string{"a", "b", "c"}, m.names())
+	assert.Equal(t, map[string]int{"A": 1}, calls, "listing names converts nothing")
+
+	all, err := m.all()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "A!", "b": "B!", "c": "C!"}, all)
+	assert.Equal(t, map[string]int{"A": 1, "B": 1, "C": 1}, calls)
+	assert.Nil(t, m.raw, "raw values are released once fully converted")
+	assert.Equal(t, []string{"a", "b", "c"}, m.names())
+}
+
+func TestLazySchemaV6(t *testing.T) {
+	resp := largeV6Response(4)
+	resp.Provider = &tfplugin6.Schema{Block: &tfplugin6.Schema_Block{}}
+	ls, err := newLazySchemaV6(resp, nil)
+	require.NoError(t, err)
+
+	r, ok, err := ls.resource("example_resource_1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Contains(t, r.Block.Attributes, "attr_0")
+	_, ok, _ = ls.dataSource("example_resource_1")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"example_data_0", "example_data_2"}, schemaNames(ls, ls.dataSources))
+	assert.Empty(t, schemaNames(ls, ls.functions))
+	assert.NotNil(t, schemaNames(ls, ls.functions))
+
+	ps, err := ls.providerSchema()
+	require.NoError(t, err)
+	assert.Len(t, ps.ResourceSchemas, 4)
+	assert.Same(t, r, ps.ResourceSchemas["example_resource_1"], "already converted entries are reused")
+	assert.NotNil(t, ps.Functions)
+	assert.NotNil(t, ps.ConfigSchema)
+	again, err := ls.providerSchema()
+	require.NoError(t, err)
+	assert.Same(t, ps, again)
+}
+
+func TestLazySchemaV5(t *testing.T) {
+	ls, err := newLazySchemaV5(&tfplugin5.GetProviderSchema_Response{
+		Functions: map[string]*tfplugin5.Function{
+			"f": {Return: &tfplugin5.Function_Return{Type: []byte(`"string"`)}},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	f, ok, err := ls.function("f")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, f.ReturnType.IsPrimitiveType())
+	config, err := ls.configSchema()
+	require.NoError(t, err)
+	assert.Equal(t, &tfjson.Schema{}, config, "a missing provider schema is reported as empty")
+	ps, err := ls.providerSchema()
+	require.NoError(t, err)
+	assert.NotNil(t, ps.ResourceSchemas)
+}
+
+func TestLazySchemaJSON(t *testing.T) {
+	ls, err := decodeBundledSchema([]byte(`{
+		"provider": {"version": 0, "block": {"attributes": {"region": {"type": "string", "optional": true}}}},
+		"resource_schemas": {
+			"example_a": {"version": 1, "block": {"attributes": {"id": {"type": "string", "computed": true}}}},
+			"example_b": {"version": 0, "block": {"attributes": {"id": {"type": "not-a-type"}}}}
+		},
+		"functions": {"example_fn": {"parameters": [], "return_type": "string"}}
+	}`), Request{}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"example_a", "example_b"}, schemaNames(ls, ls.resources))
+	resources := ls.resources.(*lazyMap[json.RawMessage, *tfjson.Schema])
+	assert.Empty(t, resources.converted, "listing names decodes no entries")
+
+	a, ok, err := ls.resource("example_a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), a.Version)
+	assert.Len(t, resources.converted, 1, "only the requested entry is decoded")
+
+	_, ok, err = ls.resource("example_b")
+	assert.True(t, ok)
+	require.ErrorIs(t, err, ErrSchemaCorrupted, "an entry that fails to decode is an error")
+	assert.Contains(t, err.Error(), `"example_b"`)
+	assert.Len(t, resources.converted, 1, "an entry that fails to decode is not kept")
+
+	f, ok, err := ls.function("example_fn")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, f.ReturnType.IsPrimitiveType())
+	config, err := ls.configSchema()
+	require.NoError(t, err)
+	assert.Contains(t, config.Block.Attributes, "region")
+
+	_, err = ls.providerSchema()
+	require.ErrorIs(t, err, ErrSchemaCorrupted)
+	assert.False(t, ls.converted())
+	assert.NotNil(t, resources.raw, "raw values are kept when an entry fails to decode")
+}
+
+func TestLazySchemaJSON_CorruptConfig(t *testing.T) {
+	ls, err := decodeBundledSchema([]byte(`{"provider": {"block": 5}, "resource_schemas": {"example_a": {"block": {}}}}`), Request{}, nil)
+	require.NoError(t, err)
+
+	_, err = ls.configSchema()
+	require.ErrorIs(t, err, ErrSchemaCorrupted)
+	_, err = ls.providerSchema()
+	require.ErrorIs(t, err, ErrSchemaCorrupted)
+	_, ok, err := ls.resource("example_a")
+	require.NoError(t, err, "other entries are still served")
+	assert.True(t, ok)
+}
+
+func TestLazySchema_NilResponse(t *testing.T) {
+	_, err := newLazySchemaV6(nil, nil)
+	assert.Error(t, err)
+	_, err = newLazySchemaV5(nil, nil)
+	assert.Error(t, err)
+}
+
+// BenchmarkLazySchema_SingleResource shows the cost of looking up one
+// resource of a large provider compared with converting it all.
+func BenchmarkLazySchema_SingleResource(b *testing.B) {
+	resp := largeV6Response(3000)
+	b.Run("lazy", func(b *testing.B) {
+		for b.Loop() {
+			ls, _ := newLazySchemaV6(resp, nil)
+			if _, ok, _ := ls.resource("example_resource_42"); !ok {
+				b.Fatal("missing resource")
+			}
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		for b.Loop() {
+			ls, _ := newLazySchemaV6(resp, nil)
+			if ps, _ := ls.providerSchema(); ps.ResourceSchemas["example_resource_42"] == nil {
+				b.Fatal("missing resource")
+			}
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ListOptions filters and pages the names returned by List. The zero value
+// returns every name.
+type ListOptions struct {
+	// Prefix keeps the names starting with it, such as "azurerm_storage_".
+	Prefix string
+	// Regex keeps the names matching this regular expression, in the
+	// syntax of the regexp package. It is not anchored.
+	Regex string
+	// Offset skips this many of the names that pass the filters.
+	Offset int
+	// Limit is the maximum number of names returned. Zero means no limit.
+	// A page shorter than Limit is the last one.
+	Limit int
+}
+
+// List returns the sorted names of the given kind that the provider
+// declares, filtered and paged by opts. Names are read as by
+// ListResources, and filtering happens before any slice is copied, so
+// listing a few of azurerm's thousands of resources allocates only the
+// page returned. Kinds without names, KindProviderConfig among them, fail.
+func (s *Server) List(request Request, kind Kind, opts ListOptions) ([]string, error) {
+	s.logger(logComponentSchema).Debug("Listing names", append(s.requestLogAttrs(request), "kind", kind, "options", opts)...)
+
+	var pick func(*providerMetadata) []string
+	switch kind {
+	case KindResource:
+		pick = func(md *providerMetadata) []string { return md.resources }
+	case KindDataSource:
+		pick = func(md *providerMetadata) []string { return md.dataSources }
+	case KindEphemeralResource:
+		pick = func(md *providerMetadata) []string { return md.ephemeralResources }
+	case KindFunction:
+		pick = func(md *providerMetadata) []string { return md.functions }
+	case KindIdentity, KindAction:
+		return nil, fmt.Errorf("%s schemas: %w", kind, ErrNotImplemented)
+	default:
+		return nil, fmt.Errorf("schema kind %q has no names to list", kind)
+	}
+
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, errors.New("list offset and limit must not be negative")
+	}
+	var re *regexp.Regexp
+	if opts.Regex != "" {
+		var err error
+		if re, err = regexp.Compile(opts.Regex); err != nil {
+			return nil, fmt.Errorf("invalid list regex: %w", err)
+		}
+	}
+
+	names, err := s.listNames(request, kind, pick)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+
+	var (
+		page    []string
+		skipped int
+	)
+	// The names are sorted, so those with the prefix are contiguous.
+	for _, name := range names[sort.SearchStrings(names, opts.Prefix):] {
+		if !strings.HasPrefix(name, opts.Prefix) {
+			break
+		}
+		if re != nil && !re.MatchString(name) {
+			continue
+		}
+		if skipped < opts.Offset {
+			skipped++
+			continue
+		}
+		page = append(page, name)
+		if opts.Limit > 0 && len(page) == opts.Limit {
+			break
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,66 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_List(t *testing.T) {
+	schema := `{"resource_schemas": {
+		"example_storage_account": {"version": 0, "block": {}},
+		"example_storage_blob": {"version": 0, "block": {}},
+		"example_storage_container": {"version": 0, "block": {}},
+		"example_storage_queue": {"version": 0, "block": {}},
+		"example_network": {"version": 0, "block": {}},
+		"example_subnet": {"version": 0, "block": {}}
+	}, "functions": {"parse": {"return_type": "string"}}}`
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(schema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"all", ListOptions{}, []string{"example_network", "example_storage_account", "example_storage_blob", "example_storage_container", "example_storage_queue", "example_subnet"}},
+		{"prefix", ListOptions{Prefix: "example_storage_"}, []string{"example_storage_account", "example_storage_blob", "example_storage_container", "example_storage_queue"}},
+		{"regex", ListOptions{Regex: `(blob|net)`}, []string{"example_network", "example_storage_blob", "example_subnet"}},
+		{"prefix and regex", ListOptions{Prefix: "example_s", Regex: `e$`}, []string{"example_storage_queue"}},
+		{"first page", ListOptions{Prefix: "example_storage_", Limit: 3}, []string{"example_storage_account", "example_storage_blob", "example_storage_container"}},
+		{"last page", ListOptions{Prefix: "example_storage_", Limit: 3, Offset: 3}, []string{"example_storage_queue"}},
+		{"past the end", ListOptions{Offset: 10}, nil},
+		{"no match", ListOptions{Prefix: "other_"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(req, KindResource, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	functions, err := s.List(req, KindFunction, ListOptions{Prefix: "p"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"parse"}, functions)
+
+	page, err := s.List(req, KindResource, ListOptions{Limit: 1})
+	require.NoError(t, err)
+	page[0] = "modified"
+	all, err := s.ListResources(req)
+	require.NoError(t, err)
+	assert.Equal(t, "example_network", all[0], "pages do not share the cached names")
+
+	_, err = s.List(req, KindResource, ListOptions{Regex: "("})
+	assert.ErrorContains(t, err, "invalid list regex")
+	_, err = s.List(req, KindResource, ListOptions{Limit: -1})
+	assert.Error(t, err)
+	_, err = s.List(req, KindProviderConfig, ListOptions{})
+	assert.ErrorContains(t, err, "has no names")
+	_, err = s.List(req, KindIdentity, ListOptions{})
+	assert.ErrorIs(t, err, ErrNotImplemented)
+}
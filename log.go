@@ -0,0 +1,110 @@
+package tfpluginschema
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logComponentKey is the attribute key identifying which stage of the
+// pipeline emitted a log record.
+const logComponentKey = "component"
+
+// Log components. Every record emitted by the Server carries one of these
+// under the "component" key so that logs can be filtered by stage.
+const (
+	logComponentRegistry = "registry" // registry API calls and version resolution
+	logComponentDownload = "download" // provider archive downloads
+	logComponentExtract  = "extract"  // archive extraction and cache publication
+	logComponentPlugin   = "plugin"   // provider process lifecycle and gRPC calls
+	logComponentConvert  = "convert"  // protobuf to terraform-json conversion
+	logComponentCache    = "cache"    // in-memory and on-disk cache lookups
+	logComponentSummary  = "summary"  // per-provider summaries; see WithLogSummaries
+	logComponentSchema   = "schema"   // schema lookups by the Server's methods
+	logComponentServer   = "server"   // Server configuration
+)
+
+// Attribute keys shared by the records of every component, so that logs
+// aggregated from many Servers, for example as JSON, can be grouped by them.
+const (
+	logKeyProvider = "provider"    // "<namespace>/<name>" as requested
+	logKeyVersion  = "version"     // concrete version or constraint, when known
+	logKeyRegistry = "registry"    // registry host, e.g. "registry.opentofu.org"
+	logKeyDuration = "duration_ms" // elapsed time in whole milliseconds
+	logKeyBytes    = "bytes"       // size of a transfer or file
+)
+
+// WithLogAttrs adds attrs to every record the Server emits, after the
+// attributes of the logger passed to NewServer. It is meant for static
+// values identifying the process, such as a run ID, by which logs from a
+// fleet can be told apart once aggregated:
+//
+//	s := NewServer(logger, WithLogAttrs(slog.String("run_id", runID)))
+func WithLogAttrs(attrs ...slog.Attr) ServerOption {
+	return func(s *Server) {
+		s.logAttrs = append(s.logAttrs, attrs...)
+	}
+}
+
+// WithLogLevel sets the minimum level of records the Server emits. Records
+// below level are dropped before they reach the logger's handler; the
+// handler's own level filtering still applies on top of this. This makes it
+// possible to quiet (or enable Debug output from) the Server without
+// reconfiguring a logger shared with the rest of an application.
+func WithLogLevel(level slog.Leveler) ServerOption {
+	return func(s *Server) {
+		s.logLevel = level
+	}
+}
+
+// logger returns the Server's logger annotated with the given component.
+func (s *Server) logger(component string) *slog.Logger {
+	return s.l.With(logComponentKey, component)
+}
+
+// requestLogAttrs returns the provider, registry and, if set, version
+// attributes of request. An empty RegistryType is reported as the Server's
+// default registry.
+func (s *Server) requestLogAttrs(request Request) []any {
+	attrs := []any{
+		logKeyProvider, request.Namespace + "/" + request.Name,
+		logKeyRegistry, normalizedRegistryType(s.registryOrDefault(request.RegistryType)).host(),
+	}
+	if request.Version != "" {
+		attrs = append(attrs, logKeyVersion, request.Version)
+	}
+	return attrs
+}
+
+// durationLogAttr returns d as the duration attribute.
+func durationLogAttr(d time.Duration) slog.Attr {
+	return slog.Int64(logKeyDuration, d.Milliseconds())
+}
+
+// levelHandler wraps a slog.Handler, discarding records below a minimum
+// level.
+type levelHandler struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+// Enabled reports whether the record level meets both the configured minimum
+// and the wrapped handler's own requirements.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.handler.Enabled(ctx, level)
+}
+
+// Handle passes the record to the wrapped handler.
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a levelHandler wrapping the wrapped handler's WithAttrs.
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a levelHandler wrapping the wrapped handler's WithGroup.
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
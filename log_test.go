@@ -0,0 +1,156 @@
+package tfpluginschema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeLogRecords parses newline-delimited JSON log output.
+func decodeLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		rec := map[string]any{}
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	require.NoError(t, sc.Err())
+	return records
+}
+
+func TestLogger_AddsComponent(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	buf.Reset()
+
+	s.logger(logComponentDownload).Info("hello", "bytes", 42)
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, logComponentDownload, records[0][logComponentKey])
+	assert.EqualValues(t, 42, records[0]["bytes"])
+}
+
+func TestWithLogLevel_FiltersRecords(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s := NewServer(l, WithLogLevel(slog.LevelWarn))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	s.logger(logComponentCache).Debug("debug")
+	s.logger(logComponentCache).Info("info")
+	s.logger(logComponentCache).Warn("warn")
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "warn", records[0]["msg"])
+	assert.Equal(t, logComponentCache, records[0][logComponentKey])
+
+	// The caller's logger is left untouched.
+	l.Info("shared")
+	records = decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "shared", records[0]["msg"])
+}
+
+func TestWithLogLevel_HandlerLevelStillApplies(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := NewServer(l, WithLogLevel(slog.LevelDebug))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	s.logger(logComponentPlugin).Info("info")
+	s.logger(logComponentPlugin).Error("error")
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "error", records[0]["msg"])
+}
+
+func TestGet_CacheHitLogsComponent(t *testing.T) {
+	var buf bytes.Buffer
+	cacheRoot := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeOpenTofu}
+	writeFakeProviderBinary(t, cacheRoot, req)
+
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, nil)), WithCacheDir(cacheRoot))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	require.NoError(t, s.Get(req))
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Provider cache hit", records[0]["msg"])
+	assert.Equal(t, logComponentCache, records[0][logComponentKey])
+	assert.Equal(t, "5.0.0", records[0][logKeyVersion])
+	assert.Equal(t, "hashicorp/aws", records[0][logKeyProvider])
+	assert.Equal(t, "registry.opentofu.org", records[0][logKeyRegistry])
+}
+
+func TestServer_EveryRecordHasComponent(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s := NewServer(l, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	s.storeSchema(req, cacheKey(req), newConvertedSchema(&tfjson.ProviderSchema{
+		ResourceSchemas: map[string]*tfjson.Schema{"example_db": {Block: &tfjson.SchemaBlock{}}},
+	}))
+
+	_, err := s.GetResourceSchema(req, "example_db")
+	require.NoError(t, err)
+	_, err = s.ListResources(req)
+	require.NoError(t, err)
+	_, err = s.ListWriteOnlyAttributes(req)
+	require.NoError(t, err)
+	_, err = s.GetCompletionIndex(req)
+	require.NoError(t, err)
+	_, err = s.GetFeatureSupport(req)
+	require.NoError(t, err)
+	_, err = s.GetFunctionDetails(req, "missing")
+	require.Error(t, err)
+
+	records := decodeLogRecords(t, &buf)
+	require.NotEmpty(t, records)
+	for _, rec := range records {
+		assert.NotEmpty(t, rec[logComponentKey], rec["msg"])
+	}
+}
+
+func TestWithLogAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s := NewServer(l, WithLogLevel(slog.LevelInfo), WithLogAttrs(slog.String("run_id", "r-1")), WithLogAttrs(slog.Int("shard", 2)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	s.logger(logComponentDownload).Info("downloaded", durationLogAttr(1500*time.Millisecond), logKeyBytes, 42)
+	s.logger(logComponentDownload).Debug("dropped by the Server's level")
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "r-1", records[0]["run_id"])
+	assert.EqualValues(t, 2, records[0]["shard"])
+	assert.Equal(t, logComponentDownload, records[0][logComponentKey])
+	assert.EqualValues(t, 1500, records[0][logKeyDuration])
+	assert.EqualValues(t, 42, records[0][logKeyBytes])
+}
+
+func TestServer_RequestLogAttrs(t *testing.T) {
+	s := NewServer(nil, WithDefaultRegistry(RegistryTypeTerraform))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	assert.Equal(t, []any{logKeyProvider, "hashicorp/aws", logKeyRegistry, "registry.terraform.io", logKeyVersion, "~> 5.0"},
+		s.requestLogAttrs(Request{Namespace: "hashicorp", Name: "aws", Version: "~> 5.0"}))
+	assert.Equal(t, []any{logKeyProvider, "hashicorp/aws", logKeyRegistry, "registry.opentofu.org"},
+		s.requestLogAttrs(Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}))
+}
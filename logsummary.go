@@ -0,0 +1,202 @@
+package tfpluginschema
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithLogSummaries replaces the Info records the Server emits about each
+// provider (version resolved, cache hit or miss, archive downloaded,
+// provider extracted, schema retrieved, ...) with one summary record per
+// provider, counting how often each message was logged. This keeps the logs
+// of a Warm over dozens of providers readable. Debug, Warn and Error
+// records, and Info records not about a provider, are emitted as usual.
+//
+// A provider's summary is written once its schema is loaded, when Warm
+// finishes with it, and, for anything logged since, by Cleanup.
+func WithLogSummaries(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.logSummaries = enabled
+	}
+}
+
+// logSummaryKey identifies the provider a summary is about.
+type logSummaryKey struct {
+	provider string // lower-cased "<namespace>/<name>"
+	registry string
+}
+
+// logSummary counts the records held back for one provider.
+type logSummary struct {
+	provider string // as first logged
+	version  string // as last logged
+	start    time.Time
+	counts   map[string]int
+}
+
+// logSummaries holds back the Info records about providers until their
+// summary is written. It is safe for concurrent use.
+type logSummaries struct {
+	mu   sync.Mutex
+	out  slog.Handler
+	byID map[logSummaryKey]*logSummary
+}
+
+// add counts one record about the provider described by attrs.
+func (ls *logSummaries) add(msg string, attrs map[string]string) {
+	key := logSummaryKey{provider: strings.ToLower(attrs[logKeyProvider]), registry: attrs[logKeyRegistry]}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	sum, ok := ls.byID[key]
+	if !ok {
+		sum = &logSummary{provider: attrs[logKeyProvider], start: time.Now(), counts: make(map[string]int)}
+		ls.byID[key] = sum
+	}
+	if v := attrs[logKeyVersion]; v != "" {
+		sum.version = v
+	}
+	sum.counts[msg]++
+}
+
+// flush writes the summary of key, if any records about it are held.
+func (ls *logSummaries) flush(key logSummaryKey) {
+	ls.mu.Lock()
+	sum, ok := ls.byID[key]
+	delete(ls.byID, key)
+	ls.mu.Unlock()
+	if ok {
+		ls.write(key, sum)
+	}
+}
+
+// flushAll writes every held summary, in provider order.
+func (ls *logSummaries) flushAll() {
+	ls.mu.Lock()
+	held := ls.byID
+	ls.byID = make(map[logSummaryKey]*logSummary)
+	ls.mu.Unlock()
+	keys := slices.SortedFunc(maps.Keys(held), func(a, b logSummaryKey) int {
+		return strings.Compare(a.provider+"\x00"+a.registry, b.provider+"\x00"+b.registry)
+	})
+	for _, key := range keys {
+		ls.write(key, held[key])
+	}
+}
+
+func (ls *logSummaries) write(key logSummaryKey, sum *logSummary) {
+	ctx := context.Background()
+	if !ls.out.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	total := 0
+	counts := make([]any, 0, len(sum.counts))
+	for _, msg := range slices.Sorted(maps.Keys(sum.counts)) {
+		total += sum.counts[msg]
+		counts = append(counts, slog.Int(msg, sum.counts[msg]))
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "Provider log summary", 0)
+	r.AddAttrs(slog.String(logComponentKey, logComponentSummary), slog.String(logKeyProvider, sum.provider))
+	if sum.version != "" {
+		r.AddAttrs(slog.String(logKeyVersion, sum.version))
+	}
+	r.AddAttrs(
+		slog.String(logKeyRegistry, key.registry),
+		slog.Int("records", total),
+		slog.Group("messages", counts...),
+		durationLogAttr(time.Since(sum.start)),
+	)
+	_ = ls.out.Handle(ctx, r)
+}
+
+// flushLogSummary writes the summary of request's provider when
+// WithLogSummaries is set.
+func (s *Server) flushLogSummary(request Request) {
+	if s.summaries == nil {
+		return
+	}
+	s.summaries.flush(logSummaryKey{
+		provider: strings.ToLower(request.Namespace + "/" + request.Name),
+		registry: normalizedRegistryType(s.registryOrDefault(request.RegistryType)).host(),
+	})
+}
+
+// summaryHandler wraps a slog.Handler, diverting Info records about a
+// provider to a logSummaries. It tracks the provider attributes added by
+// WithAttrs, since the Server attaches them to loggers rather than records.
+type summaryHandler struct {
+	handler   slog.Handler
+	summaries *logSummaries
+	// attrs holds the values of the provider attribute keys added so far.
+	attrs map[string]string
+	// grouped is set once WithGroup has been called, after which added
+	// attributes are no longer the Server's top-level keys.
+	grouped bool
+}
+
+// Enabled reports whether the wrapped handler handles records at level.
+func (h *summaryHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle counts Info records about a provider and passes on the others.
+func (h *summaryHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level != slog.LevelInfo {
+		return h.handler.Handle(ctx, r)
+	}
+	attrs := h.attrs
+	if !h.grouped {
+		var own []slog.Attr
+		r.Attrs(func(a slog.Attr) bool {
+			own = append(own, a)
+			return true
+		})
+		attrs = withSummaryAttrs(attrs, own)
+	}
+	if attrs[logKeyProvider] == "" {
+		return h.handler.Handle(ctx, r)
+	}
+	h.summaries.add(r.Message, attrs)
+	return nil
+}
+
+// WithAttrs returns a summaryHandler wrapping the wrapped handler's
+// WithAttrs and remembering any provider attributes among attrs.
+func (h *summaryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.handler = h.handler.WithAttrs(attrs)
+	if !h.grouped {
+		next.attrs = withSummaryAttrs(h.attrs, attrs)
+	}
+	return &next
+}
+
+// WithGroup returns a summaryHandler wrapping the wrapped handler's
+// WithGroup.
+func (h *summaryHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.handler = h.handler.WithGroup(name)
+	next.grouped = next.grouped || name != ""
+	return &next
+}
+
+// withSummaryAttrs returns known updated with the values of the provider,
+// registry and version attributes among attrs, copying it only if any are.
+func withSummaryAttrs(known map[string]string, attrs []slog.Attr) map[string]string {
+	updated, copied := known, false
+	for _, a := range attrs {
+		if a.Key != logKeyProvider && a.Key != logKeyRegistry && a.Key != logKeyVersion {
+			continue
+		}
+		if !copied {
+			updated, copied = make(map[string]string, 3), true
+			maps.Copy(updated, known)
+		}
+		updated[a.Key] = a.Value.String()
+	}
+	return updated
+}
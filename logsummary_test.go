@@ -0,0 +1,77 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogSummaries(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, nil)), WithCacheDir(t.TempDir()),
+		WithLogSummaries(true), WithLogAttrs(slog.String("run_id", "r1")))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	request := Request{Namespace: "Example", Name: "one", Version: "1.0.0"}
+	l := s.logger(logComponentCache).With(s.requestLogAttrs(request)...)
+	l.Info("Provider cache miss")
+	l.Info("Provider cache miss")
+	l.Info("Extracted provider", "path", "/tmp/x")
+	l.Warn("Slow download")
+	s.logger(logComponentRegistry).Info("Listed providers", "count", 3)
+	s.logger(logComponentRegistry).Info("Fetched available versions", logKeyProvider, "example/two")
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 2, "only records not about a provider, or above Info, pass through")
+	assert.Equal(t, "Slow download", records[0]["msg"])
+	assert.Equal(t, "Listed providers", records[1]["msg"])
+
+	s.flushLogSummary(Request{Namespace: "example", Name: "one"})
+	records = decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	sum := records[0]
+	assert.Equal(t, "Provider log summary", sum["msg"])
+	assert.Equal(t, logComponentSummary, sum[logComponentKey])
+	assert.Equal(t, "Example/one", sum[logKeyProvider])
+	assert.Equal(t, "1.0.0", sum[logKeyVersion])
+	assert.Equal(t, "registry.opentofu.org", sum[logKeyRegistry])
+	assert.Equal(t, "r1", sum["run_id"])
+	assert.EqualValues(t, 3, sum["records"])
+	assert.Equal(t, map[string]any{"Provider cache miss": 2.0, "Extracted provider": 1.0}, sum["messages"])
+
+	// Nothing is held any more for the flushed provider; the rest is
+	// written by Cleanup.
+	s.flushLogSummary(request)
+	assert.Empty(t, decodeLogRecords(t, &buf))
+	require.NoError(t, s.Cleanup())
+	records = decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "example/two", records[0][logKeyProvider])
+	assert.Equal(t, map[string]any{"Fetched available versions": 1.0}, records[0]["messages"])
+}
+
+func TestWithLogSummaries_LevelFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, nil)), WithCacheDir(t.TempDir()),
+		WithLogSummaries(true), WithLogLevel(slog.LevelWarn))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	request := Request{Namespace: "example", Name: "one", Version: "1.0.0"}
+	s.logger(logComponentCache).With(s.requestLogAttrs(request)...).Info("Provider cache miss")
+	s.flushLogSummary(request)
+	assert.Empty(t, decodeLogRecords(t, &buf))
+}
+
+func TestSummaryHandler_IgnoresGroupedAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, nil)), WithCacheDir(t.TempDir()), WithLogSummaries(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	s.l.WithGroup("details").Info("Grouped", logKeyProvider, "example/one")
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Grouped", records[0]["msg"])
+}
@@ -0,0 +1,163 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errMetadataUnsupported is returned when a provider does not implement the
+// GetMetadata RPC; callers fall back to retrieving the full schema.
+var errMetadataUnsupported = errors.New("provider does not support GetMetadata")
+
+// providerMetadata holds the sorted names a provider declares, as returned
+// by the GetMetadata RPC.
+type providerMetadata struct {
+	resources          []string
+	dataSources        []string
+	ephemeralResources []string
+	functions          []string
+	features           providerFeatures
+}
+
+type metadataCache map[providerKey]*providerMetadata
+
+// schemaMetadata returns the names in schema, without converting any
+// schemas.
+func schemaMetadata(schema *lazySchema) *providerMetadata {
+	return &providerMetadata{
+		resources:          schemaNames(schema, schema.resources),
+		dataSources:        schemaNames(schema, schema.dataSources),
+		ephemeralResources: schemaNames(schema, schema.ephemeralResources),
+		functions:          schemaNames(schema, schema.functions),
+		features:           schema.features,
+	}
+}
+
+// listNames returns the names of kind pick selects from the metadata of
+// request. The slice is shared with the cache and must not be modified. It
+// fails with ErrNotSupportedByProvider if there are none because the
+// provider predates entries of kind.
+func (s *Server) listNames(request Request, kind Kind, pick func(*providerMetadata) []string) ([]string, error) {
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	md, err := s.getMetadata(request)
+	if err != nil {
+		return nil, err
+	}
+	names := pick(md)
+	if len(names) == 0 && md.features.support(kind) == featureUnsupported {
+		return nil, errNotSupported(request, kind)
+	}
+	return names, nil
+}
+
+// getMetadata returns the names declared by the provider of request, whose
+// version must be fixed. A schema that is already cached or bundled is used
+// as is. Otherwise the provider is asked with GetMetadata, which neither
+// transfers nor converts any schemas, so listing the resources of a large
+// provider stays cheap. Providers that do not implement it are asked for
+// their full schema instead, which is kept for later lookups.
+func (s *Server) getMetadata(request Request) (*providerMetadata, error) {
+	if !request.fixedVersion() {
+		return nil, fmt.Errorf("version must be fixed before getting metadata")
+	}
+	request.RegistryType = normalizedRegistryType(request.RegistryType)
+	key := cacheKey(request)
+
+	s.mu.RLock()
+	schema, hasSchema := s.sc[key]
+	md, hasMetadata := s.mdc[key]
+	s.mu.RUnlock()
+	if hasSchema {
+		return schemaMetadata(schema), nil
+	}
+	if hasMetadata {
+		return md, nil
+	}
+	if loaded, ok := s.loadedSchema(request); ok {
+		return schemaMetadata(loaded), nil
+	}
+
+	if s.sources != nil {
+		schema, err := s.getSchema(request)
+		if err != nil {
+			return nil, err
+		}
+		return schemaMetadata(schema), nil
+	}
+
+	bundled, ok, err := s.bundledSchema(request)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return schemaMetadata(s.storeSchema(request, key, bundled)), nil
+	}
+	if !s.pluginExec {
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotBundled, request.String())
+	}
+
+	resp, err, _ := s.metadata.Do(key.String(), func() (any, error) {
+		return s.loadMetadata(request, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*providerMetadata), nil
+}
+
+// loadMetadata downloads the provider for request and runs it to list its
+// schema names.
+func (s *Server) loadMetadata(request Request, key providerKey) (*providerMetadata, error) {
+	pl := s.logger(logComponentPlugin).With(s.requestLogAttrs(request)...)
+
+	s.mu.RLock()
+	schema, hasSchema := s.sc[key]
+	md, hasMetadata := s.mdc[key]
+	s.mu.RUnlock()
+	if hasSchema {
+		return schemaMetadata(schema), nil
+	}
+	if hasMetadata {
+		return md, nil
+	}
+
+	client, providerPath, err := s.startProvider(request, key)
+	if err != nil {
+		return nil, err
+	}
+	defer client.close()
+
+	md, err = client.metadata()
+	if errors.Is(err, errMetadataUnsupported) {
+		pl.Debug("Provider does not support GetMetadata, retrieving its full schema", "error", err)
+		schema, err := client.rawSchema()
+		if err != nil {
+			err = fmt.Errorf("failed to get provider schema: %w", err)
+			s.retainProviderOnFailure(err, providerPath)
+			return nil, err
+		}
+		schema.features = client.features()
+		return schemaMetadata(s.storeSchema(request, key, schema)), nil
+	}
+	if err != nil {
+		s.retainProviderOnFailure(err, providerPath)
+		return nil, err
+	}
+	md.features = client.features()
+	pl.Debug("Retrieved provider metadata",
+		"resources", len(md.resources),
+		"data_sources", len(md.dataSources),
+		"ephemeral_resources", len(md.ephemeralResources),
+		"functions", len(md.functions))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.mdc[key]; ok {
+		return existing, nil
+	}
+	s.mdc[key] = md
+	return md, nil
+}
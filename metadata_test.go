@@ -0,0 +1,170 @@
+package tfpluginschema
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
+	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// metadataV6ProviderClient answers GetMetadata; calling any other method
+// panics.
+type metadataV6ProviderClient struct {
+	tfplugin6.ProviderClient
+	resp *tfplugin6.GetMetadata_Response
+	err  error
+}
+
+func (c metadataV6ProviderClient) GetMetadata(context.Context, *tfplugin6.GetMetadata_Request, ...grpc.CallOption) (*tfplugin6.GetMetadata_Response, error) {
+	return c.resp, c.err
+}
+
+type metadataV5ProviderClient struct {
+	tfplugin5.ProviderClient
+	resp *tfplugin5.GetMetadata_Response
+}
+
+func (c metadataV5ProviderClient) GetMetadata(context.Context, *tfplugin5.GetMetadata_Request, ...grpc.CallOption) (*tfplugin5.GetMetadata_Response, error) {
+	return c.resp, nil
+}
+
+func newV6MetadataClient(client tfplugin6.ProviderClient) *universalProviderClient {
+	return &universalProviderClient{v6: &providerGRPCClientV6{
+		providerGRPCClient: &providerGRPCClient[*tfplugin6.GetProviderSchema_Request, *tfplugin6.GetProviderSchema_Response]{
+			grpcClient: v6SchemaClient{client: client},
+		},
+	}}
+}
+
+func TestUniversalProviderClient_Metadata_V6(t *testing.T) {
+	client := newV6MetadataClient(metadataV6ProviderClient{resp: &tfplugin6.GetMetadata_Response{
+		Resources: []*tfplugin6.GetMetadata_ResourceMetadata{
+			{TypeName: "test_b"}, {TypeName: "test_a"},
+		},
+		DataSources:        []*tfplugin6.GetMetadata_DataSourceMetadata{{TypeName: "test_data"}},
+		EphemeralResources: []*tfplugin6.GetMetadata_EphemeralResourceMetadata{{TypeName: "test_secret"}},
+		Functions:          []*tfplugin6.GetMetadata_FunctionMetadata{{Name: "parse"}},
+	}})
+
+	md, err := client.metadata()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test_a", "test_b"}, md.resources)
+	assert.Equal(t, []string{"test_data"}, md.dataSources)
+	assert.Equal(t, []string{"test_secret"}, md.ephemeralResources)
+	assert.Equal(t, []string{"parse"}, md.functions)
+}
+
+func TestUniversalProviderClient_Metadata_V5(t *testing.T) {
+	client := &universalProviderClient{v5: &providerGRPCClientV5{
+		providerGRPCClient: &providerGRPCClient[*tfplugin5.GetProviderSchema_Request, *tfplugin5.GetProviderSchema_Response]{
+			grpcClient: v5SchemaClient{client: metadataV5ProviderClient{resp: &tfplugin5.GetMetadata_Response{
+				Resources: []*tfplugin5.GetMetadata_ResourceMetadata{{TypeName: "test_instance"}},
+			}}},
+		},
+	}}
+
+	md, err := client.metadata()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test_instance"}, md.resources)
+	assert.Empty(t, md.dataSources)
+}
+
+func TestUniversalProviderClient_Metadata_Unsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		client *universalProviderClient
+	}{
+		{
+			name:   "unimplemented",
+			client: newV6MetadataClient(metadataV6ProviderClient{err: status.Error(codes.Unimplemented, "unknown method GetMetadata")}),
+		},
+		{
+			name: "error diagnostic",
+			client: newV6MetadataClient(metadataV6ProviderClient{resp: &tfplugin6.GetMetadata_Response{
+				Diagnostics: []*tfplugin6.Diagnostic{{Severity: tfplugin6.Diagnostic_ERROR, Summary: "not configured"}},
+			}}),
+		},
+		{
+			name: "schema client without GetMetadata",
+			client: &universalProviderClient{v6: &providerGRPCClientV6{
+				providerGRPCClient: &providerGRPCClient[*tfplugin6.GetProviderSchema_Request, *tfplugin6.GetProviderSchema_Response]{grpcClient: &mockV6SchemaClient{}},
+			}},
+		},
+		{
+			name:   "no protocol",
+			client: &universalProviderClient{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.client.metadata()
+			assert.ErrorIs(t, err, errMetadataUnsupported)
+		})
+	}
+}
+
+func TestUniversalProviderClient_Metadata_Error(t *testing.T) {
+	client := newV6MetadataClient(metadataV6ProviderClient{err: errors.New("connection reset")})
+
+	_, err := client.metadata()
+	assert.ErrorContains(t, err, "connection reset")
+	assert.NotErrorIs(t, err, errMetadataUnsupported)
+}
+
+func TestServer_ListResources_UsesCachedMetadata(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	request := Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0"}
+	s.mdc[cacheKey(request)] = &providerMetadata{
+		resources:   []string{"aws_instance", "aws_vpc"},
+		dataSources: []string{"aws_ami"},
+		functions:   []string{"arn_parse"},
+	}
+
+	resources, err := s.ListResources(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_instance", "aws_vpc"}, resources)
+	resources[0] = "modified"
+
+	resources, err = s.ListResources(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_instance", "aws_vpc"}, resources, "callers must not share the cached slice")
+
+	dataSources, err := s.ListDataSources(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_ami"}, dataSources)
+	functions, err := s.ListFunctions(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arn_parse"}, functions)
+	ephemeral, err := s.ListEphemeralResources(request)
+	require.NoError(t, err)
+	assert.Empty(t, ephemeral)
+
+	// The schema itself was never retrieved.
+	_, err = s.GetResourceSchema(request, "aws_instance")
+	assert.ErrorIs(t, err, ErrSchemaNotBundled)
+
+	require.NoError(t, s.CleanupRequest(request))
+	assert.Empty(t, s.mdc)
+}
+
+func TestServer_ListResources_PrefersCachedSchema(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/hashicorp/aws/5.40.0.json": &fstest.MapFile{Data: []byte(bundledAWSSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	request := Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0"}
+
+	resources, err := s.ListResources(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_instance"}, resources)
+	assert.Empty(t, s.mdc)
+	assert.Len(t, s.sc, 1)
+}
@@ -0,0 +1,261 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+
+	"github.com/matt-FFFFFF/tfpluginschema/validate"
+)
+
+// ModuleProviders is the provider set of a module tree; see
+// ResolveModuleProviders.
+type ModuleProviders struct {
+	// Providers holds one Request per provider required anywhere in the
+	// tree, with Version resolved to the newest release satisfying every
+	// module's constraint. They are sorted by registry, namespace and name.
+	Providers []Request `json:"providers"`
+	// Unresolved lists the sources of module calls that were not followed:
+	// Git, HTTP and other package sources, which would have to be
+	// downloaded, and registry modules whose registry does not publish
+	// module details. Their provider requirements are missing from
+	// Providers.
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// ResolveModuleProviders loads the root module in dir and, recursively,
+// every module it calls, and returns the providers they require. Local
+// modules ("./" and "../" sources) are read from disk. Registry modules are
+// resolved to the newest version satisfying their version constraint, and
+// their requirements are taken from the module details the registry
+// publishes, which the Terraform registry does but the OpenTofu registry
+// does not; use WithDefaultRegistry(RegistryTypeTerraform) or registry
+// hosts in module sources to follow them.
+//
+// The version constraints that modules place on the same provider are
+// intersected with IntersectConstraints, and a provider whose constraints no
+// release satisfies fails with ErrNoMatchingVersion. When the constraints
+// contradict each other the error names the modules that demanded them:
+// local modules by their path relative to dir, registry modules by address
+// and version.
+func (s *Server) ResolveModuleProviders(dir string) (*ModuleProviders, error) {
+	w := &moduleWalker{
+		s:         s,
+		root:      dir,
+		visited:   make(map[string]bool),
+		providers: make(map[providerKey]*moduleProvider),
+	}
+	if err := w.local(dir); err != nil {
+		return nil, err
+	}
+
+	keys := make([]providerKey, 0, len(w.providers))
+	for k := range w.providers {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b providerKey) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	result := &ModuleProviders{Unresolved: w.unresolved}
+	for _, k := range keys {
+		p := w.providers[k]
+		req := p.req
+		sets := make([]goversion.Constraints, len(p.constraints))
+		for i, c := range p.constraints {
+			sets[i] = c.constraints
+		}
+		merged, conflicts, err := IntersectConstraints(sets)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraints for provider %s/%s: %w", req.Namespace, req.Name, err)
+		}
+		if len(conflicts) > 0 {
+			explained := make([]string, len(conflicts))
+			for i, c := range conflicts {
+				explained[i] = fmt.Sprintf("%q (module %s) conflicts with %q (module %s)",
+					strings.TrimSpace(c.Constraint.String()), p.constraints[c.Index].module, strings.TrimSpace(c.Other.String()), p.constraints[c.OtherIndex].module)
+			}
+			return nil, fmt.Errorf("failed to resolve provider %s/%s: %w: %s", req.Namespace, req.Name, ErrNoMatchingVersion, strings.Join(explained, "; "))
+		}
+		versions := make([]string, len(merged))
+		for i, c := range merged {
+			versions[i] = strings.TrimSpace(c.String())
+		}
+		slices.Sort(versions)
+		req.Version = strings.Join(versions, ", ")
+		resolved, err := s.prepareRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve provider %s/%s (constraints %q): %w", req.Namespace, req.Name, req.Version, err)
+		}
+		result.Providers = append(result.Providers, resolved)
+	}
+	return result, nil
+}
+
+// moduleProvider collects the constraints on one provider.
+type moduleProvider struct {
+	req         Request
+	constraints []moduleConstraint
+}
+
+// moduleConstraint is the version constraint one module places on a
+// provider.
+type moduleConstraint struct {
+	module      string
+	constraints goversion.Constraints
+}
+
+// moduleWalker walks a module tree for ResolveModuleProviders.
+type moduleWalker struct {
+	s          *Server
+	root       string
+	visited    map[string]bool
+	providers  map[providerKey]*moduleProvider
+	unresolved []string
+}
+
+// isLocalModuleSource reports whether source is a local path, which
+// Terraform recognises by its "./" or "../" prefix.
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// require records the requirement of module on a provider.
+func (w *moduleWalker) require(module, source, constraint string) error {
+	req, err := ParseProviderSource(source)
+	if errors.Is(err, ErrBuiltInProvider) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	req.RegistryType = w.s.registryOrDefault(req.RegistryType)
+	key := cacheKey(req)
+	p, ok := w.providers[key]
+	if !ok {
+		p = &moduleProvider{req: req}
+		w.providers[key] = p
+	}
+	if constraint = strings.TrimSpace(constraint); constraint == "" {
+		return nil
+	}
+	constraints, err := goversion.NewConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("invalid version constraint %q for provider %s/%s: %w", constraint, req.Namespace, req.Name, err)
+	}
+	p.constraints = append(p.constraints, moduleConstraint{module: module, constraints: constraints})
+	return nil
+}
+
+// local walks the module in dir on disk.
+func (w *moduleWalker) local(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if w.visited[abs] {
+		return nil
+	}
+	w.visited[abs] = true
+
+	m, diags := validate.LoadModule(dir)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to load module %s: %s", dir, diags.Error())
+	}
+	module := dir
+	if rel, err := filepath.Rel(w.root, dir); err == nil {
+		module = filepath.ToSlash(rel)
+	}
+	for _, name := range m.ProviderNames() {
+		p := m.Providers[name]
+		if err := w.require(module, p.Source, p.Version); err != nil {
+			return fmt.Errorf("module %s: %w", dir, err)
+		}
+	}
+	for _, call := range m.ModuleCalls {
+		if isLocalModuleSource(call.Source) {
+			err = w.local(filepath.Join(dir, filepath.FromSlash(call.Source)))
+		} else {
+			err = w.registry(call.Source, call.Version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registry walks a module from the module registry.
+func (w *moduleWalker) registry(source, version string) error {
+	m, err := ParseModuleSource(source)
+	if errors.Is(err, ErrNotRegistryModule) {
+		w.unresolved = append(w.unresolved, source)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m.Version = version
+	if m, err = w.s.resolveModule(m); err != nil {
+		return fmt.Errorf("failed to resolve module %s: %w", source, err)
+	}
+	details, err := w.s.GetModuleDetails(m)
+	if errors.Is(err, ErrPluginNotFound) {
+		w.unresolved = append(w.unresolved, source)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get module %s: %w", source, err)
+	}
+	return w.registryContents(m, details, m.Subdir)
+}
+
+// registryContents walks the module at subdir of a registry module package.
+func (w *moduleWalker) registryContents(m ModuleRequest, details *ModuleDetails, subdir string) error {
+	m.Subdir = subdir
+	key := string(m.RegistryType) + "/" + m.String() + "@" + m.Version
+	if w.visited[key] {
+		return nil
+	}
+	w.visited[key] = true
+
+	contents, ok := details.Submodule(subdir)
+	if !ok {
+		w.unresolved = append(w.unresolved, m.String())
+		return nil
+	}
+	for _, p := range contents.ProviderDependencies {
+		source := p.Source
+		if source == "" {
+			source = p.Name
+		}
+		if err := w.require(m.String()+"@"+m.Version, source, p.Version); err != nil {
+			return fmt.Errorf("module %s: %w", m, err)
+		}
+	}
+	for _, dep := range contents.Dependencies {
+		var err error
+		if isLocalModuleSource(dep.Source) {
+			sub := path.Join(subdir, dep.Source)
+			if sub == ".." || strings.HasPrefix(sub, "../") {
+				w.unresolved = append(w.unresolved, dep.Source)
+				continue
+			}
+			if sub == "." {
+				sub = ""
+			}
+			err = w.registryContents(m, details, sub)
+		} else {
+			err = w.registry(dep.Source, dep.Version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModuleTree writes files, keyed by slash-separated path, under a new
+// temporary directory.
+func writeModuleTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func moduleTreeRegistry(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	versions := map[string]string{
+		"aws":    `[{"version": "5.0.0"}, {"version": "5.10.0"}, {"version": "5.29.0"}, {"version": "5.30.0"}]`,
+		"random": `[{"version": "3.6.0"}]`,
+		"null":   `[{"version": "3.2.0"}]`,
+	}
+	for name, list := range versions {
+		mux.HandleFunc("/v1/providers/hashicorp/"+name+"/versions", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"versions": ` + list + `}`))
+		})
+	}
+	mux.HandleFunc("/v1/modules/example/vpc/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"modules": [{"versions": [{"version": "1.0.0"}, {"version": "1.1.0"}, {"version": "2.0.0"}]}]}`))
+	})
+	mux.HandleFunc("/v1/modules/example/vpc/aws/1.1.0", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"version": "1.1.0",
+			"root": {"provider_dependencies": [{"name": "aws", "namespace": "hashicorp", "source": "hashicorp/aws", "version": ">= 5.10"}],
+				"dependencies": [{"name": "sub", "source": "./modules/sub"}]},
+			"submodules": [{"path": "modules/sub",
+				"provider_dependencies": [{"name": "null", "namespace": "hashicorp", "source": "hashicorp/null"}],
+				"dependencies": [{"name": "up", "source": "../../"}]}]}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+		http.NotFound(w, r)
+	})
+	return mux
+}
+
+func TestServer_ResolveModuleProviders(t *testing.T) {
+	dir := writeModuleTree(t, map[string]string{
+		"main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0"
+    }
+  }
+}
+module "net" {
+  source = "./net"
+}
+module "vpc" {
+  source  = "example/vpc/aws"
+  version = "~> 1.0"
+}
+module "pkg" {
+  source = "git::https://example.com/pkg.git"
+}
+resource "terraform_data" "x" {}
+`,
+		"net/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "< 5.30"
+    }
+  }
+}
+resource "random_id" "x" {}
+module "loop" {
+  source = "../"
+}
+`,
+	})
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, moduleTreeRegistry(t))))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, err := s.ResolveModuleProviders(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Request{
+		{Namespace: "hashicorp", Name: "aws", Version: "5.29.0", RegistryType: RegistryTypeOpenTofu},
+		{Namespace: "hashicorp", Name: "null", Version: "3.2.0", RegistryType: RegistryTypeOpenTofu},
+		{Namespace: "hashicorp", Name: "random", Version: "3.6.0", RegistryType: RegistryTypeOpenTofu},
+	}, got.Providers)
+	assert.Equal(t, []string{"git::https://example.com/pkg.git"}, got.Unresolved)
+}
+
+func TestServer_ResolveModuleProviders_Conflict(t *testing.T) {
+	dir := writeModuleTree(t, map[string]string{
+		"main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "< 5.0"
+    }
+  }
+}
+module "net" {
+  source = "./net"
+}
+`,
+		"net/main.tf": `
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.10"
+    }
+  }
+}
+`,
+	})
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, moduleTreeRegistry(t))))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.ResolveModuleProviders(dir)
+	require.ErrorIs(t, err, ErrNoMatchingVersion)
+	assert.ErrorContains(t, err, `">= 5.10" (module net) conflicts with "< 5.0" (module .)`)
+}
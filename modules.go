@@ -0,0 +1,348 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// ErrNotRegistryModule is returned by ParseModuleSource for module sources
+// that are not module registry addresses, such as local paths and Git or
+// HTTP URLs.
+var ErrNotRegistryModule = errors.New("not a module registry address")
+
+// ModuleRequest identifies a module in a module registry, such as
+// "terraform-aws-modules/vpc/aws".
+type ModuleRequest struct {
+	Namespace    string       // Namespace of the module (e.g., "terraform-aws-modules")
+	Name         string       // Name of the module (e.g., "vpc")
+	System       string       // Target system, usually the main provider (e.g., "aws")
+	Version      string       // Version of the module (e.g., "5.8.1") or constraint (e.g., "~> 5.0"); empty selects the latest
+	RegistryType RegistryType // Registry to use (defaults to the Server's default registry)
+	Subdir       string       // Subdirectory of the module package from a "//" suffix (e.g., "modules/vpc-endpoints"), if any
+}
+
+// String returns the module's address as written in a module source,
+// without its registry host or version.
+func (m ModuleRequest) String() string {
+	addr := m.Namespace + "/" + m.Name + "/" + m.System
+	if m.Subdir != "" {
+		addr += "//" + m.Subdir
+	}
+	return addr
+}
+
+// ParseModuleSource parses the source of a module block into a
+// ModuleRequest with an empty Version. Registry addresses take the forms
+//
+//	terraform-aws-modules/vpc/aws
+//	registry.terraform.io/terraform-aws-modules/vpc/aws
+//	app.terraform.io/example-org/network/azurerm//modules/spoke
+//
+// Any other source, such as "./network" or
+// "git::https://example.com/network.git", yields ErrNotRegistryModule.
+func ParseModuleSource(source string) (ModuleRequest, error) {
+	source = strings.TrimSpace(source)
+	addr, subdir, _ := strings.Cut(source, "//")
+	if strings.Contains(addr, "::") || strings.HasPrefix(addr, ".") || strings.HasPrefix(addr, "/") {
+		return ModuleRequest{}, fmt.Errorf("%w: %s", ErrNotRegistryModule, source)
+	}
+
+	parts := strings.Split(addr, "/")
+	var m ModuleRequest
+	switch len(parts) {
+	case 3:
+		// A dotted first segment is a host, as in the GitHub shorthand
+		// "github.com/org/repo"; registry namespaces have no dots.
+		if strings.Contains(parts[0], ".") {
+			return ModuleRequest{}, fmt.Errorf("%w: %s", ErrNotRegistryModule, source)
+		}
+		m.Namespace, m.Name, m.System = parts[0], parts[1], parts[2]
+	case 4:
+		host := strings.ToLower(parts[0])
+		rt, ok := registryHosts[host]
+		if !ok {
+			if _, ok := RegistryType(host).customHost(); !ok {
+				return ModuleRequest{}, fmt.Errorf("%w: %s", ErrNotRegistryModule, source)
+			}
+			rt = RegistryType(host)
+		}
+		m.RegistryType, m.Namespace, m.Name, m.System = rt, parts[1], parts[2], parts[3]
+	default:
+		return ModuleRequest{}, fmt.Errorf("%w: %s", ErrNotRegistryModule, source)
+	}
+	m.Subdir = strings.Trim(subdir, "/")
+
+	if err := validateModuleRequest(m); err != nil {
+		return ModuleRequest{}, fmt.Errorf("invalid module source %q: %w", source, err)
+	}
+	return m, nil
+}
+
+// validateModuleRequest ensures the address segments of m are non-empty and
+// safe in a URL path segment.
+func validateModuleRequest(m ModuleRequest) error {
+	for _, c := range []struct{ name, value string }{
+		{"namespace", m.Namespace},
+		{"name", m.Name},
+		{"system", m.System},
+	} {
+		if err := validateCachePathComponent(c.name, c.value, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moduleURL returns the module registry API URL of m with the extra path
+// segments appended, using the base URL the registry host advertises.
+func (s *Server) moduleURL(m ModuleRequest, segments ...string) (string, error) {
+	if err := validateModuleRequest(m); err != nil {
+		return "", fmt.Errorf("invalid module request: %w", err)
+	}
+	base := strings.TrimSuffix(m.RegistryType.BaseURL(), "/providers") + "/modules"
+	if host, ok := m.RegistryType.customHost(); ok {
+		var err error
+		if base, err = s.discoverServiceURL(host, serviceModulesV1); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(append([]string{base, m.Namespace, m.Name, m.System}, segments...), "/"), nil
+}
+
+// GetModuleVersions returns the versions of the module published in its
+// registry, sorted in ascending order.
+func (s *Server) GetModuleVersions(m ModuleRequest) (goversion.Collection, error) {
+	m.RegistryType = normalizedRegistryType(s.registryOrDefault(m.RegistryType))
+	u, err := s.moduleURL(m, pluginApiVersions)
+	if err != nil {
+		return nil, err
+	}
+	body, status, err := s.registryGet(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get module versions: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, u)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s => %d", ErrPluginApi, u, status)
+	}
+
+	var result struct {
+		Modules []pluginApiVersionsResponse `json:"modules"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode module versions response: %w", err)
+	}
+	var versions goversion.Collection
+	for _, mod := range result.Modules {
+		for _, v := range mod.Versions {
+			ver, err := goversion.NewVersion(v.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse version %q: %w", v.Version, err)
+			}
+			versions = append(versions, ver)
+		}
+	}
+	slices.SortFunc(versions, func(a, b *goversion.Version) int {
+		return a.Compare(b)
+	})
+	return slices.CompactFunc(versions, (*goversion.Version).Equal), nil
+}
+
+// resolveModule returns m with its registry set and its Version resolved to
+// the latest version satisfying the constraint.
+func (s *Server) resolveModule(m ModuleRequest) (ModuleRequest, error) {
+	m.RegistryType = normalizedRegistryType(s.registryOrDefault(m.RegistryType))
+	if v, err := goversion.NewVersion(m.Version); err == nil {
+		m.Version = v.Original()
+		return m, nil
+	}
+	var constraints goversion.Constraints
+	if m.Version != "" {
+		c, err := goversion.NewConstraint(m.Version)
+		if err != nil {
+			return m, fmt.Errorf("invalid version constraint %q: %w", m.Version, err)
+		}
+		constraints = c
+	}
+	versions, err := s.GetModuleVersions(m)
+	if err != nil {
+		return m, err
+	}
+	if len(versions) == 0 {
+		return m, fmt.Errorf("%w: module %s has no versions", ErrNoMatchingVersion, m)
+	}
+	latest, err := GetLatestVersionMatch(versions, constraints)
+	if err != nil {
+		return m, fmt.Errorf("%w: %s for module %s", ErrNoMatchingVersion, m.Version, m)
+	}
+	m.Version = latest.Original()
+	return m, nil
+}
+
+// GetModuleSource resolves m's version and returns the address of the
+// module package that the registry's download endpoint points to, such as
+// "git::https://github.com/terraform-aws-modules/terraform-aws-vpc?ref=v5.8.1".
+// The address uses go-getter syntax, as module sources do; relative
+// locations are resolved against the download URL. m.Subdir is not
+// included.
+func (s *Server) GetModuleSource(m ModuleRequest) (string, error) {
+	m, err := s.resolveModule(m)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.moduleURL(m, m.Version, "download")
+	if err != nil {
+		return "", err
+	}
+	if s.offline {
+		return "", fmt.Errorf("%w: %s", ErrOffline, u)
+	}
+
+	req, err := s.newRegistryRequest(http.MethodGet, u)
+	if err != nil {
+		return "", fmt.Errorf("failed to create HTTP request for registry API: %w", err)
+	}
+	resp, err := s.doRegistryRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get module download location: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", ErrPluginNotFound, u)
+	default:
+		return "", fmt.Errorf("%w: %s => %d", ErrPluginApi, u, resp.StatusCode)
+	}
+
+	// The location comes in the X-Terraform-Get header, or from newer
+	// registries in a JSON body.
+	location := resp.Header.Get("X-Terraform-Get")
+	if location == "" && resp.StatusCode == http.StatusOK {
+		var body struct {
+			Location string `json:"location"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+			location = body.Location
+		}
+	}
+	if location == "" {
+		return "", fmt.Errorf("%w: %s returned no module location", ErrPluginApi, u)
+	}
+	return resolveModuleLocation(u, location), nil
+}
+
+// resolveModuleLocation resolves a relative download location against the
+// URL it was returned from. Locations with a go-getter forced getter, such
+// as "git::", and absolute URLs are returned unchanged.
+func resolveModuleLocation(downloadURL, location string) string {
+	if strings.Contains(location, "::") {
+		return location
+	}
+	ref, err := url.Parse(location)
+	if err != nil || ref.IsAbs() {
+		return location
+	}
+	if !strings.HasPrefix(location, "/") && !strings.HasPrefix(location, ".") {
+		// Shorthands such as "github.com/org/repo" are not paths.
+		return location
+	}
+	base, err := url.Parse(downloadURL)
+	if err != nil {
+		return location
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// ModuleDetails describes a published module version, as reported by
+// registries that implement the module details endpoint, such as the
+// Terraform registry.
+type ModuleDetails struct {
+	Version    string           `json:"version"`
+	Root       ModuleContents   `json:"root"`
+	Submodules []ModuleContents `json:"submodules"`
+}
+
+// ModuleContents describes the root module of a module package or one of
+// its submodules.
+type ModuleContents struct {
+	// Path is the submodule's directory within the package, empty for the
+	// root module.
+	Path                 string                     `json:"path"`
+	ProviderDependencies []ModuleProviderDependency `json:"provider_dependencies"`
+	// Dependencies are the module's own module calls.
+	Dependencies []ModuleDependency `json:"dependencies"`
+}
+
+// ModuleProviderDependency is a required_providers entry of a module.
+type ModuleProviderDependency struct {
+	Name      string `json:"name"`      // Local name (e.g., "aws")
+	Namespace string `json:"namespace"` // Namespace of the provider (e.g., "hashicorp")
+	Source    string `json:"source"`    // Source address (e.g., "hashicorp/aws")
+	Version   string `json:"version"`   // Version constraint, empty if none was declared
+}
+
+// ModuleDependency is a module block of a module.
+type ModuleDependency struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version"`
+}
+
+// Submodule returns the contents of the submodule at path, or the root
+// module if path is empty.
+func (d *ModuleDetails) Submodule(path string) (ModuleContents, bool) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return d.Root, true
+	}
+	for _, sm := range d.Submodules {
+		if strings.Trim(sm.Path, "/") == path {
+			return sm, true
+		}
+	}
+	return ModuleContents{}, false
+}
+
+// GetModuleDetails resolves m's version and returns its provider and module
+// dependencies from the registry. The details endpoint is not part of the
+// module registry protocol; registries that do not implement it, such as
+// the OpenTofu registry, yield ErrPluginNotFound.
+func (s *Server) GetModuleDetails(m ModuleRequest) (*ModuleDetails, error) {
+	m, err := s.resolveModule(m)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.moduleURL(m, m.Version)
+	if err != nil {
+		return nil, err
+	}
+	body, status, err := s.registryGet(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get module details: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, u)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s => %d", ErrPluginApi, u, status)
+	}
+	var details ModuleDetails
+	if err := json.Unmarshal(body, &details); err != nil {
+		return nil, fmt.Errorf("failed to decode module details response: %w", err)
+	}
+	if details.Version == "" {
+		details.Version = m.Version
+	}
+	return &details, nil
+}
@@ -0,0 +1,122 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModuleSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   ModuleRequest
+	}{
+		{"terraform-aws-modules/vpc/aws", ModuleRequest{Namespace: "terraform-aws-modules", Name: "vpc", System: "aws"}},
+		{"registry.terraform.io/Azure/avm-res-network/azurerm", ModuleRequest{Namespace: "Azure", Name: "avm-res-network", System: "azurerm", RegistryType: RegistryTypeTerraform}},
+		{"hashicorp/consul/aws//modules/consul-cluster", ModuleRequest{Namespace: "hashicorp", Name: "consul", System: "aws", Subdir: "modules/consul-cluster"}},
+		{"app.terraform.io/example-org/network/azurerm", ModuleRequest{Namespace: "example-org", Name: "network", System: "azurerm", RegistryType: RegistryTypeHCPTerraform}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := ParseModuleSource(tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, source := range []string{
+		"./network",
+		"../modules/network",
+		"git::https://example.com/network.git?ref=v1",
+		"github.com/hashicorp/example",
+		"s3::https://s3.amazonaws.com/bucket/network.zip",
+		"a/b",
+		"https://example.com/network.zip",
+	} {
+		t.Run(source, func(t *testing.T) {
+			_, err := ParseModuleSource(source)
+			require.ErrorIs(t, err, ErrNotRegistryModule)
+		})
+	}
+}
+
+func moduleRegistryHandler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/modules/example/network/aws/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"modules": [{"versions": [{"version": "1.2.0"}, {"version": "1.10.0"}, {"version": "2.0.0"}]}]}`))
+	})
+	mux.HandleFunc("/v1/modules/example/network/aws/1.10.0/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Terraform-Get", "git::https://example.com/network.git?ref=v1.10.0")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v1/modules/example/network/aws/2.0.0/download", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"location": "/archives/network-2.0.0.tar.gz"}`))
+	})
+	mux.HandleFunc("/v1/modules/example/network/aws/1.10.0", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"version": "1.10.0",
+			"root": {"path": "", "provider_dependencies": [{"name": "aws", "namespace": "hashicorp", "source": "hashicorp/aws", "version": ">= 5.0"}],
+				"dependencies": [{"name": "labels", "source": "example/labels/null", "version": "~> 1.0"}]},
+			"submodules": [{"path": "modules/endpoints", "provider_dependencies": [{"name": "aws", "namespace": "hashicorp", "source": "hashicorp/aws", "version": ">= 5.10"}]}]}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Logf("unexpected request %s", r.URL)
+		http.NotFound(w, r)
+	})
+	return mux
+}
+
+func TestServer_ModuleRegistry(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, moduleRegistryHandler(t))))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	m := ModuleRequest{Namespace: "example", Name: "network", System: "aws"}
+
+	versions, err := s.GetModuleVersions(m)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0", "1.10.0", "2.0.0"}, versionStrings(versions))
+
+	m.Version = "~> 1.2"
+	source, err := s.GetModuleSource(m)
+	require.NoError(t, err)
+	assert.Equal(t, "git::https://example.com/network.git?ref=v1.10.0", source)
+
+	source, err = s.GetModuleSource(ModuleRequest{Namespace: "example", Name: "network", System: "aws"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.opentofu.org/archives/network-2.0.0.tar.gz", source)
+
+	details, err := s.GetModuleDetails(m)
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.0", details.Version)
+	assert.Equal(t, []ModuleProviderDependency{{Name: "aws", Namespace: "hashicorp", Source: "hashicorp/aws", Version: ">= 5.0"}}, details.Root.ProviderDependencies)
+	assert.Equal(t, []ModuleDependency{{Name: "labels", Source: "example/labels/null", Version: "~> 1.0"}}, details.Root.Dependencies)
+	sub, ok := details.Submodule("modules/endpoints/")
+	require.True(t, ok)
+	assert.Equal(t, ">= 5.10", sub.ProviderDependencies[0].Version)
+	_, ok = details.Submodule("modules/missing")
+	assert.False(t, ok)
+
+	_, err = s.GetModuleDetails(ModuleRequest{Namespace: "example", Name: "network", System: "aws", Version: "2.0.0"})
+	require.ErrorIs(t, err, ErrPluginNotFound)
+
+	_, err = s.GetModuleSource(ModuleRequest{Namespace: "example", Name: "network", System: "aws", Version: ">= 3.0"})
+	require.ErrorIs(t, err, ErrNoMatchingVersion)
+}
+
+func TestServer_ModuleRegistry_ServiceDiscovery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"modules.v1": "/api/registry/v1/modules/", "providers.v1": "/api/registry/v1/providers/"}`))
+	})
+	mux.HandleFunc("/api/registry/v1/modules/example-org/network/azurerm/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"modules": [{"versions": [{"version": "0.3.0"}]}]}`))
+	})
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, mux)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	m, err := ParseModuleSource("app.terraform.io/example-org/network/azurerm")
+	require.NoError(t, err)
+	versions, err := s.GetModuleVersions(m)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0.3.0"}, versionStrings(versions))
+}
@@ -0,0 +1,123 @@
+package tfpluginschema
+
+import (
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Normalize rewrites ps in place into a canonical form, so that comparing or
+// fingerprinting schemas is not affected by cosmetic differences between
+// provider builds:
+//
+//   - descriptions have Windows line endings, trailing spaces on each line
+//     and surrounding blank space removed;
+//   - a description kind is only set when there is a description, and an
+//     unset kind on a description becomes "plain";
+//   - nil entries and empty attribute and block maps are removed.
+//
+// Maps are encoded with sorted keys by encoding/json, so a normalized schema
+// always marshals to the same bytes. Schemas returned by a Server are shared
+// with its cache unless WithCloneSchemas is set; normalize a copy of those.
+func Normalize(ps *tfjson.ProviderSchema) {
+	if ps == nil {
+		return
+	}
+	normalizeSchema(ps.ConfigSchema)
+	for _, m := range []map[string]*tfjson.Schema{ps.ResourceSchemas, ps.DataSourceSchemas, ps.EphemeralResourceSchemas} {
+		for name, s := range m {
+			if s == nil {
+				delete(m, name)
+				continue
+			}
+			normalizeSchema(s)
+		}
+	}
+	for name, f := range ps.Functions {
+		if f == nil {
+			delete(ps.Functions, name)
+			continue
+		}
+		normalizeFunction(f)
+	}
+}
+
+func normalizeFunction(f *tfjson.FunctionSignature) {
+	f.Description = normalizeDescription(f.Description)
+	f.Summary = normalizeDescription(f.Summary)
+	f.DeprecationMessage = normalizeDescription(f.DeprecationMessage)
+	for _, p := range f.Parameters {
+		if p != nil {
+			p.Description = normalizeDescription(p.Description)
+		}
+	}
+	if f.VariadicParameter != nil {
+		f.VariadicParameter.Description = normalizeDescription(f.VariadicParameter.Description)
+	}
+}
+
+func normalizeSchema(s *tfjson.Schema) {
+	if s != nil {
+		normalizeBlock(s.Block)
+	}
+}
+
+func normalizeBlock(b *tfjson.SchemaBlock) {
+	if b == nil {
+		return
+	}
+	b.Description, b.DescriptionKind = normalizeDescribed(b.Description, b.DescriptionKind)
+	b.Attributes = normalizeAttributes(b.Attributes)
+	for name, nb := range b.NestedBlocks {
+		if nb == nil {
+			delete(b.NestedBlocks, name)
+			continue
+		}
+		normalizeBlock(nb.Block)
+	}
+	if len(b.NestedBlocks) == 0 {
+		b.NestedBlocks = nil
+	}
+}
+
+func normalizeAttributes(attrs map[string]*tfjson.SchemaAttribute) map[string]*tfjson.SchemaAttribute {
+	for name, a := range attrs {
+		if a == nil {
+			delete(attrs, name)
+			continue
+		}
+		a.Description, a.DescriptionKind = normalizeDescribed(a.Description, a.DescriptionKind)
+		if a.AttributeNestedType != nil {
+			a.AttributeNestedType.Attributes = normalizeAttributes(a.AttributeNestedType.Attributes)
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// normalizeDescribed normalizes a description and its kind together.
+func normalizeDescribed(desc string, kind tfjson.SchemaDescriptionKind) (string, tfjson.SchemaDescriptionKind) {
+	desc = normalizeDescription(desc)
+	switch {
+	case desc == "":
+		return "", ""
+	case kind == "":
+		return desc, tfjson.SchemaDescriptionKindPlain
+	}
+	return desc, kind
+}
+
+// normalizeDescription converts line endings to "\n" and removes trailing
+// blanks from each line and blank lines around the text.
+func normalizeDescription(s string) string {
+	if s == "" {
+		return s
+	}
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,56 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestNormalize(t *testing.T) {
+	build := func(desc string, kind tfjson.SchemaDescriptionKind, emptyMaps bool) *tfjson.ProviderSchema {
+		block := &tfjson.SchemaBlock{
+			Attributes: map[string]*tfjson.SchemaAttribute{
+				"name": {AttributeType: cty.String, Required: true, Description: desc, DescriptionKind: kind},
+				"tags": {AttributeType: cty.Map(cty.String), Optional: true, DescriptionKind: tfjson.SchemaDescriptionKindPlain},
+			},
+			Description:     desc,
+			DescriptionKind: kind,
+		}
+		if emptyMaps {
+			block.NestedBlocks = map[string]*tfjson.SchemaBlockType{}
+			block.Attributes["gone"] = nil
+		}
+		return &tfjson.ProviderSchema{
+			ConfigSchema:    &tfjson.Schema{Block: &tfjson.SchemaBlock{}},
+			ResourceSchemas: map[string]*tfjson.Schema{"example_thing": {Block: block}},
+			Functions: map[string]*tfjson.FunctionSignature{
+				"parse": {Summary: desc, ReturnType: cty.String, Parameters: []*tfjson.FunctionParameter{{Name: "in", Description: desc, Type: cty.String}}},
+			},
+		}
+	}
+
+	a := build("The name.\r\nMust be unique.  \r\n", "", true)
+	b := build("\nThe name.\nMust be unique.", tfjson.SchemaDescriptionKindPlain, false)
+	Normalize(a)
+	Normalize(b)
+
+	aj, err := json.Marshal(a)
+	require.NoError(t, err)
+	bj, err := json.Marshal(b)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(bj), string(aj))
+
+	attrs := a.ResourceSchemas["example_thing"].Block.Attributes
+	assert.Equal(t, "The name.\nMust be unique.", attrs["name"].Description)
+	assert.Equal(t, tfjson.SchemaDescriptionKindPlain, attrs["name"].DescriptionKind)
+	assert.Empty(t, attrs["tags"].DescriptionKind, "a kind without a description is dropped")
+	assert.NotContains(t, attrs, "gone")
+	assert.Nil(t, a.ResourceSchemas["example_thing"].Block.NestedBlocks)
+	assert.Equal(t, "The name.\nMust be unique.", a.Functions["parse"].Parameters[0].Description)
+
+	Normalize(nil)
+}
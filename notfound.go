@@ -0,0 +1,64 @@
+package tfpluginschema
+
+import (
+	"maps"
+	"time"
+)
+
+// defaultNotFoundTTL is how long a registry's 404 for a provider's versions
+// is remembered unless WithNotFoundTTL says otherwise.
+const defaultNotFoundTTL = 5 * time.Minute
+
+// WithNotFoundTTL sets how long the Server remembers that a registry does
+// not know a provider, as answered by a 404 from its versions endpoint.
+// Within that time, version lookups of the provider on that registry fail
+// with the same ErrPluginNotFound error without another request, so that a
+// caller falling back from one registry to the other, for every provider of
+// a large configuration, does not ask the first registry again each time.
+// The default is five minutes; a negative d disables the cache. The cache is
+// kept in memory, cleared by Cleanup and CleanupRequest, and bypassed by
+// WithForceFetch.
+func WithNotFoundTTL(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.notFoundTTL = d
+	}
+}
+
+// notFound is a remembered 404 for a provider's versions.
+type notFound struct {
+	err     error
+	expires time.Time
+}
+
+// cachedNotFound returns the error of a remembered 404 for the versions
+// list under key, if it has not expired.
+func (s *Server) cachedNotFound(key providerKey) (error, bool) {
+	if s.forceFetch {
+		return nil, false
+	}
+	s.mu.RLock()
+	nf, ok := s.notFound[key]
+	s.mu.RUnlock()
+	if !ok || time.Now().After(nf.expires) {
+		return nil, false
+	}
+	return nf.err, true
+}
+
+// storeNotFound remembers err, caused by a 404 for the versions list under
+// key, for the Server's not-found TTL.
+func (s *Server) storeNotFound(key providerKey, err error) {
+	ttl := s.notFoundTTL
+	if ttl == 0 {
+		ttl = defaultNotFoundTTL
+	}
+	if ttl < 0 {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Expired entries are dropped here, so that the map stays small.
+	maps.DeleteFunc(s.notFound, func(_ providerKey, nf notFound) bool { return now.After(nf.expires) })
+	s.notFound[key] = notFound{err: err, expires: now.Add(ttl)}
+}
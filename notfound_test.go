@@ -0,0 +1,72 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_NotFoundCache(t *testing.T) {
+	var requests atomic.Int32
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	req := VersionsRequest{Namespace: "example", Name: "missing"}
+
+	t.Run("remembered", func(t *testing.T) {
+		requests.Store(0)
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		_, err := s.GetAvailableVersions(req)
+		require.ErrorIs(t, err, ErrPluginNotFound)
+		_, again := s.GetAvailableVersions(VersionsRequest{Namespace: "Example", Name: "Missing"})
+		assert.Equal(t, err, again)
+		assert.EqualValues(t, 1, requests.Load())
+
+		// Another registry is asked separately.
+		_, err = s.GetAvailableVersions(VersionsRequest{Namespace: "example", Name: "missing", RegistryType: RegistryTypeTerraform})
+		require.ErrorIs(t, err, ErrPluginNotFound)
+		assert.EqualValues(t, 2, requests.Load())
+
+		require.NoError(t, s.CleanupRequest(Request{Namespace: "example", Name: "missing"}))
+		_, err = s.GetAvailableVersions(req)
+		require.ErrorIs(t, err, ErrPluginNotFound)
+		assert.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		requests.Store(0)
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithNotFoundTTL(time.Millisecond))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		_, err := s.GetAvailableVersions(req)
+		require.ErrorIs(t, err, ErrPluginNotFound)
+		time.Sleep(5 * time.Millisecond)
+		_, err = s.GetAvailableVersions(req)
+		require.ErrorIs(t, err, ErrPluginNotFound)
+		assert.EqualValues(t, 2, requests.Load())
+	})
+
+	for name, opt := range map[string]ServerOption{
+		"disabled":    WithNotFoundTTL(-1),
+		"force fetch": WithForceFetch(true),
+	} {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), opt)
+			t.Cleanup(func() { _ = s.Cleanup() })
+
+			for range 2 {
+				_, err := s.GetAvailableVersions(req)
+				require.ErrorIs(t, err, ErrPluginNotFound)
+			}
+			assert.EqualValues(t, 2, requests.Load())
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package tfpluginschema
+
+import (
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// WithNestedObjectTypes returns attributes whose type is an object, or a
+// list, set or map of objects, as nested attributes, the way protocol v6
+// providers describe them. Protocol v5 cannot express nested attributes, so
+// v5 providers give such attributes an object type instead; with this
+// option consumers can walk both through AttributeNestedType.
+//
+// The nested attributes are derived from the object type: optional object
+// attributes become optional, the others required, and all of them are
+// computed if the attribute is computed and not configurable. Sensitivity
+// is inherited. Objects nested inside other collections, such as lists of
+// lists of objects, keep their type. The result is always a copy.
+func WithNestedObjectTypes() SchemaOption {
+	return func(o *schemaOptions) {
+		o.expandObjectTypes = true
+	}
+}
+
+func expandBlockObjectTypes(b *tfjson.SchemaBlock) {
+	if b == nil {
+		return
+	}
+	expandAttributeObjectTypes(b.Attributes)
+	for _, nb := range b.NestedBlocks {
+		if nb != nil {
+			expandBlockObjectTypes(nb.Block)
+		}
+	}
+}
+
+func expandAttributeObjectTypes(attrs map[string]*tfjson.SchemaAttribute) {
+	for _, a := range attrs {
+		if a == nil {
+			continue
+		}
+		if a.AttributeNestedType != nil {
+			expandAttributeObjectTypes(a.AttributeNestedType.Attributes)
+			continue
+		}
+		if nested := nestedTypeOf(a); nested != nil {
+			a.AttributeNestedType = nested
+			a.AttributeType = cty.NilType
+		}
+	}
+}
+
+// nestedTypeOf returns the nested attribute form of a's object type, or nil
+// if a does not have one.
+func nestedTypeOf(a *tfjson.SchemaAttribute) *tfjson.SchemaNestedAttributeType {
+	ty := a.AttributeType
+	if ty == cty.NilType {
+		return nil
+	}
+	var mode tfjson.SchemaNestingMode
+	switch {
+	case ty.IsObjectType():
+		mode = tfjson.SchemaNestingModeSingle
+	case ty.IsListType() && ty.ElementType().IsObjectType():
+		mode, ty = tfjson.SchemaNestingModeList, ty.ElementType()
+	case ty.IsSetType() && ty.ElementType().IsObjectType():
+		mode, ty = tfjson.SchemaNestingModeSet, ty.ElementType()
+	case ty.IsMapType() && ty.ElementType().IsObjectType():
+		mode, ty = tfjson.SchemaNestingModeMap, ty.ElementType()
+	default:
+		return nil
+	}
+
+	computedOnly := a.Computed && !a.Optional && !a.Required
+	nested := &tfjson.SchemaNestedAttributeType{NestingMode: mode}
+	if len(ty.AttributeTypes()) > 0 {
+		nested.Attributes = make(map[string]*tfjson.SchemaAttribute, len(ty.AttributeTypes()))
+	}
+	for name, attrType := range ty.AttributeTypes() {
+		na := &tfjson.SchemaAttribute{
+			AttributeType: attrType,
+			Sensitive:     a.Sensitive,
+		}
+		switch {
+		case computedOnly:
+			na.Computed = true
+		case ty.AttributeOptional(name):
+			na.Optional = true
+		default:
+			na.Required = true
+		}
+		if inner := nestedTypeOf(na); inner != nil {
+			na.AttributeNestedType = inner
+			na.AttributeType = cty.NilType
+		}
+		nested.Attributes[name] = na
+	}
+	return nested
+}
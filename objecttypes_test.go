@@ -0,0 +1,85 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const objectTypedSchema = `{
+	"resource_schemas": {"example_thing": {"version": 0, "block": {
+		"attributes": {
+			"name": {"type": "string", "required": true},
+			"settings": {"type": ["object", {"size": "number", "tags": ["map", "string"]}, ["tags"]], "optional": true, "sensitive": true},
+			"rules": {"type": ["list", ["object", {"port": "number", "target": ["object", {"host": "string"}]}]], "required": true},
+			"status": {"type": ["set", ["object", {"state": "string"}]], "computed": true},
+			"matrix": {"type": ["list", ["list", ["object", {"x": "number"}]]], "optional": true}
+		},
+		"block_types": {"inner": {"nesting_mode": "single", "block": {"attributes": {
+			"labels": {"type": ["map", ["object", {"value": "string"}]], "optional": true}
+		}}}}
+	}}}
+}`
+
+func TestServer_WithNestedObjectTypes(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(objectTypedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	schema, err := s.GetResourceSchema(req, "example_thing", WithNestedObjectTypes())
+	require.NoError(t, err)
+	attrs := schema.Block.Attributes
+
+	assert.Equal(t, cty.String, attrs["name"].AttributeType)
+	assert.Nil(t, attrs["name"].AttributeNestedType)
+
+	settings := attrs["settings"]
+	require.NotNil(t, settings.AttributeNestedType)
+	assert.Equal(t, cty.NilType, settings.AttributeType)
+	assert.Equal(t, tfjson.SchemaNestingModeSingle, settings.AttributeNestedType.NestingMode)
+	assert.True(t, settings.AttributeNestedType.Attributes["size"].Required)
+	assert.True(t, settings.AttributeNestedType.Attributes["tags"].Optional)
+	assert.True(t, settings.AttributeNestedType.Attributes["tags"].Sensitive)
+	assert.Equal(t, cty.Map(cty.String), settings.AttributeNestedType.Attributes["tags"].AttributeType)
+
+	rules := attrs["rules"].AttributeNestedType
+	require.NotNil(t, rules)
+	assert.Equal(t, tfjson.SchemaNestingModeList, rules.NestingMode)
+	target := rules.Attributes["target"]
+	require.NotNil(t, target.AttributeNestedType, "objects inside objects are expanded too")
+	assert.Equal(t, tfjson.SchemaNestingModeSingle, target.AttributeNestedType.NestingMode)
+	assert.True(t, target.AttributeNestedType.Attributes["host"].Required)
+
+	status := attrs["status"].AttributeNestedType
+	require.NotNil(t, status)
+	assert.Equal(t, tfjson.SchemaNestingModeSet, status.NestingMode)
+	assert.True(t, status.Attributes["state"].Computed)
+	assert.False(t, status.Attributes["state"].Required)
+
+	assert.Nil(t, attrs["matrix"].AttributeNestedType, "lists of lists keep their type")
+
+	labels := schema.Block.NestedBlocks["inner"].Block.Attributes["labels"].AttributeNestedType
+	require.NotNil(t, labels)
+	assert.Equal(t, tfjson.SchemaNestingModeMap, labels.NestingMode)
+
+	cached, err := s.GetResourceSchema(req, "example_thing")
+	require.NoError(t, err)
+	assert.Nil(t, cached.Block.Attributes["settings"].AttributeNestedType, "the cached schema is unchanged")
+	assert.True(t, cached.Block.Attributes["settings"].AttributeType.IsObjectType())
+}
+
+func TestServer_WithNestedObjectTypes_WithoutDescriptions(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	schema, err := s.GetResourceSchema(Request{Namespace: "example", Name: "example", Version: "1.0.0"}, "example_thing", WithNestedObjectTypes(), WithoutDescriptions())
+	require.NoError(t, err)
+	assert.Empty(t, schema.Block.Description)
+	assert.Equal(t, cty.String, schema.Block.Attributes["name"].AttributeType)
+}
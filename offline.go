@@ -0,0 +1,66 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// ErrOffline is returned when a Server created with WithOffline needs the
+// network to answer a request.
+var ErrOffline = errors.New("offline: not available from the local cache")
+
+// WithOffline stops the Server from making any network request. Providers
+// are served from the on-disk cache, registry API responses from those
+// stored for revalidation, and version constraints are resolved against the
+// versions in the cache when the versions listing is not stored. Anything
+// else fails with ErrOffline. This suits pre-warmed caches in air-gapped
+// environments. Offline mode takes precedence over WithForceFetch.
+func WithOffline(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.offline = enabled
+	}
+}
+
+// cachedVersions returns the versions of the provider present in the
+// on-disk cache for the current platform or in the schema bundle, sorted in
+// ascending order.
+func (s *Server) cachedVersions(req VersionsRequest) (goversion.Collection, error) {
+	key := normalizedRequest(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType})
+	dir := filepath.Join(
+		s.cacheDir,
+		cachePathSegment(string(key.RegistryType)),
+		cachePathSegment(key.Namespace),
+		providerFileNamePrefix+cachePathSegment(key.Name),
+	)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	versions, err := s.bundledVersions(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		v, err := goversion.NewVersion(e.Name())
+		if err != nil {
+			continue
+		}
+		if _, ok := findProviderBinary(filepath.Join(dir, e.Name(), runtime.GOOS+"_"+runtime.GOARCH), key.Name); ok {
+			versions = append(versions, v)
+		}
+	}
+	slices.SortFunc(versions, func(a, b *goversion.Version) int {
+		return a.Compare(b)
+	})
+	return slices.CompactFunc(versions, (*goversion.Version).Equal), nil
+}
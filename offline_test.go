@@ -0,0 +1,71 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRegistryClient returns a client that fails the test if it is used.
+func failingRegistryClient(t *testing.T) *http.Client {
+	return stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request while offline: %s", r.URL)
+	}))
+}
+
+func TestServer_Offline_ServesCache(t *testing.T) {
+	cacheRoot := t.TempDir()
+	writeFakeProviderBinary(t, cacheRoot, Request{Namespace: "hashicorp", Name: "aws", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu})
+	writeFakeProviderBinary(t, cacheRoot, Request{Namespace: "hashicorp", Name: "aws", Version: "1.10.0", RegistryType: RegistryTypeOpenTofu})
+
+	s := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(failingRegistryClient(t)), WithOffline(true), WithForceFetch(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "1.10.0", versions[1].String())
+
+	require.NoError(t, s.Get(Request{Namespace: "hashicorp", Name: "aws", Version: "~> 1.2"}))
+
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "1.2.3"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusHit, plan.CacheStatus)
+}
+
+func TestServer_Offline_CacheMiss(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithOffline(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	assert.ErrorIs(t, err, ErrOffline)
+
+	err = s.Get(Request{Namespace: "hashicorp", Name: "aws", Version: "1.2.3"})
+	assert.ErrorIs(t, err, ErrOffline)
+
+	_, err = s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "1.2.3"})
+	assert.ErrorIs(t, err, ErrOffline)
+
+	_, err = s.ListProviders(ProvidersRequest{Namespace: "hashicorp"})
+	assert.ErrorIs(t, err, ErrOffline)
+}
+
+func TestServer_Offline_StoredRegistryResponse(t *testing.T) {
+	cacheRoot := t.TempDir()
+	online := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"versions":[{"version":"2.0.0"}]}`))
+	}))))
+	t.Cleanup(func() { _ = online.Cleanup() })
+	_, err := online.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+
+	s := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(failingRegistryClient(t)), WithOffline(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, "2.0.0", versions[0].String())
+}
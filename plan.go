@@ -0,0 +1,94 @@
+package tfpluginschema
+
+import "fmt"
+
+// DownloadPlan describes what Server.Get would do for a request, without
+// downloading anything.
+type DownloadPlan struct {
+	Request     Request     // Request with alias, registry type and version resolved
+	CacheStatus CacheStatus // CacheStatusHit if Get would be served from the cache
+	CachePath   string      // Path of the cached provider binary, set on a cache hit
+	URL         string      // Archive download URL, set on a cache miss
+	FileName    string      // Archive file name reported by the registry, set on a cache miss
+	SHASum      string      // SHA-256 of the archive reported by the registry, if any
+	Size        int64       // Archive size in bytes, or -1 if unknown
+}
+
+// Plan resolves request exactly as Get would and reports what Get would
+// download, without downloading or extracting anything. It is intended for
+// policy checks and for pre-computing progress totals. A cache entry Get would download again, because it does not match
+// Request.Hashes or is unverified under WithRequireVerification, is a miss.
+//
+// On a cache hit the registry download endpoint is not queried, mirroring
+// Get, so URL and FileName are empty and Size is 0. On a cache miss the
+// archive size is obtained with a HEAD request to the download URL; if the
+// host does not report a length, Size is -1.
+//
+// The download endpoint's response is not stored for revalidation, unlike
+// Get's. Resolving a version constraint still queries the registry's
+// versions endpoint, and that listing is cached, in memory and under the
+// cache directory, as it would be for Get. Plan with a fixed version
+// writes nothing.
+func (s *Server) Plan(request Request) (DownloadPlan, error) {
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return DownloadPlan{}, err
+	}
+	plan := DownloadPlan{Request: request, CacheStatus: CacheStatusMiss, Size: -1}
+	key := cacheKey(request)
+
+	if !s.forceFetch || s.offline {
+		s.mu.RLock()
+		path, ok := s.dlc[key]
+		s.mu.RUnlock()
+		if ok {
+			// Get fails rather than downloading again if the provider in
+			// use does not match the request's hashes.
+			if err := checkRecordedHashes(cacheProviderDir(s.cacheDir, request), request.Hashes); err != nil {
+				return DownloadPlan{}, err
+			}
+			plan.CacheStatus, plan.CachePath, plan.Size = CacheStatusHit, path, 0
+			return plan, nil
+		}
+		extractDir := cacheProviderDir(s.cacheDir, request)
+		if _, found := findProviderBinary(extractDir, request.Name); !found {
+			// Get moves an entry from the legacy layout rather than
+			// downloading it again.
+			if legacy := legacyCacheProviderDir(s.cacheDir, request); legacy != extractDir {
+				extractDir = legacy
+			}
+		}
+		if err := checkWithinBaseDir(s.cacheDir, extractDir); err != nil {
+			return DownloadPlan{}, err
+		}
+		path, staleErr := s.cachedProvider(request, extractDir)
+		if staleErr != nil && s.offline {
+			return DownloadPlan{}, staleErr
+		}
+		if path != "" {
+			plan.CacheStatus, plan.CachePath, plan.Size = CacheStatusHit, path, 0
+			return plan, nil
+		}
+	}
+
+	if s.offline {
+		return DownloadPlan{}, fmt.Errorf("%w: provider %s/%s %s", ErrOffline, request.Namespace, request.Name, request.Version)
+	}
+
+	rl := s.logger(logComponentRegistry).With(s.requestLogAttrs(request)...)
+	pluginResponse, err := s.queryDownloadMetadata(request, rl, false)
+	if err != nil {
+		return DownloadPlan{}, err
+	}
+	plan.URL = pluginResponse.DownloadURL
+	plan.FileName = pluginResponse.FileName
+	plan.SHASum = pluginResponse.SHASum
+
+	info, err := s.probeDownload(plan.URL)
+	if err != nil {
+		return DownloadPlan{}, err
+	}
+	plan.Size = info.size
+	rl.Debug("Planned provider download", "url", plan.URL, logKeyBytes, plan.Size)
+	return plan, nil
+}
@@ -0,0 +1,161 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Plan_CacheMiss(t *testing.T) {
+	var methods []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/5.0.0/download/" + runtime.GOOS + "/" + runtime.GOARCH:
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `{"filename":"terraform-provider-aws_5.0.0.zip","download_url":"https://releases.example.com/aws.zip","shasum":"7d1507284a5757cac6b62708a4ef00bfc5d695256489cb704f12b4b9e6255df2"}`)
+		case "/aws.zip":
+			w.Header().Set("Content-Length", "1234")
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+
+	cacheDir := t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeTerraform})
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusMiss, plan.CacheStatus)
+	assert.Equal(t, "https://releases.example.com/aws.zip", plan.URL)
+	assert.Equal(t, "terraform-provider-aws_5.0.0.zip", plan.FileName)
+	assert.Equal(t, "7d1507284a5757cac6b62708a4ef00bfc5d695256489cb704f12b4b9e6255df2", plan.SHASum)
+	assert.Equal(t, int64(1234), plan.Size)
+	assert.Empty(t, plan.CachePath)
+	assert.Equal(t, http.MethodHead+" /aws.zip", methods[len(methods)-1])
+
+	// Nothing was downloaded or cached.
+	s.mu.RLock()
+	assert.Empty(t, s.dlc)
+	s.mu.RUnlock()
+	assert.NoDirExists(t, filepath.Join(cacheDir, registryResponseCacheDir), "the download endpoint's response is not stored")
+}
+
+func TestServer_Plan_CacheHitSkipsRegistry(t *testing.T) {
+	cacheRoot := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeOpenTofu}
+	bin := writeFakeProviderBinary(t, cacheRoot, req)
+
+	client := stubRegistryClient(t, http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	}))
+	s := NewServer(nil, WithCacheDir(cacheRoot), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	plan, err := s.Plan(req)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusHit, plan.CacheStatus)
+	assert.Equal(t, bin, plan.CachePath)
+	assert.Zero(t, plan.Size)
+	assert.Empty(t, plan.URL)
+}
+
+func TestServer_Plan_ResolvesVersion(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/versions":
+			fmt.Fprint(w, `{"versions":[{"version":"4.0.0"},{"version":"5.1.0"}]}`)
+		case "/v1/providers/hashicorp/aws/5.1.0/download/" + runtime.GOOS + "/" + runtime.GOARCH:
+			fmt.Fprint(w, `{"filename":"aws.zip","download_url":"https://releases.example.com/aws.zip"}`)
+		case "/aws.zip":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "~>5.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "5.1.0", plan.Request.Version)
+	assert.Equal(t, RegistryTypeOpenTofu, plan.Request.RegistryType)
+	assert.Equal(t, int64(-1), plan.Size)
+}
+
+func TestServer_Plan_NotFound(t *testing.T) {
+	client := stubRegistryClient(t, http.NotFoundHandler())
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.Plan(Request{Namespace: "hashicorp", Name: "nope", Version: "1.0.0"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrPluginNotFound))
+}
+
+func TestServer_Plan_MatchesGetForUnusableEntries(t *testing.T) {
+	archive := providerArchive(t, "null")
+	client := stubSignedRegistry(t, archive, nil, nil)
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	cacheDir := t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		pinned := req
+		pinned.Hashes = []string{"zh:" + sha256Hex([]byte("other"))}
+		fresh := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+		t.Cleanup(func() { _ = fresh.Cleanup() })
+		plan, err := fresh.Plan(pinned)
+		require.NoError(t, err)
+		assert.Equal(t, CacheStatusMiss, plan.CacheStatus)
+		assert.Equal(t, "https://releases.example.com/archive.zip", plan.URL)
+
+		_, err = s.Plan(pinned)
+		assert.ErrorIs(t, err, ErrHashMismatch, "the provider in use does not match")
+		offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true))
+		t.Cleanup(func() { _ = offline.Cleanup() })
+		_, err = offline.Plan(pinned)
+		assert.ErrorIs(t, err, ErrHashMismatch)
+	})
+
+	t.Run("unverified", func(t *testing.T) {
+		strict := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client), WithRequireVerification(true))
+		t.Cleanup(func() { _ = strict.Cleanup() })
+		plan, err := strict.Plan(req)
+		require.NoError(t, err)
+		assert.Equal(t, CacheStatusMiss, plan.CacheStatus)
+
+		offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true), WithRequireVerification(true))
+		t.Cleanup(func() { _ = offline.Cleanup() })
+		_, err = offline.Plan(req)
+		assert.ErrorIs(t, err, ErrVerificationFailed)
+	})
+
+	t.Run("usable", func(t *testing.T) {
+		fresh := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+		t.Cleanup(func() { _ = fresh.Cleanup() })
+		plan, err := fresh.Plan(req)
+		require.NoError(t, err)
+		assert.Equal(t, CacheStatusHit, plan.CacheStatus)
+	})
+}
+
+func TestServer_Plan_CreatesNoDirectories(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubSignedRegistry(t, providerArchive(t, "null"), nil, nil)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	plan, err := s.Plan(Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheStatusMiss, plan.CacheStatus)
+	assert.NoDirExists(t, cacheDir)
+}
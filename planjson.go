@@ -0,0 +1,156 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// planDoc holds the parts of "terraform show -json" output that name
+// providers. The same command describes saved plans and state; plans carry
+// configuration, prior_state and planned_values, state carries values.
+type planDoc struct {
+	Configuration *struct {
+		ProviderConfig map[string]struct {
+			FullName          string `json:"full_name"`
+			VersionConstraint string `json:"version_constraint"`
+		} `json:"provider_config"`
+	} `json:"configuration"`
+	PlannedValues *planValues `json:"planned_values"`
+	PriorState    *struct {
+		Values *planValues `json:"values"`
+	} `json:"prior_state"`
+	Values          *planValues     `json:"values"`
+	ProviderSchemas json.RawMessage `json:"provider_schemas"`
+}
+
+type planValues struct {
+	RootModule *planModule `json:"root_module"`
+}
+
+type planModule struct {
+	Resources []struct {
+		ProviderName string `json:"provider_name"`
+	} `json:"resources"`
+	ChildModules []*planModule `json:"child_modules"`
+}
+
+// ProvidersFromPlanJSON reads the output of "terraform show -json" for a
+// saved plan or for the state, or its OpenTofu equivalent, and returns a
+// Request for every provider it uses, sorted by registry, namespace and
+// name. The output does not record the versions that were installed; for
+// plans, Version holds the configuration's version constraints, joined so
+// that a later fetch resolves the newest version satisfying all of them.
+// For state, Version is empty. The built-in terraform provider is left out.
+func ProvidersFromPlanJSON(r io.Reader) ([]Request, error) {
+	var doc planDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to read plan JSON: %w", err)
+	}
+	return doc.requests()
+}
+
+// LoadPlanJSON is ProvidersFromPlanJSON that also loads the document's
+// provider_schemas section, when it has one, into the Server as
+// LoadTerraformSchemaJSON does.
+func (s *Server) LoadPlanJSON(r io.Reader) ([]Request, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan JSON: %w", err)
+	}
+	var doc planDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read plan JSON: %w", err)
+	}
+	if len(doc.ProviderSchemas) > 0 && !bytes.Equal(doc.ProviderSchemas, []byte("null")) {
+		if err := s.LoadTerraformSchemaJSON(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+	return doc.requests()
+}
+
+// requests returns the providers named in doc, with their constraints.
+func (doc *planDoc) requests() ([]Request, error) {
+	type entry struct {
+		req         Request
+		constraints []string
+	}
+	entries := make(map[providerKey]*entry)
+	add := func(source, constraint string) error {
+		if source == "" {
+			return nil
+		}
+		req, err := ParseProviderSource(source)
+		if errors.Is(err, ErrBuiltInProvider) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		key := cacheKey(req)
+		e, ok := entries[key]
+		if !ok {
+			e = &entry{req: req}
+			entries[key] = e
+		}
+		if constraint = strings.TrimSpace(constraint); constraint != "" && !slices.Contains(e.constraints, constraint) {
+			e.constraints = append(e.constraints, constraint)
+		}
+		return nil
+	}
+
+	if doc.Configuration != nil {
+		for _, pc := range doc.Configuration.ProviderConfig {
+			if err := add(pc.FullName, pc.VersionConstraint); err != nil {
+				return nil, err
+			}
+		}
+	}
+	values := []*planValues{doc.PlannedValues, doc.Values}
+	if doc.PriorState != nil {
+		values = append(values, doc.PriorState.Values)
+	}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if err := v.RootModule.walk(func(source string) error { return add(source, "") }); err != nil {
+			return nil, err
+		}
+	}
+
+	requests := make([]Request, 0, len(entries))
+	for _, e := range entries {
+		slices.Sort(e.constraints)
+		e.req.Version = strings.Join(e.constraints, ", ")
+		requests = append(requests, e.req)
+	}
+	slices.SortFunc(requests, func(a, b Request) int {
+		return strings.Compare(cacheKey(a).String(), cacheKey(b).String())
+	})
+	return requests, nil
+}
+
+// walk calls fn with the provider of every resource in m and its child
+// modules.
+func (m *planModule) walk(fn func(source string) error) error {
+	if m == nil {
+		return nil
+	}
+	for _, r := range m.Resources {
+		if err := fn(r.ProviderName); err != nil {
+			return err
+		}
+	}
+	for _, c := range m.ChildModules {
+		if err := c.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
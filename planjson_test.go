@@ -0,0 +1,75 @@
+package tfpluginschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const planJSON = `{
+	"format_version": "1.2",
+	"terraform_version": "1.9.0",
+	"configuration": {
+		"provider_config": {
+			"aws": {"name": "aws", "full_name": "registry.terraform.io/hashicorp/aws", "version_constraint": "~> 5.0"},
+			"module.net:aws": {"name": "aws", "full_name": "registry.terraform.io/hashicorp/aws", "module_address": "module.net", "version_constraint": ">= 5.10"},
+			"random": {"name": "random", "full_name": "registry.terraform.io/hashicorp/random"}
+		}
+	},
+	"planned_values": {"root_module": {
+		"resources": [{"address": "terraform_data.x", "provider_name": "terraform.io/builtin/terraform"}],
+		"child_modules": [{"address": "module.net", "resources": [
+			{"address": "module.net.azurerm_vnet.x", "provider_name": "registry.opentofu.org/hashicorp/azurerm"}
+		]}]
+	}}
+}`
+
+func TestProvidersFromPlanJSON(t *testing.T) {
+	requests, err := ProvidersFromPlanJSON(strings.NewReader(planJSON))
+	require.NoError(t, err)
+	assert.Equal(t, []Request{
+		{Namespace: "hashicorp", Name: "azurerm", RegistryType: RegistryTypeOpenTofu},
+		{Namespace: "hashicorp", Name: "aws", Version: ">= 5.10, ~> 5.0", RegistryType: RegistryTypeTerraform},
+		{Namespace: "hashicorp", Name: "random", RegistryType: RegistryTypeTerraform},
+	}, requests)
+}
+
+func TestProvidersFromPlanJSON_State(t *testing.T) {
+	state := `{"format_version": "1.0", "values": {"root_module": {"resources": [
+		{"address": "aws_s3_bucket.b", "provider_name": "registry.terraform.io/hashicorp/aws"},
+		{"address": "aws_s3_bucket.c", "provider_name": "registry.terraform.io/hashicorp/aws"}
+	]}}}`
+	requests, err := ProvidersFromPlanJSON(strings.NewReader(state))
+	require.NoError(t, err)
+	assert.Equal(t, []Request{{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform}}, requests)
+
+	_, err = ProvidersFromPlanJSON(strings.NewReader(`{"values": {"root_module": {"resources": [{"provider_name": "a/b/c/d"}]}}}`))
+	assert.Error(t, err)
+}
+
+func TestServer_LoadPlanJSON(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	doc := `{"format_version": "1.0",
+		"configuration": {"provider_config": {"aws": {"full_name": "registry.terraform.io/hashicorp/aws", "version_constraint": "5.40.0"}}},
+		"provider_schemas": {"registry.terraform.io/hashicorp/aws": {
+			"resource_schemas": {"aws_s3_bucket": {"version": 0, "block": {}}}
+		}}
+	}`
+
+	requests, err := s.LoadPlanJSON(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "5.40.0", requests[0].Version)
+
+	names, err := s.ListResources(requests[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_s3_bucket"}, names)
+
+	requests, err = s.LoadPlanJSON(strings.NewReader(planJSON))
+	require.NoError(t, err)
+	assert.Len(t, requests, 3)
+}
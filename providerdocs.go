@@ -0,0 +1,120 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// terraformDocsURL is the base URL of the Terraform registry's
+// documentation pages.
+const terraformDocsURL = "https://registry.terraform.io/v2/provider-docs"
+
+// ErrDocNotFound is returned by GetResourceDoc when no documentation page is
+// published for the resource.
+var ErrDocNotFound = errors.New("documentation page not found")
+
+// GetResourceDoc returns the Markdown documentation page of resource, a
+// resource type such as "aws_instance", as published for the provider
+// version selected by request. For the Terraform registry the page comes
+// from its documentation API. For other registries it is read from the
+// provider's GitHub repository at the release tag, where the registries
+// publish it from: docs/resources/<name>.md, or the older
+// website/docs/r/<name>.html.markdown. The repository is found as for
+// GetChangelog.
+//
+// The page is written for people; docgen.Examples extracts its examples.
+func (s *Server) GetResourceDoc(request Request, resource string) (string, error) {
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return "", err
+	}
+	slug := strings.TrimPrefix(resource, request.Name+"_")
+	if err := validateCachePathComponent("resource", slug, true); err != nil {
+		return "", fmt.Errorf("invalid resource name: %w", err)
+	}
+
+	if request.RegistryType == RegistryTypeTerraform {
+		page, ok, err := s.terraformResourceDoc(request, slug)
+		if err != nil || ok {
+			return page, err
+		}
+	}
+
+	owner, repo, err := s.providerRepository(request)
+	if errors.Is(err, errNotGitHub) {
+		return "", fmt.Errorf("%w: %w", ErrDocNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, path := range []string{"docs/resources/" + slug + ".md", "website/docs/r/" + slug + ".html.markdown"} {
+		var page string
+		found, err := s.githubGet(fmt.Sprintf("%s/%s/%s/%s/%s", githubRawURL, owner, repo, url.PathEscape("v"+request.Version), path), nil, &page)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return page, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s of %s/%s %s", ErrDocNotFound, resource, request.Namespace, request.Name, request.Version)
+}
+
+// terraformResourceDoc looks the resource page with slug up in the list of
+// documentation pages of the provider version and fetches it from the
+// Terraform registry's documentation API. It reports false if the registry
+// does not know the version or lists no such page.
+func (s *Server) terraformResourceDoc(request Request, slug string) (string, bool, error) {
+	u := request.RegistryType.BaseURL() + "/" + request.Namespace + "/" + request.Name + "/" + request.Version
+	body, status, err := s.registryGet(u)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get provider documentation: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if status != http.StatusOK {
+		return "", false, fmt.Errorf("%w: %s => %d", ErrPluginApi, u, status)
+	}
+	var version struct {
+		Docs []struct {
+			ID       string `json:"id"`
+			Slug     string `json:"slug"`
+			Category string `json:"category"`
+			Language string `json:"language"`
+		} `json:"docs"`
+	}
+	if err := decodeRegistryJSON(body, &version, "provider version"); err != nil {
+		return "", false, fmt.Errorf("%s: %w", u, err)
+	}
+
+	for _, d := range version.Docs {
+		if d.Category != "resources" || d.Slug != slug || (d.Language != "" && d.Language != "hcl") {
+			continue
+		}
+		u := terraformDocsURL + "/" + url.PathEscape(d.ID)
+		body, status, err := s.registryGet(u)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get provider documentation: %w", err)
+		}
+		if status != http.StatusOK {
+			return "", false, fmt.Errorf("%w: %s => %d", ErrPluginApi, u, status)
+		}
+		var doc struct {
+			Data struct {
+				Attributes struct {
+					Content string `json:"content"`
+				} `json:"attributes"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return "", false, fmt.Errorf("failed to decode provider documentation from %s: %w", u, err)
+		}
+		return doc.Data.Attributes.Content, true, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,55 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_GetResourceDoc(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/example/cloud/2.0.0":
+			fmt.Fprint(w, `{"docs":[
+				{"id":"10","slug":"widget","category":"data-sources","language":"hcl"},
+				{"id":"11","slug":"widget","category":"resources","language":"python"},
+				{"id":"12","slug":"widget","category":"resources","language":"hcl"}
+			]}`)
+		case "/v2/provider-docs/12":
+			fmt.Fprint(w, `{"data":{"id":"12","attributes":{"content":"# example_widget from the registry"}}}`)
+		case "/v1/providers/example/cloud":
+			fmt.Fprint(w, `{"source":"https://github.com/example/terraform-provider-cloud"}`)
+		case "/example/terraform-provider-cloud/v2.0.0/website/docs/r/gadget.html.markdown":
+			fmt.Fprint(w, "# cloud_gadget from GitHub")
+		case "/example/terraform-provider-tofu/v1.0.0/docs/resources/thing.md":
+			fmt.Fprint(w, "# tofu_thing from GitHub")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	terraform := Request{Namespace: "example", Name: "cloud", Version: "2.0.0", RegistryType: RegistryTypeTerraform}
+
+	page, err := s.GetResourceDoc(terraform, "cloud_widget")
+	require.NoError(t, err)
+	assert.Equal(t, "# example_widget from the registry", page)
+
+	page, err = s.GetResourceDoc(terraform, "cloud_gadget")
+	require.NoError(t, err, "pages the registry does not list are read from GitHub")
+	assert.Equal(t, "# cloud_gadget from GitHub", page)
+
+	page, err = s.GetResourceDoc(Request{Namespace: "example", Name: "tofu", Version: "1.0.0"}, "tofu_thing")
+	require.NoError(t, err)
+	assert.Equal(t, "# tofu_thing from GitHub", page)
+
+	_, err = s.GetResourceDoc(terraform, "cloud_missing")
+	require.ErrorIs(t, err, ErrDocNotFound)
+	assert.ErrorContains(t, err, "cloud_missing of example/cloud 2.0.0")
+
+	_, err = s.GetResourceDoc(terraform, "cloud_../x")
+	assert.ErrorContains(t, err, "invalid resource name")
+}
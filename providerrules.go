@@ -0,0 +1,79 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ErrProviderBlockedByPolicy is returned for providers that the rules set
+// with WithProviderRules do not permit.
+var ErrProviderBlockedByPolicy = errors.New("provider blocked by policy")
+
+// ProviderRules permit or block providers by address. Patterns are globs,
+// as understood by path.Match, over "host/namespace/name", such as
+// "registry.terraform.io/hashicorp/*" or "*/example-corp/*"; "*" does not
+// match across "/". Matching is case-insensitive.
+//
+// A provider matching any Deny pattern is blocked. Otherwise, if Allow is
+// not empty, a provider must match one of its patterns.
+type ProviderRules struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Validate reports the first malformed pattern in r.
+func (r ProviderRules) Validate() error {
+	for _, p := range slices.Concat(r.Allow, r.Deny) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid provider rule %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// WithProviderRules makes the Server refuse, with ErrProviderBlockedByPolicy,
+// to resolve, download or read the schema of any provider that rules do not
+// permit, for organizations that only allow vetted providers. The check
+// happens before the registry is contacted. A malformed pattern blocks
+// every provider; check rules with Validate first.
+func WithProviderRules(rules ProviderRules) ServerOption {
+	return func(s *Server) {
+		s.providerRules = rules
+	}
+}
+
+// checkProviderRules returns ErrProviderBlockedByPolicy if request, whose
+// RegistryType must be normalized, is not permitted by the provider rules.
+func (s *Server) checkProviderRules(request Request) error {
+	rules := s.providerRules
+	if len(rules.Allow) == 0 && len(rules.Deny) == 0 {
+		return nil
+	}
+	if err := rules.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrProviderBlockedByPolicy, err)
+	}
+	address := strings.ToLower(request.RegistryType.host() + "/" + request.Namespace + "/" + request.Name)
+	if pattern, ok := matchProviderRule(rules.Deny, address); ok {
+		return fmt.Errorf("%w: %s matches deny rule %q", ErrProviderBlockedByPolicy, address, pattern)
+	}
+	if len(rules.Allow) == 0 {
+		return nil
+	}
+	if _, ok := matchProviderRule(rules.Allow, address); !ok {
+		return fmt.Errorf("%w: %s matches no allow rule", ErrProviderBlockedByPolicy, address)
+	}
+	return nil
+}
+
+// matchProviderRule returns the first of patterns matching address.
+func matchProviderRule(patterns []string, address string) (string, bool) {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), address); ok {
+			return p, true
+		}
+	}
+	return "", false
+}
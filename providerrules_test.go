@@ -0,0 +1,62 @@
+package tfpluginschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_CheckProviderRules(t *testing.T) {
+	rules := ProviderRules{
+		Allow: []string{"registry.terraform.io/hashicorp/*", "*/Example-Corp/*"},
+		Deny:  []string{"*/hashicorp/external"},
+	}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithProviderRules(rules))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	tests := map[string]struct {
+		req     Request
+		blocked string
+	}{
+		"allowed":          {req: Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform}},
+		"case-insensitive": {req: Request{Namespace: "example-corp", Name: "Cloud", RegistryType: RegistryTypeOpenTofu}},
+		"denied":           {req: Request{Namespace: "hashicorp", Name: "external", RegistryType: RegistryTypeTerraform}, blocked: `matches deny rule "*/hashicorp/external"`},
+		"other registry":   {req: Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}, blocked: "registry.opentofu.org/hashicorp/aws matches no allow rule"},
+		"not allowed":      {req: Request{Namespace: "someone", Name: "aws", RegistryType: RegistryTypeTerraform}, blocked: "matches no allow rule"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := s.checkProviderRules(tt.req)
+			if tt.blocked == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrProviderBlockedByPolicy)
+			assert.ErrorContains(t, err, tt.blocked)
+		})
+	}
+}
+
+func TestServer_ProviderRules_BlockBeforeRegistry(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithProviderRules(ProviderRules{Deny: []string{"*/*/*"}}))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetResourceSchema(Request{Namespace: "hashicorp", Name: "aws"}, "aws_instance")
+	assert.ErrorIs(t, err, ErrProviderBlockedByPolicy)
+
+	_, err = s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	assert.ErrorIs(t, err, ErrProviderBlockedByPolicy)
+}
+
+func TestProviderRules_Validate(t *testing.T) {
+	assert.NoError(t, ProviderRules{Allow: []string{"*/hashicorp/*"}}.Validate())
+
+	rules := ProviderRules{Deny: []string{"["}}
+	assert.ErrorContains(t, rules.Validate(), `invalid provider rule "["`)
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithProviderRules(rules))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	err := s.checkProviderRules(Request{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})
+	assert.ErrorIs(t, err, ErrProviderBlockedByPolicy, "malformed rules block every provider")
+}
@@ -0,0 +1,172 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxProviderListPages bounds how many pages ListProviders will follow, as a
+// guard against registries returning a pagination cursor that never ends.
+const maxProviderListPages = 1000
+
+// ProvidersRequest identifies a registry namespace whose providers should be
+// listed.
+type ProvidersRequest struct {
+	Namespace    string       // Namespace to enumerate (e.g., "hashicorp")
+	RegistryType RegistryType // Registry to use (defaults to OpenTofu if not specified)
+}
+
+// String returns the URL of the first page of the provider listing endpoint
+// for display. It does not validate the request; use URL for that.
+func (p ProvidersRequest) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(p.RegistryType.BaseURL())
+	sb.WriteRune(urlPathSeparator)
+	sb.WriteString(p.Namespace)
+	return sb.String()
+}
+
+// URL returns the URL of the first page of the provider listing endpoint, or
+// an error if Namespace is empty or not safe in a URL path segment.
+func (p ProvidersRequest) URL() (string, error) {
+	if err := validateCachePathComponent("namespace", p.Namespace, true); err != nil {
+		return "", fmt.Errorf("invalid providers request: %w", err)
+	}
+	return p.String(), nil
+}
+
+// ProviderInfo describes a provider returned by the registry listing API.
+type ProviderInfo struct {
+	Namespace    string       // Namespace of the provider (e.g., "hashicorp")
+	Name         string       // Name of the provider (e.g., "aws")
+	Version      string       // Latest version advertised by the registry
+	Description  string       // Short description, if the registry provides one
+	Source       string       // Source repository URL, if the registry provides one
+	Tier         string       // Registry tier (e.g., "official", "partner"), if provided
+	Downloads    int64        // Download count, if the registry provides one
+	RegistryType RegistryType // Registry the provider was listed from
+}
+
+// Request returns a Request for this provider, pinned to the listed version.
+// It is a convenience for batch-fetching schemas after enumeration.
+func (p ProviderInfo) Request() Request {
+	return Request{
+		Namespace:    p.Namespace,
+		Name:         p.Name,
+		Version:      p.Version,
+		RegistryType: p.RegistryType,
+	}
+}
+
+type pluginApiProvidersResponse struct {
+	Meta struct {
+		Limit         int `json:"limit"`
+		CurrentOffset int `json:"current_offset"`
+		NextOffset    int `json:"next_offset"`
+	} `json:"meta"`
+	Providers []struct {
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+		Source      string `json:"source"`
+		Tier        string `json:"tier"`
+		Downloads   int64  `json:"downloads"`
+	} `json:"providers"`
+}
+
+// ListProviders enumerates every provider published under req.Namespace,
+// following the registry's offset-based pagination until all pages have been
+// read. Results are sorted by name.
+//
+// Not every registry implements the listing endpoint; a 404 is reported as
+// ErrPluginNotFound.
+func (s *Server) ListProviders(req ProvidersRequest) ([]ProviderInfo, error) {
+	if err := validateCachePathComponent("namespace", req.Namespace, true); err != nil {
+		return nil, fmt.Errorf("invalid providers request: %w", err)
+	}
+	req.RegistryType = normalizedRegistryType(s.registryOrDefault(req.RegistryType))
+
+	l := s.logger(logComponentRegistry).With("namespace", req.Namespace, logKeyRegistry, s.registryOrDefault(req.RegistryType).host())
+
+	var providers []ProviderInfo
+	offset := 0
+	for page := 0; ; page++ {
+		if page >= maxProviderListPages {
+			return nil, fmt.Errorf("%w: provider listing for %s exceeded %d pages", ErrPluginApi, req.Namespace, maxProviderListPages)
+		}
+
+		pageURL, err := req.URL()
+		if err != nil {
+			return nil, err
+		}
+		if pageURL, err = s.registryURL(req.RegistryType, pageURL); err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			pageURL += "?offset=" + url.QueryEscape(strconv.Itoa(offset))
+		}
+		l.Debug("Listing providers", "url", pageURL)
+
+		result, err := s.fetchProvidersPage(pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range result.Providers {
+			providers = append(providers, ProviderInfo{
+				Namespace:    p.Namespace,
+				Name:         p.Name,
+				Version:      p.Version,
+				Description:  p.Description,
+				Source:       p.Source,
+				Tier:         p.Tier,
+				Downloads:    p.Downloads,
+				RegistryType: req.RegistryType,
+			})
+		}
+
+		// Stop when the registry reports no further page, or when the cursor
+		// fails to advance (which would otherwise loop forever).
+		next := result.Meta.NextOffset
+		if len(result.Providers) == 0 || next <= offset || next <= result.Meta.CurrentOffset {
+			break
+		}
+		offset = next
+	}
+
+	slices.SortFunc(providers, func(a, b ProviderInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	l.Info("Listed providers", "count", len(providers))
+	return providers, nil
+}
+
+// fetchProvidersPage retrieves and decodes a single page of the provider
+// listing endpoint, through registryGet, so that pages are size-bounded and
+// stored for revalidation like other registry responses.
+func (s *Server) fetchProvidersPage(pageURL string) (pluginApiProvidersResponse, error) {
+	var result pluginApiProvidersResponse
+
+	body, status, err := s.registryGet(pageURL)
+	if err != nil {
+		return result, fmt.Errorf("failed to list providers: %w", err)
+	}
+	if status == http.StatusNotFound {
+		return result, fmt.Errorf("%w: %s", ErrPluginNotFound, pageURL)
+	}
+	if status != http.StatusOK {
+		return result, fmt.Errorf("%w: %s => %d", ErrPluginApi, pageURL, status)
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return result, fmt.Errorf("failed to decode provider listing response: %w", err)
+	}
+	return result, nil
+}
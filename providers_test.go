@@ -0,0 +1,131 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvidersRequest_String(t *testing.T) {
+	assert.Equal(t, "https://registry.terraform.io/v1/providers/hashicorp",
+		ProvidersRequest{Namespace: "hashicorp", RegistryType: RegistryTypeTerraform}.String())
+	assert.Equal(t, "https://registry.opentofu.org/v1/providers/hashicorp",
+		ProvidersRequest{Namespace: "hashicorp"}.String())
+}
+
+func TestProvidersRequest_URL(t *testing.T) {
+	u, err := ProvidersRequest{Namespace: "hashicorp"}.URL()
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.opentofu.org/v1/providers/hashicorp", u)
+
+	_, err = ProvidersRequest{Namespace: "../etc"}.URL()
+	assert.Error(t, err)
+}
+
+func TestServer_ListProviders_FollowsPagination(t *testing.T) {
+	var offsets []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/providers/hashicorp", r.URL.Path)
+		offset := r.URL.Query().Get("offset")
+		offsets = append(offsets, offset)
+		switch offset {
+		case "":
+			fmt.Fprint(w, `{"meta":{"limit":2,"current_offset":0,"next_offset":2},"providers":[
+				{"namespace":"hashicorp","name":"random","version":"3.6.0","tier":"official"},
+				{"namespace":"hashicorp","name":"aws","version":"5.0.0","description":"AWS"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"meta":{"limit":2,"current_offset":2},"providers":[
+				{"namespace":"hashicorp","name":"azurerm","version":"4.0.0","downloads":42}]}`)
+		default:
+			t.Errorf("unexpected offset %q", offset)
+		}
+	}))
+
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, err := s.ListProviders(ProvidersRequest{Namespace: "hashicorp", RegistryType: RegistryTypeTerraform})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "2"}, offsets)
+	require.Len(t, got, 3)
+	assert.Equal(t, []string{"aws", "azurerm", "random"}, []string{got[0].Name, got[1].Name, got[2].Name})
+	assert.Equal(t, "AWS", got[0].Description)
+	assert.Equal(t, int64(42), got[1].Downloads)
+	assert.Equal(t, "official", got[2].Tier)
+	assert.Equal(t, Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeTerraform}, got[0].Request())
+}
+
+func TestServer_ListProviders_StopsWhenCursorDoesNotAdvance(t *testing.T) {
+	calls := 0
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"meta":{"current_offset":0,"next_offset":0},"providers":[{"namespace":"n","name":"p","version":"1.0.0"}]}`)
+	}))
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	got, err := s.ListProviders(ProvidersRequest{Namespace: "n"})
+	require.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1, calls)
+}
+
+func TestServer_ListProviders_NotFound(t *testing.T) {
+	client := stubRegistryClient(t, http.NotFoundHandler())
+	s := NewServer(nil, WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.ListProviders(ProvidersRequest{Namespace: "n"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrPluginNotFound))
+}
+
+func TestServer_ListProviders_TooLarge(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.Copy(w, io.LimitReader(neverEnding(' '), maxRegistryResponseSize+1))
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.ListProviders(ProvidersRequest{Namespace: "hashicorp"})
+	assert.ErrorContains(t, err, "larger than")
+}
+
+func TestServer_ListProviders_RevalidatesStoredPages(t *testing.T) {
+	var requests, notModified int
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"meta":{"limit":10,"current_offset":0},"providers":[{"namespace":"hashicorp","name":"aws","version":"5.0.0"}]}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	for range 2 {
+		got, err := s.ListProviders(ProvidersRequest{Namespace: "hashicorp"})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, "aws", got[0].Name)
+	}
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified, "the second listing is served from the stored page")
+}
+
+func TestServer_ListProviders_InvalidNamespace(t *testing.T) {
+	s := NewServer(nil)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.ListProviders(ProvidersRequest{Namespace: "../etc"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid providers request")
+}
@@ -0,0 +1,134 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RegistryTypeHCPTerraform is the private registry of HCP Terraform
+// (Terraform Cloud). Its providers are addressed as
+// app.terraform.io/<organization>/<name>, so Namespace holds the
+// organization. Requests need an API token; see WithRegistryToken and
+// WithTerraformCredentials.
+//
+// Any other registry host, such as a Terraform Enterprise installation, is
+// used the same way by converting its host name to a RegistryType:
+//
+//	RegistryType("tfe.example.com")
+const RegistryTypeHCPTerraform RegistryType = "app.terraform.io"
+
+// serviceDiscoveryPath is where a registry host publishes the base URLs of
+// the services it offers.
+const serviceDiscoveryPath = "/.well-known/terraform.json"
+
+// ParseRegistryType converts a registry name or host into a RegistryType.
+// "opentofu" and "terraform" and their registry hosts select the public
+// registries; any other host name, optionally with a port, selects that
+// host's private registry. Names are compared case-insensitively.
+func ParseRegistryType(s string) (RegistryType, error) {
+	r := RegistryType(strings.ToLower(strings.TrimSpace(s)))
+	switch r {
+	case RegistryTypeOpenTofu, RegistryTypeTerraform:
+		return r, nil
+	}
+	if rt, ok := registryHosts[string(r)]; ok {
+		return rt, nil
+	}
+	if _, ok := r.customHost(); ok {
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid registry %q: expected %q, %q or a registry host name", s, RegistryTypeOpenTofu, RegistryTypeTerraform)
+}
+
+// customHost returns the lower-cased host name r holds when it names a
+// registry host other than the public OpenTofu and Terraform registries.
+func (r RegistryType) customHost() (string, bool) {
+	host := strings.ToLower(string(r))
+	if !strings.ContainsAny(host, ".:") {
+		return "", false
+	}
+	if _, ok := registryHosts[host]; ok {
+		return "", false
+	}
+	u, err := url.Parse("https://" + host)
+	if err != nil || u.Host != host || u.Hostname() == "" {
+		return "", false
+	}
+	return host, true
+}
+
+// Services a registry host may advertise in its discovery document.
+const (
+	serviceProvidersV1 = "providers.v1"
+	serviceModulesV1   = "modules.v1"
+)
+
+// serviceNames describes each service in error messages.
+var serviceNames = map[string]string{
+	serviceProvidersV1: "provider registry",
+	serviceModulesV1:   "module registry",
+}
+
+// registryURL returns u, built for registry r from r.BaseURL, with the base
+// replaced by the provider registry URL the host advertises through service
+// discovery. URLs for the public registries are returned unchanged.
+func (s *Server) registryURL(r RegistryType, u string) (string, error) {
+	host, ok := r.customHost()
+	if !ok {
+		return u, nil
+	}
+	base, err := s.discoverServiceURL(host, serviceProvidersV1)
+	if err != nil {
+		return "", err
+	}
+	return base + strings.TrimPrefix(u, r.BaseURL()), nil
+}
+
+// discoverServiceURL returns the base URL host advertises for service, such
+// as "providers.v1", without a trailing slash. Results are kept for the
+// life of the Server.
+func (s *Server) discoverServiceURL(host, service string) (string, error) {
+	key := service + " " + host
+	s.mu.RLock()
+	base, ok := s.discovered[key]
+	s.mu.RUnlock()
+	if ok {
+		return base, nil
+	}
+
+	discoveryURL := "https://" + host + serviceDiscoveryPath
+	s.logger(logComponentRegistry).Debug("Discovering registry services", "url", discoveryURL)
+	body, status, err := s.registryGet(discoveryURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover services of registry %s: %w", host, err)
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("%w: service discovery %s => %d", ErrPluginApi, discoveryURL, status)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("failed to decode service discovery document of registry %s: %w", host, err)
+	}
+	advertised, _ := doc[service].(string)
+	if advertised == "" {
+		return "", fmt.Errorf("%w: registry %s does not offer the %s protocol", ErrPluginApi, host, serviceNames[service])
+	}
+
+	ref, err := url.Parse(advertised)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s URL %q from registry %s: %w", service, advertised, host, err)
+	}
+	resolved := (&url.URL{Scheme: "https", Host: host, Path: serviceDiscoveryPath}).ResolveReference(ref)
+	if resolved.Scheme != "https" {
+		return "", fmt.Errorf("invalid %s URL %q from registry %s: must use https", service, advertised, host)
+	}
+	base = strings.TrimSuffix(resolved.String(), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discovered[key] = base
+	return base, nil
+}
@@ -0,0 +1,81 @@
+package tfpluginschema
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegistryType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    RegistryType
+		wantErr bool
+	}{
+		{in: "opentofu", want: RegistryTypeOpenTofu},
+		{in: "Terraform", want: RegistryTypeTerraform},
+		{in: "registry.terraform.io", want: RegistryTypeTerraform},
+		{in: "registry.opentofu.org", want: RegistryTypeOpenTofu},
+		{in: "App.Terraform.io", want: RegistryTypeHCPTerraform},
+		{in: "tfe.example.com:8443", want: "tfe.example.com:8443"},
+		{in: "github", wantErr: true},
+		{in: "https://tfe.example.com", wantErr: true},
+		{in: "tfe.example.com/path", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRegistryType(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServer_RegistryHost_ServiceDiscovery(t *testing.T) {
+	var discoveries atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		discoveries.Add(1)
+		_, _ = w.Write([]byte(`{"modules.v1":"/api/registry/v1/modules/","providers.v1":"/api/registry/v1/providers/"}`))
+	})
+	mux.HandleFunc("/api/registry/v1/providers/example-org/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"},{"version":"1.1.0"}]}`))
+	})
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, mux)), WithRegistryToken("app.terraform.io", "secret"))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	for _, name := range []string{"internal", "other"} {
+		versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example-org", Name: name, RegistryType: RegistryTypeHCPTerraform})
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "1.1.0", versions[1].String())
+	}
+	assert.Equal(t, int32(1), discoveries.Load(), "discovery results are reused")
+}
+
+func TestServer_RegistryHost_NoProviderService(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"modules.v1":"/v1/modules/"}`))
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example-org", Name: "internal", RegistryType: "tfe.example.com"})
+	assert.ErrorIs(t, err, ErrPluginApi)
+	assert.ErrorContains(t, err, "does not offer the provider registry protocol")
+}
+
+func TestRegistryType_Normalized(t *testing.T) {
+	assert.Equal(t, RegistryTypeHCPTerraform, normalizedRegistryType("APP.terraform.IO"))
+	assert.Equal(t, "https://app.terraform.io/v1/providers", RegistryType("App.Terraform.io").BaseURL())
+	assert.Equal(t, cacheKey(Request{Namespace: "o", Name: "p", Version: "1.0.0", RegistryType: "App.Terraform.io"}),
+		cacheKey(Request{Namespace: "o", Name: "p", Version: "1.0.0", RegistryType: RegistryTypeHCPTerraform}))
+}
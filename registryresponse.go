@@ -0,0 +1,166 @@
+package tfpluginschema
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// ErrInvalidRegistryResponse is returned when a registry answers with a
+// versions list or download metadata that does not have the expected shape.
+var ErrInvalidRegistryResponse = errors.New("invalid registry response")
+
+// WithSkipInvalidVersions controls whether entries of a registry's versions
+// list that are not versions, such as "banana", are left out with a warning,
+// so that the provider's other releases remain usable. It is on by default;
+// with it off, one such entry fails the whole lookup with
+// ErrInvalidRegistryResponse. Entries of the wrong JSON type are treated
+// alike. A list that is not a JSON object with a "versions" array always
+// fails.
+func WithSkipInvalidVersions(skip bool) ServerOption {
+	return func(s *Server) {
+		s.skipInvalidVersions = skip
+	}
+}
+
+// decodeRegistryJSON decodes body into v, reporting a malformed document or
+// a field of the wrong type as ErrInvalidRegistryResponse. what names the
+// document, such as "versions list", for the message.
+func decodeRegistryJSON(body []byte, v any, what string) error {
+	err := json.Unmarshal(body, v)
+	if err == nil {
+		return nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("%w: registry returned %s where %q is a JSON %s, expected %s", ErrInvalidRegistryResponse, what, typeErr.Field, typeErr.Value, jsonKind(typeErr.Type))
+	}
+	return fmt.Errorf("%w: registry returned %s that is not valid JSON: %w", ErrInvalidRegistryResponse, what, err)
+}
+
+// jsonKind names the JSON type that decodes into t, such as "array".
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return "number"
+	}
+}
+
+// registryPageMeta is the "meta" object with which registry list endpoints
+// link to the next page of a paginated list.
+type registryPageMeta struct {
+	NextURL string `json:"next_url"`
+}
+
+// listedVersion is an entry of a registry versions list.
+type listedVersion struct {
+	version   *goversion.Version
+	protocols []string
+	platforms []Platform
+}
+
+// parseVersionsResponse checks the versions list in body and returns its
+// entries, unsorted, and the "meta.next_url" of the next page, if any.
+// Entries that are not semantic versions fail it, unless skipInvalid is set,
+// in which case they are logged to l and left out. Malformed protocols and
+// platforms of an entry are ignored, as they are only informational.
+func parseVersionsResponse(body []byte, skipInvalid bool, l *slog.Logger) ([]listedVersion, string, error) {
+	var result struct {
+		Versions *[]json.RawMessage `json:"versions"`
+		Meta     registryPageMeta   `json:"meta"`
+	}
+	if err := decodeRegistryJSON(body, &result, "versions list"); err != nil {
+		return nil, "", err
+	}
+	if result.Versions == nil {
+		return nil, "", fmt.Errorf("%w: registry returned versions list without a \"versions\" array", ErrInvalidRegistryResponse)
+	}
+
+	listed := make([]listedVersion, 0, len(*result.Versions))
+	for i, raw := range *result.Versions {
+		var entry struct {
+			Version *string `json:"version"`
+		}
+		var err error
+		switch {
+		case json.Unmarshal(raw, &entry) != nil:
+			err = fmt.Errorf("%w: registry returned versions list with entry %d that is not an object with a string \"version\": %s", ErrInvalidRegistryResponse, i, raw)
+		case entry.Version == nil:
+			err = fmt.Errorf("%w: registry returned versions list with entry %d missing \"version\"", ErrInvalidRegistryResponse, i)
+		default:
+			var ver *goversion.Version
+			if ver, err = goversion.NewVersion(*entry.Version); err == nil {
+				var details struct {
+					Protocols []string   `json:"protocols"`
+					Platforms []Platform `json:"platforms"`
+				}
+				_ = json.Unmarshal(raw, &details)
+				listed = append(listed, listedVersion{version: ver, protocols: details.Protocols, platforms: details.Platforms})
+				continue
+			}
+			err = fmt.Errorf("%w: registry returned versions list with non-semver entry '%s'", ErrInvalidRegistryResponse, *entry.Version)
+		}
+		if !skipInvalid {
+			return nil, "", err
+		}
+		l.Warn("Skipped invalid entry in registry versions list", "error", err)
+	}
+	return listed, result.Meta.NextURL, nil
+}
+
+// nextPageURL resolves next, the link to the next page of the list fetched
+// from current, against current. Links to another host are refused, so that
+// registry credentials are not sent elsewhere.
+func nextPageURL(current, next string) (string, error) {
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("%w: registry returned next page link '%s' that is not a URL", ErrInvalidRegistryResponse, next)
+	}
+	u := base.ResolveReference(ref)
+	if !strings.EqualFold(u.Host, base.Host) || u.Scheme != base.Scheme {
+		return "", fmt.Errorf("%w: registry returned next page link '%s' to another host", ErrInvalidRegistryResponse, next)
+	}
+	return u.String(), nil
+}
+
+// validateDownloadResponse checks the fields of registry download metadata
+// that are used to fetch the provider. The filename is checked separately
+// by validateProviderFileName.
+func validateDownloadResponse(r pluginApiResponse) error {
+	if r.DownloadURL == "" {
+		return fmt.Errorf("%w: registry returned download metadata without a \"download_url\"", ErrInvalidRegistryResponse)
+	}
+	if r.SHASum != "" {
+		if b, err := hex.DecodeString(r.SHASum); err != nil || len(b) != 32 {
+			return fmt.Errorf("%w: registry returned download metadata with \"shasum\" '%s', expected a hex SHA-256", ErrInvalidRegistryResponse, r.SHASum)
+		}
+	}
+	for _, p := range r.Protocols {
+		if _, err := goversion.NewVersion(strings.TrimSpace(p)); err != nil {
+			return fmt.Errorf("%w: registry returned download metadata with protocol version '%s'", ErrInvalidRegistryResponse, p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_InvalidVersionsResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+		// skipped is the result by default, or nil if it fails
+		// regardless.
+		skipped []string
+	}{
+		{
+			name:    "non-semver entry",
+			body:    `{"versions":[{"version":"1.0.0"},{"version":"banana"},{"version":"2.0.0"}]}`,
+			wantErr: "registry returned versions list with non-semver entry 'banana'",
+			skipped: []string{"1.0.0", "2.0.0"},
+		},
+		{
+			name:    "entry of the wrong type",
+			body:    `{"versions":[{"version":1},{"version":"1.0.0"}]}`,
+			wantErr: `registry returned versions list with entry 0 that is not an object with a string "version": {"version":1}`,
+			skipped: []string{"1.0.0"},
+		},
+		{
+			name:    "entry without a version",
+			body:    `{"versions":[{"protocols":["5.0"]}]}`,
+			wantErr: `registry returned versions list with entry 0 missing "version"`,
+			skipped: []string{},
+		},
+		{
+			name:    "no versions array",
+			body:    `{"modules":[]}`,
+			wantErr: `registry returned versions list without a "versions" array`,
+		},
+		{
+			name:    "versions of the wrong type",
+			body:    `{"versions":"1.0.0"}`,
+			wantErr: `registry returned versions list where "versions" is a JSON string, expected array`,
+		},
+		{
+			name:    "not JSON",
+			body:    `<html>`,
+			wantErr: "registry returned versions list that is not valid JSON",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			req := VersionsRequest{Namespace: "example", Name: "broken"}
+
+			s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithSkipInvalidVersions(false))
+			t.Cleanup(func() { _ = s.Cleanup() })
+			_, err := s.GetAvailableVersions(req)
+			require.ErrorIs(t, err, ErrInvalidRegistryResponse)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.ErrorContains(t, err, "/v1/providers/example/broken/versions")
+
+			s = NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+			t.Cleanup(func() { _ = s.Cleanup() })
+			versions, err := s.GetAvailableVersions(req)
+			if tt.skipped == nil {
+				require.ErrorIs(t, err, ErrInvalidRegistryResponse)
+				return
+			}
+			require.NoError(t, err)
+			got := make([]string, len(versions))
+			for i, v := range versions {
+				got[i] = v.String()
+			}
+			assert.Equal(t, tt.skipped, got)
+		})
+	}
+}
+
+func TestServer_InvalidDownloadResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "no download URL",
+			body:    `{"filename":"terraform-provider-aws_5.0.0.zip"}`,
+			wantErr: `registry returned download metadata without a "download_url"`,
+		},
+		{
+			name:    "malformed shasum",
+			body:    `{"filename":"terraform-provider-aws_5.0.0.zip","download_url":"https://releases.example.com/aws.zip","shasum":"abc123"}`,
+			wantErr: `registry returned download metadata with "shasum" 'abc123', expected a hex SHA-256`,
+		},
+		{
+			name:    "malformed protocol",
+			body:    `{"filename":"terraform-provider-aws_5.0.0.zip","download_url":"https://releases.example.com/aws.zip","protocols":["five"]}`,
+			wantErr: "registry returned download metadata with protocol version 'five'",
+		},
+		{
+			name:    "field of the wrong type",
+			body:    `{"filename":"terraform-provider-aws_5.0.0.zip","download_url":["https://releases.example.com/aws.zip"]}`,
+			wantErr: `registry returned download metadata where "download_url" is a JSON array, expected string`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+			t.Cleanup(func() { _ = s.Cleanup() })
+
+			_, err := s.Plan(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+			require.ErrorIs(t, err, ErrInvalidRegistryResponse)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestServer_SkipInvalidVersions_Warns(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"versions":[{"version":"banana"},{"version":"1.0.0"}]}`)
+	}))
+	var buf bytes.Buffer
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})), WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example", Name: "broken"})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+
+	records := decodeLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "example/broken", records[0][logKeyProvider])
+	assert.Contains(t, records[0]["error"], "non-semver entry 'banana'")
+}
@@ -0,0 +1,401 @@
+// Package render formats provider schemas for terminals, as aligned tables
+// or trees of attributes and nested blocks with their types, whether they
+// are required, optional or computed, and whether they are sensitive. It
+// is used by the CLI's schema command and can be reused by other terminal
+// UIs.
+package render
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// Options configures Table and Tree.
+type Options struct {
+	// Color highlights the output with ANSI escape codes: required
+	// attributes, types, sensitive values and deprecations. Leave it off
+	// when the output is not a terminal.
+	Color bool
+	// Descriptions adds a column with the first paragraph of each
+	// description, rendered to plain text and cut at DescriptionWidth.
+	Descriptions bool
+	// DescriptionWidth is the maximum width of a description in runes.
+	// Zero means 60.
+	DescriptionWidth int
+}
+
+const defaultDescriptionWidth = 60
+
+// ANSI escape codes used with Options.Color.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// Table writes s as a table with one row per attribute and nested block.
+// Nested entries are named by their dot-separated path, such as
+// "rule.priority", and follow their parent. Attributes come before nested
+// blocks at each level, each sorted by name.
+//
+//	NAME           TYPE        MODE      FLAGS
+//	name           string      required
+//	rule           list block  optional
+//	rule.priority  number      optional
+func Table(w io.Writer, s *tfjson.Schema, opts Options) error {
+	rows := schemaRows(s)
+	header := row{name: "NAME", typ: "TYPE", mode: "MODE", flags: "FLAGS", description: "DESCRIPTION", header: true}
+	lines := []row{header}
+	for _, r := range rows {
+		r.name = strings.Join(r.path, ".")
+		lines = append(lines, r)
+	}
+	return writeRows(w, lines, opts)
+}
+
+// Tree writes s as a tree of attributes and nested blocks, drawn with
+// box-drawing characters, with the type, mode and flags of each entry
+// aligned after it. Entries are ordered as by Table.
+//
+//	├── name          string      required
+//	└── rule          list block  optional
+//	    └── priority  number      optional
+func Tree(w io.Writer, s *tfjson.Schema, opts Options) error {
+	rows := schemaRows(s)
+	var lines []row
+	// open[d] records whether the entry at depth d has siblings after it,
+	// which continue its vertical line.
+	var open []bool
+	for i, r := range rows {
+		depth := len(r.path) - 1
+		last := true
+		for _, next := range rows[i+1:] {
+			if len(next.path) <= depth {
+				break
+			}
+			if len(next.path) == depth+1 {
+				last = false
+				break
+			}
+		}
+		open = append(open[:depth], !last)
+
+		var prefix strings.Builder
+		for _, o := range open[:depth] {
+			if o {
+				prefix.WriteString("│   ")
+			} else {
+				prefix.WriteString("    ")
+			}
+		}
+		if last {
+			prefix.WriteString("└── ")
+		} else {
+			prefix.WriteString("├── ")
+		}
+		r.prefix = prefix.String()
+		r.name = r.path[depth]
+		lines = append(lines, r)
+	}
+	return writeRows(w, lines, opts)
+}
+
+// row is one attribute or nested block.
+type row struct {
+	path        []string
+	prefix      string // tree drawing before name, not colorized
+	name        string
+	typ         string
+	mode        string
+	flags       string
+	description string
+	required    bool
+	sensitive   bool
+	deprecated  bool
+	header      bool
+}
+
+// schemaRows returns the rows for every attribute and nested block of s,
+// depth first.
+func schemaRows(s *tfjson.Schema) []row {
+	if s == nil || s.Block == nil {
+		return nil
+	}
+	return blockRows(s.Block, nil)
+}
+
+func blockRows(b *tfjson.SchemaBlock, path []string) []row {
+	rows := attributeRows(b.Attributes, path)
+	for _, name := range sortedKeys(b.NestedBlocks) {
+		bt := b.NestedBlocks[name]
+		if bt == nil {
+			continue
+		}
+		block := bt.Block
+		if block == nil {
+			block = &tfjson.SchemaBlock{}
+		}
+		r := row{
+			path:        childPath(path, name),
+			typ:         blockType(bt),
+			mode:        "optional",
+			description: tfpluginschema.DescriptionPlaintext(block.Description, block.DescriptionKind),
+			deprecated:  block.Deprecated,
+		}
+		if bt.MinItems > 0 {
+			r.mode, r.required = "required", true
+		}
+		r.flags = flags(false, false, block.Deprecated)
+		rows = append(rows, r)
+		rows = append(rows, blockRows(block, r.path)...)
+	}
+	return rows
+}
+
+func attributeRows(attrs map[string]*tfjson.SchemaAttribute, path []string) []row {
+	var rows []row
+	for _, name := range sortedKeys(attrs) {
+		a := attrs[name]
+		if a == nil {
+			continue
+		}
+		r := row{
+			path:        childPath(path, name),
+			mode:        mode(a),
+			flags:       flags(a.Sensitive, a.WriteOnly, a.Deprecated),
+			description: tfpluginschema.DescriptionPlaintext(a.Description, a.DescriptionKind),
+			required:    a.Required,
+			sensitive:   a.Sensitive,
+			deprecated:  a.Deprecated,
+		}
+		if nt := a.AttributeNestedType; nt != nil {
+			r.typ = nestedAttributeType(nt)
+			rows = append(rows, r)
+			rows = append(rows, attributeRows(nt.Attributes, r.path)...)
+			continue
+		}
+		r.typ = typeName(a.AttributeType)
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+// mode describes whether an attribute is set by the configuration, the
+// provider or either.
+func mode(a *tfjson.SchemaAttribute) string {
+	switch {
+	case a.Required:
+		return "required"
+	case a.Optional && a.Computed:
+		return "optional, computed"
+	case a.Optional:
+		return "optional"
+	case a.Computed:
+		return "computed"
+	}
+	return ""
+}
+
+func flags(sensitive, writeOnly, deprecated bool) string {
+	var f []string
+	if sensitive {
+		f = append(f, "sensitive")
+	}
+	if writeOnly {
+		f = append(f, "write-only")
+	}
+	if deprecated {
+		f = append(f, "deprecated")
+	}
+	return strings.Join(f, ", ")
+}
+
+// blockType describes a nested block's nesting mode and item limits, e.g.
+// "list block [1..1]".
+func blockType(bt *tfjson.SchemaBlockType) string {
+	s := "block"
+	switch bt.NestingMode {
+	case tfjson.SchemaNestingModeList:
+		s = "list block"
+	case tfjson.SchemaNestingModeSet:
+		s = "set block"
+	case tfjson.SchemaNestingModeMap:
+		s = "map block"
+	}
+	if bt.MinItems > 0 || bt.MaxItems > 0 {
+		upper := "*"
+		if bt.MaxItems > 0 {
+			upper = fmt.Sprint(bt.MaxItems)
+		}
+		s += fmt.Sprintf(" [%d..%s]", bt.MinItems, upper)
+	}
+	return s
+}
+
+// nestedAttributeType describes a nested attribute's nesting mode, e.g.
+// "list(object)".
+func nestedAttributeType(nt *tfjson.SchemaNestedAttributeType) string {
+	switch nt.NestingMode {
+	case tfjson.SchemaNestingModeList:
+		return "list(object)"
+	case tfjson.SchemaNestingModeSet:
+		return "set(object)"
+	case tfjson.SchemaNestingModeMap:
+		return "map(object)"
+	default:
+		return "object"
+	}
+}
+
+// typeName returns a type in Terraform's type constraint syntax, e.g.
+// "list(string)". Object and tuple types are not spelled out.
+func typeName(t cty.Type) string {
+	switch {
+	case t == cty.NilType:
+		return ""
+	case t == cty.DynamicPseudoType:
+		return "any"
+	case t.IsPrimitiveType():
+		return t.FriendlyName()
+	case t.IsListType():
+		return "list(" + typeName(t.ElementType()) + ")"
+	case t.IsSetType():
+		return "set(" + typeName(t.ElementType()) + ")"
+	case t.IsMapType():
+		return "map(" + typeName(t.ElementType()) + ")"
+	case t.IsObjectType():
+		return "object"
+	case t.IsTupleType():
+		return "tuple"
+	default:
+		return t.FriendlyName()
+	}
+}
+
+// writeRows writes rows as aligned columns. Widths are counted in runes
+// and ignore color, so colorized output stays aligned.
+func writeRows(w io.Writer, rows []row, opts Options) error {
+	width := opts.DescriptionWidth
+	if width <= 0 {
+		width = defaultDescriptionWidth
+	}
+	cells := make([][]string, len(rows))
+	for i, r := range rows {
+		description := r.description
+		if !r.header {
+			description = summarize(description, width)
+		}
+		cells[i] = []string{r.prefix + r.name, r.typ, r.mode, r.flags}
+		if opts.Descriptions {
+			cells[i] = append(cells[i], description)
+		}
+	}
+
+	var widths []int
+	for _, c := range cells {
+		for len(widths) < len(c) {
+			widths = append(widths, 0)
+		}
+		for j, cell := range c {
+			widths[j] = max(widths[j], utf8.RuneCountInString(cell))
+		}
+	}
+
+	for i, r := range rows {
+		var line strings.Builder
+		c := cells[i]
+		// Trailing empty columns are not padded.
+		last := len(c) - 1
+		for last > 0 && c[last] == "" {
+			last--
+		}
+		for j := 0; j <= last; j++ {
+			if j > 0 {
+				line.WriteString("  ")
+			}
+			cell := c[j]
+			pad := ""
+			if j < last {
+				pad = strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+			}
+			if j == 0 && r.prefix != "" {
+				line.WriteString(r.prefix)
+				cell = strings.TrimPrefix(cell, r.prefix)
+			}
+			line.WriteString(colorize(cell, columnColor(r, j), opts.Color))
+			line.WriteString(pad)
+		}
+		line.WriteString("\n")
+		if _, err := io.WriteString(w, line.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnColor returns the ANSI color of column j of r.
+func columnColor(r row, j int) string {
+	switch {
+	case r.header:
+		return ansiBold
+	case j == 0 && r.required:
+		return ansiBold
+	case j == 1:
+		return ansiCyan
+	case j == 2 && r.mode == "computed":
+		return ansiDim
+	case j == 3 && r.sensitive:
+		return ansiRed
+	case j == 3 && r.deprecated:
+		return ansiYellow
+	case j == 4:
+		return ansiDim
+	}
+	return ""
+}
+
+func colorize(s, color string, enabled bool) string {
+	if !enabled || color == "" || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// summarize returns the first paragraph of a plain text description on one
+// line, cut to width runes.
+func summarize(s string, width int) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n\n"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:width-1])) + "…"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func childPath(path []string, name string) []string {
+	return append(slices.Clip(path), name)
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var testSchema = &tfjson.Schema{Block: &tfjson.SchemaBlock{
+	Attributes: map[string]*tfjson.SchemaAttribute{
+		"name":     {AttributeType: cty.String, Required: true, Description: "The **name**.", DescriptionKind: tfjson.SchemaDescriptionKindMarkdown},
+		"tags":     {AttributeType: cty.Map(cty.String), Optional: true, Computed: true},
+		"id":       {AttributeType: cty.String, Computed: true},
+		"password": {AttributeType: cty.String, Optional: true, Sensitive: true, Deprecated: true},
+		"settings": {
+			Optional: true,
+			AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+				NestingMode: tfjson.SchemaNestingModeList,
+				Attributes: map[string]*tfjson.SchemaAttribute{
+					"enabled": {AttributeType: cty.Bool, Required: true},
+				},
+			},
+		},
+	},
+	NestedBlocks: map[string]*tfjson.SchemaBlockType{
+		"rule": {
+			NestingMode: tfjson.SchemaNestingModeList,
+			MinItems:    1,
+			Block: &tfjson.SchemaBlock{
+				Attributes: map[string]*tfjson.SchemaAttribute{
+					"priority": {AttributeType: cty.Number, Optional: true},
+				},
+				NestedBlocks: map[string]*tfjson.SchemaBlockType{
+					"match": {NestingMode: tfjson.SchemaNestingModeSingle, Block: &tfjson.SchemaBlock{
+						Attributes: map[string]*tfjson.SchemaAttribute{"value": {AttributeType: cty.String, Required: true}},
+					}},
+				},
+			},
+		},
+	},
+}}
+
+func TestTable(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, Table(&sb, testSchema, Options{}))
+	assert.Equal(t, strings.Join([]string{
+		"NAME              TYPE               MODE                FLAGS",
+		"id                string             computed",
+		"name              string             required",
+		"password          string             optional            sensitive, deprecated",
+		"settings          list(object)       optional",
+		"settings.enabled  bool               required",
+		"tags              map(string)        optional, computed",
+		"rule              list block [1..*]  required",
+		"rule.priority     number             optional",
+		"rule.match        block              optional",
+		"rule.match.value  string             required",
+		"",
+	}, "\n"), sb.String())
+}
+
+func TestTree(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, Tree(&sb, testSchema, Options{}))
+	assert.Equal(t, strings.Join([]string{
+		"├── id             string             computed",
+		"├── name           string             required",
+		"├── password       string             optional            sensitive, deprecated",
+		"├── settings       list(object)       optional",
+		"│   └── enabled    bool               required",
+		"├── tags           map(string)        optional, computed",
+		"└── rule           list block [1..*]  required",
+		"    ├── priority   number             optional",
+		"    └── match      block              optional",
+		"        └── value  string             required",
+		"",
+	}, "\n"), sb.String())
+}
+
+func TestTable_DescriptionsAndColor(t *testing.T) {
+	s := &tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{
+		"name": testSchema.Block.Attributes["name"],
+		"long": {AttributeType: cty.String, Optional: true, Description: "A description that goes on for rather longer than the column.\n\nSecond paragraph."},
+	}}}
+
+	var sb strings.Builder
+	require.NoError(t, Table(&sb, s, Options{Descriptions: true, DescriptionWidth: 20}))
+	assert.Equal(t, strings.Join([]string{
+		"NAME  TYPE    MODE      FLAGS  DESCRIPTION",
+		"long  string  optional         A description that…",
+		"name  string  required         The name.",
+		"",
+	}, "\n"), sb.String())
+
+	sb.Reset()
+	require.NoError(t, Table(&sb, s, Options{Color: true}))
+	assert.Contains(t, sb.String(), ansiBold+"name"+ansiReset+"  "+ansiCyan+"string"+ansiReset)
+	assert.NotContains(t, sb.String(), ansiBold+"long")
+}
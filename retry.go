@@ -0,0 +1,105 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Backoff bounds used when a RetryPolicy leaves them unset.
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+)
+
+// RetryPolicy controls how registry API requests are retried after a
+// transport error or a 429, 502, 503 or 504 response. Provider archive
+// downloads are not retried. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the delay before the first retry; it doubles on
+	// each subsequent retry. Defaults to 500ms.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff caps the delay between attempts, including delays asked
+	// for by a Retry-After header. Defaults to 10s.
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// WithRetryPolicy configures retries of registry API requests.
+func WithRetryPolicy(p RetryPolicy) ServerOption {
+	return func(s *Server) {
+		s.retryPolicy = p
+	}
+}
+
+// delay returns how long to wait before the given retry (1 for the first),
+// preferring a Retry-After header on resp when it gives a number of seconds.
+func (p RetryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	initial, maxBackoff := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, maxBackoff)
+		}
+	}
+	d := initial
+	for i := 1; i < retry && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// retryable reports whether a registry request that ended with resp and err
+// is worth repeating.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// doRegistryRequest sends a registry API request, retrying it as configured
+// by WithRetryPolicy. The response or error of the last attempt is returned.
+func (s *Server) doRegistryRequest(req *http.Request) (*http.Response, error) {
+	if s.offline {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, req.URL)
+	}
+	attempts := max(s.retryPolicy.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		resp, err := s.httpClient.Do(req.Clone(req.Context()))
+		if attempt >= attempts || !retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := s.retryPolicy.delay(attempt, resp)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		s.logger(logComponentRegistry).Debug("Retrying registry request",
+			"url", req.URL.String(), "attempt", attempt+1, "status", status, "error", err, "delay", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1, nil))
+	assert.Equal(t, 2*time.Second, p.delay(2, nil))
+	assert.Equal(t, 4*time.Second, p.delay(3, nil))
+	assert.Equal(t, 5*time.Second, p.delay(4, nil))
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	assert.Equal(t, 3*time.Second, p.delay(1, resp))
+	resp.Header.Set("Retry-After", "60")
+	assert.Equal(t, 5*time.Second, p.delay(1, resp), "Retry-After is capped by MaxBackoff")
+
+	assert.Equal(t, defaultRetryInitialBackoff, RetryPolicy{}.delay(1, nil))
+}
+
+func TestServer_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      RetryPolicy
+		failures    int32
+		status      int
+		wantErr     bool
+		wantAttempt int32
+	}{
+		{"disabled by default", RetryPolicy{}, 1, http.StatusServiceUnavailable, true, 1},
+		{"recovers after 503s", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, 2, http.StatusServiceUnavailable, false, 3},
+		{"recovers after 429", RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}, 1, http.StatusTooManyRequests, false, 2},
+		{"gives up", RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}, 5, http.StatusBadGateway, true, 2},
+		{"not retried on 404", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, 5, http.StatusNotFound, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				fmt.Fprint(w, `{"versions":[{"version":"1.0.0"}]}`)
+			}))
+			s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithRetryPolicy(tt.policy))
+			t.Cleanup(func() { _ = s.Cleanup() })
+
+			_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantAttempt, attempts.Load())
+		})
+	}
+}
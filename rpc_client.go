@@ -5,13 +5,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"os/exec"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
 	"github.com/matt-FFFFFF/tfpluginschema/tfplugin5"
 	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	// terraform-json provides the unified ProviderSchema type we use
 	tfjson "github.com/hashicorp/terraform-json"
@@ -32,8 +41,21 @@ const (
 var (
 	// ErrNotImplemented is returned when a method is not implemented
 	ErrNotImplemented = errors.New("not implemented")
+	// ErrSchemaMessageTooLarge is returned when a provider's schema response
+	// exceeds the gRPC receive message size limit.
+	ErrSchemaMessageTooLarge = errors.New("provider schema exceeds the gRPC message size limit")
 )
 
+// WithGRPCMaxRecvMsgSize sets the largest gRPC message, in bytes, accepted
+// from a provider plugin. The plugin framework's default is 2 GiB; lower it
+// to cap memory use, or raise it back if an environment's gRPC defaults are
+// too small for large providers. A value <= 0 keeps the default.
+func WithGRPCMaxRecvMsgSize(n int) ServerOption {
+	return func(s *Server) {
+		s.grpcMaxRecvMsgSize = n
+	}
+}
+
 // providerGRPCPlugin implements the plugin.GRPCPlugin interface for connecting to provider binaries
 type providerGRPCPlugin struct {
 	plugin.Plugin
@@ -88,6 +110,61 @@ func (c v6SchemaClient) getSchema(ctx context.Context, req *tfplugin6.GetProvide
 	return c.client.GetProviderSchema(ctx, req, opts...)
 }
 
+// metadataClient is implemented by schema clients that can call the
+// GetMetadata RPC, which lists a provider's resources, data sources,
+// ephemeral resources and functions without their schemas.
+type metadataClient interface {
+	getMetadata(ctx context.Context, opts ...grpc.CallOption) (*providerMetadata, error)
+}
+
+// getMetadata calls GetMetadata on the V5 client and implements the metadataClient interface.
+func (c v5SchemaClient) getMetadata(ctx context.Context, opts ...grpc.CallOption) (*providerMetadata, error) {
+	resp, err := c.client.GetMetadata(ctx, &tfplugin5.GetMetadata_Request{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range resp.GetDiagnostics() {
+		if d.GetSeverity() == tfplugin5.Diagnostic_ERROR {
+			return nil, fmt.Errorf("%w: %s", errMetadataUnsupported, d.GetSummary())
+		}
+	}
+	return &providerMetadata{
+		resources:          metadataNames(resp.GetResources(), (*tfplugin5.GetMetadata_ResourceMetadata).GetTypeName),
+		dataSources:        metadataNames(resp.GetDataSources(), (*tfplugin5.GetMetadata_DataSourceMetadata).GetTypeName),
+		ephemeralResources: metadataNames(resp.GetEphemeralResources(), (*tfplugin5.GetMetadata_EphemeralResourceMetadata).GetTypeName),
+		functions:          metadataNames(resp.GetFunctions(), (*tfplugin5.GetMetadata_FunctionMetadata).GetName),
+	}, nil
+}
+
+// getMetadata calls GetMetadata on the V6 client and implements the metadataClient interface.
+func (c v6SchemaClient) getMetadata(ctx context.Context, opts ...grpc.CallOption) (*providerMetadata, error) {
+	resp, err := c.client.GetMetadata(ctx, &tfplugin6.GetMetadata_Request{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range resp.GetDiagnostics() {
+		if d.GetSeverity() == tfplugin6.Diagnostic_ERROR {
+			return nil, fmt.Errorf("%w: %s", errMetadataUnsupported, d.GetSummary())
+		}
+	}
+	return &providerMetadata{
+		resources:          metadataNames(resp.GetResources(), (*tfplugin6.GetMetadata_ResourceMetadata).GetTypeName),
+		dataSources:        metadataNames(resp.GetDataSources(), (*tfplugin6.GetMetadata_DataSourceMetadata).GetTypeName),
+		ephemeralResources: metadataNames(resp.GetEphemeralResources(), (*tfplugin6.GetMetadata_EphemeralResourceMetadata).GetTypeName),
+		functions:          metadataNames(resp.GetFunctions(), (*tfplugin6.GetMetadata_FunctionMetadata).GetName),
+	}, nil
+}
+
+// metadataNames returns the sorted names of a GetMetadata response list.
+func metadataNames[T any](items []T, name func(T) string) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, name(item))
+	}
+	slices.Sort(names)
+	return names
+}
+
 // providerGRPCClient is a generic wrapper for gRPC clients
 type providerGRPCClient[TReq, TResp any] struct {
 	grpcClient schemaClient[TReq, TResp]
@@ -97,12 +174,32 @@ type providerGRPCClient[TReq, TResp any] struct {
 func (c *providerGRPCClient[TReq, TResp]) Schema(req TReq) (TResp, error) {
 	var zeroResp TResp
 	protoResp, err := c.grpcClient.getSchema(context.Background(), req)
+	if status.Code(err) == codes.ResourceExhausted {
+		return zeroResp, fmt.Errorf("%w (raise it with WithGRPCMaxRecvMsgSize): %w", ErrSchemaMessageTooLarge, err)
+	}
 	if err != nil {
 		return zeroResp, fmt.Errorf("failed to get provider schema: %w", err)
 	}
 	return protoResp, nil
 }
 
+// Metadata calls GetMetadata on the provider. It returns
+// errMetadataUnsupported if the provider does not implement it.
+func (c *providerGRPCClient[TReq, TResp]) Metadata() (*providerMetadata, error) {
+	mc, ok := c.grpcClient.(metadataClient)
+	if !ok {
+		return nil, errMetadataUnsupported
+	}
+	md, err := mc.getMetadata(context.Background())
+	if status.Code(err) == codes.Unimplemented {
+		return nil, fmt.Errorf("%w: %w", errMetadataUnsupported, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider metadata: %w", err)
+	}
+	return md, nil
+}
+
 // providerGRPCClientV5 wraps the gRPC client for protocol v5
 type providerGRPCClientV5 struct {
 	*providerGRPCClient[*tfplugin5.GetProviderSchema_Request, *tfplugin5.GetProviderSchema_Response]
@@ -131,17 +228,32 @@ type universalProvider interface {
 	v6Schema() (*tfplugin6.GetProviderSchema_Response, error)
 	// schema returns a unified terraform-json ProviderSchema representation for either protocol
 	schema() (*tfjson.ProviderSchema, error)
+	// rawSchema returns the provider's schema response, converted lazily
+	rawSchema() (*lazySchema, error)
+	// metadata lists the provider's schema names without their schemas
+	metadata() (*providerMetadata, error)
+	// features probes the optional parts of the protocol the provider implements
+	features() providerFeatures
 	close()
 }
 
 // newGrpcClient creates a provider client that supports both V5 and V6 protocols.
-func newGrpcClient(providerPath string) (universalProvider, error) {
+// Schema conversion is logged to l. maxRecvMsgSize, if > 0, overrides the
+// gRPC receive message size limit.
+func newGrpcClient(providerPath string, l *slog.Logger, maxRecvMsgSize int) (universalProvider, error) {
 	// No need for ProtocolVersion here as we are using VersionedPlugins
 	handshakeConfig := plugin.HandshakeConfig{
 		MagicCookieKey:   magicCookieKey,
 		MagicCookieValue: magicCookieValue,
 	}
 
+	// Dial options are applied after go-plugin's own defaults, so this
+	// call option takes precedence.
+	var dialOpts []grpc.DialOption
+	if maxRecvMsgSize > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxRecvMsgSize)))
+	}
+
 	client := plugin.NewClient(&plugin.ClientConfig{
 		HandshakeConfig: handshakeConfig,
 		VersionedPlugins: map[int]plugin.PluginSet{
@@ -151,6 +263,7 @@ func newGrpcClient(providerPath string) (universalProvider, error) {
 		Cmd:              exec.Command(providerPath),
 		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
 		Logger:           hclog.New(&hclog.LoggerOptions{Level: hclog.Error}),
+		GRPCDialOptions:  dialOpts,
 	})
 
 	// Connect via RPC
@@ -173,12 +286,14 @@ func newGrpcClient(providerPath string) (universalProvider, error) {
 		return &universalProviderClient{
 			v5:        v5Client,
 			closeFunc: client.Kill,
+			l:         l,
 		}, nil
 	}
 	if v6Client, ok := raw.(*providerGRPCClientV6); ok {
 		return &universalProviderClient{
 			v6:        v6Client,
 			closeFunc: client.Kill,
+			l:         l,
 		}, nil
 	}
 
@@ -191,6 +306,7 @@ type universalProviderClient struct {
 	v5        *providerGRPCClientV5
 	v6        *providerGRPCClientV6
 	closeFunc func()
+	l         *slog.Logger
 }
 
 func (c *universalProviderClient) v5Schema() (*tfplugin5.GetProviderSchema_Response, error) {
@@ -218,35 +334,142 @@ func (c *universalProviderClient) close() {
 // schema returns a unified terraform-json ProviderSchema regardless of whether the underlying
 // provider uses protocol v5 or v6. It prefers v6 when available and falls back to v5.
 func (c *universalProviderClient) schema() (*tfjson.ProviderSchema, error) {
+	var errs []error
+
 	// Prefer v6
 	if c.v6 != nil {
 		resp, err := c.v6.v6Schema()
 		if err == nil {
+			start := time.Now()
 			ps, convErr := convertV6ResponseToTFJSON(resp)
 			if convErr != nil {
 				return nil, fmt.Errorf("failed to convert v6 response: %w", convErr)
 			}
+			c.logConversion(6, start)
 			return ps, nil
 		}
+		errs = append(errs, err)
 	}
 
 	// Fallback to v5
 	if c.v5 != nil {
 		resp, err := c.v5.v5Schema()
 		if err == nil {
+			start := time.Now()
 			ps, convErr := convertV5ResponseToTFJSON(resp)
 			if convErr != nil {
 				return nil, fmt.Errorf("failed to convert v5 response: %w", convErr)
 			}
+			c.logConversion(5, start)
 			return ps, nil
 		}
+		errs = append(errs, err)
+	}
+
+	return nil, schemaFetchError(errs)
+}
+
+// rawSchema returns the provider's schema without converting it; entries are
+// converted on first access. Like schema, it prefers v6 and falls back to v5.
+func (c *universalProviderClient) rawSchema() (*lazySchema, error) {
+	var errs []error
+	if c.v6 != nil {
+		resp, err := c.v6.v6Schema()
+		if err == nil {
+			return newLazySchemaV6(resp, c.l)
+		}
+		errs = append(errs, err)
+	}
+	if c.v5 != nil {
+		resp, err := c.v5.v5Schema()
+		if err == nil {
+			return newLazySchemaV5(resp, c.l)
+		}
+		errs = append(errs, err)
 	}
+	return nil, schemaFetchError(errs)
+}
 
-	return nil, fmt.Errorf("failed to get provider schema for either V5 or V6 protocols")
+// metadata calls GetMetadata with whichever protocol was negotiated.
+func (c *universalProviderClient) metadata() (*providerMetadata, error) {
+	switch {
+	case c.v6 != nil:
+		return c.v6.Metadata()
+	case c.v5 != nil:
+		return c.v5.Metadata()
+	}
+	return nil, errMetadataUnsupported
+}
+
+// schemaFetchError reports why no protocol returned a schema, keeping the
+// underlying errors (such as ErrSchemaMessageTooLarge) inspectable.
+func schemaFetchError(errs []error) error {
+	const msg = "failed to get provider schema for either V5 or V6 protocols"
+	if len(errs) == 0 {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s: %w", msg, errors.Join(errs...))
+}
+
+// logConversion records how long converting a schema response took.
+func (c *universalProviderClient) logConversion(protocol int, start time.Time) {
+	if c.l == nil {
+		return
+	}
+	c.l.Debug("Converted provider schema", "protocol", protocol, durationLogAttr(time.Since(start)))
 }
 
 // Conversion helpers ------------------------------------------------------
 
+// parallelConvertThreshold is the number of schemas below which conversion
+// stays on the calling goroutine; small providers gain nothing from a
+// worker pool.
+const parallelConvertThreshold = 64
+
+// convertMap converts every value in a map of provider schemas, returning nil
+// for an empty map. Large maps (e.g. azurerm's ~1500 resources) are spread
+// across a pool of GOMAXPROCS workers; the converters are pure, so the only
+// shared state is the input map, which is only read.
+func convertMap[V, R any](in map[string]V, convert func(V) R) map[string]R {
+	return convertMapN(in, runtime.GOMAXPROCS(0), convert)
+}
+
+// convertMapN is convertMap with an explicit worker count.
+func convertMapN[V, R any](in map[string]V, workers int, convert func(V) R) map[string]R {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]R, len(in))
+	if workers < 2 || len(in) < parallelConvertThreshold {
+		for k, v := range in {
+			out[k] = convert(v)
+		}
+		return out
+	}
+
+	keys := slices.Collect(maps.Keys(in))
+	results := make([]R, len(keys))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, len(keys)) {
+		wg.Go(func() {
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(keys) {
+					return
+				}
+				results[i] = convert(in[keys[i]])
+			}
+		})
+	}
+	wg.Wait()
+
+	for i, k := range keys {
+		out[k] = results[i]
+	}
+	return out
+}
+
 // convertV6ResponseToTFJSON converts a tfplugin6 GetProviderSchema_Response into a terraform-json ProviderSchema
 func convertV6ResponseToTFJSON(resp *tfplugin6.GetProviderSchema_Response) (*tfjson.ProviderSchema, error) {
 	if resp == nil {
@@ -261,36 +484,16 @@ func convertV6ResponseToTFJSON(resp *tfplugin6.GetProviderSchema_Response) (*tfj
 	}
 
 	// Resource schemas
-	if len(resp.ResourceSchemas) > 0 {
-		ps.ResourceSchemas = make(map[string]*tfjson.Schema, len(resp.ResourceSchemas))
-		for k, v := range resp.ResourceSchemas {
-			ps.ResourceSchemas[k] = convertV6SchemaToTFJSON(v)
-		}
-	}
+	ps.ResourceSchemas = convertMap(resp.ResourceSchemas, convertV6SchemaToTFJSON)
 
 	// Data source schemas
-	if len(resp.DataSourceSchemas) > 0 {
-		ps.DataSourceSchemas = make(map[string]*tfjson.Schema, len(resp.DataSourceSchemas))
-		for k, v := range resp.DataSourceSchemas {
-			ps.DataSourceSchemas[k] = convertV6SchemaToTFJSON(v)
-		}
-	}
+	ps.DataSourceSchemas = convertMap(resp.DataSourceSchemas, convertV6SchemaToTFJSON)
 
 	// Ephemeral resource schemas
-	if len(resp.EphemeralResourceSchemas) > 0 {
-		ps.EphemeralResourceSchemas = make(map[string]*tfjson.Schema, len(resp.EphemeralResourceSchemas))
-		for k, v := range resp.EphemeralResourceSchemas {
-			ps.EphemeralResourceSchemas[k] = convertV6SchemaToTFJSON(v)
-		}
-	}
+	ps.EphemeralResourceSchemas = convertMap(resp.EphemeralResourceSchemas, convertV6SchemaToTFJSON)
 
 	// Functions
-	if len(resp.Functions) > 0 {
-		ps.Functions = make(map[string]*tfjson.FunctionSignature, len(resp.Functions))
-		for k, v := range resp.Functions {
-			ps.Functions[k] = convertV6FunctionToTFJSON(v)
-		}
-	}
+	ps.Functions = convertMap(resp.Functions, convertV6FunctionToTFJSON)
 
 	// Note: GetProviderSchema does not include resource identity schemas in the v6 response.
 	// Those are available via a separate RPC. Leave ResourceIdentitySchemas nil for now.
@@ -312,36 +515,16 @@ func convertV5ResponseToTFJSON(resp *tfplugin5.GetProviderSchema_Response) (*tfj
 	}
 
 	// Resource schemas
-	if len(resp.ResourceSchemas) > 0 {
-		ps.ResourceSchemas = make(map[string]*tfjson.Schema, len(resp.ResourceSchemas))
-		for k, v := range resp.ResourceSchemas {
-			ps.ResourceSchemas[k] = convertV5SchemaToTFJSON(v)
-		}
-	}
+	ps.ResourceSchemas = convertMap(resp.ResourceSchemas, convertV5SchemaToTFJSON)
 
 	// Data source schemas
-	if len(resp.DataSourceSchemas) > 0 {
-		ps.DataSourceSchemas = make(map[string]*tfjson.Schema, len(resp.DataSourceSchemas))
-		for k, v := range resp.DataSourceSchemas {
-			ps.DataSourceSchemas[k] = convertV5SchemaToTFJSON(v)
-		}
-	}
+	ps.DataSourceSchemas = convertMap(resp.DataSourceSchemas, convertV5SchemaToTFJSON)
 
 	// Ephemeral resource schemas
-	if len(resp.EphemeralResourceSchemas) > 0 {
-		ps.EphemeralResourceSchemas = make(map[string]*tfjson.Schema, len(resp.EphemeralResourceSchemas))
-		for k, v := range resp.EphemeralResourceSchemas {
-			ps.EphemeralResourceSchemas[k] = convertV5SchemaToTFJSON(v)
-		}
-	}
+	ps.EphemeralResourceSchemas = convertMap(resp.EphemeralResourceSchemas, convertV5SchemaToTFJSON)
 
 	// Functions
-	if len(resp.Functions) > 0 {
-		ps.Functions = make(map[string]*tfjson.FunctionSignature, len(resp.Functions))
-		for k, v := range resp.Functions {
-			ps.Functions[k] = convertV5FunctionToTFJSON(v)
-		}
-	}
+	ps.Functions = convertMap(resp.Functions, convertV5FunctionToTFJSON)
 
 	return ps, nil
 }
@@ -363,60 +546,19 @@ func convertV6BlockToTFJSON(b *tfplugin6.Schema_Block) *tfjson.SchemaBlock {
 	}
 	sb := &tfjson.SchemaBlock{
 		Description:     b.GetDescription(),
-		DescriptionKind: tfjson.SchemaDescriptionKindPlain,
+		DescriptionKind: convertV6DescriptionKind(b.GetDescriptionKind()),
 		Deprecated:      b.GetDeprecated(),
-	}
-
-	// Description kind
-	switch b.GetDescriptionKind() {
-	case tfplugin6.StringKind_MARKDOWN:
-		sb.DescriptionKind = tfjson.SchemaDescriptionKindMarkdown
-	default:
-		sb.DescriptionKind = tfjson.SchemaDescriptionKindPlain
-	}
-
-	// Attributes
-	if len(b.GetAttributes()) > 0 {
-		sb.Attributes = make(map[string]*tfjson.SchemaAttribute, len(b.GetAttributes()))
-		for _, a := range b.GetAttributes() {
-			sa := &tfjson.SchemaAttribute{
-				Description:     a.GetDescription(),
-				Deprecated:      a.GetDeprecated(),
-				Required:        a.GetRequired(),
-				Optional:        a.GetOptional(),
-				Computed:        a.GetComputed(),
-				Sensitive:       a.GetSensitive(),
-				WriteOnly:       a.GetWriteOnly(),
-				DescriptionKind: tfjson.SchemaDescriptionKindPlain,
-			}
-			switch a.GetDescriptionKind() {
-			case tfplugin6.StringKind_MARKDOWN:
-				sa.DescriptionKind = tfjson.SchemaDescriptionKindMarkdown
-			default:
-				sa.DescriptionKind = tfjson.SchemaDescriptionKindPlain
-			}
-
-			// Attribute type (bytes contain JSON type signature). Prefer explicit type
-			if tbytes := a.GetType(); len(tbytes) > 0 {
-				if ctyType, err := decodeCtyTypeFromJSONBytes(tbytes); err == nil {
-					sa.AttributeType = ctyType
-				}
-			}
-
-			// Nested type
-			if a.NestedType != nil {
-				sa.AttributeNestedType = convertV6ObjectToNested(a.NestedType)
-			}
-
-			sb.Attributes[a.GetName()] = sa
-		}
+		Attributes:      convertV6Attributes(b.GetAttributes()),
 	}
 
 	// Block types
-	if len(b.GetBlockTypes()) > 0 {
-		sb.NestedBlocks = make(map[string]*tfjson.SchemaBlockType, len(b.GetBlockTypes()))
-		for _, nb := range b.GetBlockTypes() {
-			bt := &tfjson.SchemaBlockType{
+	if blockTypes := b.GetBlockTypes(); len(blockTypes) > 0 {
+		sb.NestedBlocks = make(map[string]*tfjson.SchemaBlockType, len(blockTypes))
+		// One allocation holds every block type of the block.
+		slab := make([]tfjson.SchemaBlockType, len(blockTypes))
+		for i, nb := range blockTypes {
+			bt := &slab[i]
+			*bt = tfjson.SchemaBlockType{
 				Block:    convertV6BlockToTFJSON(nb.GetBlock()),
 				MinItems: uint64(nb.GetMinItems()),
 				MaxItems: uint64(nb.GetMaxItems()),
@@ -442,41 +584,61 @@ func convertV6BlockToTFJSON(b *tfplugin6.Schema_Block) *tfjson.SchemaBlock {
 	return sb
 }
 
-func convertV6ObjectToNested(o *tfplugin6.Schema_Object) *tfjson.SchemaNestedAttributeType {
-	if o == nil {
+// convertV6Attributes converts the attributes of a block or nested
+// attribute type, returning nil if there are none. Large providers have
+// hundreds of thousands of attributes, so they are allocated together
+// rather than one by one.
+func convertV6Attributes(in []*tfplugin6.Schema_Attribute) map[string]*tfjson.SchemaAttribute {
+	if len(in) == 0 {
 		return nil
 	}
-	n := &tfjson.SchemaNestedAttributeType{}
-	if len(o.GetAttributes()) > 0 {
-		n.Attributes = make(map[string]*tfjson.SchemaAttribute, len(o.GetAttributes()))
-		for _, a := range o.GetAttributes() {
-			sa := &tfjson.SchemaAttribute{
-				Description:     a.GetDescription(),
-				Deprecated:      a.GetDeprecated(),
-				Required:        a.GetRequired(),
-				Optional:        a.GetOptional(),
-				Computed:        a.GetComputed(),
-				Sensitive:       a.GetSensitive(),
-				WriteOnly:       a.GetWriteOnly(),
-				DescriptionKind: tfjson.SchemaDescriptionKindPlain,
-			}
-			switch a.GetDescriptionKind() {
-			case tfplugin6.StringKind_MARKDOWN:
-				sa.DescriptionKind = tfjson.SchemaDescriptionKindMarkdown
-			default:
-				sa.DescriptionKind = tfjson.SchemaDescriptionKindPlain
-			}
-			// Attribute type
-			if tbytes := a.GetType(); len(tbytes) > 0 {
-				if ctyType, err := decodeCtyTypeFromJSONBytes(tbytes); err == nil {
-					sa.AttributeType = ctyType
-				}
-			}
-			if a.NestedType != nil {
-				sa.AttributeNestedType = convertV6ObjectToNested(a.NestedType)
+	out := make(map[string]*tfjson.SchemaAttribute, len(in))
+	slab := make([]tfjson.SchemaAttribute, len(in))
+	for i, a := range in {
+		sa := &slab[i]
+		*sa = tfjson.SchemaAttribute{
+			Description:     a.GetDescription(),
+			DescriptionKind: convertV6DescriptionKind(a.GetDescriptionKind()),
+			Deprecated:      a.GetDeprecated(),
+			Required:        a.GetRequired(),
+			Optional:        a.GetOptional(),
+			Computed:        a.GetComputed(),
+			Sensitive:       a.GetSensitive(),
+			WriteOnly:       a.GetWriteOnly(),
+		}
+
+		// Attribute type (bytes contain JSON type signature). Prefer explicit type
+		if tbytes := a.GetType(); len(tbytes) > 0 {
+			if ctyType, err := decodeCtyTypeFromJSONBytes(tbytes); err == nil {
+				sa.AttributeType = ctyType
 			}
-			n.Attributes[a.GetName()] = sa
 		}
+
+		// Nested type
+		if a.NestedType != nil {
+			sa.AttributeNestedType = convertV6ObjectToNested(a.NestedType)
+		}
+
+		out[a.GetName()] = sa
+	}
+	return out
+}
+
+// convertV6DescriptionKind maps a proto string kind onto terraform-json,
+// defaulting to plain text.
+func convertV6DescriptionKind(k tfplugin6.StringKind) tfjson.SchemaDescriptionKind {
+	if k == tfplugin6.StringKind_MARKDOWN {
+		return tfjson.SchemaDescriptionKindMarkdown
+	}
+	return tfjson.SchemaDescriptionKindPlain
+}
+
+func convertV6ObjectToNested(o *tfplugin6.Schema_Object) *tfjson.SchemaNestedAttributeType {
+	if o == nil {
+		return nil
+	}
+	n := &tfjson.SchemaNestedAttributeType{
+		Attributes: convertV6Attributes(o.GetAttributes()),
 	}
 
 	switch o.GetNesting() {
@@ -566,44 +728,29 @@ func convertV5BlockToTFJSON(b *tfplugin5.Schema_Block) *tfjson.SchemaBlock {
 	// Reuse v6 implementation by mapping types
 	sb := &tfjson.SchemaBlock{
 		Description:     b.GetDescription(),
-		DescriptionKind: tfjson.SchemaDescriptionKindPlain,
+		DescriptionKind: convertV5DescriptionKind(b.GetDescriptionKind()),
 		Deprecated:      b.GetDeprecated(),
 	}
 
-	switch b.GetDescriptionKind() {
-	case tfplugin5.StringKind_MARKDOWN:
-		sb.DescriptionKind = tfjson.SchemaDescriptionKindMarkdown
-	default:
-		sb.DescriptionKind = tfjson.SchemaDescriptionKindPlain
-	}
-
-	if len(b.GetAttributes()) > 0 {
-		sb.Attributes = make(map[string]*tfjson.SchemaAttribute, len(b.GetAttributes()))
-		for _, a := range b.GetAttributes() {
-			sa := &tfjson.SchemaAttribute{
+	if attrs := b.GetAttributes(); len(attrs) > 0 {
+		sb.Attributes = make(map[string]*tfjson.SchemaAttribute, len(attrs))
+		// One allocation holds every attribute of the block; see
+		// convertV6Attributes.
+		slab := make([]tfjson.SchemaAttribute, len(attrs))
+		for i, a := range attrs {
+			sa := &slab[i]
+			*sa = tfjson.SchemaAttribute{
 				Description:     a.GetDescription(),
+				DescriptionKind: convertV5DescriptionKind(a.GetDescriptionKind()),
 				Deprecated:      a.GetDeprecated(),
 				Required:        a.GetRequired(),
 				Optional:        a.GetOptional(),
 				Computed:        a.GetComputed(),
 				Sensitive:       a.GetSensitive(),
 				WriteOnly:       a.GetWriteOnly(),
-				DescriptionKind: tfjson.SchemaDescriptionKindPlain,
 			}
-			switch a.GetDescriptionKind() {
-			case tfplugin5.StringKind_MARKDOWN:
-				sa.DescriptionKind = tfjson.SchemaDescriptionKindMarkdown
-			default:
-				sa.DescriptionKind = tfjson.SchemaDescriptionKindPlain
-			}
-
-			// Nested type (not available in v5 Attribute schema)
-			// v5 uses raw type bytes on the Attribute, so we avoid attempting to access NestedType here
-			// if a.NestedType != nil {
-			//     sa.AttributeNestedType = convertV5ObjectToNested(a.NestedType)
-			// }
 
-			// Attribute type
+			// v5 attributes have no nested types, only raw type bytes.
 			if tbytes := a.GetType(); len(tbytes) > 0 {
 				if ctyType, err := decodeCtyTypeFromJSONBytes(tbytes); err == nil {
 					sa.AttributeType = ctyType
@@ -614,10 +761,12 @@ func convertV5BlockToTFJSON(b *tfplugin5.Schema_Block) *tfjson.SchemaBlock {
 		}
 	}
 
-	if len(b.GetBlockTypes()) > 0 {
-		sb.NestedBlocks = make(map[string]*tfjson.SchemaBlockType, len(b.GetBlockTypes()))
-		for _, nb := range b.GetBlockTypes() {
-			bt := &tfjson.SchemaBlockType{
+	if blockTypes := b.GetBlockTypes(); len(blockTypes) > 0 {
+		sb.NestedBlocks = make(map[string]*tfjson.SchemaBlockType, len(blockTypes))
+		slab := make([]tfjson.SchemaBlockType, len(blockTypes))
+		for i, nb := range blockTypes {
+			bt := &slab[i]
+			*bt = tfjson.SchemaBlockType{
 				Block:    convertV5BlockToTFJSON(nb.GetBlock()),
 				MinItems: uint64(nb.GetMinItems()),
 				MaxItems: uint64(nb.GetMaxItems()),
@@ -643,6 +792,14 @@ func convertV5BlockToTFJSON(b *tfplugin5.Schema_Block) *tfjson.SchemaBlock {
 	return sb
 }
 
+// convertV5DescriptionKind is convertV6DescriptionKind for protocol 5.
+func convertV5DescriptionKind(k tfplugin5.StringKind) tfjson.SchemaDescriptionKind {
+	if k == tfplugin5.StringKind_MARKDOWN {
+		return tfjson.SchemaDescriptionKindMarkdown
+	}
+	return tfjson.SchemaDescriptionKindPlain
+}
+
 func convertV5FunctionToTFJSON(f *tfplugin5.Function) *tfjson.FunctionSignature {
 	if f == nil {
 		return nil
@@ -694,13 +851,57 @@ func convertV5FunctionToTFJSON(f *tfplugin5.Function) *tfjson.FunctionSignature
 	return fs
 }
 
-// decodeCtyTypeFromJSONBytes attempts to parse provider-sent JSON type bytes into cty.Type.
-// It first uses tftypes.ParseJSONType for robust decoding, then converts to cty.Type
-// via JSON, falling back to direct cty/json parsing if needed.
+// ctyTypeCacheLimit bounds the number of type signatures remembered by
+// decodeCtyTypeFromJSONBytes. Providers use a small vocabulary of types
+// over and over, so only unusual schemas reach it.
+const ctyTypeCacheLimit = 4096
+
+// ctyTypeCache holds decoded type signatures keyed by their JSON. cty types
+// are immutable, so one value is shared by every attribute with the type.
+var ctyTypeCache = struct {
+	sync.RWMutex
+	m map[string]cty.Type
+}{m: make(map[string]cty.Type)}
+
+// decodeCtyTypeFromJSONBytes parses provider-sent JSON type bytes into a
+// cty.Type, remembering the result for the next attribute with the same
+// type. It runs once per attribute, so the primitive types most attributes
+// have are recognised without parsing or locking.
 func decodeCtyTypeFromJSONBytes(buf []byte) (cty.Type, error) {
 	if len(buf) == 0 {
 		return cty.NilType, fmt.Errorf("empty type bytes")
 	}
+	switch string(buf) {
+	case `"string"`:
+		return cty.String, nil
+	case `"number"`:
+		return cty.Number, nil
+	case `"bool"`:
+		return cty.Bool, nil
+	case `"dynamic"`:
+		return cty.DynamicPseudoType, nil
+	}
+	ctyTypeCache.RLock()
+	ty, ok := ctyTypeCache.m[string(buf)]
+	ctyTypeCache.RUnlock()
+	if ok {
+		return ty, nil
+	}
+	ty, err := decodeCtyType(buf)
+	if err != nil {
+		return cty.NilType, err
+	}
+	ctyTypeCache.Lock()
+	if len(ctyTypeCache.m) < ctyTypeCacheLimit {
+		ctyTypeCache.m[string(buf)] = ty
+	}
+	ctyTypeCache.Unlock()
+	return ty, nil
+}
+
+// decodeCtyType parses JSON type bytes into a cty.Type, accepting a subset of
+// looser encodings that cty/json rejects.
+func decodeCtyType(buf []byte) (cty.Type, error) {
 	// Providers send JSON-encoded Terraform type signatures. Try cty/json first.
 	if ty, err := ctyjson.UnmarshalType(buf); err == nil {
 		return ty, nil
@@ -767,9 +968,13 @@ func decodeCtyTypeFromJSONBytes(buf []byte) (cty.Type, error) {
 	return cty.NilType, fmt.Errorf("invalid complex type description")
 }
 
-// primitiveFromString maps simple string names to cty primitive types.
+// primitiveFromString maps simple string names to cty primitive types and
+// the dynamic pseudo-type, which attributes accepting any value (such as
+// azapi's body) are declared with.
 func primitiveFromString(s string) (cty.Type, error) {
 	switch s {
+	case "dynamic":
+		return cty.DynamicPseudoType, nil
 	case "string":
 		return cty.String, nil
 	case "number":
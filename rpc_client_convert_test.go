@@ -1,6 +1,9 @@
 package tfpluginschema
 
 import (
+	"encoding/json"
+	"fmt"
+	"runtime"
 	"testing"
 
 	tfjson "github.com/hashicorp/terraform-json"
@@ -8,6 +11,7 @@ import (
 	"github.com/matt-FFFFFF/tfpluginschema/tfplugin6"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestConvertV6BlockToTFJSON_NestingModesAndAttributes(t *testing.T) {
@@ -127,3 +131,190 @@ func TestDecodeCtyTypeFromJSONBytes_Cases(t *testing.T) {
 	_, err = decodeCtyTypeFromJSONBytes([]byte(`{"object":{"a":"number"}}`))
 	require.NoError(t, err)
 }
+
+func TestDecodeCtyTypeFromJSONBytes_Cache(t *testing.T) {
+	buf := []byte(`["map",["set","string"]]`)
+	first, err := decodeCtyTypeFromJSONBytes(buf)
+	require.NoError(t, err)
+	ctyTypeCache.RLock()
+	cached, ok := ctyTypeCache.m[string(buf)]
+	ctyTypeCache.RUnlock()
+	require.True(t, ok)
+	assert.True(t, first.Equals(cached))
+
+	// The cache keeps a copy of the type bytes, so reusing buf for another
+	// type does not return the cached one.
+	buf[2] = 'x'
+	_, err = decodeCtyTypeFromJSONBytes(buf)
+	assert.Error(t, err)
+
+	ctyTypeCache.RLock()
+	n := len(ctyTypeCache.m)
+	ctyTypeCache.RUnlock()
+	assert.LessOrEqual(t, n, ctyTypeCacheLimit)
+}
+
+func TestDecodeCtyTypeFromJSONBytes_Primitives(t *testing.T) {
+	for in, want := range map[string]cty.Type{
+		`"string"`:  cty.String,
+		`"number"`:  cty.Number,
+		`"bool"`:    cty.Bool,
+		`"dynamic"`: cty.DynamicPseudoType,
+	} {
+		got, err := decodeCtyTypeFromJSONBytes([]byte(in))
+		require.NoError(t, err)
+		assert.True(t, want.Equals(got), in)
+		slow, err := decodeCtyType([]byte(in))
+		require.NoError(t, err)
+		assert.True(t, slow.Equals(got), "the fast path agrees with full parsing for %s", in)
+	}
+	_, err := decodeCtyTypeFromJSONBytes([]byte(`"strin"`))
+	assert.Error(t, err)
+}
+
+// BenchmarkDecodeCtyTypeFromJSONBytes measures type decoding, which runs
+// once per attribute, for primitive types, repeated complex types and
+// complex types parsed afresh.
+func BenchmarkDecodeCtyTypeFromJSONBytes(b *testing.B) {
+	primitive := []byte(`"string"`)
+	complexType := []byte(`["list",["object",{"name":"string","value":"number"}]]`)
+	for name, decode := range map[string]func() (cty.Type, error){
+		"primitive": func() (cty.Type, error) { return decodeCtyTypeFromJSONBytes(primitive) },
+		"memoized":  func() (cty.Type, error) { return decodeCtyTypeFromJSONBytes(complexType) },
+		"uncached":  func() (cty.Type, error) { return decodeCtyType(complexType) },
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertV6BlockToTFJSON_Independent(t *testing.T) {
+	b := convertV6BlockToTFJSON(largeV6Response(1).ResourceSchemas["example_resource_0"].Block)
+	b.Attributes["attr_0"].Optional = false
+	assert.True(t, b.Attributes["attr_1"].Optional, "attributes sharing an allocation are independent")
+	b.NestedBlocks["block_0"].MaxItems = 3
+	assert.Zero(t, b.NestedBlocks["block_1"].MaxItems)
+}
+
+func TestDecodeCtyTypeFromJSONBytes_Dynamic(t *testing.T) {
+	tests := map[string]cty.Type{
+		`"dynamic"`:                     cty.DynamicPseudoType,
+		`["list","dynamic"]`:            cty.List(cty.DynamicPseudoType),
+		`["object",{"body":"dynamic"}]`: cty.Object(map[string]cty.Type{"body": cty.DynamicPseudoType}),
+		`{"map":"dynamic"}`:             cty.Map(cty.DynamicPseudoType),
+		`{"object":{"body":"dynamic"}}`: cty.Object(map[string]cty.Type{"body": cty.DynamicPseudoType}),
+	}
+	for in, want := range tests {
+		ty, err := decodeCtyTypeFromJSONBytes([]byte(in))
+		require.NoError(t, err, in)
+		assert.True(t, want.Equals(ty), "%s decoded to %#v", in, ty)
+	}
+}
+
+func TestConvert_DynamicAttributeRoundTrip(t *testing.T) {
+	// azapi declares its body attribute as dynamic, in both protocols.
+	v6 := convertV6SchemaToTFJSON(&tfplugin6.Schema{Block: &tfplugin6.Schema_Block{Attributes: []*tfplugin6.Schema_Attribute{
+		{Name: "body", Type: []byte(`"dynamic"`), Optional: true},
+	}}})
+	v5 := convertV5SchemaToTFJSON(&tfplugin5.Schema{Block: &tfplugin5.Schema_Block{Attributes: []*tfplugin5.Schema_Attribute{
+		{Name: "body", Type: []byte(`"dynamic"`), Optional: true},
+	}}})
+	fn := convertV6FunctionToTFJSON(&tfplugin6.Function{
+		Parameters: []*tfplugin6.Function_Parameter{{Name: "value", Type: []byte(`"dynamic"`)}},
+		Return:     &tfplugin6.Function_Return{Type: []byte(`"dynamic"`)},
+	})
+
+	for _, schema := range []*tfjson.Schema{v6, v5} {
+		assert.Equal(t, cty.DynamicPseudoType, schema.Block.Attributes["body"].AttributeType)
+
+		// The type survives the JSON form used by schema bundles and
+		// snapshots.
+		buf, err := json.Marshal(schema)
+		require.NoError(t, err)
+		assert.Contains(t, string(buf), `"type":"dynamic"`)
+		var decoded tfjson.Schema
+		require.NoError(t, json.Unmarshal(buf, &decoded))
+		assert.Equal(t, cty.DynamicPseudoType, decoded.Block.Attributes["body"].AttributeType)
+	}
+	assert.Equal(t, cty.DynamicPseudoType, fn.Parameters[0].Type)
+	assert.Equal(t, cty.DynamicPseudoType, fn.ReturnType)
+}
+
+// largeV6Response builds a synthetic schema response roughly the size of
+// azurerm: n resources, each with a few dozen attributes and nested blocks.
+func largeV6Response(n int) *tfplugin6.GetProviderSchema_Response {
+	block := func() *tfplugin6.Schema_Block {
+		b := &tfplugin6.Schema_Block{}
+		for i := range 30 {
+			b.Attributes = append(b.Attributes, &tfplugin6.Schema_Attribute{
+				Name:        fmt.Sprintf("attr_%d", i),
+				Description: "An attribute.",
+				Optional:    true,
+				Type:        []byte(`["list",["object",{"name":"string","value":"number"}]]`),
+			})
+		}
+		for i := range 3 {
+			b.BlockTypes = append(b.BlockTypes, &tfplugin6.Schema_NestedBlock{
+				TypeName: fmt.Sprintf("block_%d", i),
+				Nesting:  tfplugin6.Schema_NestedBlock_LIST,
+				Block: &tfplugin6.Schema_Block{Attributes: []*tfplugin6.Schema_Attribute{
+					{Name: "id", Type: []byte(`"string"`), Computed: true},
+				}},
+			})
+		}
+		return b
+	}
+	resp := &tfplugin6.GetProviderSchema_Response{
+		ResourceSchemas:   make(map[string]*tfplugin6.Schema, n),
+		DataSourceSchemas: make(map[string]*tfplugin6.Schema, n/2),
+	}
+	for i := range n {
+		resp.ResourceSchemas[fmt.Sprintf("example_resource_%d", i)] = &tfplugin6.Schema{Block: block()}
+		if i%2 == 0 {
+			resp.DataSourceSchemas[fmt.Sprintf("example_data_%d", i)] = &tfplugin6.Schema{Block: block()}
+		}
+	}
+	return resp
+}
+
+func TestConvertMapN_ParallelMatchesSerial(t *testing.T) {
+	resp := largeV6Response(parallelConvertThreshold * 2)
+	serial := convertMapN(resp.ResourceSchemas, 1, convertV6SchemaToTFJSON)
+	parallel := convertMapN(resp.ResourceSchemas, 8, convertV6SchemaToTFJSON)
+	require.Len(t, parallel, len(resp.ResourceSchemas))
+	assert.Equal(t, serial, parallel)
+}
+
+func TestConvertMap_Empty(t *testing.T) {
+	assert.Nil(t, convertMap(map[string]*tfplugin6.Schema{}, convertV6SchemaToTFJSON))
+	assert.Nil(t, convertMap[*tfplugin6.Function](nil, convertV6FunctionToTFJSON))
+}
+
+// BenchmarkConvertV6ResponseToTFJSON compares serial conversion with the
+// worker pool on an azurerm-sized response, reporting allocations. Run with
+// -cpu to vary the pool size, e.g. -cpu 1,4,8, or use "make bench".
+func BenchmarkConvertV6ResponseToTFJSON(b *testing.B) {
+	resp := largeV6Response(1500)
+	b.ReportAllocs()
+	for name, workers := range map[string]int{"serial": 1, "parallel": runtime.GOMAXPROCS(0)} {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				convertMapN(resp.ResourceSchemas, workers, convertV6SchemaToTFJSON)
+				convertMapN(resp.DataSourceSchemas, workers, convertV6SchemaToTFJSON)
+			}
+		})
+	}
+	b.Run("response", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := convertV6ResponseToTFJSON(resp); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Mock implementations for testing
@@ -425,7 +427,7 @@ func TestConstants(t *testing.T) {
 
 func TestNewGrpcClient_InvalidPath(t *testing.T) {
 	// Test with a non-existent provider path
-	_, err := newGrpcClient("/nonexistent/provider/path/that/does/not/exist")
+	_, err := newGrpcClient("/nonexistent/provider/path/that/does/not/exist", nil, 0)
 
 	// Should return an error
 	assert.Error(t, err)
@@ -720,6 +722,38 @@ func TestUniversalProviderClient_Schema_FallbackV6ToV5(t *testing.T) {
 	mockV5.AssertExpectations(t)
 }
 
+func TestUniversalProviderClient_RawSchema_MessageTooLarge(t *testing.T) {
+	mockV6 := &mockV6SchemaClient{}
+	innerV6 := &providerGRPCClient[*tfplugin6.GetProviderSchema_Request, *tfplugin6.GetProviderSchema_Response]{grpcClient: mockV6}
+	client := &universalProviderClient{v6: &providerGRPCClientV6{providerGRPCClient: innerV6}}
+
+	tooLarge := status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5000000 vs. 4194304)")
+	mockV6.On("getSchema", mock.Anything, mock.Anything, mock.Anything).Return(nil, tooLarge)
+
+	_, err := client.rawSchema()
+	assert.ErrorIs(t, err, ErrSchemaMessageTooLarge)
+	assert.Contains(t, err.Error(), "WithGRPCMaxRecvMsgSize")
+	assert.Contains(t, err.Error(), "larger than max")
+	mockV6.AssertExpectations(t)
+}
+
+func TestUniversalProviderClient_Schema_KeepsUnderlyingErrors(t *testing.T) {
+	mockV5 := &mockV5SchemaClient{}
+	innerV5 := &providerGRPCClient[*tfplugin5.GetProviderSchema_Request, *tfplugin5.GetProviderSchema_Response]{grpcClient: mockV5}
+	client := &universalProviderClient{v5: &providerGRPCClientV5{providerGRPCClient: innerV5}}
+
+	mockV5.On("getSchema", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("connection reset"))
+
+	_, err := client.schema()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "either V5 or V6")
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.NotErrorIs(t, err, ErrSchemaMessageTooLarge)
+
+	_, err = (&universalProviderClient{}).rawSchema()
+	assert.EqualError(t, err, "failed to get provider schema for either V5 or V6 protocols")
+}
+
 // Small test to reference provider-level mocks so static analysis doesn't flag them as unused.
 func TestHelper_UseProviderMocks(t *testing.T) {
 	t.Helper()
@@ -0,0 +1,270 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// schemaBundleExt is the file extension of schemas in a schema bundle.
+const schemaBundleExt = ".json"
+
+// ErrSchemaNotBundled is returned when a provider schema is not in the
+// schema bundle and provider plugins cannot be executed to obtain it.
+var ErrSchemaNotBundled = errors.New("provider schema not in the schema bundle and plugin execution is unavailable")
+
+// WithSchemaBundle serves provider schemas from pre-generated JSON files in
+// fsys, such as an embed.FS, instead of downloading and executing the
+// provider. Files are laid out as
+//
+//	<registry-type>/<namespace>/<name>/<version>.json
+//
+// with lower-cased segments, for example "opentofu/hashicorp/aws/5.40.0.json".
+// Each file holds either a single provider schema, as written by
+// WriteSchemaBundle, or the output of "terraform providers schema -json"
+// (or its OpenTofu equivalent) containing the provider. Files may instead
+// be zstd-compressed and named "<version>.json.zst"; see
+// WithSchemaCompression.
+//
+// Requests for providers that are not in the bundle fall back to executing
+// the provider unless that is disabled; see WithPluginExec.
+func WithSchemaBundle(fsys fs.FS) ServerOption {
+	return func(s *Server) {
+		s.schemaBundle = fsys
+	}
+}
+
+// WithSchemaBundleDir is WithSchemaBundle for a directory on disk.
+func WithSchemaBundleDir(dir string) ServerOption {
+	return WithSchemaBundle(os.DirFS(dir))
+}
+
+// WithPluginExec controls whether provider plugins may be executed to read
+// their schemas. It defaults to true, except on platforms that cannot start
+// subprocesses (js/wasm and wasip1). When disabled, schemas are served only
+// from the schema bundle and other requests fail with ErrSchemaNotBundled.
+func WithPluginExec(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.pluginExec = enabled
+	}
+}
+
+// schemaBundlePath returns the path of request's schema within a bundle.
+// request.Version must already be a concrete version.
+func schemaBundlePath(request Request) string {
+	request = normalizedRequest(request)
+	version := request.Version
+	if v, err := goversion.NewVersion(version); err == nil {
+		version = v.String()
+	}
+	return path.Join(
+		cachePathSegment(string(request.RegistryType)),
+		cachePathSegment(request.Namespace),
+		cachePathSegment(request.Name),
+		cachePathSegment(version)+schemaBundleExt,
+	)
+}
+
+// bundledSchema returns request's schema from the schema bundle. It returns
+// false when no bundle is configured or the provider is not in it.
+func (s *Server) bundledSchema(request Request) (*lazySchema, bool, error) {
+	if s.schemaBundle == nil {
+		return nil, false, nil
+	}
+	return readBundledSchema(s.schemaBundle, request, s.logger(logComponentCache))
+}
+
+// readBundledSchema returns request's schema from the bundle in fsys, or
+// false if it is not in it. Entries that fail to decode are logged to l,
+// which may be nil.
+func readBundledSchema(fsys fs.FS, request Request, l *slog.Logger) (*lazySchema, bool, error) {
+	data, p, err := readBundleFile(fsys, schemaBundlePath(request))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read schema bundle file %s: %w", p, err)
+	}
+	ls, err := decodeBundledSchema(data, request, l)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode schema bundle file %s: %w", p, err)
+	}
+	return ls, true, nil
+}
+
+// readBundleFile returns the contents of the bundle file at p, or of its
+// compressed form if p does not exist, and the path that was read.
+func readBundleFile(fsys fs.FS, p string) ([]byte, string, error) {
+	data, err := fs.ReadFile(fsys, p)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = fs.ReadFile(fsys, p+zstdExt)
+		if err == nil {
+			p += zstdExt
+		}
+	}
+	return data, p, err
+}
+
+// statBundleFile is readBundleFile without reading the file.
+func statBundleFile(fsys fs.FS, p string) error {
+	_, err := fs.Stat(fsys, p)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, err = fs.Stat(fsys, p+zstdExt)
+	}
+	return err
+}
+
+// decodeBundledSchema decodes a schema bundle file, compressed or not,
+// picking request's provider out of "providers schema -json" output. Only
+// the document's structure is decoded; its entries are decoded when first
+// requested (see newLazySchemaJSON).
+func decodeBundledSchema(data []byte, request Request, l *slog.Logger) (*lazySchema, error) {
+	data, err := decompressSchema(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		providerSchemaJSON
+		ProviderSchemas map[string]json.RawMessage `json:"provider_schemas"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.ProviderSchemas == nil {
+		return newLazySchemaJSON(&doc.providerSchemaJSON, l), nil
+	}
+
+	raw, ok := pickProviderSchema(doc.ProviderSchemas, request, func(raw json.RawMessage) bool {
+		return len(raw) > 0 && string(raw) != "null"
+	})
+	if !ok {
+		return nil, fmt.Errorf("no schema for %s/%s in provider_schemas", request.Namespace, request.Name)
+	}
+	var ps providerSchemaJSON
+	if err := json.Unmarshal(raw, &ps); err != nil {
+		return nil, err
+	}
+	return newLazySchemaJSON(&ps, l), nil
+}
+
+// pickProviderSchema returns request's provider from the provider_schemas
+// of "providers schema -json" output, which are keyed by source address.
+// Entries for which present returns false are skipped. The registry host is
+// ignored, since Terraform and OpenTofu record different hosts for the same
+// provider.
+func pickProviderSchema[V any](schemas map[string]V, request Request, present func(V) bool) (V, bool) {
+	suffix := "/" + strings.ToLower(request.Namespace+"/"+request.Name)
+	for source, ps := range schemas {
+		if present(ps) && strings.HasSuffix(strings.ToLower(source), suffix) {
+			return ps, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// bundledVersions returns the versions of the provider present in the schema
+// bundle, in no particular order.
+func (s *Server) bundledVersions(req VersionsRequest) (goversion.Collection, error) {
+	if s.schemaBundle == nil {
+		return nil, nil
+	}
+	return readBundledVersions(s.schemaBundle, req)
+}
+
+// readBundledVersions returns the versions of the provider present in the
+// bundle in fsys, in no particular order.
+func readBundledVersions(fsys fs.FS, req VersionsRequest) (goversion.Collection, error) {
+	key := normalizedRequest(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType})
+	dir := path.Join(
+		cachePathSegment(string(key.RegistryType)),
+		cachePathSegment(key.Namespace),
+		cachePathSegment(key.Name),
+	)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read schema bundle directory: %w", err)
+	}
+
+	var versions goversion.Collection
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(strings.TrimSuffix(e.Name(), zstdExt), schemaBundleExt)
+		if e.IsDir() || !ok {
+			continue
+		}
+		if v, err := goversion.NewVersion(name); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// resolveWriteRequest resolves the provider alias, registry and version of
+// a request whose schema is written to files named after them.
+func (s *Server) resolveWriteRequest(request Request) (Request, error) {
+	var err error
+	if request.Namespace, request.Name, err = s.resolveProviderAlias(request.Namespace, request.Name); err != nil {
+		return request, err
+	}
+	request.RegistryType = s.registryOrDefault(request.RegistryType)
+	if !request.fixedVersion() {
+		return request.fixVersion(s)
+	}
+	return request, nil
+}
+
+// WriteSchemaBundle reads the schema of the provider and writes it to dir
+// in the layout read by WithSchemaBundle, so that a bundle can be generated
+// where providers can be executed and served where they cannot. A version
+// constraint is resolved to the latest matching version first. The schema
+// is encoded by MarshalCanonical, so that bundles diff well in version
+// control, and compressed as set by WithSchemaCompression. The path of the written file
+// is returned.
+func (s *Server) WriteSchemaBundle(request Request, dir string) (string, error) {
+	request, err := s.resolveWriteRequest(request)
+	if err != nil {
+		return "", err
+	}
+
+	ls, err := s.readSchema(request)
+	if err != nil {
+		return "", err
+	}
+	ps, err := ls.providerSchema()
+	if err != nil {
+		return "", err
+	}
+	data, err := MarshalCanonical(ps)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode provider schema: %w", err)
+	}
+	if data, err = CompressSchema(data, s.schemaCompression); err != nil {
+		return "", fmt.Errorf("failed to compress provider schema: %w", err)
+	}
+
+	file := filepath.Join(dir, filepath.FromSlash(schemaBundlePath(request)))
+	// Only one of the plain and compressed files may exist, since the
+	// plain one is read first.
+	stale := file + zstdExt
+	if s.schemaCompression != CompressionNone {
+		file, stale = stale, file
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create schema bundle directory: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write schema bundle file: %w", err)
+	}
+	if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove schema bundle file: %w", err)
+	}
+	return file, nil
+}
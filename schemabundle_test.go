@@ -0,0 +1,105 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bundledAWSSchema = `{
+	"provider": {"version": 0, "block": {"attributes": {"region": {"type": "string", "optional": true}}}},
+	"resource_schemas": {"aws_instance": {"version": 1, "block": {"attributes": {"ami": {"type": "string", "required": true}}}}}
+}`
+
+const bundledProvidersSchemaJSON = `{
+	"format_version": "1.0",
+	"provider_schemas": {
+		"registry.terraform.io/hashicorp/random": {
+			"provider": {"version": 0, "block": {}},
+			"resource_schemas": {"random_pet": {"version": 0, "block": {}}}
+		}
+	}
+}`
+
+func TestServer_SchemaBundle(t *testing.T) {
+	bundle := fstest.MapFS{
+		"opentofu/hashicorp/aws/5.40.0.json":     {Data: []byte(bundledAWSSchema)},
+		"terraform/hashicorp/random/3.6.0.json":  {Data: []byte(bundledProvidersSchemaJSON)},
+		"opentofu/hashicorp/broken/1.0.0.json":   {Data: []byte(`{`)},
+		"opentofu/hashicorp/aws/not-a-version.x": {Data: []byte(`{}`)},
+	}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	schema, err := s.GetResourceSchema(Request{Namespace: "HashiCorp", Name: "aws", Version: "v5.40"}, "aws_instance")
+	require.NoError(t, err)
+	assert.True(t, schema.Block.Attributes["ami"].Required)
+
+	provider, err := s.GetProviderSchema(Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0"})
+	require.NoError(t, err)
+	assert.Contains(t, provider.Block.Attributes, "region")
+
+	resources, err := s.ListResources(Request{Namespace: "hashicorp", Name: "random", Version: "3.6.0", RegistryType: RegistryTypeTerraform})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"random_pet"}, resources)
+
+	_, err = s.ListResources(Request{Namespace: "hashicorp", Name: "broken", Version: "1.0.0"})
+	assert.ErrorContains(t, err, "failed to decode schema bundle file")
+
+	_, err = s.ListResources(Request{Namespace: "hashicorp", Name: "aws", Version: "5.41.0"})
+	assert.ErrorIs(t, err, ErrSchemaNotBundled)
+}
+
+func TestServer_SchemaBundle_OfflineVersions(t *testing.T) {
+	bundle := fstest.MapFS{
+		"opentofu/hashicorp/aws/5.40.0.json": {Data: []byte(bundledAWSSchema)},
+		"opentofu/hashicorp/aws/5.9.0.json":  {Data: []byte(bundledAWSSchema)},
+	}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithOffline(true))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "5.40.0", versions[1].String())
+
+	resources, err := s.ListResources(Request{Namespace: "hashicorp", Name: "aws", Version: "~> 5.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_instance"}, resources)
+}
+
+// BenchmarkDecodeBundledSchema_SingleResource shows the cost of looking up
+// one resource of a large persisted schema compared with decoding it all.
+func BenchmarkDecodeBundledSchema_SingleResource(b *testing.B) {
+	data := largeSchemaJSON(b, 3000)
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	b.Run("lazy", func(b *testing.B) {
+		for b.Loop() {
+			ls, err := decodeBundledSchema(data, req, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, ok, _ := ls.resource("example_resource_42"); !ok {
+				b.Fatal("missing resource")
+			}
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		for b.Loop() {
+			ls, err := decodeBundledSchema(data, req, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if ps, _ := ls.providerSchema(); ps.ResourceSchemas["example_resource_42"] == nil {
+				b.Fatal("missing resource")
+			}
+		}
+	})
+}
+
+func TestSchemaBundlePath(t *testing.T) {
+	assert.Equal(t, "opentofu/azure/azapi/2.5.0.json", schemaBundlePath(Request{Namespace: "Azure", Name: "AzAPI", Version: "v2.5"}))
+	assert.Equal(t, "terraform/hashicorp/aws/5.40.0.json", schemaBundlePath(Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0", RegistryType: RegistryTypeTerraform}))
+}
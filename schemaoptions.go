@@ -0,0 +1,109 @@
+package tfpluginschema
+
+import (
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// SchemaOption configures a single call to one of the Get*Schema methods.
+type SchemaOption func(*schemaOptions)
+
+type schemaOptions struct {
+	withoutDescriptions   bool
+	plaintextDescriptions bool
+	expandObjectTypes     bool
+}
+
+// WithoutDescriptions returns the schema without descriptions: block and
+// attribute descriptions and their kinds, and function summaries and
+// descriptions, including any markdown in them. Consumers that only need
+// the structure, such as policy engines, get a much smaller schema; for
+// azurerm descriptions are most of its size. The result is always a copy,
+// so the Server's cached schema keeps its descriptions.
+func WithoutDescriptions() SchemaOption {
+	return func(o *schemaOptions) {
+		o.withoutDescriptions = true
+	}
+}
+
+func newSchemaOptions(opts []SchemaOption) schemaOptions {
+	var o schemaOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// returnSchema applies the WithCloneSchemas policy and the call's options to
+// a cached schema.
+func (s *Server) returnSchema(schema *tfjson.Schema, opts []SchemaOption) *tfjson.Schema {
+	o := newSchemaOptions(opts)
+	if o.withoutDescriptions || o.plaintextDescriptions || o.expandObjectTypes {
+		schema = CloneSchema(schema)
+		if o.withoutDescriptions {
+			stripSchemaDescriptions(schema)
+		} else if o.plaintextDescriptions {
+			plaintextSchemaDescriptions(schema)
+		}
+		if o.expandObjectTypes && schema != nil {
+			expandBlockObjectTypes(schema.Block)
+		}
+		return schema
+	}
+	if s.cloneSchemas {
+		return CloneSchema(schema)
+	}
+	return schema
+}
+
+// returnFunction is returnSchema for function signatures.
+func (s *Server) returnFunction(f *tfjson.FunctionSignature, opts []SchemaOption) *tfjson.FunctionSignature {
+	o := newSchemaOptions(opts)
+	if o.withoutDescriptions {
+		f = CloneFunctionSignature(f)
+		f.Summary, f.Description = "", ""
+		for _, p := range f.Parameters {
+			if p != nil {
+				p.Description = ""
+			}
+		}
+		if f.VariadicParameter != nil {
+			f.VariadicParameter.Description = ""
+		}
+		return f
+	}
+	if s.cloneSchemas {
+		return CloneFunctionSignature(f)
+	}
+	return f
+}
+
+func stripSchemaDescriptions(s *tfjson.Schema) {
+	if s != nil {
+		stripBlockDescriptions(s.Block)
+	}
+}
+
+func stripBlockDescriptions(b *tfjson.SchemaBlock) {
+	if b == nil {
+		return
+	}
+	b.Description, b.DescriptionKind = "", ""
+	stripAttributeDescriptions(b.Attributes)
+	for _, nb := range b.NestedBlocks {
+		if nb != nil {
+			stripBlockDescriptions(nb.Block)
+		}
+	}
+}
+
+func stripAttributeDescriptions(attrs map[string]*tfjson.SchemaAttribute) {
+	for _, a := range attrs {
+		if a == nil {
+			continue
+		}
+		a.Description, a.DescriptionKind = "", ""
+		if a.AttributeNestedType != nil {
+			stripAttributeDescriptions(a.AttributeNestedType.Attributes)
+		}
+	}
+}
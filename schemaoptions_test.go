@@ -0,0 +1,53 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const describedSchema = `{
+	"provider": {"version": 0, "block": {"description": "Provider config.", "description_kind": "plain"}},
+	"resource_schemas": {"example_thing": {"version": 0, "block": {
+		"description": "A **thing**.", "description_kind": "markdown",
+		"attributes": {"name": {"type": "string", "required": true, "description": "The name.", "description_kind": "plain"}},
+		"block_types": {"rule": {"nesting_mode": "list", "block": {"description": "A rule.", "attributes": {"priority": {"type": "number", "optional": true, "description": "Priority."}}}}}
+	}}},
+	"functions": {"parse": {"summary": "Parse.", "description": "Parses input.", "return_type": "string",
+		"parameters": [{"name": "in", "description": "Input.", "type": "string"}]}}
+}`
+
+func TestServer_WithoutDescriptions(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	stripped, err := s.GetResourceSchema(req, "example_thing", WithoutDescriptions())
+	require.NoError(t, err)
+	assert.Empty(t, stripped.Block.Description)
+	assert.Empty(t, stripped.Block.DescriptionKind)
+	assert.Empty(t, stripped.Block.Attributes["name"].Description)
+	assert.True(t, stripped.Block.Attributes["name"].Required)
+	assert.Empty(t, stripped.Block.NestedBlocks["rule"].Block.Attributes["priority"].Description)
+
+	full, err := s.GetResourceSchema(req, "example_thing")
+	require.NoError(t, err)
+	assert.Equal(t, "A **thing**.", full.Block.Description, "the cached schema keeps its descriptions")
+	assert.Equal(t, "The name.", full.Block.Attributes["name"].Description)
+
+	provider, err := s.GetProviderSchema(req, WithoutDescriptions())
+	require.NoError(t, err)
+	assert.Empty(t, provider.Block.Description)
+
+	fn, err := s.GetFunctionSchema(req, "parse", WithoutDescriptions())
+	require.NoError(t, err)
+	assert.Empty(t, fn.Summary)
+	assert.Empty(t, fn.Description)
+	assert.Empty(t, fn.Parameters[0].Description)
+	fullFn, err := s.GetFunctionSchema(req, "parse")
+	require.NoError(t, err)
+	assert.Equal(t, "Input.", fullFn.Parameters[0].Description)
+}
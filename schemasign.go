@@ -0,0 +1,207 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// schemaBundleFormatVersion is the manifest format written by
+// SignSchemaBundle. VerifySchemaBundle rejects other versions.
+const schemaBundleFormatVersion = 1
+
+// SchemaBundleManifest lists the files of a schema bundle signed by
+// SignSchemaBundle. It is stored at the root of the bundle as
+// manifest.json, next to its signature in manifest.json.sig.
+type SchemaBundleManifest struct {
+	FormatVersion int          `json:"format_version"`
+	Created       time.Time    `json:"created"`
+	Files         []BundleFile `json:"files"`
+}
+
+// SignSchemaBundle signs the schema bundle in dir, as written by
+// WriteSchemaBundle, with key: it writes a manifest of the size and
+// SHA-256 of every file in dir, sorted by path, and its Ed25519 signature,
+// which VerifySchemaBundle checks. A manifest left by an earlier signing is
+// replaced, so the bundle must be signed again after files are added.
+func SignSchemaBundle(dir string, key ed25519.PrivateKey) (*SchemaBundleManifest, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid schema bundle signing key")
+	}
+	manifest := &SchemaBundleManifest{FormatVersion: schemaBundleFormatVersion, Created: time.Now().UTC()}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == bundleManifestName || rel == bundleSignatureName {
+			return nil
+		}
+		f, err := bundleFileOf(rel, p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schema bundle files: %w", err)
+	}
+	slices.SortFunc(manifest.Files, func(a, b BundleFile) int { return strings.Compare(a.Path, b.Path) })
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema bundle manifest: %w", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, bundleManifestName), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write schema bundle manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, bundleSignatureName), []byte(signature), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write schema bundle signature: %w", err)
+	}
+	return manifest, nil
+}
+
+// VerifySchemaBundle checks the manifest of the schema bundle in fsys, as
+// signed by SignSchemaBundle, against key, and returns the bundle for
+// WithSchemaBundle or BundleSource. The returned file system only has the
+// files the manifest lists; each is checked against its size and SHA-256
+// whenever it is read, and one that does not match fails with
+// ErrBundleVerification, as does a manifest that is missing, malformed or
+// not signed with key.
+func VerifySchemaBundle(fsys fs.FS, key ed25519.PublicKey) (fs.FS, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid schema bundle verification key")
+	}
+	data, err := fs.ReadFile(fsys, bundleManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBundleVerification, err)
+	}
+	signature, err := fs.ReadFile(fsys, bundleSignatureName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBundleVerification, err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, data, sig) {
+		return nil, fmt.Errorf("%w: schema bundle manifest signature does not verify", ErrBundleVerification)
+	}
+	var manifest SchemaBundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid schema bundle manifest: %w", ErrBundleVerification, err)
+	}
+	if manifest.FormatVersion != schemaBundleFormatVersion {
+		return nil, fmt.Errorf("unsupported schema bundle format version %d", manifest.FormatVersion)
+	}
+	v := &verifiedSchemaBundle{fsys: fsys, files: make(map[string]BundleFile, len(manifest.Files)), dirs: map[string]bool{".": true}}
+	for _, f := range manifest.Files {
+		if !fs.ValidPath(f.Path) || f.Path == "." {
+			return nil, fmt.Errorf("%w: invalid path %q in schema bundle manifest", ErrBundleVerification, f.Path)
+		}
+		v.files[f.Path] = f
+		for dir := path.Dir(f.Path); dir != "."; dir = path.Dir(dir) {
+			v.dirs[dir] = true
+		}
+	}
+	return v, nil
+}
+
+// verifiedSchemaBundle is the file system VerifySchemaBundle returns.
+type verifiedSchemaBundle struct {
+	fsys fs.FS
+	// files are the files the manifest lists, by path, and dirs the
+	// directories holding them.
+	files map[string]BundleFile
+	dirs  map[string]bool
+}
+
+func (v *verifiedSchemaBundle) Open(name string) (fs.File, error) {
+	if v.dirs[name] {
+		return v.fsys.Open(name)
+	}
+	data, err := v.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := v.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &verifiedFile{File: f, r: bytes.NewReader(data)}, nil
+}
+
+func (v *verifiedSchemaBundle) ReadFile(name string) ([]byte, error) {
+	want, ok := v.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := v.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, want.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != want.Size || hex.EncodeToString(sum[:]) != strings.ToLower(want.SHA256) {
+		return nil, fmt.Errorf("%w: %s does not match the schema bundle manifest", ErrBundleVerification, name)
+	}
+	return data, nil
+}
+
+func (v *verifiedSchemaBundle) Stat(name string) (fs.FileInfo, error) {
+	if _, ok := v.files[name]; !ok && !v.dirs[name] {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fs.Stat(v.fsys, name)
+}
+
+// ReadDir lists only the listed files of the directory name, and the
+// directories holding them.
+func (v *verifiedSchemaBundle) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !v.dirs[name] {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(v.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		p := path.Join(name, e.Name())
+		_, listed := v.files[p]
+		return !listed && !v.dirs[p]
+	}), nil
+}
+
+// verifiedFile is a listed file of a verifiedSchemaBundle, read from the
+// contents that were checked rather than from the file again.
+type verifiedFile struct {
+	fs.File
+	r *bytes.Reader
+}
+
+func (f *verifiedFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
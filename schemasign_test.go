@@ -0,0 +1,64 @@
+package tfpluginschema
+
+import (
+	"crypto/ed25519"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignSchemaBundle(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "opentofu", "example", "example", "1.0.0.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(bundlePath), 0o755))
+	require.NoError(t, os.WriteFile(bundlePath, []byte(writeOnlySchema), 0o644))
+
+	manifest, err := SignSchemaBundle(dir, private)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, "opentofu/example/example/1.0.0.json", manifest.Files[0].Path)
+	assert.Equal(t, int64(len(writeOnlySchema)), manifest.Files[0].Size)
+	again, err := SignSchemaBundle(dir, private)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files, again.Files, "the manifest does not list itself")
+
+	bundle, err := VerifySchemaBundle(os.DirFS(dir), public)
+	require.NoError(t, err)
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	_, err = s.GetResourceSchema(Request{Namespace: "example", Name: "example", Version: "1.0.0"}, "example_db")
+	require.NoError(t, err)
+
+	entries, err := fs.ReadDir(bundle, ".")
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "only the listed files are visible")
+	assert.Equal(t, "opentofu", entries[0].Name())
+	f, err := bundle.Open("opentofu/example/example/1.0.0.json")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, writeOnlySchema, string(data))
+
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(bundlePath), "2.0.0.json"), []byte(writeOnlySchema), 0o644))
+	_, err = fs.Stat(bundle, "opentofu/example/example/2.0.0.json")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "files added after signing are not served")
+
+	require.NoError(t, os.WriteFile(bundlePath, []byte(`{"resource_schemas": {}}`), 0o644))
+	_, err = fs.ReadFile(bundle, "opentofu/example/example/1.0.0.json")
+	assert.ErrorIs(t, err, ErrBundleVerification)
+
+	other, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, err = VerifySchemaBundle(os.DirFS(dir), other)
+	assert.ErrorIs(t, err, ErrBundleVerification)
+	_, err = VerifySchemaBundle(os.DirFS(t.TempDir()), public)
+	assert.ErrorIs(t, err, ErrBundleVerification, "an unsigned bundle does not verify")
+}
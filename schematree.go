@@ -0,0 +1,127 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// WriteSchemaTree reads the schema of the provider and writes it to dir as
+// one file per entry, encoded by MarshalCanonical, for reviewing schema
+// changes in version control:
+//
+//	<registry-type>/<namespace>/<name>/<version>/provider.json
+//	<registry-type>/<namespace>/<name>/<version>/resources/<resource>.json
+//	<registry-type>/<namespace>/<name>/<version>/data-sources/<data-source>.json
+//	<registry-type>/<namespace>/<name>/<version>/ephemeral-resources/<ephemeral-resource>.json
+//	<registry-type>/<namespace>/<name>/<version>/functions/<function>.json
+//
+// A version constraint is resolved to the latest matching version first.
+// Files left in those directories by an earlier run for entries the
+// provider no longer has are removed. The schema of every entry is
+// converted, so this takes a while on a large provider. The path of the
+// version directory is returned.
+func (s *Server) WriteSchemaTree(request Request, dir string) (string, error) {
+	request, err := s.resolveWriteRequest(request)
+	if err != nil {
+		return "", err
+	}
+	ls, err := s.readSchema(request)
+	if err != nil {
+		return "", err
+	}
+	ps, err := ls.providerSchema()
+	if err != nil {
+		return "", err
+	}
+
+	root := filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(schemaBundlePath(request), schemaBundleExt)))
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create schema tree directory: %w", err)
+	}
+	config := ps.ConfigSchema
+	if config == nil {
+		config = &tfjson.Schema{}
+	}
+	data, err := marshalCanonicalSchema(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode provider schema: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "provider.json"), data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write schema tree file: %w", err)
+	}
+
+	for _, d := range []struct {
+		dir     string
+		schemas map[string]*tfjson.Schema
+	}{
+		{"resources", ps.ResourceSchemas},
+		{"data-sources", ps.DataSourceSchemas},
+		{"ephemeral-resources", ps.EphemeralResourceSchemas},
+	} {
+		files := make(map[string][]byte, len(d.schemas))
+		for name, schema := range d.schemas {
+			if schema == nil {
+				continue
+			}
+			if files[name], err = marshalCanonicalSchema(schema); err != nil {
+				return "", fmt.Errorf("failed to encode schema of %s: %w", name, err)
+			}
+		}
+		if err := writeSchemaTreeDir(filepath.Join(root, d.dir), files); err != nil {
+			return "", err
+		}
+	}
+	files := make(map[string][]byte, len(ps.Functions))
+	for name, f := range ps.Functions {
+		if f == nil {
+			continue
+		}
+		if files[name], err = marshalCanonicalFunction(f); err != nil {
+			return "", fmt.Errorf("failed to encode signature of function %s: %w", name, err)
+		}
+	}
+	if err := writeSchemaTreeDir(filepath.Join(root, "functions"), files); err != nil {
+		return "", err
+	}
+	return root, nil
+}
+
+// writeSchemaTreeDir writes files, keyed by entry name, to dir as
+// <name>.json and removes any other .json file in it. The directory is
+// only created if there is something to write.
+func writeSchemaTreeDir(dir string, files map[string][]byte) error {
+	for name := range files {
+		if err := validateCachePathComponent("entry name", name, true); err != nil {
+			return fmt.Errorf("invalid schema entry name: %w", err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read schema tree directory: %w", err)
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if _, keep := files[name]; e.IsDir() || !ok || keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			return fmt.Errorf("failed to remove stale schema tree file: %w", err)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create schema tree directory: %w", err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write schema tree file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_WriteSchemaTree(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "Example", Name: "example", Version: "1.0.0"}
+
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "opentofu", "example", "example", "1.0.0", "resources", "example_removed.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stale), 0o755))
+	require.NoError(t, os.WriteFile(stale, []byte("{}\n"), 0o644))
+	notes := filepath.Join(filepath.Dir(stale), "README.md")
+	require.NoError(t, os.WriteFile(notes, []byte("kept"), 0o644))
+
+	root, err := s.WriteSchemaTree(req, dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "opentofu", "example", "example", "1.0.0"), root)
+
+	var files []string
+	require.NoError(t, filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	assert.Equal(t, []string{
+		"ephemeral-resources/example_token.json",
+		"provider.json",
+		"resources/README.md",
+		"resources/example_bucket.json",
+		"resources/example_db.json",
+	}, files, "stale schema files are removed, other files kept")
+
+	data, err := os.ReadFile(filepath.Join(root, "resources", "example_bucket.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "version": 0,
+  "block": {
+    "attributes": {
+      "name": {
+        "type": "string",
+        "required": true
+      }
+    }
+  }
+}
+`, string(data))
+
+	var schema tfjson.Schema
+	data, err = os.ReadFile(filepath.Join(root, "resources", "example_db.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &schema))
+	want, err := s.GetResourceSchema(req, "example_db")
+	require.NoError(t, err)
+	assert.Equal(t, want.Block.Attributes["password_wo"], schema.Block.Attributes["password_wo"])
+}
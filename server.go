@@ -1,22 +1,25 @@
 package tfpluginschema
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
-	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	goversion "github.com/hashicorp/go-version"
 	tfjson "github.com/hashicorp/terraform-json"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -147,6 +150,58 @@ func ensureWithinBaseDir(baseDir, targetDir string) error {
 	return nil
 }
 
+// checkWithinBaseDir is ensureWithinBaseDir for read-only callers such as
+// Plan: it applies the same checks to the parts of targetDir that exist,
+// but creates nothing. A missing segment ends the check, since nothing
+// beneath it can be read.
+func checkWithinBaseDir(baseDir, targetDir string) error {
+	baseClean := filepath.Clean(baseDir)
+	targetClean := filepath.Clean(targetDir)
+	rel, err := filepath.Rel(baseClean, targetClean)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate cache path: %w", err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return fmt.Errorf("computed cache path %q escapes cache root %q", targetClean, baseClean)
+	}
+	if rel == "." {
+		return nil
+	}
+	segments := strings.Split(rel, string(filepath.Separator))
+	current := baseClean
+	for i, segment := range segments {
+		current = filepath.Join(current, segment)
+		info, err := os.Lstat(current)
+		switch {
+		case os.IsNotExist(err):
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to stat cache path segment %q: %w", current, err)
+		case info.Mode()&os.ModeSymlink != 0 && i < len(segments)-1:
+			return fmt.Errorf("cache path segment %q is a symlink; refusing to traverse", current)
+		case info.Mode()&os.ModeSymlink != 0:
+			baseReal, err := filepath.EvalSymlinks(baseClean)
+			if err != nil {
+				return fmt.Errorf("failed to resolve cache root %q: %w", baseClean, err)
+			}
+			resolved, err := filepath.EvalSymlinks(current)
+			if err != nil {
+				return fmt.Errorf("failed to resolve cache path leaf %q: %w", current, err)
+			}
+			rel, err := filepath.Rel(baseReal, resolved)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate resolved cache leaf: %w", err)
+			}
+			if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+				return fmt.Errorf("resolved cache path %q escapes cache root %q (symlink)", resolved, baseReal)
+			}
+		case !info.IsDir():
+			return fmt.Errorf("cache path segment %q exists and is not a directory", current)
+		}
+	}
+	return nil
+}
+
 // RegistryType represents the type of provider registry to use.
 type RegistryType string
 
@@ -157,9 +212,40 @@ const (
 	RegistryTypeTerraform RegistryType = "terraform"
 )
 
+// WithDefaultRegistry sets the registry used for requests whose
+// RegistryType is empty. The default is RegistryTypeOpenTofu.
+func WithDefaultRegistry(r RegistryType) ServerOption {
+	return func(s *Server) {
+		s.defaultRegistry = r
+	}
+}
+
+// registryOrDefault returns r, or the Server's default registry if r is
+// empty.
+func (s *Server) registryOrDefault(r RegistryType) RegistryType {
+	if r == "" {
+		return s.defaultRegistry
+	}
+	return r
+}
+
+// host returns the host name of the registry, as used in BaseURL.
+func (r RegistryType) host() string {
+	u, err := url.Parse(r.BaseURL())
+	if err != nil {
+		return string(r)
+	}
+	return u.Host
+}
+
 // BaseURL returns the base URL for the registry API.
 // It defaults to OpenTofu registry for empty or unknown registry types.
+// For a registry host, it returns the conventional "/v1/providers" path;
+// the Server uses the path the host advertises through service discovery.
 func (r RegistryType) BaseURL() string {
+	if host, ok := r.customHost(); ok {
+		return "https://" + host + "/v1/providers"
+	}
 	switch r {
 	case RegistryTypeTerraform:
 		return "https://registry.terraform.io/v1/providers"
@@ -171,6 +257,9 @@ func (r RegistryType) BaseURL() string {
 var (
 	ErrPluginNotFound = fmt.Errorf("plugin not found")
 	ErrPluginApi      = fmt.Errorf("plugin API error")
+	// ErrSchemaNotFound is returned when a provider has no resource, data
+	// source, function or ephemeral resource with the requested name.
+	ErrSchemaNotFound = fmt.Errorf("schema not found")
 )
 
 // ContextKey is a type used to store the server instance in the context.
@@ -178,21 +267,28 @@ type ContextKey struct{}
 
 // Request is a request structure used to specify the details of a plugin
 // so that it can be downloaded.
-// Note that the request fields are case-sensitive.
+// Namespace and Name are matched case-insensitively for caching purposes, as
+// registries treat "Azure/azapi" and "azure/azapi" as the same provider. The
+// casing supplied by the caller is preserved in registry URLs and in the
+// requests reported to a CacheStatusFunc.
 type Request struct {
 	Namespace    string       // Namespace of the provider (e.g., "Azure")
 	Name         string       // Name of the provider (e.g., "azapi")
 	Version      string       // Version of the provider (e.g., "2.5.0") or constraint (e.g., ">=1.0.0", "~>2.1")
 	RegistryType RegistryType // Registry to use (defaults to OpenTofu if not specified)
+	// Hashes, if set, pins the provider package to the hashes recorded for
+	// it in a dependency lock file: "zh:" followed by the hex SHA-256 of
+	// the archive, or "h1:" followed by the dirhash of its contents (see
+	// ParseLockFile). A downloaded archive matching none of them fails with
+	// ErrHashMismatch before it is extracted.
+	Hashes []string
 }
 
 // String returns a string representation of the Request in the format:
 // "https://{registry}/v1/providers/{namespace}/{name}/{version}/download/{os}/{arch}"
 // where {registry} is either registry.opentofu.org (default) or registry.terraform.io.
-// This format is used to construct the URL for downloading the plugin.
-// Note: String is a best-effort representation. Server.Get validates the
-// request's components before constructing the URL, so callers using the
-// public Server API do not need to pre-validate Request fields.
+// String is intended for display and does not validate the request; use URL
+// to build the download endpoint URL.
 func (r Request) String() string {
 	sb := strings.Builder{}
 	sb.WriteString(r.RegistryType.BaseURL())
@@ -209,6 +305,23 @@ func (r Request) String() string {
 	return sb.String()
 }
 
+// URL returns the registry download endpoint for the request in the format
+// documented on String. It returns an error if Namespace, Name or Version is
+// empty or contains characters that are not safe in a URL path segment, so
+// a version constraint must be resolved to a concrete version first.
+func (r Request) URL() (string, error) {
+	if err := validateCachePathComponent("namespace", r.Namespace, true); err != nil {
+		return "", fmt.Errorf("invalid provider request: %w", err)
+	}
+	if err := validateCachePathComponent("name", r.Name, true); err != nil {
+		return "", fmt.Errorf("invalid provider request: %w", err)
+	}
+	if err := validateCachePathComponent("version", r.Version, true); err != nil {
+		return "", fmt.Errorf("invalid provider request: %w", err)
+	}
+	return r.String(), nil
+}
+
 func (r Request) fixedVersion() bool {
 	_, err := goversion.NewVersion(r.Version)
 	return err == nil
@@ -220,8 +333,10 @@ func (r Request) fixVersion(s *Server) (Request, error) {
 		if err != nil {
 			return Request{}, fmt.Errorf("failed to get latest version: %w", err)
 		}
+		s.logger(logComponentRegistry).Info("Resolved provider version",
+			append(s.requestLogAttrs(Request{Namespace: r.Namespace, Name: r.Name, RegistryType: r.RegistryType}),
+				"constraint", r.Version, "resolved_version", ver)...)
 		r.Version = ver
-		s.l.Info("No version specified, using latest version", "version", r.Version)
 	}
 	return r, nil
 }
@@ -232,24 +347,211 @@ type pluginApiResponse struct {
 	Arch        string   `json:"arch"`
 	FileName    string   `json:"filename"`
 	DownloadURL string   `json:"download_url"`
+	SHASum      string   `json:"shasum"`
+
+	SHASumsURL          string `json:"shasums_url"`
+	SHASumsSignatureURL string `json:"shasums_signature_url"`
+	SigningKeys         struct {
+		GPGPublicKeys []struct {
+			KeyID      string `json:"key_id"`
+			ASCIIArmor string `json:"ascii_armor"`
+		} `json:"gpg_public_keys"`
+	} `json:"signing_keys"`
+
+	// metadataURL is the registry URL the response was fetched from.
+	metadataURL string
 }
 
-type downloadCache map[Request]string
-type schemaCache map[Request]*tfjson.ProviderSchema
-type versionsCache map[VersionsRequest]goversion.Collection
+// The in-memory caches are keyed by cacheKey / versionsCacheKey, never by the
+// caller-supplied request directly.
+type downloadCache map[providerKey]string
+type schemaCache map[providerKey]*lazySchema
+type versionsCache map[providerKey]goversion.Collection
 
 // Server is a struct that manages the plugin download and caching process.
+//
+// A Server is safe for concurrent use by multiple goroutines, except for
+// Cleanup and CleanupRequest, which must not run concurrently with other
+// calls. Concurrent requests for the same provider share a single version
+// lookup, download and plugin run, and all callers see the same result;
+// requests for different providers proceed in parallel.
 type Server struct {
-	tmpDir        string
-	dlc           downloadCache
-	sc            schemaCache
+	// cacheState holds the in-memory caches and temporary directory; it is
+	// shared between Servers created with WithSharedCache.
+	*cacheState
 	l             *slog.Logger
-	versionsc     versionsCache
-	mu            *sync.RWMutex
 	cacheDir      string
 	forceFetch    bool
 	cacheStatusFn CacheStatusFunc
 	httpClient    *http.Client
+	logLevel      slog.Leveler
+	userAgent     string
+	// logAttrs are added to every record; see WithLogAttrs.
+	logAttrs []slog.Attr
+	// logSummaries enables summaries, which holds back the Info records
+	// about providers; see WithLogSummaries.
+	logSummaries bool
+	summaries    *logSummaries
+	// downloadParts and parallelMinSize configure ranged parallel
+	// downloads; see WithParallelDownload.
+	downloadParts   int
+	parallelMinSize int64
+	// skipDiskSpaceCheck disables the preflight check; see
+	// WithDiskSpaceCheck.
+	skipDiskSpaceCheck bool
+	// Temporary directory policy; see WithTempDir, WithPerRequestTempDir
+	// and WithKeepOnFailure.
+	tempRoot          string
+	perRequestTempDir bool
+	keepOnFailure     bool
+	// requestHeaders are added to registry API requests; see
+	// WithRequestHeaders.
+	requestHeaders http.Header
+	// registryTokens maps registry hosts onto bearer tokens; see
+	// WithRegistryToken.
+	registryTokens map[string]string
+	// registryCompat enables workarounds for proxying registries; see
+	// WithRegistryCompat.
+	registryCompat bool
+	// skipInvalidVersions leaves malformed entries out of registry
+	// versions lists; see WithSkipInvalidVersions.
+	skipInvalidVersions bool
+	// githubToken is sent to GitHub by GetChangelog; see WithGitHubToken.
+	githubToken string
+	// contentStore stores provider binaries by SHA-256; see
+	// WithContentStore.
+	contentStore bool
+	// integrityCheck re-verifies cached binaries before execution; see
+	// WithIntegrityCheck.
+	integrityCheck bool
+	// auditLogPath and auditTag configure the execution audit log; see
+	// WithAuditLog. auditMu serializes writes to it.
+	auditLogPath string
+	auditTag     string
+	auditMu      sync.Mutex
+	// execPolicy vets provider binaries before they run; see
+	// WithExecPolicy.
+	execPolicy ExecPolicy
+	// providerRules permit or block providers; see WithProviderRules.
+	providerRules ProviderRules
+	// verifiers are the verification methods downloaded archives must
+	// pass, and requireVerification demands that at least one does.
+	verifiers           []namedVerifier
+	requireVerification bool
+	// terraformCredentials enables Terraform's token lookup; see
+	// WithTerraformCredentials.
+	terraformCredentials bool
+	// retryPolicy governs retries of registry API requests; see
+	// WithRetryPolicy.
+	retryPolicy RetryPolicy
+	// defaultRegistry replaces an empty RegistryType in requests; see
+	// WithDefaultRegistry.
+	defaultRegistry RegistryType
+	// offline disables network access; see WithOffline.
+	offline bool
+	// httpTimeout bounds each HTTP request; see WithTimeout.
+	httpTimeout time.Duration
+	// maxRedirects limits redirects per request; see WithMaxRedirects.
+	maxRedirects int
+	// endpointOverrides maps lower-cased hosts onto the base URLs their
+	// requests are sent to; see WithEndpointOverride.
+	endpointOverrides map[string]string
+	// notFoundTTL is how long registry 404s for versions lists are
+	// remembered; see WithNotFoundTTL.
+	notFoundTTL time.Duration
+	// defaultNamespace is assumed for requests without a namespace; see
+	// WithDefaultNamespace.
+	defaultNamespace string
+	// providerAliases maps lower-cased "namespace/name" pairs onto their
+	// replacement "namespace/name"; see WithProviderAliases.
+	providerAliases map[string]string
+	// grpcMaxRecvMsgSize overrides the plugin client's receive limit; see
+	// WithGRPCMaxRecvMsgSize.
+	grpcMaxRecvMsgSize int
+	// cloneSchemas makes the Get*Schema methods return deep copies; see
+	// WithCloneSchemas.
+	cloneSchemas bool
+	// schemaBundle holds pre-generated schemas; see WithSchemaBundle.
+	// pluginExec allows provider plugins to be executed; see
+	// WithPluginExec.
+	schemaBundle fs.FS
+	pluginExec   bool
+	// schemaCompression compresses persisted schemas; see
+	// WithSchemaCompression.
+	schemaCompression CompressionLevel
+	// internSchemas shares identical parts of held schemas; see
+	// WithSchemaInterning.
+	internSchemas bool
+	// sources replaces the bundle and registry lookups when set; see
+	// WithSchemaSources.
+	sources []SchemaSource
+	// sharedCache requests a process-wide cacheState; see WithSharedCache.
+	// sharedKey is set while the Server holds a reference to one.
+	sharedCache bool
+	sharedKey   *sharedCacheKey
+	// cacheHits and cacheMisses count cache statuses for CacheStats.
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+	// schemaLoads and conversions time schema retrieval and conversion for
+	// DebugStats.
+	schemaLoads timings
+	conversions timings
+	// subs holds the channels of Subscribe.
+	subs subscribers
+}
+
+// cacheState is the in-memory state a Server accumulates between calls.
+type cacheState struct {
+	mu        *sync.RWMutex
+	dlc       downloadCache
+	sc        schemaCache
+	versionsc versionsCache
+	mdc       metadataCache
+	// listed holds the protocols and platforms of the entries of the
+	// versions lists in versionsc, keyed by version.
+	listed map[providerKey]map[string]listedVersion
+	// fetched records how each provider in dlc was obtained, for
+	// SupplyChainReport.
+	fetched map[providerKey]fetchedProvider
+	// notFound remembers registry 404s for versions lists; see
+	// WithNotFoundTTL.
+	notFound map[providerKey]notFound
+	// loaded holds the schemas added by LoadTerraformSchemaJSON, keyed by
+	// namespace and name only.
+	loaded map[providerKey]*lazySchema
+	// discovered maps "<service> <host>" onto the base URL the registry
+	// host advertises for the service; see Server.discoverServiceURL.
+	discovered map[string]string
+	// compatHosts holds the registry API hosts whose archive downloads
+	// carry the registry headers; see WithRegistryCompat.
+	compatHosts map[string]struct{}
+	// downloads, schemas, metadata and versions deduplicate concurrent
+	// downloads, schema and metadata retrievals and version lookups of the
+	// same provider.
+	downloads singleflight.Group
+	schemas   singleflight.Group
+	metadata  singleflight.Group
+	versions  singleflight.Group
+	// tmpDir is created on first use. tempRetained is set once files have
+	// been kept after a failure, which stops Cleanup from removing tmpDir.
+	tmpDir       string
+	tempRetained bool
+}
+
+func newCacheState() *cacheState {
+	return &cacheState{
+		mu:          &sync.RWMutex{},
+		dlc:         make(downloadCache),
+		sc:          make(schemaCache),
+		versionsc:   make(versionsCache),
+		listed:      make(map[providerKey]map[string]listedVersion),
+		fetched:     make(map[providerKey]fetchedProvider),
+		notFound:    make(map[providerKey]notFound),
+		mdc:         make(metadataCache),
+		loaded:      make(map[providerKey]*lazySchema),
+		discovered:  make(map[string]string),
+		compatHosts: make(map[string]struct{}),
+	}
 }
 
 // NewServer creates a new Server instance with an optional logger and zero or
@@ -263,20 +565,51 @@ func NewServer(l *slog.Logger, opts ...ServerOption) *Server {
 			AddSource: false,
 		}))
 	}
-	l.Info("Creating new server instance")
 	s := &Server{
-		dlc:        make(downloadCache),
-		sc:         make(schemaCache),
+		cacheState: newCacheState(),
 		l:          l,
-		versionsc:  make(versionsCache),
-		mu:         &sync.RWMutex{},
 		cacheDir:   defaultCacheDir(),
-		httpClient: http.DefaultClient,
+		httpClient: newDefaultHTTPClient(),
+		userAgent:  defaultUserAgent(),
+		pluginExec: pluginExecSupported,
+
+		defaultNamespace:    defaultProviderNamespace,
+		skipInvalidVersions: true,
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
-	l.Debug("Server configured", "cache_dir", s.cacheDir, "force_fetch", s.forceFetch)
+	if s.httpTimeout > 0 || s.maxRedirects > 0 || len(s.endpointOverrides) > 0 {
+		c := *s.httpClient
+		if s.httpTimeout > 0 {
+			c.Timeout = s.httpTimeout
+		}
+		if s.maxRedirects > 0 {
+			c.CheckRedirect = limitRedirects(s.maxRedirects, c.CheckRedirect)
+		}
+		if len(s.endpointOverrides) > 0 {
+			wrapped := c.Transport
+			if wrapped == nil {
+				wrapped = http.DefaultTransport
+			}
+			c.Transport = &endpointTransport{overrides: s.endpointOverrides, wrapped: wrapped}
+		}
+		s.httpClient = &c
+	}
+	if s.sharedCache {
+		s.cacheState, s.sharedKey = acquireSharedCache(s.cacheDir, s.tempRoot)
+	}
+	if s.logLevel != nil {
+		s.l = slog.New(&levelHandler{level: s.logLevel, handler: s.l.Handler()})
+	}
+	if s.logSummaries {
+		s.summaries = &logSummaries{out: s.l.Handler().WithAttrs(s.logAttrs), byID: make(map[logSummaryKey]*logSummary)}
+		s.l = slog.New(&summaryHandler{handler: s.l.Handler(), summaries: s.summaries})
+	}
+	if len(s.logAttrs) > 0 {
+		s.l = slog.New(s.l.Handler().WithAttrs(s.logAttrs))
+	}
+	s.logger(logComponentServer).Debug("Server configured", "cache_dir", s.cacheDir, "force_fetch", s.forceFetch)
 	return s
 }
 
@@ -286,12 +619,13 @@ func (s *Server) CacheDir() string {
 	return s.cacheDir
 }
 
-func (s *Server) readSchema(request Request) (*tfjson.ProviderSchema, error) {
-	if !request.fixedVersion() {
-		var err error
-		if request, err = request.fixVersion(s); err != nil {
-			return nil, err
-		}
+func (s *Server) readSchema(request Request) (*lazySchema, error) {
+	// Resolve the version once here; getSchema and the download it may
+	// trigger use the prepared request as is, so that a version published
+	// mid-call cannot make them disagree.
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return nil, err
 	}
 
 	resp, err := s.getSchema(request)
@@ -306,19 +640,131 @@ func (s *Server) readSchema(request Request) (*tfjson.ProviderSchema, error) {
 	return resp, nil
 }
 
-// Cleanup removes the Server's in-memory state and any legacy temporary
-// directory used for plugin downloads.
-func (s *Server) Cleanup() {
+// Cleanup removes the Server's in-memory state and the temporary directory
+// used for plugin downloads. The persistent on-disk cache is not touched.
+// It is a no-op for the filesystem when no temporary directory was created,
+// and it refuses to remove a directory that is not one the Server created
+// under its temp root. The Server remains usable afterwards.
+//
+// For a Server created with WithSharedCache, Cleanup releases its reference
+// to the shared state; only the last Server to do so clears the caches and
+// removes the temporary directory. The Server is then detached and continues
+// with private state.
+func (s *Server) Cleanup() error {
+	if s.summaries != nil {
+		s.summaries.flushAll()
+	}
+	if s.sharedKey != nil {
+		last := releaseSharedCache(s.sharedKey, s.cacheState)
+		s.sharedKey = nil
+		if !last {
+			s.cacheState = newCacheState()
+			return nil
+		}
+	}
+
 	s.mu.Lock()
-	tmpDir := s.tmpDir
+	tmpDir, retained := s.tmpDir, s.tempRetained
 	clear(s.dlc)
 	clear(s.sc)
 	clear(s.versionsc)
+	clear(s.listed)
+	clear(s.fetched)
+	clear(s.notFound)
+	clear(s.mdc)
+	clear(s.loaded)
+	clear(s.discovered)
+	clear(s.compatHosts)
 	s.tmpDir = ""
+	s.tempRetained = false
 	s.mu.Unlock()
 
-	s.l.Info("Cleaning up temporary directory", "dir", tmpDir)
-	os.RemoveAll(tmpDir)
+	if tmpDir == "" {
+		return nil
+	}
+	if retained {
+		s.logger(logComponentCache).Info("Keeping temporary directory with files retained after a failure", "dir", tmpDir)
+		return nil
+	}
+	if err := s.checkOwnedTempPath(tmpDir, tmpDir); err != nil {
+		return err
+	}
+	s.logger(logComponentCache).Debug("Cleaning up temporary directory", "dir", tmpDir)
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return fmt.Errorf("failed to remove temporary directory %s: %w", tmpDir, err)
+	}
+	return nil
+}
+
+// CleanupRequest discards the Server's in-memory state for a single
+// provider and removes its files from the temporary directory (archives
+// and, with WithPerRequestTempDir, its working directory). If
+// request.Version is a concrete version only that version is affected;
+// otherwise every version of the provider is. The persistent on-disk cache,
+// including partial downloads kept there, is not touched.
+func (s *Server) CleanupRequest(request Request) error {
+	var err error
+	if request.Namespace, request.Name, err = s.resolveProviderAlias(request.Namespace, request.Name); err != nil {
+		return err
+	}
+	request.RegistryType = s.registryOrDefault(request.RegistryType)
+	if err := s.validateCacheRequestIdentity(request); err != nil {
+		return fmt.Errorf("invalid provider request: %w", err)
+	}
+	key := normalizedRequest(request)
+	allVersions := !request.fixedVersion()
+	mkey := cacheKey(request)
+	matches := func(k providerKey) bool {
+		return k.host == mkey.host && k.namespace == mkey.namespace && k.name == mkey.name &&
+			(allVersions || k.version == mkey.version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.DeleteFunc(s.dlc, func(k providerKey, _ string) bool { return matches(k) })
+	maps.DeleteFunc(s.sc, func(k providerKey, _ *lazySchema) bool { return matches(k) })
+	maps.DeleteFunc(s.mdc, func(k providerKey, _ *providerMetadata) bool { return matches(k) })
+	maps.DeleteFunc(s.fetched, func(k providerKey, _ fetchedProvider) bool { return matches(k) })
+	if allVersions {
+		delete(s.loaded, providerKey{namespace: mkey.namespace, name: mkey.name})
+		vkey := versionsCacheKey(VersionsRequest{Namespace: key.Namespace, Name: key.Name, RegistryType: key.RegistryType})
+		delete(s.versionsc, vkey)
+		delete(s.listed, vkey)
+		delete(s.notFound, vkey)
+	}
+
+	if s.tmpDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(s.tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to read temporary directory %s: %w", s.tmpDir, err)
+	}
+	// Archives are named "<registry>-terraform-provider-<name>_<version>_...";
+	// per-request directories "<registry>_<namespace>_<name>_<version>".
+	archivePrefix := string(key.RegistryType) + "-" + providerFileNamePrefix + key.Name + "_"
+	dirPrefix := strings.Join([]string{string(key.RegistryType), key.Namespace, key.Name, ""}, "_")
+	if !allVersions {
+		archivePrefix += key.Version + "_"
+		dirPrefix += key.Version
+	}
+	var errs []error
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if !(e.IsDir() && (name == dirPrefix || allVersions && strings.HasPrefix(name, dirPrefix))) &&
+			!(!e.IsDir() && strings.HasPrefix(name, archivePrefix)) {
+			continue
+		}
+		path := filepath.Join(s.tmpDir, e.Name())
+		if err := s.checkOwnedTempPath(s.tmpDir, path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // validateProviderFileName ensures the filename reported by the registry is a
@@ -354,7 +800,7 @@ func validateProviderFileName(name string) error {
 // Values must only contain characters from a conservative URL-safe set:
 // ASCII letters, digits, and the unreserved punctuation "-", "_", ".", "+",
 // "~". This avoids having to URL-escape segments when constructing registry
-// URLs via Request.String(), and rejects characters (like "?", "#", "%",
+// URLs via Request.URL(), and rejects characters (like "?", "#", "%",
 // "/", or whitespace) that would change URL semantics or escape the cache
 // root on disk.
 func validateCachePathComponent(name, value string, required bool) error {
@@ -417,69 +863,94 @@ func (s *Server) validateCacheRequestVersion(request Request) error {
 // the TFPLUGINSCHEMA_CACHE_DIR environment variable). Subsequent calls for the
 // same provider/version/os/arch are served from the cache. Pass
 // WithForceFetch(true) to NewServer to bypass the cache and always download.
-// Cleanup() removes only the Server's in-memory state and any legacy temp
+// Cleanup() removes only the Server's in-memory state and its temp
 // directory; the persistent cache is preserved across runs.
 func (s *Server) Get(request Request) error {
-	if err := s.validateCacheRequestIdentity(request); err != nil {
-		return fmt.Errorf("invalid provider request: %w", err)
+	request, err := s.prepareRequest(request)
+	if err != nil {
+		return err
 	}
+	return s.get(request)
+}
 
-	// Normalize RegistryType so that empty/unknown values share the same
-	// map key (and therefore the same in-memory dlc/sc entries) as
-	// RegistryTypeOpenTofu, matching the behavior of BaseURL() and the
-	// on-disk cache layout. Without this, callers passing an empty/unknown
-	// RegistryType would miss the in-memory cache even when an equivalent
-	// OpenTofu-keyed entry already exists.
-	request.RegistryType = normalizedRegistryType(request.RegistryType)
-
-	var notifyRequest Request
-	var notifyStatus CacheStatus
-	var shouldNotify bool
+// get is Get for a request already passed through prepareRequest, so that
+// callers which resolved the version themselves do not resolve it again.
+func (s *Server) get(request Request) (err error) {
+	key := cacheKey(request)
 
-	if !request.fixedVersion() {
-		var err error
-		request, err = request.fixVersion(s)
-		if err != nil {
-			return err
-		}
+	s.mu.RLock()
+	if _, exists := s.dlc[key]; exists {
+		s.mu.RUnlock()
+		s.logger(logComponentCache).Debug("Provider served from in-memory download cache",
+			s.requestLogAttrs(request)...)
+		// The provider may have been fetched for a request without hashes.
+		return checkRecordedHashes(cacheProviderDir(s.cacheDir, request), request.Hashes)
 	}
+	s.mu.RUnlock()
 
-	// The (possibly resolved) version is now used for URL/cache-path
-	// construction, so it must be URL/path safe.
-	if err := s.validateCacheRequestVersion(request); err != nil {
-		return fmt.Errorf("invalid provider request: %w", err)
+	// Concurrent calls for the same provider share one download; calls for
+	// different providers proceed in parallel. s.mu is only held to access
+	// the in-memory state, never across network or disk I/O.
+	_, err, _ = s.downloads.Do(key.String(), func() (any, error) {
+		return nil, s.download(request, key)
+	})
+	if err != nil {
+		return err
 	}
+	// A shared download was checked against the hashes of the request
+	// that started it, not necessarily these.
+	return checkRecordedHashes(cacheProviderDir(s.cacheDir, request), request.Hashes)
+}
 
-	// Build the request-scoped logger *after* fixVersion, so that logs
-	// carry the concrete resolved version rather than the caller-supplied
-	// constraint (e.g. "~>2.1").
-	l := s.l.With("request_namespace", request.Namespace, "request_name", request.Name, "request_version", request.Version)
-
-	s.mu.RLock()
-	if _, exists := s.dlc[request]; exists {
-		l.Info("Request already exists in download cache")
-		s.mu.RUnlock()
-		return nil // Request already exists, no need to add again
+// cachedProvider returns the path of the provider binary in the on-disk
+// cache entry extractDir if Get can be served from it, or "" if there is no
+// entry. An entry that cannot be used, because it matches none of
+// request.Hashes or, with WithRequireVerification, records no
+// verification, is reported with an error: Get downloads it again, or
+// fails with the error when offline. Plan uses the same decision.
+func (s *Server) cachedProvider(request Request, extractDir string) (string, error) {
+	path, ok := findProviderBinary(extractDir, request.Name)
+	if !ok {
+		return "", nil
 	}
-	s.mu.RUnlock()
+	if s.requireVerification && !isVerified(extractDir) {
+		return "", fmt.Errorf("%w: cached provider %s/%s %s was not verified", ErrVerificationFailed, request.Namespace, request.Name, request.Version)
+	}
+	if err := checkRecordedHashes(extractDir, request.Hashes); err != nil {
+		return "", fmt.Errorf("cached provider %s/%s %s: %w", request.Namespace, request.Name, request.Version, err)
+	}
+	return path, nil
+}
 
-	// Lock for the download and extraction process to avoid multiple downloads of the same plugin.
-	// The cache-status callback reference is captured under the lock and
-	// invoked *after* the lock is released, so user callbacks may safely
-	// call back into the Server without deadlocking.
-	s.mu.Lock()
-	var notifyFn CacheStatusFunc
+// download places the provider for request, which must be prepared by
+// prepareRequest, in the on-disk cache and records it in the download cache
+// under key. The cache status is reported once download returns.
+func (s *Server) download(request Request, key providerKey) (err error) {
+	var notifyStatus CacheStatus
+	var shouldNotify bool
 	defer func() {
-		s.mu.Unlock()
-		if shouldNotify && notifyFn != nil {
-			s.notifyCacheStatusWith(notifyFn, notifyRequest, notifyStatus)
+		if shouldNotify {
+			s.recordCacheStatus(notifyStatus)
+			s.notifyCacheStatusWith(s.cacheStatusFn, request, notifyStatus)
 		}
 	}()
 
-	// Re-check after acquiring the write lock: another goroutine may have
-	// populated the cache between the RUnlock above and Lock here.
-	if _, exists := s.dlc[request]; exists {
-		l.Info("Request already exists in download cache")
+	// Build the request-scoped logger *after* fixVersion, so that logs
+	// carry the concrete resolved version rather than the caller-supplied
+	// constraint (e.g. "~>2.1").
+	requestAttrs := s.requestLogAttrs(request)
+	cl := s.logger(logComponentCache).With(requestAttrs...)
+	rl := s.logger(logComponentRegistry).With(requestAttrs...)
+	dl := s.logger(logComponentDownload).With(requestAttrs...)
+	xl := s.logger(logComponentExtract).With(requestAttrs...)
+
+	// Re-check: a download that finished between the lookup in Get and
+	// joining the flight has already populated the cache.
+	s.mu.RLock()
+	_, exists := s.dlc[key]
+	s.mu.RUnlock()
+	if exists {
+		cl.Debug("Provider served from in-memory download cache")
 		return nil
 	}
 
@@ -489,126 +960,146 @@ func (s *Server) Get(request Request) error {
 		return err
 	}
 
-	if !s.forceFetch {
-		if path, ok := findProviderBinary(extractDir, request.Name); ok {
-			l.Info("Provider cache hit", "path", path, "cache_dir", s.cacheDir)
-			s.dlc[request] = path
-			notifyRequest, notifyStatus, shouldNotify = request, CacheStatusHit, true
-			notifyFn = s.cacheStatusFn
+	if !s.forceFetch || s.offline {
+		if moved, err := migrateLegacyCacheEntry(s.cacheDir, request); err != nil {
+			cl.Warn("Failed to move legacy cache entry", "error", err)
+		} else if moved {
+			cl.Info("Moved cache entry to its lower-case path", "path", extractDir)
+		}
+		path, staleErr := s.cachedProvider(request, extractDir)
+		if staleErr != nil {
+			if s.offline {
+				return staleErr
+			}
+			cl.Info("Cached provider cannot be used; downloading it again", "error", staleErr)
+		} else if path != "" {
+			cl.Info("Provider cache hit", "path", path, "cache_dir", s.cacheDir)
+			touchCacheEntry(extractDir)
+			s.mu.Lock()
+			s.dlc[key] = path
+			s.fetched[key] = fetchedProvider{request: request, cached: true}
+			s.mu.Unlock()
+			notifyStatus, shouldNotify = CacheStatusHit, true
 			return nil
 		}
 	}
 
-	l.Info("Provider cache miss, downloading", "cache_dir", s.cacheDir, "force_fetch", s.forceFetch)
-	notifyRequest, notifyStatus, shouldNotify = request, CacheStatusMiss, true
-	notifyFn = s.cacheStatusFn
-
-	registryApiRequest, err := http.NewRequest(http.MethodGet, request.String(), nil)
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request for registry API: %w", err)
-	}
-	l.Debug("Sending request to registry API", "url", registryApiRequest.URL.String())
-
-	resp, err := s.httpClient.Do(registryApiRequest)
-	if err != nil {
-		return fmt.Errorf("failed to send HTTP request to registry API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("%w: %s", ErrPluginNotFound, request.String())
+	if s.offline {
+		return fmt.Errorf("%w: provider %s/%s %s", ErrOffline, request.Namespace, request.Name, request.Version)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %s => %d", ErrPluginApi, request.String(), resp.StatusCode)
-	}
+	cl.Info("Provider cache miss", "cache_dir", s.cacheDir, "force_fetch", s.forceFetch)
+	notifyStatus, shouldNotify = CacheStatusMiss, true
 
-	var pluginResponse pluginApiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&pluginResponse); err != nil {
-		return fmt.Errorf("failed to decode plugin API response: %w", err)
-	}
-
-	l.Info("Plugin API response received", "arch", pluginResponse.Arch, "os", pluginResponse.OS, "filename", pluginResponse.FileName, "download_url", pluginResponse.DownloadURL)
-
-	// Sanitize the filename reported by the registry before using it as a
-	// local filesystem path component. It must be a simple base name with no
-	// path separators or traversal; anything else is rejected to avoid
-	// writing outside s.tmpDir if the registry response is malicious or
-	// corrupted.
-	if err := validateProviderFileName(pluginResponse.FileName); err != nil {
-		return fmt.Errorf("invalid plugin filename from registry: %w", err)
+	pluginResponse, err := s.fetchDownloadMetadata(request, rl)
+	if err != nil {
+		return err
 	}
-
 	downloadURL := pluginResponse.DownloadURL
-	if downloadURL == "" {
-		return fmt.Errorf("download URL is empty for request: %s", request.String())
-	}
 
-	// Create a temp directory for the download so that partial downloads do not
+	// Download into a temp directory so that partial downloads do not
 	// corrupt the persistent cache.
-	if s.tmpDir == "" {
-		tmpFile, err := os.MkdirTemp("", "tfpluginschema-")
-		if err != nil {
-			return fmt.Errorf("failed to create temporary directory: %w", err)
-		}
-		s.tmpDir = tmpFile
-	}
-
-	downloadRequest, err := http.NewRequest(http.MethodGet, downloadURL, nil)
+	s.mu.Lock()
+	workDir, err := s.requestTempDir(normalizedRequest(request))
+	s.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request for plugin download: %w", err)
+		return err
 	}
-
-	resp, err = s.httpClient.Do(downloadRequest)
-	if err != nil {
-		return fmt.Errorf("failed to download plugin: %w", err)
+	if s.perRequestTempDir {
+		defer func() {
+			if err == nil {
+				os.RemoveAll(workDir)
+			}
+		}()
+	}
+
+	// The archive name is qualified with the registry type because both
+	// registries publish identically named archives. A partial file left by
+	// a failed attempt is kept under this name in the cache directory, and
+	// moved back here so that this attempt resumes it.
+	archiveName := string(request.RegistryType) + "-" + pluginResponse.FileName
+	pluginFilePath := filepath.Join(workDir, archiveName)
+	s.claimPartialArchive(s.partialArchivePath(archiveName), pluginFilePath)
+
+	// Probe the archive up front when its size is needed: for the disk
+	// space preflight, and to decide between a single-stream and a ranged
+	// download. A failed probe only means the size is unknown.
+	info := downloadInfo{size: -1}
+	if !s.skipDiskSpaceCheck || s.downloadParts > 1 {
+		if info, err = s.probeDownload(downloadURL); err != nil {
+			dl.Debug("Download probe failed", "error", err)
+		}
 	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download plugin: %s => %d", downloadURL, resp.StatusCode)
+	if err := s.checkDiskSpace(workDir, s.cacheDir, info.size-fileSize(pluginFilePath), info.size); err != nil {
+		return err
 	}
 
-	pluginFilePath := filepath.Join(s.tmpDir, pluginResponse.FileName)
-
-	file, err := os.Create(pluginFilePath)
+	downloadStart := time.Now()
+	written, resumedFrom, err := s.fetchArchive(downloadURL, pluginFilePath, pluginResponse.SHASum, info)
+	if downloadLinkRejected(err) {
+		// Signed download links expire. If the one from the metadata was
+		// rejected, ask the registry for a fresh link and try once more.
+		dl.Info("Download link rejected; requesting a new one from the registry", "url", downloadURL, "error", err)
+		s.forgetRegistryResponse(pluginResponse.metadataURL)
+		if pluginResponse, err = s.fetchDownloadMetadata(request, rl); err != nil {
+			return err
+		}
+		downloadURL = pluginResponse.DownloadURL
+		written, resumedFrom, err = s.fetchArchive(downloadURL, pluginFilePath, pluginResponse.SHASum, info)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create plugin file: %w", err)
+		s.parkPartialArchive(pluginFilePath, s.partialArchivePath(archiveName))
+		return err
 	}
 
 	// Ensure the downloaded archive is removed once we're done with it;
 	// otherwise s.tmpDir can accumulate zip files for long-lived processes.
-	defer os.Remove(pluginFilePath)
+	// WithKeepOnFailure retains it if anything below fails.
+	defer func() {
+		if err != nil && s.keepOnFailure {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.retainOnFailure(err, "archive", pluginFilePath)
+			return
+		}
+		os.Remove(pluginFilePath)
+	}()
 
-	if _, err := file.ReadFrom(resp.Body); err != nil {
-		file.Close()
-		return fmt.Errorf("failed to read plugin data into file: %w", err)
-	}
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("failed to close plugin file: %w", err)
+	dl.Info("Downloaded provider archive", "filename", pluginResponse.FileName, logKeyBytes, written, "resumed_from", resumedFrom, durationLogAttr(time.Since(downloadStart)))
+
+	var verifiedBy []string
+	if len(s.verifiers) > 0 || s.requireVerification {
+		artifact, err := verificationArtifact(request, pluginFilePath, pluginResponse)
+		if err != nil {
+			return err
+		}
+		if verifiedBy, err = s.verifyArchive(artifact); err != nil {
+			return err
+		}
 	}
 
 	// Extract atomically: unzip into a sibling staging directory, then rename
 	// into place. This ensures concurrent readers never observe a partial
 	// cache entry (findProviderBinary would otherwise treat a half-populated
-	// directory as a cache hit). If a previous run left a partial staging
-	// directory behind, clear it first.
-	stagingDir := extractDir + ".partial"
-	if err := ensureWithinBaseDir(s.cacheDir, stagingDir); err != nil {
+	// directory as a cache hit). The staging directory has a unique name so
+	// that concurrent extractions of the same provider, in this or another
+	// process, do not interfere. Staging directories abandoned by crashed
+	// runs are swept first.
+	if err := ensureWithinBaseDir(s.cacheDir, extractDir+stagingDirSuffix); err != nil {
 		return err
 	}
-	if err := os.RemoveAll(stagingDir); err != nil {
-		return fmt.Errorf("failed to clear staging directory %s: %w", stagingDir, err)
+	s.removeStaleStagingDirs(extractDir)
+	// Create the staging leaf symlink-safely. os.MkdirTemp creates a new
+	// directory with an exclusive mkdir, so it cannot follow a raced-in
+	// symlink. Then Lstat to confirm we created a real directory (not a
+	// symlink). The parent chain has already been materialized
+	// symlink-safely by ensureWithinBaseDir above.
+	stagingDir, err := os.MkdirTemp(filepath.Dir(extractDir), filepath.Base(extractDir)+stagingDirSuffix+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
 	}
-	// Create the staging leaf symlink-safely. Use os.Mkdir (not MkdirAll,
-	// which would follow a raced-in symlink at the leaf path) so the call
-	// fails with EEXIST if anything is created at stagingDir between the
-	// RemoveAll above and this Mkdir. Then Lstat to confirm we created a
-	// real directory (not a symlink). The parent chain has already been
-	// materialized symlink-safely by ensureWithinBaseDir above.
-	if err := os.Mkdir(stagingDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create staging directory %s: %w", stagingDir, err)
+	if err := os.Chmod(stagingDir, 0o755); err != nil {
+		return fmt.Errorf("failed to set staging directory permissions: %w", err)
 	}
 	if info, err := os.Lstat(stagingDir); err != nil {
 		return fmt.Errorf("failed to stat staging directory %s: %w", stagingDir, err)
@@ -616,12 +1107,74 @@ func (s *Server) Get(request Request) error {
 		return fmt.Errorf("staging directory %s is not a real directory", stagingDir)
 	}
 	// Ensure we don't leave a partial staging directory behind on any error
-	// path below. On success the RemoveAll after Rename is a no-op.
-	defer os.RemoveAll(stagingDir)
+	// path below, unless WithKeepOnFailure asks for it to be retained. On
+	// success the RemoveAll after Rename is a no-op.
+	defer func() {
+		if err != nil && s.keepOnFailure {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.retainOnFailure(err, "staging_dir", stagingDir)
+			return
+		}
+		os.RemoveAll(stagingDir)
+	}()
 
 	if err := unzip(pluginFilePath, stagingDir); err != nil {
 		return fmt.Errorf("failed to unzip plugin file: %w", err)
 	}
+	// The h1: hash is taken over the extracted files, as Terraform does, so
+	// a pinned archive is checked here, before it can enter the cache.
+	hashes, err := packageHashes(pluginFilePath, stagingDir)
+	if err != nil {
+		return err
+	}
+	if len(request.Hashes) > 0 {
+		if err = matchHashes(hashes, request.Hashes); err != nil {
+			return fmt.Errorf("downloaded provider %s/%s %s: %w", request.Namespace, request.Name, request.Version, err)
+		}
+	}
+
+	// Check the extracted files before publishing them, so that an archive
+	// without the provider binary never becomes a cache entry.
+	wantProviderFileName := strings.ToLower(providerFileNamePrefix + request.Name)
+	var binaryPath string
+	if err = fs.WalkDir(os.DirFS(stagingDir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error walking extracted directory (%s): %w", stagingDir, err)
+		}
+
+		xl.Debug("Checking extracted file", "path", path, "is_dir", d.IsDir(), "name", d.Name())
+
+		if d.IsDir() && d.Name() != "." {
+			return fs.SkipDir
+		}
+
+		if !strings.HasPrefix(strings.ToLower(d.Name()), wantProviderFileName) {
+			return nil
+		}
+
+		binaryPath = path
+		return fs.SkipAll
+	}); err != nil {
+		return fmt.Errorf("error checking extracted files: %w", err)
+	}
+	if binaryPath == "" {
+		return fmt.Errorf("provider file not found in extracted directory (%s) for request: %s", stagingDir, request.String())
+	}
+	if s.contentStore {
+		if err = s.storeBinaryContent(stagingDir, binaryPath, extractDir); err != nil {
+			return err
+		}
+	}
+	if err = recordBinaryChecksum(stagingDir, binaryPath); err != nil {
+		return err
+	}
+	if err = recordVerification(stagingDir, verifiedBy); err != nil {
+		return err
+	}
+	if err = recordPackageHashes(stagingDir, hashes); err != nil {
+		return err
+	}
 
 	// Publish the staging directory into the cache atomically. To stay
 	// readable for any concurrent reader (and to avoid hard failures on
@@ -636,9 +1189,10 @@ func (s *Server) Get(request Request) error {
 	// materialized the shared parent chain of stagingDir/extractDir using
 	// a symlink-safe, segment-by-segment walk. Calling MkdirAll here
 	// would re-introduce symlink-following and a fresh TOCTOU window.
-	oldDir := extractDir + ".old"
-	// Best-effort: clear any leftover ".old" from a previous interrupted run.
-	_ = os.RemoveAll(oldDir)
+	//
+	// The aside name derives from the unique staging name, so concurrent
+	// publishers never move entries onto each other.
+	oldDir := stagingDir + ".old"
 	movedAside := false
 	if _, statErr := os.Lstat(extractDir); statErr == nil {
 		if err := os.Rename(extractDir, oldDir); err != nil {
@@ -665,39 +1219,116 @@ func (s *Server) Get(request Request) error {
 		_ = os.RemoveAll(oldDir)
 	}
 
-	// check the extracted directory
-	wantProviderFileName := fmt.Sprintf("%s%s", providerFileNamePrefix, request.Name)
-	if err = fs.WalkDir(os.DirFS(extractDir), ".", func(path string, d fs.DirEntry, err error) error {
+	providerPath := filepath.Join(extractDir, binaryPath)
+	s.mu.Lock()
+	s.dlc[key] = providerPath
+	s.fetched[key] = fetchedProvider{request: request, download: &pluginResponse}
+	s.mu.Unlock()
+	xl.Info("Extracted provider", "path", providerPath)
+
+	return nil
+}
+
+// prepareRequest applies the normalisation shared by Get and Plan: alias
+// resolution, identity validation, registry type defaulting, the provider
+// rules and version resolution. The returned request carries a concrete, path-safe version.
+func (s *Server) prepareRequest(request Request) (Request, error) {
+	// Rewrite legacy/aliased addresses (e.g. "-/aws") to their current
+	// registry coordinates before validating or keying any caches.
+	var err error
+	if request.Namespace, request.Name, err = s.resolveProviderAlias(request.Namespace, request.Name); err != nil {
+		return Request{}, err
+	}
+
+	if err := s.validateCacheRequestIdentity(request); err != nil {
+		return Request{}, fmt.Errorf("invalid provider request: %w", err)
+	}
+
+	// Normalize RegistryType so that empty/unknown values resolve versions
+	// against the same registry that BaseURL() targets. The in-memory
+	// caches are keyed by cacheKey, which additionally folds the
+	// namespace/name case so "Azure/azapi" and "azure/azapi" share entries.
+	request.RegistryType = normalizedRegistryType(s.registryOrDefault(request.RegistryType))
+	if err := s.checkProviderRules(request); err != nil {
+		return Request{}, err
+	}
+
+	if !request.fixedVersion() {
+		request, err = request.fixVersion(s)
 		if err != nil {
-			return fmt.Errorf("error walking extracted directory (%s): %w", extractDir, err)
+			return Request{}, err
 		}
+	}
+	// Spell the version the way cacheKey does, so that "1.0" and "1.0.0"
+	// share the on-disk cache entry and its recorded hashes as well as the
+	// in-memory caches.
+	request.Version = canonicalVersion(request.Version)
+
+	// The (possibly resolved) version is now used for URL/cache-path
+	// construction, so it must be URL/path safe.
+	if err := s.validateCacheRequestVersion(request); err != nil {
+		return Request{}, fmt.Errorf("invalid provider request: %w", err)
+	}
+	return request, nil
+}
 
-		l.Debug("Checking extracted file", "path", path, "is_dir", d.IsDir(), "name", d.Name())
+// fetchDownloadMetadata queries the registry download endpoint for request
+// and validates the fields of the response that are later used to build
+// local paths and outbound requests.
+func (s *Server) fetchDownloadMetadata(request Request, rl *slog.Logger) (pluginApiResponse, error) {
+	return s.queryDownloadMetadata(request, rl, true)
+}
 
-		if d.IsDir() && d.Name() != "." {
-			return fs.SkipDir
-		}
+// queryDownloadMetadata is fetchDownloadMetadata, storing the response for
+// revalidation only if store is set.
+func (s *Server) queryDownloadMetadata(request Request, rl *slog.Logger, store bool) (pluginApiResponse, error) {
+	var pluginResponse pluginApiResponse
 
-		if !strings.HasPrefix(d.Name(), wantProviderFileName) {
-			return nil
-		}
+	u, err := request.URL()
+	if err != nil {
+		return pluginResponse, err
+	}
+	if u, err = s.registryURL(request.RegistryType, u); err != nil {
+		return pluginResponse, err
+	}
+	rl.Debug("Sending request to registry API", "url", u)
 
-		l.Info("Found provider file", "provider_file_name", d.Name())
+	body, status, err := s.registryFetch(u, store)
+	if err != nil {
+		return pluginResponse, err
+	}
 
-		s.dlc[request] = filepath.Join(extractDir, path)
+	if status == http.StatusNotFound {
+		return pluginResponse, fmt.Errorf("%w: %s", ErrPluginNotFound, u)
+	}
 
-		return fs.SkipAll
-	}); err != nil {
-		return fmt.Errorf("error checking extracted files: %w", err)
+	if status != http.StatusOK {
+		return pluginResponse, fmt.Errorf("%w: %s => %d", ErrPluginApi, u, status)
 	}
 
-	// At this point we still hold the write lock (deferred Unlock above), so we must NOT
-	// attempt to acquire a read lock again (doing so deadlocks). Just check directly.
-	if _, exists := s.dlc[request]; !exists {
-		return fmt.Errorf("provider file not found in extracted directory (%s) for request: %s", extractDir, request.String())
+	if err := decodeRegistryJSON(body, &pluginResponse, "download metadata"); err != nil {
+		return pluginResponse, fmt.Errorf("%s: %w", u, err)
 	}
+	pluginResponse.metadataURL = u
 
-	return nil
+	rl.Debug("Registry download metadata received", "arch", pluginResponse.Arch, "os", pluginResponse.OS, "filename", pluginResponse.FileName, "download_url", pluginResponse.DownloadURL, "protocols", pluginResponse.Protocols)
+
+	// Sanitize the filename reported by the registry before using it as a
+	// local filesystem path component. It must be a simple base name with no
+	// path separators or traversal; anything else is rejected to avoid
+	// writing outside s.tmpDir if the registry response is malicious or
+	// corrupted.
+	if err := validateProviderFileName(pluginResponse.FileName); err != nil {
+		return pluginResponse, fmt.Errorf("invalid plugin filename from registry: %w", err)
+	}
+
+	if err := validateDownloadResponse(pluginResponse); err != nil {
+		return pluginResponse, fmt.Errorf("%s: %w", u, err)
+	}
+	if pluginResponse.DownloadURL, err = s.resolveDownloadURL(u, pluginResponse.DownloadURL); err != nil {
+		return pluginResponse, err
+	}
+	return pluginResponse, nil
 }
 
 // notifyCacheStatusWith invokes the provided cache-status callback. The
@@ -711,240 +1342,255 @@ func (s *Server) notifyCacheStatusWith(fn CacheStatusFunc, request Request, stat
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			s.l.Error("cache status callback panicked", "panic", r)
+			s.logger(logComponentCache).Error("cache status callback panicked", "panic", r)
 		}
 	}()
 	fn(request, status)
 }
 
 // GetResourceSchema retrieves the schema for a specific resource from the provider.
-func (s *Server) GetResourceSchema(request Request, resource string) (*tfjson.Schema, error) {
-	s.l.Info("Getting resource schema", "request", request, "resource", resource)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	schemaResource, ok := schemaResp.ResourceSchemas[resource]
-	if !ok {
-		return nil, fmt.Errorf("resource schema not found: %s", resource)
-	}
-
-	return schemaResource, nil
+// The result is shared with the Server's cache and must not be modified
+// unless WithCloneSchemas is set; see CloneSchema.
+func (s *Server) GetResourceSchema(request Request, resource string, opts ...SchemaOption) (*tfjson.Schema, error) {
+	return s.getKindSchema(request, KindResource, resource, opts)
 }
 
 // GetDataSourceSchema retrieves the schema for a specific data source from the provider.
-func (s *Server) GetDataSourceSchema(request Request, dataSource string) (*tfjson.Schema, error) {
-	s.l.Info("Getting data source schema", "request", request, "data_source", dataSource)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	schemaResource, ok := schemaResp.DataSourceSchemas[dataSource]
-	if !ok {
-		return nil, fmt.Errorf("data source schema not found: %s", dataSource)
-	}
-
-	return schemaResource, nil
+// Like GetResourceSchema, the result is shared unless WithCloneSchemas is set.
+func (s *Server) GetDataSourceSchema(request Request, dataSource string, opts ...SchemaOption) (*tfjson.Schema, error) {
+	return s.getKindSchema(request, KindDataSource, dataSource, opts)
 }
 
 // GetFunctionSchema retrieves the schema for a specific function from the provider.
-func (s *Server) GetFunctionSchema(request Request, function string) (*tfjson.FunctionSignature, error) {
-	s.l.Info("Getting function schema", "request", request, "function", function)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	schemaFunction, ok := schemaResp.Functions[function]
-	if !ok {
-		return nil, fmt.Errorf("function schema not found: %s", function)
-	}
-	return schemaFunction, nil
+// Like GetResourceSchema, the result is shared unless WithCloneSchemas is set;
+// see CloneFunctionSignature.
+func (s *Server) GetFunctionSchema(request Request, function string, opts ...SchemaOption) (*tfjson.FunctionSignature, error) {
+	return s.getFunction(request, function, opts)
 }
 
 // GetEphemeralResourceSchema retrieves the schema for a specific ephemeral resource from the provider.
-func (s *Server) GetEphemeralResourceSchema(request Request, ephemeralResource string) (*tfjson.Schema, error) {
-	s.l.Info("Getting ephemeral resource schema", "request", request, "ephemeral_resource", ephemeralResource)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	schemaResource, ok := schemaResp.EphemeralResourceSchemas[ephemeralResource]
-	if !ok {
-		return nil, fmt.Errorf("ephemeral resource schema not found: %s", ephemeralResource)
-	}
-
-	return schemaResource, nil
+// Like GetResourceSchema, the result is shared unless WithCloneSchemas is set.
+func (s *Server) GetEphemeralResourceSchema(request Request, ephemeralResource string, opts ...SchemaOption) (*tfjson.Schema, error) {
+	return s.getKindSchema(request, KindEphemeralResource, ephemeralResource, opts)
 }
 
 // GetProviderSchema retrieves the schema for the provider configuration.
-func (s *Server) GetProviderSchema(request Request) (*tfjson.Schema, error) {
-	s.l.Info("Getting provider schema", "request", request)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	return schemaResp.ConfigSchema, nil
+// Like GetResourceSchema, the result is shared unless WithCloneSchemas is set.
+func (s *Server) GetProviderSchema(request Request, opts ...SchemaOption) (*tfjson.Schema, error) {
+	return s.getKindSchema(request, KindProviderConfig, "", opts)
 }
 
 // ListResources retrieves the list of resource names from the provider.
+// Unless the provider's schema is already cached, the names are read with
+// the provider's GetMetadata RPC, which transfers and converts no schemas;
+// the same applies to the other List methods.
 func (s *Server) ListResources(request Request) ([]string, error) {
-	s.l.Info("Listing resources", "request", request)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	resources := make([]string, 0, len(schemaResp.ResourceSchemas))
-	for name := range schemaResp.ResourceSchemas {
-		resources = append(resources, name)
-	}
-
-	slices.Sort(resources)
-	return resources, nil
+	return s.List(request, KindResource, ListOptions{})
 }
 
 // ListDataSources retrieves the list of data source names from the provider.
 func (s *Server) ListDataSources(request Request) ([]string, error) {
-	s.l.Info("Listing data sources", "request", request)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	dataSources := make([]string, 0, len(schemaResp.DataSourceSchemas))
-	for name := range schemaResp.DataSourceSchemas {
-		dataSources = append(dataSources, name)
-	}
-
-	slices.Sort(dataSources)
-	return dataSources, nil
+	return s.List(request, KindDataSource, ListOptions{})
 }
 
 // ListFunctions retrieves the list of function names from the provider.
+// It fails with ErrNotSupportedByProvider if the provider was run and found
+// to predate provider-defined functions.
 func (s *Server) ListFunctions(request Request) ([]string, error) {
-	s.l.Info("Listing functions", "request", request)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	functions := make([]string, 0, len(schemaResp.Functions))
-	for name := range schemaResp.Functions {
-		functions = append(functions, name)
-	}
-
-	slices.Sort(functions)
-	return functions, nil
+	return s.List(request, KindFunction, ListOptions{})
 }
 
 // ListEphemeralResources retrieves the list of ephemeral resource names from the provider.
+// It fails with ErrNotSupportedByProvider if the provider was run and found
+// to predate ephemeral resources.
 func (s *Server) ListEphemeralResources(request Request) ([]string, error) {
-	s.l.Info("Listing ephemeral resources", "request", request)
-
-	schemaResp, err := s.readSchema(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read provider schema: %w", err)
-	}
-
-	ephemeralResources := make([]string, 0, len(schemaResp.EphemeralResourceSchemas))
-	for name := range schemaResp.EphemeralResourceSchemas {
-		ephemeralResources = append(ephemeralResources, name)
-	}
-
-	slices.Sort(ephemeralResources)
-	return ephemeralResources, nil
+	return s.List(request, KindEphemeralResource, ListOptions{})
 }
 
 // getSchema creates a universal provider client for the given request
-func (s *Server) getSchema(request Request) (*tfjson.ProviderSchema, error) {
-	s.l.Info("Getting provider schema", "request", request)
-
+func (s *Server) getSchema(request Request) (*lazySchema, error) {
 	if !request.fixedVersion() {
 		return nil, fmt.Errorf("version must be fixed before getting schema")
 	}
 
-	// Normalize RegistryType so the in-memory schema cache (s.sc) and
-	// download cache (s.dlc) share the same key for empty/unknown values
-	// as RegistryTypeOpenTofu. Server.Get performs the same normalization.
+	// Key the in-memory schema cache (s.sc) and download cache (s.dlc)
+	// the same way Server.Get does, so empty/unknown RegistryType values and
+	// differently-cased namespace/name values share entries.
 	request.RegistryType = normalizedRegistryType(request.RegistryType)
+	key := cacheKey(request)
 
 	s.mu.RLock()
-	if resp, exists := s.sc[request]; exists {
+	if resp, exists := s.sc[key]; exists {
 		s.mu.RUnlock()
+		s.logger(logComponentCache).Debug("Provider schema served from in-memory cache", s.requestLogAttrs(request)...)
 		return resp, nil
 	}
 	s.mu.RUnlock()
 
-	// Ensure the provider is downloaded
-	if err := s.Get(request); err != nil {
-		return nil, fmt.Errorf("failed to download provider: %w", err)
+	if loaded, ok := s.loadedSchema(request); ok {
+		s.logger(logComponentCache).Debug("Provider schema served from loaded schema JSON", s.requestLogAttrs(request)...)
+		return loaded, nil
 	}
 
-	// Get the provider path
-	s.mu.RLock()
-	providerPath, exists := s.dlc[request]
-	if !exists {
-		s.mu.RUnlock()
-		return nil, fmt.Errorf("provider not found in cache: %s", request.String())
+	if s.sources != nil {
+		resp, err, _ := s.schemas.Do(key.String(), func() (any, error) {
+			return s.sourceSchema(request, key)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*lazySchema), nil
 	}
-	s.mu.RUnlock()
 
-	client, err := newGrpcClient(providerPath)
+	bundled, ok, err := s.bundledSchema(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+		return nil, err
+	}
+	if ok {
+		s.logger(logComponentCache).Debug("Provider schema served from schema bundle", s.requestLogAttrs(request)...)
+		return s.storeSchema(request, key, bundled), nil
+	}
+	if !s.pluginExec {
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotBundled, request.String())
 	}
-	defer client.close()
 
-	// Use the unified Schema() method to retrieve a terraform-json ProviderSchema
-	providerSchema, err := client.schema()
+	// Concurrent calls for the same provider share one plugin run.
+	resp, err, _ := s.schemas.Do(key.String(), func() (any, error) {
+		return s.loadSchema(request, key)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get provider schema: %w", err)
+		return nil, err
 	}
+	return resp.(*lazySchema), nil
+}
 
-	if providerSchema == nil {
-		return nil, errors.New("provider schema is nil")
+// storeSchema records schema under key unless another goroutine got there
+// first, and returns the recorded schema. Keeping the first one means every
+// caller sees the same schema instance. Recording a schema publishes
+// SchemaEventRefreshed for request.
+func (s *Server) storeSchema(request Request, key providerKey, schema *lazySchema) *lazySchema {
+	if s.internSchemas {
+		schema.intern()
+	}
+	schema.timeConversions(&s.conversions)
+	s.mu.Lock()
+	if existing, ok := s.sc[key]; ok {
+		s.mu.Unlock()
+		return existing
 	}
+	s.sc[key] = schema
+	s.mu.Unlock()
+	s.publish(SchemaEvent{Kind: SchemaEventRefreshed, Request: request})
+	s.flushLogSummary(request)
+	return schema
+}
+
+// loadSchema downloads the provider for request, whose version must be
+// fixed, and runs it to retrieve its schema.
+func (s *Server) loadSchema(request Request, key providerKey) (*lazySchema, error) {
+	pl := s.logger(logComponentPlugin).With(s.requestLogAttrs(request)...)
 
-	// Sanitize nil values to avoid nil dereference errors later
-	// (these should ideally never be nil, but just in case).
-	if providerSchema.DataSourceSchemas == nil {
-		providerSchema.DataSourceSchemas = make(map[string]*tfjson.Schema)
+	// A run that finished after the lookup in getSchema has already
+	// recorded the schema.
+	s.mu.RLock()
+	resp, exists := s.sc[key]
+	s.mu.RUnlock()
+	if exists {
+		return resp, nil
 	}
 
-	if providerSchema.ResourceSchemas == nil {
-		providerSchema.ResourceSchemas = make(map[string]*tfjson.Schema)
+	pluginStart := time.Now()
+	client, providerPath, err := s.startProvider(request, key)
+	if err != nil {
+		return nil, err
 	}
+	defer client.close()
+
+	// Fetch the raw schema; entries are converted to terraform-json on
+	// first access rather than all up-front.
+	providerSchema, err := client.rawSchema()
+	if err != nil {
+		err = fmt.Errorf("failed to get provider schema: %w", err)
+		s.retainProviderOnFailure(err, providerPath)
+		return nil, err
+	}
+	pl.Info("Retrieved provider schema",
+		"resources", len(schemaNames(providerSchema, providerSchema.resources)),
+		"data_sources", len(schemaNames(providerSchema, providerSchema.dataSources)),
+		"ephemeral_resources", len(schemaNames(providerSchema, providerSchema.ephemeralResources)),
+		"functions", len(schemaNames(providerSchema, providerSchema.functions)),
+		durationLogAttr(time.Since(pluginStart)))
+	s.schemaLoads.record(time.Since(pluginStart))
+	providerSchema.features = client.features()
+
+	return s.storeSchema(request, key, providerSchema), nil
+}
 
-	if providerSchema.EphemeralResourceSchemas == nil {
-		providerSchema.EphemeralResourceSchemas = make(map[string]*tfjson.Schema)
+// startProvider downloads the provider for request, whose version must be
+// fixed, verifies its binary as configured and starts it. The caller must
+// close the returned client.
+func (s *Server) startProvider(request Request, key providerKey) (universalProvider, string, error) {
+	// Ensure the provider is downloaded
+	err := s.get(request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download provider: %w", err)
 	}
 
-	if providerSchema.Functions == nil {
-		providerSchema.Functions = make(map[string]*tfjson.FunctionSignature)
+	// Get the provider path
+	s.mu.RLock()
+	providerPath, exists := s.dlc[key]
+	if !exists {
+		s.mu.RUnlock()
+		return nil, "", fmt.Errorf("provider not found in cache: %s", request.String())
 	}
+	s.mu.RUnlock()
 
-	if providerSchema.ConfigSchema == nil {
-		providerSchema.ConfigSchema = &tfjson.Schema{}
+	if s.integrityCheck {
+		if providerPath, err = s.verifyCachedProvider(request, key, providerPath); err != nil {
+			return nil, "", err
+		}
+	}
+	if s.contentStore {
+		if err := s.verifyProviderBinary(providerPath); err != nil {
+			return nil, "", err
+		}
 	}
 
-	// cache and return
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.sc[request] = providerSchema
-	return s.sc[request], nil
+	client, err := s.startProviderBinary(request, providerPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, providerPath, nil
+}
+
+// startProviderBinary checks the provider binary at providerPath against
+// the exec policy, records its execution in the audit log and starts it.
+// The caller must close the returned client.
+func (s *Server) startProviderBinary(request Request, providerPath string) (universalProvider, error) {
+	if s.execPolicy != nil || s.auditLogPath != "" {
+		digest, err := hashFile(providerPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash provider binary: %w", err)
+		}
+		if err := s.checkExecPolicy(request, providerPath, digest); err != nil {
+			return nil, err
+		}
+		if s.auditLogPath != "" {
+			if err := s.writeAuditEntry(request, providerPath, digest); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	pluginStart := time.Now()
+	client, err := newGrpcClient(providerPath, s.logger(logComponentPlugin), s.grpcMaxRecvMsgSize)
+	if err != nil {
+		err = fmt.Errorf("failed to create gRPC client: %w", err)
+		s.retainProviderOnFailure(err, providerPath)
+		return nil, err
+	}
+	s.logger(logComponentPlugin).Debug("Started provider plugin", append(s.requestLogAttrs(request), "path", providerPath, durationLogAttr(time.Since(pluginStart)))...)
+	return client, nil
 }
 
 // latestVersionOf returns the latest version from the provided collection that matches the given constraints.
@@ -63,9 +63,11 @@ func TestServer_AzAPI(t *testing.T) {
 			assert.Len(t, s.dlc, 1)
 
 			// Get schema
-			schema, err := s.getSchema(request)
+			ls, err := s.getSchema(request)
+			require.NoError(t, err)
+			require.NotNil(t, ls)
+			schema, err := ls.providerSchema()
 			require.NoError(t, err)
-			require.NotNil(t, schema)
 
 			// Check that we got actual schema data
 			var resourceSchemas = schema.ResourceSchemas
@@ -145,9 +147,11 @@ func TestServer_AzureRM(t *testing.T) {
 			assert.Len(t, s.dlc, 1)
 
 			// Get schema
-			schema, err := s.getSchema(request)
+			ls, err := s.getSchema(request)
+			require.NoError(t, err)
+			require.NotNil(t, ls)
+			schema, err := ls.providerSchema()
 			require.NoError(t, err)
-			require.NotNil(t, schema)
 
 			// Check that we got actual schema data
 			resourceSchemas := schema.ResourceSchemas
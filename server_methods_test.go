@@ -1,6 +1,8 @@
 package tfpluginschema
 
 import (
+	"bytes"
+	"log/slog"
 	"testing"
 
 	goversion "github.com/hashicorp/go-version"
@@ -11,13 +13,13 @@ import (
 
 func TestGetDataSourceSchema_Success(t *testing.T) {
 	s := NewServer(nil)
-	t.Cleanup(s.Cleanup)
+	t.Cleanup(func() { _ = s.Cleanup() })
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{
 		DataSourceSchemas: map[string]*tfjson.Schema{
 			"ds": {Block: &tfjson.SchemaBlock{}},
 		},
-	}
+	})
 	got, err := s.GetDataSourceSchema(req, "ds")
 	require.NoError(t, err)
 	assert.NotNil(t, got)
@@ -26,22 +28,23 @@ func TestGetDataSourceSchema_Success(t *testing.T) {
 func TestGetDataSourceSchema_NotFound(t *testing.T) {
 	s := NewServer(nil)
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{DataSourceSchemas: map[string]*tfjson.Schema{}}
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{DataSourceSchemas: map[string]*tfjson.Schema{}})
 	got, err := s.GetDataSourceSchema(req, "missing")
 	assert.Nil(t, got)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "data source schema not found")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
 }
 
 func TestGetFunctionSchema_Success(t *testing.T) {
 	s := NewServer(nil)
-	t.Cleanup(s.Cleanup)
+	t.Cleanup(func() { _ = s.Cleanup() })
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{
 		Functions: map[string]*tfjson.FunctionSignature{
 			"fn": {Summary: "ok"},
 		},
-	}
+	})
 	got, err := s.GetFunctionSchema(req, "fn")
 	require.NoError(t, err)
 	assert.NotNil(t, got)
@@ -51,22 +54,23 @@ func TestGetFunctionSchema_Success(t *testing.T) {
 func TestGetFunctionSchema_NotFound(t *testing.T) {
 	s := NewServer(nil)
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{Functions: map[string]*tfjson.FunctionSignature{}}
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{Functions: map[string]*tfjson.FunctionSignature{}})
 	got, err := s.GetFunctionSchema(req, "missing")
 	assert.Nil(t, got)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "function schema not found")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
 }
 
 func TestGetEphemeralResourceSchema_Success(t *testing.T) {
 	s := NewServer(nil)
-	t.Cleanup(s.Cleanup)
+	t.Cleanup(func() { _ = s.Cleanup() })
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{
 		EphemeralResourceSchemas: map[string]*tfjson.Schema{
 			"er": {Block: &tfjson.SchemaBlock{}},
 		},
-	}
+	})
 	got, err := s.GetEphemeralResourceSchema(req, "er")
 	require.NoError(t, err)
 	assert.NotNil(t, got)
@@ -75,21 +79,23 @@ func TestGetEphemeralResourceSchema_Success(t *testing.T) {
 func TestGetEphemeralResourceSchema_NotFound(t *testing.T) {
 	s := NewServer(nil)
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{EphemeralResourceSchemas: map[string]*tfjson.Schema{}}
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{EphemeralResourceSchemas: map[string]*tfjson.Schema{}})
 	got, err := s.GetEphemeralResourceSchema(req, "missing")
 	assert.Nil(t, got)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "ephemeral resource schema not found")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
 }
 
 func TestGetResourceSchema_NotFound(t *testing.T) {
 	s := NewServer(nil)
 	req := Request{Namespace: "n", Name: "p", Version: "1.2.3", RegistryType: RegistryTypeOpenTofu}
-	s.sc[req] = &tfjson.ProviderSchema{ResourceSchemas: map[string]*tfjson.Schema{}}
+	s.sc[cacheKey(req)] = newConvertedSchema(&tfjson.ProviderSchema{ResourceSchemas: map[string]*tfjson.Schema{}})
 	got, err := s.GetResourceSchema(req, "missing")
 	assert.Nil(t, got)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "resource schema not found")
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
 }
 
 func TestRequest_fixedVersion(t *testing.T) {
@@ -252,7 +258,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"1.0.0", "1.1.0", "2.0.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"1.0.0", "1.1.0", "2.0.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -270,7 +276,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"0.9.0", "1.0.0", "1.5.0", "2.0.0", "2.1.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"0.9.0", "1.0.0", "1.5.0", "2.0.0", "2.1.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -288,7 +294,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"1.0.0", "1.1.0", "1.1.5", "1.2.0", "2.0.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"1.0.0", "1.1.0", "1.1.5", "1.2.0", "2.0.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -306,7 +312,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"1.0.0", "1.1.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"1.0.0", "1.1.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -324,7 +330,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"1.0.0", "1.1.0", "1.1.5", "1.2.0", "2.0.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"1.0.0", "1.1.0", "1.1.5", "1.2.0", "2.0.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -342,7 +348,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response with no matching versions
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"1.0.0", "2.0.0", "3.0.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"1.0.0", "2.0.0", "3.0.0"})
 			},
 			expectedResult: Request{},
 			expectedError:  "failed to get latest version",
@@ -403,7 +409,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"1.0.0", "1.1.0", "1.2.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"1.0.0", "1.1.0", "1.2.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -421,7 +427,7 @@ func TestRequest_fixVersion(t *testing.T) {
 			},
 			setupServer: func(s *Server) {
 				// Mock the versions response
-				s.versionsc[VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu}] = mustVersions([]string{"0.9.0", "1.0.0", "1.2.0", "1.3.0", "1.4.0", "2.0.0"})
+				s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions([]string{"0.9.0", "1.0.0", "1.2.0", "1.3.0", "1.4.0", "2.0.0"})
 			},
 			expectedResult: Request{
 				Namespace: "hashicorp",
@@ -472,7 +478,7 @@ func TestRequest_fixVersion_PassesRegistryType(t *testing.T) {
 		Name:         "aws",
 		RegistryType: RegistryTypeTerraform,
 	}
-	s.versionsc[key] = mustVersions(t, "1.0.0", "1.1.0", "2.0.0")
+	s.versionsc[versionsCacheKey(key)] = mustVersions(t, "1.0.0", "1.1.0", "2.0.0")
 
 	req := Request{
 		Namespace:    "hashicorp",
@@ -485,3 +491,27 @@ func TestRequest_fixVersion_PassesRegistryType(t *testing.T) {
 	assert.Equal(t, "2.0.0", got.Version)
 	assert.Equal(t, RegistryTypeTerraform, got.RegistryType)
 }
+
+func TestServer_ReadSchema_ResolvesVersionOnce(t *testing.T) {
+	var buf bytes.Buffer
+	registry := newCountingRegistry(t, "null")
+	s := NewServer(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		WithCacheDir(t.TempDir()), WithHTTPClient(stubRegistryClient(t, registry)), WithDiskSpaceCheck(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	// The fake binary cannot run; only the resolution leading up to it
+	// matters here.
+	_, err := s.ListResources(Request{Namespace: "hashicorp", Name: "null", Version: "~> 1.0"})
+	require.Error(t, err)
+
+	resolved := 0
+	for _, rec := range decodeLogRecords(t, &buf) {
+		if rec["msg"] == "Resolved provider version" {
+			resolved++
+			assert.Equal(t, "1.0.0", rec["resolved_version"])
+		}
+	}
+	assert.Equal(t, 1, resolved)
+	assert.Equal(t, 1, registry.count("/v1/providers/hashicorp/null/versions"))
+	assert.Equal(t, 1, registry.count("/null.zip"))
+}
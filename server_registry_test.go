@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestRequest_String_URLFormat tests that the URL format is correct for both registries
@@ -137,6 +138,32 @@ func TestBackwardCompatibility_VersionsRequestWithoutRegistryType(t *testing.T)
 	assert.Contains(t, url, "https://registry.opentofu.org/v1/providers/hashicorp/aws/versions")
 }
 
+func TestRequest_URL(t *testing.T) {
+	u, err := Request{Namespace: "hashicorp", Name: "random", Version: "3.6.0"}.URL()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(u, "https://registry.opentofu.org/v1/providers/hashicorp/random/3.6.0/download/"), u)
+
+	for _, req := range []Request{
+		{Name: "random", Version: "3.6.0"},
+		{Namespace: "hashicorp", Name: "random"},
+		{Namespace: "hashicorp", Name: "random", Version: "~>3.6"},
+		{Namespace: "hashi corp", Name: "random", Version: "3.6.0"},
+		{Namespace: "hashicorp", Name: "random?x=1", Version: "3.6.0"},
+	} {
+		_, err := req.URL()
+		assert.Error(t, err, "%+v", req)
+	}
+}
+
+func TestVersionsRequest_URL(t *testing.T) {
+	u, err := VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeTerraform}.URL()
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.terraform.io/v1/providers/hashicorp/aws/versions", u)
+
+	_, err = VersionsRequest{Namespace: "hashicorp", Name: "a/b"}.URL()
+	assert.Error(t, err)
+}
+
 // TestRegistryTypeConstants verifies that the constants are defined correctly
 func TestRegistryTypeConstants(t *testing.T) {
 	assert.Equal(t, RegistryType("opentofu"), RegistryTypeOpenTofu, "OpenTofu constant should be 'opentofu'")
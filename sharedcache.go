@@ -0,0 +1,85 @@
+package tfpluginschema
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// WithSharedCache makes the Server share its in-memory caches and temporary
+// directory with every other Server in the process created with this option
+// for the same cache directory and temporary root (see WithCacheDir and
+// WithTempDir). Libraries embedding tfpluginschema in different components
+// then reuse each other's downloads and schemas instead of each creating a
+// temporary directory and fetching the same providers.
+//
+// Shared state is reference counted: Cleanup on one Server only detaches
+// it, and the temporary directory is removed when the last Server sharing it
+// is cleaned up. Use CleanupRequest with care, as it affects every Server
+// sharing the cache.
+func WithSharedCache(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.sharedCache = enabled
+	}
+}
+
+// sharedCacheKey identifies a process-wide cacheState.
+type sharedCacheKey struct {
+	cacheDir string
+	tempRoot string
+}
+
+type sharedCacheEntry struct {
+	state *cacheState
+	refs  int
+}
+
+var sharedCaches = struct {
+	sync.Mutex
+	m map[sharedCacheKey]*sharedCacheEntry
+}{m: make(map[sharedCacheKey]*sharedCacheEntry)}
+
+// acquireSharedCache returns the cacheState shared by Servers using
+// cacheDir and tempRoot, creating it if needed, and takes a reference to it.
+func acquireSharedCache(cacheDir, tempRoot string) (*cacheState, *sharedCacheKey) {
+	key := &sharedCacheKey{cacheDir: canonicalDir(cacheDir), tempRoot: canonicalDir(tempRoot)}
+
+	sharedCaches.Lock()
+	defer sharedCaches.Unlock()
+	e, ok := sharedCaches.m[*key]
+	if !ok {
+		e = &sharedCacheEntry{state: newCacheState()}
+		sharedCaches.m[*key] = e
+	}
+	e.refs++
+	return e.state, key
+}
+
+// releaseSharedCache drops a reference taken by acquireSharedCache and
+// reports whether it was the last one, in which case the caller is
+// responsible for cleaning up state.
+func releaseSharedCache(key *sharedCacheKey, state *cacheState) bool {
+	sharedCaches.Lock()
+	defer sharedCaches.Unlock()
+	e, ok := sharedCaches.m[*key]
+	if !ok || e.state != state {
+		return true
+	}
+	e.refs--
+	if e.refs > 0 {
+		return false
+	}
+	delete(sharedCaches.m, *key)
+	return true
+}
+
+// canonicalDir returns an absolute, cleaned form of dir so that equivalent
+// spellings share a cache. An empty dir stays empty.
+func canonicalDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
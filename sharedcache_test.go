@@ -0,0 +1,72 @@
+package tfpluginschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSharedCache_SharesState(t *testing.T) {
+	cacheDir, tempRoot := t.TempDir(), t.TempDir()
+	s1 := NewServer(nil, WithCacheDir(cacheDir), WithTempDir(tempRoot), WithSharedCache(true))
+	s2 := NewServer(nil, WithCacheDir(filepath.Join(cacheDir, ".")), WithTempDir(tempRoot), WithSharedCache(true))
+	private := NewServer(nil, WithCacheDir(cacheDir), WithTempDir(tempRoot))
+	other := NewServer(nil, WithCacheDir(t.TempDir()), WithTempDir(tempRoot), WithSharedCache(true))
+	t.Cleanup(func() {
+		_ = private.Cleanup()
+		_ = other.Cleanup()
+	})
+
+	assert.Same(t, s1.cacheState, s2.cacheState)
+	assert.NotSame(t, s1.cacheState, private.cacheState)
+	assert.NotSame(t, s1.cacheState, other.cacheState)
+
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", RegistryType: RegistryTypeOpenTofu}
+	s1.mu.Lock()
+	s1.dlc[cacheKey(req)] = "/path/to/provider"
+	dir, err := s1.requestTempDir(req)
+	s1.mu.Unlock()
+	require.NoError(t, err)
+	assert.Equal(t, "/path/to/provider", s2.dlc[cacheKey(req)])
+	assert.Equal(t, dir, s2.tmpDir)
+
+	// The first Cleanup only releases s1's reference.
+	require.NoError(t, s1.Cleanup())
+	assert.DirExists(t, dir)
+	assert.Contains(t, s2.dlc, cacheKey(req))
+	assert.Empty(t, s1.dlc, "a released Server continues with private state")
+	require.NoError(t, s1.Cleanup(), "releasing twice is harmless")
+	assert.DirExists(t, dir)
+
+	// The last one removes the shared temporary directory.
+	require.NoError(t, s2.Cleanup())
+	assert.NoDirExists(t, dir)
+	assert.Empty(t, s2.dlc)
+
+	// A new Server starts from fresh shared state.
+	s3 := NewServer(nil, WithCacheDir(cacheDir), WithTempDir(tempRoot), WithSharedCache(true))
+	t.Cleanup(func() { _ = s3.Cleanup() })
+	assert.Empty(t, s3.dlc)
+	assert.NotSame(t, s2.cacheState, s3.cacheState)
+}
+
+func TestWithSharedCache_RelativeTempRoot(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	require.NoError(t, os.Mkdir("tmp", 0o755))
+
+	s1 := NewServer(nil, WithCacheDir(t.TempDir()), WithTempDir("tmp"), WithSharedCache(true))
+	s1.mu.Lock()
+	dir, err := s1.requestTempDir(Request{})
+	s1.mu.Unlock()
+	require.NoError(t, err)
+
+	s2 := NewServer(nil, WithCacheDir(s1.cacheDir), WithTempDir(filepath.Join(root, "tmp")), WithSharedCache(true))
+	require.Same(t, s1.cacheState, s2.cacheState)
+	require.NoError(t, s1.Cleanup())
+	require.NoError(t, s2.Cleanup())
+	assert.NoDirExists(t, filepath.Join(root, dir))
+}
@@ -0,0 +1,192 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// snapshotFormatVersion is the format written by Snapshot. RestoreSnapshot
+// rejects other versions.
+const snapshotFormatVersion = 1
+
+// snapshotDoc is the JSON document written by Snapshot.
+type snapshotDoc struct {
+	FormatVersion int                `json:"format_version"`
+	Schemas       []snapshotSchema   `json:"schemas,omitempty"`
+	Versions      []snapshotVersions `json:"versions,omitempty"`
+	Downloads     []snapshotDownload `json:"downloads,omitempty"`
+}
+
+// snapshotKey is the serialized form of a providerKey.
+type snapshotKey struct {
+	Host      string `json:"host"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+}
+
+type snapshotSchema struct {
+	snapshotKey
+	Schema          *tfjson.ProviderSchema     `json:"schema"`
+	FunctionDetails map[string]FunctionDetails `json:"function_details,omitempty"`
+}
+
+type snapshotVersions struct {
+	snapshotKey
+	Versions []string `json:"versions"`
+}
+
+type snapshotDownload struct {
+	snapshotKey
+	Path string `json:"path"`
+}
+
+func newSnapshotKey(k providerKey) snapshotKey {
+	return snapshotKey{Host: k.host, Namespace: k.namespace, Name: k.name, Version: k.version, Platform: k.platform}
+}
+
+func (k snapshotKey) providerKey() providerKey {
+	return providerKey{host: k.Host, namespace: k.Namespace, name: k.Name, version: k.Version, platform: k.Platform}
+}
+
+// Snapshot writes the Server's in-memory state as JSON to w: the schema
+// cache, the versions cache and the index of downloaded providers.
+// RestoreSnapshot loads it into another Server, for example in a later CLI
+// invocation or CI job, so that it starts warm. Schemas are written fully
+// converted, so a snapshot of large providers can take a while to write.
+// The snapshot is compressed as set by WithSchemaCompression.
+func (s *Server) Snapshot(w io.Writer) error {
+	s.mu.RLock()
+	schemas := maps.Clone(s.sc)
+	doc := snapshotDoc{FormatVersion: snapshotFormatVersion}
+	for k, v := range s.versionsc {
+		versions := make([]string, len(v))
+		for i, ver := range v {
+			versions[i] = ver.Original()
+		}
+		doc.Versions = append(doc.Versions, snapshotVersions{snapshotKey: newSnapshotKey(k), Versions: versions})
+	}
+	for k, v := range s.dlc {
+		doc.Downloads = append(doc.Downloads, snapshotDownload{snapshotKey: newSnapshotKey(k), Path: v})
+	}
+	s.mu.RUnlock()
+
+	// Converting the schemas may take a while; it is done without holding
+	// s.mu.
+	for k, v := range schemas {
+		ps, err := v.providerSchema()
+		if err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		doc.Schemas = append(doc.Schemas, snapshotSchema{snapshotKey: newSnapshotKey(k), Schema: ps, FunctionDetails: v.functionDetails})
+	}
+
+	cw, flush, err := compressingWriter(w, s.schemaCompression)
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := json.NewEncoder(cw).Encode(doc); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// RestoreSnapshot loads state written by Snapshot, compressed or not, into
+// the Server. Entries the Server already holds are kept. Downloaded
+// providers are only restored if their binary still exists inside the
+// Server's cache directory; others are downloaded again when needed.
+// Version lists are restored as they were, so versions published since the
+// snapshot was taken are not seen until the Server is cleaned up.
+func (s *Server) RestoreSnapshot(r io.Reader) error {
+	r, release, err := decompressingReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer release()
+	var doc snapshotDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if doc.FormatVersion != snapshotFormatVersion {
+		return fmt.Errorf("unsupported snapshot format version %d", doc.FormatVersion)
+	}
+
+	versions := make(map[providerKey]goversion.Collection, len(doc.Versions))
+	for _, e := range doc.Versions {
+		collection := make(goversion.Collection, 0, len(e.Versions))
+		for _, v := range e.Versions {
+			ver, err := goversion.NewVersion(v)
+			if err != nil {
+				return fmt.Errorf("invalid version %q in snapshot: %w", v, err)
+			}
+			collection = append(collection, ver)
+		}
+		versions[e.providerKey()] = collection
+	}
+
+	downloads := make(map[providerKey]string, len(doc.Downloads))
+	for _, e := range doc.Downloads {
+		if !s.snapshotPathUsable(e.Path) {
+			s.logger(logComponentCache).Debug("Skipping provider from snapshot that is no longer cached", "path", e.Path)
+			continue
+		}
+		downloads[e.providerKey()] = e.Path
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range doc.Schemas {
+		if e.Schema == nil {
+			continue
+		}
+		if _, ok := s.sc[e.providerKey()]; !ok {
+			schema := newConvertedSchema(e.Schema)
+			schema.functionDetails = e.FunctionDetails
+			if s.internSchemas {
+				schema.intern()
+			}
+			s.sc[e.providerKey()] = schema
+		}
+	}
+	for k, v := range versions {
+		if _, ok := s.versionsc[k]; !ok {
+			s.versionsc[k] = v
+		}
+	}
+	for k, v := range downloads {
+		if _, ok := s.dlc[k]; !ok {
+			s.dlc[k] = v
+		}
+	}
+	return nil
+}
+
+// snapshotPathUsable reports whether path, taken from a snapshot, names an
+// existing provider binary inside the cache directory. Anything else could
+// make the Server execute a file it did not download.
+func (s *Server) snapshotPathUsable(path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	rel, err := filepath.Rel(s.cacheDir, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	if !strings.HasPrefix(strings.ToLower(filepath.Base(path)), providerFileNamePrefix) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,58 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_SnapshotRoundTrip(t *testing.T) {
+	cacheDir := t.TempDir()
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0", RegistryType: RegistryTypeOpenTofu}
+	bundle := fstest.MapFS{"opentofu/hashicorp/aws/5.40.0.json": {Data: []byte(bundledAWSSchema)}}
+
+	src := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle))
+	t.Cleanup(func() { _ = src.Cleanup() })
+	_, err := src.GetResourceSchema(req, "aws_instance")
+	require.NoError(t, err)
+	bin := writeFakeProviderBinary(t, cacheDir, req)
+	outside := filepath.Join(t.TempDir(), providerFileNamePrefix+"evil")
+	src.mu.Lock()
+	src.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = mustVersions(t, "5.39.0", "5.40.0")
+	src.dlc[cacheKey(req)] = bin
+	src.dlc[cacheKey(Request{Namespace: "evil", Name: "evil", Version: "1.0.0"})] = outside
+	src.mu.Unlock()
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Snapshot(&buf))
+
+	dst := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = dst.Cleanup() })
+	require.NoError(t, dst.RestoreSnapshot(&buf))
+
+	schema, err := dst.GetResourceSchema(Request{Namespace: "hashicorp", Name: "aws", Version: "~> 5.0"}, "aws_instance")
+	require.NoError(t, err)
+	assert.True(t, schema.Block.Attributes["ami"].Required)
+
+	versions, err := dst.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	require.NoError(t, dst.Get(req))
+	assert.Equal(t, bin, dst.dlc[cacheKey(req)])
+	assert.Len(t, dst.dlc, 1, "paths outside the cache directory must not be restored")
+}
+
+func TestServer_RestoreSnapshot_Invalid(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	assert.ErrorContains(t, s.RestoreSnapshot(strings.NewReader(`{"format_version":99}`)), "unsupported snapshot format version 99")
+	assert.ErrorContains(t, s.RestoreSnapshot(strings.NewReader(`{`)), "failed to read snapshot")
+	assert.ErrorContains(t, s.RestoreSnapshot(strings.NewReader(`{"format_version":1,"versions":[{"host":"h","namespace":"n","name":"p","versions":["x"]}]}`)), "invalid version")
+}
@@ -0,0 +1,392 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	goversion "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ErrSourceMiss is returned by a SchemaSource that cannot supply the
+// requested provider, and by a Server when none of its sources can.
+var ErrSourceMiss = errors.New("provider not available from schema source")
+
+// SchemaSource supplies provider schemas to a Server; see
+// WithSchemaSources. Requests passed to a source have their alias resolved
+// and their RegistryType set.
+type SchemaSource interface {
+	// Resolve returns the versions of the provider the source can supply,
+	// in any order. A source that cannot list versions returns none.
+	Resolve(req VersionsRequest) (goversion.Collection, error)
+	// Fetch makes request's schema available to Schema, downloading the
+	// provider if needed. request.Version is a concrete version. Fetch
+	// returns ErrSourceMiss if the source cannot supply the provider.
+	Fetch(request Request) error
+	// Schema returns request's provider schema after a successful Fetch.
+	Schema(request Request) (*tfjson.ProviderSchema, error)
+}
+
+// lazySource is implemented by sources that run the provider, so that the
+// Server can keep the schema in its unconverted form.
+type lazySource interface {
+	lazySchema(request Request) (*lazySchema, error)
+}
+
+// boundSource is implemented by sources that use the Server they are
+// configured on.
+type boundSource interface {
+	bind(s *Server) SchemaSource
+}
+
+// WithSchemaSources composes the Server from sources, which replace the
+// default lookup of the schema bundle followed by the registry. Schemas are
+// taken from the first source whose Fetch succeeds, in the order given.
+// Available versions, and so version constraints, are resolved against the
+// versions of all sources together.
+//
+//	s := NewServer(nil, WithSchemaSources(
+//		BundleSource(bundle),
+//		LocalBinarySource(os.Getenv("TF_PLUGIN_CACHE_DIR")),
+//		RegistrySource(),
+//	))
+//
+// WithSchemaBundle is ignored when sources are set; use BundleSource.
+func WithSchemaSources(sources ...SchemaSource) ServerOption {
+	return func(s *Server) {
+		s.sources = make([]SchemaSource, 0, len(sources))
+		for _, src := range sources {
+			if b, ok := src.(boundSource); ok {
+				src = b.bind(s)
+			}
+			s.sources = append(s.sources, src)
+		}
+	}
+}
+
+// sourceVersions returns the versions of req from every source, sorted in
+// ascending order without duplicates.
+func (s *Server) sourceVersions(req VersionsRequest) (goversion.Collection, error) {
+	var versions goversion.Collection
+	for _, src := range s.sources {
+		v, err := src.Resolve(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve versions: %w", err)
+		}
+		versions = append(versions, v...)
+	}
+	slices.SortFunc(versions, func(a, b *goversion.Version) int {
+		return a.Compare(b)
+	})
+	return slices.CompactFunc(versions, (*goversion.Version).Equal), nil
+}
+
+// sourceSchema returns request's schema from the first source that can
+// supply it, and records it under key.
+func (s *Server) sourceSchema(request Request, key providerKey) (*lazySchema, error) {
+	s.mu.RLock()
+	resp, exists := s.sc[key]
+	s.mu.RUnlock()
+	if exists {
+		return resp, nil
+	}
+
+	l := s.logger(logComponentCache).With(s.requestLogAttrs(request)...)
+	for i, src := range s.sources {
+		err := src.Fetch(request)
+		if errors.Is(err, ErrSourceMiss) {
+			l.Debug("Schema source cannot supply provider", "source", i, "error", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var schema *lazySchema
+		if ls, ok := src.(lazySource); ok {
+			schema, err = ls.lazySchema(request)
+		} else {
+			var ps *tfjson.ProviderSchema
+			if ps, err = src.Schema(request); err == nil {
+				schema = newConvertedSchema(ps)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.Debug("Provider schema served from schema source", "source", i)
+		return s.storeSchema(request, key, schema), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSourceMiss, request.String())
+}
+
+// RegistrySource returns the source that downloads providers from their
+// registry and executes them, as a Server does by default. It uses the
+// registry, cache and verification settings of the Server it is configured
+// on, and cannot supply schemas when plugin execution is disabled.
+func RegistrySource() SchemaSource {
+	return registrySource{}
+}
+
+type registrySource struct {
+	s *Server
+}
+
+func (r registrySource) bind(s *Server) SchemaSource {
+	return registrySource{s: s}
+}
+
+func (r registrySource) Resolve(req VersionsRequest) (goversion.Collection, error) {
+	return r.s.registryVersions(req)
+}
+
+func (r registrySource) Fetch(request Request) error {
+	if !r.s.pluginExec {
+		return fmt.Errorf("%w: plugin execution is disabled", ErrSourceMiss)
+	}
+	return r.s.get(request)
+}
+
+func (r registrySource) Schema(request Request) (*tfjson.ProviderSchema, error) {
+	ls, err := r.lazySchema(request)
+	if err != nil {
+		return nil, err
+	}
+	return ls.providerSchema()
+}
+
+func (r registrySource) lazySchema(request Request) (*lazySchema, error) {
+	return r.s.loadSchema(request, cacheKey(request))
+}
+
+// LocalBinarySource returns a source that executes provider binaries found
+// in dir, which is laid out as a Terraform plugin cache directory:
+//
+//	<host>/<namespace>/<name>/<version>/<os>_<arch>/terraform-provider-<name>_v<version>
+//
+// with the host of the request's registry, for example
+// "registry.terraform.io/hashicorp/aws/5.40.0/linux_amd64". Binaries are not
+// downloaded, verified against checksums or removed by Cleanup, but are
+// recorded in the audit log. With WithRequireVerification the source
+// refuses to execute them and fails with ErrVerificationFailed; use
+// TrustedLocalBinarySource for a directory whose binaries are trusted.
+func LocalBinarySource(dir string) SchemaSource {
+	return localBinarySource{dir: dir}
+}
+
+// TrustedLocalBinarySource is LocalBinarySource for a directory the caller
+// vouches for: its binaries are executed even with WithRequireVerification.
+func TrustedLocalBinarySource(dir string) SchemaSource {
+	return localBinarySource{dir: dir, trusted: true}
+}
+
+type localBinarySource struct {
+	dir     string
+	trusted bool
+	s       *Server
+}
+
+func (b localBinarySource) bind(s *Server) SchemaSource {
+	b.s = s
+	return b
+}
+
+// providerDir returns the directory holding the versions of req.
+func (b localBinarySource) providerDir(req VersionsRequest) string {
+	return filepath.Join(b.dir, req.RegistryType.host(), strings.ToLower(req.Namespace), strings.ToLower(req.Name))
+}
+
+func (b localBinarySource) binary(request Request) (string, bool) {
+	dir := filepath.Join(
+		b.providerDir(VersionsRequest{Namespace: request.Namespace, Name: request.Name, RegistryType: request.RegistryType}),
+		request.Version,
+		runtime.GOOS+"_"+runtime.GOARCH,
+	)
+	return findProviderBinary(dir, request.Name)
+}
+
+func (b localBinarySource) Resolve(req VersionsRequest) (goversion.Collection, error) {
+	entries, err := os.ReadDir(b.providerDir(req))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+	var versions goversion.Collection
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		v, err := goversion.NewVersion(e.Name())
+		if err != nil {
+			continue
+		}
+		req := Request{Namespace: req.Namespace, Name: req.Name, Version: e.Name(), RegistryType: req.RegistryType}
+		if _, ok := b.binary(req); ok {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+func (b localBinarySource) Fetch(request Request) error {
+	if !b.s.pluginExec {
+		return fmt.Errorf("%w: plugin execution is disabled", ErrSourceMiss)
+	}
+	if _, ok := b.binary(request); !ok {
+		return fmt.Errorf("%w: no binary for %s/%s %s in %s", ErrSourceMiss, request.Namespace, request.Name, request.Version, b.dir)
+	}
+	return b.checkVerification(request)
+}
+
+// checkVerification refuses the unverified binaries of an untrusted
+// directory when the Server requires verification.
+func (b localBinarySource) checkVerification(request Request) error {
+	if b.s.requireVerification && !b.trusted {
+		return fmt.Errorf("%w: local binary for %s/%s %s in %s is not verified", ErrVerificationFailed, request.Namespace, request.Name, request.Version, b.dir)
+	}
+	return nil
+}
+
+func (b localBinarySource) Schema(request Request) (*tfjson.ProviderSchema, error) {
+	ls, err := b.lazySchema(request)
+	if err != nil {
+		return nil, err
+	}
+	return ls.providerSchema()
+}
+
+func (b localBinarySource) lazySchema(request Request) (*lazySchema, error) {
+	path, ok := b.binary(request)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSourceMiss, request.String())
+	}
+	if err := b.checkVerification(request); err != nil {
+		return nil, err
+	}
+	client, err := b.s.startProviderBinary(request, path)
+	if err != nil {
+		return nil, err
+	}
+	defer client.close()
+	schema, err := client.rawSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider schema: %w", err)
+	}
+	return schema, nil
+}
+
+// BundleSource returns a source that serves the schema bundle in fsys, laid
+// out as described for WithSchemaBundle.
+func BundleSource(fsys fs.FS) SchemaSource {
+	return bundleSource{fsys: fsys}
+}
+
+type bundleSource struct {
+	fsys fs.FS
+	l    *slog.Logger
+}
+
+func (b bundleSource) bind(s *Server) SchemaSource {
+	b.l = s.logger(logComponentCache)
+	return b
+}
+
+func (b bundleSource) Resolve(req VersionsRequest) (goversion.Collection, error) {
+	return readBundledVersions(b.fsys, req)
+}
+
+func (b bundleSource) Fetch(request Request) error {
+	p := schemaBundlePath(request)
+	err := statBundleFile(b.fsys, p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s not in schema bundle", ErrSourceMiss, p)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schema bundle file %s: %w", p, err)
+	}
+	return nil
+}
+
+func (b bundleSource) Schema(request Request) (*tfjson.ProviderSchema, error) {
+	ls, err := b.lazySchema(request)
+	if err != nil {
+		return nil, err
+	}
+	return ls.providerSchema()
+}
+
+func (b bundleSource) lazySchema(request Request) (*lazySchema, error) {
+	ls, ok, err := readBundledSchema(b.fsys, request, b.l)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s not in schema bundle", ErrSourceMiss, schemaBundlePath(request))
+	}
+	return ls, nil
+}
+
+// ProvidersSchemaFileSource returns a source that serves the providers in
+// file, the output of "terraform providers schema -json" or its OpenTofu
+// equivalent. The file does not record provider versions, so the source
+// resolves none and serves its schema for whichever version is requested;
+// place it after sources that can tell versions apart.
+func ProvidersSchemaFileSource(file string) SchemaSource {
+	return &providersSchemaFileSource{file: file}
+}
+
+type providersSchemaFileSource struct {
+	file string
+	// once guards the decoding of file into schemas or err.
+	once    sync.Once
+	schemas map[string]*tfjson.ProviderSchema
+	err     error
+}
+
+func (f *providersSchemaFileSource) load() (map[string]*tfjson.ProviderSchema, error) {
+	f.once.Do(func() {
+		data, err := os.ReadFile(f.file)
+		if err != nil {
+			f.err = fmt.Errorf("failed to read providers schema file: %w", err)
+			return
+		}
+		var doc struct {
+			ProviderSchemas map[string]*tfjson.ProviderSchema `json:"provider_schemas"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			f.err = fmt.Errorf("failed to decode providers schema file %s: %w", f.file, err)
+			return
+		}
+		f.schemas = doc.ProviderSchemas
+	})
+	return f.schemas, f.err
+}
+
+func (f *providersSchemaFileSource) Resolve(VersionsRequest) (goversion.Collection, error) {
+	return nil, nil
+}
+
+func (f *providersSchemaFileSource) Fetch(request Request) error {
+	_, err := f.Schema(request)
+	return err
+}
+
+func (f *providersSchemaFileSource) Schema(request Request) (*tfjson.ProviderSchema, error) {
+	schemas, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	ps, ok := pickProviderSchema(schemas, request, func(ps *tfjson.ProviderSchema) bool { return ps != nil })
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s not in %s", ErrSourceMiss, strings.ToLower(request.Namespace), strings.ToLower(request.Name), f.file)
+	}
+	return ps, nil
+}
@@ -0,0 +1,147 @@
+package tfpluginschema
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	goversion "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_SchemaSources_Fallback(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	file := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"format_version": "1.0", "provider_schemas": {
+		"registry.terraform.io/example/example": {"resource_schemas": {"example_file": {"version": 0, "block": {}}}}
+	}}`), 0o644))
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)),
+		WithSchemaSources(BundleSource(bundle), ProvidersSchemaFileSource(file)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	names, err := s.ListResources(Request{Namespace: "example", Name: "example", Version: "1.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_bucket", "example_db"}, names)
+
+	names, err = s.ListResources(Request{Namespace: "Example", Name: "example", Version: "2.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_file"}, names)
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example", Name: "example"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", versions[0].String())
+	assert.Len(t, versions, 1)
+
+	_, err = s.ListResources(Request{Namespace: "example", Name: "other", Version: "1.0.0"})
+	require.ErrorIs(t, err, ErrSourceMiss)
+}
+
+// recordingSource is a SchemaSource that records the requests it receives.
+type recordingSource struct {
+	versions []string
+	schema   *tfjson.ProviderSchema
+	fetched  []string
+}
+
+func (r *recordingSource) Resolve(VersionsRequest) (goversion.Collection, error) {
+	var vs goversion.Collection
+	for _, v := range r.versions {
+		vs = append(vs, goversion.Must(goversion.NewVersion(v)))
+	}
+	return vs, nil
+}
+
+func (r *recordingSource) Fetch(request Request) error {
+	r.fetched = append(r.fetched, request.Version)
+	if r.schema == nil {
+		return ErrSourceMiss
+	}
+	return nil
+}
+
+func (r *recordingSource) Schema(Request) (*tfjson.ProviderSchema, error) {
+	return r.schema, nil
+}
+
+func TestServer_SchemaSources_Order(t *testing.T) {
+	empty := &recordingSource{versions: []string{"1.0.0", "2.0.0"}}
+	full := &recordingSource{versions: []string{"1.5.0", "1.0.0"}, schema: &tfjson.ProviderSchema{
+		DataSourceSchemas: map[string]*tfjson.Schema{"example_thing": {Block: &tfjson.SchemaBlock{}}},
+	}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaSources(empty, full))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example", Name: "example"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.5.0", "2.0.0"}, versionStrings(versions))
+
+	names, err := s.ListDataSources(Request{Namespace: "example", Name: "example", Version: "< 2.0.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_thing"}, names)
+	assert.Equal(t, []string{"1.5.0"}, empty.fetched)
+	assert.Equal(t, []string{"1.5.0"}, full.fetched)
+
+	_, err = s.GetDataSourceSchema(Request{Namespace: "example", Name: "example", Version: "1.5.0"}, "example_thing")
+	require.NoError(t, err)
+	assert.Len(t, full.fetched, 1, "schema served from the in-memory cache")
+}
+
+func TestLocalBinarySource_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"1.0.0", "1.2.0"} {
+		platform := filepath.Join(dir, "registry.terraform.io", "hashicorp", "aws", v, runtime.GOOS+"_"+runtime.GOARCH)
+		require.NoError(t, os.MkdirAll(platform, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(platform, "terraform-provider-aws_v"+v+"_x5"), []byte("fake"), 0o755))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "registry.terraform.io", "hashicorp", "aws", "2.0.0", "other_arch"), 0o755))
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaSources(LocalBinarySource(dir)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "HashiCorp", Name: "aws", RegistryType: RegistryTypeTerraform})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.2.0"}, versionStrings(versions))
+
+	versions, err = s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	assert.Empty(t, versions, "binaries are looked up under the registry's host")
+
+	s = NewServer(nil, WithCacheDir(t.TempDir()), WithPluginExec(false), WithSchemaSources(LocalBinarySource(dir)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	_, err = s.ListResources(Request{Namespace: "hashicorp", Name: "aws", Version: "1.0.0", RegistryType: RegistryTypeTerraform})
+	require.ErrorIs(t, err, ErrSourceMiss)
+}
+
+func TestLocalBinarySource_RequireVerification(t *testing.T) {
+	dir := t.TempDir()
+	platform := filepath.Join(dir, "registry.terraform.io", "hashicorp", "aws", "1.0.0", runtime.GOOS+"_"+runtime.GOARCH)
+	require.NoError(t, os.MkdirAll(platform, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(platform, "terraform-provider-aws_v1.0.0_x5"), []byte("fake"), 0o755))
+	req := Request{Namespace: "hashicorp", Name: "aws", Version: "1.0.0", RegistryType: RegistryTypeTerraform}
+
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithRequireVerification(true),
+		WithSchemaSources(LocalBinarySource(dir)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	_, err := s.ListResources(req)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+
+	trusted := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithRequireVerification(true),
+		WithSchemaSources(TrustedLocalBinarySource(dir)))
+	t.Cleanup(func() { _ = trusted.Cleanup() })
+	_, err = trusted.ListResources(req)
+	require.Error(t, err, "the fake binary does not start")
+	assert.NotErrorIs(t, err, ErrVerificationFailed)
+}
+
+func versionStrings(vs goversion.Collection) []string {
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		out[i] = v.String()
+	}
+	return out
+}
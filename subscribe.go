@@ -0,0 +1,197 @@
+package tfpluginschema
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// SchemaEventKind says what a SchemaEvent reports.
+type SchemaEventKind int
+
+const (
+	// SchemaEventRefreshed reports that the Server read a provider's
+	// schema, from the plugin, a schema bundle or a schema source, into its
+	// in-memory cache. Schemas served from that cache do not repeat it, so
+	// it fires again for a provider only after Cleanup or CleanupRequest.
+	SchemaEventRefreshed SchemaEventKind = iota
+	// SchemaEventNewVersion reports that the registry lists a newer version
+	// of a provider than the newest one in an earlier listing. Listings are
+	// kept in memory, so a new version is only seen by WatchVersions, which
+	// lists the provider again on a schedule, or once CleanupRequest or
+	// Cleanup has discarded the previous listing.
+	SchemaEventNewVersion
+)
+
+// String returns a human-readable form of the SchemaEventKind.
+func (k SchemaEventKind) String() string {
+	switch k {
+	case SchemaEventRefreshed:
+		return "refreshed"
+	case SchemaEventNewVersion:
+		return "new version"
+	default:
+		return "unknown"
+	}
+}
+
+// SchemaEvent is sent to the subscribers of a Server; see Subscribe.
+type SchemaEvent struct {
+	Kind SchemaEventKind
+	// Request identifies the provider. For SchemaEventNewVersion, Version
+	// is the new version.
+	Request Request
+	// Previous is the newest version listed before, for
+	// SchemaEventNewVersion.
+	Previous string
+}
+
+// subscribers holds the channels returned by Subscribe and the newest
+// version each provider was listed with.
+type subscribers struct {
+	mu     sync.Mutex
+	chans  map[chan SchemaEvent]struct{}
+	newest map[providerKey]*goversion.Version
+}
+
+// Subscribe returns a channel receiving a SchemaEvent whenever a provider's
+// schema is refreshed or a new version of it is listed, so that long-running
+// processes, such as editor integrations, can reload what they show without
+// polling. The channel buffers up to buffer events; events are never waited
+// for, so a subscriber that falls further behind misses them. The returned
+// function ends the subscription and closes the channel.
+//
+// Schemas are refreshed, and versions listed, by the Server's other methods;
+// Subscribe does not call the registry itself. Run WatchVersions alongside it
+// to be told about new releases of a set of providers.
+//
+// Events are only reported by this Server, not by others sharing its cache
+// through WithSharedCache.
+func (s *Server) Subscribe(buffer int) (<-chan SchemaEvent, func()) {
+	ch := make(chan SchemaEvent, max(buffer, 0))
+	s.subs.mu.Lock()
+	if s.subs.chans == nil {
+		s.subs.chans = make(map[chan SchemaEvent]struct{})
+	}
+	s.subs.chans[ch] = struct{}{}
+	s.subs.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.subs.mu.Lock()
+			delete(s.subs.chans, ch)
+			s.subs.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publish sends e to every subscriber with room for it.
+func (s *Server) publish(e SchemaEvent) {
+	s.subs.mu.Lock()
+	defer s.subs.mu.Unlock()
+	for ch := range s.subs.chans {
+		select {
+		case ch <- e:
+		default:
+			s.logger(logComponentCache).Debug("Dropped schema event for a subscriber that is behind", append(s.requestLogAttrs(e.Request), "kind", e.Kind.String())...)
+		}
+	}
+}
+
+// noteVersions records the newest of versions, listed for req under key,
+// and publishes SchemaEventNewVersion if it is newer than the one recorded
+// before.
+func (s *Server) noteVersions(req VersionsRequest, key providerKey, versions goversion.Collection) {
+	if len(versions) == 0 {
+		return
+	}
+	latest := versions[len(versions)-1]
+
+	s.subs.mu.Lock()
+	if s.subs.newest == nil {
+		s.subs.newest = make(map[providerKey]*goversion.Version)
+	}
+	previous, seen := s.subs.newest[key]
+	if seen && !latest.GreaterThan(previous) {
+		s.subs.mu.Unlock()
+		return
+	}
+	s.subs.newest[key] = latest
+	s.subs.mu.Unlock()
+
+	if seen {
+		s.publish(SchemaEvent{
+			Kind:     SchemaEventNewVersion,
+			Request:  Request{Namespace: req.Namespace, Name: req.Name, Version: latest.String(), RegistryType: req.RegistryType},
+			Previous: previous.String(),
+		})
+	}
+}
+
+// WatchVersions lists the versions of each of requests every interval until
+// ctx is done, starting straight away, and so publishes SchemaEventNewVersion
+// to the Server's subscribers when a provider is released. Each listing
+// replaces the one held in memory, so the Server's other methods see the
+// new versions too. A failed listing is logged and tried again at the next interval.
+//
+// WatchVersions returns an error if interval is not positive or a request is
+// invalid, and otherwise ctx.Err() once ctx is done.
+func (s *Server) WatchVersions(ctx context.Context, interval time.Duration, requests ...VersionsRequest) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval %s: must be positive", interval)
+	}
+	prepared := make([]VersionsRequest, len(requests))
+	for i, req := range requests {
+		var err error
+		if prepared[i], err = s.prepareVersionsRequest(req); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, req := range prepared {
+			if ctx.Err() != nil {
+				break
+			}
+			s.pollVersions(req)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollVersions lists the versions of req, which must be validated and
+// normalized, again and publishes any new version.
+func (s *Server) pollVersions(req VersionsRequest) {
+	key := versionsCacheKey(req)
+	s.mu.Lock()
+	delete(s.versionsc, key)
+	delete(s.notFound, key)
+	s.mu.Unlock()
+
+	var versions goversion.Collection
+	var err error
+	if s.sources != nil {
+		versions, err = s.sourceVersions(req)
+	} else {
+		versions, err = s.registryVersions(req)
+	}
+	if err != nil {
+		s.logger(logComponentRegistry).Warn("Failed to list versions while watching for new ones",
+			append(s.requestLogAttrs(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType}), "error", err)...)
+		return
+	}
+	// Registry listings have been noted already; noting them again publishes
+	// nothing.
+	s.noteVersions(req, key, versions)
+}
@@ -0,0 +1,161 @@
+package tfpluginschema
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Subscribe_Refreshed(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(describedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	events, cancel := s.Subscribe(4)
+	_, err := s.GetResourceSchema(req, "example_thing")
+	require.NoError(t, err)
+	_, err = s.GetProviderSchema(req)
+	require.NoError(t, err)
+
+	require.Len(t, events, 1, "schemas served from memory are not reported again")
+	e := <-events
+	assert.Equal(t, SchemaEventRefreshed, e.Kind)
+	assert.Equal(t, "example", e.Request.Name)
+	assert.Equal(t, "1.0.0", e.Request.Version)
+
+	require.NoError(t, s.CleanupRequest(req))
+	_, err = s.GetProviderSchema(req)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	cancel()
+	cancel()
+	<-events
+	_, ok := <-events
+	assert.False(t, ok, "cancel closes the channel")
+}
+
+func TestServer_Subscribe_NewVersion(t *testing.T) {
+	var listing atomic.Value
+	listing.Store(`{"versions":[{"version":"1.0.0"},{"version":"1.1.0"}]}`)
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, listing.Load())
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := VersionsRequest{Namespace: "hashicorp", Name: "aws"}
+
+	events, cancel := s.Subscribe(1)
+	defer cancel()
+
+	_, err := s.GetAvailableVersions(req)
+	require.NoError(t, err)
+	assert.Empty(t, events, "the first listing is not a new version")
+
+	require.NoError(t, s.CleanupRequest(Request{Namespace: "hashicorp", Name: "aws"}))
+	_, err = s.GetAvailableVersions(req)
+	require.NoError(t, err)
+	assert.Empty(t, events, "an unchanged listing is not a new version")
+
+	listing.Store(`{"versions":[{"version":"1.0.0"},{"version":"1.1.0"},{"version":"1.2.0"}]}`)
+	require.NoError(t, s.CleanupRequest(Request{Namespace: "hashicorp", Name: "aws"}))
+	_, err = s.GetAvailableVersions(req)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	e := <-events
+	assert.Equal(t, SchemaEventNewVersion, e.Kind)
+	assert.Equal(t, "new version", e.Kind.String())
+	assert.Equal(t, "1.2.0", e.Request.Version)
+	assert.Equal(t, "1.1.0", e.Previous)
+}
+
+func TestServer_Subscribe_DropsWhenBehind(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()))
+	events, cancel := s.Subscribe(1)
+	defer cancel()
+
+	s.publish(SchemaEvent{Kind: SchemaEventRefreshed, Request: Request{Name: "a"}})
+	s.publish(SchemaEvent{Kind: SchemaEventRefreshed, Request: Request{Name: "b"}})
+	require.Len(t, events, 1)
+	assert.Equal(t, "a", (<-events).Request.Name)
+}
+
+func TestServer_WatchVersions(t *testing.T) {
+	var listing atomic.Value
+	listing.Store(`{"versions":[{"version":"1.0.0"},{"version":"1.1.0"}]}`)
+	var failing atomic.Bool
+	var requests atomic.Int32
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, listing.Load())
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	events, cancel := s.Subscribe(4)
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.WatchVersions(ctx, 10*time.Millisecond, VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	}()
+
+	require.Eventually(t, func() bool { return requests.Load() >= 3 }, 5*time.Second, 5*time.Millisecond)
+	assert.Empty(t, events, "an unchanged listing is not a new version")
+
+	// A failed listing is tried again.
+	failing.Store(true)
+	seen := requests.Load()
+	require.Eventually(t, func() bool { return requests.Load() >= seen+2 }, 5*time.Second, 5*time.Millisecond)
+	failing.Store(false)
+
+	listing.Store(`{"versions":[{"version":"1.0.0"},{"version":"1.1.0"},{"version":"1.2.0"}]}`)
+	var e SchemaEvent
+	select {
+	case e = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for the new version")
+	}
+	assert.Equal(t, SchemaEventNewVersion, e.Kind)
+	assert.Equal(t, "aws", e.Request.Name)
+	assert.Equal(t, "1.2.0", e.Request.Version)
+	assert.Equal(t, "1.1.0", e.Previous)
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", versions[len(versions)-1].String(), "the watched listing replaces the one in memory")
+
+	stop()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchVersions did not return")
+	}
+	assert.Empty(t, events, "the new version is reported once")
+}
+
+func TestServer_WatchVersions_Invalid(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)))
+	req := VersionsRequest{Namespace: "hashicorp", Name: "aws"}
+
+	assert.Error(t, s.WatchVersions(context.Background(), 0, req))
+	assert.Error(t, s.WatchVersions(context.Background(), time.Second, req, VersionsRequest{Namespace: "hashicorp"}))
+
+	ctx, stop := context.WithCancel(context.Background())
+	stop()
+	assert.ErrorIs(t, s.WatchVersions(ctx, time.Second, req), context.Canceled, "nothing is listed once ctx is done")
+}
@@ -0,0 +1,166 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// fetchedProvider records how a provider in the download cache was
+// obtained.
+type fetchedProvider struct {
+	request Request
+	// cached is set when the provider was found in the on-disk cache.
+	cached bool
+	// download is the registry's download metadata, for providers
+	// downloaded by this Server.
+	download *pluginApiResponse
+}
+
+// SupplyChainReport describes the providers a Server fetched, for
+// attaching to release artifacts. See Server.SupplyChainReport.
+type SupplyChainReport struct {
+	Generated time.Time          `json:"generated"`
+	Providers []SupplyChainEntry `json:"providers"`
+}
+
+// SupplyChainEntry describes one provider of a SupplyChainReport.
+type SupplyChainEntry struct {
+	Provider      string       `json:"provider"`                 // "<host>/<namespace>/<name>"
+	Registry      RegistryType `json:"registry"`                 // Registry the provider came from
+	Version       string       `json:"version"`                  // Concrete version
+	Platform      string       `json:"platform"`                 // "<os>_<arch>"
+	Protocols     []string     `json:"protocols,omitempty"`      // Plugin protocol versions the registry reports
+	DownloadURL   string       `json:"download_url,omitempty"`   // Where the archive is downloaded from
+	FileName      string       `json:"filename,omitempty"`       // Archive file name
+	ArchiveSHA256 string       `json:"archive_sha256,omitempty"` // Archive digest the registry reports
+	BinarySHA256  string       `json:"binary_sha256,omitempty"`  // Digest of the provider binary in the cache
+	Verification  []string     `json:"verification,omitempty"`   // Verification methods the archive passed
+	Cached        bool         `json:"cached"`                   // Whether the provider was already in the on-disk cache
+	Error         string       `json:"error,omitempty"`          // Why some of the above is missing
+}
+
+// Verified reports whether the archive passed a verification method.
+func (e SupplyChainEntry) Verified() bool {
+	return len(e.Verification) > 0
+}
+
+// SupplyChainReport returns the providers this Server has downloaded or
+// loaded from its on-disk cache since it was created or last cleaned up,
+// sorted by address: their versions, protocols, where they
+// came from, the digests of their archives and binaries, and the
+// verification methods they passed (see WithVerifier).
+//
+// For providers found in the on-disk cache, the download metadata is asked
+// of the registry again, or taken from stored registry responses when
+// offline. A provider whose details cannot all be gathered is reported
+// with an Error.
+func (s *Server) SupplyChainReport() *SupplyChainReport {
+	s.mu.RLock()
+	fetched := make([]fetchedProvider, 0, len(s.fetched))
+	paths := make(map[providerKey]string, len(s.fetched))
+	for key, f := range s.fetched {
+		fetched = append(fetched, f)
+		paths[key] = s.dlc[key]
+	}
+	s.mu.RUnlock()
+	slices.SortFunc(fetched, func(a, b fetchedProvider) int {
+		return strings.Compare(cacheKey(a.request).String(), cacheKey(b.request).String())
+	})
+
+	report := &SupplyChainReport{Generated: time.Now().UTC(), Providers: make([]SupplyChainEntry, len(fetched))}
+	for i, f := range fetched {
+		key := cacheKey(f.request)
+		e := SupplyChainEntry{
+			Provider: key.host + "/" + key.namespace + "/" + key.name,
+			Registry: f.request.RegistryType,
+			Version:  f.request.Version,
+			Platform: key.platform,
+			Cached:   f.cached,
+		}
+		var errs []string
+		if path := paths[key]; path != "" {
+			digest, err := hashFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("failed to hash provider binary: %v", err))
+			}
+			e.BinarySHA256 = digest
+		}
+		e.Verification = verifiedMethods(cacheProviderDir(s.cacheDir, f.request))
+
+		download := f.download
+		if download == nil {
+			r, err := s.fetchDownloadMetadata(f.request, s.logger(logComponentRegistry).With(s.requestLogAttrs(f.request)...))
+			if err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				download = &r
+			}
+		}
+		if download != nil {
+			e.Protocols = download.Protocols
+			e.DownloadURL = download.DownloadURL
+			e.FileName = download.FileName
+			e.ArchiveSHA256 = strings.ToLower(download.SHASum)
+		}
+		e.Error = strings.Join(errs, "; ")
+		report.Providers[i] = e
+	}
+	return report
+}
+
+// Markdown renders r as a document with a table of the providers and a
+// list of the problems met while gathering their details.
+func (r *SupplyChainReport) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# Provider supply chain report\n\n")
+	fmt.Fprintf(&sb, "Generated %s.\n", r.Generated.Format(time.RFC3339))
+	if len(r.Providers) == 0 {
+		sb.WriteString("\nNo providers were fetched.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("\n| Provider | Version | Platform | Protocols | Archive SHA-256 | Binary SHA-256 | Verification | Source |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, e := range r.Providers {
+		verification := "unverified"
+		if e.Verified() {
+			verification = strings.Join(e.Verification, ", ")
+		}
+		source := markdownCell(e.DownloadURL)
+		if e.Cached {
+			source += " (cached)"
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(e.Provider), markdownCell(e.Version), markdownCell(e.Platform),
+			markdownCell(strings.Join(e.Protocols, ", ")), markdownCode(e.ArchiveSHA256), markdownCode(e.BinarySHA256),
+			markdownCell(verification), strings.TrimSpace(source))
+	}
+
+	first := true
+	for _, e := range r.Providers {
+		if e.Error == "" {
+			continue
+		}
+		if first {
+			sb.WriteString("\n## Problems\n\n")
+			first = false
+		}
+		fmt.Fprintf(&sb, "- %s %s: %s\n", e.Provider, e.Version, e.Error)
+	}
+	return sb.String()
+}
+
+// markdownCell escapes s for use in a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// markdownCode formats s as inline code, or returns "" for an empty s.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
@@ -0,0 +1,60 @@
+package tfpluginschema
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_SupplyChainReport(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	archive := providerArchive(t, "null")
+	client := stubSignedRegistry(t, archive, nil, nil)
+	cacheDir := t.TempDir()
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	want := SupplyChainEntry{
+		Provider:      "registry.opentofu.org/hashicorp/null",
+		Registry:      RegistryTypeOpenTofu,
+		Version:       "1.0.0",
+		Platform:      platform,
+		DownloadURL:   "https://releases.example.com/archive.zip",
+		FileName:      providerFileNamePrefix + "null_1.0.0_" + platform + ".zip",
+		ArchiveSHA256: sha256Hex(archive),
+		BinarySHA256:  sha256Hex([]byte("binary")),
+		Verification:  []string{"internal"},
+	}
+
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client), WithVerifier("internal", func(Artifact) error { return nil }))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	assert.Empty(t, s.SupplyChainReport().Providers)
+	require.NoError(t, s.Get(req))
+
+	report := s.SupplyChainReport()
+	require.Len(t, report.Providers, 1)
+	assert.Equal(t, want, report.Providers[0])
+	md := report.Markdown()
+	assert.Contains(t, md, "# Provider supply chain report\n")
+	assert.Contains(t, md, "| registry.opentofu.org/hashicorp/null | 1.0.0 | "+platform+" |  | `"+want.ArchiveSHA256+"` | `"+want.BinarySHA256+"` | internal | https://releases.example.com/archive.zip |\n")
+	assert.NotContains(t, md, "## Problems")
+
+	// A provider served from the on-disk cache has its download metadata
+	// fetched again.
+	cached := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = cached.Cleanup() })
+	require.NoError(t, cached.Get(req))
+	want.Cached = true
+	assert.Equal(t, []SupplyChainEntry{want}, cached.SupplyChainReport().Providers)
+
+	offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true))
+	t.Cleanup(func() { _ = offline.Cleanup() })
+	require.NoError(t, offline.Get(req))
+	report = offline.SupplyChainReport()
+	require.Len(t, report.Providers, 1)
+	assert.Contains(t, report.Providers[0].Error, ErrOffline.Error())
+	assert.Contains(t, report.Markdown(), "## Problems\n\n- registry.opentofu.org/hashicorp/null 1.0.0: ")
+
+	require.NoError(t, s.Cleanup())
+	assert.Empty(t, s.SupplyChainReport().Providers)
+}
@@ -0,0 +1,139 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// stagingDirSuffix is appended, with a unique suffix, to the cache entry
+	// directory to name the directory a provider is extracted into before
+	// it is renamed into place.
+	stagingDirSuffix = ".partial"
+	// staleStagingAge is how old a staging directory must be before it is
+	// assumed to have been abandoned by a crashed or cancelled extraction.
+	staleStagingAge = time.Hour
+)
+
+// WithTempDir sets the directory under which the Server creates its
+// temporary working directory for downloads. It defaults to os.TempDir().
+// Choosing a root on the same filesystem as the cache directory avoids
+// copying archives between filesystems. An empty root is ignored.
+func WithTempDir(root string) ServerOption {
+	return func(s *Server) {
+		if root != "" {
+			s.tempRoot = root
+		}
+	}
+}
+
+// WithPerRequestTempDir gives every download its own subdirectory of the
+// Server's temporary directory, named after the provider, which is removed
+// as soon as the provider has been extracted into the cache. Without it,
+// all downloads share one directory that lives until Cleanup.
+func WithPerRequestTempDir(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.perRequestTempDir = enabled
+	}
+}
+
+// WithKeepOnFailure retains working files for debugging when a download,
+// extraction or schema retrieval fails: the downloaded archive, the
+// partially extracted staging directory, and the Server's temporary
+// directory, which Cleanup then leaves in place. Their locations are logged
+// at Warn level.
+func WithKeepOnFailure(enabled bool) ServerOption {
+	return func(s *Server) {
+		s.keepOnFailure = enabled
+	}
+}
+
+// requestTempDir returns the working directory for downloading key,
+// creating the Server's temporary directory under the configured root on
+// first use. The caller must hold s.mu.
+func (s *Server) requestTempDir(key Request) (string, error) {
+	if s.tmpDir == "" {
+		dir, err := os.MkdirTemp(s.tempRoot, "tfpluginschema-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		s.tmpDir = dir
+	}
+	if !s.perRequestTempDir {
+		return s.tmpDir, nil
+	}
+	dir := filepath.Join(s.tmpDir, strings.Join([]string{
+		cachePathSegment(string(key.RegistryType)),
+		cachePathSegment(key.Namespace),
+		cachePathSegment(key.Name),
+		cachePathSegment(key.Version),
+	}, "_"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	return dir, nil
+}
+
+// checkOwnedTempPath refuses to let cleanup touch anything but the
+// Server's own temporary directory, tmpDir, or entries inside it. tmpDir
+// must be a "tfpluginschema-" directory directly under the temp root, as
+// created by requestTempDir.
+func (s *Server) checkOwnedTempPath(tmpDir, path string) error {
+	root := s.tempRoot
+	if root == "" {
+		root = os.TempDir()
+	}
+	tmpDir = filepath.Clean(tmpDir)
+	if canonicalDir(filepath.Dir(tmpDir)) != canonicalDir(root) || !strings.HasPrefix(filepath.Base(tmpDir), "tfpluginschema-") {
+		return fmt.Errorf("refusing to remove %s: not a temporary directory created under %s", path, root)
+	}
+	rel, err := filepath.Rel(tmpDir, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to remove %s: outside temporary directory %s", path, tmpDir)
+	}
+	return nil
+}
+
+// retainProviderOnFailure reports, when WithKeepOnFailure is set, the
+// location of a provider whose schema could not be retrieved. The extracted
+// provider stays in the cache regardless; this makes it easy to find.
+func (s *Server) retainProviderOnFailure(reason error, providerPath string) {
+	if !s.keepOnFailure {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retainOnFailure(reason, "provider_path", providerPath, "extracted_dir", filepath.Dir(providerPath))
+}
+
+// retainOnFailure records that working files were kept for debugging, so
+// that Cleanup leaves the temporary directory in place. The caller must
+// hold s.mu.
+func (s *Server) retainOnFailure(reason error, paths ...any) {
+	s.tempRetained = true
+	s.logger(logComponentCache).Warn("Retaining working files after failure", append([]any{"error", reason, "temp_dir", s.tmpDir}, paths...)...)
+}
+
+// removeStaleStagingDirs removes staging directories of extractDir, and
+// entries moved aside while publishing, that were abandoned by crashed or
+// cancelled extractions. Recent ones may belong to an extraction still in
+// progress and are left alone. Failures are ignored; stale directories only
+// waste space, as lookups never read them.
+func (s *Server) removeStaleStagingDirs(extractDir string) {
+	matches, err := filepath.Glob(extractDir + stagingDirSuffix + "*")
+	if err != nil {
+		return
+	}
+	for _, dir := range matches {
+		info, err := os.Lstat(dir)
+		if err != nil || time.Since(info.ModTime()) < staleStagingAge {
+			continue
+		}
+		if err := os.RemoveAll(dir); err == nil {
+			s.logger(logComponentExtract).Debug("Removed stale staging directory", "path", dir)
+		}
+	}
+}
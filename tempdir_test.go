@@ -0,0 +1,261 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProviderRegistry serves registry download metadata for req and the
+// given archive bytes, returning a client routed to it.
+func stubProviderRegistry(t *testing.T, req Request, archive []byte) *http.Client {
+	t.Helper()
+	metaPath := fmt.Sprintf("/v1/providers/%s/%s/%s/download/%s/%s", req.Namespace, req.Name, req.Version, runtime.GOOS, runtime.GOARCH)
+	return stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case metaPath:
+			fmt.Fprintf(w, `{"filename":"%s_%s.zip","download_url":"https://releases.example.com/archive.zip"}`, providerFileNamePrefix+req.Name, req.Version)
+		case "/archive.zip":
+			w.Header().Set("Content-Length", fmt.Sprint(len(archive)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(archive)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// providerArchive returns a zip containing a fake provider binary.
+func providerArchive(t *testing.T, name string) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "provider.zip")
+	createZip(t, path, map[string]string{providerFileNamePrefix + name + "_v1.0.0": "binary"})
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestWithTempDir_Root(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	root := t.TempDir()
+	s := NewServer(nil,
+		WithCacheDir(t.TempDir()),
+		WithHTTPClient(stubProviderRegistry(t, req, providerArchive(t, "null"))),
+		WithTempDir(root),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	require.NoError(t, s.Get(req))
+	assert.Equal(t, root, filepath.Dir(s.tmpDir))
+	entries, err := os.ReadDir(s.tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "downloaded archive should be removed after extraction")
+
+	tmpDir := s.tmpDir
+	require.NoError(t, s.Cleanup())
+	_, err = os.Stat(tmpDir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWithPerRequestTempDir(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	s := NewServer(nil,
+		WithCacheDir(t.TempDir()),
+		WithHTTPClient(stubProviderRegistry(t, req, providerArchive(t, "null"))),
+		WithTempDir(t.TempDir()),
+		WithPerRequestTempDir(true),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	s.mu.Lock()
+	dir, err := s.requestTempDir(normalizedRequest(Request{Namespace: "HashiCorp", Name: "null", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}))
+	s.mu.Unlock()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(s.tmpDir, "opentofu_hashicorp_null_1.0.0"), dir)
+
+	require.NoError(t, s.Get(req))
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "per-request directory should be removed after a successful download")
+}
+
+func TestWithKeepOnFailure(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	cacheDir := t.TempDir()
+	s := NewServer(nil,
+		WithCacheDir(cacheDir),
+		WithHTTPClient(stubProviderRegistry(t, req, []byte("not a zip"))),
+		WithTempDir(t.TempDir()),
+		WithKeepOnFailure(true),
+	)
+
+	err := s.Get(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unzip")
+
+	entries, err := os.ReadDir(s.tmpDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "archive should be retained")
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".zip"))
+
+	staging, err := filepath.Glob(cacheProviderDir(cacheDir, normalizedRequest(Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu})) + stagingDirSuffix + "-*")
+	require.NoError(t, err)
+	assert.Len(t, staging, 1, "staging directory should be retained")
+
+	tmpDir := s.tmpDir
+	require.NoError(t, s.Cleanup())
+	_, err = os.Stat(tmpDir)
+	assert.NoError(t, err, "Cleanup must keep a temp dir holding retained files")
+	require.NoError(t, os.RemoveAll(tmpDir))
+}
+
+func TestWithoutKeepOnFailure_RemovesWorkingFiles(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	cacheDir := t.TempDir()
+	s := NewServer(nil,
+		WithCacheDir(cacheDir),
+		WithHTTPClient(stubProviderRegistry(t, req, []byte("not a zip"))),
+		WithTempDir(t.TempDir()),
+	)
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	require.Error(t, s.Get(req))
+	entries, err := os.ReadDir(s.tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	staging, err := filepath.Glob(cacheProviderDir(cacheDir, normalizedRequest(req)) + stagingDirSuffix + "*")
+	require.NoError(t, err)
+	assert.Empty(t, staging)
+}
+
+func TestServer_Cleanup_NoTempDir(t *testing.T) {
+	s := NewServer(nil)
+	require.NoError(t, s.Cleanup())
+	require.NoError(t, s.Cleanup(), "Cleanup must be idempotent")
+}
+
+func TestServer_Cleanup_RefusesForeignDir(t *testing.T) {
+	foreign := t.TempDir()
+	s := NewServer(nil, WithTempDir(t.TempDir()))
+	s.tmpDir = foreign
+
+	err := s.Cleanup()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to remove")
+	_, err = os.Stat(foreign)
+	assert.NoError(t, err)
+}
+
+func TestCheckOwnedTempPath(t *testing.T) {
+	root := t.TempDir()
+	s := NewServer(nil, WithTempDir(root))
+	tmpDir := filepath.Join(root, "tfpluginschema-123")
+
+	assert.NoError(t, s.checkOwnedTempPath(tmpDir, tmpDir))
+	assert.NoError(t, s.checkOwnedTempPath(tmpDir, filepath.Join(tmpDir, "archive.zip")))
+	assert.Error(t, s.checkOwnedTempPath(tmpDir, root))
+	assert.Error(t, s.checkOwnedTempPath(tmpDir, filepath.Join(tmpDir, "..", "other")))
+	assert.Error(t, s.checkOwnedTempPath(filepath.Join(root, "other-123"), filepath.Join(root, "other-123")))
+	assert.Error(t, s.checkOwnedTempPath(filepath.Join(root, "nested", "tfpluginschema-1"), filepath.Join(root, "nested", "tfpluginschema-1")))
+}
+
+func TestServer_CleanupRequest(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithTempDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	aws5Request := Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	aws5 := cacheKey(aws5Request)
+	aws6 := cacheKey(Request{Namespace: "hashicorp", Name: "aws", Version: "6.0.0"})
+	azurerm := cacheKey(Request{Namespace: "hashicorp", Name: "azurerm", Version: "4.0.0"})
+
+	s.mu.Lock()
+	_, err := s.requestTempDir(normalizedRequest(aws5Request))
+	s.mu.Unlock()
+	require.NoError(t, err)
+	for _, k := range []providerKey{aws5, aws6, azurerm} {
+		s.dlc[k] = "provider"
+		s.sc[k] = nil
+	}
+	s.versionsc[versionsCacheKey(VersionsRequest{Namespace: "hashicorp", Name: "aws", RegistryType: RegistryTypeOpenTofu})] = nil
+
+	files := []string{
+		"opentofu-terraform-provider-aws_5.0.0_linux_amd64.zip",
+		"opentofu-terraform-provider-aws_6.0.0_linux_amd64.zip",
+		"opentofu-terraform-provider-azurerm_4.0.0_linux_amd64.zip",
+	}
+	for _, f := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(s.tmpDir, f), nil, 0o600))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(s.tmpDir, "opentofu_hashicorp_aws_5.0.0"), 0o700))
+
+	require.NoError(t, s.CleanupRequest(Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}))
+	assert.NotContains(t, s.dlc, aws5)
+	assert.NotContains(t, s.sc, aws5)
+	assert.Contains(t, s.dlc, aws6)
+	assert.Len(t, s.versionsc, 1, "a single-version cleanup keeps the versions list")
+	assert.NoFileExists(t, filepath.Join(s.tmpDir, files[0]))
+	assert.NoDirExists(t, filepath.Join(s.tmpDir, "opentofu_hashicorp_aws_5.0.0"))
+	assert.FileExists(t, filepath.Join(s.tmpDir, files[1]))
+
+	require.NoError(t, s.CleanupRequest(Request{Namespace: "hashicorp", Name: "aws"}))
+	assert.NotContains(t, s.dlc, aws6)
+	assert.Empty(t, s.versionsc)
+	assert.NoFileExists(t, filepath.Join(s.tmpDir, files[1]))
+
+	assert.Contains(t, s.dlc, azurerm)
+	assert.FileExists(t, filepath.Join(s.tmpDir, files[2]), "other providers are untouched")
+}
+
+func TestServer_CleanupRequest_InvalidRequest(t *testing.T) {
+	s := NewServer(nil)
+	assert.Error(t, s.CleanupRequest(Request{Namespace: "../x", Name: "aws"}))
+}
+
+func TestServer_Get_ArchiveWithoutBinaryIsNotPublished(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	archive := filepath.Join(t.TempDir(), "null.zip")
+	createZip(t, archive, map[string]string{"README.md": "no binary here"})
+	blob, err := os.ReadFile(archive)
+	require.NoError(t, err)
+
+	cacheDir := t.TempDir()
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(stubProviderRegistry(t, req, blob)))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	err = s.Get(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provider file not found")
+
+	extractDir := cacheProviderDir(cacheDir, normalizedRequest(Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}))
+	_, err = os.Stat(extractDir)
+	assert.True(t, os.IsNotExist(err), "a cache entry without the binary must not be published")
+}
+
+func TestServer_RemoveStaleStagingDirs(t *testing.T) {
+	extractDir := filepath.Join(t.TempDir(), "linux_amd64")
+	stale := extractDir + stagingDirSuffix + "-1"
+	legacy := extractDir + stagingDirSuffix
+	recent := extractDir + stagingDirSuffix + "-2"
+	for _, dir := range []string{stale, legacy, recent} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+	}
+	old := time.Now().Add(-2 * staleStagingAge)
+	require.NoError(t, os.Chtimes(stale, old, old))
+	require.NoError(t, os.Chtimes(legacy, old, old))
+
+	NewServer(nil).removeStaleStagingDirs(extractDir)
+
+	for dir, want := range map[string]bool{stale: false, legacy: false, recent: true} {
+		_, err := os.Stat(dir)
+		assert.Equal(t, want, err == nil, dir)
+	}
+}
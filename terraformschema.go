@@ -0,0 +1,78 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// LoadTerraformSchemaJSON reads the output of "terraform providers schema
+// -json" (or its OpenTofu equivalent) from r and adds every provider in it
+// to the Server's schema cache, so that schemas already generated in CI are
+// served without downloading the providers again.
+//
+// The output does not record provider versions, so a loaded schema is
+// served for any concrete version of its provider; constraints are still
+// resolved against the registry. Providers are matched by namespace and
+// name whatever their registry host, since Terraform and OpenTofu record
+// different hosts for the same provider. Schemas loaded later replace
+// earlier ones. Cleanup forgets them, as does CleanupRequest for all
+// versions of a provider.
+func (s *Server) LoadTerraformSchemaJSON(r io.Reader) error {
+	var doc struct {
+		ProviderSchemas map[string]*tfjson.ProviderSchema `json:"provider_schemas"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to read providers schema JSON: %w", err)
+	}
+	if doc.ProviderSchemas == nil {
+		return errors.New("failed to read providers schema JSON: no provider_schemas")
+	}
+
+	loaded := make(map[providerKey]*lazySchema, len(doc.ProviderSchemas))
+	for source, schema := range doc.ProviderSchemas {
+		if schema == nil {
+			continue
+		}
+		key, err := loadedSchemaKey(source)
+		if err != nil {
+			return err
+		}
+		loaded[key] = newConvertedSchema(schema)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.Copy(s.loaded, loaded)
+	s.logger(logComponentCache).Debug("Loaded provider schemas", "providers", len(loaded))
+	return nil
+}
+
+// loadedSchemaKey returns the key of a loaded schema from its source
+// address, such as "registry.terraform.io/hashicorp/aws". Only namespace
+// and name are set.
+func loadedSchemaKey(source string) (providerKey, error) {
+	parts := strings.Split(source, "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return providerKey{}, fmt.Errorf("invalid provider source address %q", source)
+	}
+	return providerKey{
+		namespace: strings.ToLower(parts[len(parts)-2]),
+		name:      strings.ToLower(parts[len(parts)-1]),
+	}, nil
+}
+
+// loadedSchema returns the schema loaded by LoadTerraformSchemaJSON for
+// request's provider, if any.
+func (s *Server) loadedSchema(request Request) (*lazySchema, bool) {
+	key := providerKey{namespace: strings.ToLower(request.Namespace), name: strings.ToLower(request.Name)}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	schema, ok := s.loaded[key]
+	return schema, ok
+}
@@ -0,0 +1,58 @@
+package tfpluginschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const providersSchemaJSON = `{
+	"format_version": "1.0",
+	"provider_schemas": {
+		"registry.terraform.io/hashicorp/aws": {
+			"provider": {"version": 0, "block": {"attributes": {"region": {"type": "string", "optional": true}}}},
+			"resource_schemas": {"aws_s3_bucket": {"version": 0, "block": {"attributes": {"bucket": {"type": "string", "optional": true}}}}},
+			"data_source_schemas": {"aws_region": {"version": 0, "block": {}}}
+		},
+		"registry.opentofu.org/hashicorp/random": {
+			"resource_schemas": {"random_id": {"version": 0, "block": {}}}
+		}
+	}
+}`
+
+func TestServer_LoadTerraformSchemaJSON(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.LoadTerraformSchemaJSON(strings.NewReader(providersSchemaJSON)))
+
+	aws := Request{Namespace: "HashiCorp", Name: "aws", Version: "5.40.0"}
+	schema, err := s.GetResourceSchema(aws, "aws_s3_bucket")
+	require.NoError(t, err)
+	assert.Contains(t, schema.Block.Attributes, "bucket")
+
+	names, err := s.ListDataSources(Request{Namespace: "hashicorp", Name: "aws", Version: "4.0.0", RegistryType: RegistryTypeTerraform})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aws_region"}, names)
+
+	names, err = s.ListResources(Request{Namespace: "hashicorp", Name: "random", Version: "3.6.0"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"random_id"}, names)
+
+	_, err = s.ListResources(Request{Namespace: "hashicorp", Name: "azurerm", Version: "4.0.0"})
+	require.ErrorIs(t, err, ErrSchemaNotBundled)
+
+	require.NoError(t, s.CleanupRequest(Request{Namespace: "hashicorp", Name: "aws"}))
+	_, err = s.GetResourceSchema(aws, "aws_s3_bucket")
+	require.ErrorIs(t, err, ErrSchemaNotBundled)
+}
+
+func TestServer_LoadTerraformSchemaJSON_Invalid(t *testing.T) {
+	s := NewServer(nil, WithCacheDir(t.TempDir()))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	assert.Error(t, s.LoadTerraformSchemaJSON(strings.NewReader(`not json`)))
+	assert.Error(t, s.LoadTerraformSchemaJSON(strings.NewReader(`{"resource_schemas": {}}`)))
+	assert.Error(t, s.LoadTerraformSchemaJSON(strings.NewReader(`{"provider_schemas": {"aws": {}}}`)))
+}
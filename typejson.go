@@ -0,0 +1,80 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// AttributeTypeJSON returns the JSON type signature of a, such as
+// ["list","string"], for passing on to tools that take Terraform type
+// signatures rather than a cty.Type. It uses the encoding providers send
+// over the plugin protocol, so the result matches what the provider sent.
+// It returns nil for attributes without a type, such as nested attributes.
+func AttributeTypeJSON(a *tfjson.SchemaAttribute) (json.RawMessage, error) {
+	if a == nil || a.AttributeType == cty.NilType {
+		return nil, nil
+	}
+	buf, err := ctyjson.MarshalType(a.AttributeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attribute type: %w", err)
+	}
+	return buf, nil
+}
+
+// SchemaTypesJSON returns the JSON type signatures of every typed attribute
+// in s, including those in nested blocks and nested attributes, keyed by
+// their dot-separated path, for example "rule.priority".
+func SchemaTypesJSON(s *tfjson.Schema) (map[string]json.RawMessage, error) {
+	types := make(map[string]json.RawMessage)
+	if s == nil {
+		return types, nil
+	}
+	if err := collectBlockTypesJSON(types, "", s.Block); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+func collectBlockTypesJSON(types map[string]json.RawMessage, prefix string, b *tfjson.SchemaBlock) error {
+	if b == nil {
+		return nil
+	}
+	if err := collectAttributeTypesJSON(types, prefix, b.Attributes); err != nil {
+		return err
+	}
+	for name, nb := range b.NestedBlocks {
+		if nb == nil {
+			continue
+		}
+		if err := collectBlockTypesJSON(types, prefix+name+".", nb.Block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func collectAttributeTypesJSON(types map[string]json.RawMessage, prefix string, attrs map[string]*tfjson.SchemaAttribute) error {
+	for name, a := range attrs {
+		if a == nil {
+			continue
+		}
+		if a.AttributeNestedType != nil {
+			if err := collectAttributeTypesJSON(types, prefix+name+".", a.AttributeNestedType.Attributes); err != nil {
+				return err
+			}
+			continue
+		}
+		buf, err := AttributeTypeJSON(a)
+		if err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, name, err)
+		}
+		if buf != nil {
+			types[prefix+name] = buf
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package tfpluginschema
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestAttributeTypeJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  cty.Type
+		want string
+	}{
+		{name: "primitive", typ: cty.String, want: `"string"`},
+		{name: "collection", typ: cty.List(cty.Number), want: `["list","number"]`},
+		{name: "object", typ: cty.Object(map[string]cty.Type{"a": cty.Bool}), want: `["object",{"a":"bool"}]`},
+		{name: "optional attributes", typ: cty.ObjectWithOptionalAttrs(map[string]cty.Type{"a": cty.Bool, "b": cty.String}, []string{"b"}), want: `["object",{"a":"bool","b":"string"},["b"]]`},
+		{name: "dynamic", typ: cty.DynamicPseudoType, want: `"dynamic"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AttributeTypeJSON(&tfjson.SchemaAttribute{AttributeType: tt.typ})
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+
+	got, err := AttributeTypeJSON(&tfjson.SchemaAttribute{AttributeNestedType: &tfjson.SchemaNestedAttributeType{}})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	got, err = AttributeTypeJSON(nil)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestSchemaTypesJSON(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(objectTypedSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	schema, err := s.GetResourceSchema(req, "example_thing")
+	require.NoError(t, err)
+	types, err := SchemaTypesJSON(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"string"`, string(types["name"]))
+	assert.JSONEq(t, `["object",{"size":"number","tags":["map","string"]},["tags"]]`, string(types["settings"]))
+	assert.JSONEq(t, `["map",["object",{"value":"string"}]]`, string(types["inner.labels"]))
+
+	// With nested attributes, the paths descend into them.
+	nested, err := s.GetResourceSchema(req, "example_thing", WithNestedObjectTypes())
+	require.NoError(t, err)
+	types, err = SchemaTypesJSON(nested)
+	require.NoError(t, err)
+	assert.NotContains(t, types, "settings")
+	assert.JSONEq(t, `"number"`, string(types["settings.size"]))
+	assert.JSONEq(t, `"string"`, string(types["rules.target.host"]))
+
+	// The signatures decode back into the same types.
+	var decoded cty.Type
+	require.NoError(t, json.Unmarshal(types["settings.tags"], &decoded))
+	assert.Equal(t, cty.Map(cty.String), decoded)
+
+	empty, err := SchemaTypesJSON(nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
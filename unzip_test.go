@@ -12,7 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func createZip(t *testing.T, path string, entries map[string]string) {
+func createZip(t testing.TB, path string, entries map[string]string) {
 	t.Helper()
 	f, err := os.Create(path)
 	require.NoError(t, err)
@@ -0,0 +1,167 @@
+// Package tfpluginschema is version 2 of the tfpluginschema API. Every call
+// that may reach the registry or run a provider takes a context first, the
+// per-kind Get*Schema and List* methods are merged into Schema and Names,
+// and failures to find a provider or a schema are typed errors carrying the
+// request.
+//
+// The context is checked before a call starts: a call made with a done
+// context returns its error without doing any work. A call in progress is
+// not cancelled yet, because the version 1 engine takes no context; only
+// its HTTP requests are bounded, by the WithTimeout option of version 1.
+//
+// A Client wraps a version 1 Server, which does the work and which Server
+// returns for anything v2 does not cover yet. Version 1 stays supported;
+// the tfpluginschema-migrate command rewrites version 1 call sites:
+//
+//	go run github.com/matt-FFFFFF/tfpluginschema/v2/cmd/tfpluginschema-migrate -w ./...
+package tfpluginschema
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	goversion "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+
+	v1 "github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// Request, VersionsRequest, Kind and SchemaOption are those of version 1,
+// so that values can be passed between the two APIs while callers migrate.
+type (
+	Request         = v1.Request
+	VersionsRequest = v1.VersionsRequest
+	Kind            = v1.Kind
+	SchemaOption    = v1.SchemaOption
+)
+
+// The kinds of schema entry; see the Kind constants of version 1.
+const (
+	KindResource          = v1.KindResource
+	KindDataSource        = v1.KindDataSource
+	KindEphemeralResource = v1.KindEphemeralResource
+	KindFunction          = v1.KindFunction
+	KindProviderConfig    = v1.KindProviderConfig
+)
+
+// Client looks up provider schemas. It is safe for concurrent use.
+type Client struct {
+	s *v1.Server
+}
+
+// Option configures a Client.
+type Option func(*config)
+
+type config struct {
+	logger        *slog.Logger
+	serverOptions []v1.ServerOption
+}
+
+// WithLogger sets the logger of the Client. By default only errors are
+// logged, and they are discarded.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithServerOptions applies options of version 1, such as WithCacheDir or
+// WithOffline, to the Server the Client wraps.
+func WithServerOptions(opts ...v1.ServerOption) Option {
+	return func(c *config) {
+		c.serverOptions = append(c.serverOptions, opts...)
+	}
+}
+
+// New returns a Client configured by opts.
+func New(opts ...Option) *Client {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &Client{s: v1.NewServer(c.logger, c.serverOptions...)}
+}
+
+// Server returns the version 1 Server the Client wraps, for the methods
+// that have no version 2 form yet.
+func (c *Client) Server() *v1.Server {
+	return c.s
+}
+
+// Close removes the Client's temporary files and clears its in-memory
+// caches. Calls in progress should have returned first.
+func (c *Client) Close() error {
+	return c.s.Cleanup()
+}
+
+// Download downloads and extracts the provider of req, if it is not cached
+// already.
+func (c *Client) Download(ctx context.Context, req Request) error {
+	_, err := run(ctx, func() (struct{}, error) {
+		return struct{}{}, c.s.Get(req)
+	})
+	return providerError(req, err)
+}
+
+// Versions returns the versions of the provider listed by the registry, or
+// by the schema sources, in ascending order.
+func (c *Client) Versions(ctx context.Context, req VersionsRequest) (goversion.Collection, error) {
+	versions, err := run(ctx, func() (goversion.Collection, error) {
+		return c.s.GetAvailableVersions(req)
+	})
+	return versions, providerError(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType}, err)
+}
+
+// Schema returns the schema of the entry of kind and name, which must not
+// be KindFunction; see Function. The name is ignored for
+// KindProviderConfig.
+func (c *Client) Schema(ctx context.Context, req Request, kind Kind, name string, opts ...SchemaOption) (*tfjson.Schema, error) {
+	if kind == KindFunction {
+		return nil, errors.New("functions have no block schema; use Function")
+	}
+	entry, err := run(ctx, func() (*v1.SchemaEntry, error) {
+		return c.s.GetSchemaByKind(req, kind, name, opts...)
+	})
+	if err != nil {
+		return nil, schemaError(req, kind, name, err)
+	}
+	return entry.Schema, nil
+}
+
+// ProviderSchema returns the schema of the provider configuration block.
+func (c *Client) ProviderSchema(ctx context.Context, req Request, opts ...SchemaOption) (*tfjson.Schema, error) {
+	return c.Schema(ctx, req, KindProviderConfig, "", opts...)
+}
+
+// Function returns the signature of the provider-defined function name.
+func (c *Client) Function(ctx context.Context, req Request, name string, opts ...SchemaOption) (*tfjson.FunctionSignature, error) {
+	entry, err := run(ctx, func() (*v1.SchemaEntry, error) {
+		return c.s.GetSchemaByKind(req, KindFunction, name, opts...)
+	})
+	if err != nil {
+		return nil, schemaError(req, KindFunction, name, err)
+	}
+	return entry.Function, nil
+}
+
+// Names returns the sorted names of the entries of kind that the provider
+// declares.
+func (c *Client) Names(ctx context.Context, req Request, kind Kind) ([]string, error) {
+	names, err := run(ctx, func() ([]string, error) {
+		return c.s.List(req, kind, v1.ListOptions{})
+	})
+	return names, providerError(req, err)
+}
+
+// run calls f unless ctx is done already, in which case it returns
+// ctx.Err(). The version 1 Server takes no context, so a call that has
+// started runs to completion; run does not leave work going in the
+// background.
+func run[T any](ctx context.Context, f func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	return f()
+}
@@ -0,0 +1,117 @@
+package tfpluginschema
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/matt-FFFFFF/tfpluginschema"
+)
+
+const exampleSchema = `{
+	"provider": {"version": 0, "block": {"attributes": {"region": {"type": "string", "optional": true}}}},
+	"resource_schemas": {"example_thing": {"version": 0, "block": {"attributes": {"name": {"type": "string", "required": true}}}}},
+	"data_source_schemas": {"example_info": {"version": 0, "block": {}}},
+	"functions": {"parse": {"return_type": "string", "parameters": [{"name": "in", "type": "string"}]}}
+}`
+
+// roundTripFunc lets a function stand in for the registry.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// registryClient returns an HTTP client answering every request with status
+// and body.
+func registryClient(status int, body string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}, Request: r}, nil
+	})}
+}
+
+// newBundleClient returns a Client serving exampleSchema from a schema
+// bundle, as example/example 1.0.0.
+func newBundleClient(t *testing.T) *Client {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(exampleSchema)}}
+	c := New(WithServerOptions(
+		v1.WithCacheDir(t.TempDir()),
+		v1.WithHTTPClient(registryClient(http.StatusInternalServerError, "")),
+		v1.WithSchemaBundle(bundle),
+		v1.WithPluginExec(false),
+	))
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestClient_Schema(t *testing.T) {
+	c := newBundleClient(t)
+	ctx := context.Background()
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	schema, err := c.Schema(ctx, req, KindResource, "example_thing")
+	require.NoError(t, err)
+	assert.True(t, schema.Block.Attributes["name"].Required)
+
+	_, err = c.Schema(ctx, req, KindDataSource, "example_info")
+	require.NoError(t, err)
+
+	provider, err := c.ProviderSchema(ctx, req)
+	require.NoError(t, err)
+	assert.Contains(t, provider.Block.Attributes, "region")
+
+	f, err := c.Function(ctx, req, "parse")
+	require.NoError(t, err)
+	require.Len(t, f.Parameters, 1)
+	assert.Equal(t, "in", f.Parameters[0].Name)
+
+	_, err = c.Schema(ctx, req, KindFunction, "parse")
+	assert.Error(t, err, "functions are looked up with Function")
+
+	names, err := c.Names(ctx, req, KindResource)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_thing"}, names)
+
+	assert.Same(t, c.s, c.Server())
+}
+
+func TestClient_Versions(t *testing.T) {
+	c := New(WithServerOptions(
+		v1.WithCacheDir(t.TempDir()),
+		v1.WithHTTPClient(registryClient(http.StatusOK, `{"versions":[{"version":"1.1.0"},{"version":"1.0.0"}]}`)),
+	))
+	t.Cleanup(func() { _ = c.Close() })
+
+	versions, err := c.Versions(context.Background(), VersionsRequest{Namespace: "hashicorp", Name: "aws"})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "1.1.0", versions[1].String())
+}
+
+func TestClient_Context(t *testing.T) {
+	var calls int
+	c := New(WithServerOptions(
+		v1.WithCacheDir(t.TempDir()),
+		v1.WithHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+			return registryClient(http.StatusOK, `{"versions":[{"version":"1.0.0"}]}`).Transport.RoundTrip(r)
+		})}),
+	))
+	t.Cleanup(func() { _ = c.Close() })
+	req := VersionsRequest{Namespace: "hashicorp", Name: "aws"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.Versions(ctx, req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls, "nothing is started once ctx is done")
+
+	versions, err := c.Versions(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+	assert.Equal(t, 1, calls, "the call has finished when it returns")
+}
@@ -0,0 +1,135 @@
+// Command tfpluginschema-migrate rewrites Go code written against version 1
+// of tfpluginschema to use the Client of version 2. It replaces NewServer
+// with New, *Server with *Client, and the calls that have a Client form:
+//
+//	s.GetResourceSchema(req, "azurerm_resource_group")
+//
+// becomes
+//
+//	s.Schema(ctx, req, tfpluginschemav2.KindResource, "azurerm_resource_group")
+//
+// with ctx the context.Context parameter of the enclosing function, or
+// context.TODO() where there is none. Other Server methods are called on
+// the Server the Client wraps. Options of version 1 are kept, wrapped in
+// WithServerOptions.
+//
+// Usage:
+//
+//	tfpluginschema-migrate [-w] PATH...
+//
+// Each PATH is a Go file or a directory; a directory ending in "/..." is
+// walked recursively. Without -w, the rewritten files are printed. Every
+// rewrite, and every call left for a person to finish, is reported on
+// standard error as "file:line:column: message", which makes up the
+// migration guide for the code.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write the rewritten files in place instead of printing them")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: tfpluginschema-migrate [-w] PATH...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Args(), *write, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run migrates the Go files under paths, writing them in place if write is
+// set and otherwise printing them to stdout, and reports to stderr.
+func run(paths []string, write bool, stdout, stderr io.Writer) error {
+	files, err := goFiles(paths)
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out, notes, err := migrateFile(path, src)
+		if err != nil {
+			return err
+		}
+		for _, n := range notes {
+			fmt.Fprintln(stderr, n)
+		}
+		if out == nil || bytes.Equal(out, src) {
+			continue
+		}
+		if !write {
+			fmt.Fprintf(stdout, "// %s\n%s", path, out)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goFiles expands paths into the Go files to migrate. Directories are read
+// without their subdirectories unless the path ends in "/...", and then
+// vendor, testdata and hidden directories are skipped.
+func goFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		dir, recursive := strings.CutSuffix(filepath.ToSlash(path), "/...")
+		dir = filepath.FromSlash(dir)
+		if dir == "" {
+			dir = "."
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, dir)
+			continue
+		}
+		err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p == dir {
+					return nil
+				}
+				name := d.Name()
+				if !recursive || name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(p, ".go") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src := "package example\n\nimport \"github.com/matt-FFFFFF/tfpluginschema\"\n\nfunc f(s *tfpluginschema.Server) { s.Cleanup() }\n"
+	for _, name := range []string{"a.go", "sub/b.go", "testdata/c.go", "sub/notes.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	}
+
+	files, err := goFiles([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.go")}, files, "a directory is read without its subdirectories")
+	files, err = goFiles([]string{dir + "/..."})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "b.go")}, files, "testdata is skipped")
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, run([]string{filepath.Join(dir, "a.go")}, false, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "func f(s *tfpluginschemav2.Client) { s.Close() }")
+	assert.Contains(t, stderr.String(), "Cleanup: replaced with Close")
+	data, err := os.ReadFile(filepath.Join(dir, "a.go"))
+	require.NoError(t, err)
+	assert.Equal(t, src, string(data), "files are only printed without -w")
+
+	stdout.Reset()
+	require.NoError(t, run([]string{dir + "/..."}, true, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+	for _, name := range []string{"a.go", "sub/b.go"} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "s.Close()", name)
+	}
+
+	assert.Error(t, run([]string{filepath.Join(dir, "missing")}, false, &stdout, &stderr))
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+)
+
+const (
+	v1Path = "github.com/matt-FFFFFF/tfpluginschema"
+	v2Path = "github.com/matt-FFFFFF/tfpluginschema/v2"
+	// v2Name is the name the version 2 package is imported as, so that it
+	// can sit next to version 1 while the options of version 1 are still
+	// used.
+	v2Name = "tfpluginschemav2"
+)
+
+// methodRewrite describes how a call of a method of the version 1 Server is
+// written against a version 2 Client.
+type methodRewrite struct {
+	// name is the Client method.
+	name string
+	// kind, if set, is the Kind constant inserted after the request.
+	kind string
+}
+
+// methodRewrites are the Server methods with a Client form. Every one of
+// them gains a context as its first argument. Other methods are called on
+// the Server the Client wraps.
+var methodRewrites = map[string]methodRewrite{
+	"Get":                        {name: "Download"},
+	"GetAvailableVersions":       {name: "Versions"},
+	"GetResourceSchema":          {name: "Schema", kind: "KindResource"},
+	"GetDataSourceSchema":        {name: "Schema", kind: "KindDataSource"},
+	"GetEphemeralResourceSchema": {name: "Schema", kind: "KindEphemeralResource"},
+	"GetProviderSchema":          {name: "ProviderSchema"},
+	"GetFunctionSchema":          {name: "Function"},
+	"ListResources":              {name: "Names", kind: "KindResource"},
+	"ListDataSources":            {name: "Names", kind: "KindDataSource"},
+	"ListEphemeralResources":     {name: "Names", kind: "KindEphemeralResource"},
+	"ListFunctions":              {name: "Names", kind: "KindFunction"},
+}
+
+// note is a line of the migration report.
+type note struct {
+	pos token.Position
+	msg string
+}
+
+func (n note) String() string {
+	return fmt.Sprintf("%s: %s", n.pos, n.msg)
+}
+
+// migrateFile rewrites the calls to version 1 in src, the contents of
+// filename, for version 2. It returns the new contents, or nil if nothing
+// changed, and notes for the migration report: one per rewrite, and one
+// per call left for a person to finish.
+//
+// Without type information, Servers are recognised by name: the variables,
+// parameters and struct fields declared as *Server or assigned the result
+// of NewServer anywhere in the file.
+func migrateFile(filename string, src []byte) ([]byte, []note, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &migration{fset: fset, file: f}
+	if !m.findImport() {
+		return nil, m.notes, nil
+	}
+	m.collectServers()
+	m.rewrite()
+	if !m.changed {
+		return nil, m.notes, nil
+	}
+	m.fixImports()
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, nil, err
+	}
+	// Reformatting sorts the added imports into place.
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, m.notes, nil
+}
+
+// migration holds the state of rewriting one file.
+type migration struct {
+	fset *token.FileSet
+	file *ast.File
+	// v1 is the name version 1 is imported as.
+	v1 string
+	// servers are the names of the variables and fields holding Servers.
+	servers map[string]bool
+	// needContext is set when a context.TODO() was added.
+	needContext bool
+	changed     bool
+	notes       []note
+}
+
+func (m *migration) notef(pos token.Pos, format string, args ...any) {
+	m.notes = append(m.notes, note{m.fset.Position(pos), fmt.Sprintf(format, args...)})
+}
+
+// findImport reports whether the file imports version 1, and records its
+// name.
+func (m *migration) findImport() bool {
+	for _, spec := range m.file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path != v1Path {
+			continue
+		}
+		m.v1 = "tfpluginschema"
+		if spec.Name != nil {
+			if spec.Name.Name == "." || spec.Name.Name == "_" {
+				m.notef(spec.Pos(), "%s is imported as %q; migrate this file by hand", v1Path, spec.Name.Name)
+				return false
+			}
+			m.v1 = spec.Name.Name
+		}
+		return true
+	}
+	return false
+}
+
+// isV1 reports whether e is the selector name.X of version 1.
+func (m *migration) isV1(e ast.Expr, name string) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == m.v1
+}
+
+// isServerType reports whether e is *Server of version 1.
+func (m *migration) isServerType(e ast.Expr) bool {
+	star, ok := e.(*ast.StarExpr)
+	return ok && m.isV1(star.X, "Server")
+}
+
+// isNewServer reports whether e calls NewServer of version 1.
+func (m *migration) isNewServer(e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	return ok && m.isV1(call.Fun, "NewServer")
+}
+
+// collectServers records the names declared as, or assigned, a Server.
+func (m *migration) collectServers() {
+	m.servers = make(map[string]bool)
+	addNames := func(names []*ast.Ident) {
+		for _, id := range names {
+			if id.Name != "_" {
+				m.servers[id.Name] = true
+			}
+		}
+	}
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			if m.isServerType(n.Type) {
+				addNames(n.Names)
+			}
+		case *ast.ValueSpec:
+			if n.Type != nil && m.isServerType(n.Type) {
+				addNames(n.Names)
+			}
+			for i, v := range n.Values {
+				if i < len(n.Names) && m.isNewServer(v) {
+					addNames(n.Names[i : i+1])
+				}
+			}
+		case *ast.AssignStmt:
+			for i, rhs := range n.Rhs {
+				if i >= len(n.Lhs) || !m.isNewServer(rhs) {
+					continue
+				}
+				switch lhs := n.Lhs[i].(type) {
+				case *ast.Ident:
+					addNames([]*ast.Ident{lhs})
+				case *ast.SelectorExpr:
+					addNames([]*ast.Ident{lhs.Sel})
+				}
+			}
+		case *ast.KeyValueExpr:
+			if key, ok := n.Key.(*ast.Ident); ok && m.isNewServer(n.Value) {
+				addNames([]*ast.Ident{key})
+			}
+		}
+		return true
+	})
+}
+
+// isServer reports whether e names a Server.
+func (m *migration) isServer(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return m.servers[e.Name]
+	case *ast.SelectorExpr:
+		return m.servers[e.Sel.Name]
+	}
+	return false
+}
+
+// v2Sel returns the selector name of version 2.
+func v2Sel(name string) *ast.SelectorExpr {
+	return &ast.SelectorExpr{X: ast.NewIdent(v2Name), Sel: ast.NewIdent(name)}
+}
+
+// rewrite rewrites the Server types, NewServer calls and method calls.
+func (m *migration) rewrite() {
+	var stack []ast.Node
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+		switch n := n.(type) {
+		case *ast.StarExpr:
+			if m.isServerType(n) {
+				n.X = v2Sel("Client")
+				m.changed = true
+			}
+		case *ast.CallExpr:
+			if m.isNewServer(n) {
+				m.rewriteNewServer(n)
+			} else if sel, ok := n.Fun.(*ast.SelectorExpr); ok && m.isServer(sel.X) && sel.Sel.Name != "Server" {
+				// Server is the Client method that rewritten calls go
+				// through, and not one of version 1.
+				m.rewriteMethod(n, sel, stack)
+			}
+		}
+		return true
+	})
+}
+
+// rewriteNewServer turns NewServer(l, opts...) into
+// New(WithLogger(l), WithServerOptions(opts...)).
+func (m *migration) rewriteNewServer(call *ast.CallExpr) {
+	var args []ast.Expr
+	if len(call.Args) > 0 {
+		if id, ok := call.Args[0].(*ast.Ident); !ok || id.Name != "nil" {
+			args = append(args, &ast.CallExpr{Fun: v2Sel("WithLogger"), Args: call.Args[:1]})
+		}
+	}
+	if len(call.Args) > 1 {
+		opts := &ast.CallExpr{Fun: v2Sel("WithServerOptions"), Args: call.Args[1:]}
+		if call.Ellipsis.IsValid() {
+			opts.Ellipsis = call.Ellipsis
+		}
+		args = append(args, opts)
+	}
+	m.notef(call.Pos(), "NewServer: replaced with %s.New", v2Name)
+	call.Fun = v2Sel("New")
+	call.Args = args
+	call.Ellipsis = token.NoPos
+	m.changed = true
+}
+
+// rewriteMethod rewrites the call of a Server method, inside the nodes of
+// stack.
+func (m *migration) rewriteMethod(call *ast.CallExpr, sel *ast.SelectorExpr, stack []ast.Node) {
+	method, pos := sel.Sel.Name, call.Pos()
+	switch r, ok := methodRewrites[method]; {
+	case method == "Cleanup":
+		sel.Sel = ast.NewIdent("Close")
+		m.notef(pos, "Cleanup: replaced with Close")
+	case ok:
+		args := []ast.Expr{m.contextFor(call, stack)}
+		if len(call.Args) > 0 {
+			args = append(args, call.Args[0])
+			if r.kind != "" {
+				args = append(args, v2Sel(r.kind))
+			}
+			args = append(args, call.Args[1:]...)
+		}
+		call.Args = args
+		sel.Sel = ast.NewIdent(r.name)
+		m.notef(pos, "%s: replaced with %s", method, r.name)
+	default:
+		sel.X = &ast.CallExpr{Fun: &ast.SelectorExpr{X: sel.X, Sel: ast.NewIdent("Server")}}
+		m.notef(pos, "%s: has no version 2 form yet; called on the version 1 Server", method)
+	}
+	m.changed = true
+}
+
+// contextFor returns the context to pass to call: the context.Context
+// parameter of the innermost enclosing function that has one, or else
+// context.TODO().
+func (m *migration) contextFor(call *ast.CallExpr, stack []ast.Node) ast.Expr {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var ft *ast.FuncType
+		switch fn := stack[i].(type) {
+		case *ast.FuncDecl:
+			ft = fn.Type
+		case *ast.FuncLit:
+			ft = fn.Type
+		default:
+			continue
+		}
+		for _, field := range ft.Params.List {
+			sel, ok := field.Type.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Context" {
+				continue
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "context" {
+				continue
+			}
+			for _, name := range field.Names {
+				if name.Name != "_" {
+					return ast.NewIdent(name.Name)
+				}
+			}
+		}
+	}
+	m.needContext = true
+	m.notef(call.Pos(), "no context in scope; passing context.TODO()")
+	return &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent("context"), Sel: ast.NewIdent("TODO")}}
+}
+
+// fixImports adds the imports of version 2 and, if needed, context, and
+// drops version 1 if nothing refers to it any more.
+func (m *migration) fixImports() {
+	used := false
+	ast.Inspect(m.file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == m.v1 {
+				used = true
+			}
+		}
+		return !used
+	})
+
+	var decl *ast.GenDecl
+	for _, d := range m.file.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			for i, spec := range gd.Specs {
+				is := spec.(*ast.ImportSpec)
+				if path, _ := strconv.Unquote(is.Path.Value); path == v1Path {
+					decl = gd
+					if !decl.Lparen.IsValid() {
+						// Parenthesise a single import so that more can
+						// be added.
+						decl.Lparen, decl.Rparen = is.Pos(), is.End()
+					}
+					if !used {
+						gd.Specs = append(gd.Specs[:i], gd.Specs[i+1:]...)
+					}
+					break
+				}
+			}
+		}
+	}
+	pos := decl.Rparen - 1
+	decl.Specs = append(decl.Specs, &ast.ImportSpec{
+		Name: ast.NewIdent(v2Name),
+		Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(v2Path), ValuePos: pos},
+	})
+	if m.needContext && !m.importsContext() {
+		decl.Specs = append(decl.Specs, &ast.ImportSpec{
+			Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("context"), ValuePos: pos},
+		})
+	}
+}
+
+// importsContext reports whether the file imports the context package.
+func (m *migration) importsContext() bool {
+	for _, spec := range m.file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == "context" && spec.Name == nil {
+			return true
+		}
+	}
+	return false
+}
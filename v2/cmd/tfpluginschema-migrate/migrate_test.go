@@ -0,0 +1,203 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		want  string
+		notes []string
+	}{
+		{
+			name: "calls with and without a context",
+			src: `package example
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+type app struct {
+	server *tfpluginschema.Server
+}
+
+func newApp(l *slog.Logger, dir string) *app {
+	return &app{server: tfpluginschema.NewServer(l, tfpluginschema.WithCacheDir(dir))}
+}
+
+func (a *app) resource(ctx context.Context, req tfpluginschema.Request) error {
+	_, err := a.server.GetResourceSchema(req, "example_thing", tfpluginschema.WithoutDescriptions())
+	return err
+}
+
+func main() {
+	s := tfpluginschema.NewServer(nil)
+	defer s.Cleanup()
+	req := tfpluginschema.Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	if err := s.Get(req); err != nil {
+		panic(err)
+	}
+	names, _ := s.ListDataSources(req)
+	_, _ = s.Warm([]tfpluginschema.Request{req})
+	_ = names
+}
+`,
+			want: `package example
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+	tfpluginschemav2 "github.com/matt-FFFFFF/tfpluginschema/v2"
+)
+
+type app struct {
+	server *tfpluginschemav2.Client
+}
+
+func newApp(l *slog.Logger, dir string) *app {
+	return &app{server: tfpluginschemav2.New(tfpluginschemav2.WithLogger(l), tfpluginschemav2.WithServerOptions(tfpluginschema.WithCacheDir(dir)))}
+}
+
+func (a *app) resource(ctx context.Context, req tfpluginschema.Request) error {
+	_, err := a.server.Schema(ctx, req, tfpluginschemav2.KindResource, "example_thing", tfpluginschema.WithoutDescriptions())
+	return err
+}
+
+func main() {
+	s := tfpluginschemav2.New()
+	defer s.Close()
+	req := tfpluginschema.Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	if err := s.Download(context.TODO(), req); err != nil {
+		panic(err)
+	}
+	names, _ := s.Names(context.TODO(), req, tfpluginschemav2.KindDataSource)
+	_, _ = s.Server().Warm([]tfpluginschema.Request{req})
+	_ = names
+}
+`,
+			notes: []string{
+				"a.go:15:22: NewServer: replaced with tfpluginschemav2.New",
+				"a.go:19:12: GetResourceSchema: replaced with Schema",
+				"a.go:24:7: NewServer: replaced with tfpluginschemav2.New",
+				"a.go:25:8: Cleanup: replaced with Close",
+				"a.go:27:12: no context in scope; passing context.TODO()",
+				"a.go:27:12: Get: replaced with Download",
+				"a.go:30:14: no context in scope; passing context.TODO()",
+				"a.go:30:14: ListDataSources: replaced with Names",
+				"a.go:31:9: Warm: has no version 2 form yet; called on the version 1 Server",
+			},
+		},
+		{
+			name: "renamed import that is no longer needed",
+			src: `package example
+
+import tps "github.com/matt-FFFFFF/tfpluginschema"
+
+func providerSchema(s *tps.Server, versions func() error) {
+	s.GetProviderSchema(s.Request)
+	s.GetFunctionSchema(s.Request, "parse")
+}
+`,
+			want: `package example
+
+import (
+	"context"
+	tfpluginschemav2 "github.com/matt-FFFFFF/tfpluginschema/v2"
+)
+
+func providerSchema(s *tfpluginschemav2.Client, versions func() error) {
+	s.ProviderSchema(context.TODO(), s.Request)
+	s.Function(context.TODO(), s.Request, "parse")
+}
+`,
+			notes: []string{
+				"a.go:6:2: no context in scope; passing context.TODO()",
+				"a.go:6:2: GetProviderSchema: replaced with ProviderSchema",
+				"a.go:7:2: no context in scope; passing context.TODO()",
+				"a.go:7:2: GetFunctionSchema: replaced with Function",
+			},
+		},
+		{
+			name: "context from a function literal and other receivers left alone",
+			src: `package example
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+)
+
+var server = tfpluginschema.NewServer(nil, opts...)
+
+func handler(c *http.Client) func(context.Context, tfpluginschema.VersionsRequest) {
+	return func(ctx context.Context, vr tfpluginschema.VersionsRequest) {
+		server.GetAvailableVersions(vr)
+		c.Get("https://example.com")
+	}
+}
+`,
+			want: `package example
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/matt-FFFFFF/tfpluginschema"
+	tfpluginschemav2 "github.com/matt-FFFFFF/tfpluginschema/v2"
+)
+
+var server = tfpluginschemav2.New(tfpluginschemav2.WithServerOptions(opts...))
+
+func handler(c *http.Client) func(context.Context, tfpluginschema.VersionsRequest) {
+	return func(ctx context.Context, vr tfpluginschema.VersionsRequest) {
+		server.Versions(ctx, vr)
+		c.Get("https://example.com")
+	}
+}
+`,
+			notes: []string{
+				"a.go:10:14: NewServer: replaced with tfpluginschemav2.New",
+				"a.go:14:3: GetAvailableVersions: replaced with Versions",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes, err := migrateFile("a.go", []byte(tt.src))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+			var lines []string
+			for _, n := range notes {
+				lines = append(lines, n.String())
+			}
+			assert.Equal(t, tt.notes, lines)
+		})
+	}
+}
+
+func TestMigrateFile_Unchanged(t *testing.T) {
+	got, notes, err := migrateFile("a.go", []byte("package example\n\nimport \"fmt\"\n\nfunc f() { fmt.Println() }\n"))
+	require.NoError(t, err)
+	assert.Nil(t, got, "files not importing version 1 are left alone")
+	assert.Empty(t, notes)
+
+	got, notes, err = migrateFile("a.go", []byte("package example\n\nimport . \"github.com/matt-FFFFFF/tfpluginschema\"\n\nvar s = NewServer(nil)\n"))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	require.Len(t, notes, 1)
+	assert.Contains(t, notes[0].String(), "migrate this file by hand")
+
+	_, _, err = migrateFile("a.go", []byte("package"))
+	assert.Error(t, err)
+}
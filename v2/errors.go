@@ -0,0 +1,60 @@
+package tfpluginschema
+
+import (
+	"errors"
+
+	v1 "github.com/matt-FFFFFF/tfpluginschema"
+)
+
+// The sentinels of version 1 that the typed errors below match with
+// errors.Is.
+var (
+	ErrPluginNotFound = v1.ErrPluginNotFound
+	ErrSchemaNotFound = v1.ErrSchemaNotFound
+)
+
+// ProviderNotFoundError reports that the registry does not know a provider.
+// It matches ErrPluginNotFound.
+type ProviderNotFoundError struct {
+	// Provider is the request that was looked up.
+	Provider Request
+	// Err is the error of the version 1 Server.
+	Err error
+}
+
+func (e *ProviderNotFoundError) Error() string { return e.Err.Error() }
+
+func (e *ProviderNotFoundError) Unwrap() error { return e.Err }
+
+// SchemaNotFoundError reports that a provider has no entry of a kind with a
+// name. It matches ErrSchemaNotFound.
+type SchemaNotFoundError struct {
+	// Provider is the request that was looked up.
+	Provider Request
+	Kind     Kind
+	Name     string
+	// Err is the error of the version 1 Server.
+	Err error
+}
+
+func (e *SchemaNotFoundError) Error() string { return e.Err.Error() }
+
+func (e *SchemaNotFoundError) Unwrap() error { return e.Err }
+
+// providerError returns err as a ProviderNotFoundError for req if it is
+// one, and otherwise unchanged.
+func providerError(req Request, err error) error {
+	if errors.Is(err, v1.ErrPluginNotFound) {
+		return &ProviderNotFoundError{Provider: req, Err: err}
+	}
+	return err
+}
+
+// schemaError returns err as a SchemaNotFoundError for the entry of kind
+// and name of req if it is one, and otherwise as providerError does.
+func schemaError(req Request, kind Kind, name string, err error) error {
+	if errors.Is(err, v1.ErrSchemaNotFound) {
+		return &SchemaNotFoundError{Provider: req, Kind: kind, Name: name, Err: err}
+	}
+	return providerError(req, err)
+}
@@ -0,0 +1,53 @@
+package tfpluginschema
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/matt-FFFFFF/tfpluginschema"
+)
+
+func TestSchemaNotFoundError(t *testing.T) {
+	c := newBundleClient(t)
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	_, err := c.Schema(context.Background(), req, KindResource, "example_missing")
+	require.ErrorIs(t, err, ErrSchemaNotFound)
+	var notFound *SchemaNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, req, notFound.Provider)
+	assert.Equal(t, KindResource, notFound.Kind)
+	assert.Equal(t, "example_missing", notFound.Name)
+	assert.Equal(t, notFound.Err.Error(), err.Error())
+
+	_, err = c.Function(context.Background(), req, "missing")
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, KindFunction, notFound.Kind)
+}
+
+func TestProviderNotFoundError(t *testing.T) {
+	c := New(WithServerOptions(
+		v1.WithCacheDir(t.TempDir()),
+		v1.WithHTTPClient(registryClient(http.StatusNotFound, "")),
+	))
+	t.Cleanup(func() { _ = c.Close() })
+
+	_, err := c.Versions(context.Background(), VersionsRequest{Namespace: "example", Name: "missing"})
+	require.ErrorIs(t, err, ErrPluginNotFound)
+	var notFound *ProviderNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "example", notFound.Provider.Namespace)
+	assert.Equal(t, "missing", notFound.Provider.Name)
+
+	err = c.Download(context.Background(), Request{Namespace: "example", Name: "missing", Version: "1.0.0"})
+	require.ErrorAs(t, err, &notFound)
+
+	other := errors.New("boom")
+	assert.Same(t, other, providerError(Request{}, other), "other errors are not wrapped")
+	assert.NoError(t, schemaError(Request{}, KindResource, "x", nil))
+}
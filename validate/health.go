@@ -0,0 +1,227 @@
+package validate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Health is how a module uses its providers' schemas; see Module.Health.
+type Health struct {
+	// Deprecated holds the references to deprecated resource types,
+	// arguments, nested blocks and exported attributes.
+	Deprecated []Reference
+	// MissingRequired holds a reference for each required argument, or
+	// nested block with a minimum count, that a block leaves out. Its Range
+	// is that of the block.
+	MissingRequired []Reference
+	// Features lists the optional provider features the module uses.
+	Features []FeatureUse
+}
+
+// Names of the provider features reported in FeatureUse, matching the
+// fields of tfpluginschema.FeatureMatrix.
+const (
+	FeatureEphemeralResources  = "ephemeral_resources"
+	FeatureWriteOnlyAttributes = "write_only_attributes"
+	FeatureFunctions           = "functions"
+)
+
+// FeatureUse is an optional provider feature used by a module.
+type FeatureUse struct {
+	Provider string // Local name of the provider
+	Feature  string // One of the Feature constants
+	// Count is the number of ephemeral blocks, write-only arguments set, or
+	// calls of provider-defined functions.
+	Count int
+}
+
+// Health checks the references of the module (see References) against
+// its providers' schemas, reporting deprecated and missing arguments and the
+// provider features the module uses. Unlike Validate, it returns structured
+// results rather than diagnostics, for reports over many modules. Unknown
+// types are skipped, since Validate reports them. A provider whose schema
+// cannot be loaded is reported once in the diagnostics, and its blocks are
+// skipped.
+func (m *Module) Health(lookup SchemaFunc) (*Health, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	h := &Health{}
+	providers := make(map[blockAddr]string, len(m.Blocks))
+	syntax := make(map[blockAddr]bool, len(m.Blocks))
+	for _, b := range m.Blocks {
+		addr := blockAddr{b.Kind, b.Type, b.Name}
+		providers[addr] = b.Provider
+		_, syntax[addr] = b.Body.(*hclsyntax.Body)
+	}
+
+	type schemaKey struct {
+		kind BlockKind
+		typ  string
+	}
+	schemas := make(map[schemaKey]*tfjson.Schema)
+	failed := make(map[string]bool)
+	schemaFor := func(provider string, ref Reference) *tfjson.Schema {
+		key := schemaKey{ref.Kind, ref.Type}
+		if schema, ok := schemas[key]; ok || failed[provider] {
+			return schema
+		}
+		p := m.Providers[provider]
+		schema, err := lookup(p, ref.Kind, ref.Type)
+		if err != nil {
+			failed[provider] = true
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to load provider schema",
+				Detail:   fmt.Sprintf("Could not load the schema for provider %q (%s): %s.", p.LocalName, p.Source, err),
+				Subject:  ref.Range.Ptr(),
+			})
+			return nil
+		}
+		schemas[key] = schema
+		return schema
+	}
+
+	refs := m.References()
+	set := make(map[blockAddr]map[string]bool)
+	for _, ref := range refs {
+		if ref.Expression {
+			continue
+		}
+		addr := blockAddr{ref.Kind, ref.Type, ref.Name}
+		if set[addr] == nil {
+			set[addr] = make(map[string]bool)
+		}
+		set[addr][strings.Join(ref.Path, ".")] = true
+	}
+
+	uses := make(map[FeatureUse]int)
+	for _, ref := range refs {
+		addr := blockAddr{ref.Kind, ref.Type, ref.Name}
+		provider := providers[addr]
+		schema := schemaFor(provider, ref)
+		if schema == nil {
+			continue
+		}
+		if len(ref.Path) == 0 {
+			if schema.Block != nil && schema.Block.Deprecated {
+				h.Deprecated = append(h.Deprecated, ref)
+			}
+			if ref.Expression {
+				continue
+			}
+			if ref.Kind == BlockKindEphemeralResource {
+				uses[FeatureUse{Provider: provider, Feature: FeatureEphemeralResources}]++
+			}
+			h.MissingRequired = appendMissing(h.MissingRequired, ref, schema.Block, set[addr], syntax[addr])
+			continue
+		}
+		attr, deprecated := resolvePath(schema.Block, ref.Path)
+		if deprecated {
+			h.Deprecated = append(h.Deprecated, ref)
+		}
+		if attr != nil && attr.WriteOnly && !ref.Expression {
+			uses[FeatureUse{Provider: provider, Feature: FeatureWriteOnlyAttributes}]++
+		}
+	}
+	for provider, n := range m.providerFunctionCalls() {
+		uses[FeatureUse{Provider: provider, Feature: FeatureFunctions}] += n
+	}
+
+	for use, n := range uses {
+		use.Count = n
+		h.Features = append(h.Features, use)
+	}
+	slices.SortFunc(h.Features, func(a, b FeatureUse) int {
+		return strings.Compare(a.Provider+"\x00"+a.Feature, b.Provider+"\x00"+b.Feature)
+	})
+	return h, diags
+}
+
+// appendMissing appends a reference for each required argument or nested
+// block of schema that block leaves out, given the set of paths it sets.
+// Nested blocks are only checked in native syntax, where References finds
+// their content.
+func appendMissing(missing []Reference, block Reference, schema *tfjson.SchemaBlock, set map[string]bool, nested bool) []Reference {
+	var walk func(schema *tfjson.SchemaBlock, prefix []string)
+	walk = func(schema *tfjson.SchemaBlock, prefix []string) {
+		if schema == nil {
+			return
+		}
+		report := func(name string) {
+			ref := block
+			ref.Path = append(slices.Clip(prefix), name)
+			missing = append(missing, ref)
+		}
+		for _, name := range slices.Sorted(maps.Keys(schema.Attributes)) {
+			if a := schema.Attributes[name]; a != nil && a.Required && !set[strings.Join(append(slices.Clip(prefix), name), ".")] {
+				report(name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(schema.NestedBlocks)) {
+			bt := schema.NestedBlocks[name]
+			if bt == nil {
+				continue
+			}
+			path := append(slices.Clip(prefix), name)
+			switch {
+			case !set[strings.Join(path, ".")]:
+				if bt.MinItems > 0 {
+					report(name)
+				}
+			case nested:
+				walk(bt.Block, path)
+			}
+		}
+	}
+	walk(schema, nil)
+	return missing
+}
+
+// resolvePath returns the attribute that path names in block, if any, and
+// whether the attribute or any nested block on the way is deprecated.
+// Anything after an attribute's name lies within its value.
+func resolvePath(block *tfjson.SchemaBlock, path []string) (*tfjson.SchemaAttribute, bool) {
+	deprecated := false
+	for _, name := range path {
+		if block == nil {
+			return nil, deprecated
+		}
+		if a := block.Attributes[name]; a != nil {
+			return a, deprecated || a.Deprecated
+		}
+		bt := block.NestedBlocks[name]
+		if bt == nil {
+			return nil, deprecated
+		}
+		block = nestedBlock(bt)
+		deprecated = deprecated || block.Deprecated
+	}
+	return nil, deprecated
+}
+
+// providerFunctionCalls counts the calls of provider-defined functions, such
+// as provider::azurerm::normalise_resource_id(...), by provider local name.
+// Only native syntax files are searched.
+func (m *Module) providerFunctionCalls() map[string]int {
+	calls := make(map[string]int)
+	for _, f := range m.Files {
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			if call, ok := node.(*hclsyntax.FunctionCallExpr); ok {
+				if parts := strings.Split(call.Name, "::"); len(parts) == 3 && parts[0] == "provider" {
+					calls[parts[1]]++
+				}
+			}
+			return nil
+		})
+	}
+	return calls
+}
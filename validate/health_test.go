@@ -0,0 +1,111 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestModule_Health(t *testing.T) {
+	secret := &tfjson.Schema{Block: &tfjson.SchemaBlock{
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"value":    {AttributeType: cty.String, Computed: true, Sensitive: true},
+			"password": {AttributeType: cty.String, Optional: true, WriteOnly: true},
+		},
+	}}
+	legacy := &tfjson.Schema{Block: &tfjson.SchemaBlock{Deprecated: true}}
+	lookup := func(_ ProviderRequirement, kind BlockKind, typ string) (*tfjson.Schema, error) {
+		switch {
+		case typ == "example_secret":
+			return secret, nil
+		case typ == "example_legacy":
+			return legacy, nil
+		}
+		return widgetLookup(ProviderRequirement{}, kind, typ)
+	}
+
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": `
+resource "example_widget" "a" {
+  old = "x"
+  dynamic "rule" {
+    for_each = [1]
+    content {}
+  }
+}
+
+resource "example_widget" "b" {
+  name = provider::example::name("b")
+  rule {
+    port = provider::example::port()
+  }
+}
+
+resource "example_secret" "s" {
+  password = "hunter2"
+}
+
+ephemeral "example_secret" "e" {}
+
+resource "example_legacy" "l" {}
+
+resource "example_unknown" "u" {}
+
+output "old" {
+  value = example_widget.b.old
+}
+`}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	h, diags := m.Health(lookup)
+	require.Empty(t, diags)
+	assert.ElementsMatch(t, []string{
+		"resource example_widget.a old",
+		"resource example_widget.b old (expr)",
+		"resource example_legacy.l",
+	}, refStrings(h.Deprecated))
+	assert.ElementsMatch(t, []string{
+		"resource example_widget.a name",
+		"resource example_widget.a rule.port",
+	}, refStrings(h.MissingRequired))
+	assert.Equal(t, []FeatureUse{
+		{Provider: "example", Feature: FeatureEphemeralResources, Count: 1},
+		{Provider: "example", Feature: FeatureFunctions, Count: 2},
+		{Provider: "example", Feature: FeatureWriteOnlyAttributes, Count: 1},
+	}, h.Features)
+}
+
+func TestModule_Health_MissingNestedBlock(t *testing.T) {
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": `
+resource "example_widget" "a" {
+  name = "a"
+}
+`}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	h, diags := m.Health(widgetLookup)
+	require.Empty(t, diags)
+	assert.Equal(t, []string{"resource example_widget.a rule"}, refStrings(h.MissingRequired))
+	assert.Empty(t, h.Deprecated)
+	assert.Empty(t, h.Features)
+}
+
+func TestModule_Health_SchemaErrorReportedOncePerProvider(t *testing.T) {
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": `
+resource "example_widget" "a" {}
+resource "example_gadget" "b" {}
+`}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	calls := 0
+	h, diags := m.Health(func(ProviderRequirement, BlockKind, string) (*tfjson.Schema, error) {
+		calls++
+		return nil, errors.New("registry unavailable")
+	})
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"Failed to load provider schema"}, summaries(diags))
+	assert.Empty(t, h.MissingRequired)
+}
@@ -0,0 +1,284 @@
+// Package validate statically checks the resource, data source and ephemeral
+// resource blocks of a Terraform or OpenTofu module against provider schemas.
+// It needs no init, backend or provider credentials: only the schemas, which
+// callers typically obtain from a tfpluginschema.Server.
+package validate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// BlockKind identifies the kind of a top-level block that Validate checks.
+type BlockKind string
+
+const (
+	BlockKindResource          BlockKind = "resource"
+	BlockKindDataSource        BlockKind = "data"
+	BlockKindEphemeralResource BlockKind = "ephemeral"
+)
+
+// ProviderRequirement is a provider required by a module, from a
+// required_providers entry or implied by a block's resource type.
+type ProviderRequirement struct {
+	LocalName string // Name used within the module (e.g., "aws")
+	Source    string // Source address (e.g., "hashicorp/aws"); implied from LocalName if not declared
+	Version   string // Version constraint, empty if none was declared
+}
+
+// Block is a resource, data or ephemeral block in a module.
+type Block struct {
+	Kind     BlockKind
+	Type     string // Resource type (e.g., "aws_instance")
+	Name     string
+	Provider string // Local name of the provider the block belongs to
+	Body     hcl.Body
+	DefRange hcl.Range
+}
+
+// ModuleCall is a module block in a module.
+type ModuleCall struct {
+	Name     string
+	Source   string // Source address (e.g., "./network" or "terraform-aws-modules/vpc/aws")
+	Version  string // Version constraint, empty if none was declared
+	DefRange hcl.Range
+}
+
+// Module is the parsed content of a module directory that Validate needs.
+type Module struct {
+	Dir         string
+	Providers   map[string]ProviderRequirement // Keyed by local name
+	Blocks      []Block
+	ModuleCalls []ModuleCall
+	// Files holds every parsed file by name, for rendering diagnostics with
+	// source snippets (see hcl.NewDiagnosticTextWriter).
+	Files map[string]*hcl.File
+}
+
+var fileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: string(BlockKindResource), LabelNames: []string{"type", "name"}},
+		{Type: string(BlockKindDataSource), LabelNames: []string{"type", "name"}},
+		{Type: string(BlockKindEphemeralResource), LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+	},
+}
+
+var moduleCallSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "source", Required: true}, {Name: "version"}},
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+}
+
+// LoadModule parses the .tf and .tf.json files in dir, not recursing into
+// subdirectories. Override files are skipped, because merging them is beyond
+// a static check. Parse errors are returned as diagnostics alongside
+// whatever could be loaded.
+func LoadModule(dir string) (*Module, hcl.Diagnostics) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to read module directory",
+			Detail:   err.Error(),
+		}}
+	}
+
+	parser := hclparse.NewParser()
+	m := &Module{
+		Dir:       dir,
+		Providers: make(map[string]ProviderRequirement),
+	}
+	var diags hcl.Diagnostics
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || isOverrideFile(name) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		var f *hcl.File
+		var fileDiags hcl.Diagnostics
+		switch {
+		case strings.HasSuffix(name, ".tf"):
+			f, fileDiags = parser.ParseHCLFile(path)
+		case strings.HasSuffix(name, ".tf.json"):
+			f, fileDiags = parser.ParseJSONFile(path)
+		default:
+			continue
+		}
+		diags = append(diags, fileDiags...)
+		if f == nil {
+			continue
+		}
+		diags = append(diags, m.loadFile(f)...)
+	}
+	m.Files = parser.Files()
+
+	// Blocks whose provider has no required_providers entry use the
+	// implied source, as Terraform does.
+	for _, b := range m.Blocks {
+		if _, ok := m.Providers[b.Provider]; !ok {
+			m.Providers[b.Provider] = ProviderRequirement{LocalName: b.Provider, Source: b.Provider}
+		}
+	}
+	return m, diags
+}
+
+func (m *Module) loadFile(f *hcl.File) hcl.Diagnostics {
+	content, _, diags := f.Body.PartialContent(fileSchema)
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "terraform":
+			diags = append(diags, m.loadTerraformBlock(block)...)
+		case "module":
+			call, callDiags := loadModuleCall(block)
+			diags = append(diags, callDiags...)
+			if !callDiags.HasErrors() {
+				m.ModuleCalls = append(m.ModuleCalls, call)
+			}
+		default:
+			b, blockDiags := loadBlock(block)
+			diags = append(diags, blockDiags...)
+			m.Blocks = append(m.Blocks, b)
+		}
+	}
+	return diags
+}
+
+func (m *Module) loadTerraformBlock(block *hcl.Block) hcl.Diagnostics {
+	content, _, diags := block.Body.PartialContent(terraformBlockSchema)
+	for _, rp := range content.Blocks {
+		attrs, attrDiags := rp.Body.JustAttributes()
+		diags = append(diags, attrDiags...)
+		for name, attr := range attrs {
+			req, reqDiags := decodeRequirement(name, attr)
+			diags = append(diags, reqDiags...)
+			if reqDiags.HasErrors() {
+				continue
+			}
+			m.Providers[name] = req
+		}
+	}
+	return diags
+}
+
+// decodeRequirement decodes one required_providers entry, either an object
+// with source and version, or the legacy version-only string form.
+func decodeRequirement(name string, attr *hcl.Attribute) (ProviderRequirement, hcl.Diagnostics) {
+	req := ProviderRequirement{LocalName: name, Source: name}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return req, diags
+	}
+	invalid := hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid required_providers entry",
+		Detail:   fmt.Sprintf("The entry for %q must be an object with source and version strings.", name),
+		Subject:  attr.Expr.Range().Ptr(),
+	}}
+	ty := val.Type()
+	switch {
+	case ty == cty.String && val.IsKnown() && !val.IsNull():
+		req.Version = val.AsString()
+	case ty.IsObjectType() && val.IsKnown() && !val.IsNull():
+		for _, field := range []struct {
+			name string
+			dst  *string
+		}{{"source", &req.Source}, {"version", &req.Version}} {
+			if !ty.HasAttribute(field.name) {
+				continue
+			}
+			v := val.GetAttr(field.name)
+			if v.Type() != cty.String || !v.IsKnown() || v.IsNull() {
+				return req, invalid
+			}
+			*field.dst = v.AsString()
+		}
+	default:
+		return req, invalid
+	}
+	return req, nil
+}
+
+// loadModuleCall decodes the source and version of a module block. Both
+// must be literal strings, as Terraform requires.
+func loadModuleCall(block *hcl.Block) (ModuleCall, hcl.Diagnostics) {
+	call := ModuleCall{Name: block.Labels[0], DefRange: block.DefRange}
+	content, _, diags := block.Body.PartialContent(moduleCallSchema)
+	if diags.HasErrors() {
+		return call, diags
+	}
+	for _, field := range []struct {
+		name string
+		dst  *string
+	}{{"source", &call.Source}, {"version", &call.Version}} {
+		attr, ok := content.Attributes[field.name]
+		if !ok {
+			continue
+		}
+		val, valDiags := attr.Expr.Value(nil)
+		if valDiags.HasErrors() || val.Type() != cty.String || !val.IsKnown() || val.IsNull() {
+			return call, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid module " + field.name,
+				Detail:   fmt.Sprintf("The %s of module %q must be a literal string.", field.name, call.Name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+		*field.dst = val.AsString()
+	}
+	return call, diags
+}
+
+var providerMetaSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "provider"}},
+}
+
+// loadBlock records a resource, data or ephemeral block and the provider it
+// belongs to: the provider meta-argument if set, otherwise the resource
+// type's prefix.
+func loadBlock(block *hcl.Block) (Block, hcl.Diagnostics) {
+	b := Block{
+		Kind:     BlockKind(block.Type),
+		Type:     block.Labels[0],
+		Name:     block.Labels[1],
+		Body:     block.Body,
+		DefRange: block.DefRange,
+	}
+	b.Provider, _, _ = strings.Cut(b.Type, "_")
+
+	content, _, diags := block.Body.PartialContent(providerMetaSchema)
+	if attr, ok := content.Attributes["provider"]; ok {
+		traversal, travDiags := hcl.AbsTraversalForExpr(attr.Expr)
+		diags = append(diags, travDiags...)
+		if !travDiags.HasErrors() {
+			b.Provider = traversal.RootName()
+		}
+	}
+	return b, diags
+}
+
+// ProviderNames returns the local names of the module's providers, sorted.
+func (m *Module) ProviderNames() []string {
+	names := make([]string, 0, len(m.Providers))
+	for name := range m.Providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func isOverrideFile(name string) bool {
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".tf")
+	return base == "override" || strings.HasSuffix(base, "_override")
+}
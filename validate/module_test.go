@@ -0,0 +1,121 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModule writes files into a new temporary module directory.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestLoadModule(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"versions.tf": `
+terraform {
+  required_providers {
+    azapi = {
+      source  = "Azure/azapi"
+      version = "~> 2.0"
+    }
+    legacy = "1.0.0"
+  }
+}
+`,
+		"main.tf": `
+resource "azapi_resource" "a" {}
+data "aws_ami" "b" {}
+ephemeral "random_password" "c" {
+  provider = legacy.alias
+}
+variable "ignored" {}
+`,
+		"main.tf.json":     `{"resource": {"azapi_update_resource": {"d": {}}}}`,
+		"main_override.tf": `resource "nope_thing" "x" {}`,
+		"README.md":        "not terraform",
+	})
+
+	m, diags := LoadModule(dir)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	assert.Equal(t, map[string]ProviderRequirement{
+		"azapi":  {LocalName: "azapi", Source: "Azure/azapi", Version: "~> 2.0"},
+		"legacy": {LocalName: "legacy", Source: "legacy", Version: "1.0.0"},
+		"aws":    {LocalName: "aws", Source: "aws"},
+	}, m.Providers)
+	assert.Equal(t, []string{"aws", "azapi", "legacy"}, m.ProviderNames())
+
+	got := make(map[string]Block)
+	for _, b := range m.Blocks {
+		got[string(b.Kind)+"."+b.Type+"."+b.Name] = b
+	}
+	require.Len(t, got, 4)
+	assert.Equal(t, "azapi", got["resource.azapi_resource.a"].Provider)
+	assert.Equal(t, "aws", got["data.aws_ami.b"].Provider)
+	assert.Equal(t, "legacy", got["ephemeral.random_password.c"].Provider)
+	assert.Equal(t, "azapi", got["resource.azapi_update_resource.d"].Provider)
+	assert.Len(t, m.Files, 3)
+}
+
+func TestLoadModule_Errors(t *testing.T) {
+	_, diags := LoadModule(filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, diags.HasErrors())
+
+	dir := writeModule(t, map[string]string{
+		"bad.tf": `resource "a_b" {`,
+		"providers.tf": `
+terraform {
+  required_providers {
+    x = 42
+  }
+}
+`,
+	})
+	m, diags := LoadModule(dir)
+	require.NotNil(t, m)
+	require.True(t, diags.HasErrors())
+	var summaries []string
+	for _, d := range diags {
+		summaries = append(summaries, d.Summary)
+	}
+	assert.Contains(t, summaries, "Invalid required_providers entry")
+}
+
+func TestLoadModule_ModuleCalls(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"main.tf": `
+module "net" {
+  source = "./network"
+  cidr   = "10.0.0.0/16"
+}
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0"
+}
+module "bad" {
+  source = var.source
+}
+`,
+	})
+	m, diags := LoadModule(dir)
+	require.NotNil(t, m)
+	require.Len(t, diags, 1)
+	assert.Equal(t, "Invalid module source", diags[0].Summary)
+
+	require.Len(t, m.ModuleCalls, 2)
+	assert.Equal(t, "net", m.ModuleCalls[0].Name)
+	assert.Equal(t, "./network", m.ModuleCalls[0].Source)
+	assert.Empty(t, m.ModuleCalls[0].Version)
+	assert.Equal(t, "terraform-aws-modules/vpc/aws", m.ModuleCalls[1].Source)
+	assert.Equal(t, "~> 5.0", m.ModuleCalls[1].Version)
+}
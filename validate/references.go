@@ -0,0 +1,156 @@
+package validate
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Reference is a use, somewhere in a module, of a resource, data source or
+// ephemeral resource type, or of an attribute or nested block within one.
+type Reference struct {
+	Kind BlockKind
+	Type string // Resource type (e.g., "aws_instance")
+	Name string // Name of the block the reference is in or to
+	// Path is the attribute path within the type: the names of nested
+	// blocks and then of an argument or exported attribute, with list and
+	// map indexes left out. It is empty for the block itself.
+	Path []string
+	// Expression is set for a reference from an expression, such as
+	// aws_instance.web.private_ip in an output, rather than an argument or
+	// nested block set in the block itself.
+	Expression bool
+	Range      hcl.Range
+}
+
+// References returns every reference in the module: for each block, the
+// block itself and the arguments and nested blocks it sets,
+// with the content of dynamic blocks attributed to the block type they
+// generate; then the references to those blocks from expressions anywhere
+// in the module. Meta-arguments and meta-blocks such as count and lifecycle
+// are left out.
+//
+// Nested blocks, and references from expressions, are only found in native
+// syntax files; .tf.json files contribute their blocks and their top-level
+// arguments.
+func (m *Module) References() []Reference {
+	var refs []Reference
+	declared := make(map[blockAddr]bool, len(m.Blocks))
+	for _, b := range m.Blocks {
+		declared[blockAddr{b.Kind, b.Type, b.Name}] = true
+		ref := Reference{Kind: b.Kind, Type: b.Type, Name: b.Name, Range: b.DefRange}
+		refs = append(refs, ref)
+		refs = appendBodyReferences(refs, ref, b.Body, metaSchemas[b.Kind])
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.Files)) {
+		body, ok := m.Files[name].Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			attr, ok := node.(*hclsyntax.Attribute)
+			if !ok {
+				return nil
+			}
+			for _, traversal := range attr.Expr.Variables() {
+				if ref, ok := traversalReference(traversal); ok && declared[blockAddr{ref.Kind, ref.Type, ref.Name}] {
+					refs = append(refs, ref)
+				}
+			}
+			return nil
+		})
+	}
+	return refs
+}
+
+// blockAddr identifies a block by its kind, type and name.
+type blockAddr struct {
+	kind BlockKind
+	typ  string
+	name string
+}
+
+// appendBodyReferences appends the arguments and nested blocks set in body,
+// skipping those in meta, which may be nil, at this level. parent is the
+// reference to body itself.
+func appendBodyReferences(refs []Reference, parent Reference, body hcl.Body, meta *hcl.BodySchema) []Reference {
+	child := func(name string, rng hcl.Range) Reference {
+		ref := parent
+		ref.Path = append(slices.Clip(parent.Path), name)
+		ref.Range = rng
+		return ref
+	}
+
+	syntax, ok := body.(*hclsyntax.Body)
+	if !ok {
+		attrs, _ := body.JustAttributes()
+		for _, name := range slices.Sorted(maps.Keys(attrs)) {
+			if !isMetaAttribute(meta, name) {
+				refs = append(refs, child(name, attrs[name].NameRange))
+			}
+		}
+		return refs
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(syntax.Attributes)) {
+		if !isMetaAttribute(meta, name) {
+			refs = append(refs, child(name, syntax.Attributes[name].NameRange))
+		}
+	}
+	for _, blk := range syntax.Blocks {
+		switch {
+		case blk.Type == "dynamic" && len(blk.Labels) == 1:
+			ref := child(blk.Labels[0], blk.LabelRanges[0])
+			refs = append(refs, ref)
+			for _, content := range blk.Body.Blocks {
+				if content.Type == "content" {
+					refs = appendBodyReferences(refs, ref, content.Body, nil)
+				}
+			}
+		case isMetaBlock(meta, blk.Type):
+		default:
+			ref := child(blk.Type, blk.TypeRange)
+			refs = append(refs, ref)
+			refs = appendBodyReferences(refs, ref, blk.Body, nil)
+		}
+	}
+	return refs
+}
+
+func isMetaAttribute(meta *hcl.BodySchema, name string) bool {
+	return meta != nil && slices.ContainsFunc(meta.Attributes, func(a hcl.AttributeSchema) bool { return a.Name == name })
+}
+
+func isMetaBlock(meta *hcl.BodySchema, typ string) bool {
+	return meta != nil && slices.ContainsFunc(meta.Blocks, func(b hcl.BlockHeaderSchema) bool { return b.Type == typ })
+}
+
+// traversalReference interprets a traversal such as aws_instance.web.id,
+// data.aws_ami.ubuntu.id or ephemeral.random_password.db.result. Whether
+// the module declares the block is left to the caller.
+func traversalReference(traversal hcl.Traversal) (Reference, bool) {
+	names := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		if attr, ok := step.(hcl.TraverseAttr); ok {
+			names = append(names, attr.Name)
+		}
+	}
+	ref := Reference{Kind: BlockKindResource, Expression: true, Range: traversal.SourceRange()}
+	switch names[0] {
+	case "data":
+		ref.Kind, names = BlockKindDataSource, names[1:]
+	case "ephemeral":
+		ref.Kind, names = BlockKindEphemeralResource, names[1:]
+	}
+	if len(names) < 2 {
+		return Reference{}, false
+	}
+	ref.Type, ref.Name = names[0], names[1]
+	if len(names) > 2 {
+		ref.Path = names[2:]
+	}
+	return ref, true
+}
@@ -0,0 +1,88 @@
+package validate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refStrings renders references as "<kind> <type>.<name> <path>", with a
+// trailing "(expr)" for references from expressions.
+func refStrings(refs []Reference) []string {
+	out := make([]string, len(refs))
+	for i, r := range refs {
+		out[i] = strings.TrimSpace(string(r.Kind) + " " + r.Type + "." + r.Name + " " + strings.Join(r.Path, "."))
+		if r.Expression {
+			out[i] += " (expr)"
+		}
+	}
+	return out
+}
+
+func TestModule_References(t *testing.T) {
+	m, diags := LoadModule(writeModule(t, map[string]string{
+		"main.tf": `
+resource "example_widget" "a" {
+  count = 2
+  name  = "a"
+  rule {
+    port = 80
+  }
+  dynamic "rule" {
+    for_each = [443]
+    content {
+      port = rule.value
+    }
+  }
+  lifecycle {
+    ignore_changes = [name]
+  }
+}
+
+data "example_lookup" "b" {
+  key = example_widget.a[0].id
+}
+
+output "ip" {
+  value = [for w in example_widget.a : w.address.ip]
+}
+
+output "other" {
+  value = data.example_lookup.b.result.value
+}
+
+output "undeclared" {
+  value = example_missing.x.id
+}
+`,
+		"extra.tf.json": `{"resource": {"example_gadget": {"c": {"size": 1}}}}`,
+	}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	refs := m.References()
+	assert.ElementsMatch(t, []string{
+		"resource example_gadget.c",
+		"resource example_gadget.c size",
+		"resource example_widget.a",
+		"resource example_widget.a name",
+		"resource example_widget.a rule",
+		"resource example_widget.a rule.port",
+		"resource example_widget.a rule",
+		"resource example_widget.a rule.port",
+		"data example_lookup.b",
+		"data example_lookup.b key",
+		"resource example_widget.a id (expr)",
+		"resource example_widget.a (expr)",
+		"data example_lookup.b result.value (expr)",
+	}, refStrings(refs))
+
+	for _, r := range refs {
+		if r.Expression && r.Kind == BlockKindDataSource {
+			assert.Equal(t, "main.tf", filepath.Base(r.Range.Filename))
+			assert.Equal(t, 28, r.Range.Start.Line)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package validate
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// CheckUpgrade reports what would break if the version constraints of some
+// of the module's providers were changed: versions maps provider local names
+// to proposed constraints (e.g., "azurerm": "~> 5.0"). The blocks of those
+// providers are validated against both the current and the proposed schemas,
+// and only the diagnostics the proposed schemas add are returned, such as a
+// removed resource type, a removed or newly required argument, or a newly
+// deprecated one. Problems the module already has are left out. References
+// to their attributes from expressions elsewhere in the module, such as
+// outputs, are reported if the attribute no longer exists.
+//
+// If the current schemas cannot be loaded, nothing is left out.
+func (m *Module) CheckUpgrade(versions map[string]string, lookup SchemaFunc) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	current := &Module{Dir: m.Dir, Providers: m.Providers, Files: m.Files}
+	proposed := &Module{Dir: m.Dir, Providers: maps.Clone(m.Providers), Files: m.Files}
+	for name, version := range versions {
+		p, ok := m.Providers[name]
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown provider",
+				Detail:   fmt.Sprintf("The module does not use a provider with local name %q.", name),
+			})
+			continue
+		}
+		p.Version = version
+		proposed.Providers[name] = p
+	}
+	for _, b := range m.Blocks {
+		if _, ok := versions[b.Provider]; ok {
+			current.Blocks = append(current.Blocks, b)
+			proposed.Blocks = append(proposed.Blocks, b)
+		}
+	}
+
+	existing := make(map[string]int)
+	for _, d := range current.Validate(lookup) {
+		existing[diagnosticKey(d)]++
+	}
+	for _, d := range proposed.Validate(lookup) {
+		if key := diagnosticKey(d); existing[key] > 0 {
+			existing[key]--
+			continue
+		}
+		diags = append(diags, d)
+	}
+	return append(diags, m.brokenReferences(versions, current.Providers, proposed.Providers, lookup)...)
+}
+
+// brokenReferences reports the references from expressions to attributes of
+// blocks of the bumped providers that exist in the current schemas but not
+// in the proposed ones. References to types that no longer exist are not
+// reported, since Validate reports the blocks themselves.
+func (m *Module) brokenReferences(versions map[string]string, current, proposed map[string]ProviderRequirement, lookup SchemaFunc) hcl.Diagnostics {
+	providers := make(map[blockAddr]string, len(m.Blocks))
+	for _, b := range m.Blocks {
+		providers[blockAddr{b.Kind, b.Type, b.Name}] = b.Provider
+	}
+	type schemaKey struct {
+		p    ProviderRequirement
+		kind BlockKind
+		typ  string
+	}
+	schemas := make(map[schemaKey]*tfjson.Schema)
+	schemaFor := func(p ProviderRequirement, kind BlockKind, typ string) *tfjson.Schema {
+		key := schemaKey{p, kind, typ}
+		schema, ok := schemas[key]
+		if !ok {
+			schema, _ = lookup(p, kind, typ)
+			schemas[key] = schema
+		}
+		return schema
+	}
+
+	var diags hcl.Diagnostics
+	for _, ref := range m.References() {
+		provider := providers[blockAddr{ref.Kind, ref.Type, ref.Name}]
+		if _, bumped := versions[provider]; !bumped || !ref.Expression || len(ref.Path) == 0 {
+			continue
+		}
+		before := schemaFor(current[provider], ref.Kind, ref.Type)
+		after := schemaFor(proposed[provider], ref.Kind, ref.Type)
+		if before == nil || after == nil || !hasPath(before.Block, ref.Path) || hasPath(after.Block, ref.Path) {
+			continue
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported attribute",
+			Detail:   fmt.Sprintf("The %s type %q has no attribute %q in the proposed version.", kindNoun(ref.Kind), ref.Type, strings.Join(ref.Path, ".")),
+			Subject:  ref.Range.Ptr(),
+		})
+	}
+	return diags
+}
+
+// hasPath reports whether path names an attribute of block, or of its
+// nested blocks, or a nested block. Anything after an attribute's name lies
+// within its value and is not checked.
+func hasPath(block *tfjson.SchemaBlock, path []string) bool {
+	for _, name := range path {
+		if block == nil {
+			return false
+		}
+		if block.Attributes[name] != nil {
+			return true
+		}
+		bt := block.NestedBlocks[name]
+		if bt == nil {
+			return false
+		}
+		block = nestedBlock(bt)
+	}
+	return true
+}
+
+// diagnosticKey identifies a diagnostic by its severity, text and subject,
+// for matching the same problem across two validations.
+func diagnosticKey(d *hcl.Diagnostic) string {
+	subject := ""
+	if d.Subject != nil {
+		subject = d.Subject.String()
+	}
+	return fmt.Sprintf("%d\x00%s\x00%s\x00%s", d.Severity, d.Summary, d.Detail, subject)
+}
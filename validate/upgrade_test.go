@@ -0,0 +1,145 @@
+package validate
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// widgetSchemaV2 drops the size argument and the gadget resource type of
+// version 1, and adds a required zone argument.
+var widgetSchemaV2 = &tfjson.Schema{Block: &tfjson.SchemaBlock{
+	Attributes: map[string]*tfjson.SchemaAttribute{
+		"name": {AttributeType: cty.String, Required: true},
+		"zone": {AttributeType: cty.String, Required: true},
+		"id":   {AttributeType: cty.String, Computed: true},
+	},
+}}
+
+func versionedLookup(p ProviderRequirement, kind BlockKind, typ string) (*tfjson.Schema, error) {
+	if p.LocalName != "example" || kind != BlockKindResource {
+		return nil, nil
+	}
+	switch {
+	case p.Version == "~> 2.0" && typ == "example_widget":
+		return widgetSchemaV2, nil
+	case p.Version == "~> 1.0" && (typ == "example_widget" || typ == "example_gadget"):
+		return &tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: widgetSchemaV2.Block.Attributes}}, nil
+	}
+	return nil, nil
+}
+
+func TestModule_CheckUpgrade(t *testing.T) {
+	dir := writeModule(t, map[string]string{"main.tf": `
+terraform {
+  required_providers {
+    example = { source = "example/example", version = "~> 1.0" }
+  }
+}
+
+resource "example_widget" "ok" {
+  name = "a"
+  zone = "z"
+}
+
+resource "example_widget" "broken" {
+  name = "b"
+  id   = "already invalid"
+}
+
+resource "example_gadget" "gone" {
+  name = "c"
+  zone = "z"
+}
+
+resource "other_thing" "untouched" {}
+`})
+	m, diags := LoadModule(dir)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	// The current version already lacks zone in "broken" and rejects its
+	// id; only the removed gadget type is new. other_thing is not bumped.
+	diags = m.CheckUpgrade(map[string]string{"example": "~> 2.0"}, versionedLookup)
+	require.Equal(t, []string{"Invalid resource type"}, summaries(diags))
+	assert.Contains(t, diags[0].Detail, `"example_gadget"`)
+
+	// Checking the current version against itself finds nothing.
+	assert.Empty(t, m.CheckUpgrade(map[string]string{"example": "~> 1.0"}, versionedLookup))
+	assert.Equal(t, "~> 1.0", m.Providers["example"].Version, "the module is not modified")
+}
+
+func TestModule_CheckUpgrade_Arguments(t *testing.T) {
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": `
+resource "example_widget" "a" {
+  name = "a"
+  size = 1
+  old  = "x"
+  rule { port = 80 }
+}
+`}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	lookup := func(p ProviderRequirement, kind BlockKind, typ string) (*tfjson.Schema, error) {
+		if p.Version == "2.0.0" {
+			return widgetSchemaV2, nil
+		}
+		return widgetLookup(p, kind, typ)
+	}
+	diags = m.CheckUpgrade(map[string]string{"example": "2.0.0"}, lookup)
+	assert.ElementsMatch(t, []string{
+		"Missing required argument",
+		"Unsupported argument",
+		"Unsupported argument",
+		"Unsupported block type",
+	}, summaries(diags), "the deprecation warning for old is not new")
+}
+
+func TestModule_CheckUpgrade_UnknownProvider(t *testing.T) {
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": `resource "example_widget" "a" {}`}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	diags = m.CheckUpgrade(map[string]string{"azurerm": "~> 5.0"}, widgetLookup)
+	require.Equal(t, []string{"Unknown provider"}, summaries(diags))
+	assert.Contains(t, diags[0].Detail, `"azurerm"`)
+}
+
+func TestModule_CheckUpgrade_ExpressionReferences(t *testing.T) {
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": `
+resource "example_widget" "a" {
+  name = "a"
+  zone = "z"
+}
+
+output "size" {
+  value = example_widget.a.size
+}
+
+output "id" {
+  value = example_widget.a.id
+}
+
+output "never" {
+  value = example_widget.a.nope
+}
+`}))
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	lookup := func(p ProviderRequirement, _ BlockKind, _ string) (*tfjson.Schema, error) {
+		if p.Version == "2.0.0" {
+			return widgetSchemaV2, nil
+		}
+		return &tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: map[string]*tfjson.SchemaAttribute{
+			"name": widgetSchemaV2.Block.Attributes["name"],
+			"zone": widgetSchemaV2.Block.Attributes["zone"],
+			"id":   widgetSchemaV2.Block.Attributes["id"],
+			"size": {AttributeType: cty.Number, Computed: true},
+		}}}, nil
+	}
+	diags = m.CheckUpgrade(map[string]string{"example": "2.0.0"}, lookup)
+	require.Equal(t, []string{"Unsupported attribute"}, summaries(diags), "nope never existed")
+	assert.Contains(t, diags[0].Detail, `"size"`)
+	assert.Equal(t, 8, diags[0].Subject.Start.Line)
+}
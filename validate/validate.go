@@ -0,0 +1,260 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// SchemaFunc returns the schema for the block kind and type from the given
+// provider. It returns a nil schema and a nil error if the provider has no
+// such type.
+type SchemaFunc func(p ProviderRequirement, kind BlockKind, typ string) (*tfjson.Schema, error)
+
+// metaSchemas holds the meta-arguments and meta-blocks each block kind
+// accepts in addition to its provider schema. Their contents are not checked.
+var metaSchemas = map[BlockKind]*hcl.BodySchema{
+	BlockKindResource: {
+		Attributes: []hcl.AttributeSchema{{Name: "count"}, {Name: "for_each"}, {Name: "provider"}, {Name: "depends_on"}},
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "lifecycle"},
+			{Type: "connection"},
+			{Type: "provisioner", LabelNames: []string{"type"}},
+		},
+	},
+	BlockKindDataSource: {
+		Attributes: []hcl.AttributeSchema{{Name: "count"}, {Name: "for_each"}, {Name: "provider"}, {Name: "depends_on"}},
+		Blocks:     []hcl.BlockHeaderSchema{{Type: "lifecycle"}},
+	},
+	BlockKindEphemeralResource: {
+		Attributes: []hcl.AttributeSchema{{Name: "count"}, {Name: "for_each"}, {Name: "provider"}, {Name: "depends_on"}},
+		Blocks:     []hcl.BlockHeaderSchema{{Type: "lifecycle"}},
+	},
+}
+
+var dynamicBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "for_each", Required: true},
+		{Name: "iterator"},
+		{Name: "labels"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{{Type: "content"}},
+}
+
+// Validate checks every block in the module against its provider's schema:
+// the resource type must exist, required arguments and blocks must be set,
+// unknown or read-only arguments must not be, and nested block counts must
+// be within the schema's limits. Deprecated arguments produce warnings.
+// Expressions are not evaluated, so argument values are not type-checked.
+//
+// A provider whose schema cannot be loaded is reported once, and its
+// remaining blocks are skipped.
+func (m *Module) Validate(lookup SchemaFunc) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	failed := make(map[string]bool)
+	for _, b := range m.Blocks {
+		p := m.Providers[b.Provider]
+		if failed[p.LocalName] {
+			continue
+		}
+		schema, err := lookup(p, b.Kind, b.Type)
+		if err != nil {
+			failed[p.LocalName] = true
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to load provider schema",
+				Detail:   fmt.Sprintf("Could not load the schema for provider %q (%s): %s.", p.LocalName, p.Source, err),
+				Subject:  b.DefRange.Ptr(),
+			})
+			continue
+		}
+		if schema == nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid " + kindNoun(b.Kind) + " type",
+				Detail:   fmt.Sprintf("The provider %s does not support %s type %q.", p.Source, kindNoun(b.Kind), b.Type),
+				Subject:  b.DefRange.Ptr(),
+			})
+			continue
+		}
+		block := schema.Block
+		if block == nil {
+			block = &tfjson.SchemaBlock{}
+		}
+		diags = append(diags, validateBody(b.Body, block, metaSchemas[b.Kind])...)
+	}
+	return diags
+}
+
+func kindNoun(kind BlockKind) string {
+	switch kind {
+	case BlockKindDataSource:
+		return "data source"
+	case BlockKindEphemeralResource:
+		return "ephemeral resource"
+	default:
+		return "resource"
+	}
+}
+
+// validateBody checks body against block, also accepting the arguments and
+// blocks in meta (which may be nil) and dynamic blocks.
+func validateBody(body hcl.Body, block *tfjson.SchemaBlock, meta *hcl.BodySchema) hcl.Diagnostics {
+	content, diags := body.Content(bodySchema(block, meta))
+
+	for name, attr := range content.Attributes {
+		a := block.Attributes[name]
+		if a == nil {
+			continue
+		}
+		if a.Computed && !a.Optional && !a.Required {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid configuration",
+				Detail:   fmt.Sprintf("Can't configure a value for %q: its value will be decided automatically based on the result of applying this configuration.", name),
+				Subject:  attr.NameRange.Ptr(),
+			})
+		}
+		if a.Deprecated {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Deprecated attribute",
+				Detail:   fmt.Sprintf("The attribute %q is deprecated. Refer to the provider documentation for details.", name),
+				Subject:  attr.NameRange.Ptr(),
+			})
+		}
+	}
+
+	nested := make(map[string][]*hcl.Block)
+	dynamic := make(map[string]bool)
+	for _, blk := range content.Blocks {
+		if blk.Type == "dynamic" {
+			name := blk.Labels[0]
+			bt := block.NestedBlocks[name]
+			if bt == nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Unsupported block type",
+					Detail:   fmt.Sprintf("Blocks of type %q are not expected here.", name),
+					Subject:  blk.LabelRanges[0].Ptr(),
+				})
+				continue
+			}
+			dynamic[name] = true
+			diags = append(diags, validateDynamicBlock(blk, bt)...)
+			continue
+		}
+		if bt := block.NestedBlocks[blk.Type]; bt != nil {
+			nested[blk.Type] = append(nested[blk.Type], blk)
+			diags = append(diags, validateBody(blk.Body, nestedBlock(bt), nil)...)
+		}
+	}
+
+	for name, bt := range block.NestedBlocks {
+		// A dynamic block may generate any number of blocks, so the
+		// count can only be checked once expressions are evaluated.
+		if bt == nil || dynamic[name] {
+			continue
+		}
+		blocks := nested[name]
+		minItems, maxItems := bt.MinItems, bt.MaxItems
+		if bt.NestingMode == tfjson.SchemaNestingModeSingle || bt.NestingMode == tfjson.SchemaNestingModeGroup {
+			maxItems = 1
+		}
+		if n := uint64(len(blocks)); n < minItems {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Insufficient %s blocks", name),
+				Detail:   fmt.Sprintf("At least %d %q blocks are required.", minItems, name),
+				Subject:  body.MissingItemRange().Ptr(),
+			})
+		}
+		if maxItems > 0 && uint64(len(blocks)) > maxItems {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Too many %s blocks", name),
+				Detail:   fmt.Sprintf("No more than %d %q blocks are allowed.", maxItems, name),
+				Subject:  blocks[maxItems].DefRange.Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// validateDynamicBlock checks a dynamic block's own arguments and the body of
+// its content block against the nested block it generates.
+func validateDynamicBlock(blk *hcl.Block, bt *tfjson.SchemaBlockType) hcl.Diagnostics {
+	content, diags := blk.Body.Content(dynamicBlockSchema)
+	var contents []*hcl.Block
+	for _, c := range content.Blocks {
+		if c.Type == "content" {
+			contents = append(contents, c)
+		}
+	}
+	switch len(contents) {
+	case 0:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing content block",
+			Detail:   "A dynamic block must have a nested block of type \"content\" to describe the body of each generated block.",
+			Subject:  blk.Body.MissingItemRange().Ptr(),
+		})
+	case 1:
+		diags = append(diags, validateBody(contents[0].Body, nestedBlock(bt), nil)...)
+	default:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Extraneous content block",
+			Detail:   "Only one nested content block is allowed for each dynamic block.",
+			Subject:  contents[1].DefRange.Ptr(),
+		})
+	}
+	return diags
+}
+
+func nestedBlock(bt *tfjson.SchemaBlockType) *tfjson.SchemaBlock {
+	if bt.Block == nil {
+		return &tfjson.SchemaBlock{}
+	}
+	return bt.Block
+}
+
+// bodySchema converts a schema block, plus optional meta-arguments, into the
+// hcl.BodySchema used to decode a configuration body. Read-only attributes
+// are included so that setting one gets a specific diagnostic rather than
+// "Unsupported argument".
+func bodySchema(block *tfjson.SchemaBlock, meta *hcl.BodySchema) *hcl.BodySchema {
+	bs := &hcl.BodySchema{}
+	for name, a := range block.Attributes {
+		if a == nil {
+			continue
+		}
+		bs.Attributes = append(bs.Attributes, hcl.AttributeSchema{Name: name, Required: a.Required})
+	}
+	for name, bt := range block.NestedBlocks {
+		if bt == nil {
+			continue
+		}
+		var labels []string
+		if bt.NestingMode == tfjson.SchemaNestingModeMap {
+			labels = []string{"key"}
+		}
+		bs.Blocks = append(bs.Blocks, hcl.BlockHeaderSchema{Type: name, LabelNames: labels})
+	}
+	bs.Blocks = append(bs.Blocks, hcl.BlockHeaderSchema{Type: "dynamic", LabelNames: []string{"type"}})
+
+	if meta != nil {
+		for _, a := range meta.Attributes {
+			if block.Attributes[a.Name] == nil {
+				bs.Attributes = append(bs.Attributes, a)
+			}
+		}
+		for _, b := range meta.Blocks {
+			if block.NestedBlocks[b.Type] == nil {
+				bs.Blocks = append(bs.Blocks, b)
+			}
+		}
+	}
+	return bs
+}
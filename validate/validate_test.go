@@ -0,0 +1,190 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var widgetSchema = &tfjson.Schema{Block: &tfjson.SchemaBlock{
+	Attributes: map[string]*tfjson.SchemaAttribute{
+		"name":  {AttributeType: cty.String, Required: true},
+		"size":  {AttributeType: cty.Number, Optional: true},
+		"old":   {AttributeType: cty.String, Optional: true, Deprecated: true},
+		"id":    {AttributeType: cty.String, Computed: true},
+		"label": {AttributeType: cty.String, Optional: true, Computed: true},
+	},
+	NestedBlocks: map[string]*tfjson.SchemaBlockType{
+		"rule": {
+			NestingMode: tfjson.SchemaNestingModeList,
+			MinItems:    1,
+			MaxItems:    2,
+			Block: &tfjson.SchemaBlock{
+				Attributes: map[string]*tfjson.SchemaAttribute{
+					"port": {AttributeType: cty.Number, Required: true},
+				},
+			},
+		},
+		"timeouts": {NestingMode: tfjson.SchemaNestingModeSingle, Block: &tfjson.SchemaBlock{}},
+	},
+}}
+
+func widgetLookup(_ ProviderRequirement, kind BlockKind, typ string) (*tfjson.Schema, error) {
+	if kind == BlockKindResource && typ == "example_widget" {
+		return widgetSchema, nil
+	}
+	return nil, nil
+}
+
+// validateSource validates a module consisting of a single main.tf.
+func validateSource(t *testing.T, src string, lookup SchemaFunc) hcl.Diagnostics {
+	t.Helper()
+	m, diags := LoadModule(writeModule(t, map[string]string{"main.tf": src}))
+	require.False(t, diags.HasErrors(), diags.Error())
+	return m.Validate(lookup)
+}
+
+func summaries(diags hcl.Diagnostics) []string {
+	out := make([]string, len(diags))
+	for i, d := range diags {
+		out[i] = d.Summary
+	}
+	return out
+}
+
+func TestValidate_Valid(t *testing.T) {
+	diags := validateSource(t, `
+resource "example_widget" "a" {
+  count = 2
+  name  = "a"
+  label = "x"
+  rule {
+    port = 80
+  }
+  dynamic "rule" {
+    for_each = [1, 2, 3]
+    content {
+      port = rule.value
+    }
+  }
+  timeouts {}
+  lifecycle {
+    create_before_destroy = true
+  }
+  provisioner "local-exec" {
+    command = "true"
+  }
+}
+`, widgetLookup)
+	assert.Empty(t, diags)
+}
+
+func TestValidate_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "unknown type",
+			src:  `resource "example_gadget" "a" {}`,
+			want: []string{"Invalid resource type"},
+		},
+		{
+			name: "unknown data source",
+			src:  `data "example_widget" "a" {}`,
+			want: []string{"Invalid data source type"},
+		},
+		{
+			name: "missing required argument and block",
+			src:  `resource "example_widget" "a" {}`,
+			want: []string{"Missing required argument", "Insufficient rule blocks"},
+		},
+		{
+			name: "unsupported and read-only arguments",
+			src: `resource "example_widget" "a" {
+  name  = "a"
+  id    = "b"
+  bogus = 1
+  rule { port = 1 }
+}`,
+			want: []string{"Unsupported argument", "Invalid configuration"},
+		},
+		{
+			name: "too many blocks",
+			src: `resource "example_widget" "a" {
+  name = "a"
+  rule { port = 1 }
+  rule { port = 2 }
+  rule { port = 3 }
+  timeouts {}
+  timeouts {}
+}`,
+			want: []string{"Too many rule blocks", "Too many timeouts blocks"},
+		},
+		{
+			name: "nested block contents",
+			src: `resource "example_widget" "a" {
+  name = "a"
+  rule {}
+  dynamic "rule" {
+    for_each = []
+    content {
+      port  = 1
+      extra = 2
+    }
+  }
+  dynamic "unknown" {
+    for_each = []
+    content {}
+  }
+}`,
+			want: []string{"Missing required argument", "Unsupported argument", "Unsupported block type"},
+		},
+		{
+			name: "deprecated attribute",
+			src: `resource "example_widget" "a" {
+  name = "a"
+  old  = "b"
+  rule { port = 1 }
+}`,
+			want: []string{"Deprecated attribute"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := validateSource(t, tt.src, widgetLookup)
+			assert.ElementsMatch(t, tt.want, summaries(diags), diags.Error())
+		})
+	}
+}
+
+func TestValidate_DeprecatedIsWarning(t *testing.T) {
+	diags := validateSource(t, `resource "example_widget" "a" {
+  name = "a"
+  old  = "b"
+  rule { port = 1 }
+}`, widgetLookup)
+	require.Len(t, diags, 1)
+	assert.Equal(t, hcl.DiagWarning, diags[0].Severity)
+	assert.False(t, diags.HasErrors())
+}
+
+func TestValidate_SchemaErrorReportedOncePerProvider(t *testing.T) {
+	calls := 0
+	diags := validateSource(t, `
+resource "example_widget" "a" {}
+resource "example_widget" "b" {}
+`, func(ProviderRequirement, BlockKind, string) (*tfjson.Schema, error) {
+		calls++
+		return nil, errors.New("registry unavailable")
+	})
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"Failed to load provider schema"}, summaries(diags))
+	assert.Contains(t, diags[0].Detail, "registry unavailable")
+}
@@ -0,0 +1,401 @@
+package tfpluginschema
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+const (
+	// verifiedFileName is the file in each cache entry listing the
+	// verification methods the provider archive passed, one per line.
+	verifiedFileName = ".tfpluginschema-verified"
+	// sigstoreMethod is the name the built-in Sigstore verifier is
+	// registered under.
+	sigstoreMethod = "sigstore"
+	// gpgMethod is the name the built-in GPG verifier is registered under.
+	gpgMethod = "gpg"
+	// maxVerificationFileSize bounds the checksum lists and signature
+	// bundles fetched for verification.
+	maxVerificationFileSize = 4 << 20
+)
+
+var (
+	// ErrVerificationFailed is returned when a provider archive fails a
+	// verification method, or when WithRequireVerification is set and no
+	// method passed.
+	ErrVerificationFailed = errors.New("provider verification failed")
+	// ErrVerificationUnavailable is returned, wrapped, by a Verifier for a
+	// provider that does not publish what the method checks, such as a
+	// provider without Sigstore bundles. It is not a failure by itself.
+	ErrVerificationUnavailable = errors.New("verification material not published")
+)
+
+// Artifact describes a downloaded provider archive for a Verifier.
+type Artifact struct {
+	// Request is the provider, with a concrete version and registry type.
+	Request Request
+	// Path is the downloaded archive.
+	Path string
+	// FileName is the archive's file name as published by the registry.
+	FileName string
+	// SHA256 is the hex SHA-256 digest of the archive.
+	SHA256 string
+	// SHASumsURL is the URL of the checksum list covering the archive, if
+	// the registry reports one.
+	SHASumsURL string
+	// SHASumsSignatureURL is the URL of the GPG signature of the checksum
+	// list, if the registry reports one.
+	SHASumsSignatureURL string
+	// GPGPublicKeys are the ASCII-armored public keys the registry reports
+	// for checking that signature.
+	GPGPublicKeys []string
+}
+
+// Verifier checks a downloaded provider archive before it is extracted. It
+// returns nil if the archive passes, an error wrapping
+// ErrVerificationUnavailable if the provider publishes nothing to check, or
+// any other error if the archive fails. Verifiers may be called
+// concurrently.
+type Verifier func(a Artifact) error
+
+// WithVerifier adds a verification method, named name in logs, errors and
+// the cache entry, that every downloaded provider archive must pass.
+// Methods run in the order they were added. A failing method aborts the
+// download with ErrVerificationFailed; one that does not apply to the
+// provider is skipped. Adding a method under an existing name replaces it.
+func WithVerifier(name string, v Verifier) ServerOption {
+	return func(s *Server) {
+		i := slices.IndexFunc(s.verifiers, func(nv namedVerifier) bool { return nv.name == name })
+		if i < 0 {
+			s.verifiers = append(s.verifiers, namedVerifier{name: name, verify: v})
+			return
+		}
+		s.verifiers[i].verify = v
+	}
+}
+
+// WithRequireVerification makes the Server refuse to execute a provider
+// unless at least one verification method, added with WithVerifier,
+// WithGPGVerification or WithSigstoreVerification, passed for its archive. Downloads that no
+// method applies to fail with ErrVerificationFailed, and cached providers
+// without a recorded verification are downloaded and verified again. Binaries
+// of a LocalBinarySource are refused, as nothing verifies them; those of a
+// TrustedLocalBinarySource are executed.
+func WithRequireVerification(required bool) ServerOption {
+	return func(s *Server) {
+		s.requireVerification = required
+	}
+}
+
+// SigstoreOptions configures WithSigstoreVerification.
+type SigstoreOptions struct {
+	// CosignPath is the cosign executable; the default is "cosign" looked
+	// up in PATH.
+	CosignPath string
+	// BundleSuffix is appended to the checksum list URL to find its
+	// Sigstore bundle; the default is ".sigstore.json".
+	BundleSuffix string
+	// CertificateIdentityRegexp must match the identity in the signing
+	// certificate, such as "^https://github.com/hashicorp/". It is
+	// required: the identity is what makes the signature mean anything, so
+	// it is not guessed from what the registry reports.
+	CertificateIdentityRegexp string
+	// CertificateOIDCIssuer must be the issuer of the signing certificate;
+	// the default is GitHub Actions.
+	CertificateOIDCIssuer string
+}
+
+// WithSigstoreVerification adds a verification method, named "sigstore",
+// for providers whose releases are signed with Sigstore: the checksum list
+// reported by the registry must have a Sigstore bundle next to it, which
+// "cosign verify-blob" checks, and must list the archive's checksum.
+// Providers publishing no bundle are skipped unless WithRequireVerification
+// is set. cosign must be installed. Every archive fails verification if
+// opts.CertificateIdentityRegexp is empty.
+func WithSigstoreVerification(opts SigstoreOptions) ServerOption {
+	return func(s *Server) {
+		WithVerifier(sigstoreMethod, func(a Artifact) error {
+			return s.verifySigstore(a, opts)
+		})(s)
+	}
+}
+
+// WithGPGVerification adds a verification method, named "gpg", that checks
+// the checksum list reported by the registry (shasums_url) against its
+// detached GPG signature (shasums_signature_url) with the public keys the
+// registry reports for the provider (signing_keys), and that the list
+// names the archive's checksum. This is the check "terraform init" makes.
+// Providers for which the registry reports no signature or keys are
+// skipped unless WithRequireVerification is set.
+func WithGPGVerification() ServerOption {
+	return func(s *Server) {
+		WithVerifier(gpgMethod, s.verifyGPG)(s)
+	}
+}
+
+// namedVerifier is a verification method added with WithVerifier.
+type namedVerifier struct {
+	name   string
+	verify Verifier
+}
+
+// verifyArchive runs the verification methods on a and returns the names
+// of those that passed.
+func (s *Server) verifyArchive(a Artifact) ([]string, error) {
+	l := s.logger(logComponentDownload).With(s.requestLogAttrs(a.Request)...)
+	var passed []string
+	for _, v := range s.verifiers {
+		err := v.verify(a)
+		switch {
+		case err == nil:
+			l.Info("Provider archive verified", "method", v.name)
+			passed = append(passed, v.name)
+		case errors.Is(err, ErrVerificationUnavailable):
+			l.Debug("Verification method does not apply to provider", "method", v.name, "error", err)
+		default:
+			return nil, fmt.Errorf("%w: %s/%s %s: %s: %w", ErrVerificationFailed, a.Request.Namespace, a.Request.Name, a.Request.Version, v.name, err)
+		}
+	}
+	if s.requireVerification && len(passed) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s %s: no verification method passed", ErrVerificationFailed, a.Request.Namespace, a.Request.Name, a.Request.Version)
+	}
+	return passed, nil
+}
+
+// recordVerification writes the verification methods that passed to the
+// cache entry in dir.
+func recordVerification(dir string, methods []string) error {
+	if len(methods) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, verifiedFileName), []byte(strings.Join(methods, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record provider verification: %w", err)
+	}
+	return nil
+}
+
+// verifiedMethods returns the verification methods the cache entry in dir
+// records as passed.
+func verifiedMethods(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, verifiedFileName))
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// isVerified reports whether the cache entry in dir records a passed
+// verification method.
+func isVerified(dir string) bool {
+	return len(verifiedMethods(dir)) > 0
+}
+
+// verifySigstore checks the Sigstore bundle of the checksum list covering
+// a with cosign, then that the list names a's checksum.
+func (s *Server) verifySigstore(a Artifact, opts SigstoreOptions) error {
+	if opts.CertificateIdentityRegexp == "" {
+		return errors.New("no certificate identity is configured (SigstoreOptions.CertificateIdentityRegexp)")
+	}
+	if a.SHASumsURL == "" {
+		return fmt.Errorf("%w: registry reports no checksum list", ErrVerificationUnavailable)
+	}
+	suffix := opts.BundleSuffix
+	if suffix == "" {
+		suffix = ".sigstore.json"
+	}
+	bundle, found, err := s.fetchVerificationFile(a.SHASumsURL + suffix)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: no Sigstore bundle at %s", ErrVerificationUnavailable, a.SHASumsURL+suffix)
+	}
+	sums, found, err := s.fetchVerificationFile(a.SHASumsURL)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("checksum list %s not found", a.SHASumsURL)
+	}
+
+	identity := opts.CertificateIdentityRegexp
+	issuer := opts.CertificateOIDCIssuer
+	if issuer == "" {
+		issuer = "https://token.actions.githubusercontent.com"
+	}
+	cosign := opts.CosignPath
+	if cosign == "" {
+		cosign = "cosign"
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(a.Path), "sigstore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for verification: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	sumsPath, bundlePath := filepath.Join(dir, "SHA256SUMS"), filepath.Join(dir, "SHA256SUMS"+suffix)
+	if err := os.WriteFile(sumsPath, sums, 0o600); err != nil {
+		return fmt.Errorf("failed to write checksum list: %w", err)
+	}
+	if err := os.WriteFile(bundlePath, bundle, 0o600); err != nil {
+		return fmt.Errorf("failed to write Sigstore bundle: %w", err)
+	}
+	cmd := exec.Command(cosign, "verify-blob",
+		"--bundle", bundlePath,
+		"--certificate-identity-regexp", identity,
+		"--certificate-oidc-issuer", issuer,
+		sumsPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cosign verify-blob: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	if !checksumListed(sums, a.SHA256, a.FileName) {
+		return fmt.Errorf("signed checksum list does not list %s with sha256 %s", a.FileName, a.SHA256)
+	}
+	return nil
+}
+
+// verifyGPG checks the detached GPG signature of the checksum list covering
+// a with the keys the registry reports, then that the list names a's
+// checksum.
+func (s *Server) verifyGPG(a Artifact) error {
+	if a.SHASumsURL == "" || a.SHASumsSignatureURL == "" {
+		return fmt.Errorf("%w: registry reports no signed checksum list", ErrVerificationUnavailable)
+	}
+	if len(a.GPGPublicKeys) == 0 {
+		return fmt.Errorf("%w: registry reports no GPG signing keys", ErrVerificationUnavailable)
+	}
+	var keyring openpgp.EntityList
+	for _, armored := range a.GPGPublicKeys {
+		keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+		if err != nil {
+			return fmt.Errorf("invalid GPG signing key: %w", err)
+		}
+		keyring = append(keyring, keys...)
+	}
+	sums, found, err := s.fetchVerificationFile(a.SHASumsURL)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("checksum list %s not found", a.SHASumsURL)
+	}
+	signature, found, err := s.fetchVerificationFile(a.SHASumsSignatureURL)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("checksum list signature %s not found", a.SHASumsSignatureURL)
+	}
+	signer, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(sums), bytes.NewReader(signature), nil)
+	if err != nil {
+		return fmt.Errorf("checksum list signature does not verify: %w", err)
+	}
+	if !checksumListed(sums, a.SHA256, a.FileName) {
+		return fmt.Errorf("signed checksum list does not list %s with sha256 %s", a.FileName, a.SHA256)
+	}
+	s.logger(logComponentDownload).Debug("Checksum list signature verified", append(s.requestLogAttrs(a.Request), "key_id", signer.PrimaryKey.KeyIdString())...)
+	return nil
+}
+
+// checksumListed reports whether sums, in the format of sha256sum, lists
+// fileName with the hex digest.
+func checksumListed(sums []byte, digest, fileName string) bool {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.EqualFold(fields[0], digest) && strings.TrimPrefix(fields[1], "*") == fileName {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchVerificationFile fetches u without registry credentials, reporting
+// false if it is not found.
+func (s *Server) fetchVerificationFile(u string) ([]byte, bool, error) {
+	if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, false, fmt.Errorf("invalid verification URL %q", u)
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request for %s: %w", u, err)
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("failed to fetch %s: status %d", u, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxVerificationFileSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", u, err)
+	}
+	if len(body) > maxVerificationFileSize {
+		return nil, false, fmt.Errorf("%s is larger than %d bytes", u, maxVerificationFileSize)
+	}
+	return body, true, nil
+}
+
+// verificationArtifact describes the archive at path downloaded for
+// request, hashing it if the registry reported no checksum.
+func verificationArtifact(request Request, path string, r pluginApiResponse) (Artifact, error) {
+	digest := strings.ToLower(r.SHASum)
+	if digest == "" {
+		var err error
+		if digest, err = hashFile(path); err != nil {
+			return Artifact{}, fmt.Errorf("failed to hash provider archive: %w", err)
+		}
+	}
+	var keys []string
+	for _, k := range r.SigningKeys.GPGPublicKeys {
+		if k.ASCIIArmor != "" {
+			keys = append(keys, k.ASCIIArmor)
+		}
+	}
+	return Artifact{
+		Request:             request,
+		Path:                path,
+		FileName:            r.FileName,
+		SHA256:              digest,
+		SHASumsURL:          resolveRegistryURL(r.metadataURL, r.SHASumsURL),
+		SHASumsSignatureURL: resolveRegistryURL(r.metadataURL, r.SHASumsSignatureURL),
+		GPGPublicKeys:       keys,
+	}, nil
+}
+
+// resolveRegistryURL resolves ref, a possibly relative URL from the
+// registry response fetched from base. An empty ref stays empty.
+func resolveRegistryURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
@@ -0,0 +1,294 @@
+package tfpluginschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSignedRegistry serves the null provider with a checksum list, and
+// serves bundle as its Sigstore bundle if not nil.
+func stubSignedRegistry(t *testing.T, archive, bundle []byte, downloads *atomic.Int32) *http.Client {
+	t.Helper()
+	fileName := providerFileNamePrefix + "null_1.0.0_" + runtime.GOOS + "_" + runtime.GOARCH + ".zip"
+	return stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/providers/hashicorp/null/1.0.0/download/%s/%s", runtime.GOOS, runtime.GOARCH):
+			fmt.Fprintf(w, `{"filename":%q,"download_url":"https://releases.example.com/archive.zip","shasum":%q,
+				"shasums_url":"https://releases.example.com/SHA256SUMS","shasums_signature_url":"https://releases.example.com/SHA256SUMS.sig"}`,
+				fileName, sha256Hex(archive))
+		case "/archive.zip":
+			if r.Method == http.MethodGet && downloads != nil {
+				downloads.Add(1)
+			}
+			_, _ = w.Write(archive)
+		case "/SHA256SUMS":
+			fmt.Fprintf(w, "%s  other.zip\n%s  %s\n", sha256Hex([]byte("other")), sha256Hex(archive), fileName)
+		case "/SHA256SUMS.sigstore.json":
+			if bundle == nil {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(bundle)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestWithVerifier(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	archive := providerArchive(t, "null")
+	client := stubSignedRegistry(t, archive, nil, nil)
+
+	t.Run("passed", func(t *testing.T) {
+		var got Artifact
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client),
+			WithVerifier("skipped", func(Artifact) error { return ErrVerificationUnavailable }),
+			WithVerifier("internal", func(a Artifact) error {
+				got = a
+				data, err := os.ReadFile(a.Path)
+				require.NoError(t, err)
+				assert.Equal(t, archive, data)
+				return nil
+			}))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		require.NoError(t, s.Get(req))
+		assert.Equal(t, normalizedRequest(req), got.Request)
+		assert.Equal(t, sha256Hex(archive), got.SHA256)
+		assert.Equal(t, "https://releases.example.com/SHA256SUMS", got.SHASumsURL)
+		assert.Equal(t, "https://releases.example.com/SHA256SUMS.sig", got.SHASumsSignatureURL)
+		data, err := os.ReadFile(filepath.Join(cacheProviderDir(s.CacheDir(), normalizedRequest(req)), verifiedFileName))
+		require.NoError(t, err)
+		assert.Equal(t, "internal\n", string(data))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		errUnknown := errors.New("unknown checksum")
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client),
+			WithVerifier("internal", func(Artifact) error { return errUnknown }))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		err := s.Get(req)
+		require.ErrorIs(t, err, ErrVerificationFailed)
+		require.ErrorIs(t, err, errUnknown)
+		assert.ErrorContains(t, err, "hashicorp/null 1.0.0: internal: ")
+		assert.NoDirExists(t, cacheProviderDir(s.CacheDir(), normalizedRequest(req)))
+	})
+
+	t.Run("required but unavailable", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client), WithRequireVerification(true),
+			WithVerifier("internal", func(Artifact) error { return fmt.Errorf("%w: not listed", ErrVerificationUnavailable) }))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		err := s.Get(req)
+		require.ErrorIs(t, err, ErrVerificationFailed)
+		assert.ErrorContains(t, err, "no verification method passed")
+	})
+}
+
+func TestWithRequireVerification_CachedEntry(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	var downloads atomic.Int32
+	client := stubSignedRegistry(t, providerArchive(t, "null"), nil, &downloads)
+	cacheDir := t.TempDir()
+
+	s := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	require.NoError(t, s.Get(req))
+	require.EqualValues(t, 1, downloads.Load())
+
+	offline := NewServer(nil, WithCacheDir(cacheDir), WithOffline(true), WithRequireVerification(true))
+	t.Cleanup(func() { _ = offline.Cleanup() })
+	assert.ErrorIs(t, offline.Get(req), ErrVerificationFailed)
+
+	verified := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client), WithRequireVerification(true),
+		WithVerifier("internal", func(Artifact) error { return nil }))
+	t.Cleanup(func() { _ = verified.Cleanup() })
+	require.NoError(t, verified.Get(req))
+	assert.EqualValues(t, 2, downloads.Load(), "an unverified cache entry is downloaded again")
+
+	again := NewServer(nil, WithCacheDir(cacheDir), WithHTTPClient(client), WithRequireVerification(true))
+	t.Cleanup(func() { _ = again.Cleanup() })
+	require.NoError(t, again.Get(req))
+	assert.EqualValues(t, 2, downloads.Load(), "a verified cache entry is used")
+}
+
+func TestWithSigstoreVerification(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of cosign")
+	}
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0", RegistryType: RegistryTypeOpenTofu}
+	archive := providerArchive(t, "null")
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args")
+	cosign := filepath.Join(dir, "cosign")
+	require.NoError(t, os.WriteFile(cosign, fmt.Appendf(nil, "#!/bin/sh\necho \"$@\" > %s\n[ \"$(cat \"$3\")\" = bundle ] || { echo bad bundle; exit 1; }\n", argsPath), 0o755))
+	opts := SigstoreOptions{CosignPath: cosign, CertificateIdentityRegexp: "^https://github.com/hashicorp/"}
+
+	t.Run("verified", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubSignedRegistry(t, archive, []byte("bundle"), nil)),
+			WithSigstoreVerification(opts), WithRequireVerification(true))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		require.NoError(t, s.Get(req))
+		args, err := os.ReadFile(argsPath)
+		require.NoError(t, err)
+		assert.Regexp(t, `^verify-blob --bundle \S+ --certificate-identity-regexp \^https://github.com/hashicorp/ --certificate-oidc-issuer https://token.actions.githubusercontent.com \S+SHA256SUMS\n$`, string(args))
+	})
+
+	t.Run("rejected by cosign", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubSignedRegistry(t, archive, []byte("forged"), nil)),
+			WithSigstoreVerification(opts))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		err := s.Get(req)
+		require.ErrorIs(t, err, ErrVerificationFailed)
+		assert.ErrorContains(t, err, "sigstore: cosign verify-blob: exit status 1: bad bundle")
+	})
+
+	t.Run("archive not listed", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubSignedRegistry(t, archive, []byte("bundle"), nil)))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		a := Artifact{Request: normalizedRequest(req), Path: filepath.Join(t.TempDir(), "archive.zip"), FileName: "x.zip", SHA256: sha256Hex(archive), SHASumsURL: "https://releases.example.com/SHA256SUMS"}
+		assert.ErrorContains(t, s.verifySigstore(a, opts), "does not list x.zip")
+	})
+
+	t.Run("no identity configured", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubSignedRegistry(t, archive, []byte("bundle"), nil)),
+			WithSigstoreVerification(SigstoreOptions{CosignPath: cosign}))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		err := s.Get(req)
+		require.ErrorIs(t, err, ErrVerificationFailed)
+		assert.ErrorContains(t, err, "no certificate identity is configured")
+	})
+
+	t.Run("no bundle published", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubSignedRegistry(t, archive, nil, nil)),
+			WithSigstoreVerification(opts))
+		t.Cleanup(func() { _ = s.Cleanup() })
+		require.NoError(t, s.Get(req))
+		assert.NoFileExists(t, filepath.Join(cacheProviderDir(s.CacheDir(), normalizedRequest(req)), verifiedFileName))
+	})
+}
+
+// stubGPGRegistry serves the null provider with a checksum list, its
+// detached signature by signer, and the public keys of keys as the
+// registry's signing keys.
+func stubGPGRegistry(t *testing.T, archive []byte, signer *openpgp.Entity, keys ...*openpgp.Entity) *http.Client {
+	t.Helper()
+	fileName := providerFileNamePrefix + "null_1.0.0_" + runtime.GOOS + "_" + runtime.GOARCH + ".zip"
+	sums := fmt.Appendf(nil, "%s  %s\n", sha256Hex(archive), fileName)
+	var signature bytes.Buffer
+	require.NoError(t, openpgp.DetachSign(&signature, signer, bytes.NewReader(sums), nil))
+	type gpgKey struct {
+		KeyID      string `json:"key_id"`
+		ASCIIArmor string `json:"ascii_armor"`
+	}
+	var published []gpgKey
+	for _, k := range keys {
+		published = append(published, gpgKey{KeyID: k.PrimaryKey.KeyIdString(), ASCIIArmor: armoredPublicKey(t, k)})
+	}
+	signingKeys, err := json.Marshal(map[string]any{"gpg_public_keys": published})
+	require.NoError(t, err)
+	return stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/providers/hashicorp/null/1.0.0/download/%s/%s", runtime.GOOS, runtime.GOARCH):
+			fmt.Fprintf(w, `{"filename":%q,"download_url":"https://releases.example.com/archive.zip","shasum":%q,
+				"shasums_url":"https://releases.example.com/SHA256SUMS","shasums_signature_url":"https://releases.example.com/SHA256SUMS.sig",
+				"signing_keys":%s}`, fileName, sha256Hex(archive), signingKeys)
+		case "/archive.zip":
+			_, _ = w.Write(archive)
+		case "/SHA256SUMS":
+			_, _ = w.Write(sums)
+		case "/SHA256SUMS.sig":
+			_, _ = w.Write(signature.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func armoredPublicKey(t *testing.T, e *openpgp.Entity) string {
+	t.Helper()
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	require.NoError(t, w.Close())
+	return armored.String()
+}
+
+func TestWithGPGVerification(t *testing.T) {
+	req := Request{Namespace: "hashicorp", Name: "null", Version: "1.0.0"}
+	archive := providerArchive(t, "null")
+	signer, err := openpgp.NewEntity("Example", "", "security@example.com", nil)
+	require.NoError(t, err)
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	require.NoError(t, err)
+
+	t.Run("verified", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubGPGRegistry(t, archive, signer, other, signer)),
+			WithGPGVerification(), WithRequireVerification(true))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		require.NoError(t, s.Get(req))
+		data, err := os.ReadFile(filepath.Join(cacheProviderDir(s.CacheDir(), normalizedRequest(req)), verifiedFileName))
+		require.NoError(t, err)
+		assert.Equal(t, "gpg\n", string(data))
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubGPGRegistry(t, archive, other, signer)),
+			WithGPGVerification())
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		err := s.Get(req)
+		require.ErrorIs(t, err, ErrVerificationFailed)
+		assert.ErrorContains(t, err, "gpg: checksum list signature does not verify")
+	})
+
+	t.Run("archive not listed", func(t *testing.T) {
+		s := NewServer(nil, WithHTTPClient(stubGPGRegistry(t, archive, signer, signer)))
+		t.Cleanup(func() { _ = s.Cleanup() })
+
+		a := Artifact{FileName: "x.zip", SHA256: sha256Hex(archive), SHASumsURL: "https://releases.example.com/SHA256SUMS",
+			SHASumsSignatureURL: "https://releases.example.com/SHA256SUMS.sig", GPGPublicKeys: []string{armoredPublicKey(t, signer)}}
+		assert.ErrorContains(t, s.verifyGPG(a), "does not list x.zip")
+	})
+
+	t.Run("no keys reported", func(t *testing.T) {
+		s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubGPGRegistry(t, archive, signer)), WithGPGVerification())
+		t.Cleanup(func() { _ = s.Cleanup() })
+		require.NoError(t, s.Get(req))
+		assert.NoFileExists(t, filepath.Join(cacheProviderDir(s.CacheDir(), normalizedRequest(req)), verifiedFileName))
+
+		required := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(stubGPGRegistry(t, archive, signer)),
+			WithGPGVerification(), WithRequireVerification(true))
+		t.Cleanup(func() { _ = required.Cleanup() })
+		assert.ErrorIs(t, required.Get(req), ErrVerificationFailed)
+	})
+}
+
+func TestChecksumListed(t *testing.T) {
+	sums := []byte("AABB  a.zip\nccdd *b.zip\nmalformed\n")
+	assert.True(t, checksumListed(sums, "aabb", "a.zip"))
+	assert.True(t, checksumListed(sums, "ccdd", "b.zip"))
+	assert.False(t, checksumListed(sums, "aabb", "b.zip"))
+	assert.False(t, checksumListed(sums, "malformed", ""))
+}
@@ -0,0 +1,261 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// VersionAliases names versions of providers, so that lists of providers to
+// fetch can refer to them as "aws@current" and "aws@next" and be updated in
+// one place. Keys are provider sources, in any form ParseProviderSource
+// accepts, with an "@" and the alias name; values are versions or version
+// constraints:
+//
+//	VersionAliases{
+//		"hashicorp/aws@current": "5.40.0",
+//		"aws@next":              "~> 6.0",
+//	}
+//
+// A source without a registry host matches the provider in any registry.
+type VersionAliases map[string]string
+
+// Validate reports the first malformed entry: a key that is not a provider
+// source followed by an alias name, an alias name that would be read as a
+// version constraint, or a value that is not a version constraint.
+func (a VersionAliases) Validate() error {
+	for _, key := range slices.Sorted(maps.Keys(a)) {
+		if _, _, err := parseVersionAliasKey(key); err != nil {
+			return err
+		}
+		if _, err := goversion.NewConstraint(a[key]); err != nil {
+			return fmt.Errorf("invalid version alias %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ParseRequest is the package's ParseRequest, except that a suffix naming
+// an alias of the provider, as in "aws@current", is replaced with the
+// version it stands for. Other suffixes are parsed as usual.
+func (a VersionAliases) ParseRequest(s string) (Request, error) {
+	source, alias, ok := strings.Cut(strings.TrimSpace(s), "@")
+	if !ok {
+		return ParseRequest(s)
+	}
+	req, err := ParseProviderSource(source)
+	if err != nil {
+		return Request{}, err
+	}
+	alias = strings.TrimSpace(alias)
+	for _, key := range slices.Sorted(maps.Keys(a)) {
+		from, name, err := parseVersionAliasKey(key)
+		if err != nil || !strings.EqualFold(name, alias) {
+			continue
+		}
+		if !strings.EqualFold(from.Namespace, req.Namespace) || !strings.EqualFold(from.Name, req.Name) {
+			continue
+		}
+		if from.RegistryType != "" && req.RegistryType != "" && from.RegistryType != req.RegistryType {
+			continue
+		}
+		req.Version = strings.TrimSpace(a[key])
+		return req, nil
+	}
+	return ParseRequest(s)
+}
+
+// parseVersionAliasKey splits a VersionAliases key into its provider and
+// alias name.
+func parseVersionAliasKey(key string) (Request, string, error) {
+	source, name, ok := strings.Cut(key, "@")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return Request{}, "", fmt.Errorf("invalid version alias %q: expected source@name", key)
+	}
+	if _, err := goversion.NewConstraint(name); err == nil {
+		return Request{}, "", fmt.Errorf("invalid version alias %q: %q is a version constraint", key, name)
+	}
+	req, err := ParseProviderSource(source)
+	if err != nil {
+		return Request{}, "", fmt.Errorf("invalid version alias %q: %w", key, err)
+	}
+	return req, name, nil
+}
+
+// VersionComparison lays out the entries of several versions of a provider
+// side by side. See Server.CompareVersions.
+type VersionComparison struct {
+	// Labels name the compared versions, in the order given.
+	Labels []string `json:"labels"`
+	// Versions are the concrete versions the requests resolved to, in the
+	// same order.
+	Versions []string `json:"versions"`
+	// Entries are the resources, data sources, ephemeral resources and
+	// functions of any of the versions, sorted by kind and name, with the
+	// provider configuration first.
+	Entries []ComparedEntry `json:"entries"`
+}
+
+// ComparedEntry is one entry of a VersionComparison.
+type ComparedEntry struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name,omitempty"`
+	// Present reports, for each compared version, whether it has the entry.
+	Present []bool `json:"present"`
+	// Changed is set when the entry's schema, normalized as by Normalize,
+	// differs between the versions that have it.
+	Changed bool `json:"changed"`
+}
+
+// Same reports whether every compared version has the entry with the same
+// schema.
+func (e ComparedEntry) Same() bool {
+	return !e.Changed && !slices.Contains(e.Present, false)
+}
+
+// Differences returns the entries that are not the same in every version.
+func (c *VersionComparison) Differences() []ComparedEntry {
+	var diffs []ComparedEntry
+	for _, e := range c.Entries {
+		if !e.Same() {
+			diffs = append(diffs, e)
+		}
+	}
+	return diffs
+}
+
+// CompareVersions fetches the providers of requests, usually versions of
+// the same provider, at the same time, and compares their schemas entry by
+// entry. labels name the versions in the result, such as the alias names
+// the requests were written with; if nil, the resolved versions are used.
+// Every version is kept in the Server's caches, so the schemas can then be
+// read side by side with the other methods.
+func (s *Server) CompareVersions(labels []string, requests []Request) (*VersionComparison, error) {
+	if labels != nil && len(labels) != len(requests) {
+		return nil, fmt.Errorf("got %d labels for %d requests", len(labels), len(requests))
+	}
+	result, err := s.Warm(requests, WithBatchConcurrency(len(requests)))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &VersionComparison{Labels: labels, Versions: make([]string, len(requests))}
+	schemas := make([]*tfjson.ProviderSchema, len(requests))
+	for i, item := range result.Items {
+		request := item.Request
+		request.Version = item.Version
+		request, err := s.prepareRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		ls, err := s.getSchema(request)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := ls.providerSchema()
+		if err != nil {
+			return nil, err
+		}
+		c.Versions[i], schemas[i] = request.Version, ps
+	}
+	if c.Labels == nil {
+		c.Labels = slices.Clone(c.Versions)
+	}
+
+	config := make([][]byte, len(schemas))
+	for i, ps := range schemas {
+		if ps.ConfigSchema != nil {
+			if config[i], err = marshalCanonicalSchema(ps.ConfigSchema); err != nil {
+				return nil, fmt.Errorf("failed to encode provider schema: %w", err)
+			}
+		}
+	}
+	c.Entries = append(c.Entries, compareEntry(KindProviderConfig, "", config))
+	for _, kind := range []Kind{KindResource, KindDataSource, KindEphemeralResource, KindFunction} {
+		names := make(map[string]bool)
+		for _, ps := range schemas {
+			for name := range entriesOfKind(ps, kind) {
+				names[name] = true
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(names)) {
+			encoded := make([][]byte, len(schemas))
+			for i, ps := range schemas {
+				if encoded[i], err = encodeEntry(ps, kind, name); err != nil {
+					return nil, fmt.Errorf("failed to encode %s %s: %w", kind.label(), name, err)
+				}
+			}
+			c.Entries = append(c.Entries, compareEntry(kind, name, encoded))
+		}
+	}
+	return c, nil
+}
+
+// entriesOfKind returns the names of the entries of ps of kind.
+func entriesOfKind(ps *tfjson.ProviderSchema, kind Kind) map[string]bool {
+	names := make(map[string]bool)
+	if kind == KindFunction {
+		for name, f := range ps.Functions {
+			names[name] = f != nil
+		}
+	} else {
+		for name, schema := range schemaMapOfKind(ps, kind) {
+			names[name] = schema != nil
+		}
+	}
+	maps.DeleteFunc(names, func(_ string, present bool) bool { return !present })
+	return names
+}
+
+// schemaMapOfKind returns the schemas of ps of kind, which must not be
+// KindFunction.
+func schemaMapOfKind(ps *tfjson.ProviderSchema, kind Kind) map[string]*tfjson.Schema {
+	switch kind {
+	case KindResource:
+		return ps.ResourceSchemas
+	case KindDataSource:
+		return ps.DataSourceSchemas
+	case KindEphemeralResource:
+		return ps.EphemeralResourceSchemas
+	}
+	return nil
+}
+
+// encodeEntry returns the canonical encoding of the entry of ps, or nil if
+// ps does not have it.
+func encodeEntry(ps *tfjson.ProviderSchema, kind Kind, name string) ([]byte, error) {
+	if kind == KindFunction {
+		if f := ps.Functions[name]; f != nil {
+			return marshalCanonicalFunction(f)
+		}
+		return nil, nil
+	}
+	if schema := schemaMapOfKind(ps, kind)[name]; schema != nil {
+		return marshalCanonicalSchema(schema)
+	}
+	return nil, nil
+}
+
+// compareEntry compares the encodings of one entry in each version, nil
+// where a version does not have it.
+func compareEntry(kind Kind, name string, encoded [][]byte) ComparedEntry {
+	e := ComparedEntry{Kind: kind, Name: name, Present: make([]bool, len(encoded))}
+	var first []byte
+	for i, data := range encoded {
+		if data == nil {
+			continue
+		}
+		e.Present[i] = true
+		if first == nil {
+			first = data
+		} else if string(data) != string(first) {
+			e.Changed = true
+		}
+	}
+	return e
+}
@@ -0,0 +1,101 @@
+package tfpluginschema
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestVersionAliases_ParseRequest(t *testing.T) {
+	aliases := VersionAliases{
+		"hashicorp/aws@current": "5.40.0",
+		"aws@next":              "~> 6.0",
+		"registry.opentofu.org/hashicorp/null@pinned": "3.2.2",
+	}
+	require.NoError(t, aliases.Validate())
+
+	tests := []struct {
+		source string
+		want   Request
+	}{
+		{"hashicorp/aws@current", Request{Namespace: "hashicorp", Name: "aws", Version: "5.40.0"}},
+		{"aws@Next", Request{Namespace: "hashicorp", Name: "aws", Version: "~> 6.0"}},
+		{"registry.terraform.io/hashicorp/aws@next", Request{Namespace: "hashicorp", Name: "aws", Version: "~> 6.0", RegistryType: RegistryTypeTerraform}},
+		{"hashicorp/aws@5.0.0", Request{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}},
+		{"hashicorp/null@pinned", Request{Namespace: "hashicorp", Name: "null", Version: "3.2.2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := aliases.ParseRequest(tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := aliases.ParseRequest("hashicorp/azurerm@current")
+	assert.Error(t, err, "aliases are per provider")
+	_, err = aliases.ParseRequest("registry.terraform.io/hashicorp/null@pinned")
+	assert.Error(t, err, "aliases with a host are per registry")
+}
+
+func TestVersionAliases_Validate(t *testing.T) {
+	for key, value := range map[string]string{
+		"hashicorp/aws":         "5.0.0",
+		"hashicorp/aws@":        "5.0.0",
+		"hashicorp/aws@5.0.0":   "5.0.0",
+		"a/b/c/d@current":       "5.0.0",
+		"hashicorp/aws@current": "five",
+	} {
+		assert.Error(t, VersionAliases{key: value}.Validate(), key)
+	}
+}
+
+func TestServer_CompareVersions(t *testing.T) {
+	attr := func(t cty.Type) *tfjson.SchemaAttribute {
+		return &tfjson.SchemaAttribute{AttributeType: t, Optional: true}
+	}
+	schema := func(attrs map[string]*tfjson.SchemaAttribute) *tfjson.Schema {
+		return &tfjson.Schema{Block: &tfjson.SchemaBlock{Attributes: attrs}}
+	}
+	current := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+	next := Request{Namespace: "example", Name: "example", Version: "2.0.0"}
+	s := serverWithSchema(t, current, newConvertedSchema(&tfjson.ProviderSchema{
+		ConfigSchema: schema(map[string]*tfjson.SchemaAttribute{"region": attr(cty.String)}),
+		ResourceSchemas: map[string]*tfjson.Schema{
+			"example_db":  schema(map[string]*tfjson.SchemaAttribute{"name": attr(cty.String)}),
+			"example_old": schema(nil),
+		},
+	}))
+	s.storeSchema(next, cacheKey(next), newConvertedSchema(&tfjson.ProviderSchema{
+		ConfigSchema: schema(map[string]*tfjson.SchemaAttribute{"region": attr(cty.String)}),
+		ResourceSchemas: map[string]*tfjson.Schema{
+			"example_db":  schema(map[string]*tfjson.SchemaAttribute{"name": attr(cty.Number)}),
+			"example_new": schema(nil),
+		},
+	}))
+
+	c, err := s.CompareVersions([]string{"current", "next"}, []Request{current, next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"current", "next"}, c.Labels)
+	assert.Equal(t, []string{"1.0.0", "2.0.0"}, c.Versions)
+	assert.Equal(t, []ComparedEntry{
+		{Kind: KindProviderConfig, Present: []bool{true, true}},
+		{Kind: KindResource, Name: "example_db", Present: []bool{true, true}, Changed: true},
+		{Kind: KindResource, Name: "example_new", Present: []bool{false, true}},
+		{Kind: KindResource, Name: "example_old", Present: []bool{true, false}},
+	}, c.Entries)
+	assert.Equal(t, c.Entries[1:], c.Differences())
+
+	c, err = s.CompareVersions(nil, []Request{current, current})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.0.0"}, c.Labels)
+	assert.Empty(t, c.Differences())
+
+	_, err = s.CompareVersions([]string{"current"}, []Request{current, next})
+	assert.Error(t, err)
+	_, err = s.CompareVersions(nil, []Request{current, {Namespace: "example", Name: "example", Version: "3.0.0"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// Platform is an operating system and architecture a provider release is
+// built for, as listed by the registry.
+type Platform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// String returns the platform in the form used by provider archive names,
+// such as "linux_amd64".
+func (p Platform) String() string {
+	return p.OS + "_" + p.Arch
+}
+
+// CurrentPlatform returns the platform of the running program, which is the
+// one Server downloads providers for.
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// VersionDetails describes one version of a provider; see GetVersionDetails.
+type VersionDetails struct {
+	Version *goversion.Version
+	// Protocols are the plugin protocol versions the release supports,
+	// such as "5.0", and Platforms the platforms it is built for, as listed
+	// by the registry. Both are empty if the registry does not list them
+	// or the versions were not fetched from the registry.
+	Protocols []string
+	Platforms []Platform
+	// Published is the Last-Modified time of the archive for the current
+	// platform, and Size its length in bytes. They are only set with
+	// WithDownloadDetails; Published is zero and Size -1 when unknown.
+	Published time.Time
+	Size      int64
+}
+
+// SupportsPlatform reports whether the registry lists a build of the version
+// for p. It also reports true if the registry lists no platforms.
+func (d VersionDetails) SupportsPlatform(p Platform) bool {
+	return len(d.Platforms) == 0 || slices.Contains(d.Platforms, p)
+}
+
+// WithDownloadDetails makes GetVersionDetails also query the download
+// metadata and archive of every version for the current platform, to set
+// Published and Size. This costs two requests per version, so combine it
+// with WithVersionsLimit for providers with many releases. Versions without
+// a build for the current platform are not queried.
+func WithDownloadDetails() VersionsOption {
+	return func(o *versionsOptions) {
+		o.downloadDetails = true
+	}
+}
+
+// GetVersionDetails returns details of the provider's available versions
+// that satisfy constraints, sorted in ascending order like
+// GetAvailableVersionsMatching and accepting the same options. The
+// protocols and platforms come with the versions list; WithDownloadDetails
+// adds the publication time and size of each release.
+func (s *Server) GetVersionDetails(req VersionsRequest, constraints goversion.Constraints, opts ...VersionsOption) ([]VersionDetails, error) {
+	var o versionsOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	versions, err := s.GetAvailableVersionsMatching(req, constraints, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// GetAvailableVersionsMatching has validated req.
+	req, _ = s.prepareVersionsRequest(req)
+	key := versionsCacheKey(req)
+
+	s.mu.RLock()
+	listed := s.listed[key]
+	s.mu.RUnlock()
+
+	details := make([]VersionDetails, len(versions))
+	for i, v := range versions {
+		lv := listed[v.String()]
+		details[i] = VersionDetails{Version: v, Protocols: lv.protocols, Platforms: lv.platforms, Size: -1}
+		if !o.downloadDetails || !details[i].SupportsPlatform(CurrentPlatform()) {
+			continue
+		}
+		if err := s.fetchDownloadDetails(req, &details[i]); err != nil {
+			return nil, err
+		}
+	}
+	return details, nil
+}
+
+// fetchDownloadDetails sets the Published and Size of d from the download
+// metadata and archive of its version for the current platform.
+func (s *Server) fetchDownloadDetails(req VersionsRequest, d *VersionDetails) error {
+	if s.offline {
+		return fmt.Errorf("%w: download details of %s/%s %s", ErrOffline, req.Namespace, req.Name, d.Version.Original())
+	}
+	request := Request{Namespace: req.Namespace, Name: req.Name, Version: d.Version.Original(), RegistryType: req.RegistryType}
+	rl := s.logger(logComponentRegistry).With(s.requestLogAttrs(request)...)
+	pluginResponse, err := s.fetchDownloadMetadata(request, rl)
+	if err != nil {
+		return err
+	}
+	info, err := s.probeDownload(pluginResponse.DownloadURL)
+	if err != nil {
+		return err
+	}
+	d.Published, d.Size = info.modified, info.size
+	return nil
+}
+
+// storeListedVersions records the protocols and platforms of the entries of
+// a versions list fetched for key.
+func (s *Server) storeListedVersions(key providerKey, listed []listedVersion) {
+	byVersion := make(map[string]listedVersion, len(listed))
+	for _, lv := range listed {
+		byVersion[lv.version.String()] = lv
+	}
+	s.mu.Lock()
+	s.listed[key] = byVersion
+	s.mu.Unlock()
+}
@@ -0,0 +1,83 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_GetVersionDetails(t *testing.T) {
+	published := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var downloads []string
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/example/detailed/versions":
+			fmt.Fprintf(w, `{"versions":[
+				{"version":"1.0.0","protocols":["5.0"],"platforms":[{"os":%[1]q,"arch":%[2]q},{"os":"plan9","arch":"386"}]},
+				{"version":"2.0.0","protocols":["6.0"],"platforms":[{"os":"plan9","arch":"386"}]},
+				{"version":"3.0.0","protocols":"bad"}
+			]}`, runtime.GOOS, runtime.GOARCH)
+		case "/v1/providers/example/detailed/1.0.0/download/" + runtime.GOOS + "/" + runtime.GOARCH,
+			"/v1/providers/example/detailed/3.0.0/download/" + runtime.GOOS + "/" + runtime.GOARCH:
+			downloads = append(downloads, r.URL.Path)
+			fmt.Fprint(w, `{"filename":"terraform-provider-detailed.zip","download_url":"https://releases.example.com/detailed.zip"}`)
+		case "/detailed.zip":
+			w.Header().Set("Content-Length", "2048")
+			w.Header().Set("Last-Modified", published.Format(http.TimeFormat))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := VersionsRequest{Namespace: "example", Name: "detailed"}
+
+	details, err := s.GetVersionDetails(req, nil)
+	require.NoError(t, err)
+	require.Len(t, details, 3)
+	assert.Equal(t, "1.0.0", details[0].Version.String())
+	assert.Equal(t, []string{"5.0"}, details[0].Protocols)
+	assert.Equal(t, []Platform{CurrentPlatform(), {OS: "plan9", Arch: "386"}}, details[0].Platforms)
+	assert.True(t, details[0].SupportsPlatform(CurrentPlatform()))
+	assert.False(t, details[1].SupportsPlatform(CurrentPlatform()))
+	assert.Empty(t, details[2].Protocols, "malformed details are ignored")
+	assert.True(t, details[2].SupportsPlatform(CurrentPlatform()), "no platforms listed")
+	for _, d := range details {
+		assert.Zero(t, d.Published)
+		assert.EqualValues(t, -1, d.Size)
+	}
+	assert.Empty(t, downloads)
+
+	constraints, err := goversion.NewConstraint("< 3.0.0")
+	require.NoError(t, err)
+	details, err = s.GetVersionDetails(req, constraints, WithDownloadDetails())
+	require.NoError(t, err)
+	require.Len(t, details, 2)
+	assert.Equal(t, published, details[0].Published.UTC())
+	assert.EqualValues(t, 2048, details[0].Size)
+	assert.Zero(t, details[1].Published, "no build for the current platform")
+	assert.EqualValues(t, -1, details[1].Size)
+	assert.Len(t, downloads, 1)
+
+	details, err = s.GetVersionDetails(req, nil, WithVersionsLimit(1), WithDownloadDetails())
+	require.NoError(t, err)
+	require.Len(t, details, 1)
+	assert.Equal(t, "3.0.0", details[0].Version.String())
+	assert.EqualValues(t, 2048, details[0].Size)
+
+	// The details go with the versions list.
+	require.NoError(t, s.CleanupRequest(Request{Namespace: "example", Name: "detailed"}))
+	s.mu.RLock()
+	assert.Empty(t, s.listed)
+	s.mu.RUnlock()
+}
+
+func TestPlatform_String(t *testing.T) {
+	assert.Equal(t, "linux_amd64", Platform{OS: "linux", Arch: "amd64"}.String())
+}
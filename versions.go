@@ -1,8 +1,9 @@
 package tfpluginschema
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"slices"
 	"strings"
@@ -14,6 +15,10 @@ const (
 	pluginApiVersions = "versions"
 )
 
+// ErrNoMatchingVersion is returned when none of a provider's versions satisfy
+// the requested version constraint.
+var ErrNoMatchingVersion = fmt.Errorf("no matching version found")
+
 type pluginApiVersionsResponse struct {
 	Versions []struct {
 		Version string `json:"version"`
@@ -26,10 +31,8 @@ type VersionsRequest struct {
 	RegistryType RegistryType // Registry to use (defaults to OpenTofu if not specified)
 }
 
-// String returns a best-effort URL for the versions endpoint. It does not
-// panic on invalid input; callers using the public Server API go through
-// validateVersionsRequest, which rejects unsafe namespace/name values before
-// a URL is ever constructed.
+// String returns the URL of the versions endpoint for display. It does not
+// validate the request; use URL for that.
 func (v VersionsRequest) String() string {
 	sb := strings.Builder{}
 	sb.WriteString(v.RegistryType.BaseURL())
@@ -42,9 +45,18 @@ func (v VersionsRequest) String() string {
 	return sb.String()
 }
 
+// URL returns the versions endpoint URL, or an error if Namespace or Name is
+// empty or contains characters that are not safe in a URL path segment.
+func (v VersionsRequest) URL() (string, error) {
+	if err := validateVersionsRequest(v); err != nil {
+		return "", fmt.Errorf("invalid versions request: %w", err)
+	}
+	return v.String(), nil
+}
+
 // validateVersionsRequest ensures namespace/name are non-empty and URL/path
 // safe. It mirrors the identity-validation rules applied by Server.Get so
-// that VersionsRequest.String() segments never need URL-escaping and can't
+// that VersionsRequest.URL() segments never need URL-escaping and can't
 // alter URL semantics.
 func validateVersionsRequest(req VersionsRequest) error {
 	if err := validateCachePathComponent("namespace", req.Namespace, true); err != nil {
@@ -57,67 +69,236 @@ func validateVersionsRequest(req VersionsRequest) error {
 // It caches the results to avoid redundant network calls.
 // It returns a sorted collection of versions.
 func (s *Server) GetAvailableVersions(req VersionsRequest) (goversion.Collection, error) {
+	req, err := s.prepareVersionsRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if s.sources != nil {
+		return s.sourceVersions(req)
+	}
+	return s.registryVersions(req)
+}
+
+// prepareVersionsRequest resolves aliases in req, validates it and
+// normalizes its RegistryType.
+func (s *Server) prepareVersionsRequest(req VersionsRequest) (VersionsRequest, error) {
+	var err error
+	if req.Namespace, req.Name, err = s.resolveProviderAlias(req.Namespace, req.Name); err != nil {
+		return req, err
+	}
+
 	if err := validateVersionsRequest(req); err != nil {
-		return nil, fmt.Errorf("invalid versions request: %w", err)
+		return req, fmt.Errorf("invalid versions request: %w", err)
 	}
 
 	// Normalize RegistryType so empty/unknown values share the same
 	// cache key as the registry they actually resolve to via BaseURL
 	// (OpenTofu). Without this, a caller passing an unknown RegistryType
 	// would still hit OpenTofu but cache under a distinct key, producing
-	// avoidable cache misses and duplicate network calls.
-	req.RegistryType = normalizedRegistryType(req.RegistryType)
+	// avoidable cache misses and duplicate network calls. The key also
+	// folds namespace/name case, as registries do.
+	req.RegistryType = normalizedRegistryType(s.registryOrDefault(req.RegistryType))
+	if err := s.checkProviderRules(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType}); err != nil {
+		return req, err
+	}
+	return req, nil
+}
 
-	l := s.l.With("request_namespace", req.Namespace, "request_name", req.Name)
+// registryVersions returns the versions of req, which must be validated and
+// normalized, from the in-memory cache or the registry.
+func (s *Server) registryVersions(req VersionsRequest) (goversion.Collection, error) {
+	key := versionsCacheKey(req)
+
+	l := s.logger(logComponentRegistry).With(s.requestLogAttrs(Request{Namespace: req.Namespace, Name: req.Name, RegistryType: req.RegistryType})...)
 
 	s.mu.RLock()
-	if v, ok := s.versionsc[req]; ok {
+	if v, ok := s.versionsc[key]; ok {
 		s.mu.RUnlock()
-		l.Info("Request already exists in download cache")
+		l.Debug("Versions served from in-memory cache")
 		return v, nil
 	}
 	s.mu.RUnlock()
+	if err, ok := s.cachedNotFound(key); ok {
+		l.Debug("Provider not found, served from in-memory cache")
+		return nil, err
+	}
 
-	var result pluginApiVersionsResponse
-
-	versionRequest, err := http.NewRequest(http.MethodGet, req.String(), nil)
+	// Concurrent lookups of the same provider share one registry request,
+	// so that they also agree on the latest version.
+	versions, err, _ := s.versions.Do(key.String(), func() (any, error) {
+		return s.fetchVersions(req, key, l)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request for versions: %w", err)
+		return nil, err
+	}
+	return versions.(goversion.Collection), nil
+}
+
+// storeVersions records versions under key unless another goroutine got
+// there first, and returns the recorded collection.
+func (s *Server) storeVersions(key providerKey, versions goversion.Collection) goversion.Collection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.versionsc[key]; ok {
+		return existing
 	}
+	s.versionsc[key] = versions
+	return versions
+}
 
-	resp, err := s.httpClient.Do(versionRequest)
+// fetchVersions retrieves the versions of req from the registry, or from
+// the provider cache when offline, and records them under key.
+func (s *Server) fetchVersions(req VersionsRequest, key providerKey, l *slog.Logger) (goversion.Collection, error) {
+	// A lookup that finished after the cache check in GetAvailableVersions
+	// has already recorded the versions.
+	s.mu.RLock()
+	v, ok := s.versionsc[key]
+	s.mu.RUnlock()
+	if ok {
+		return v, nil
+	}
+
+	u, err := req.URL()
+	if err != nil {
+		return nil, err
+	}
+	if u, err = s.registryURL(req.RegistryType, u); err != nil {
+		return nil, err
+	}
+	body, status, err := s.registryGet(u)
+	if errors.Is(err, ErrOffline) {
+		versions, cerr := s.cachedVersions(req)
+		if cerr != nil {
+			return nil, cerr
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("failed to get versions: %w", err)
+		}
+		l.Debug("Versions served from the provider cache while offline", "count", len(versions))
+		return s.storeVersions(key, versions), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get versions: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get versions: %s => %d", req.String(), resp.StatusCode)
+	if status == http.StatusNotFound {
+		err := fmt.Errorf("failed to get versions: %w: %s => %d", ErrPluginNotFound, u, status)
+		s.storeNotFound(key, err)
+		return nil, err
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode versions response: %w", err)
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to get versions: %s => %d", u, status)
 	}
 
-	var versions goversion.Collection
-	for _, v := range result.Versions {
-		ver, err := goversion.NewVersion(v.Version)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse version %q: %w", v.Version, err)
-		}
-		versions = append(versions, ver)
+	listed, err := s.versionPages(u, body, l)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(goversion.Collection, len(listed))
+	for i, lv := range listed {
+		versions[i] = lv.version
 	}
 
 	slices.SortFunc(versions, func(a, b *goversion.Version) int {
 		return a.Compare(b)
 	})
+	versions = slices.CompactFunc(versions, (*goversion.Version).Equal)
+	s.storeListedVersions(key, listed)
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.versionsc[req] = versions
+	if len(versions) > 0 {
+		l.Info("Fetched available versions", "count", len(versions), "latest", versions[len(versions)-1].String())
+	}
+
+	versions = s.storeVersions(key, versions)
+	s.noteVersions(req, key, versions)
 	return versions, nil
 }
 
+// maxVersionPages bounds the number of pages of a paginated versions list
+// that are fetched, in case a registry keeps linking to further pages.
+const maxVersionPages = 100
+
+// versionPages parses body, the versions list fetched from u, and fetches
+// and parses the pages it links to through "meta.next_url", returning the
+// entries of every page.
+func (s *Server) versionPages(u string, body []byte, l *slog.Logger) ([]listedVersion, error) {
+	var versions []listedVersion
+	seen := map[string]struct{}{u: {}}
+	for page := 1; ; page++ {
+		pageVersions, next, err := parseVersionsResponse(body, s.skipInvalidVersions, l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get versions: %s: %w", u, err)
+		}
+		versions = append(versions, pageVersions...)
+		if next == "" {
+			return versions, nil
+		}
+
+		if u, err = nextPageURL(u, next); err != nil {
+			return nil, fmt.Errorf("failed to get versions: %w", err)
+		}
+		if _, ok := seen[u]; ok {
+			return nil, fmt.Errorf("failed to get versions: %w: registry versions list links back to page %s", ErrInvalidRegistryResponse, u)
+		}
+		if page == maxVersionPages {
+			return nil, fmt.Errorf("failed to get versions: %w: registry versions list has more than %d pages", ErrInvalidRegistryResponse, maxVersionPages)
+		}
+		seen[u] = struct{}{}
+
+		l.Debug("Fetching next page of versions", "url", u)
+		var status int
+		if body, status, err = s.registryGet(u); err != nil {
+			return nil, fmt.Errorf("failed to get versions: %w", err)
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to get versions: %s => %d", u, status)
+		}
+	}
+}
+
+// VersionsOption configures GetAvailableVersionsMatching.
+type VersionsOption func(*versionsOptions)
+
+type versionsOptions struct {
+	limit           int
+	downloadDetails bool
+}
+
+// WithVersionsLimit caps the result of GetAvailableVersionsMatching to the n
+// newest matching versions. n <= 0 means no cap.
+func WithVersionsLimit(n int) VersionsOption {
+	return func(o *versionsOptions) {
+		o.limit = n
+	}
+}
+
+// GetAvailableVersionsMatching returns the provider's available versions
+// that satisfy constraints, sorted in ascending order like
+// GetAvailableVersions. Nil or empty constraints match every version. The
+// result is empty, not an error, when no version matches.
+func (s *Server) GetAvailableVersionsMatching(req VersionsRequest, constraints goversion.Constraints, opts ...VersionsOption) (goversion.Collection, error) {
+	var o versionsOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	versions, err := s.GetAvailableVersions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := goversion.Collection{}
+	for _, v := range versions {
+		if constraints.Len() == 0 || constraints.Check(v) {
+			matching = append(matching, v)
+		}
+	}
+	if o.limit > 0 && len(matching) > o.limit {
+		matching = matching[len(matching)-o.limit:]
+	}
+	return matching, nil
+}
+
 // GetLatestVersionMatch returns the latest version from the provided collection that matches the given constraints.
 // The versions collection must be sorted in ascending order.
 // If no versions match the constraints, an error is returned.
@@ -147,8 +328,72 @@ func GetLatestVersionMatch(versions goversion.Collection, constraints goversion.
 	}
 
 	if lastGood == nil {
-		return nil, fmt.Errorf("no matching version found")
+		return nil, ErrNoMatchingVersion
 	}
 
 	return lastGood, nil
 }
+
+// PessimisticPatchConstraint returns the constraint "~> X.Y.0" admitting
+// every patch release of the minor version X.Y named by version, e.g. "5.40"
+// or "5.40.2". A missing minor component is taken as 0.
+func PessimisticPatchConstraint(version string) (string, error) {
+	v, err := goversion.NewVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	seg := v.Segments()
+	return fmt.Sprintf("~> %d.%d.0", seg[0], seg[1]), nil
+}
+
+// PessimisticMinorConstraint returns the constraint "~> X.0" admitting
+// every minor and patch release of the major version X named by version,
+// e.g. "5" or "5.40.2".
+func PessimisticMinorConstraint(version string) (string, error) {
+	v, err := goversion.NewVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	return fmt.Sprintf("~> %d.0", v.Segments()[0]), nil
+}
+
+// LatestPatchOf returns the newest release of the provider within the minor
+// version named by minor (e.g. "5.40"), as selected by
+// PessimisticPatchConstraint. ErrNoMatchingVersion is returned if there is
+// none.
+func (s *Server) LatestPatchOf(req VersionsRequest, minor string) (*goversion.Version, error) {
+	c, err := PessimisticPatchConstraint(minor)
+	if err != nil {
+		return nil, err
+	}
+	return s.latestMatching(req, c)
+}
+
+// LatestMinorOf returns the newest release of the provider within the major
+// version named by major (e.g. "5"), as selected by
+// PessimisticMinorConstraint. ErrNoMatchingVersion is returned if there is
+// none.
+func (s *Server) LatestMinorOf(req VersionsRequest, major string) (*goversion.Version, error) {
+	c, err := PessimisticMinorConstraint(major)
+	if err != nil {
+		return nil, err
+	}
+	return s.latestMatching(req, c)
+}
+
+// latestMatching returns the newest available version satisfying the
+// constraint string c.
+func (s *Server) latestMatching(req VersionsRequest, c string) (*goversion.Version, error) {
+	constraints, err := goversion.NewConstraint(c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %w", c, err)
+	}
+	versions, err := s.GetAvailableVersionsMatching(req, constraints, WithVersionsLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s %s", ErrNoMatchingVersion, req.Namespace, req.Name, c)
+	}
+	return versions[0], nil
+}
@@ -1,6 +1,10 @@
 package tfpluginschema
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	goversion "github.com/hashicorp/go-version"
@@ -103,3 +107,136 @@ func TestGetLatestVersionMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLatestVersionMatch_NoMatchIsSentinel(t *testing.T) {
+	_, err := GetLatestVersionMatch(mustVersions(t, "1.0.0"), mustConstraints(t, "> 1.0.0"))
+	if !errors.Is(err, ErrNoMatchingVersion) {
+		t.Fatalf("expected ErrNoMatchingVersion, got %v", err)
+	}
+}
+
+func TestServer_GetAvailableVersionsMatching(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"versions":[{"version":"5.1.0"},{"version":"4.9.0"},{"version":"5.0.0"},{"version":"5.2.1"},{"version":"6.0.0"}]}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := VersionsRequest{Namespace: "hashicorp", Name: "aws"}
+
+	tests := []struct {
+		name        string
+		constraints goversion.Constraints
+		opts        []VersionsOption
+		want        []string
+	}{
+		{"no constraints", nil, nil, []string{"4.9.0", "5.0.0", "5.1.0", "5.2.1", "6.0.0"}},
+		{"pessimistic", mustConstraints(t, "~> 5.0"), nil, []string{"5.0.0", "5.1.0", "5.2.1"}},
+		{"limit keeps newest", mustConstraints(t, "~> 5.0"), []VersionsOption{WithVersionsLimit(2)}, []string{"5.1.0", "5.2.1"}},
+		{"limit larger than matches", mustConstraints(t, ">= 6.0"), []VersionsOption{WithVersionsLimit(5)}, []string{"6.0.0"}},
+		{"no match", mustConstraints(t, "> 7.0"), nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetAvailableVersionsMatching(req, tt.constraints, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotStrings := make([]string, len(got))
+			for i, v := range got {
+				gotStrings[i] = v.String()
+			}
+			if fmt.Sprint(gotStrings) != fmt.Sprint(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, gotStrings)
+			}
+		})
+	}
+}
+
+func TestPessimisticConstraints(t *testing.T) {
+	tests := []struct {
+		fn      func(string) (string, error)
+		version string
+		want    string
+	}{
+		{PessimisticPatchConstraint, "5.40", "~> 5.40.0"},
+		{PessimisticPatchConstraint, "5.40.3", "~> 5.40.0"},
+		{PessimisticPatchConstraint, "5", "~> 5.0.0"},
+		{PessimisticMinorConstraint, "5", "~> 5.0"},
+		{PessimisticMinorConstraint, "5.40.3", "~> 5.0"},
+	}
+	for _, tt := range tests {
+		got, err := tt.fn(tt.version)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.version, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.version, tt.want, got)
+		}
+	}
+	if _, err := PessimisticMinorConstraint("five"); err == nil {
+		t.Fatal("expected an error for an invalid version")
+	}
+}
+
+func TestServer_LatestPatchOfAndMinorOf(t *testing.T) {
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"versions":[{"version":"5.40.0"},{"version":"5.40.2"},{"version":"5.41.0"},{"version":"5.42.0-beta1"},{"version":"6.0.0"}]}`)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := VersionsRequest{Namespace: "hashicorp", Name: "aws"}
+
+	v, err := s.LatestPatchOf(req, "5.40")
+	if err != nil || v.String() != "5.40.2" {
+		t.Fatalf("LatestPatchOf: expected 5.40.2, got %v, %v", v, err)
+	}
+	v, err = s.LatestMinorOf(req, "5")
+	if err != nil || v.String() != "5.41.0" {
+		t.Fatalf("LatestMinorOf: expected 5.41.0, got %v, %v", v, err)
+	}
+	if _, err := s.LatestPatchOf(req, "5.39"); !errors.Is(err, ErrNoMatchingVersion) {
+		t.Fatalf("expected ErrNoMatchingVersion, got %v", err)
+	}
+}
+
+func TestServer_GetAvailableVersions_Paginated(t *testing.T) {
+	pages := map[string]string{
+		"paged?":         `{"versions":[{"version":"1.0.0"},{"version":"1.1.0"}],"meta":{"limit":2,"current_offset":0,"next_offset":2,"next_url":"/v1/providers/example/paged/versions?offset=2"}}`,
+		"paged?offset=2": `{"versions":[{"version":"2.0.0"},{"version":"1.1.0"}],"meta":{"limit":2,"current_offset":2,"next_offset":4,"next_url":"?offset=4"}}`,
+		"paged?offset=4": `{"versions":[{"version":"3.0.0"}],"meta":{"limit":2,"current_offset":4}}`,
+		"loop?":          `{"versions":[],"meta":{"next_url":"?offset=1"}}`,
+		"loop?offset=1":  `{"versions":[],"meta":{"next_url":"?offset=1"}}`,
+		"cross?":         `{"versions":[],"meta":{"next_url":"https://elsewhere.example/v1/providers/example/paged/versions"}}`,
+	}
+	var requests int
+	client := stubRegistryClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/providers/example/"), "/versions")
+		body, ok := pages[name+"?"+r.URL.RawQuery]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(client))
+	t.Cleanup(func() { _ = s.Cleanup() })
+
+	versions, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example", Name: "paged"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fmt.Sprint(versions); got != "[1.0.0 1.1.0 2.0.0 3.0.0]" {
+		t.Fatalf("expected every page's versions once, got %s", got)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+
+	for _, name := range []string{"loop", "cross"} {
+		_, err := s.GetAvailableVersions(VersionsRequest{Namespace: "example", Name: name})
+		if !errors.Is(err, ErrInvalidRegistryResponse) {
+			t.Fatalf("%s: expected ErrInvalidRegistryResponse, got %v", name, err)
+		}
+	}
+}
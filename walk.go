@@ -0,0 +1,145 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"iter"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ResourceSchemas returns an iterator over the name and schema of every
+// resource of the provider, in name order. The provider's schema is read
+// when ResourceSchemas is called, and any error is returned then. An entry
+// of a persisted or bundled schema that fails to decode is logged and
+// skipped; WalkResources returns an error for it instead.
+//
+// Schemas that no Get*Schema call has converted yet are converted as the
+// iteration reaches them and are not kept by the Server, so streaming
+// through a provider with thousands of resources holds one converted schema
+// at a time instead of all of them. The schemas are returned as by
+// GetResourceSchema with the same options.
+func (s *Server) ResourceSchemas(request Request, opts ...SchemaOption) (iter.Seq2[string, *tfjson.Schema], error) {
+	return schemaSeq(s, request, pickResources, s.schemaReturner(opts))
+}
+
+// DataSourceSchemas is ResourceSchemas for data sources.
+func (s *Server) DataSourceSchemas(request Request, opts ...SchemaOption) (iter.Seq2[string, *tfjson.Schema], error) {
+	return schemaSeq(s, request, pickDataSources, s.schemaReturner(opts))
+}
+
+// EphemeralResourceSchemas is ResourceSchemas for ephemeral resources.
+func (s *Server) EphemeralResourceSchemas(request Request, opts ...SchemaOption) (iter.Seq2[string, *tfjson.Schema], error) {
+	return schemaSeq(s, request, pickEphemeralResources, s.schemaReturner(opts))
+}
+
+// FunctionSchemas is ResourceSchemas for provider-defined functions.
+func (s *Server) FunctionSchemas(request Request, opts ...SchemaOption) (iter.Seq2[string, *tfjson.FunctionSignature], error) {
+	return schemaSeq(s, request, pickFunctions, s.functionReturner(opts))
+}
+
+// WalkResources calls fn with the name and schema of every resource of the
+// provider, in name order, as ResourceSchemas yields them. It stops at the
+// first error fn returns and returns that error as is. It also stops at an
+// entry that fails to decode, returning an error wrapping
+// ErrSchemaCorrupted.
+func (s *Server) WalkResources(request Request, fn func(name string, schema *tfjson.Schema) error, opts ...SchemaOption) error {
+	return walkSchemas(s, request, pickResources, s.schemaReturner(opts), fn)
+}
+
+// WalkDataSources is WalkResources for data sources.
+func (s *Server) WalkDataSources(request Request, fn func(name string, schema *tfjson.Schema) error, opts ...SchemaOption) error {
+	return walkSchemas(s, request, pickDataSources, s.schemaReturner(opts), fn)
+}
+
+// WalkEphemeralResources is WalkResources for ephemeral resources.
+func (s *Server) WalkEphemeralResources(request Request, fn func(name string, schema *tfjson.Schema) error, opts ...SchemaOption) error {
+	return walkSchemas(s, request, pickEphemeralResources, s.schemaReturner(opts), fn)
+}
+
+// WalkFunctions is WalkResources for provider-defined functions.
+func (s *Server) WalkFunctions(request Request, fn func(name string, signature *tfjson.FunctionSignature) error, opts ...SchemaOption) error {
+	return walkSchemas(s, request, pickFunctions, s.functionReturner(opts), fn)
+}
+
+func pickResources(ls *lazySchema) schemaMap[*tfjson.Schema] { return ls.resources }
+
+func pickDataSources(ls *lazySchema) schemaMap[*tfjson.Schema] { return ls.dataSources }
+
+func pickEphemeralResources(ls *lazySchema) schemaMap[*tfjson.Schema] { return ls.ephemeralResources }
+
+func pickFunctions(ls *lazySchema) schemaMap[*tfjson.FunctionSignature] { return ls.functions }
+
+func (s *Server) schemaReturner(opts []SchemaOption) func(*tfjson.Schema) *tfjson.Schema {
+	return func(r *tfjson.Schema) *tfjson.Schema { return s.returnSchema(r, opts) }
+}
+
+func (s *Server) functionReturner(opts []SchemaOption) func(*tfjson.FunctionSignature) *tfjson.FunctionSignature {
+	return func(r *tfjson.FunctionSignature) *tfjson.FunctionSignature { return s.returnFunction(r, opts) }
+}
+
+// schemaEntry is an entry of a schema map, or the error decoding it.
+type schemaEntry[R any] struct {
+	schema R
+	err    error
+}
+
+// schemaEntries reads the schema of request and returns an iterator over
+// the entries of the map pick selects from it.
+func schemaEntries[R any](s *Server, request Request, pick func(*lazySchema) schemaMap[R]) (iter.Seq2[string, schemaEntry[R]], error) {
+	ls, err := s.readSchema(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+	m := pick(ls)
+	names := schemaNames(ls, m)
+	return func(yield func(string, schemaEntry[R]) bool) {
+		for _, name := range names {
+			r, ok, err := peekSchema(ls, m, name)
+			if !ok {
+				continue
+			}
+			if !yield(name, schemaEntry[R]{schema: r, err: err}) {
+				return
+			}
+		}
+	}, nil
+}
+
+// schemaSeq returns an iterator over the entries schemaEntries returns,
+// passing each through ret and skipping those that fail to decode.
+func schemaSeq[R any](s *Server, request Request, pick func(*lazySchema) schemaMap[R], ret func(R) R) (iter.Seq2[string, R], error) {
+	entries, err := schemaEntries(s, request, pick)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(string, R) bool) {
+		for name, e := range entries {
+			if e.err != nil {
+				s.logger(logComponentSchema).Warn("Skipping schema entry that fails to decode", append(s.requestLogAttrs(request), "error", e.err)...)
+				continue
+			}
+			if !yield(name, ret(e.schema)) {
+				return
+			}
+		}
+	}, nil
+}
+
+// walkSchemas calls fn with the entries schemaEntries returns, passed
+// through ret, and stops at the first that fails to decode or that fn
+// returns an error for.
+func walkSchemas[R any](s *Server, request Request, pick func(*lazySchema) schemaMap[R], ret func(R) R, fn func(string, R) error) error {
+	entries, err := schemaEntries(s, request, pick)
+	if err != nil {
+		return err
+	}
+	for name, e := range entries {
+		if e.err != nil {
+			return e.err
+		}
+		if err := fn(name, ret(e.schema)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
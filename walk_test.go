@@ -0,0 +1,153 @@
+package tfpluginschema
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const walkSchema = `{
+	"resource_schemas": {
+		"example_b": {"version": 0, "block": {"description": "B."}},
+		"example_a": {"version": 0, "block": {"description": "A."}},
+		"example_c": {"version": 0, "block": {"description": "C."}}
+	},
+	"data_source_schemas": {"example_d": {"version": 0, "block": {}}},
+	"functions": {"parse": {"summary": "Parse.", "return_type": "string"}}
+}`
+
+func TestServer_ResourceSchemas(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(walkSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	seq, err := s.ResourceSchemas(req)
+	require.NoError(t, err)
+	var names, descriptions []string
+	for name, schema := range seq {
+		names = append(names, name)
+		descriptions = append(descriptions, schema.Block.Description)
+	}
+	assert.Equal(t, []string{"example_a", "example_b", "example_c"}, names)
+	assert.Equal(t, []string{"A.", "B.", "C."}, descriptions)
+
+	// Breaking out of the loop stops the iteration.
+	var first []string
+	for name := range seq {
+		first = append(first, name)
+		break
+	}
+	assert.Equal(t, []string{"example_a"}, first)
+
+	stripped, err := s.DataSourceSchemas(req, WithoutDescriptions())
+	require.NoError(t, err)
+	for name, schema := range stripped {
+		assert.Equal(t, "example_d", name)
+		assert.NotNil(t, schema.Block)
+	}
+
+	functions, err := s.FunctionSchemas(req)
+	require.NoError(t, err)
+	for name, fn := range functions {
+		assert.Equal(t, "parse", name)
+		assert.Equal(t, "Parse.", fn.Summary)
+	}
+
+	_, err = s.ResourceSchemas(Request{Namespace: "example", Name: "missing", Version: "1.0.0"})
+	assert.Error(t, err)
+}
+
+func TestServer_WalkResources(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(walkSchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	var names []string
+	require.NoError(t, s.WalkResources(req, func(name string, _ *tfjson.Schema) error {
+		names = append(names, name)
+		return nil
+	}))
+	assert.Equal(t, []string{"example_a", "example_b", "example_c"}, names)
+
+	stop := errors.New("stop")
+	names = nil
+	err := s.WalkResources(req, func(name string, _ *tfjson.Schema) error {
+		names = append(names, name)
+		if name == "example_b" {
+			return stop
+		}
+		return nil
+	})
+	assert.Same(t, stop, err)
+	assert.Equal(t, []string{"example_a", "example_b"}, names)
+
+	count := 0
+	require.NoError(t, s.WalkFunctions(req, func(string, *tfjson.FunctionSignature) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 1, count)
+	require.NoError(t, s.WalkEphemeralResources(req, func(string, *tfjson.Schema) error {
+		t.Error("the provider has no ephemeral resources")
+		return nil
+	}))
+}
+
+func TestServer_CorruptSchemaEntry(t *testing.T) {
+	schema := `{"resource_schemas": {"example_a": {"block": {}}, "example_b": {"block": 5}, "example_c": {"block": {}}}}`
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(schema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	_, err := s.GetResourceSchema(req, "example_b")
+	require.ErrorIs(t, err, ErrSchemaCorrupted)
+	_, err = s.GetResourceSchema(req, "example_a")
+	require.NoError(t, err)
+
+	seq, err := s.ResourceSchemas(req)
+	require.NoError(t, err)
+	var names []string
+	for name := range seq {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"example_a", "example_c"}, names, "the iterator skips the corrupted entry")
+
+	names = nil
+	err = s.WalkResources(req, func(name string, _ *tfjson.Schema) error {
+		names = append(names, name)
+		return nil
+	})
+	require.ErrorIs(t, err, ErrSchemaCorrupted)
+	assert.Equal(t, []string{"example_a"}, names)
+}
+
+func TestLazyMap_Peek(t *testing.T) {
+	conversions := 0
+	m := newLazyMap(map[string]int{"a": 1, "b": 2}, func(v int) int {
+		conversions++
+		return v * 10
+	})
+
+	r, ok, err := m.peek("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 10, r)
+	assert.Empty(t, m.converted, "peek does not keep the conversion")
+
+	_, _, _ = m.get("b")
+	r, ok, err = m.peek("b")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 20, r)
+	assert.Equal(t, 2, conversions, "peek reuses a kept conversion")
+
+	_, ok, _ = m.peek("c")
+	assert.False(t, ok)
+}
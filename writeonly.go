@@ -0,0 +1,90 @@
+package tfpluginschema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// WriteOnlyAttribute identifies a write-only attribute of a managed
+// resource. Write-only attributes, added in protocol 6.10, accept a value
+// in configuration that is passed to the provider but never stored in the
+// plan or state.
+type WriteOnlyAttribute struct {
+	// Resource is the resource type, e.g. "aws_db_instance".
+	Resource string `json:"resource"`
+	// Path is the dot-separated path of the attribute within the
+	// resource, through any nested blocks and nested attributes, e.g.
+	// "password_wo" or "settings.secret_wo".
+	Path string `json:"path"`
+}
+
+// String returns the attribute as "<resource>.<path>".
+func (a WriteOnlyAttribute) String() string {
+	return a.Resource + "." + a.Path
+}
+
+// ListWriteOnlyAttributes returns every write-only attribute of the
+// provider's managed resources, sorted by resource and path. Together with
+// ListEphemeralResources, whose results are never persisted either, it
+// tells security tooling which values stay out of state. The schema of
+// every resource is converted, so the first call on a large provider takes
+// a while.
+func (s *Server) ListWriteOnlyAttributes(request Request) ([]WriteOnlyAttribute, error) {
+	s.logger(logComponentSchema).Debug("Listing write-only attributes", s.requestLogAttrs(request)...)
+
+	schemaResp, err := s.readSchema(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider schema: %w", err)
+	}
+
+	var attrs []WriteOnlyAttribute
+	for _, name := range schemaNames(schemaResp, schemaResp.resources) {
+		schema, ok, err := schemaResp.resource(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || schema == nil {
+			continue
+		}
+		for _, path := range writeOnlyBlockPaths(schema.Block, nil) {
+			attrs = append(attrs, WriteOnlyAttribute{Resource: name, Path: path})
+		}
+	}
+	return attrs, nil
+}
+
+// writeOnlyBlockPaths returns the sorted paths of the write-only attributes
+// in b, each prefixed with prefix.
+func writeOnlyBlockPaths(b *tfjson.SchemaBlock, prefix []string) []string {
+	if b == nil {
+		return nil
+	}
+	paths := writeOnlyAttributePaths(b.Attributes, prefix)
+	for name, nb := range b.NestedBlocks {
+		if nb != nil {
+			paths = append(paths, writeOnlyBlockPaths(nb.Block, append(slices.Clip(prefix), name))...)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func writeOnlyAttributePaths(attrs map[string]*tfjson.SchemaAttribute, prefix []string) []string {
+	var paths []string
+	for name, a := range attrs {
+		if a == nil {
+			continue
+		}
+		path := append(slices.Clip(prefix), name)
+		if a.WriteOnly {
+			paths = append(paths, strings.Join(path, "."))
+		}
+		if a.AttributeNestedType != nil {
+			paths = append(paths, writeOnlyAttributePaths(a.AttributeNestedType.Attributes, path)...)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,49 @@
+package tfpluginschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const writeOnlySchema = `{
+	"resource_schemas": {
+		"example_db": {"version": 0, "block": {
+			"attributes": {
+				"name": {"type": "string", "required": true},
+				"password_wo": {"type": "string", "optional": true, "write_only": true},
+				"settings": {"optional": true, "nested_type": {"nesting_mode": "single", "attributes": {
+					"secret_wo": {"type": "string", "optional": true, "write_only": true},
+					"size": {"type": "number", "optional": true}
+				}}}
+			},
+			"block_types": {"credentials": {"nesting_mode": "list", "block": {"attributes": {
+				"token_wo": {"type": "string", "optional": true, "write_only": true}
+			}}}}
+		}},
+		"example_bucket": {"version": 0, "block": {"attributes": {"name": {"type": "string", "required": true}}}}
+	},
+	"ephemeral_resource_schemas": {"example_token": {"version": 0, "block": {"attributes": {"value": {"type": "string", "computed": true}}}}}
+}`
+
+func TestServer_ListWriteOnlyAttributes(t *testing.T) {
+	bundle := fstest.MapFS{"opentofu/example/example/1.0.0.json": {Data: []byte(writeOnlySchema)}}
+	s := NewServer(nil, WithCacheDir(t.TempDir()), WithHTTPClient(failingRegistryClient(t)), WithSchemaBundle(bundle), WithPluginExec(false))
+	t.Cleanup(func() { _ = s.Cleanup() })
+	req := Request{Namespace: "example", Name: "example", Version: "1.0.0"}
+
+	attrs, err := s.ListWriteOnlyAttributes(req)
+	require.NoError(t, err)
+	assert.Equal(t, []WriteOnlyAttribute{
+		{Resource: "example_db", Path: "credentials.token_wo"},
+		{Resource: "example_db", Path: "password_wo"},
+		{Resource: "example_db", Path: "settings.secret_wo"},
+	}, attrs)
+	assert.Equal(t, "example_db.password_wo", attrs[1].String())
+
+	ephemeral, err := s.ListEphemeralResources(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example_token"}, ephemeral)
+}